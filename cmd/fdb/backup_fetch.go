@@ -6,7 +6,6 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/fdb"
 	"github.com/wal-g/wal-g/utility"
@@ -25,12 +24,12 @@ var backupFetchCmd = &cobra.Command{
 		defer func() { _ = signalHandler.Close() }()
 
 		folder, err := internal.ConfigureFolder()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		restoreCmd, err := internal.GetCommandSettingContext(ctx, internal.NameStreamRestoreCmd)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		targetBackupSelector, err := internal.NewBackupNameSelector(args[0])
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		fdb.HandleBackupFetch(ctx, folder, targetBackupSelector, restoreCmd)
 	},
 }
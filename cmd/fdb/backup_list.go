@@ -2,7 +2,6 @@ package fdb
 
 import (
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -16,7 +15,7 @@ var backupListCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		folder, err := internal.ConfigureFolder()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		internal.DefaultHandleBackupList(folder.GetSubFolder(utility.BaseBackupPath), false, false)
 	},
 }
@@ -3,7 +3,6 @@ package fdb
 import (
 	"github.com/spf13/cobra"
 	"github.com/wal-g/storages/storage"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -48,40 +47,40 @@ var deleteEverythingCmd = &cobra.Command{
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler, err := newFdbDeleteHandler(folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.DeleteEverything(confirmed)
 }
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler, err := newFdbDeleteHandler(folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler, err := newFdbDeleteHandler(folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
 func runDeleteRetainAfter(args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler, err := newFdbDeleteHandler(folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.HandleDeleteRetainAfter(args, confirmed)
 }
@@ -7,7 +7,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -41,7 +40,7 @@ var (
 
 			arguments := greenplum.NewBackupArguments(permanent, userData, prepareSegmentFwdArgs())
 			backupHandler, err := greenplum.NewBackupHandler(arguments)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			backupHandler.HandleBackupPush()
 		},
 	}
@@ -2,11 +2,9 @@ package gp
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -23,7 +21,7 @@ var cmd = &cobra.Command{
 	Version: strings.Join([]string{walgVersion, gitRevision, buildDate, "GreenplumDB"}, "\t"),
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		err := internal.AssertRequiredSettingsSet()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	},
 }
 
@@ -32,7 +30,7 @@ var cmd = &cobra.Command{
 func Execute() {
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		internal.Exit(1)
 	}
 }
 
@@ -43,5 +41,6 @@ func init() {
 	cmd.PersistentFlags().StringVar(&internal.CfgFile, "config", "", "config file (default is $HOME/.wal-g.yaml)")
 	_ = cmd.MarkFlagRequired("config") // config is required for Greenplum WAL-G
 	cmd.InitDefaultVersionFlag()
+	cmd.PersistentFlags().BoolVar(&internal.ReadOnly, "read-only", false, "Reject any writes and deletions in the storage")
 	internal.AddConfigFlags(cmd)
 }
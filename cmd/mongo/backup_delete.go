@@ -6,7 +6,6 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
@@ -31,14 +30,14 @@ var backupDeleteCmd = &cobra.Command{
 
 		// set up storage downloader client
 		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		// set up storage downloader client
 		purger, err := archive.NewStoragePurger(archive.NewDefaultStorageSettings())
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		err = mongo.HandleBackupDelete(args[0], downloader, purger, !confirmedBackupDelete)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	},
 }
 
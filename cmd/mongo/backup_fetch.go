@@ -6,7 +6,6 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/utility"
@@ -25,15 +24,15 @@ var backupFetchCmd = &cobra.Command{
 		defer func() { _ = signalHandler.Close() }()
 
 		folder, err := internal.ConfigureFolder()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		restoreCmd, err := internal.GetCommandSettingContext(ctx, internal.NameStreamRestoreCmd)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		restoreCmd.Stdout = os.Stdout
 		restoreCmd.Stderr = os.Stderr
 
 		err = mongo.HandleBackupFetch(ctx, folder, args[0], restoreCmd)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	},
 }
 
@@ -4,14 +4,19 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
 )
 
 const backupListShortDescription = "Prints available backups"
 
-var verbose bool
+var (
+	verbose    bool
+	jsonOutput bool
+	pretty     bool
+	columns    []string
+)
 
 // backupListCmd represents the backupList command
 var backupListCmd = &cobra.Command{
@@ -20,14 +25,21 @@ var backupListCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
-		tracelog.ErrorLogger.FatalOnError(err)
-		listing := archive.NewDefaultTabbedBackupListing()
-		err = mongo.HandleBackupsList(downloader, listing, os.Stdout, verbose)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
+		var listing archive.BackupListing = archive.NewDefaultTabbedBackupListing()
+		if jsonOutput {
+			listing = archive.NewJSONBackupListing(pretty)
+		}
+		err = mongo.HandleBackupsList(downloader, listing, os.Stdout, verbose, columns)
+		internal.FatalOnError(err)
 	},
 }
 
 func init() {
 	cmd.AddCommand(backupListCmd)
 	backupListCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose mode")
+	backupListCmd.Flags().BoolVar(&jsonOutput, "json", false, "Prints output in json format")
+	backupListCmd.Flags().BoolVar(&pretty, "pretty", false, "Prints indented json output")
+	backupListCmd.Flags().StringSliceVar(&columns, "columns", nil,
+		"Comma separated list of backup fields to print, implies verbose mode")
 }
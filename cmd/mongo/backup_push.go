@@ -6,7 +6,6 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
@@ -35,29 +34,29 @@ var backupPushCmd = &cobra.Command{
 		defer func() { _ = signalHandler.Close() }()
 
 		mongodbURL, err := internal.GetRequiredSetting(internal.MongoDBUriSetting)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		// set up mongodb client and oplog fetcher
 		mongoClient, err := client.NewMongoClient(ctx, mongodbURL)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		uplProvider, err := internal.ConfigureUploader()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		uplProvider.UploadingFolder = uplProvider.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
 
 		backupCmd, err := internal.GetCommandSettingContext(ctx, internal.NameStreamCreateCmd)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		backupCmd.Stderr = os.Stderr
 		uploader := archive.NewStorageUploader(uplProvider)
 		metaConstructor := archive.NewBackupMongoMetaConstructor(ctx, mongoClient, uplProvider.UploadingFolder, permanent)
 
 		err = mongo.HandleBackupPush(uploader, metaConstructor, backupCmd)
-		tracelog.ErrorLogger.FatalfOnError("Backup creation failed: %v", err)
+		internal.FatalfOnError("Backup creation failed: %v", err)
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
 		internal.RequiredSettings[internal.NameStreamCreateCmd] = true
 		err := internal.AssertRequiredSettingsSet()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	},
 }
 
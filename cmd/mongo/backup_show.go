@@ -7,7 +7,7 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
 	"github.com/wal-g/wal-g/internal/databases/mongo/models"
@@ -28,7 +28,7 @@ var backupShowCmd = &cobra.Command{
 
 		// set up storage downloader client
 		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		err = mongo.HandleBackupShow(
 			downloader,
@@ -37,7 +37,7 @@ var backupShowCmd = &cobra.Command{
 				return json.Marshal(b)
 			},
 			os.Stdout)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	},
 }
 
@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
@@ -39,29 +38,29 @@ func runPurge(cmd *cobra.Command, args []string) {
 		mongo.PurgeGarbage(purgeGarbage)}
 	if cmd.Flags().Changed(retainAfterFlag) {
 		retainAfterTime, err := time.Parse(time.RFC3339, retainAfter)
-		tracelog.ErrorLogger.FatalfOnError("Can not parse retain time: %v", err)
+		internal.FatalfOnError("Can not parse retain time: %v", err)
 		opts = append(opts, mongo.PurgeRetainAfter(retainAfterTime))
 	} else if cmd.Flags().Changed(purgeOplogFlag) {
-		tracelog.ErrorLogger.Fatalf("Flag %q requires %q to be passed\n", purgeOplogFlag, retainAfterFlag)
+		internal.Fatalf("Flag %q requires %q to be passed\n", purgeOplogFlag, retainAfterFlag)
 	}
 
 	if cmd.Flags().Changed(retainCountFlag) {
 		if retainCount == 0 { // TODO: provide folder cleanup
-			tracelog.ErrorLogger.Fatalln("Retain count can not be 0")
+			internal.Fatal("Retain count can not be 0")
 		}
 		opts = append(opts, mongo.PurgeRetainCount(int(retainCount)))
 	}
 
 	// set up storage downloader client
 	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	// set up storage downloader client
 	purger, err := archive.NewStoragePurger(archive.NewDefaultStorageSettings())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	err = mongo.HandlePurge(downloader, purger, opts...)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 func init() {
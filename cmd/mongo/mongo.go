@@ -2,11 +2,9 @@ package mongo
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -23,9 +21,9 @@ var cmd = &cobra.Command{
 	Version: strings.Join([]string{walgVersion, gitRevision, buildDate, "MongoDB"}, "\t"),
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		err := internal.AssertRequiredSettingsSet()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		err = internal.ConfigureAndRunDefaultWebServer()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	},
 }
 
@@ -34,7 +32,7 @@ var cmd = &cobra.Command{
 func Execute() {
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		internal.Exit(1)
 	}
 }
 
@@ -45,5 +43,6 @@ func init() {
 	internal.RequiredSettings[internal.MongoDBUriSetting] = true
 	cmd.PersistentFlags().StringVar(&internal.CfgFile, "config", "", "config file (default is $HOME/.wal-g.yaml)")
 	cmd.InitDefaultVersionFlag()
+	cmd.PersistentFlags().BoolVar(&internal.ReadOnly, "read-only", false, "Reject any writes and deletions in the storage")
 	internal.AddConfigFlags(cmd)
 }
@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mongo"
 	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
@@ -23,7 +22,7 @@ var oplogPurgeCmd = &cobra.Command{
 
 func pitrDiscoveryAfterTime() *time.Time {
 	pitrDur, err := internal.GetOplogPITRDiscoveryIntervalSetting()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	if pitrDur == nil {
 		return nil
 	}
@@ -36,14 +35,14 @@ func runOplogPurge(cmd *cobra.Command, args []string) {
 	pitrAfterTime := pitrDiscoveryAfterTime()
 	// set up storage downloader client
 	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	// set up storage purger client
 	purger, err := archive.NewStoragePurger(archive.NewDefaultStorageSettings())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	err = mongo.HandleOplogPurge(downloader, purger, pitrAfterTime, !confirmedOplogPurge)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 func init() {
@@ -27,7 +27,7 @@ var oplogPushCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		var err error
-		defer func() { tracelog.ErrorLogger.FatalOnError(err) }()
+		defer func() { internal.FatalOnError(err) }()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
@@ -25,7 +25,7 @@ var oplogReplayCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		var err error
-		defer func() { tracelog.ErrorLogger.FatalOnError(err) }()
+		defer func() { internal.FatalOnError(err) }()
 
 		ctx, cancel := context.WithCancel(context.Background())
 		signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
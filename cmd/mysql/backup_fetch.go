@@ -3,7 +3,6 @@ package mysql
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mysql"
 )
@@ -22,17 +21,17 @@ var (
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			internal.RequiredSettings[internal.NameStreamRestoreCmd] = true
 			err := internal.AssertRequiredSettingsSet()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			folder, err := internal.ConfigureFolder()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			restoreCmd, err := internal.GetCommandSetting(internal.NameStreamRestoreCmd)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			prepareCmd, _ := internal.GetCommandSetting(internal.MysqlBackupPrepareCmd)
 
 			targetBackupSelector, err := createTargetBackupSelector(args, fetchTargetUserData)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 
 			mysql.HandleBackupFetch(folder, targetBackupSelector, restoreCmd, prepareCmd)
 		},
@@ -3,7 +3,6 @@ package mysql
 import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/mysql"
 )
@@ -24,13 +23,13 @@ var (
 			internal.RequiredSettings[internal.NameStreamCreateCmd] = true
 			internal.RequiredSettings[internal.MysqlDatasourceNameSetting] = true
 			err := internal.AssertRequiredSettingsSet()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			uploader, err := internal.ConfigureUploader()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			backupCmd, err := internal.GetCommandSetting(internal.NameStreamCreateCmd)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 
 			if userData == "" {
 				userData = viper.GetString(internal.SentinelUserDataSetting)
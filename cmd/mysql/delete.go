@@ -59,13 +59,13 @@ type DeleteHandler struct {
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	deleteHandler, err := NewMySQLDeleteHandler()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	deleteHandler.HandleDeleteEverything(args, deleteHandler.permanentObjects, confirmed)
 }
 
 func runDeleteTarget(cmd *cobra.Command, args []string) {
 	deleteHandler, err := NewMySQLDeleteHandler()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	bname := args[0]                                             // backup name
 	backupSelector, err := internal.NewBackupNameSelector(bname) //todo: add selection by userdata
@@ -76,14 +76,14 @@ func runDeleteTarget(cmd *cobra.Command, args []string) {
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
 	deleteHandler, err := NewMySQLDeleteHandler()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
 	deleteHandler, err := NewMySQLDeleteHandler()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
@@ -142,7 +142,7 @@ func IsPermanent(objectName string, permanentBackups map[string]bool) bool {
 
 func NewMySQLDeleteHandler() (*DeleteHandler, error) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	backups, err := internal.GetBackupSentinelObjects(folder)
 	if err != nil {
@@ -2,7 +2,6 @@ package mysql
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -34,7 +33,7 @@ var cmd = &cobra.Command{
 func Execute() {
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		internal.Exit(1)
 	}
 }
 
@@ -45,5 +44,6 @@ func init() {
 	cmd.PersistentFlags().StringVar(&internal.CfgFile, "config", "", "config file (default is $HOME/.walg.json)")
 	cmd.PersistentFlags().BoolVarP(&internal.Turbo, "turbo", "", false, "Ignore all kinds of throttling defined in config")
 	cmd.InitDefaultVersionFlag()
+	cmd.PersistentFlags().BoolVar(&internal.ReadOnly, "read-only", false, "Reject any writes and deletions in the storage")
 	internal.AddConfigFlags(cmd)
 }
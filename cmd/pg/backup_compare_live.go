@@ -0,0 +1,41 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupCompareLiveShortDescription = "Prints the files of the data directory added, removed or changed " +
+		"since the backup"
+	backupCompareLiveJSONDescription      = "Output the difference in JSON format"
+	backupCompareLiveChecksumsDescription = "Also compare the SHA256 checksums of the files of the same size " +
+		"recorded with WALG_BACKUP_FILE_CHECKSUMS, reading every such file of the data directory"
+)
+
+var (
+	backupCompareLiveJSON      bool
+	backupCompareLiveChecksums bool
+)
+
+var backupCompareLiveCmd = &cobra.Command{
+	Use:   "backup-compare-live backup_name db_directory",
+	Short: backupCompareLiveShortDescription,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		internal.FatalOnError(err)
+		postgres.HandleBackupCompareLive(folder, args[0], args[1], backupCompareLiveChecksums,
+			os.Stdout, backupCompareLiveJSON)
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupCompareLiveCmd)
+	backupCompareLiveCmd.Flags().BoolVar(&backupCompareLiveJSON, "json", false, backupCompareLiveJSONDescription)
+	backupCompareLiveCmd.Flags().BoolVar(&backupCompareLiveChecksums, "checksums", false,
+		backupCompareLiveChecksumsDescription)
+}
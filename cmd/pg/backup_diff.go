@@ -0,0 +1,32 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupDiffShortDescription = "Prints the files added, removed or changed between two backups"
+	backupDiffJSONDescription  = "Output the difference in JSON format"
+)
+
+var backupDiffJSON bool
+
+var backupDiffCmd = &cobra.Command{
+	Use:   "backup-diff from_backup_name to_backup_name",
+	Short: backupDiffShortDescription,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		internal.FatalOnError(err)
+		postgres.HandleBackupDiff(folder, args[0], args[1], os.Stdout, backupDiffJSON)
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupDiffCmd)
+	backupDiffCmd.Flags().BoolVar(&backupDiffJSON, "json", false, backupDiffJSONDescription)
+}
@@ -0,0 +1,41 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupEstimateShortDescription = "Estimates the size of the backup without making it"
+	backupEstimateLongDescription  = `Walks the data directory the way backup-push does and prints the file count,
+the size of the data to archive and the compressed size estimated from a sampled compression ratio.
+Nothing is uploaded and postgres is not contacted. With WALG_DELTA_MAX_STEPS set the delta is estimated
+from the latest backup by scanning the pages of the paged files.`
+	estimateFullBackupDescription = "Estimate the full backup even if the delta backup would be made"
+)
+
+var (
+	// backupEstimateCmd represents the backupEstimate command
+	backupEstimateCmd = &cobra.Command{
+		Use:   "backup-estimate db_directory",
+		Short: backupEstimateShortDescription,
+		Long:  backupEstimateLongDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			postgres.HandleBackupEstimate(folder, args[0], estimateFullBackup, os.Stdout)
+		},
+	}
+	estimateFullBackup = false
+)
+
+func init() {
+	cmd.AddCommand(backupEstimateCmd)
+
+	backupEstimateCmd.Flags().BoolVarP(&estimateFullBackup, fullBackupFlag, fullBackupShorthand,
+		false, estimateFullBackupDescription)
+}
@@ -21,6 +21,40 @@ For information about pattern syntax view: https://golang.org/pkg/path/filepath/
 	reverseDeltaUnpackDescription = "Unpack delta backups in reverse order (beta feature)"
 	skipRedundantTarsDescription  = "Skip tars with no useful data (requires reverse delta unpack)"
 	targetUserDataDescription     = "Fetch storage backup which has the specified user data"
+	resumeFetchDescription        = "Resume the interrupted fetch skipping already extracted tar partitions"
+	consistencyWalDescription     = "Also fetch the WAL segments required to make the backup consistent into pg_wal"
+	targetTimeDescription         = "Write recovery settings to recover up to the specified time (recovery_target_time)"
+	targetLsnDescription          = "Write recovery settings to recover up to the specified LSN (recovery_target_lsn)"
+	targetInclusiveDescription    = "Whether to stop just after (true) or just before (false) the recovery target"
+	targetActionDescription       = "Action after the recovery target is reached: pause, promote or shutdown"
+	applyDelayDescription         = "Write recovery_min_apply_delay to delay the WAL replay, e.g. 30min or 1h"
+	restoreExtraConfigDescription = "Restore the files captured by WALG_BACKUP_EXTRA_FILES into this directory " +
+		"keeping their original paths relative to it (use / to restore to the original paths)"
+	expectedPgVersionDescription = "Fail if the backup PostgreSQL major version (e.g. 13 or 9.6) differs. " +
+		"If not set, the version is detected from pg_ctl on PATH and mismatches are only logged"
+	forceFetchDescription = "Skip the checks that the target filesystem has enough free space for the backup " +
+		"and that the delta bases were not modified, only warn about the backup files missing after the restore"
+	toTarDescription = "Write the full backup to the specified local tar file instead of extracting it. " +
+		"The arguments are [backup_name] then"
+	toTarCompressionDescription = "Compress the tar file written with --to-tar using the method: " +
+		"lz4, lzma, zstd or brotli (uncompressed by default)"
+	relocateRootDescription = "Restore the tablespaces under the specified root directory, " +
+		"rewriting their absolute paths in the symlinks and tablespace_map. " +
+		"destination_directory must be inside the root"
+	writeManifestDescription = "Write the backup_manifest into the restored directory " +
+		"to verify it with pg_verifybackup (PostgreSQL 13+)"
+	incrementalOntoDescription = "Update the data directory holding the restored ancestor of the backup " +
+		"by applying only the deltas after it. The arguments are [backup_name] then"
+	recreateSlotsDescription = "Recreate the replication slots recorded in the backup on the running server " +
+		"instead of fetching it. The arguments are [backup_name] then"
+	noRecoveryDescription = "Restore the files for the inspection only: rename the recovery triggering files " +
+		"and make the server refuse to start, so the WAL replay is never attempted"
+	verifyManifestDescription = "Check every extracted file against the SHA256 checksum recorded at backup-push " +
+		"with WALG_BACKUP_FILE_CHECKSUMS and fail on the first mismatch"
+	preExtractHookDescription = "Shell command preparing the directory to extract the backup into, " +
+		"e.g. a mounted snapshot or clone, and printing its path on stdout. The arguments are [backup_name] then"
+	postExtractHookDescription = "Shell command finalizing the extract target after the successful fetch, " +
+		"run with WALG_FETCH_DIRECTORY and WALG_FETCH_BACKUP_NAME set instead of WALG_POST_FETCH_HOOK"
 )
 
 var fileMask string
@@ -28,34 +62,238 @@ var restoreSpec string
 var reverseDeltaUnpack bool
 var skipRedundantTars bool
 var fetchTargetUserData string
+var resumeFetch bool
+var fetchConsistencyWal bool
+var recoveryTargetTime string
+var recoveryTargetLsn string
+var recoveryTargetInclusive string
+var recoveryTargetAction string
+var recoveryApplyDelay string
+var expectedPgVersion string
+var restoreExtraConfig string
+var forceFetch bool
+var toTar string
+var toTarCompression string
+var relocateRoot string
+var recreateSlots bool
+var incrementalOnto string
+var writeManifest bool
+var noRecovery bool
+var verifyManifest bool
+var preExtractHook string
+var postExtractHook string
 
 var backupFetchCmd = &cobra.Command{
-	Use:   "backup-fetch destination_directory [backup_name | --target-user-data <data>]",
+	Use: "backup-fetch destination_directory [backup_name | --target-user-data <data>] | " +
+		"--to-tar <file> [backup_name | --target-user-data <data>] | " +
+		"--recreate-slots [backup_name | --target-user-data <data>] | " +
+		"--incremental-onto <existing_directory> [backup_name | --target-user-data <data>] | " +
+		"--pre-extract-hook <command> [backup_name | --target-user-data <data>]",
 	Short: backupFetchShortDescription, // TODO : improve description
-	Args:  cobra.RangeArgs(1, 2),
+	Args:  checkBackupFetchArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		if fetchTargetUserData == "" {
 			fetchTargetUserData = viper.GetString(internal.FetchTargetUserDataSetting)
 		}
+		if toTar != "" && recreateSlots {
+			internal.Fatal("--to-tar and --recreate-slots can't be used together\n")
+		}
+		if noRecovery && (toTar != "" || recreateSlots) {
+			internal.Fatal("--no-recovery can't be used with --to-tar or --recreate-slots\n")
+		}
+		if incrementalOnto != "" && (toTar != "" || recreateSlots) {
+			internal.Fatal("--incremental-onto can't be used with --to-tar or --recreate-slots\n")
+		}
+		if preExtractHook != "" && (toTar != "" || recreateSlots || incrementalOnto != "") {
+			internal.Fatal("--pre-extract-hook can't be used with --to-tar, --recreate-slots " +
+				"or --incremental-onto\n")
+		}
+		if postExtractHook != "" && (toTar != "" || recreateSlots) {
+			internal.Fatal("--post-extract-hook can't be used with --to-tar or --recreate-slots\n")
+		}
+		if postgres.GetPostFetchHook(postExtractHook) != "" && (toTar != "" || recreateSlots) {
+			// the hook is configured for the restores into a directory, so it is not a reason to fail the export
+			tracelog.WarningLogger.Printf("%s is not run with --to-tar and --recreate-slots, "+
+				"there is no restored directory\n", internal.PostFetchHookSetting)
+		}
+		if toTar != "" || recreateSlots || preExtractHook != "" {
+			// there is no destination directory, the backup name is the only argument
+			args = append([]string{""}, args...)
+		}
+		if incrementalOnto != "" {
+			args = append([]string{incrementalOnto}, args...)
+		}
 		targetBackupSelector, err := createTargetFetchBackupSelector(cmd, args, fetchTargetUserData)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
+
+		if toTar != "" {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			internal.HandleBackupFetch(folder, targetBackupSelector, func(folder storage.Folder, backup internal.Backup) {
+				postgres.HandleBackupTarExport(folder, backup, toTar, toTarCompression)
+			})
+			return
+		}
+
+		if recreateSlots {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			internal.HandleBackupFetch(folder, targetBackupSelector, postgres.HandleReplicationSlotsRecreate)
+			return
+		}
+
+		recoveryConfig, err := postgres.NewRecoveryConfig(recoveryTargetTime, recoveryTargetLsn,
+			recoveryTargetInclusive, recoveryTargetAction, recoveryApplyDelay)
+		internal.FatalOnError(err)
+		if noRecovery && (recoveryConfig.HasSettings() || fetchConsistencyWal) {
+			internal.Fatal("--no-recovery can't be used with the recovery target, " +
+				"--apply-delay or --consistency-wal\n")
+		}
+
+		pgVersionChecker, err := postgres.NewPgVersionChecker(expectedPgVersion)
+		internal.FatalOnError(err)
 
 		folder, err := internal.ConfigureFolder()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
+
+		if preExtractHook != "" {
+			// the extract target is the directory prepared by the hook
+			args[0], targetBackupSelector = postgres.HandlePreExtractHook(folder, targetBackupSelector, preExtractHook)
+		}
+
+		if relocateRoot != "" {
+			internal.FatalOnError(postgres.CheckRelocationRoot(relocateRoot, args[0]))
+		}
 
 		var pgFetcher func(folder storage.Folder, backup internal.Backup)
 		reverseDeltaUnpack = reverseDeltaUnpack || viper.GetBool(internal.UseReverseUnpackSetting)
 		skipRedundantTars = skipRedundantTars || viper.GetBool(internal.SkipRedundantTarsSetting)
-		if reverseDeltaUnpack {
-			pgFetcher = postgres.GetPgFetcherNew(args[0], fileMask, restoreSpec, skipRedundantTars)
+		if incrementalOnto != "" {
+			if reverseDeltaUnpack || resumeFetch || fileMask != "" || relocateRoot != "" || verifyManifest {
+				internal.Fatal("--incremental-onto can't be used with the reverse delta unpack, " +
+					"--resume, --mask, --relocate-root or --verify-manifest\n")
+			}
+			pgFetcher = postgres.GetPgFetcherIncrementalOnto(args[0], restoreSpec)
+		} else if reverseDeltaUnpack {
+			if resumeFetch || verifyManifest {
+				internal.Fatal("--resume and --verify-manifest are not supported with the reverse delta unpack\n")
+			}
+			pgFetcher = postgres.GetPgFetcherNew(args[0], fileMask, restoreSpec, relocateRoot, skipRedundantTars)
 		} else {
-			pgFetcher = postgres.GetPgFetcherOld(args[0], fileMask, restoreSpec)
+			pgFetcher = postgres.GetPgFetcherOld(args[0], fileMask, restoreSpec, relocateRoot, resumeFetch, verifyManifest)
+		}
+
+		if relocateRoot != "" {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandleTablespaceMapRelocation(args[0], relocateRoot)
+			}
+		}
+
+		if fileMask == "" {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandleRestoredFilesCheck(folder, backup, args[0], forceFetch)
+			}
+		}
+
+		if !forceFetch && fileMask == "" && incrementalOnto == "" {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				postgres.HandleFreeSpaceCheck(folder, backup, args[0])
+				backupFetcher(folder, backup)
+			}
+		}
+
+		if !forceFetch {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				postgres.HandleDeltaBasesCheck(folder, backup)
+				backupFetcher(folder, backup)
+			}
+		}
+
+		backupFetcher := pgFetcher
+		pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+			postgres.HandleColdTierReport(folder, backup)
+			backupFetcher(folder, backup)
+		}
+
+		if pgVersionChecker != nil {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				postgres.HandlePgVersionCheckBeforeFetch(folder, backup, pgVersionChecker)
+				backupFetcher(folder, backup)
+				if fileMask == "" {
+					postgres.HandlePgVersionCheckAfterFetch(args[0], pgVersionChecker)
+				}
+			}
+		}
+
+		if fetchConsistencyWal {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandleConsistencyWalFetch(folder, backup, args[0])
+			}
+		}
+
+		if restoreExtraConfig != "" {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandleExtraFilesRestore(folder, backup, restoreExtraConfig)
+			}
+		}
+
+		if writeManifest {
+			if fileMask != "" {
+				internal.Fatal("--write-manifest can't be used with --mask\n")
+			}
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandleBackupManifestWrite(folder, backup, args[0])
+			}
+		}
+
+		if noRecovery {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandleNoRecovery(backup, args[0])
+			}
+		}
+
+		if recoveryConfig.HasSettings() {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandleRecoveryConfigWrite(folder, backup, args[0], recoveryConfig)
+			}
+		}
+
+		if postFetchHook := postgres.GetPostFetchHook(postExtractHook); postFetchHook != "" {
+			backupFetcher := pgFetcher
+			pgFetcher = func(folder storage.Folder, backup internal.Backup) {
+				backupFetcher(folder, backup)
+				postgres.HandlePostFetchHook(backup, args[0], postFetchHook)
+			}
 		}
 
 		internal.HandleBackupFetch(folder, targetBackupSelector, pgFetcher)
 	},
 }
 
+func checkBackupFetchArgs(cmd *cobra.Command, args []string) error {
+	if toTar != "" || recreateSlots || incrementalOnto != "" || preExtractHook != "" {
+		return cobra.MaximumNArgs(1)(cmd, args)
+	}
+	return cobra.RangeArgs(1, 2)(cmd, args)
+}
+
 // create the BackupSelector to select the backup to fetch
 func createTargetFetchBackupSelector(cmd *cobra.Command,
 	args []string, targetUserData string) (internal.BackupSelector, error) {
@@ -81,5 +319,27 @@ func init() {
 		false, skipRedundantTarsDescription)
 	backupFetchCmd.Flags().StringVar(&fetchTargetUserData, "target-user-data",
 		"", targetUserDataDescription)
+	backupFetchCmd.Flags().BoolVar(&resumeFetch, "resume",
+		false, resumeFetchDescription)
+	backupFetchCmd.Flags().BoolVar(&fetchConsistencyWal, "consistency-wal",
+		false, consistencyWalDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetTime, "target-time", "", targetTimeDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetLsn, "target-lsn", "", targetLsnDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetInclusive, "target-inclusive", "", targetInclusiveDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryTargetAction, "target-action", "", targetActionDescription)
+	backupFetchCmd.Flags().StringVar(&recoveryApplyDelay, "apply-delay", "", applyDelayDescription)
+	backupFetchCmd.Flags().StringVar(&restoreExtraConfig, "restore-extra-config", "", restoreExtraConfigDescription)
+	backupFetchCmd.Flags().StringVar(&expectedPgVersion, "expected-pg-version", "", expectedPgVersionDescription)
+	backupFetchCmd.Flags().BoolVar(&forceFetch, "force", false, forceFetchDescription)
+	backupFetchCmd.Flags().StringVar(&toTar, "to-tar", "", toTarDescription)
+	backupFetchCmd.Flags().StringVar(&toTarCompression, "to-tar-compression", "", toTarCompressionDescription)
+	backupFetchCmd.Flags().StringVar(&relocateRoot, "relocate-root", "", relocateRootDescription)
+	backupFetchCmd.Flags().BoolVar(&recreateSlots, "recreate-slots", false, recreateSlotsDescription)
+	backupFetchCmd.Flags().BoolVar(&writeManifest, "write-manifest", false, writeManifestDescription)
+	backupFetchCmd.Flags().BoolVar(&noRecovery, "no-recovery", false, noRecoveryDescription)
+	backupFetchCmd.Flags().BoolVar(&verifyManifest, "verify-manifest", false, verifyManifestDescription)
+	backupFetchCmd.Flags().StringVar(&incrementalOnto, "incremental-onto", "", incrementalOntoDescription)
+	backupFetchCmd.Flags().StringVar(&preExtractHook, "pre-extract-hook", "", preExtractHookDescription)
+	backupFetchCmd.Flags().StringVar(&postExtractHook, "post-extract-hook", "", postExtractHookDescription)
 	cmd.AddCommand(backupFetchCmd)
 }
@@ -0,0 +1,46 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupFlattenShortDescription = "Materializes a delta backup as a new standalone full backup"
+	backupFlattenLongDescription  = `Restores the delta backup with its delta chain into a temporary directory
+and uploads the result as a new full backup, named as the delta without its increment suffix.
+The system identifier and the LSNs of the delta are preserved, so the new backup restores
+to the same point without traversing the chain. The temporary directory needs the space
+of the restored cluster, which is checked before the restore unless --force is given.
+Backups with tablespaces are not supported.`
+	backupFlattenKeepChainDescription = "Keep the flattened delta backup. " +
+		"By default it is deleted unless it is permanent or other deltas are made from it"
+	backupFlattenTempDirDescription = "Directory to restore the delta backup into, the system temporary directory by default"
+	backupFlattenForceDescription   = "Flatten the backup even if the temporary directory seems to lack the free space"
+)
+
+var (
+	backupFlattenKeepChain bool
+	backupFlattenTempDir   string
+	backupFlattenForce     bool
+)
+
+var backupFlattenCmd = &cobra.Command{
+	Use:   "backup-flatten delta_backup_name",
+	Short: backupFlattenShortDescription,
+	Long:  backupFlattenLongDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		uploader, err := postgres.ConfigureBackupUploader()
+		internal.FatalOnError(err)
+		postgres.HandleBackupFlatten(uploader, args[0], backupFlattenTempDir, backupFlattenKeepChain, backupFlattenForce)
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupFlattenCmd)
+	backupFlattenCmd.Flags().BoolVar(&backupFlattenKeepChain, "keep-chain", false, backupFlattenKeepChainDescription)
+	backupFlattenCmd.Flags().StringVar(&backupFlattenTempDir, "temp-directory", "", backupFlattenTempDirDescription)
+	backupFlattenCmd.Flags().BoolVar(&backupFlattenForce, "force", false, backupFlattenForceDescription)
+}
@@ -1,8 +1,9 @@
 package pg
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 	"github.com/wal-g/wal-g/utility"
@@ -13,6 +14,10 @@ const (
 	PrettyFlag                 = "pretty"
 	JSONFlag                   = "json"
 	DetailFlag                 = "detail"
+	FormatFlag                 = "format"
+	MaxAgeFlag                 = "max-age"
+	maxAgeFlagDescription      = "Exit with non-zero code if the newest backup is older than the specified duration"
+	formatFlagDescription      = "Output format: table, csv or json. Takes precedence over --json"
 )
 
 var (
@@ -23,17 +28,24 @@ var (
 		Args:  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			folder, err := internal.ConfigureFolder()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
+			listFormat, err := internal.GetBackupListFormat(backupListFormat, json)
+			internal.FatalOnError(err)
 			if detail {
-				postgres.HandleDetailedBackupList(folder.GetSubFolder(utility.BaseBackupPath), pretty, json)
+				postgres.HandleDetailedBackupListWithFormat(folder.GetSubFolder(utility.BaseBackupPath), listFormat, pretty)
 			} else {
-				internal.DefaultHandleBackupList(folder.GetSubFolder(utility.BaseBackupPath), pretty, json)
+				internal.DefaultHandleBackupListWithFormat(folder.GetSubFolder(utility.BaseBackupPath), listFormat, pretty)
+			}
+			if maxAge > 0 {
+				internal.HandleBackupMaxAgeCheck(folder.GetSubFolder(utility.BaseBackupPath), maxAge)
 			}
 		},
 	}
-	pretty = false
-	json   = false
-	detail = false
+	pretty           = false
+	json             = false
+	detail           = false
+	backupListFormat = ""
+	maxAge           time.Duration
 )
 
 func init() {
@@ -44,4 +56,6 @@ func init() {
 	backupListCmd.Flags().BoolVar(&pretty, PrettyFlag, false, "Prints more readable output")
 	backupListCmd.Flags().BoolVar(&json, JSONFlag, false, "Prints output in json format")
 	backupListCmd.Flags().BoolVar(&detail, DetailFlag, false, "Prints extra backup details")
+	backupListCmd.Flags().StringVar(&backupListFormat, FormatFlag, "", formatFlagDescription)
+	backupListCmd.Flags().DurationVar(&maxAge, MaxAgeFlag, 0, maxAgeFlagDescription)
 }
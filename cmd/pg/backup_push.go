@@ -2,6 +2,8 @@ package pg
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/wal-g/wal-g/utility"
 
@@ -25,6 +27,14 @@ const (
 	deltaFromUserDataFlag     = "delta-from-user-data"
 	deltaFromNameFlag         = "delta-from-name"
 	addUserDataFlag           = "add-user-data"
+	checkpointFlag            = "checkpoint"
+	guaranteedConsistentFlag  = "guaranteed-consistent"
+	consistencyTimeoutFlag    = "consistency-timeout"
+	noWaitForWalFlag          = "no-wait-for-wal"
+	waitForWalFlag            = "wait-for-wal"
+	allowDeltaBaseFlag        = "allow-delta-base"
+	noMasterCheckFlag         = "no-master-check"
+	retentionClassFlag        = "retention-class"
 
 	permanentShorthand             = "p"
 	fullBackupShorthand            = "f"
@@ -61,18 +71,25 @@ var (
 				deltaFromUserData = viper.GetString(internal.DeltaFromUserDataSetting)
 			}
 			deltaBaseSelector, err := createDeltaBaseSelector(cmd, deltaFromName, deltaFromUserData)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 
 			if userData == "" {
 				userData = viper.GetString(internal.SentinelUserDataSetting)
 			}
+			fastCheckpoint, err := postgres.ParseCheckpointMode(checkpointMode)
+			internal.FatalOnError(err)
+			if waitForWal && noWaitForWal {
+				internal.Fatalf("--%s and --%s can't be used together\n", waitForWalFlag, noWaitForWalFlag)
+			}
+
 			arguments := postgres.NewBackupArguments(dataDirectory, utility.BaseBackupPath,
 				permanent, verifyPageChecksums || viper.GetBool(internal.VerifyPageChecksumsSetting),
 				fullBackup, storeAllCorruptBlocks || viper.GetBool(internal.StoreAllCorruptBlocksSetting),
-				tarBallComposerType, deltaBaseSelector, userData)
+				tarBallComposerType, deltaBaseSelector, userData, fastCheckpoint,
+				guaranteedConsistent, consistencyTimeout, noWaitForWal, allowDeltaBase, noMasterCheck, retentionClass)
 
 			backupHandler, err := postgres.NewBackupHandler(arguments)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			backupHandler.HandleBackupPush()
 		},
 	}
@@ -84,6 +101,14 @@ var (
 	deltaFromName         = ""
 	deltaFromUserData     = ""
 	userData              = ""
+	checkpointMode        = postgres.FastCheckpointMode
+	guaranteedConsistent  = false
+	consistencyTimeout    = 10 * time.Minute
+	noWaitForWal          = false
+	waitForWal            = false
+	allowDeltaBase        = false
+	noMasterCheck         = false
+	retentionClass        = ""
 )
 
 // create the BackupSelector for delta backup base according to the provided flags
@@ -129,4 +154,22 @@ func init() {
 		"", "Select the backup specified by UserData as the target for the delta backup")
 	backupPushCmd.Flags().StringVar(&userData, addUserDataFlag,
 		"", "Write the provided user data to the backup sentinel and metadata files.")
+	backupPushCmd.Flags().StringVar(&checkpointMode, checkpointFlag,
+		postgres.FastCheckpointMode, "Checkpoint mode at the backup start: fast or spread")
+	backupPushCmd.Flags().BoolVar(&guaranteedConsistent, guaranteedConsistentFlag,
+		false, "Wait until the WAL required to restore the backup is archived before finishing")
+	backupPushCmd.Flags().DurationVar(&consistencyTimeout, consistencyTimeoutFlag,
+		10*time.Minute, "How long to wait for the WAL archival with --"+guaranteedConsistentFlag)
+	backupPushCmd.Flags().BoolVar(&noWaitForWal, noWaitForWalFlag,
+		false, "Stop the backup without waiting until the WAL required to restore the backup is archived")
+	// the backup stop waits for the WAL archival by default, the flag is kept for the existing scripts
+	backupPushCmd.Flags().BoolVar(&waitForWal, waitForWalFlag,
+		false, "Does nothing, the backup stop waits for the WAL archival unless --"+noWaitForWalFlag+" is set")
+	backupPushCmd.Flags().BoolVar(&allowDeltaBase, allowDeltaBaseFlag,
+		false, "Allow the backup selected by --"+deltaFromNameFlag+" to be a delta backup itself")
+	backupPushCmd.Flags().BoolVar(&noMasterCheck, noMasterCheckFlag,
+		false, "Limited mode for managed Postgres: tolerate the unavailable system identifier and data directory "+
+			"queries (remote backup only)")
+	backupPushCmd.Flags().StringVar(&retentionClass, retentionClassFlag,
+		"", "Retention class of the backup for delete gfs: "+strings.Join(internal.RetentionClasses, ", "))
 }
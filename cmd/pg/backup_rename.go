@@ -0,0 +1,31 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupRenameShortDescription = "Renames a backup"
+	backupRenameLongDescription  = `Copies the objects of the backup to the new name, the sentinel last,
+and deletes the objects of the old name afterwards. The deltas made from the backup
+are updated to the new name. The new name must not be taken, and must contain
+the WAL segment of the old name, e.g. nightly_000000010000000000000002.`
+)
+
+var backupRenameCmd = &cobra.Command{
+	Use:   "backup-rename backup_name new_backup_name",
+	Short: backupRenameShortDescription,
+	Long:  backupRenameLongDescription,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		internal.FatalOnError(err)
+		postgres.HandleBackupRename(folder, args[0], args[1])
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupRenameCmd)
+}
@@ -0,0 +1,34 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupRepairSentinelShortDescription = "Reconstructs the lost or corrupted sentinel of a backup from its tar partitions"
+	backupRepairSentinelLongDescription  = `Scans the tar partitions of the backup and uploads the sentinel rebuilt from them,
+so backup-fetch can restore the backup. The sentinel is marked as reconstructed,
+the fields which can't be recovered (e.g. the finish LSN) are reported.`
+	backupRepairSentinelForceDescription = "Replace the sentinel even if it is readable"
+)
+
+var backupRepairSentinelForce bool
+
+var backupRepairSentinelCmd = &cobra.Command{
+	Use:   "backup-repair-sentinel backup_name",
+	Short: backupRepairSentinelShortDescription,
+	Long:  backupRepairSentinelLongDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		internal.FatalOnError(err)
+		postgres.HandleBackupRepairSentinel(folder, args[0], backupRepairSentinelForce)
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupRepairSentinelCmd)
+	backupRepairSentinelCmd.Flags().BoolVar(&backupRepairSentinelForce, "force", false, backupRepairSentinelForceDescription)
+}
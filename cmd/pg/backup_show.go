@@ -0,0 +1,36 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupShowShortDescription = "Shows the WAL segments a backup needs to be consistent"
+	backupShowLongDescription  = `Computes the first and the last WAL segment of the backup from the start
+and the finish LSN in its sentinel, with the WAL segment size of the backup. These segments
+and the ones between them must be kept in the storage to restore the backup.`
+	backupShowJSONDescription = "Print the WAL segments in JSON"
+)
+
+var backupShowJSON bool
+
+var backupShowCmd = &cobra.Command{
+	Use:   "backup-show backup_name",
+	Short: backupShowShortDescription,
+	Long:  backupShowLongDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		internal.FatalOnError(err)
+		postgres.HandleBackupShow(folder, args[0], os.Stdout, backupShowJSON)
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupShowCmd)
+	backupShowCmd.Flags().BoolVar(&backupShowJSON, "json", false, backupShowJSONDescription)
+}
@@ -0,0 +1,32 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	backupTouchShortDescription = "Refreshes the modification time of the objects of a backup"
+	backupTouchLongDescription  = `Rewrites the objects of the backup, its delta chain and its WAL segments,
+each sentinel last, so the age-based lifecycle rules of the bucket start counting from now. Every object
+is downloaded and uploaded to a temporary folder and back, which takes four times the size of the objects
+in traffic. The touched backup is sorted as the latest one by the modification time in the backup listings,
+LATEST and the retention.`
+)
+
+var backupTouchCmd = &cobra.Command{
+	Use:   "backup-touch backup_name",
+	Short: backupTouchShortDescription,
+	Long:  backupTouchLongDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		internal.FatalOnError(err)
+		postgres.HandleBackupTouch(folder, args[0])
+	},
+}
+
+func init() {
+	cmd.AddCommand(backupTouchCmd)
+}
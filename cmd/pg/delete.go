@@ -8,6 +8,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -16,8 +17,10 @@ import (
 
 const UseSentinelTimeFlag = "use-sentinel-time"
 const UseSentinelTimeDescription = "Use backup creation time from sentinel for backups ordering."
+const DryRunDescription = "Only print the objects to delete, overrides --confirm"
 
 var confirmed = false
+var dryRun = false
 var useSentinelTime = false
 var deleteTargetUserData = ""
 
@@ -49,6 +52,13 @@ var deleteEverythingCmd = &cobra.Command{
 	Run:       runDeleteEverything,
 }
 
+var deleteGFSCmd = &cobra.Command{
+	Use:     internal.DeleteGFSUsageExample,
+	Example: internal.DeleteGFSExamples,
+	Args:    internal.DeleteGFSArgsValidator,
+	Run:     runDeleteGFS,
+}
+
 var deleteTargetCmd = &cobra.Command{
 	Use:     internal.DeleteTargetUsageExample, // TODO : improve description
 	Example: internal.DeleteTargetExamples,
@@ -58,7 +68,7 @@ var deleteTargetCmd = &cobra.Command{
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 	if len(permanentBackups) > 0 {
@@ -67,14 +77,14 @@ func runDeleteBefore(cmd *cobra.Command, args []string) {
 	}
 
 	deleteHandler, err := newPostgresDeleteHandler(folder, permanentBackups, permanentWals)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
-	deleteHandler.HandleDeleteBefore(args, confirmed)
+	deleteHandler.HandleDeleteBefore(args, isDeletionConfirmed())
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 	if len(permanentBackups) > 0 {
@@ -83,26 +93,26 @@ func runDeleteRetain(cmd *cobra.Command, args []string) {
 	}
 
 	deleteHandler, err := newPostgresDeleteHandler(folder, permanentBackups, permanentWals)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
-	deleteHandler.HandleDeleteRetain(args, confirmed)
+	deleteHandler.HandleDeleteRetain(args, isDeletionConfirmed())
 }
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
 	deleteHandler, err := newPostgresDeleteHandler(folder, permanentBackups, permanentWals)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
-	deleteHandler.HandleDeleteEverything(args, permanentBackups, confirmed)
+	deleteHandler.HandleDeleteEverything(args, permanentBackups, isDeletionConfirmed())
 }
 
 func runDeleteTarget(cmd *cobra.Command, args []string) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 	if len(permanentBackups) > 0 {
@@ -119,10 +129,36 @@ func runDeleteTarget(cmd *cobra.Command, args []string) {
 	}
 
 	deleteHandler, err := newPostgresDeleteHandler(folder, permanentBackups, permanentWals)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	targetBackupSelector, err := createTargetDeleteBackupSelector(cmd, args, deleteTargetUserData)
-	tracelog.ErrorLogger.FatalOnError(err)
-	deleteHandler.HandleDeleteTarget(targetBackupSelector, confirmed, findFullBackup)
+	internal.FatalOnError(err)
+	deleteHandler.HandleDeleteTarget(targetBackupSelector, isDeletionConfirmed(), findFullBackup)
+}
+
+func runDeleteGFS(cmd *cobra.Command, args []string) {
+	folder, err := internal.ConfigureFolder()
+	internal.FatalOnError(err)
+
+	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
+	if len(permanentBackups) > 0 {
+		tracelog.InfoLogger.Printf("Found permanent objects: backups=%v, wals=%v\n",
+			permanentBackups, permanentWals)
+	}
+
+	deleteHandler, err := newPostgresDeleteHandler(folder, permanentBackups, permanentWals)
+	internal.FatalOnError(err)
+	retentionClasses, err := postgres.GetBackupRetentionClasses(folder)
+	internal.FatalOnError(err)
+
+	deleteHandler.HandleDeleteGFS(args, retentionClasses, isDeletionConfirmed())
+}
+
+// isDeletionConfirmed reports whether the objects should be actually deleted
+func isDeletionConfirmed() bool {
+	if dryRun && confirmed {
+		tracelog.WarningLogger.Printf("--%s is set, --%s is ignored\n", internal.DryRunFlag, internal.ConfirmFlag)
+	}
+	return confirmed && !dryRun
 }
 
 func init() {
@@ -131,8 +167,9 @@ func init() {
 	deleteTargetCmd.Flags().StringVar(
 		&deleteTargetUserData, internal.DeleteTargetUserDataFlag, "", internal.DeleteTargetUserDataDescription)
 
-	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd)
+	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd, deleteGFSCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().BoolVar(&dryRun, internal.DryRunFlag, false, DryRunDescription)
 	deleteCmd.PersistentFlags().BoolVar(&useSentinelTime, UseSentinelTimeFlag, false, UseSentinelTimeDescription)
 }
 
@@ -157,6 +194,11 @@ func newPostgresDeleteHandler(folder storage.Folder, permanentBackups, permanent
 			lessFunc = makeLessFunc(startTimeByBackupName)
 		}
 	}
+	if margin := viper.GetUint64(internal.WalRetentionMarginSetting); margin > 0 {
+		tracelog.InfoLogger.Printf("Keeping %d extra WAL segments before the oldest retained backup\n", margin)
+		lessFunc = postgres.WithWalRetentionMargin(lessFunc, margin)
+	}
+	lessFunc = postgres.WithoutChunkStore(lessFunc)
 	postgresBackups, err := makePostgresBackupObjects(folder, backups, startTimeByBackupName)
 	if err != nil {
 		return nil, err
@@ -168,6 +210,9 @@ func newPostgresDeleteHandler(folder storage.Folder, permanentBackups, permanent
 		lessFunc,
 		internal.IsPermanentFunc(
 			makePostgresPermanentFunc(permanentBackups, permanentWals)),
+		internal.AfterDeleteFunc(func(confirmed bool) error {
+			return postgres.DeleteUnreferencedChunks(folder, confirmed)
+		}),
 	)
 
 	return deleteHandler, nil
@@ -2,18 +2,24 @@ package pg
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/tracing"
+	"github.com/wal-g/wal-g/utility"
 )
 
-const WalgShortDescription = "PostgreSQL backup tool"
+const (
+	WalgShortDescription = "PostgreSQL backup tool"
+
+	walSegmentSizeFlag        = "wal-segment-size"
+	walSegmentSizeDescription = "WAL segment size to use instead of the one recorded in the backup sentinel " +
+		"or WALG_PG_WAL_SIZE, e.g. 16MB or 1GB"
+)
 
 var (
 	// These variables are here only to show current version. They are set in makefile during build process
@@ -21,16 +27,30 @@ var (
 	gitRevision = "devel"
 	buildDate   = "devel"
 
+	walSegmentSize string
+
 	cmd = &cobra.Command{
 		Use:     "wal-g",
 		Short:   WalgShortDescription, // TODO : improve short and long descriptions
 		Version: strings.Join([]string{walgVersion, gitRevision, buildDate, "PostgreSQL"}, "\t"),
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			internal.SetErrorOperation(cmd.Name())
 			err := internal.AssertRequiredSettingsSet()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			if viper.IsSet(internal.PgWalSize) {
 				postgres.SetWalSize(viper.GetUint64(internal.PgWalSize))
 			}
+			if walSegmentSize != "" {
+				err = postgres.OverrideWalSegmentSize(walSegmentSize)
+				internal.FatalOnError(err)
+			}
+			utility.SetObjectKeyNormalization(viper.GetBool(internal.NormalizeKeysSetting))
+			err = internal.ConfigureTracing()
+			internal.FatalOnError(err)
+			tracing.StartCommand(cmd.Name())
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			tracing.FinishCommand()
 		},
 	}
 )
@@ -40,7 +60,7 @@ var (
 func Execute() {
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		internal.Exit(1)
 	}
 }
 
@@ -50,6 +70,10 @@ func init() {
 
 	cmd.PersistentFlags().StringVar(&internal.CfgFile, "config", "", "config file (default is $HOME/.walg.json)")
 	cmd.PersistentFlags().BoolVarP(&internal.Turbo, "turbo", "", false, "Ignore all kinds of throttling defined in config")
+	cmd.PersistentFlags().BoolVar(&internal.Verbose, "verbose", false, "Print debug messages, overrides WALG_LOG_LEVEL")
+	cmd.PersistentFlags().BoolVarP(&internal.Quiet, "quiet", "q", false, "Print only error messages")
+	cmd.PersistentFlags().StringVar(&walSegmentSize, walSegmentSizeFlag, "", walSegmentSizeDescription)
 	cmd.InitDefaultVersionFlag()
+	cmd.PersistentFlags().BoolVar(&internal.ReadOnly, "read-only", false, "Reject any writes and deletions in the storage")
 	internal.AddConfigFlags(cmd)
 }
@@ -0,0 +1,115 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const (
+	stShortDescription    = "Low-level storage tools"
+	stCatShortDescription = "Writes the decrypted and decompressed storage object to stdout or to the file"
+	stCatLongDescription  = "Downloads the object at the path relative to the storage prefix, " +
+		"e.g. basebackups_005/base_000000010000000000000002/tar_partitions/part_1.tar.lz4. " +
+		"The object is decrypted and decompressed according to its extension."
+	stLsShortDescription  = "Lists the objects and the folders at the storage prefix"
+	stGetShortDescription = "Downloads the decrypted and decompressed storage object to the file"
+	stGetLongDescription  = "Downloads the object at the path relative to the storage prefix to the file, " +
+		"named after the object without the compression extension by default. " +
+		"The object is decrypted and decompressed according to its extension."
+	stPutShortDescription = "Uploads the file to the storage object"
+	stPutLongDescription  = "Uploads the file to the object at the path relative to the storage prefix. " +
+		"The file is compressed and encrypted according to the object extension, as the backups are."
+	stRmShortDescription = "Deletes the storage object"
+
+	stCatOutputFlag        = "output"
+	stCatOutputShorthand   = "o"
+	stCatOutputDescription = "Write the object to the file instead of stdout"
+
+	stLsRecursiveFlag        = "recursive"
+	stLsRecursiveShorthand   = "r"
+	stLsRecursiveDescription = "List the objects of the subfolders too"
+)
+
+var (
+	stCatOutput   string
+	stLsRecursive bool
+
+	stCmd = &cobra.Command{
+		Use:   "st",
+		Short: stShortDescription,
+	}
+
+	stCatCmd = &cobra.Command{
+		Use:   "cat object_path",
+		Short: stCatShortDescription,
+		Long:  stCatLongDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			internal.HandleStorageObjectCat(folder, args[0], stCatOutput)
+		},
+	}
+
+	stLsCmd = &cobra.Command{
+		Use:   "ls [prefix]",
+		Short: stLsShortDescription,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			prefix := ""
+			if len(args) > 0 {
+				prefix = args[0]
+			}
+			internal.HandleStorageList(folder, prefix, stLsRecursive)
+		},
+	}
+
+	stGetCmd = &cobra.Command{
+		Use:   "get object_path [file_path]",
+		Short: stGetShortDescription,
+		Long:  stGetLongDescription,
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			outputPath := ""
+			if len(args) > 1 {
+				outputPath = args[1]
+			}
+			internal.HandleStorageObjectGet(folder, args[0], outputPath)
+		},
+	}
+
+	stPutCmd = &cobra.Command{
+		Use:   "put object_path file_path",
+		Short: stPutShortDescription,
+		Long:  stPutLongDescription,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			internal.HandleStorageObjectPut(folder, args[0], args[1])
+		},
+	}
+
+	stRmCmd = &cobra.Command{
+		Use:   "rm object_path",
+		Short: stRmShortDescription,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			internal.HandleStorageObjectDelete(folder, args[0])
+		},
+	}
+)
+
+func init() {
+	cmd.AddCommand(stCmd)
+	stCmd.AddCommand(stCatCmd, stLsCmd, stGetCmd, stPutCmd, stRmCmd)
+
+	stCatCmd.Flags().StringVarP(&stCatOutput, stCatOutputFlag, stCatOutputShorthand, "", stCatOutputDescription)
+	stLsCmd.Flags().BoolVarP(&stLsRecursive, stLsRecursiveFlag, stLsRecursiveShorthand, false, stLsRecursiveDescription)
+}
@@ -0,0 +1,43 @@
+package pg
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	tierShortDescription = "Moves old backups and WAL to the cold storage tier"
+	tierLongDescription  = "Moves objects older than the threshold from the storage to the cold storage " +
+		"configured by " + internal.ColdStorageConfigSetting
+
+	olderThanFlag        = "older-than"
+	olderThanDescription = "Move objects last modified earlier than this duration ago, at least 1h"
+)
+
+var (
+	olderThan     time.Duration
+	tierConfirmed = false
+
+	tierCmd = &cobra.Command{
+		Use:   "tier",
+		Short: tierShortDescription,
+		Long:  tierLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			folder, err := internal.ConfigureFolder()
+			internal.FatalOnError(err)
+			postgres.HandleTier(folder, olderThan, tierConfirmed)
+		},
+	}
+)
+
+func init() {
+	cmd.AddCommand(tierCmd)
+
+	tierCmd.Flags().DurationVar(&olderThan, olderThanFlag, 0, olderThanDescription)
+	tierCmd.Flags().BoolVar(&tierConfirmed, internal.ConfirmFlag, false, "Confirms moving objects")
+	_ = tierCmd.MarkFlagRequired(olderThanFlag)
+}
@@ -0,0 +1,30 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	trainDictShortDescription = "Trains a zstd dictionary on the files of the data directory"
+	trainDictLongDescription  = `Samples the files of the data directory and trains a zstd dictionary on them.
+Point WALG_ZSTD_DICT_PATH to the written file to compress with the dictionary.`
+	trainDictSizeDescription = "Maximum size of the dictionary in bytes"
+)
+
+var trainDictSize int
+
+var trainDictCmd = &cobra.Command{
+	Use:   "train-dict data_directory output_file",
+	Short: trainDictShortDescription,
+	Long:  trainDictLongDescription,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		postgres.HandleTrainZstdDictionary(args[0], args[1], trainDictSize)
+	},
+}
+
+func init() {
+	cmd.AddCommand(trainDictCmd)
+	trainDictCmd.Flags().IntVar(&trainDictSize, "size", postgres.DefaultZstdDictionarySize, trainDictSizeDescription)
+}
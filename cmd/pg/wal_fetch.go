@@ -2,25 +2,35 @@ package pg
 
 import (
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 )
 
-const WalFetchShortDescription = "Fetches a WAL file from storage"
+const (
+	WalFetchShortDescription = "Fetches a WAL file from storage"
+	forceWalFetchDescription = "Write the WAL file to stdout even if it is a terminal"
+	stdoutWalFetchLocation   = "-"
+)
+
+var forceWalFetch bool
 
 // walFetchCmd represents the walFetch command
 var walFetchCmd = &cobra.Command{
-	Use:   "wal-fetch wal_name destination_filename",
+	Use:   "wal-fetch wal_name destination_filename | -",
 	Short: WalFetchShortDescription, // TODO : improve description
 	Args:  cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		folder, err := internal.ConfigureFolder()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
+		if args[1] == stdoutWalFetchLocation {
+			postgres.HandleWALFetchToStdout(folder, args[0], forceWalFetch)
+			return
+		}
 		postgres.HandleWALFetch(folder, args[0], args[1], true)
 	},
 }
 
 func init() {
+	walFetchCmd.Flags().BoolVar(&forceWalFetch, "force", false, forceWalFetchDescription)
 	cmd.AddCommand(walFetchCmd)
 }
@@ -0,0 +1,40 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	walMetadataListShortDescription = "Prints the archival time of WAL segments recorded by WALG_UPLOAD_WAL_METADATA"
+	walMetadataListFromDescription  = "The first WAL segment name to list"
+	walMetadataListToDescription    = "The last WAL segment name to list"
+	walMetadataListJSONDescription  = "Output the list in JSON format"
+)
+
+var (
+	walMetadataListFrom string
+	walMetadataListTo   string
+	walMetadataListJSON bool
+)
+
+var walMetadataListCmd = &cobra.Command{
+	Use:   "wal-metadata-list",
+	Short: walMetadataListShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := internal.ConfigureFolder()
+		internal.FatalOnError(err)
+		postgres.HandleWalMetadataList(folder, walMetadataListFrom, walMetadataListTo, os.Stdout, walMetadataListJSON)
+	},
+}
+
+func init() {
+	cmd.AddCommand(walMetadataListCmd)
+	walMetadataListCmd.Flags().StringVar(&walMetadataListFrom, "from", "", walMetadataListFromDescription)
+	walMetadataListCmd.Flags().StringVar(&walMetadataListTo, "to", "", walMetadataListToDescription)
+	walMetadataListCmd.Flags().BoolVar(&walMetadataListJSON, "json", false, walMetadataListJSONDescription)
+}
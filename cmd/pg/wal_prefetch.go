@@ -2,7 +2,7 @@ package pg
 
 import (
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 )
 
@@ -17,7 +17,7 @@ var walPrefetchCmd = &cobra.Command{
 	Hidden: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		uploader, err := postgres.ConfigureWalUploaderWithoutCompressMethod()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		postgres.HandleWALPrefetch(uploader, args[0], args[1])
 	},
 }
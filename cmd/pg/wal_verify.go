@@ -9,7 +9,6 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -21,6 +20,11 @@ const (
 	useJSONOutputFlag        = "json"
 	useJSONOutputDescription = "Show output in JSON format."
 
+	checkContentFlag        = "check-content"
+	checkContentDescription = "Confirm the existence of each found WAL segment with a separate storage request " +
+		"and verify the segments against the checksums from the WAL metadata " +
+		"(concurrency is limited by WALG_DOWNLOAD_CONCURRENCY)."
+
 	checkIntegrityArg = "integrity"
 	checkTimelineArg  = "timeline"
 )
@@ -38,7 +42,7 @@ var (
 		Args:  checkArgs,
 		Run: func(cmd *cobra.Command, checks []string) {
 			folder, err := internal.ConfigureFolder()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			outputType := postgres.WalVerifyTableOutput
 			if useJSONOutput {
 				outputType = postgres.WalVerifyJSONOutput
@@ -46,10 +50,11 @@ var (
 			outputWriter := postgres.NewWalVerifyOutputWriter(outputType, os.Stdout)
 			checkTypes := parseChecks(checks)
 
-			postgres.HandleWalVerify(checkTypes, folder, postgres.QueryCurrentWalSegment(), outputWriter)
+			postgres.HandleWalVerify(checkTypes, folder, postgres.QueryCurrentWalSegment(), outputWriter, checkContent)
 		},
 	}
 	useJSONOutput bool
+	checkContent  bool
 )
 
 func parseChecks(checks []string) []postgres.WalVerifyCheckType {
@@ -63,7 +68,7 @@ func parseChecks(checks []string) []postgres.WalVerifyCheckType {
 	for check := range uniqueChecks {
 		checkType, ok := availableChecks[check]
 		if !ok {
-			tracelog.ErrorLogger.Fatalf("Check %s is not available.", check)
+			internal.Fatalf("Check %s is not available.", check)
 		}
 		checkTypes = append(checkTypes, checkType)
 	}
@@ -90,4 +95,5 @@ func checkArgs(cmd *cobra.Command, args []string) error {
 func init() {
 	cmd.AddCommand(walVerifyCmd)
 	walVerifyCmd.Flags().BoolVar(&useJSONOutput, useJSONOutputFlag, false, useJSONOutputDescription)
+	walVerifyCmd.Flags().BoolVar(&checkContent, checkContentFlag, false, checkContentDescription)
 }
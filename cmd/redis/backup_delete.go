@@ -7,7 +7,6 @@ import (
 	"github.com/wal-g/wal-g/utility"
 
 	"github.com/spf13/cobra"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -39,19 +38,19 @@ func runDelete(cmd *cobra.Command, args []string) {
 
 	if cmd.Flags().Changed(retainAfterFlag) {
 		retainAfterTime, err := time.Parse(time.RFC3339, retainAfter)
-		tracelog.ErrorLogger.FatalfOnError("Can not parse retain time: %v", err)
+		internal.FatalfOnError("Can not parse retain time: %v", err)
 		opts = append(opts, redis.PurgeRetainAfter(retainAfterTime))
 	}
 
 	if cmd.Flags().Changed(retainCountFlag) {
 		if retainCount == 0 {
-			tracelog.ErrorLogger.Fatalln("Retain count can not be 0")
+			internal.Fatal("Retain count can not be 0")
 		}
 		opts = append(opts, redis.PurgeRetainCount(int(retainCount)))
 	}
 
 	err := redis.HandlePurge(utility.BaseBackupPath, opts...)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 func init() {
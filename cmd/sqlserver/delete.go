@@ -3,7 +3,6 @@ package sqlserver
 import (
 	"github.com/spf13/cobra"
 	"github.com/wal-g/storages/storage"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -41,21 +40,21 @@ var deleteEverythingCmd = &cobra.Command{
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	deleteHandler, err := newSQLServerDeleteHandler()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.DeleteEverything(confirmed)
 }
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
 	deleteHandler, err := newSQLServerDeleteHandler()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
 	deleteHandler, err := newSQLServerDeleteHandler()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
@@ -68,7 +67,7 @@ func init() {
 
 func newSQLServerDeleteHandler() (*internal.DeleteHandler, error) {
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	backups, err := internal.GetBackupSentinelObjects(folder)
 	if err != nil {
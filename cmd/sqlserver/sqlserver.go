@@ -2,7 +2,6 @@ package sqlserver
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -27,7 +26,7 @@ var cmd = &cobra.Command{
 func Execute() {
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		internal.Exit(1)
 	}
 }
 
@@ -35,5 +34,6 @@ func init() {
 	internal.ConfigureSettings(internal.SQLSERVER)
 	cobra.OnInitialize(internal.InitConfig, internal.Configure)
 	cmd.PersistentFlags().StringVar(&internal.CfgFile, "config", "", "config file (default is $HOME/.walg.json)")
+	cmd.PersistentFlags().BoolVar(&internal.ReadOnly, "read-only", false, "Reject any writes and deletions in the storage")
 	cmd.InitDefaultVersionFlag()
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/tracing"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -161,8 +162,10 @@ func GetBackupByName(backupName, subfolder string, folder storage.Folder) (Backu
 }
 
 // TODO : unit tests
-func UploadSentinel(uploader UploaderProvider, sentinelDto interface{}, backupName string) error {
+func UploadSentinel(uploader UploaderProvider, sentinelDto interface{}, backupName string) (err error) {
 	sentinelName := SentinelNameFromBackup(backupName)
+	span := tracing.Start("upload_sentinel", tracing.PathAttribute.String(sentinelName))
+	defer func() { tracing.End(span, err) }()
 
 	dtoBody, err := json.Marshal(sentinelDto)
 	if err != nil {
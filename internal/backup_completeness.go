@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+// backupCompletenessDto is the part of the sentinel which tells whether all the backup objects are uploaded
+type backupCompletenessDto struct {
+	// Finished is set by the backup push after all the uploads succeeded
+	Finished    bool                `json:"Finished"`
+	TarFileSets map[string][]string `json:"TarFileSets"`
+}
+
+// isBackupComplete checks whether the backup is marked finished in the sentinel. The sentinels
+// written before the mark was introduced are cross-checked against the tar partitions they list,
+// the ones listing no partitions are considered complete.
+func isBackupComplete(folder storage.Folder, backupName string) (bool, error) {
+	backup := NewBackup(folder, backupName)
+	var completeness backupCompletenessDto
+	err := backup.FetchSentinel(&completeness)
+	if err != nil {
+		return false, err
+	}
+	if completeness.Finished || len(completeness.TarFileSets) == 0 {
+		return true, nil
+	}
+
+	objects, _, err := folder.GetSubFolder(backupName + TarPartitionFolderName).ListFolder()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list the tar partitions of %s", backupName)
+	}
+	uploaded := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		uploaded[object.GetName()] = true
+	}
+	for tarName := range completeness.TarFileSets {
+		if !uploaded[tarName] {
+			tracelog.DebugLogger.Printf("Tar partition %s of %s is not uploaded\n", tarName, backupName)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FilterCompleteBackups leaves the backups which uploads are complete, so a backup
+// which sentinel is listed during a concurrent push is not selected
+func FilterCompleteBackups(folder storage.Folder, backups []BackupTime) []BackupTime {
+	completeBackups := make([]BackupTime, 0, len(backups))
+	for _, backup := range backups {
+		complete, err := isBackupComplete(folder, backup.BackupName)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Skipping backup %s: failed to check its completeness: %v\n",
+				backup.BackupName, err)
+			continue
+		}
+		if !complete {
+			tracelog.InfoLogger.Printf("Skipping incomplete backup %s\n", backup.BackupName)
+			continue
+		}
+		completeBackups = append(completeBackups, backup)
+	}
+	return completeBackups
+}
+
+// GetCompleteBackups is GetBackups for the backup listing and the LATEST selection:
+// with WALG_LIST_COMPLETE_ONLY the backups still being uploaded are left out
+func GetCompleteBackups(folder storage.Folder) ([]BackupTime, error) {
+	backups, err := GetBackups(folder)
+	if err != nil || !viper.GetBool(ListCompleteOnlySetting) {
+		return backups, err
+	}
+	backups = FilterCompleteBackups(folder, backups)
+	if len(backups) == 0 {
+		return nil, NewNoBackupsFoundError()
+	}
+	return backups, nil
+}
@@ -0,0 +1,49 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestGetCompleteBackups_ListCompleteOnly(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewStorage())
+	putObject := func(path, content string) {
+		require.NoError(t, folder.PutObject(path, strings.NewReader(content)))
+	}
+	putObject("base_1"+utility.SentinelSuffix, `{"Finished":true,"TarFileSets":{"part_1.tar.lz4":[]}}`)
+	putObject("base_2"+utility.SentinelSuffix, `{"TarFileSets":{"part_1.tar.lz4":[],"part_2.tar.lz4":[]}}`)
+	putObject("base_2/tar_partitions/part_1.tar.lz4", "")
+	putObject("base_2/tar_partitions/part_2.tar.lz4", "")
+	putObject("base_3"+utility.SentinelSuffix, `{"TarFileSets":{"part_1.tar.lz4":[],"part_2.tar.lz4":[]}}`)
+	putObject("base_3/tar_partitions/part_1.tar.lz4", "")
+	putObject("stream_4"+utility.SentinelSuffix, `{}`)
+
+	getBackupNames := func() []string {
+		backups, err := internal.GetCompleteBackups(folder)
+		require.NoError(t, err)
+		names := make([]string, 0, len(backups))
+		for _, backup := range backups {
+			names = append(names, backup.BackupName)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"base_1", "base_2", "base_3", "stream_4"}, getBackupNames())
+
+	viper.Set(internal.ListCompleteOnlySetting, true)
+	defer viper.Set(internal.ListCompleteOnlySetting, false)
+	assert.ElementsMatch(t, []string{"base_1", "base_2", "stream_4"}, getBackupNames())
+	// the other commands, e.g. delete, still see all the backups
+	backups, err := internal.GetBackups(folder)
+	require.NoError(t, err)
+	assert.Len(t, backups, 4)
+	latest, err := internal.GetLatestBackupName(folder)
+	require.NoError(t, err)
+	assert.NotEqual(t, "base_3", latest)
+}
@@ -27,11 +27,11 @@ func (err BackupNonExistenceError) Error() string {
 func GetCommandStreamFetcher(cmd *exec.Cmd) func(folder storage.Folder, backup Backup) {
 	return func(folder storage.Folder, backup Backup) {
 		stdin, err := cmd.StdinPipe()
-		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		FatalfOnError("Failed to fetch backup: %v\n", err)
 		stderr := &bytes.Buffer{}
 		cmd.Stderr = stderr
 		err = cmd.Start()
-		tracelog.ErrorLogger.FatalfOnError("Failed to start restore command: %v\n", err)
+		FatalfOnError("Failed to start restore command: %v\n", err)
 		err = downloadAndDecompressStream(backup, stdin)
 		cmdErr := cmd.Wait()
 		if err != nil || cmdErr != nil {
@@ -40,7 +40,7 @@ func GetCommandStreamFetcher(cmd *exec.Cmd) func(folder storage.Folder, backup B
 		if cmdErr != nil {
 			err = cmdErr
 		}
-		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		FatalfOnError("Failed to fetch backup: %v\n", err)
 	}
 }
 
@@ -75,10 +75,10 @@ func HandleBackupFetch(folder storage.Folder,
 	targetBackupSelector BackupSelector,
 	fetcher func(folder storage.Folder, backup Backup)) {
 	backupName, err := targetBackupSelector.Select(folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 	tracelog.DebugLogger.Printf("HandleBackupFetch(%s, folder,)\n", backupName)
 	backup, err := GetBackupByName(backupName, utility.BaseBackupPath, folder)
-	tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+	FatalfOnError("Failed to fetch backup: %v\n", err)
 
 	fetcher(folder, backup)
 }
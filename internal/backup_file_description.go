@@ -13,10 +13,15 @@ type BackupFileDescription struct {
 	MTime         time.Time
 	CorruptBlocks *CorruptBlocksInfo `json:",omitempty"`
 	UpdatesCount  uint64
+	// SHA256 and Size of the file packed in full, recorded if WALG_BACKUP_FILE_CHECKSUMS is set
+	SHA256 string `json:",omitempty"`
+	Size   int64  `json:",omitempty"`
+	// ChangedDuringBackup is "shrunk" or "grown" if the file size changed while it was packed
+	ChangedDuringBackup string `json:",omitempty"`
 }
 
 func NewBackupFileDescription(isIncremented, isSkipped bool, modTime time.Time) *BackupFileDescription {
-	return &BackupFileDescription{isIncremented, isSkipped, modTime, nil, 0}
+	return &BackupFileDescription{IsIncremented: isIncremented, IsSkipped: isSkipped, MTime: modTime}
 }
 
 type CorruptBlocksInfo struct {
@@ -1,13 +1,16 @@
 package internal
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"text/tabwriter"
+	"time"
 
 	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 )
@@ -20,21 +23,83 @@ type ErrorLogger interface {
 	FatalOnError(err error)
 }
 
+type BackupTooOldError struct {
+	error
+}
+
+func newBackupTooOldError(backupName string, age, maxAge time.Duration) BackupTooOldError {
+	return BackupTooOldError{errors.Errorf("Newest backup %s is %v old, which exceeds the maximum age of %v",
+		backupName, age.Round(time.Second), maxAge)}
+}
+
+func (err BackupTooOldError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+const (
+	// BackupListFormatTable is the aligned table, the default output of backup-list
+	BackupListFormatTable = "table"
+	BackupListFormatCSV   = "csv"
+	BackupListFormatJSON  = "json"
+)
+
+type UnknownBackupListFormatError struct {
+	error
+}
+
+func newUnknownBackupListFormatError(format string) UnknownBackupListFormatError {
+	return UnknownBackupListFormatError{errors.Errorf("unknown backup list format '%s', expected %s, %s or %s",
+		format, BackupListFormatTable, BackupListFormatCSV, BackupListFormatJSON)}
+}
+
+func (err UnknownBackupListFormatError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetBackupListFormat returns the output format of backup-list. The explicitly set format
+// takes precedence over the json flag, the table is printed if neither is set.
+func GetBackupListFormat(format string, json bool) (string, error) {
+	switch format {
+	case "":
+		if json {
+			return BackupListFormatJSON, nil
+		}
+		return BackupListFormatTable, nil
+	case BackupListFormatTable, BackupListFormatCSV, BackupListFormatJSON:
+		return format, nil
+	default:
+		return "", newUnknownBackupListFormatError(format)
+	}
+}
+
 type Logging struct {
 	InfoLogger  InfoLogger
 	ErrorLogger ErrorLogger
 }
 
 func DefaultHandleBackupList(folder storage.Folder, pretty, json bool) {
+	format := BackupListFormatTable
+	if json {
+		format = BackupListFormatJSON
+	}
+	DefaultHandleBackupListWithFormat(folder, format, pretty)
+}
+
+// DefaultHandleBackupListWithFormat prints the backups in one of the BackupListFormat* formats,
+// pretty makes the table and the json more readable
+func DefaultHandleBackupListWithFormat(folder storage.Folder, format string, pretty bool) {
 	getBackupsFunc := func() ([]BackupTime, error) {
-		return GetBackups(folder)
+		return GetCompleteBackups(folder)
 	}
 	writeBackupListFunc := func(backups []BackupTime) {
 		SortBackupTimeSlices(backups)
 		switch {
-		case json:
+		case format == BackupListFormatJSON:
 			err := WriteAsJSON(backups, os.Stdout, pretty)
-			tracelog.ErrorLogger.FatalOnError(err)
+			FatalOnError(err)
+		case format == BackupListFormatCSV:
+			err := WriteBackupListCSV(backups, os.Stdout)
+			FatalOnError(err)
 		case pretty:
 			WritePrettyBackupList(backups, os.Stdout)
 		default:
@@ -64,12 +129,48 @@ func HandleBackupList(
 	writeBackupListFunc(backups)
 }
 
+// CheckNewestBackupAge returns an error if the newest of the backups is older than maxAge
+func CheckNewestBackupAge(backups []BackupTime, maxAge time.Duration, now time.Time) error {
+	if len(backups) == 0 {
+		return NewNoBackupsFoundError()
+	}
+	newest := backups[0]
+	for _, backup := range backups[1:] {
+		if backup.Time.After(newest.Time) {
+			newest = backup
+		}
+	}
+	if age := now.Sub(newest.Time); age > maxAge {
+		return newBackupTooOldError(newest.BackupName, age, maxAge)
+	}
+	return nil
+}
+
+// HandleBackupMaxAgeCheck exits with non-zero code if there is
+// no backup in the folder newer than maxAge
+func HandleBackupMaxAgeCheck(folder storage.Folder, maxAge time.Duration) {
+	backups, err := GetBackups(folder)
+	FatalOnError(err)
+	FatalOnError(CheckNewestBackupAge(backups, maxAge, time.Now()))
+}
+
+// WriteBackupList writes the backups as the aligned table,
+// the tier column is written only for the TieredFolder backups
 func WriteBackupList(backups []BackupTime, output io.Writer) {
 	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
 	defer writer.Flush()
-	fmt.Fprintln(writer, "name\tmodified\twal_segment_backup_start")
+	tiered := HasTieredBackups(backups)
+	if tiered {
+		fmt.Fprintln(writer, "name\tmodified\twal_segment_backup_start\ttier")
+	} else {
+		fmt.Fprintln(writer, "name\tmodified\twal_segment_backup_start")
+	}
 	for _, b := range backups {
-		fmt.Fprintf(writer, "%v\t%v\t%v\n", b.BackupName, FormatTime(b.Time), b.WalFileName)
+		fmt.Fprintf(writer, "%v\t%v\t%v", b.BackupName, FormatTime(b.Time), b.WalFileName)
+		if tiered {
+			fmt.Fprintf(writer, "\t%v", b.Tier)
+		}
+		fmt.Fprintln(writer)
 	}
 }
 
@@ -77,10 +178,39 @@ func WritePrettyBackupList(backups []BackupTime, output io.Writer) {
 	writer := table.NewWriter()
 	writer.SetOutputMirror(output)
 	defer writer.Render()
-	writer.AppendHeader(table.Row{"#", "Name", "Modified", "WAL segment backup start"})
+	tiered := HasTieredBackups(backups)
+	header := table.Row{"#", "Name", "Modified", "WAL segment backup start"}
+	if tiered {
+		header = append(header, "Tier")
+	}
+	writer.AppendHeader(header)
 	for i, b := range backups {
-		writer.AppendRow(table.Row{i, b.BackupName, PrettyFormatTime(b.Time), b.WalFileName})
+		row := table.Row{i, b.BackupName, PrettyFormatTime(b.Time), b.WalFileName}
+		if tiered {
+			row = append(row, b.Tier)
+		}
+		writer.AppendRow(row)
+	}
+}
+
+// WriteBackupListCSV writes the backups as CSV with the columns of WriteBackupList,
+// the fields containing commas, quotes or line breaks are quoted
+func WriteBackupListCSV(backups []BackupTime, output io.Writer) error {
+	tiered := HasTieredBackups(backups)
+	header := []string{"name", "modified", "wal_segment_backup_start"}
+	if tiered {
+		header = append(header, "tier")
+	}
+	records := make([][]string, 0, len(backups)+1)
+	records = append(records, header)
+	for _, b := range backups {
+		record := []string{b.BackupName, FormatTime(b.Time), b.WalFileName}
+		if tiered {
+			record = append(record, b.Tier)
+		}
+		records = append(records, record)
 	}
+	return csv.NewWriter(output).WriteAll(records)
 }
 
 func WriteAsJSON(data interface{}, output io.Writer, pretty bool) error {
@@ -236,3 +236,71 @@ func TestWriteBackupList_LongColumnsValues(t *testing.T) {
 
 	assert.Equal(t, expectedRes, b.String())
 }
+
+func TestWriteBackupListCSV(t *testing.T) {
+	backups := []internal.BackupTime{
+		shortBackups[0],
+		{
+			BackupName:  "b1,with comma",
+			Time:        time.Time{},
+			WalFileName: "shortWallName1",
+		},
+	}
+	expectedRes := "name,modified,wal_segment_backup_start\n" +
+		"b0,-,shortWallName0\n" +
+		"\"b1,with comma\",-,shortWallName1\n"
+	b := bytes.Buffer{}
+	err := internal.WriteBackupListCSV(backups, &b)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, b.String())
+}
+
+func TestWriteBackupListCSV_Tiered(t *testing.T) {
+	backups := []internal.BackupTime{
+		{BackupName: "b0", WalFileName: "shortWallName0", Tier: internal.ColdTierName},
+		{BackupName: "b1", WalFileName: "shortWallName1", Tier: internal.HotTierName},
+	}
+	expectedRes := "name,modified,wal_segment_backup_start,tier\n" +
+		"b0,-,shortWallName0,cold\n" +
+		"b1,-,shortWallName1,hot\n"
+	b := bytes.Buffer{}
+	err := internal.WriteBackupListCSV(backups, &b)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, b.String())
+}
+
+func TestGetBackupListFormat(t *testing.T) {
+	format, err := internal.GetBackupListFormat("", false)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.BackupListFormatTable, format)
+
+	format, err = internal.GetBackupListFormat("", true)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.BackupListFormatJSON, format)
+
+	format, err = internal.GetBackupListFormat(internal.BackupListFormatCSV, true)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.BackupListFormatCSV, format)
+
+	_, err = internal.GetBackupListFormat("xml", false)
+	assert.IsType(t, internal.UnknownBackupListFormatError{}, err)
+}
+
+func TestCheckNewestBackupAge(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	backups := []internal.BackupTime{
+		{BackupName: "b1", Time: now.Add(-time.Hour)},
+		{BackupName: "b0", Time: now.Add(-48 * time.Hour)},
+	}
+
+	assert.NoError(t, internal.CheckNewestBackupAge(backups, 2*time.Hour, now))
+
+	err := internal.CheckNewestBackupAge(backups, 30*time.Minute, now)
+	assert.IsType(t, internal.BackupTooOldError{}, err)
+	assert.Contains(t, err.Error(), "b1")
+
+	err = internal.CheckNewestBackupAge(nil, time.Hour, now)
+	assert.IsType(t, internal.NoBackupsFoundError{}, err)
+}
@@ -8,4 +8,16 @@ type BackupTime struct {
 	BackupName  string    `json:"backup_name"`
 	Time        time.Time `json:"time"`
 	WalFileName string    `json:"wal_file_name"`
+	// Tier is the storage tier of the backup sentinel, it is set only for the TieredFolder backups
+	Tier string `json:"tier,omitempty"`
+}
+
+// HasTieredBackups reports whether the backups were listed from the TieredFolder
+func HasTieredBackups(backups []BackupTime) bool {
+	for _, backup := range backups {
+		if backup.Tier != "" {
+			return true
+		}
+	}
+	return false
 }
@@ -41,7 +41,7 @@ func (err NoBackupsFoundError) Error() string {
 
 // TODO : unit tests
 func GetLatestBackupName(folder storage.Folder) (string, error) {
-	backupTimes, err := GetBackups(folder)
+	backupTimes, err := GetCompleteBackups(folder)
 	SortBackupTimeSlices(backupTimes)
 	if err != nil {
 		return "", err
@@ -57,7 +57,7 @@ func GetBackupSentinelObjects(folder storage.Folder) ([]storage.Object, error) {
 	}
 	sentinelObjects := make([]storage.Object, 0, len(objects))
 	for _, object := range objects {
-		if !strings.HasSuffix(object.GetName(), utility.SentinelSuffix) {
+		if !strings.HasSuffix(object.GetName(), utility.GetSentinelSuffix()) {
 			continue
 		}
 		sentinelObjects = append(sentinelObjects, object)
@@ -67,7 +67,7 @@ func GetBackupSentinelObjects(folder storage.Folder) ([]storage.Object, error) {
 }
 
 // TODO : unit tests
-// GetBackups receives backup descriptions and sorts them by time
+// GetBackups receives backup descriptions and sorts them by time.
 func GetBackups(folder storage.Folder) (backups []BackupTime, err error) {
 	backups, _, err = GetBackupsAndGarbage(folder)
 	if err != nil {
@@ -98,12 +98,16 @@ func GetBackupTimeSlices(backups []storage.Object) []BackupTime {
 	backupTimes := make([]BackupTime, 0)
 	for _, object := range backups {
 		key := object.GetName()
-		if !strings.HasSuffix(key, utility.SentinelSuffix) {
+		if !strings.HasSuffix(key, utility.GetSentinelSuffix()) {
 			continue
 		}
 		time := object.GetLastModified()
-		backupTimes = append(backupTimes, BackupTime{utility.StripRightmostBackupName(key), time,
-			utility.StripWalFileName(key)})
+		backupTimes = append(backupTimes, BackupTime{
+			BackupName:  utility.StripRightmostBackupName(key),
+			Time:        time,
+			WalFileName: utility.StripWalFileName(key),
+			Tier:        GetObjectTier(object),
+		})
 	}
 	return backupTimes
 }
@@ -131,7 +135,7 @@ func GetGarbageFromPrefix(folders []storage.Folder, nonGarbage []BackupTime) []s
 }
 
 func SentinelNameFromBackup(backupName string) string {
-	return backupName + utility.SentinelSuffix
+	return backupName + utility.GetSentinelSuffix()
 }
 
 // UnwrapLatestModifier checks if LATEST is provided instead of backupName
@@ -0,0 +1,398 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// ChunkStoreFolderName is the content-addressed chunk store folder
+	// located inside the base backups folder. Chunks are shared between backups.
+	ChunkStoreFolderName = "chunks/"
+	// ChunkManifestExtension is the extension of the tar partition
+	// that lists the chunks instead of containing the tar data itself.
+	ChunkManifestExtension = "chunks"
+	DedupChunkSize         = 4 << 20
+	// ChunkNamingKeyName is the object in the chunk store holding the key the names of the encrypted chunks
+	// are computed with. It is encrypted with the crypter of the backups.
+	ChunkNamingKeyName = "naming_key"
+	// ChunkSweepGracePeriod is how long the unreferenced chunks are kept after the upload,
+	// they may belong to the partitions of a running backup-push whose manifests are not uploaded yet
+	ChunkSweepGracePeriod = 24 * time.Hour
+
+	chunkNamingKeySize = 32
+)
+
+// ChunkManifest describes the ordered list of chunks
+// which compose a single tar partition.
+type ChunkManifest struct {
+	ChunkSize int      `json:"ChunkSize"`
+	Chunks    []string `json:"Chunks"`
+}
+
+// ChunkedStorageTarBall is a StorageTarBall that splits the tar stream
+// into fixed-size chunks. Each chunk is stored only once in the chunk store
+// and the tar partition itself is replaced by the chunk manifest.
+type ChunkedStorageTarBall struct {
+	*StorageTarBall
+	chunkFolder storage.Folder
+	namer       *chunkNamer
+}
+
+// SetUp creates a new tar writer that writes to the chunk store.
+// If a name for the file is not given, default name is of
+// the form `part_....tar.chunks`.
+func (tarBall *ChunkedStorageTarBall) SetUp(crypter crypto.Crypter, names ...string) {
+	if tarBall.tarWriter == nil {
+		if len(names) > 0 && isTarPartitionKeyName(names[0]) {
+			// the chunks are skipped if already stored anyway
+			tarBall.name = names[0] + "." + ChunkManifestExtension
+		} else if len(names) > 0 {
+			tarBall.name = utility.TrimFileExtension(names[0]) + "." + ChunkManifestExtension
+		} else {
+			tarBall.name = fmt.Sprintf("part_%0.3d.tar.%v", tarBall.partNumber, ChunkManifestExtension)
+		}
+		tracelog.InfoLogger.Printf("Starting chunked part %d ...\n", tarBall.partNumber)
+
+		writeCloser := newChunkWriter(tarBall.backupName+TarPartitionFolderName+tarBall.name,
+			tarBall.uploader, tarBall.chunkFolder, tarBall.namer, crypter)
+		tarBall.writeCloser = writeCloser
+		tarBall.tarWriter = tar.NewWriter(writeCloser)
+	}
+}
+
+// ChunkedStorageTarBallMaker creates tarballs that are uploaded to the chunk store.
+type ChunkedStorageTarBallMaker struct {
+	partCount   int
+	backupName  string
+	uploader    *Uploader
+	chunkFolder storage.Folder
+	namer       *chunkNamer
+}
+
+func NewChunkedStorageTarBallMaker(backupName string, uploader *Uploader) *ChunkedStorageTarBallMaker {
+	chunkFolder := uploader.UploadingFolder.GetSubFolder(ChunkStoreFolderName)
+	return &ChunkedStorageTarBallMaker{0, backupName, uploader, chunkFolder, &chunkNamer{chunkFolder: chunkFolder}}
+}
+
+// Make returns a chunked tarball with required storage fields.
+func (tarBallMaker *ChunkedStorageTarBallMaker) Make(dedicatedUploader bool) TarBall {
+	tarBallMaker.partCount++
+	uploader := tarBallMaker.uploader
+	if dedicatedUploader {
+		uploader = uploader.Clone()
+	}
+	size := int64(0)
+	return &ChunkedStorageTarBall{
+		StorageTarBall: &StorageTarBall{
+			partNumber: tarBallMaker.partCount,
+			backupName: tarBallMaker.backupName,
+			uploader:   uploader,
+			partSize:   &size,
+		},
+		chunkFolder: tarBallMaker.chunkFolder,
+		namer:       tarBallMaker.namer,
+	}
+}
+
+// chunkNamer names the chunks by their content. The encrypted chunks are named by the HMAC-SHA256
+// keyed with the chunk naming key, so their names don't reveal the hashes of the content.
+// The unencrypted chunks are named by SHA-256.
+type chunkNamer struct {
+	chunkFolder storage.Folder
+	once        sync.Once
+	key         []byte
+	err         error
+}
+
+func (namer *chunkNamer) name(chunk []byte, crypter crypto.Crypter) (string, error) {
+	if crypter == nil {
+		hash := sha256.Sum256(chunk)
+		return hex.EncodeToString(hash[:]), nil
+	}
+	namer.once.Do(func() {
+		namer.key, namer.err = loadChunkNamingKey(namer.chunkFolder, crypter)
+	})
+	if namer.err != nil {
+		return "", namer.err
+	}
+	mac := hmac.New(sha256.New, namer.key)
+	_, _ = mac.Write(chunk)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// loadChunkNamingKey reads the chunk naming key from the chunk store, the key is generated on the first use.
+// It is read back after the upload, so the backup-pushes generating it at once use the same stored key.
+func loadChunkNamingKey(chunkFolder storage.Folder, crypter crypto.Crypter) ([]byte, error) {
+	exists, err := chunkFolder.Exists(ChunkNamingKeyName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check existence of the chunk naming key")
+	}
+	if !exists {
+		key := make([]byte, chunkNamingKeySize)
+		if _, err = rand.Read(key); err != nil {
+			return nil, errors.Wrap(err, "failed to generate the chunk naming key")
+		}
+		var encryptedKey bytes.Buffer
+		encryptingWriter, err := crypter.Encrypt(&encryptedKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encrypt the chunk naming key")
+		}
+		if _, err = encryptingWriter.Write(key); err != nil {
+			return nil, errors.Wrap(err, "failed to encrypt the chunk naming key")
+		}
+		if err = encryptingWriter.Close(); err != nil {
+			return nil, errors.Wrap(err, "failed to encrypt the chunk naming key")
+		}
+		if err = chunkFolder.PutObject(ChunkNamingKeyName, &encryptedKey); err != nil {
+			return nil, errors.Wrap(err, "failed to upload the chunk naming key")
+		}
+	}
+
+	encryptedKeyReader, err := chunkFolder.ReadObject(ChunkNamingKeyName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the chunk naming key")
+	}
+	defer utility.LoggedClose(encryptedKeyReader, "")
+	keyReader, err := crypter.Decrypt(encryptedKeyReader)
+	if err != nil {
+		// e.g. the backups are pushed with the public PGP key only
+		return nil, errors.Wrap(err, "failed to decrypt the chunk naming key, "+
+			"the dedup chunking of the encrypted backups needs the crypter to decrypt as well")
+	}
+	key, err := ioutil.ReadAll(io.LimitReader(keyReader, chunkNamingKeySize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt the chunk naming key")
+	}
+	if len(key) != chunkNamingKeySize {
+		return nil, errors.Errorf("the chunk naming key is %d bytes instead of %d", len(key), chunkNamingKeySize)
+	}
+	return key, nil
+}
+
+// chunkWriter buffers the tar stream, uploads every complete chunk
+// which is not yet present in the chunk store and writes the manifest on Close.
+type chunkWriter struct {
+	manifestPath string
+	uploader     *Uploader
+	chunkFolder  storage.Folder
+	namer        *chunkNamer
+	crypter      crypto.Crypter
+	buffer       bytes.Buffer
+	manifest     ChunkManifest
+	reusedChunks int64
+}
+
+func newChunkWriter(manifestPath string, uploader *Uploader,
+	chunkFolder storage.Folder, namer *chunkNamer, crypter crypto.Crypter) *chunkWriter {
+	return &chunkWriter{
+		manifestPath: manifestPath,
+		uploader:     uploader,
+		chunkFolder:  chunkFolder,
+		namer:        namer,
+		crypter:      crypter,
+		manifest:     ChunkManifest{ChunkSize: DedupChunkSize, Chunks: make([]string, 0)},
+	}
+}
+
+func (writer *chunkWriter) Write(p []byte) (int, error) {
+	n, _ := writer.buffer.Write(p)
+	for writer.buffer.Len() >= DedupChunkSize {
+		err := writer.flushChunk(writer.buffer.Next(DedupChunkSize))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (writer *chunkWriter) flushChunk(chunk []byte) error {
+	chunkName, err := writer.namer.name(chunk, writer.crypter)
+	if err != nil {
+		writer.uploader.Failed.Store(true)
+		return err
+	}
+	chunkName += "." + writer.uploader.Compressor.FileExtension()
+	writer.manifest.Chunks = append(writer.manifest.Chunks, chunkName)
+
+	exists, err := writer.chunkFolder.Exists(chunkName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check existence of chunk '%s'", chunkName)
+	}
+	if exists {
+		atomic.AddInt64(&writer.reusedChunks, 1)
+		tracelog.DebugLogger.Printf("Chunk %s is already stored, skipping upload", chunkName)
+		return nil
+	}
+
+	content := CompressAndEncrypt(bytes.NewReader(chunk), writer.uploader.Compressor, writer.crypter)
+	if writer.uploader.tarSize != nil {
+		content = NewWithSizeReader(content, writer.uploader.tarSize)
+	}
+	err = writer.chunkFolder.PutObject(chunkName, content)
+	if err != nil {
+		writer.uploader.Failed.Store(true)
+		return errors.Wrapf(err, "failed to upload chunk '%s'", chunkName)
+	}
+	return nil
+}
+
+// Close uploads the remaining data and the chunk manifest.
+func (writer *chunkWriter) Close() error {
+	if writer.buffer.Len() > 0 {
+		err := writer.flushChunk(writer.buffer.Bytes())
+		if err != nil {
+			return err
+		}
+		writer.buffer.Reset()
+	}
+
+	manifestBody, err := json.Marshal(writer.manifest)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal chunk manifest '%s'", writer.manifestPath)
+	}
+	tracelog.DebugLogger.Printf("Chunk manifest %s: %d chunks, %d reused",
+		writer.manifestPath, len(writer.manifest.Chunks), atomic.LoadInt64(&writer.reusedChunks))
+	return writer.uploader.Upload(writer.manifestPath, bytes.NewReader(manifestBody))
+}
+
+// ChunkedReaderMaker reads the chunk manifest and reassembles
+// the tar partition from the chunk store. The resulting stream
+// is already decrypted and decompressed: Reader decrypts the chunks
+// with the configured crypter, DecryptingReader with the given one.
+type ChunkedReaderMaker struct {
+	ManifestReaderMaker ReaderMaker
+	ChunkFolder         storage.Folder
+}
+
+func NewChunkedReaderMaker(manifestReaderMaker ReaderMaker, chunkFolder storage.Folder) *ChunkedReaderMaker {
+	return &ChunkedReaderMaker{manifestReaderMaker, chunkFolder}
+}
+
+// NewTarPartitionReaderMaker returns the reader maker suitable for
+// the tar partition stored in the partition folder of the specified backups folder.
+func NewTarPartitionReaderMaker(baseBackupFolder, tarPartitionFolder storage.Folder, tarName string) ReaderMaker {
+	readerMaker := NewStorageReaderMaker(tarPartitionFolder, tarName)
+	if IsChunkManifest(tarName) {
+		return NewChunkedReaderMaker(readerMaker, baseBackupFolder.GetSubFolder(ChunkStoreFolderName))
+	}
+	return readerMaker
+}
+
+func IsChunkManifest(tarName string) bool {
+	return strings.HasSuffix(tarName, "."+ChunkManifestExtension)
+}
+
+func (readerMaker *ChunkedReaderMaker) Path() string { return readerMaker.ManifestReaderMaker.Path() }
+
+func (readerMaker *ChunkedReaderMaker) Reader() (io.ReadCloser, error) {
+	return readerMaker.DecryptingReader(ConfigureCrypter())
+}
+
+func (readerMaker *ChunkedReaderMaker) DecryptingReader(crypter crypto.Crypter) (io.ReadCloser, error) {
+	manifest, err := readChunkManifest(readerMaker.ManifestReaderMaker)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		for _, chunkName := range manifest.Chunks {
+			err := readerMaker.readChunk(chunkName, &EmptyWriteIgnorer{writer}, crypter)
+			if err != nil {
+				_ = writer.CloseWithError(err)
+				return
+			}
+		}
+		_ = writer.Close()
+	}()
+	return reader, nil
+}
+
+func (readerMaker *ChunkedReaderMaker) readChunk(chunkName string, writer io.Writer, crypter crypto.Crypter) error {
+	decompressor := compression.FindDecompressor(utility.GetFileExtension(chunkName))
+	if decompressor == nil {
+		return newUnsupportedFileTypeError(chunkName, utility.GetFileExtension(chunkName))
+	}
+	chunkReader, err := readerMaker.ChunkFolder.ReadObject(chunkName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read chunk '%s'", chunkName)
+	}
+	defer utility.LoggedClose(chunkReader, "")
+	return decryptAndDecompress(writer, chunkReader, crypter, decompressor, chunkName)
+}
+
+func readChunkManifest(manifestReaderMaker ReaderMaker) (ChunkManifest, error) {
+	var manifest ChunkManifest
+	manifestReader, err := manifestReaderMaker.Reader()
+	if err != nil {
+		return manifest, err
+	}
+	defer utility.LoggedClose(manifestReader, "")
+	manifestBody, err := ioutil.ReadAll(manifestReader)
+	if err != nil {
+		return manifest, errors.Wrapf(err, "failed to read chunk manifest '%s'", manifestReaderMaker.Path())
+	}
+	err = json.Unmarshal(manifestBody, &manifest)
+	if err != nil {
+		return manifest, newUnmarshallingError(manifestReaderMaker.Path(), err)
+	}
+	return manifest, nil
+}
+
+// DeleteUnreferencedChunks deletes the chunks of the chunk store in the base backups folder which none
+// of the chunk manifests of the backups references. The chunks uploaded during the last gracePeriod are kept.
+func DeleteUnreferencedChunks(baseBackupFolder storage.Folder, gracePeriod time.Duration, confirmed bool) error {
+	objects, err := storage.ListFolderRecursively(baseBackupFolder)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the base backups folder")
+	}
+	referencedChunks := make(map[string]bool)
+	hasChunks := false
+	for _, object := range objects {
+		if strings.HasPrefix(object.GetName(), ChunkStoreFolderName) {
+			hasChunks = true
+			continue
+		}
+		if !IsChunkManifest(object.GetName()) {
+			continue
+		}
+		manifest, err := readChunkManifest(NewStorageReaderMaker(baseBackupFolder, object.GetName()))
+		if err != nil {
+			return err
+		}
+		for _, chunkName := range manifest.Chunks {
+			referencedChunks[chunkName] = true
+		}
+	}
+	if !hasChunks {
+		return nil
+	}
+
+	tracelog.InfoLogger.Println("Deleting the unreferenced chunks")
+	uploadedBefore := utility.TimeNowCrossPlatformUTC().Add(-gracePeriod)
+	return storage.DeleteObjectsWhere(baseBackupFolder.GetSubFolder(ChunkStoreFolderName), confirmed,
+		func(object storage.Object) bool {
+			return object.GetName() != ChunkNamingKeyName && !referencedChunks[object.GetName()] &&
+				object.GetLastModified().Before(uploadedBefore)
+		})
+}
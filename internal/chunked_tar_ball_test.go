@@ -0,0 +1,165 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/crypto/openpgp"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func packChunkedTarBall(t *testing.T, tarBallMaker internal.TarBallMaker, content []byte,
+	crypter crypto.Crypter) internal.TarBall {
+	tarBall := tarBallMaker.Make(false)
+	tarBall.SetUp(crypter)
+	_, err := internal.PackFileTo(tarBall, &tar.Header{
+		Name:     "file",
+		Mode:     int64(0600),
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	}, bytes.NewReader(content))
+	require.NoError(t, err)
+	require.NoError(t, tarBall.CloseTar())
+	return tarBall
+}
+
+func TestChunkedTarBall_RoundTrip(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	content := make([]byte, internal.DedupChunkSize*2+123)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	first := packChunkedTarBall(t, internal.NewChunkedStorageTarBallMaker("first", uploader), content, nil)
+	assert.Equal(t, "part_001.tar.chunks", first.Name())
+	chunks, _, err := folder.GetSubFolder(internal.ChunkStoreFolderName).ListFolder()
+	require.NoError(t, err)
+	storedChunks := len(chunks)
+
+	// the same content in another backup must reuse the stored chunks
+	packChunkedTarBall(t, internal.NewChunkedStorageTarBallMaker("second", uploader), content, nil)
+	chunks, _, err = folder.GetSubFolder(internal.ChunkStoreFolderName).ListFolder()
+	require.NoError(t, err)
+	assert.Equal(t, storedChunks, len(chunks))
+
+	readerMaker := internal.NewTarPartitionReaderMaker(folder,
+		folder.GetSubFolder("second"+internal.TarPartitionFolderName), first.Name())
+	reassembled := &bytes.Buffer{}
+	err = internal.DecryptAndDecompressTar(reassembled, readerMaker, nil)
+	require.NoError(t, err)
+
+	tarReader := tar.NewReader(reassembled)
+	header, err := tarReader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "file", header.Name)
+	actual, err := ioutil.ReadAll(tarReader)
+	require.NoError(t, err)
+	assert.Equal(t, content, actual)
+	_, err = tarReader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func makeChunkContent(seed int) []byte {
+	content := make([]byte, internal.DedupChunkSize+123)
+	for i := range content {
+		content[i] = byte((i + seed) % 251)
+	}
+	return content
+}
+
+func TestChunkedTarBall_EncryptedChunks(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	crypter := openpgp.CrypterFromKeyPath("./crypto/openpgp/testdata/pgpTestPrivateKey",
+		func() (string, bool) { return "", false })
+	content := makeChunkContent(0)
+
+	tarBall := packChunkedTarBall(t, internal.NewChunkedStorageTarBallMaker("first", uploader), content, crypter)
+	chunkFolder := folder.GetSubFolder(internal.ChunkStoreFolderName)
+	chunks, _, err := chunkFolder.ListFolder()
+	require.NoError(t, err)
+	storedChunks := len(chunks)
+	exists, err := chunkFolder.Exists(internal.ChunkNamingKeyName)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// the names of the encrypted chunks are not the hashes of the content
+	plainFolder := testtools.MakeDefaultInMemoryStorageFolder()
+	plainUploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], plainFolder)
+	packChunkedTarBall(t, internal.NewChunkedStorageTarBallMaker("first", plainUploader), content, nil)
+	plainChunks, _, err := plainFolder.GetSubFolder(internal.ChunkStoreFolderName).ListFolder()
+	require.NoError(t, err)
+	require.Len(t, plainChunks, storedChunks-1)
+	for _, chunk := range plainChunks {
+		assert.NotContains(t, objectNames(chunks), chunk.GetName())
+	}
+
+	// the naming key is read back from the storage, so the chunks are reused by another backup-push
+	otherUploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	packChunkedTarBall(t, internal.NewChunkedStorageTarBallMaker("second", otherUploader), content, crypter)
+	chunks, _, err = chunkFolder.ListFolder()
+	require.NoError(t, err)
+	assert.Equal(t, storedChunks, len(chunks))
+
+	readerMaker := internal.NewTarPartitionReaderMaker(folder,
+		folder.GetSubFolder("second"+internal.TarPartitionFolderName), tarBall.Name())
+	reassembled := &bytes.Buffer{}
+	require.NoError(t, internal.DecryptAndDecompressTar(reassembled, readerMaker, crypter))
+	tarReader := tar.NewReader(reassembled)
+	_, err = tarReader.Next()
+	require.NoError(t, err)
+	actual, err := ioutil.ReadAll(tarReader)
+	require.NoError(t, err)
+	assert.Equal(t, content, actual)
+}
+
+func TestDeleteUnreferencedChunks(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	packChunkedTarBall(t, internal.NewChunkedStorageTarBallMaker("first", uploader), makeChunkContent(0), nil)
+	chunkFolder := folder.GetSubFolder(internal.ChunkStoreFolderName)
+	firstChunks, _, err := chunkFolder.ListFolder()
+	require.NoError(t, err)
+	packChunkedTarBall(t, internal.NewChunkedStorageTarBallMaker("second", uploader), makeChunkContent(1), nil)
+	allChunks, _, err := chunkFolder.ListFolder()
+	require.NoError(t, err)
+	require.Greater(t, len(allChunks), len(firstChunks))
+
+	require.NoError(t, folder.DeleteObjects([]string{"second" + internal.TarPartitionFolderName + "part_001.tar.chunks"}))
+
+	// the recently uploaded chunks are kept
+	require.NoError(t, internal.DeleteUnreferencedChunks(folder, internal.ChunkSweepGracePeriod, true))
+	chunks, _, err := chunkFolder.ListFolder()
+	require.NoError(t, err)
+	assert.Len(t, chunks, len(allChunks))
+
+	// dry run
+	require.NoError(t, internal.DeleteUnreferencedChunks(folder, 0, false))
+	chunks, _, err = chunkFolder.ListFolder()
+	require.NoError(t, err)
+	assert.Len(t, chunks, len(allChunks))
+
+	require.NoError(t, internal.DeleteUnreferencedChunks(folder, 0, true))
+	chunks, _, err = chunkFolder.ListFolder()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, objectNames(firstChunks), objectNames(chunks))
+}
+
+func objectNames(objects []storage.Object) []string {
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.GetName())
+	}
+	return names
+}
@@ -0,0 +1,118 @@
+package compression
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	// the compressor limits the pipeline if it is busy for the most of the stream time
+	adaptiveDownshiftBusyShare = 0.8
+	// the upload limits the pipeline if the compressor waits for it for the most of the stream time
+	adaptiveUpshiftWaitShare = 0.5
+	// streams smaller than that are not representative
+	adaptiveMinSampleSize = 1 << 20
+)
+
+// LeveledCompressor is a Compressor that supports different compression levels
+type LeveledCompressor interface {
+	Compressor
+	NewWriterLevel(writer io.Writer, level int) io.WriteCloser
+	DefaultLevel() int
+	MinLevel() int
+}
+
+// AdaptiveCompressor starts compressing at the default level of the wrapped compressor
+// and lowers the level for the next streams while the compression is CPU-bound,
+// i.e. it can't keep the upload pipe full. When the upload becomes
+// the bottleneck again, the level is raised back up to the default. The level of a stream
+// doesn't change while it is compressed. Only zstd and brotli are LeveledCompressors.
+type AdaptiveCompressor struct {
+	LeveledCompressor
+	level int32
+}
+
+func NewAdaptiveCompressor(compressor LeveledCompressor) *AdaptiveCompressor {
+	return &AdaptiveCompressor{compressor, int32(compressor.DefaultLevel())}
+}
+
+func (compressor *AdaptiveCompressor) Level() int {
+	return int(atomic.LoadInt32(&compressor.level))
+}
+
+func (compressor *AdaptiveCompressor) NewWriter(writer io.Writer) io.WriteCloser {
+	sink := &waitMeasuringWriter{Writer: writer}
+	return &adaptiveWriter{
+		compressor: compressor,
+		sink:       sink,
+		writer:     compressor.NewWriterLevel(sink, compressor.Level()),
+	}
+}
+
+// adjustLevel is called with the timings of the finished stream
+func (compressor *AdaptiveCompressor) adjustLevel(compressing, waiting, total time.Duration) {
+	if total <= 0 {
+		return
+	}
+	level := compressor.Level()
+	newLevel := level
+	switch {
+	case float64(compressing-waiting)/float64(total) > adaptiveDownshiftBusyShare:
+		newLevel = level - 1
+	case float64(waiting)/float64(total) > adaptiveUpshiftWaitShare:
+		newLevel = level + 1
+	}
+	if newLevel < compressor.MinLevel() || newLevel > compressor.DefaultLevel() {
+		return
+	}
+	if newLevel != level && atomic.CompareAndSwapInt32(&compressor.level, int32(level), int32(newLevel)) {
+		tracelog.InfoLogger.Printf("Adaptive compression: switching %s level from %d to %d\n",
+			compressor.FileExtension(), level, newLevel)
+	}
+}
+
+// waitMeasuringWriter measures the time spent waiting for the downstream writer
+type waitMeasuringWriter struct {
+	io.Writer
+	waiting time.Duration
+}
+
+func (writer *waitMeasuringWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := writer.Writer.Write(p)
+	writer.waiting += time.Since(start)
+	return n, err
+}
+
+type adaptiveWriter struct {
+	compressor  *AdaptiveCompressor
+	sink        *waitMeasuringWriter
+	writer      io.WriteCloser
+	started     time.Time
+	compressing time.Duration
+	written     int64
+}
+
+func (writer *adaptiveWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	if writer.started.IsZero() {
+		writer.started = start
+	}
+	n, err := writer.writer.Write(p)
+	writer.compressing += time.Since(start)
+	writer.written += int64(n)
+	return n, err
+}
+
+func (writer *adaptiveWriter) Close() error {
+	start := time.Now()
+	err := writer.writer.Close()
+	writer.compressing += time.Since(start)
+	if err == nil && writer.written >= adaptiveMinSampleSize {
+		writer.compressor.adjustLevel(writer.compressing, writer.sink.waiting, time.Since(writer.started))
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+)
+
+func TestAdaptiveCompressor_RoundTrip(t *testing.T) {
+	var testData bytes.Buffer
+	_, _ = io.Copy(&testData, io.LimitReader(NewBiasedRandomReader(), 2*adaptiveMinSampleSize))
+	testCompressor(NewAdaptiveCompressor(zstd.Compressor{}), testData, t)
+}
+
+func TestAdaptiveCompressor_AdjustLevel(t *testing.T) {
+	compressor := NewAdaptiveCompressor(zstd.Compressor{})
+	assert.Equal(t, zstd.DefaultLevel, compressor.Level())
+
+	// the upload is the bottleneck, but the level never exceeds the default one
+	compressor.adjustLevel(10*time.Second, 9*time.Second, 10*time.Second)
+	assert.Equal(t, zstd.DefaultLevel, compressor.Level())
+
+	// compression is CPU-bound
+	for i := 0; i < zstd.DefaultLevel+2; i++ {
+		compressor.adjustLevel(10*time.Second, time.Second, 10*time.Second)
+	}
+	assert.Equal(t, zstd.MinLevel, compressor.Level())
+
+	// neither side clearly limits the pipeline
+	compressor.adjustLevel(5*time.Second, 2*time.Second, 10*time.Second)
+	assert.Equal(t, zstd.MinLevel, compressor.Level())
+
+	compressor.adjustLevel(10*time.Second, 9*time.Second, 10*time.Second)
+	assert.Equal(t, zstd.MinLevel+1, compressor.Level())
+}
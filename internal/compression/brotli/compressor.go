@@ -11,12 +11,26 @@ import (
 const (
 	AlgorithmName = "brotli"
 	FileExtension = "br"
+	DefaultLevel  = 3
+	MinLevel      = 0
 )
 
 type Compressor struct{}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
-	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: 3})
+	return compressor.NewWriterLevel(writer, DefaultLevel)
+}
+
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: level})
+}
+
+func (compressor Compressor) DefaultLevel() int {
+	return DefaultLevel
+}
+
+func (compressor Compressor) MinLevel() int {
+	return MinLevel
 }
 
 func (compressor Compressor) FileExtension() string {
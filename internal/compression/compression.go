@@ -26,3 +26,16 @@ func FindDecompressor(fileExtension string) Decompressor {
 	}
 	return nil
 }
+
+// magics are the leading bytes of the compressed streams by the file extension,
+// the lzma and brotli streams have none
+var magics = map[string][]byte{
+	"lz4": {0x04, 0x22, 0x4d, 0x18},
+	"zst": {0x28, 0xb5, 0x2f, 0xfd},
+	"lzo": {0x89, 'L', 'Z', 'O', 0x00, 0x0d, 0x0a, 0x1a, 0x0a},
+}
+
+// Magic returns the leading bytes of the streams compressed with the file extension, nil if they have none
+func Magic(fileExtension string) []byte {
+	return magics[fileExtension]
+}
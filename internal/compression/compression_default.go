@@ -20,3 +20,55 @@ var Decompressors = []Decompressor{
 	lzma.Decompressor{},
 	zstd.Decompressor{},
 }
+
+// PostgresCompressingAlgorithms returns the compression methods of PostgreSQL: CompressingAlgorithms and zstd,
+// the compression with zstd is supported for PostgreSQL only. The zstd files are decompressed for all the databases.
+func PostgresCompressingAlgorithms() []string {
+	return append(append([]string{}, CompressingAlgorithms...), zstd.AlgorithmName)
+}
+
+// PostgresCompressors returns the compressors of PostgresCompressingAlgorithms
+func PostgresCompressors() map[string]Compressor {
+	compressors := map[string]Compressor{zstd.AlgorithmName: zstd.Compressor{}}
+	for method, compressor := range Compressors {
+		compressors[method] = compressor
+	}
+	return compressors
+}
+
+// WithThreads returns the compressor compressing each stream with the specified number of threads,
+// if the compression method supports it
+func WithThreads(compressor Compressor, threads int) (Compressor, bool) {
+	zstdCompressor, ok := compressor.(zstd.Compressor)
+	if !ok {
+		return compressor, false
+	}
+	zstdCompressor.Threads = threads
+	return zstdCompressor, true
+}
+
+// WithDictionary returns the compressor compressing each stream with the trained dictionary,
+// if the compression method supports it
+func WithDictionary(compressor Compressor, dict []byte) (Compressor, bool) {
+	zstdCompressor, ok := compressor.(zstd.Compressor)
+	if !ok {
+		return compressor, false
+	}
+	zstdCompressor.Dict = dict
+	return zstdCompressor, true
+}
+
+// DictionaryID returns the ID of the trained zstd dictionary, 0 if dict is not one
+func DictionaryID(dict []byte) uint32 {
+	return zstd.DictionaryID(dict)
+}
+
+// SetDictionaryLoader sets the source of the dictionaries for the zstd streams compressed with one
+func SetDictionaryLoader(loader func(dictID uint32) ([]byte, error)) {
+	zstd.DictionaryLoader = loader
+}
+
+// TrainDictionary builds the zstd dictionary of at most dictSize bytes from the samples
+func TrainDictionary(samples [][]byte, dictSize int) ([]byte, error) {
+	return zstd.TrainDictionary(samples, dictSize)
+}
@@ -2,11 +2,14 @@ package compression
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -38,6 +41,17 @@ func testCompressor(compressor Compressor, testData bytes.Buffer, t *testing.T)
 	assert.Equal(t, initialData.Bytes(), decompressed.Bytes())
 }
 
+func TestPostgresCompressors(t *testing.T) {
+	compressors := PostgresCompressors()
+	assert.Equal(t, zstd.Compressor{}, compressors[zstd.AlgorithmName])
+	assert.Contains(t, PostgresCompressingAlgorithms(), zstd.AlgorithmName)
+	for _, method := range CompressingAlgorithms {
+		assert.Equal(t, Compressors[method], compressors[method])
+	}
+	assert.NotContains(t, Compressors, zstd.AlgorithmName)
+	assert.NotContains(t, CompressingAlgorithms, zstd.AlgorithmName)
+}
+
 func TestSmallDataCompression(t *testing.T) {
 	const SmallDataSize = 16 << 10
 	randomReader := io.LimitReader(NewBiasedRandomReader(), SmallDataSize)
@@ -59,3 +73,69 @@ func TestBigDataCompression(t *testing.T) {
 		testCompressor(compressor, testData, t)
 	}
 }
+
+func TestMultiThreadedZstdCompression(t *testing.T) {
+	compressor, supported := WithThreads(zstd.Compressor{}, 4)
+	assert.True(t, supported)
+	// empty stream, a single partial frame and several frames with the partial last one
+	for _, dataSize := range []int64{0, 16 << 10, 3*zstd.ParallelFrameSize + 1<<10} {
+		var testData bytes.Buffer
+		io.Copy(&testData, io.LimitReader(NewBiasedRandomReader(), dataSize))
+		testCompressor(compressor, testData, t)
+	}
+}
+
+func TestWithThreads_NotSupported(t *testing.T) {
+	compressor, supported := WithThreads(Compressors[lz4.AlgorithmName], 4)
+	assert.False(t, supported)
+	assert.Equal(t, Compressors[lz4.AlgorithmName], compressor)
+}
+
+func TestZstdDictionaryCompression(t *testing.T) {
+	samples := make([][]byte, 0)
+	for i := 0; i < 1000; i++ {
+		samples = append(samples, []byte(fmt.Sprintf("relation %d of schema public, owner postgres, tuples %d", i, i*7)))
+	}
+	dict, err := TrainDictionary(samples, 4<<10)
+	assert.NoError(t, err)
+	dictID := DictionaryID(dict)
+	assert.NotZero(t, dictID)
+
+	SetDictionaryLoader(func(id uint32) ([]byte, error) {
+		assert.Equal(t, dictID, id)
+		return dict, nil
+	})
+	defer SetDictionaryLoader(nil)
+	compressor, supported := WithDictionary(zstd.Compressor{}, dict)
+	assert.True(t, supported)
+	threadedCompressor, _ := WithThreads(compressor, 4)
+	for _, compressor := range []Compressor{compressor, threadedCompressor} {
+		var testData bytes.Buffer
+		testData.WriteString("relation 5000 of schema public, owner postgres, tuples 35000")
+		var compressed bytes.Buffer
+		writer := compressor.NewWriter(&compressed)
+		_, err = writer.Write(testData.Bytes())
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+		assert.Equal(t, dictID, zstd.FrameDictionaryID(compressed.Bytes()))
+		testCompressor(compressor, testData, t)
+	}
+}
+
+func TestMagic(t *testing.T) {
+	for _, compressingAlgorithm := range CompressingAlgorithms {
+		compressor := Compressors[compressingAlgorithm]
+		magic := Magic(compressor.FileExtension())
+		if magic == nil {
+			continue
+		}
+		var compressed bytes.Buffer
+		compressingWriter := compressor.NewWriter(&compressed)
+		_, err := compressingWriter.Write([]byte("magic"))
+		assert.NoError(t, err)
+		assert.NoError(t, compressingWriter.Close())
+		assert.True(t, bytes.HasPrefix(compressed.Bytes(), magic), compressingAlgorithm)
+	}
+	assert.NotNil(t, Magic(lz4.FileExtension))
+	assert.NotNil(t, Magic(zstd.FileExtension))
+}
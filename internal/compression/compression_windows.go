@@ -3,6 +3,7 @@
 package compression
 
 import (
+	"github.com/pkg/errors"
 	"github.com/wal-g/wal-g/internal/compression/lz4"
 	"github.com/wal-g/wal-g/internal/compression/lzma"
 )
@@ -18,3 +19,39 @@ var Decompressors = []Decompressor{
 	lz4.Decompressor{},
 	lzma.Decompressor{},
 }
+
+// PostgresCompressingAlgorithms returns CompressingAlgorithms, zstd is not available on Windows
+func PostgresCompressingAlgorithms() []string {
+	return CompressingAlgorithms
+}
+
+// PostgresCompressors returns Compressors, zstd is not available on Windows
+func PostgresCompressors() map[string]Compressor {
+	return Compressors
+}
+
+// WithThreads returns the compressor as is, none of the compression methods available on Windows
+// support multi-threaded compression of a stream
+func WithThreads(compressor Compressor, threads int) (Compressor, bool) {
+	return compressor, false
+}
+
+// WithDictionary returns the compressor as is, none of the compression methods available on Windows
+// support the dictionaries
+func WithDictionary(compressor Compressor, dict []byte) (Compressor, bool) {
+	return compressor, false
+}
+
+// DictionaryID returns 0, zstd is not available on Windows
+func DictionaryID(dict []byte) uint32 {
+	return 0
+}
+
+// SetDictionaryLoader does nothing, zstd is not available on Windows
+func SetDictionaryLoader(loader func(dictID uint32) ([]byte, error)) {
+}
+
+// TrainDictionary fails, zstd is not available on Windows
+func TrainDictionary(samples [][]byte, dictSize int) ([]byte, error) {
+	return nil, errors.New("zstd dictionaries are not supported on Windows")
+}
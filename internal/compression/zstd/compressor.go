@@ -9,12 +9,38 @@ import (
 const (
 	AlgorithmName = "zstd"
 	FileExtension = "zst"
+	DefaultLevel  = 3
+	MinLevel      = 1
 )
 
-type Compressor struct{}
+// Compressor compresses the stream with the single zstd context,
+// or with the ParallelWriter if more than one thread is set
+type Compressor struct {
+	Threads int
+	// Dict is the trained dictionary the stream is compressed with, its ID is recorded in the frames
+	Dict []byte
+}
 
 func (compressor Compressor) NewWriter(writer io.Writer) io.WriteCloser {
-	return zstd.NewWriterLevel(writer, 3)
+	return compressor.NewWriterLevel(writer, DefaultLevel)
+}
+
+func (compressor Compressor) NewWriterLevel(writer io.Writer, level int) io.WriteCloser {
+	if compressor.Threads > 1 {
+		return NewParallelWriter(writer, level, compressor.Threads, compressor.Dict)
+	}
+	if len(compressor.Dict) > 0 {
+		return zstd.NewWriterLevelDict(writer, level, compressor.Dict)
+	}
+	return zstd.NewWriterLevel(writer, level)
+}
+
+func (compressor Compressor) DefaultLevel() int {
+	return DefaultLevel
+}
+
+func (compressor Compressor) MinLevel() int {
+	return MinLevel
 }
 
 func (compressor Compressor) FileExtension() string {
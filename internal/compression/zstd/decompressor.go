@@ -1,6 +1,7 @@
 package zstd
 
 import (
+	"bufio"
 	"io"
 
 	"github.com/DataDog/zstd"
@@ -12,8 +13,14 @@ import (
 type Decompressor struct{}
 
 func (decompressor Decompressor) Decompress(dst io.Writer, src io.Reader) error {
-	zstdReader := zstd.NewReader(computils.NewUntilEOFReader(src))
-	_, err := utility.FastCopy(dst, zstdReader)
+	reader := bufio.NewReaderSize(computils.NewUntilEOFReader(src), maxFrameHeaderSize)
+	// the short stream is left to the zstd reader to report
+	frameHeader, _ := reader.Peek(maxFrameHeaderSize)
+	zstdReader, err := newReader(reader, FrameDictionaryID(frameHeader))
+	if err != nil {
+		return err
+	}
+	_, err = utility.FastCopy(dst, zstdReader)
 	if err != nil {
 		return errors.Wrap(err, "DecompressZstd: zstd write failed")
 	}
@@ -21,6 +28,21 @@ func (decompressor Decompressor) Decompress(dst io.Writer, src io.Reader) error
 	return errors.Wrap(err, "DecompressZstd: zstd reader close failed")
 }
 
+func newReader(src io.Reader, dictID uint32) (io.ReadCloser, error) {
+	if dictID == 0 {
+		return zstd.NewReader(src), nil
+	}
+	if DictionaryLoader == nil {
+		return nil, errors.Errorf("DecompressZstd: the stream is compressed with dictionary %d, "+
+			"but no dictionary is available", dictID)
+	}
+	dict, err := DictionaryLoader(dictID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DecompressZstd: failed to load dictionary %d", dictID)
+	}
+	return zstd.NewReaderDict(src, dict), nil
+}
+
 func (decompressor Decompressor) FileExtension() string {
 	return FileExtension
 }
@@ -0,0 +1,91 @@
+package zstd
+
+/*
+#include <stddef.h>
+
+// the dictionary builder is compiled in with github.com/DataDog/zstd, which does not expose it
+size_t ZDICT_trainFromBuffer(void* dictBuffer, size_t dictBufferCapacity,
+	const void* samplesBuffer, const size_t* samplesSizes, unsigned nbSamples);
+unsigned ZDICT_isError(size_t code);
+const char* ZDICT_getErrorName(size_t code);
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dictionaryMagic = 0xEC30A437
+	frameMagic      = 0xFD2FB528
+	// magic, frame header descriptor, window descriptor, dictionary ID and frame content size
+	maxFrameHeaderSize = 18
+)
+
+// DictionaryLoader provides the dictionary by its ID to decompress the streams compressed with it
+var DictionaryLoader func(dictID uint32) ([]byte, error)
+
+// DictionaryID returns the ID of the trained dictionary, 0 if dict is not one
+func DictionaryID(dict []byte) uint32 {
+	if len(dict) < 8 || binary.LittleEndian.Uint32(dict) != dictionaryMagic {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(dict[4:])
+}
+
+// FrameDictionaryID returns the ID of the dictionary the zstd frame at the start of data
+// is compressed with, 0 if it is compressed without one
+func FrameDictionaryID(data []byte) uint32 {
+	if len(data) < 5 || binary.LittleEndian.Uint32(data) != frameMagic {
+		return 0
+	}
+	descriptor := data[4]
+	offset := 5
+	if descriptor&0x20 == 0 {
+		// the window descriptor is present unless the frame is a single segment
+		offset++
+	}
+	switch descriptor & 0x3 {
+	case 1:
+		if len(data) >= offset+1 {
+			return uint32(data[offset])
+		}
+	case 2:
+		if len(data) >= offset+2 {
+			return uint32(binary.LittleEndian.Uint16(data[offset:]))
+		}
+	case 3:
+		if len(data) >= offset+4 {
+			return binary.LittleEndian.Uint32(data[offset:])
+		}
+	}
+	return 0
+}
+
+// TrainDictionary builds the dictionary of at most dictSize bytes from the samples
+// of the data it is going to compress
+func TrainDictionary(samples [][]byte, dictSize int) ([]byte, error) {
+	samplesBuffer := make([]byte, 0)
+	sampleSizes := make([]C.size_t, 0, len(samples))
+	for _, sample := range samples {
+		if len(sample) == 0 {
+			continue
+		}
+		samplesBuffer = append(samplesBuffer, sample...)
+		sampleSizes = append(sampleSizes, C.size_t(len(sample)))
+	}
+	if len(sampleSizes) == 0 {
+		return nil, errors.New("no samples to train the zstd dictionary on")
+	}
+
+	dict := make([]byte, dictSize)
+	size := C.ZDICT_trainFromBuffer(unsafe.Pointer(&dict[0]), C.size_t(dictSize),
+		unsafe.Pointer(&samplesBuffer[0]), &sampleSizes[0], C.unsigned(len(sampleSizes)))
+	if C.ZDICT_isError(size) != 0 {
+		return nil, errors.Errorf("failed to train the zstd dictionary: %s", C.GoString(C.ZDICT_getErrorName(size)))
+	}
+	return dict[:size], nil
+}
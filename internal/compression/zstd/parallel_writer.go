@@ -0,0 +1,128 @@
+package zstd
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/pkg/errors"
+)
+
+// ParallelFrameSize is the amount of input compressed into one independent frame by the ParallelWriter
+const ParallelFrameSize = 4 << 20
+
+type compressedFrame struct {
+	data []byte
+	err  error
+}
+
+// ParallelWriter splits the stream into independent zstd frames and compresses up to threads
+// frames concurrently. The concatenated frames form a valid zstd stream, so it is
+// decompressed as usual. The memory usage is about 2 * threads * ParallelFrameSize.
+type ParallelWriter struct {
+	underlying io.Writer
+	level      int
+	threads    int
+	dict       []byte
+	buffer     []byte
+	// the frames being compressed, in the stream order
+	pending   []chan compressedFrame
+	hasFrames bool
+	err       error
+}
+
+func NewParallelWriter(writer io.Writer, level, threads int, dict []byte) *ParallelWriter {
+	return &ParallelWriter{
+		underlying: writer,
+		level:      level,
+		threads:    threads,
+		dict:       dict,
+		buffer:     make([]byte, 0, ParallelFrameSize),
+		pending:    make([]chan compressedFrame, 0, threads),
+	}
+}
+
+func (writer *ParallelWriter) Write(p []byte) (int, error) {
+	if writer.err != nil {
+		return 0, writer.err
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(writer.buffer[len(writer.buffer):cap(writer.buffer)], p)
+		writer.buffer = writer.buffer[:len(writer.buffer)+n]
+		p = p[n:]
+		written += n
+		if len(writer.buffer) == cap(writer.buffer) {
+			if err := writer.compressBuffer(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close compresses the rest of the data and writes all the frames,
+// but does not close the underlying io.Writer.
+func (writer *ParallelWriter) Close() error {
+	if writer.err != nil {
+		return writer.err
+	}
+	if len(writer.buffer) > 0 || !writer.hasFrames {
+		// the empty stream still gets the empty frame, as the regular writer does
+		writer.startFrame()
+	}
+	for len(writer.pending) > 0 {
+		if err := writer.writeOldestFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (writer *ParallelWriter) compressBuffer() error {
+	if len(writer.pending) == writer.threads {
+		if err := writer.writeOldestFrame(); err != nil {
+			return err
+		}
+	}
+	writer.startFrame()
+	return nil
+}
+
+func (writer *ParallelWriter) startFrame() {
+	frame := make(chan compressedFrame, 1)
+	go func(src []byte, level int, dict []byte) {
+		data, err := compressFrame(src, level, dict)
+		frame <- compressedFrame{data, err}
+	}(writer.buffer, writer.level, writer.dict)
+	writer.pending = append(writer.pending, frame)
+	writer.hasFrames = true
+	writer.buffer = make([]byte, 0, ParallelFrameSize)
+}
+
+func (writer *ParallelWriter) writeOldestFrame() error {
+	frame := <-writer.pending[0]
+	writer.pending = writer.pending[1:]
+	if frame.err != nil {
+		writer.err = errors.Wrap(frame.err, "ParallelWriter: zstd compression failed")
+		return writer.err
+	}
+	_, err := writer.underlying.Write(frame.data)
+	if err != nil {
+		writer.err = err
+	}
+	return err
+}
+
+func compressFrame(src []byte, level int, dict []byte) ([]byte, error) {
+	if len(dict) == 0 {
+		return zstd.CompressLevel(nil, src, level)
+	}
+	var frame bytes.Buffer
+	frameWriter := zstd.NewWriterLevelDict(&frame, level, dict)
+	if _, err := frameWriter.Write(src); err != nil {
+		return nil, err
+	}
+	err := frameWriter.Close()
+	return frame.Bytes(), err
+}
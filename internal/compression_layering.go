@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// peekMagic reports whether the content begins with the magic
+func peekMagic(content io.Reader, magic []byte) (io.Reader, bool) {
+	bufferedContent := bufio.NewReader(content)
+	header, _ := bufferedContent.Peek(len(magic))
+	return bufferedContent, bytes.Equal(header, magic)
+}
+
+// decryptAndDecompress writes the decrypted and decompressed content of the object to dst.
+// CompressAndEncrypt encrypts the compressed content, but the objects written by the other tools
+// may be compressed after the encryption, so the order is detected by the magic of the compression,
+// if it has one: the object beginning with the magic is decompressed before it is decrypted.
+// The content without the magic where it is expected is reported instead of being decompressed into garbage.
+func decryptAndDecompress(dst io.Writer, content io.Reader, crypter crypto.Crypter,
+	decompressor compression.Decompressor, path string) error {
+	fileExtension := decompressor.FileExtension()
+	magic := compression.Magic(fileExtension)
+	compressedOutside := false
+	if magic != nil {
+		content, compressedOutside = peekMagic(content, magic)
+	}
+
+	if crypter != nil && compressedOutside {
+		tracelog.DebugLogger.Printf("%s is compressed after the encryption, decompressing it first\n", path)
+		return decompressAndDecrypt(dst, content, crypter, decompressor, path)
+	}
+
+	if crypter != nil {
+		decryptedContent, err := crypter.Decrypt(content)
+		if err != nil {
+			return errors.Wrap(err, "decrypt failed")
+		}
+		content = decryptedContent
+		if magic != nil {
+			content, compressedOutside = peekMagic(content, magic)
+			if !compressedOutside {
+				return newDecompressionError(errors.Errorf(
+					"the decrypted content of %s is not %s compressed", path, fileExtension))
+			}
+		}
+	} else if magic != nil && !compressedOutside {
+		return newDecompressionError(errors.Errorf(
+			"%s is not %s compressed. Is archive encrypted?", path, fileExtension))
+	}
+
+	err := decompressor.Decompress(dst, content)
+	if err != nil {
+		return errors.Wrapf(newDecompressionError(err), "%v decompress failed. Is archive encrypted?", fileExtension)
+	}
+	return nil
+}
+
+// decompressAndDecrypt decrypts the decompressed content of the object compressed after the encryption
+func decompressAndDecrypt(dst io.Writer, content io.Reader, crypter crypto.Crypter,
+	decompressor compression.Decompressor, path string) error {
+	decompressedReader, decompressedWriter := io.Pipe()
+	go func() {
+		err := decompressor.Decompress(decompressedWriter, content)
+		if err != nil {
+			err = errors.Wrapf(newDecompressionError(err), "%v decompress failed", decompressor.FileExtension())
+		}
+		_ = decompressedWriter.CloseWithError(err)
+	}()
+	defer utility.LoggedClose(decompressedReader, "")
+
+	decryptedContent, err := crypter.Decrypt(decompressedReader)
+	if err != nil {
+		return errors.Wrapf(err, "%s is compressed after the encryption, but the decompressed content "+
+			"can't be decrypted, it's either not encrypted or encrypted with another key", path)
+	}
+	_, err = utility.FastCopy(dst, decryptedContent)
+	return err
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/webserver"
+	"github.com/wal-g/wal-g/utility"
 )
 
 const (
@@ -26,15 +27,22 @@ const (
 	GP        = "GP"
 
 	DownloadConcurrencySetting   = "WALG_DOWNLOAD_CONCURRENCY"
+	DownloadRetriesSetting       = "WALG_DOWNLOAD_RETRIES"
+	DownloadRetryIntervalSetting = "WALG_DOWNLOAD_RETRY_MAX_INTERVAL"
+	DecompressConcurrencySetting = "WALG_DECOMPRESS_CONCURRENCY"
 	UploadConcurrencySetting     = "WALG_UPLOAD_CONCURRENCY"
 	UploadDiskConcurrencySetting = "WALG_UPLOAD_DISK_CONCURRENCY"
 	UploadQueueSetting           = "WALG_UPLOAD_QUEUE"
+	TarQueueMemoryLimitSetting   = "WALG_TAR_QUEUE_MEMORY_LIMIT"
 	SentinelUserDataSetting      = "WALG_SENTINEL_USER_DATA"
 	PreventWalOverwriteSetting   = "WALG_PREVENT_WAL_OVERWRITE"
 	UploadWalMetadata            = "WALG_UPLOAD_WAL_METADATA"
 	DeltaMaxStepsSetting         = "WALG_DELTA_MAX_STEPS"
 	DeltaOriginSetting           = "WALG_DELTA_ORIGIN"
 	CompressionMethodSetting     = "WALG_COMPRESSION_METHOD"
+	CompressionAdaptiveSetting   = "WALG_COMPRESSION_ADAPTIVE"
+	CompressionThreadsSetting    = "WALG_ZSTD_COMPRESSION_THREADS"
+	ZstdDictPathSetting          = "WALG_ZSTD_DICT_PATH"
 	StoragePrefixSetting         = "WALG_STORAGE_PREFIX"
 	DiskRateLimitSetting         = "WALG_DISK_RATE_LIMIT"
 	NetworkRateLimitSetting      = "WALG_NETWORK_RATE_LIMIT"
@@ -44,10 +52,13 @@ const (
 	VerifyPageChecksumsSetting   = "WALG_VERIFY_PAGE_CHECKSUMS"
 	StoreAllCorruptBlocksSetting = "WALG_STORE_ALL_CORRUPT_BLOCKS"
 	UseRatingComposerSetting     = "WALG_USE_RATING_COMPOSER"
+	SentinelSuffixSetting        = "WALG_SENTINEL_SUFFIX"
+	BackupNamePrefixSetting      = "WALG_BACKUP_NAME_PREFIX"
 	DeltaFromNameSetting         = "WALG_DELTA_FROM_NAME"
 	DeltaFromUserDataSetting     = "WALG_DELTA_FROM_USER_DATA"
 	FetchTargetUserDataSetting   = "WALG_FETCH_TARGET_USER_DATA"
 	LogLevelSetting              = "WALG_LOG_LEVEL"
+	ErrorOutputSetting           = "WALG_ERROR_OUTPUT"
 	TarSizeThresholdSetting      = "WALG_TAR_SIZE_THRESHOLD"
 	CseKmsIDSetting              = "WALG_CSE_KMS_ID"
 	CseKmsRegionSetting          = "WALG_CSE_KMS_REGION"
@@ -57,6 +68,15 @@ const (
 	PgpKeySetting                = "WALG_PGP_KEY"
 	PgpKeyPathSetting            = "WALG_PGP_KEY_PATH"
 	PgpKeyPassphraseSetting      = "WALG_PGP_KEY_PASSPHRASE"
+	PgpRequireValidKeySetting    = "WALG_PGP_REQUIRE_VALID_KEY"
+	PgpDecryptKeyPathsSetting    = "WALG_PGP_DECRYPT_KEY_PATHS"
+	PgpDecryptPassphrasesSetting = "WALG_PGP_DECRYPT_KEY_PASSPHRASES"
+	OtelEndpointSetting          = "WALG_OTEL_ENDPOINT"
+	TarFormatSetting             = "WALG_TAR_FORMAT"
+	ConfirmUploadsSetting        = "WALG_CONFIRM_UPLOADS"
+	PartialWalSegmentsSetting    = "WALG_PARTIAL_WAL_SEGMENTS"
+	ChainDownloadSetting         = "WALG_PARALLEL_CHAIN_DOWNLOAD"
+	SymlinkPolicySetting         = "WALG_SYMLINK_POLICY"
 	PgDataSetting                = "PGDATA"
 	UserSetting                  = "USER" // TODO : do something with it
 	PgPortSetting                = "PGPORT"
@@ -73,6 +93,39 @@ const (
 	MaxDelayedSegmentsCount      = "WALG_INTEGRITY_MAX_DELAYED_WALS"
 	PrefetchDir                  = "WALG_PREFETCH_DIR"
 	PgReadyRename                = "PG_READY_RENAME"
+	FailOnBgUploadErrorSetting   = "WALG_FAIL_ON_BG_UPLOAD_ERROR"
+	WalLookaheadCountSetting     = "WALG_WAL_LOOKAHEAD_COUNT"
+	DedupChunkingSetting         = "WALG_DEDUP_CHUNKING"
+	SkipExistingPartsSetting     = "WALG_SKIP_EXISTING_PARTS"
+	EncryptWalMetadataSetting    = "WALG_ENCRYPT_WAL_METADATA"
+	ColdStorageConfigSetting     = "WALG_COLD_STORAGE_CONFIG"
+	StorageReadOnlySetting       = "WALG_STORAGE_READ_ONLY"
+	ListCompleteOnlySetting      = "WALG_LIST_COMPLETE_ONLY"
+	WalRetentionMarginSetting    = "WALG_WAL_RETENTION_MARGIN"
+	BackupExtraFilesSetting      = "WALG_BACKUP_EXTRA_FILES"
+	BackupExcludedFilesSetting   = "WALG_BACKUP_EXCLUDED_FILES"
+	BackupLockSetting            = "WALG_BACKUP_LOCK"
+	RestoreSpaceMarginSetting    = "WALG_RESTORE_SPACE_MARGIN"
+	NormalizeKeysSetting         = "WALG_NORMALIZE_OBJECT_KEYS"
+	BackupSlotsSetting           = "WALG_BACKUP_REPLICATION_SLOTS"
+	WalCompressionSetting        = "WALG_WAL_COMPRESSION_METHOD"
+	BackupCompressionSetting     = "WALG_BACKUP_COMPRESSION_METHOD"
+	StreamCompressionSetting     = "WALG_STREAM_COMPRESSION_METHOD"
+	WalVerifyRoundtripSetting    = "WALG_WAL_VERIFY_ROUNDTRIP"
+	BackupFileChecksumsSetting   = "WALG_BACKUP_FILE_CHECKSUMS"
+	WalMetadataMergeConcurrency  = "WALG_WAL_METADATA_MERGE_CONCURRENCY"
+	StatisticsTimeoutSetting     = "WALG_STATISTICS_STATEMENT_TIMEOUT"
+	StatisticsConcurrency        = "WALG_STATISTICS_CONCURRENCY"
+	S3ObjectTagsSetting          = "WALG_S3_OBJECT_TAGS"
+	S3ACLSetting                 = "WALG_S3_ACL"
+	S3CredentialsRefreshSetting  = "WALG_S3_CREDENTIALS_REFRESH_INTERVAL"
+	S3ListMaxKeysSetting         = "WALG_S3_LIST_MAX_KEYS"
+	BackupFileChangePolicy       = "WALG_BACKUP_FILE_CHANGE_POLICY"
+	RestoreTruncatedPolicy       = "WALG_RESTORE_TRUNCATED_POLICY"
+	PostFetchHookSetting         = "WALG_POST_FETCH_HOOK"
+	WalShardPrefixSetting        = "WALG_WAL_SHARD_PREFIX"
+	InventoryDSNSetting          = "WALG_INVENTORY_DSN"
+	InventoryTableSetting        = "WALG_INVENTORY_TABLE"
 
 	MongoDBUriSetting               = "MONGODB_URI"
 	MongoDBLastWriteUpdateInterval  = "MONGODB_LAST_WRITE_UPDATE_INTERVAL"
@@ -126,6 +179,8 @@ var (
 
 	commonDefaultConfigValues = map[string]string{
 		DownloadConcurrencySetting:   "10",
+		DownloadRetriesSetting:       "0",
+		DownloadRetryIntervalSetting: "30s",
 		UploadConcurrencySetting:     "16",
 		UploadDiskConcurrencySetting: "1",
 		UploadQueueSetting:           "2",
@@ -133,10 +188,14 @@ var (
 		UploadWalMetadata:            "NOMETADATA",
 		DeltaMaxStepsSetting:         "0",
 		CompressionMethodSetting:     "lz4",
+		ErrorOutputSetting:           ErrorOutputText,
 		StoragePrefixSetting:         "",
 		UseWalDeltaSetting:           "false",
 		TarSizeThresholdSetting:      "1073741823", // (1 << 30) - 1
 		TotalBgUploadedLimit:         "32",
+		FailOnBgUploadErrorSetting:   "false",
+		SkipExistingPartsSetting:     "false",
+		ListCompleteOnlySetting:      "false",
 		UseReverseUnpackSetting:      "false",
 		SkipRedundantTarsSetting:     "false",
 		VerifyPageChecksumsSetting:   "false",
@@ -156,7 +215,12 @@ var (
 	}
 
 	PGDefaultSettings = map[string]string{
-		PgWalSize: "16",
+		PgWalSize:                   "16",
+		RestoreSpaceMarginSetting:   "10",
+		WalMetadataMergeConcurrency: "1",
+		StatisticsTimeoutSetting:    "10m",
+		StatisticsConcurrency:       "1",
+		InventoryTableSetting:       "walg_backups",
 	}
 
 	AllowedSettings map[string]bool
@@ -164,26 +228,41 @@ var (
 	CommonAllowedSettings = map[string]bool{
 		// WAL-G core
 		DownloadConcurrencySetting:   true,
+		DownloadRetriesSetting:       true,
+		DownloadRetryIntervalSetting: true,
+		DecompressConcurrencySetting: true,
 		UploadConcurrencySetting:     true,
 		UploadDiskConcurrencySetting: true,
 		UploadQueueSetting:           true,
+		TarQueueMemoryLimitSetting:   true,
 		SentinelUserDataSetting:      true,
 		PreventWalOverwriteSetting:   true,
 		UploadWalMetadata:            true,
 		DeltaMaxStepsSetting:         true,
 		DeltaOriginSetting:           true,
 		CompressionMethodSetting:     true,
+		StreamCompressionSetting:     true,
+		CompressionAdaptiveSetting:   true,
+		CompressionThreadsSetting:    true,
+		ZstdDictPathSetting:          true,
 		StoragePrefixSetting:         true,
+		StorageReadOnlySetting:       true,
+		ListCompleteOnlySetting:      true,
 		DiskRateLimitSetting:         true,
 		NetworkRateLimitSetting:      true,
 		UseWalDeltaSetting:           true,
 		LogLevelSetting:              true,
+		ErrorOutputSetting:           true,
 		TarSizeThresholdSetting:      true,
 		"WALG_" + GpgKeyIDSetting:    true,
 		"WALE_" + GpgKeyIDSetting:    true,
 		PgpKeySetting:                true,
 		PgpKeyPathSetting:            true,
 		PgpKeyPassphraseSetting:      true,
+		PgpRequireValidKeySetting:    true,
+		PgpDecryptKeyPathsSetting:    true,
+		PgpDecryptPassphrasesSetting: true,
+		ConfirmUploadsSetting:        true,
 		LibsodiumKeySetting:          true,
 		LibsodiumKeyPathSetting:      true,
 		TotalBgUploadedLimit:         true,
@@ -198,6 +277,8 @@ var (
 		DeltaFromNameSetting:         true,
 		DeltaFromUserDataSetting:     true,
 		FetchTargetUserDataSetting:   true,
+		SentinelSuffixSetting:        true,
+		BackupNamePrefixSetting:      true,
 
 		// Swift
 		"WALG_SWIFT_PREFIX": true,
@@ -217,6 +298,8 @@ var (
 		"AWS_DEFAULT_OUTPUT":          true,
 		"AWS_PROFILE":                 true,
 		"AWS_ROLE_SESSION_NAME":       true,
+		"AWS_ROLE_ARN":                true,
+		"AWS_WEB_IDENTITY_TOKEN_FILE": true,
 		"AWS_CA_BUNDLE":               true,
 		"AWS_SHARED_CREDENTIALS_FILE": true,
 		"AWS_CONFIG_FILE":             true,
@@ -230,6 +313,10 @@ var (
 		"WALG_CSE_KMS_ID":             true,
 		"WALG_CSE_KMS_REGION":         true,
 		"WALG_S3_MAX_PART_SIZE":       true,
+		S3ObjectTagsSetting:           true,
+		S3ACLSetting:                  true,
+		S3CredentialsRefreshSetting:   true,
+		S3ListMaxKeysSetting:          true,
 		"S3_ENDPOINT_SOURCE":          true,
 		"S3_ENDPOINT_PORT":            true,
 		"S3_USE_LIST_OBJECTS_V1":      true,
@@ -272,18 +359,49 @@ var (
 
 	PGAllowedSettings = map[string]bool{
 		// Postgres
-		PgPortSetting:     true,
-		PgUserSetting:     true,
-		PgHostSetting:     true,
-		PgDataSetting:     true,
-		PgPasswordSetting: true,
-		PgDatabaseSetting: true,
-		PgSslModeSetting:  true,
-		PgSlotName:        true,
-		PgWalSize:         true,
-		"PGPASSFILE":      true,
-		PrefetchDir:       true,
-		PgReadyRename:     true,
+		PgPortSetting:               true,
+		PgUserSetting:               true,
+		PgHostSetting:               true,
+		PgDataSetting:               true,
+		PgPasswordSetting:           true,
+		PgDatabaseSetting:           true,
+		PgSslModeSetting:            true,
+		PgSlotName:                  true,
+		PgWalSize:                   true,
+		"PGPASSFILE":                true,
+		PrefetchDir:                 true,
+		PgReadyRename:               true,
+		FailOnBgUploadErrorSetting:  true,
+		WalLookaheadCountSetting:    true,
+		DedupChunkingSetting:        true,
+		SkipExistingPartsSetting:    true,
+		EncryptWalMetadataSetting:   true,
+		ColdStorageConfigSetting:    true,
+		WalRetentionMarginSetting:   true,
+		BackupExtraFilesSetting:     true,
+		BackupExcludedFilesSetting:  true,
+		BackupLockSetting:           true,
+		RestoreSpaceMarginSetting:   true,
+		NormalizeKeysSetting:        true,
+		BackupSlotsSetting:          true,
+		WalCompressionSetting:       true,
+		BackupCompressionSetting:    true,
+		WalVerifyRoundtripSetting:   true,
+		BackupFileChecksumsSetting:  true,
+		WalMetadataMergeConcurrency: true,
+		StatisticsTimeoutSetting:    true,
+		StatisticsConcurrency:       true,
+		BackupFileChangePolicy:      true,
+		RestoreTruncatedPolicy:      true,
+		PostFetchHookSetting:        true,
+		WalShardPrefixSetting:       true,
+		InventoryDSNSetting:         true,
+		InventoryTableSetting:       true,
+		OtelEndpointSetting:         true,
+		TarFormatSetting:            true,
+		PartialWalSegmentsSetting:   true,
+		ChainDownloadSetting:        true,
+		SymlinkPolicySetting:        true,
 	}
 
 	MongoAllowedSettings = map[string]bool{
@@ -332,6 +450,11 @@ var (
 		OplogPushStatsExposeHTTP: nil,
 	}
 	Turbo bool
+	// ReadOnly rejects the writes to the storage, the same as WALG_STORAGE_READ_ONLY
+	ReadOnly bool
+	// Verbose and Quiet override WALG_LOG_LEVEL for the current invocation
+	Verbose bool
+	Quiet   bool
 )
 
 func ConfigureSettings(currentType string) {
@@ -420,7 +543,7 @@ func Configure() {
 	err := ConfigureLogging()
 	if err != nil {
 		tracelog.ErrorLogger.Println("Failed to configure logging.")
-		tracelog.ErrorLogger.FatalError(err)
+		FatalError(err)
 	}
 
 	// Show all ENV vars in DEVEL Logging Mode
@@ -432,6 +555,35 @@ func Configure() {
 	}
 
 	configureLimiters()
+
+	err = configureBackupNaming()
+	if err != nil {
+		tracelog.ErrorLogger.Println("Failed to configure backup naming.")
+		FatalError(err)
+	}
+
+	if tarFormat, ok := GetSetting(TarFormatSetting); ok {
+		TarHeaderFormat, err = ParseTarFormat(tarFormat)
+		if err != nil {
+			tracelog.ErrorLogger.Println("Failed to configure tar format.")
+			FatalError(err)
+		}
+	}
+}
+
+// configureBackupNaming applies the overrides of the sentinel suffix and backup name prefix
+func configureBackupNaming() error {
+	if suffix, ok := GetSetting(SentinelSuffixSetting); ok {
+		if err := utility.SetSentinelSuffix(suffix); err != nil {
+			return err
+		}
+	}
+	if prefix, ok := GetSetting(BackupNamePrefixSetting); ok {
+		if err := utility.SetBackupNamePrefix(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ConfigureAndRunDefaultWebServer configures and runs web server
@@ -494,7 +646,7 @@ func InitConfig() {
 		val, ok := v.(string)
 		if ok {
 			err := bindToEnv(k, val)
-			tracelog.ErrorLogger.FatalOnError(err)
+			FatalOnError(err)
 		}
 	}
 }
@@ -506,7 +658,7 @@ func ReadConfigFromFile(config *viper.Viper, configFile string) {
 	} else {
 		// Find home directory.
 		usr, err := user.Current()
-		tracelog.ErrorLogger.FatalOnError(err)
+		FatalOnError(err)
 
 		// Search config in home directory with name ".walg" (without extension).
 		config.AddConfigPath(usr.HomeDir)
@@ -610,7 +762,7 @@ func FolderFromConfig(configFile string) (storage.Folder, error) {
 
 	if err != nil {
 		tracelog.ErrorLogger.Println("Failed configure folder according to config " + configFile)
-		tracelog.ErrorLogger.FatalError(err)
+		FatalError(err)
 	}
 	return folder, err
 }
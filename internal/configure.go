@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/wal-g/wal-g/internal/crypto/yckms"
@@ -22,6 +25,8 @@ import (
 	"github.com/wal-g/wal-g/internal/crypto/openpgp"
 	"github.com/wal-g/wal-g/internal/fsutil"
 	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/internal/tracing"
+	"github.com/wal-g/wal-g/utility"
 	"golang.org/x/time/rate"
 )
 
@@ -58,10 +63,9 @@ type UnknownCompressionMethodError struct {
 	error
 }
 
-func newUnknownCompressionMethodError() UnknownCompressionMethodError {
+func newUnknownCompressionMethodError(supportedMethods []string) UnknownCompressionMethodError {
 	return UnknownCompressionMethodError{
-		errors.Errorf("Unknown compression method, supported methods are: %v",
-			compression.CompressingAlgorithms)}
+		errors.Errorf("Unknown compression method, supported methods are: %v", supportedMethods)}
 }
 
 func (err UnknownCompressionMethodError) Error() string {
@@ -131,7 +135,16 @@ func ConfigureFolder() (storage.Folder, error) {
 		return nil, err
 	}
 
-	return ConfigureStoragePrefix(folder), nil
+	folder = ConfigureStoragePrefix(folder)
+	if coldStorageConfig := viper.GetString(ColdStorageConfigSetting); coldStorageConfig != "" {
+		coldFolder, err := FolderFromConfig(coldStorageConfig)
+		if err != nil {
+			return nil, err
+		}
+		folder = NewTieredFolder(folder, coldFolder)
+	}
+	configureZstdDictionaryLoader(folder)
+	return folder, nil
 }
 
 func ConfigureStoragePrefix(folder storage.Folder) storage.Folder {
@@ -159,7 +172,24 @@ func ConfigureFolderForSpecificConfig(config *viper.Viper) (storage.Folder, erro
 		}
 
 		settings := adapter.loadSettings(config)
-		return adapter.configureFolder(prefix, settings)
+		folder, err := adapter.configureFolder(prefix, settings)
+		if err != nil {
+			return nil, err
+		}
+		if retries := config.GetInt(DownloadRetriesSetting); retries > 0 {
+			folder = NewRetryingFolder(folder, retries, config.GetDuration(DownloadRetryIntervalSetting))
+		}
+		walSharding, err := getWalSharding(config)
+		if err != nil {
+			return nil, err
+		}
+		if walSharding != "" {
+			folder = NewWalShardingFolder(folder, walSharding)
+		}
+		if isStorageReadOnlyIn(config) {
+			folder = NewReadOnlyFolder(folder)
+		}
+		return folder, nil
 	}
 	return nil, newUnconfiguredStorageError(skippedPrefixes)
 }
@@ -196,20 +226,129 @@ func GetPgSlotName() (pgSlotName string) {
 
 // TODO : unit tests
 func ConfigureCompressor() (compression.Compressor, error) {
-	compressionMethod := viper.GetString(CompressionMethodSetting)
-	if _, ok := compression.Compressors[compressionMethod]; !ok {
-		return nil, newUnknownCompressionMethodError()
+	return configureCompressor(viper.GetString(CompressionMethodSetting),
+		compression.Compressors, compression.CompressingAlgorithms)
+}
+
+// ConfigureCompressorWithSetting configures the compressor with the method from methodSetting,
+// e.g. WALG_WAL_COMPRESSION_METHOD. Falls back to WALG_COMPRESSION_METHOD if methodSetting is unset.
+func ConfigureCompressorWithSetting(methodSetting string) (compression.Compressor, error) {
+	return configureCompressor(getCompressionMethod(methodSetting),
+		compression.Compressors, compression.CompressingAlgorithms)
+}
+
+// ConfigurePostgresCompressorWithSetting is the same as ConfigureCompressorWithSetting,
+// but zstd is among the methods as well, the compression with zstd is supported for PostgreSQL only
+func ConfigurePostgresCompressorWithSetting(methodSetting string) (compression.Compressor, error) {
+	return configureCompressor(getCompressionMethod(methodSetting),
+		compression.PostgresCompressors(), compression.PostgresCompressingAlgorithms())
+}
+
+// getCompressionMethod reads the method from methodSetting, falls back to WALG_COMPRESSION_METHOD if it is unset
+func getCompressionMethod(methodSetting string) string {
+	if viper.GetString(methodSetting) == "" {
+		return viper.GetString(CompressionMethodSetting)
 	}
-	return compression.Compressors[compressionMethod], nil
+	return viper.GetString(methodSetting)
+}
+
+func configureCompressor(compressionMethod string, compressors map[string]compression.Compressor,
+	supportedMethods []string) (compression.Compressor, error) {
+	compressor, ok := compressors[compressionMethod]
+	if !ok {
+		return nil, newUnknownCompressionMethodError(supportedMethods)
+	}
+	if dictPath := viper.GetString(ZstdDictPathSetting); dictPath != "" {
+		dict, err := ReadZstdDictionary(dictPath)
+		if err != nil {
+			return nil, err
+		}
+		var supported bool
+		compressor, supported = compression.WithDictionary(compressor, dict)
+		if !supported {
+			tracelog.WarningLogger.Printf("%s is ignored, the dictionaries are not supported by %s\n",
+				ZstdDictPathSetting, compressionMethod)
+		}
+	}
+	if viper.IsSet(CompressionThreadsSetting) {
+		threads, err := getCompressionThreads()
+		if err != nil {
+			return nil, err
+		}
+		if threads > 1 {
+			var supported bool
+			compressor, supported = compression.WithThreads(compressor, threads)
+			if !supported {
+				tracelog.WarningLogger.Printf("Multi-threaded compression is not supported by %s, using one thread\n",
+					compressionMethod)
+			}
+		}
+	}
+	if viper.GetBool(CompressionAdaptiveSetting) {
+		leveledCompressor, ok := compressor.(compression.LeveledCompressor)
+		if !ok {
+			tracelog.WarningLogger.Printf("Adaptive compression is not supported by %s, using the fixed level\n",
+				compressionMethod)
+			return compressor, nil
+		}
+		return compression.NewAdaptiveCompressor(leveledCompressor), nil
+	}
+	return compressor, nil
+}
+
+// getCompressionThreads reads the number of zstd threads per compressed stream,
+// it is bounded by GOMAXPROCS
+func getCompressionThreads() (int, error) {
+	threads := viper.GetInt(CompressionThreadsSetting)
+	if threads < 1 {
+		return 0, newInvalidConcurrencyValueError(CompressionThreadsSetting, threads)
+	}
+	maxProcs := runtime.GOMAXPROCS(0)
+	if threads > maxProcs {
+		tracelog.WarningLogger.Printf("%s is greater than GOMAXPROCS, using %d threads\n",
+			CompressionThreadsSetting, maxProcs)
+		threads = maxProcs
+	}
+	uploadConcurrency, err := GetMaxUploadConcurrency()
+	if err == nil && threads*uploadConcurrency > maxProcs {
+		tracelog.WarningLogger.Printf("Up to %d compression threads (%s * %s) may run concurrently "+
+			"while GOMAXPROCS is %d\n", threads*uploadConcurrency, CompressionThreadsSetting,
+			UploadConcurrencySetting, maxProcs)
+	}
+	return threads, nil
 }
 
 func ConfigureLogging() error {
+	if Verbose && Quiet {
+		return errors.New("--verbose and --quiet flags can't be used together")
+	}
+	err := configureErrorOutput()
+	if err != nil {
+		return err
+	}
+	if Verbose {
+		return tracelog.UpdateLogLevel(tracelog.DevelLogLevel)
+	}
 	if viper.IsSet(LogLevelSetting) {
-		return tracelog.UpdateLogLevel(viper.GetString(LogLevelSetting))
+		err = tracelog.UpdateLogLevel(viper.GetString(LogLevelSetting))
+		if err != nil {
+			return err
+		}
+	}
+	if Quiet {
+		setQuietLogging()
 	}
 	return nil
 }
 
+// setQuietLogging leaves only the error messages. The data written to stdout
+// by the commands (e.g. the stream of backup-fetch) is not affected.
+func setQuietLogging() {
+	tracelog.DebugLogger = tracelog.NewErrorLogger(ioutil.Discard, "DEBUG: ")
+	tracelog.InfoLogger = tracelog.NewErrorLogger(ioutil.Discard, "INFO: ")
+	tracelog.WarningLogger = tracelog.NewErrorLogger(ioutil.Discard, "WARNING: ")
+}
+
 func getPGArchiveStatusFolderPath() string {
 	return filepath.Join(getWalFolderPath(), "archive_status")
 }
@@ -247,7 +386,17 @@ func ConfigureUploader() (uploader *Uploader, err error) {
 		return nil, errors.Wrap(err, "failed to configure compression")
 	}
 
+	if err = UploadZstdDictionary(folder); err != nil {
+		return nil, err
+	}
+
 	uploader = NewUploader(compressor, folder)
+	if viper.GetString(StreamCompressionSetting) != "" {
+		uploader.StreamCompressor, err = ConfigureCompressorWithSetting(StreamCompressionSetting)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to configure stream compression")
+		}
+	}
 	return uploader, err
 }
 
@@ -261,6 +410,50 @@ func ConfigureUploaderWithoutCompressMethod() (uploader *Uploader, err error) {
 	return uploader, err
 }
 
+func configureOpenPGPCrypter(crypter crypto.Crypter) crypto.Crypter {
+	openPGPCrypter := crypter.(*openpgp.Crypter)
+	openPGPCrypter.RequireValidKey = viper.GetBool(PgpRequireValidKeySetting)
+	openPGPCrypter.DecryptionKeys = getPGPDecryptionKeys()
+	return crypter
+}
+
+// getPGPDecryptionKeys pairs the WALG_PGP_DECRYPT_KEY_PATHS with the WALG_PGP_DECRYPT_KEY_PASSPHRASES
+// by position, the WALG_PGP_KEY_PASSPHRASE is used for all the keys if the passphrases are not set.
+// The commas and the backslashes of the passphrases are escaped with the backslash.
+func getPGPDecryptionKeys() []openpgp.DecryptionKey {
+	if !viper.IsSet(PgpDecryptKeyPathsSetting) {
+		return nil
+	}
+	var passphrases []string
+	hasPassphrases := viper.IsSet(PgpDecryptPassphrasesSetting)
+	if hasPassphrases {
+		passphrases = utility.SplitEscaped(viper.GetString(PgpDecryptPassphrasesSetting), ',')
+	}
+	defaultPassphrase, hasDefaultPassphrase := GetSetting(PgpKeyPassphraseSetting)
+
+	var decryptionKeys []openpgp.DecryptionKey
+	for _, keyPath := range strings.Split(viper.GetString(PgpDecryptKeyPathsSetting), ",") {
+		keyPath = strings.TrimSpace(keyPath)
+		if keyPath == "" {
+			continue
+		}
+		decryptionKey := openpgp.DecryptionKey{ArmoredKeyPath: keyPath}
+		switch {
+		case !hasPassphrases:
+			decryptionKey.Passphrase, decryptionKey.HasPassphrase = defaultPassphrase, hasDefaultPassphrase
+		case len(decryptionKeys) < len(passphrases):
+			decryptionKey.Passphrase = passphrases[len(decryptionKeys)]
+			decryptionKey.HasPassphrase = decryptionKey.Passphrase != ""
+		}
+		decryptionKeys = append(decryptionKeys, decryptionKey)
+	}
+	if len(passphrases) > len(decryptionKeys) {
+		tracelog.WarningLogger.Printf("%s has more passphrases than there are keys in %s, the extra ones are ignored\n",
+			PgpDecryptPassphrasesSetting, PgpDecryptKeyPathsSetting)
+	}
+	return decryptionKeys
+}
+
 // ConfigureCrypter uses environment variables to create and configure a crypter.
 // In case no configuration in environment variables found, return `<nil>` value.
 func ConfigureCrypter() crypto.Crypter {
@@ -270,17 +463,17 @@ func ConfigureCrypter() crypto.Crypter {
 
 	// key can be either private (for download) or public (for upload)
 	if viper.IsSet(PgpKeySetting) {
-		return openpgp.CrypterFromKey(viper.GetString(PgpKeySetting), loadPassphrase)
+		return configureOpenPGPCrypter(openpgp.CrypterFromKey(viper.GetString(PgpKeySetting), loadPassphrase))
 	}
 
 	// key can be either private (for download) or public (for upload)
 	if viper.IsSet(PgpKeyPathSetting) {
-		return openpgp.CrypterFromKeyPath(viper.GetString(PgpKeyPathSetting), loadPassphrase)
+		return configureOpenPGPCrypter(openpgp.CrypterFromKeyPath(viper.GetString(PgpKeyPathSetting), loadPassphrase))
 	}
 
 	if keyRingID, ok := getWaleCompatibleSetting(GpgKeyIDSetting); ok {
 		tracelog.WarningLogger.Printf(DeprecatedExternalGpgMessage)
-		return openpgp.CrypterFromKeyRingID(keyRingID, loadPassphrase)
+		return configureOpenPGPCrypter(openpgp.CrypterFromKeyRingID(keyRingID, loadPassphrase))
 	}
 
 	if viper.IsSet(CseKmsIDSetting) {
@@ -288,7 +481,10 @@ func ConfigureCrypter() crypto.Crypter {
 	}
 
 	if viper.IsSet(YcKmsKeyIDSetting) {
-		return yckms.YcCrypterFromKeyIDAndCredential(viper.GetString(YcKmsKeyIDSetting), viper.GetString(YcSaKeyFileSetting))
+		crypter, err := yckms.YcCrypterFromKeyIDAndCredential(viper.GetString(YcKmsKeyIDSetting),
+			viper.GetString(YcSaKeyFileSetting))
+		FatalOnError(err)
+		return crypter
 	}
 
 	if crypter := configureLibsodiumCrypter(); crypter != nil {
@@ -298,10 +494,33 @@ func ConfigureCrypter() crypto.Crypter {
 	return nil
 }
 
+// ConfigureTracing exports the trace spans to WALG_OTEL_ENDPOINT, if it is set
+func ConfigureTracing() error {
+	endpoint, ok := GetSetting(OtelEndpointSetting)
+	if !ok {
+		return nil
+	}
+	if err := tracing.Init(endpoint); err != nil {
+		return err
+	}
+	// the spans of the command failed with the fatal error are flushed too
+	RegisterExitHook(tracing.FinishCommand)
+	return nil
+}
+
 func GetMaxDownloadConcurrency() (int, error) {
 	return GetMaxConcurrency(DownloadConcurrencySetting)
 }
 
+// GetMaxDecompressConcurrency returns how many tar partitions may be decrypted and decompressed at once
+// during the extraction, 0 if it is not limited, which is the default
+func GetMaxDecompressConcurrency() (int, error) {
+	if !viper.IsSet(DecompressConcurrencySetting) {
+		return 0, nil
+	}
+	return GetMaxConcurrency(DecompressConcurrencySetting)
+}
+
 func GetMaxUploadConcurrency() (int, error) {
 	return GetMaxConcurrency(UploadConcurrencySetting)
 }
@@ -312,6 +531,15 @@ func getMaxUploadQueue() (int, error) {
 	return GetMaxConcurrency(UploadQueueSetting)
 }
 
+// GetTarQueueMemoryLimit returns WALG_TAR_QUEUE_MEMORY_LIMIT in bytes, 0 means no limit
+func GetTarQueueMemoryLimit() (int64, error) {
+	memoryLimit := viper.GetInt64(TarQueueMemoryLimitSetting)
+	if memoryLimit < 0 {
+		return 0, errors.Errorf("%s is expected to be non-negative, but is %d", TarQueueMemoryLimitSetting, memoryLimit)
+	}
+	return memoryLimit, nil
+}
+
 func GetMaxUploadDiskConcurrency() (int, error) {
 	if Turbo {
 		return 4, nil
@@ -362,14 +590,19 @@ func GetCommandSettingContext(ctx context.Context, variableName string) (*exec.C
 		tracelog.ErrorLogger.Print(variableName + " expected.")
 		return nil, errors.New(variableName + " not configured")
 	}
+	return NewShellCommandContext(ctx, dataStr), nil
+}
+
+// NewShellCommandContext makes the command running the command line with $SHELL, /bin/sh by default
+func NewShellCommandContext(ctx context.Context, commandLine string) *exec.Cmd {
 	shell := os.Getenv("SHELL")
 	if shell == "" {
 		shell = "/bin/sh"
 	}
-	cmd := exec.CommandContext(ctx, shell, "-c", dataStr)
+	cmd := exec.CommandContext(ctx, shell, "-c", commandLine)
 	// do not shut up subcommands by default
 	cmd.Stderr = os.Stderr
-	return cmd, nil
+	return cmd
 }
 
 func GetCommandSetting(variableName string) (*exec.Cmd, error) {
@@ -150,3 +150,72 @@ func prepareDataFolder(t *testing.T, name string) string {
 	fmt.Println(dir)
 	return dir
 }
+
+func TestConfigureLogging_VerboseAndQuietConflict(t *testing.T) {
+	internal.Verbose, internal.Quiet = true, true
+	defer func() { internal.Verbose, internal.Quiet = false, false }()
+
+	assert.Error(t, internal.ConfigureLogging())
+}
+
+func TestConfigureLogging_Quiet(t *testing.T) {
+	infoLogger, warningLogger, debugLogger := tracelog.InfoLogger, tracelog.WarningLogger, tracelog.DebugLogger
+	viper.Set(internal.LogLevelSetting, tracelog.NormalLogLevel)
+	internal.Quiet = true
+	defer func() {
+		internal.Quiet = false
+		tracelog.InfoLogger, tracelog.WarningLogger, tracelog.DebugLogger = infoLogger, warningLogger, debugLogger
+	}()
+
+	assert.NoError(t, internal.ConfigureLogging())
+	assert.Equal(t, ioutil.Discard, tracelog.InfoLogger.Writer())
+	assert.Equal(t, ioutil.Discard, tracelog.WarningLogger.Writer())
+	// the errors are still written, the exit hooks run after the fatal ones
+	assert.NotEqual(t, ioutil.Discard, tracelog.ErrorLogger.Writer())
+}
+
+func TestConfigureLogging_VerboseOverridesLogLevel(t *testing.T) {
+	viper.Set(internal.LogLevelSetting, tracelog.NormalLogLevel)
+	internal.Verbose = true
+	defer func() {
+		internal.Verbose = false
+		_ = tracelog.UpdateLogLevel(tracelog.NormalLogLevel)
+	}()
+
+	assert.NoError(t, internal.ConfigureLogging())
+	assert.Equal(t, "%+v", tracelog.GetErrorFormatter())
+}
+
+func TestConfigureCompressorWithSetting_FallsBackToCompressionMethod(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "lz4")
+	viper.Set(internal.WalCompressionSetting, "")
+	defer viper.Set(internal.CompressionMethodSetting, "lz4")
+
+	compressor, err := internal.ConfigureCompressorWithSetting(internal.WalCompressionSetting)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "lz4", compressor.FileExtension())
+}
+
+func TestConfigureCompressorWithSetting_UsesMethodSetting(t *testing.T) {
+	viper.Set(internal.CompressionMethodSetting, "lz4")
+	viper.Set(internal.BackupCompressionSetting, "zstd")
+	defer viper.Set(internal.BackupCompressionSetting, "")
+
+	compressor, err := internal.ConfigurePostgresCompressorWithSetting(internal.BackupCompressionSetting)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "zst", compressor.FileExtension())
+
+	_, err = internal.ConfigureCompressorWithSetting(internal.BackupCompressionSetting)
+	assert.IsType(t, internal.UnknownCompressionMethodError{}, err)
+}
+
+func TestConfigureCompressorWithSetting_UnknownMethod(t *testing.T) {
+	viper.Set(internal.WalCompressionSetting, "unknown")
+	defer viper.Set(internal.WalCompressionSetting, "")
+
+	_, err := internal.ConfigureCompressorWithSetting(internal.WalCompressionSetting)
+
+	assert.Error(t, err)
+}
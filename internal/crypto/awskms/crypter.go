@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/minio/sio"
+	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/ioextensions"
@@ -23,10 +24,14 @@ func (crypter *Crypter) Name() string {
 func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	if len(crypter.SymmetricKey.GetKey()) == 0 {
 		err := crypter.SymmetricKey.Generate()
-		tracelog.ErrorLogger.FatalfOnError("Can't generate symmetric key: %v", err)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't generate symmetric key")
+		}
 
 		err = crypter.SymmetricKey.Encrypt()
-		tracelog.ErrorLogger.FatalfOnError("Can't encrypt symmetric key: %v", err)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't encrypt symmetric key")
+		}
 	}
 
 	bufferedWriter := bufio.NewWriter(writer)
@@ -51,13 +56,19 @@ func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 func (crypter *Crypter) Decrypt(reader io.Reader) (io.Reader, error) {
 	encryptedSymmetricKey := make([]byte, crypter.SymmetricKey.GetEncryptedKeyLen())
 	_, err := reader.Read(encryptedSymmetricKey)
-	tracelog.ErrorLogger.FatalfOnError("Can't read encryption key from archive file header: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read encryption key from archive file header")
+	}
 
 	err = crypter.SymmetricKey.SetEncryptedKey(encryptedSymmetricKey)
-	tracelog.ErrorLogger.FatalfOnError("Can't set encrypted key: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't set encrypted key")
+	}
 
 	err = crypter.SymmetricKey.Decrypt()
-	tracelog.ErrorLogger.FatalfOnError("Can't decrypt symmetric key: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't decrypt symmetric key")
+	}
 
 	return sio.DecryptReader(reader, sio.Config{Key: crypter.SymmetricKey.GetKey()})
 }
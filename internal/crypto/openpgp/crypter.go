@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/wal-g/internal/crypto"
@@ -30,11 +31,24 @@ type Crypter struct {
 	PubKey    openpgp.EntityList
 	SecretKey openpgp.EntityList
 
+	// RequireValidKey makes encryption fail instead of warning when the encryption key is expired
+	RequireValidKey bool
+
+	// DecryptionKeys are tried along with the configured key during decryption
+	DecryptionKeys []DecryptionKey
+
 	loadPassphrase func() (string, bool)
 
 	mutex sync.RWMutex
 }
 
+// DecryptionKey is a secret key file, e.g. of a previous key rotation, to decrypt the files encrypted with it
+type DecryptionKey struct {
+	ArmoredKeyPath string
+	Passphrase     string
+	HasPassphrase  bool
+}
+
 func (crypter *Crypter) Name() string {
 	return "Opengpg/Crypter"
 }
@@ -104,6 +118,10 @@ func (crypter *Crypter) setupPubKey() error {
 
 		crypter.PubKey = entityList
 	}
+	if err := checkKeyExpiry(crypter.PubKey, time.Now(), crypter.RequireValidKey); err != nil {
+		crypter.PubKey = nil
+		return err
+	}
 	return nil
 }
 
@@ -206,5 +224,12 @@ func (crypter *Crypter) loadSecret() error {
 			return errors.WithStack(err)
 		}
 	}
+
+	decryptionKeys, err := loadDecryptionKeys(crypter.DecryptionKeys)
+	if err != nil {
+		crypter.SecretKey = nil
+		return err
+	}
+	crypter.SecretKey = append(crypter.SecretKey, decryptionKeys...)
 	return nil
 }
@@ -6,7 +6,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wal-g/wal-g/internal/crypto"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
 )
 
 var pgpTestPrivateKey string
@@ -68,3 +71,40 @@ func TestEncryptionCycleFromEnv(t *testing.T) {
 func TestEncryptionCycleFromKeyPath(t *testing.T) {
 	EncryptionCycle(t, MockArmedCrypterFromKeyPath())
 }
+
+func newArmoredPrivateKey(t *testing.T) string {
+	entity, err := openpgp.NewEntity("rotated", "", "rotated@example.com", nil)
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	armorWriter, err := armor.Encode(buf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(armorWriter, nil))
+	require.NoError(t, armorWriter.Close())
+	return buf.String()
+}
+
+func TestDecryptWithDecryptionKeys(t *testing.T) {
+	const someSecret = "encrypted before the key rotation"
+	buf := new(bytes.Buffer)
+	encrypt, err := MockArmedCrypterFromKeyPath().Encrypt(buf)
+	require.NoError(t, err)
+	_, err = encrypt.Write([]byte(someSecret))
+	require.NoError(t, err)
+	require.NoError(t, encrypt.Close())
+
+	crypter := CrypterFromKey(newArmoredPrivateKey(t), noPassphrase).(*Crypter)
+	crypter.DecryptionKeys = []DecryptionKey{{ArmoredKeyPath: PrivateKeyFilePath}}
+	decrypt, err := crypter.Decrypt(buf)
+	require.NoError(t, err)
+	decryptedBytes, err := ioutil.ReadAll(decrypt)
+	require.NoError(t, err)
+	assert.Equal(t, someSecret, string(decryptedBytes))
+}
+
+func TestDecryptWithMissingDecryptionKey(t *testing.T) {
+	crypter := CrypterFromKey(newArmoredPrivateKey(t), noPassphrase).(*Crypter)
+	crypter.DecryptionKeys = []DecryptionKey{{ArmoredKeyPath: "./testdata/missing"}}
+	_, err := crypter.Decrypt(new(bytes.Buffer))
+	assert.Error(t, err)
+	assert.Nil(t, crypter.SecretKey)
+}
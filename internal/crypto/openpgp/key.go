@@ -2,10 +2,15 @@ package openpgp
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
 	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
 )
 
 func readKey(path string) (io.Reader, error) {
@@ -55,3 +60,112 @@ func decryptSecretKey(entityList openpgp.EntityList, passphrase string) error {
 
 	return nil
 }
+
+// loadDecryptionKeys reads and decrypts the secret keys, so that they can be combined into one key ring
+func loadDecryptionKeys(decryptionKeys []DecryptionKey) (openpgp.EntityList, error) {
+	var entityList openpgp.EntityList
+	for _, decryptionKey := range decryptionKeys {
+		keyEntityList, err := readPGPKey(decryptionKey.ArmoredKeyPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the decryption key %s", decryptionKey.ArmoredKeyPath)
+		}
+		if decryptionKey.HasPassphrase {
+			err = decryptSecretKey(keyEntityList, decryptionKey.Passphrase)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to decrypt the decryption key %s", decryptionKey.ArmoredKeyPath)
+			}
+		}
+		entityList = append(entityList, keyEntityList...)
+	}
+	return entityList, nil
+}
+
+type ExpiredKeyError struct {
+	error
+}
+
+func newExpiredKeyError(keyKind string, keyID uint64, expiry time.Time) ExpiredKeyError {
+	return ExpiredKeyError{errors.Errorf("PGP %s key %X expired at %s",
+		keyKind, keyID, expiry.Format(time.RFC3339))}
+}
+
+func (err ExpiredKeyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// encryptionKeyExpiry returns the latest expiration time of the keys openpgp.Encrypt
+// may pick for the entity, and false if one of them never expires or there are none
+func encryptionKeyExpiry(entity *openpgp.Entity) (time.Time, bool) {
+	var latestExpiry time.Time
+	hasCandidates := false
+	addCandidate := func(sig *packet.Signature) bool {
+		if sig.KeyLifetimeSecs == nil {
+			return false
+		}
+		expiry := sig.CreationTime.Add(time.Duration(*sig.KeyLifetimeSecs) * time.Second)
+		if expiry.After(latestExpiry) {
+			latestExpiry = expiry
+		}
+		hasCandidates = true
+		return true
+	}
+
+	for _, subkey := range entity.Subkeys {
+		if subkey.Sig.FlagsValid && subkey.Sig.FlagEncryptCommunications && subkey.PublicKey.PubKeyAlgo.CanEncrypt() {
+			if !addCandidate(subkey.Sig) {
+				return time.Time{}, false
+			}
+		}
+	}
+
+	for _, identity := range entity.Identities {
+		selfSignature := identity.SelfSignature
+		if !selfSignature.FlagsValid ||
+			selfSignature.FlagEncryptCommunications && entity.PrimaryKey.PubKeyAlgo.CanEncrypt() {
+			if !addCandidate(selfSignature) {
+				return time.Time{}, false
+			}
+		}
+	}
+	return latestExpiry, hasCandidates
+}
+
+// primaryKeyExpiry returns the expiration time of the primary key, which certifies the other keys
+// of the entity, by the self-signature of the primary identity, and false if it never expires
+func primaryKeyExpiry(entity *openpgp.Entity) (time.Time, bool) {
+	var primaryIdentity *openpgp.Identity
+	for _, identity := range entity.Identities {
+		if primaryIdentity == nil {
+			primaryIdentity = identity
+		}
+		if identity.SelfSignature.IsPrimaryId != nil && *identity.SelfSignature.IsPrimaryId {
+			primaryIdentity = identity
+			break
+		}
+	}
+	if primaryIdentity == nil || primaryIdentity.SelfSignature.KeyLifetimeSecs == nil {
+		return time.Time{}, false
+	}
+	selfSignature := primaryIdentity.SelfSignature
+	return selfSignature.CreationTime.Add(time.Duration(*selfSignature.KeyLifetimeSecs) * time.Second), true
+}
+
+// checkKeyExpiry warns about entities whose primary key is expired, which expires the whole entity,
+// or whose encryption keys are all expired, or fails if requireValidKey is set
+func checkKeyExpiry(entityList openpgp.EntityList, now time.Time, requireValidKey bool) error {
+	for _, entity := range entityList {
+		var err error
+		if expiry, expires := primaryKeyExpiry(entity); expires && now.After(expiry) {
+			err = newExpiredKeyError("primary", entity.PrimaryKey.KeyId, expiry)
+		} else if expiry, expires = encryptionKeyExpiry(entity); expires && now.After(expiry) {
+			err = newExpiredKeyError("encryption", entity.PrimaryKey.KeyId, expiry)
+		} else {
+			continue
+		}
+		if requireValidKey {
+			return err
+		}
+		tracelog.WarningLogger.Print(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package openpgp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+)
+
+func newExpiringEntity(t *testing.T, lifetime time.Duration) *openpgp.Entity {
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	lifetimeSecs := uint32(lifetime.Seconds())
+	for _, subkey := range entity.Subkeys {
+		subkey.Sig.KeyLifetimeSecs = &lifetimeSecs
+	}
+	for _, identity := range entity.Identities {
+		identity.SelfSignature.KeyLifetimeSecs = &lifetimeSecs
+	}
+	return entity
+}
+
+func TestEncryptionKeyExpiry(t *testing.T) {
+	entity := newExpiringEntity(t, time.Hour)
+	expiry, expires := encryptionKeyExpiry(entity)
+	assert.True(t, expires)
+	assert.Equal(t, entity.Subkeys[0].Sig.CreationTime.Add(time.Hour), expiry)
+
+	entity.Subkeys[0].Sig.KeyLifetimeSecs = nil
+	_, expires = encryptionKeyExpiry(entity)
+	assert.False(t, expires)
+}
+
+func TestCheckKeyExpiry(t *testing.T) {
+	entityList := openpgp.EntityList{newExpiringEntity(t, time.Hour)}
+	assert.NoError(t, checkKeyExpiry(entityList, time.Now(), true))
+
+	later := time.Now().Add(2 * time.Hour)
+	assert.NoError(t, checkKeyExpiry(entityList, later, false))
+	err := checkKeyExpiry(entityList, later, true)
+	assert.IsType(t, ExpiredKeyError{}, err)
+}
+
+func TestCheckKeyExpiry_PrimaryKeyExpired(t *testing.T) {
+	entity := newExpiringEntity(t, time.Hour)
+	for _, subkey := range entity.Subkeys {
+		subkey.Sig.KeyLifetimeSecs = nil
+	}
+	entityList := openpgp.EntityList{entity}
+	assert.NoError(t, checkKeyExpiry(entityList, time.Now(), true))
+
+	// the encryption keys which never expire are expired with the primary key
+	err := checkKeyExpiry(entityList, time.Now().Add(2*time.Hour), true)
+	assert.IsType(t, ExpiredKeyError{}, err)
+	assert.Contains(t, err.Error(), "primary key")
+}
+
+func TestCheckKeyExpiry_TestKeyNeverExpires(t *testing.T) {
+	entityList, err := readPGPKey(PrivateKeyFilePath)
+	require.NoError(t, err)
+	assert.NoError(t, checkKeyExpiry(entityList, time.Now().Add(100*365*24*time.Hour), true))
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/minio/sio"
+	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/ioextensions"
@@ -23,7 +24,9 @@ func (crypter *YcCrypter) Name() string {
 func (crypter *YcCrypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	if crypter.symmetricKey.GetKey() == nil {
 		err := crypter.symmetricKey.CreateKey()
-		tracelog.ErrorLogger.FatalfOnError("Can't generate symmetric key: %v", err)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't generate symmetric key")
+		}
 	}
 
 	bufferedWriter := bufio.NewWriter(writer)
@@ -47,20 +50,26 @@ func (crypter *YcCrypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 
 func (crypter *YcCrypter) Decrypt(reader io.Reader) (io.Reader, error) {
 	err := crypter.symmetricKey.ReadEncryptedKey(reader)
-	tracelog.ErrorLogger.FatalfOnError("Can't read encryption key from archive file header: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read encryption key from archive file header")
+	}
 
 	err = crypter.symmetricKey.Decrypt()
-	tracelog.ErrorLogger.FatalfOnError("Can't decrypt data encryption key from archive file header: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't decrypt data encryption key from archive file header")
+	}
 
 	return sio.DecryptReader(reader, sio.Config{Key: crypter.symmetricKey.GetKey(), CipherSuites: []byte{sio.AES_256_GCM}})
 }
 
-func YcCrypterFromKeyIDAndCredential(keyID string, saFilePath string) crypto.Crypter {
+func YcCrypterFromKeyIDAndCredential(keyID string, saFilePath string) (crypto.Crypter, error) {
 	credentials := resolveCredentials(saFilePath)
 	sdk, err := ycsdk.Build(context.Background(), ycsdk.Config{
 		Credentials: credentials,
 	})
-	tracelog.ErrorLogger.FatalfOnError("Can't initialize yc sdk: %v", err)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't initialize yc sdk")
+	}
 
-	return &YcCrypter{symmetricKey: YcSymmetricKeyFromKeyIDAndSdk(keyID, sdk)}
+	return &YcCrypter{symmetricKey: YcSymmetricKeyFromKeyIDAndSdk(keyID, sdk)}, nil
 }
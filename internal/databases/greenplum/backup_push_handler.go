@@ -115,9 +115,9 @@ func (bh *BackupHandler) HandleBackupPush() {
 	}
 
 	err := bh.connect()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	err = bh.createRestorePoint(bh.curBackupInfo.backupName)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	sentinelDto := NewBackupSentinelDto(bh.curBackupInfo)
 	tracelog.InfoLogger.Println("Uploading sentinel file")
@@ -125,7 +125,7 @@ func (bh *BackupHandler) HandleBackupPush() {
 	err = internal.UploadSentinel(bh.workers.Uploader, sentinelDto, bh.curBackupInfo.backupName)
 	if err != nil {
 		tracelog.ErrorLogger.Printf("Failed to upload sentinel file for backup: %s", bh.curBackupInfo.backupName)
-		tracelog.ErrorLogger.FatalError(err)
+		internal.FatalError(err)
 	}
 	tracelog.InfoLogger.Printf("Backup %s successfully created", bh.curBackupInfo.backupName)
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -20,9 +21,35 @@ type BackupInfoMarshalFunc func(b models.Backup) ([]byte, error)
 
 type BackupListing interface {
 	Backups(backups []models.Backup, output io.Writer) error
+	Columns(backups []models.Backup, columns []string, output io.Writer) error
 	Names(backups []internal.BackupTime, output io.Writer) error
 }
 
+// BackupColumnFunc extracts single column value from backup
+type BackupColumnFunc func(b models.Backup) interface{}
+
+// BackupColumns maps column names available for backup listing to its extractors
+var BackupColumns = map[string]BackupColumnFunc{
+	"name":              func(b models.Backup) interface{} { return b.BackupName },
+	"start_local_time":  func(b models.Backup) interface{} { return b.StartLocalTime.Format(time.RFC3339) },
+	"finish_local_time": func(b models.Backup) interface{} { return b.FinishLocalTime.Format(time.RFC3339) },
+	"ts_before":         func(b models.Backup) interface{} { return b.MongoMeta.Before.LastMajTS.String() },
+	"ts_after":          func(b models.Backup) interface{} { return b.MongoMeta.After.LastMajTS.String() },
+	"data_size":         func(b models.Backup) interface{} { return b.DataSize },
+	"permanent":         func(b models.Backup) interface{} { return b.Permanent },
+	"user_data":         func(b models.Backup) interface{} { return b.UserData },
+}
+
+// ValidateBackupColumns checks that all requested columns are known
+func ValidateBackupColumns(columns []string) error {
+	for _, column := range columns {
+		if _, ok := BackupColumns[column]; !ok {
+			return fmt.Errorf("unknown backup column: %q", column)
+		}
+	}
+	return nil
+}
+
 type TabbedBackupListing struct {
 	minwidth int
 	tabwidth int
@@ -72,6 +99,36 @@ func (bl *TabbedBackupListing) Backups(backups []models.Backup, output io.Writer
 	return writer.Flush()
 }
 
+func (bl *TabbedBackupListing) Columns(backups []models.Backup, columns []string, output io.Writer) error {
+	if err := ValidateBackupColumns(columns); err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(output, bl.minwidth, bl.tabwidth, bl.padding, bl.padchar, bl.flags)
+
+	if _, err := fmt.Fprintln(writer, strings.Join(columns, "\t")); err != nil {
+		return err
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			value := BackupColumns[column](backups[i])
+			if column == "user_data" {
+				rawUserData, err := json.Marshal(value)
+				if err != nil {
+					rawUserData = []byte("<marshall_error>")
+				}
+				value = string(rawUserData)
+			}
+			values = append(values, fmt.Sprintf("%v", value))
+		}
+		if _, err := fmt.Fprintln(writer, strings.Join(values, "\t")); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
 func (bl *TabbedBackupListing) Names(backups []internal.BackupTime, output io.Writer) error {
 	writer := tabwriter.NewWriter(output, bl.minwidth, bl.tabwidth, bl.padding, bl.padchar, bl.flags)
 
@@ -90,6 +147,54 @@ func (bl *TabbedBackupListing) Names(backups []internal.BackupTime, output io.Wr
 	return writer.Flush()
 }
 
+// JSONBackupListing prints backups as json array, newest backup goes first
+type JSONBackupListing struct {
+	pretty bool
+}
+
+func NewJSONBackupListing(pretty bool) *JSONBackupListing {
+	return &JSONBackupListing{pretty: pretty}
+}
+
+func (bl *JSONBackupListing) Backups(backups []models.Backup, output io.Writer) error {
+	reversed := make([]models.Backup, 0, len(backups))
+	for i := len(backups) - 1; i >= 0; i-- {
+		reversed = append(reversed, backups[i])
+	}
+	return bl.write(reversed, output)
+}
+
+func (bl *JSONBackupListing) Columns(backups []models.Backup, columns []string, output io.Writer) error {
+	if err := ValidateBackupColumns(columns); err != nil {
+		return err
+	}
+	rows := make([]map[string]interface{}, 0, len(backups))
+	for i := len(backups) - 1; i >= 0; i-- {
+		row := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			row[column] = BackupColumns[column](backups[i])
+		}
+		rows = append(rows, row)
+	}
+	return bl.write(rows, output)
+}
+
+func (bl *JSONBackupListing) Names(backups []internal.BackupTime, output io.Writer) error {
+	reversed := make([]internal.BackupTime, 0, len(backups))
+	for i := len(backups) - 1; i >= 0; i-- {
+		reversed = append(reversed, backups[i])
+	}
+	return bl.write(reversed, output)
+}
+
+func (bl *JSONBackupListing) write(value interface{}, output io.Writer) error {
+	encoder := json.NewEncoder(output)
+	if bl.pretty {
+		encoder.SetIndent("", "    ")
+	}
+	return encoder.Encode(value)
+}
+
 type MongoMetaConstructor struct {
 	ctx       context.Context
 	client    client.MongoDriver
@@ -0,0 +1,45 @@
+package archive
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+var listingBackups = []models.Backup{
+	{
+		BackupName: "stream_20201025T222118Z",
+		MongoMeta:  models.MongoMeta{Before: models.NodeMeta{LastMajTS: models.Timestamp{TS: 1603664478, Inc: 21}}},
+		DataSize:   100,
+	},
+	{
+		BackupName: "stream_20201026T220833Z",
+		MongoMeta:  models.MongoMeta{Before: models.NodeMeta{LastMajTS: models.Timestamp{TS: 1603750113, Inc: 39}}},
+		DataSize:   200,
+		Permanent:  true,
+	},
+}
+
+func TestTabbedBackupListing_Columns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := NewDefaultTabbedBackupListing().Columns(listingBackups, []string{"name", "ts_before", "permanent"}, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "name                    ts_before     permanent\n"+
+		"stream_20201026T220833Z 1603750113.39 true\n"+
+		"stream_20201025T222118Z 1603664478.21 false\n", buf.String())
+}
+
+func TestJSONBackupListing_Columns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := NewJSONBackupListing(false).Columns(listingBackups, []string{"name", "data_size"}, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"data_size":200,"name":"stream_20201026T220833Z"},`+
+		`{"data_size":100,"name":"stream_20201025T222118Z"}]`+"\n", buf.String())
+}
+
+func TestBackupListing_UnknownColumn(t *testing.T) {
+	err := NewDefaultTabbedBackupListing().Columns(listingBackups, []string{"name", "size"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -228,7 +228,7 @@ func (su *StorageUploader) UploadGapArchive(archErr error, firstTS, lastTS model
 		return fmt.Errorf("archErr must not be nil")
 	}
 
-	arch, err := models.NewArchive(firstTS, lastTS, su.Compression().FileExtension(), models.ArchiveTypeGap)
+	arch, err := models.NewArchive(firstTS, lastTS, su.StreamCompression().FileExtension(), models.ArchiveTypeGap)
 	if err != nil {
 		return fmt.Errorf("can not build archive: %w", err)
 	}
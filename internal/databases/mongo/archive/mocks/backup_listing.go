@@ -31,6 +31,20 @@ func (_m *BackupListing) Backups(backups []models.Backup, output io.Writer) erro
 	return r0
 }
 
+// Columns provides a mock function with given fields: backups, columns, output
+func (_m *BackupListing) Columns(backups []models.Backup, columns []string, output io.Writer) error {
+	ret := _m.Called(backups, columns, output)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.Backup, []string, io.Writer) error); ok {
+		r0 = rf(backups, columns, output)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Names provides a mock function with given fields: backups, output
 func (_m *BackupListing) Names(backups []internal.BackupTime, output io.Writer) error {
 	ret := _m.Called(backups, output)
@@ -8,10 +8,16 @@ import (
 )
 
 // HandleBackupsList prints current backups.
+// When columns are given, full backups are loaded and only chosen fields are printed.
 func HandleBackupsList(downloader archive.Downloader,
 	listing archive.BackupListing,
 	output io.Writer,
-	verbose bool) error {
+	verbose bool,
+	columns []string) error {
+	if err := archive.ValidateBackupColumns(columns); err != nil {
+		return err
+	}
+
 	backupTimes, _, err := downloader.ListBackups()
 	if err != nil {
 		return err
@@ -22,7 +28,7 @@ func HandleBackupsList(downloader archive.Downloader,
 		return nil
 	}
 
-	if !verbose {
+	if !verbose && len(columns) == 0 {
 		return listing.Names(backupTimes, output)
 	}
 
@@ -31,5 +37,8 @@ func HandleBackupsList(downloader archive.Downloader,
 		return err
 	}
 
+	if len(columns) > 0 {
+		return listing.Columns(backups, columns, output)
+	}
 	return listing.Backups(backups, output)
 }
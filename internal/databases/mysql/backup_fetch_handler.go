@@ -4,7 +4,6 @@ import (
 	"os/exec"
 
 	"github.com/wal-g/storages/storage"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -15,6 +14,6 @@ func HandleBackupFetch(folder storage.Folder,
 	internal.HandleBackupFetch(folder, targetBackupSelector, internal.GetCommandStreamFetcher(restoreCmd))
 	if prepareCmd != nil {
 		err := prepareCmd.Run()
-		tracelog.ErrorLogger.FatalfOnError("failed to prepare fetched backup: %v", err)
+		internal.FatalfOnError("failed to prepare fetched backup: %v", err)
 	}
 }
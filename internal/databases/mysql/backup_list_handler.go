@@ -9,7 +9,6 @@ import (
 
 	"github.com/jedib0t/go-pretty/table"
 	"github.com/wal-g/storages/storage"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -50,8 +49,8 @@ func NewBackupDetail(backupTime internal.BackupTime, sentinel StreamSentinelDto)
 }
 
 func HandleDetailedBackupList(folder storage.Folder, pretty, json bool) {
-	backupTimes, err := internal.GetBackups(folder)
-	tracelog.ErrorLogger.FatalfOnError("Failed to fetch list of backups in storage: %s", err)
+	backupTimes, err := internal.GetCompleteBackups(folder)
+	internal.FatalfOnError("Failed to fetch list of backups in storage: %s", err)
 
 	backupDetails := make([]BackupDetail, 0, len(backupTimes))
 	for _, backupTime := range backupTimes {
@@ -59,7 +58,7 @@ func HandleDetailedBackupList(folder storage.Folder, pretty, json bool) {
 
 		var sentinel StreamSentinelDto
 		err = backup.FetchSentinel(&sentinel)
-		tracelog.ErrorLogger.FatalfOnError("Failed to load sentinel for backup %s", err)
+		internal.FatalfOnError("Failed to load sentinel for backup %s", err)
 
 		backupDetails = append(backupDetails, NewBackupDetail(backupTime, sentinel))
 	}
@@ -72,7 +71,7 @@ func HandleDetailedBackupList(folder storage.Folder, pretty, json bool) {
 	default:
 		err = writeBackupListDetails(backupDetails, os.Stdout)
 	}
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 // TODO : unit tests
@@ -14,22 +14,22 @@ func HandleBackupPush(uploader *internal.Uploader, backupCmd *exec.Cmd, isPerman
 	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
 
 	db, err := getMySQLConnection()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer utility.LoggedClose(db, "")
 
 	binlogStart := getMySQLCurrentBinlogFile(db)
 	timeStart := utility.TimeNowCrossPlatformLocal()
 
 	stdout, stderr, err := utility.StartCommandWithStdoutStderr(backupCmd)
-	tracelog.ErrorLogger.FatalfOnError("failed to start backup create command: %v", err)
+	internal.FatalfOnError("failed to start backup create command: %v", err)
 
 	fileName, err := uploader.PushStream(limiters.NewDiskLimitReader(stdout))
-	tracelog.ErrorLogger.FatalfOnError("failed to push backup: %v", err)
+	internal.FatalfOnError("failed to push backup: %v", err)
 
 	err = backupCmd.Wait()
 	if err != nil {
 		tracelog.ErrorLogger.Printf("Backup command output:\n%s", stderr.String())
-		tracelog.ErrorLogger.Fatalf("backup create command failed: %v", err)
+		internal.Fatalf("backup create command failed: %v", err)
 	}
 
 	binlogEnd := getMySQLCurrentBinlogFile(db)
@@ -64,5 +64,5 @@ func HandleBackupPush(uploader *internal.Uploader, backupCmd *exec.Cmd, isPerman
 	tracelog.InfoLogger.Printf("Backup sentinel: %s", sentinel.String())
 
 	err = internal.UploadSentinel(uploader, &sentinel, fileName)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
@@ -43,17 +43,17 @@ func (ih *indexHandler) createIndexFile() error {
 
 func HandleBinlogFetch(folder storage.Folder, backupName string, untilTS string) {
 	dstDir, err := internal.GetLogsDstSettings(internal.MysqlBinlogDstSetting)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	startTS, endTS, err := getTimestamps(folder, backupName, untilTS)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	handler := newIndexHandler(dstDir)
 
 	tracelog.InfoLogger.Printf("Fetching binlogs since %s until %s", startTS, endTS)
 	err = fetchLogs(folder, dstDir, startTS, endTS, handler)
-	tracelog.ErrorLogger.FatalfOnError("Failed to fetch binlogs: %v", err)
+	internal.FatalfOnError("Failed to fetch binlogs: %v", err)
 
 	err = handler.createIndexFile()
-	tracelog.ErrorLogger.FatalfOnError("Failed to create binlog index file: %v", err)
+	internal.FatalfOnError("Failed to create binlog index file: %v", err)
 }
@@ -26,18 +26,18 @@ func HandleBinlogPush(uploader *internal.Uploader, untilBinlog string) {
 	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(BinlogPath)
 
 	db, err := getMySQLConnection()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer utility.LoggedClose(db, "")
 
 	binlogsFolder, err := getMySQLBinlogsFolder(db)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	binlogs, err := getMySQLSortedBinlogs(db, untilBinlog)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	for _, binLog := range binlogs {
 		err = tryArchiveBinLog(uploader, path.Join(binlogsFolder, binLog), binLog)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	}
 }
 
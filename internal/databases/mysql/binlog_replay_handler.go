@@ -74,19 +74,19 @@ func (rh *replayHandler) handleBinlog(binlogPath string) error {
 
 func HandleBinlogReplay(folder storage.Folder, backupName string, untilTS string) {
 	dstDir, err := internal.GetLogsDstSettings(internal.MysqlBinlogDstSetting)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	startTS, endTS, err := getTimestamps(folder, backupName, untilTS)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	handler := newReplayHandler(endTS)
 
 	tracelog.InfoLogger.Printf("Fetching binlogs since %s until %s", startTS, endTS)
 	err = fetchLogs(folder, dstDir, startTS, endTS, handler)
-	tracelog.ErrorLogger.FatalfOnError("Failed to fetch binlogs: %v", err)
+	internal.FatalfOnError("Failed to fetch binlogs: %v", err)
 
 	err = handler.wait()
-	tracelog.ErrorLogger.FatalfOnError("Failed to apply binlogs: %v", err)
+	internal.FatalfOnError("Failed to apply binlogs: %v", err)
 }
 
 func getTimestamps(folder storage.Folder, backupName, untilTS string) (time.Time, time.Time, error) {
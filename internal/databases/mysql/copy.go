@@ -19,7 +19,7 @@ func HandleCopyBackup(fromConfigFile, toConfigFile, backupName, prefix string) {
 		return
 	}
 	infos, err := backupCopyingInfo(backupName, prefix, from, to)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.DebugLogger.Printf("copying files %s\n", strings.Join(func() []string {
 		ret := make([]string, 0)
@@ -30,7 +30,7 @@ func HandleCopyBackup(fromConfigFile, toConfigFile, backupName, prefix string) {
 		return ret
 	}(), ","))
 
-	tracelog.ErrorLogger.FatalOnError(copy.Infos(infos))
+	internal.FatalOnError(copy.Infos(infos))
 
 	tracelog.InfoLogger.Printf("Success copyed backup %s.\n", backupName)
 }
@@ -43,9 +43,9 @@ func HandleCopyAll(fromConfigFile string, toConfigFile string) {
 		return
 	}
 	infos, err := WildcardInfo(from, to)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	err = copy.Infos(infos)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	tracelog.InfoLogger.Printf("Success copyed all backups\n")
 }
 
@@ -25,42 +25,42 @@ const TimeMysqlFormat = "2006-01-02 15:04:05"
 
 func isMaster(db *sql.DB) bool {
 	rows, err := db.Query("SHOW SLAVE STATUS")
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer utility.LoggedClose(rows, "")
 	return !rows.Next()
 }
 
 func getMySQLCurrentBinlogFileLocal(db *sql.DB) (fileName string) {
 	rows, err := db.Query("SHOW MASTER STATUS")
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer utility.LoggedClose(rows, "")
 	var logFileName string
 	for rows.Next() {
 		err = utility.ScanToMap(rows, map[string]interface{}{"File": &logFileName})
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		return logFileName
 	}
-	tracelog.ErrorLogger.Fatalf("Failed to obtain current binlog file")
+	internal.Fatalf("Failed to obtain current binlog file")
 	return ""
 }
 
 func getMySQLCurrentBinlogFileFromMaster(db *sql.DB) (fileName string) {
 	rows, err := db.Query("SHOW SLAVE STATUS")
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer utility.LoggedClose(rows, "")
 	var logFileName string
 	for rows.Next() {
 		err = utility.ScanToMap(rows, map[string]interface{}{"Relay_Master_Log_File": &logFileName})
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		return logFileName
 	}
-	tracelog.ErrorLogger.Fatalf("Failed to obtain master's current binlog file")
+	internal.Fatalf("Failed to obtain master's current binlog file")
 	return ""
 }
 
 func getMySQLCurrentBinlogFile(db *sql.DB) (fileName string) {
 	takeFromMaster, err := internal.GetBoolSettingDefault(internal.MysqlTakeBinlogsFromMaster, false)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	if takeFromMaster && !isMaster(db) {
 		return getMySQLCurrentBinlogFileFromMaster(db)
 	}
@@ -26,14 +26,18 @@ var UtilityFilePaths = map[string]bool{
 	TablespaceMapFilename: true,
 }
 
-var patternPgBackupName = fmt.Sprintf("base_%[1]s(_D_%[1]s)?", PatternTimelineAndLogSegNo)
+var patternPgBackupName = fmt.Sprintf(utility.PatternBackupNamePrefix+"%[1]s(_D_%[1]s)?", PatternTimelineAndLogSegNo)
 var regexpPgBackupName = regexp.MustCompile(patternPgBackupName)
+var regexpPgBackupNameIgnoreCase = regexp.MustCompile("(?i)" + patternPgBackupName)
+var regexpPgBackupNameWhole = regexp.MustCompile("^" + patternPgBackupName + "$")
 
 // Backup contains information about a valid Postgres backup
 // generated and uploaded by WAL-G.
 type Backup struct {
 	internal.Backup
 	SentinelDto *BackupSentinelDto // used for storage query caching
+	// chainDownload has the tar partitions downloaded ahead with the whole delta chain, if set
+	chainDownload *ChainDownload
 }
 
 func ToPgBackup(source internal.Backup) (output Backup) {
@@ -52,12 +56,21 @@ func (backup *Backup) getTarPartitionFolder() storage.Folder {
 	return backup.Folder.GetSubFolder(backup.Name + internal.TarPartitionFolderName)
 }
 
+func (backup *Backup) newTarPartitionReaderMaker(tarName string) internal.ReaderMaker {
+	if backup.chainDownload != nil {
+		if readerMaker := backup.chainDownload.readerMaker(backup.Name, tarName); readerMaker != nil {
+			return readerMaker
+		}
+	}
+	return internal.NewTarPartitionReaderMaker(backup.Folder, backup.getTarPartitionFolder(), tarName)
+}
+
 func GetBackupByName(backupName, subfolder string, folder storage.Folder) (Backup, error) {
 	defaultBackup, err := internal.GetBackupByName(backupName, subfolder, folder)
 	if err != nil {
 		return Backup{}, err
 	}
-	backup := Backup{defaultBackup, nil}
+	backup := Backup{Backup: defaultBackup}
 
 	_, err = backup.GetSentinel()
 	if err != nil {
@@ -79,6 +92,20 @@ func (backup *Backup) GetTarNames() ([]string, error) {
 	return result, nil
 }
 
+// GetTarSizes returns the storage size of every tar partition of the backup
+func (backup *Backup) GetTarSizes() (map[string]int64, error) {
+	tarPartitionFolder := backup.getTarPartitionFolder()
+	objects, _, err := tarPartitionFolder.ListFolder()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list tar partitions of backup '%s'", backup.Name)
+	}
+	result := make(map[string]int64, len(objects))
+	for _, object := range objects {
+		result[object.GetName()] = object.GetSize()
+	}
+	return result, nil
+}
+
 func (backup *Backup) GetSentinel() (BackupSentinelDto, error) {
 	if backup.SentinelDto != nil {
 		return *backup.SentinelDto, nil
@@ -103,8 +130,10 @@ func (backup *Backup) FetchMeta() (ExtendedMetadataDto, error) {
 	return extendedMetadataDto, nil
 }
 
-func checkDBDirectoryForUnwrap(dbDataDirectory string, sentinelDto BackupSentinelDto) error {
-	if !sentinelDto.IsIncremental() {
+func checkDBDirectoryForUnwrap(dbDataDirectory string, sentinelDto BackupSentinelDto, progress *FetchProgress) error {
+	if progress.IsResumed() {
+		tracelog.InfoLogger.Printf("Resuming fetch into non-empty directory %s\n", dbDataDirectory)
+	} else if !sentinelDto.IsIncremental() {
 		isEmpty, err := isDirectoryEmpty(dbDataDirectory)
 		if err != nil {
 			return err
@@ -153,8 +182,9 @@ func setTablespacePaths(spec TablespaceSpec) error {
 		if err != nil {
 			return fmt.Errorf("error creating folder for tablespace %v", err)
 		}
-		err = os.Symlink(location.Location, filepath.Join(basePrefix, location.Symlink))
-		if err != nil {
+		symlinkPath := filepath.Join(basePrefix, location.Symlink)
+		err = os.Symlink(location.Location, symlinkPath)
+		if err != nil && !isExistingSymlink(symlinkPath, location.Location) {
 			return fmt.Errorf("error creating tablespace symkink %v", err)
 		}
 	}
@@ -164,21 +194,27 @@ func setTablespacePaths(spec TablespaceSpec) error {
 // check that directory is empty before unwrap
 func (backup *Backup) unwrapToEmptyDirectory(
 	dbDataDirectory string, sentinelDto BackupSentinelDto, filesToUnwrap map[string]bool, createIncrementalFiles bool,
+	progress *FetchProgress, checksumVerifier *FetchChecksumVerifier,
 ) error {
-	err := checkDBDirectoryForUnwrap(dbDataDirectory, sentinelDto)
+	err := checkDBDirectoryForUnwrap(dbDataDirectory, sentinelDto, progress)
 	if err != nil {
 		return err
 	}
 
-	return backup.unwrapOld(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles)
+	return backup.unwrapOld(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles, progress, checksumVerifier)
 }
 
 // TODO : unit tests
-// Do the job of unpacking Backup object
+// Do the job of unpacking Backup object.
+// If progress is not nil, the extracted tar partitions are recorded
+// in the progress marker and the already extracted ones are skipped.
+// If checksumVerifier is not nil, the extracted files are checked against the recorded checksums.
 func (backup *Backup) unwrapOld(
 	dbDataDirectory string, sentinelDto BackupSentinelDto, filesToUnwrap map[string]bool, createIncrementalFiles bool,
+	progress *FetchProgress, checksumVerifier *FetchChecksumVerifier,
 ) error {
 	tarInterpreter := NewFileTarInterpreter(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles)
+	tarInterpreter.checksumVerifier = checksumVerifier
 	tarsToExtract, pgControlKey, err := backup.getTarsToExtract(sentinelDto, filesToUnwrap, false)
 	if err != nil {
 		return err
@@ -191,6 +227,10 @@ func (backup *Backup) unwrapOld(
 		return newPgControlNotFoundError()
 	}
 
+	if progress != nil {
+		return backup.unwrapWithProgress(tarInterpreter, tarsToExtract, pgControlKey, needPgControl, progress)
+	}
+
 	err = internal.ExtractAll(tarInterpreter, tarsToExtract)
 	if err != nil {
 		return err
@@ -198,7 +238,38 @@ func (backup *Backup) unwrapOld(
 
 	if needPgControl {
 		err = internal.ExtractAll(tarInterpreter, []internal.ReaderMaker{
-			internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), pgControlKey)})
+			backup.newTarPartitionReaderMaker(pgControlKey)})
+		if err != nil {
+			return errors.Wrap(err, "failed to extract pg_control")
+		}
+	}
+
+	tracelog.InfoLogger.Print("\nBackup extraction complete.\n")
+	return nil
+}
+
+func (backup *Backup) unwrapWithProgress(tarInterpreter internal.TarInterpreter, tarsToExtract []internal.ReaderMaker,
+	pgControlKey string, needPgControl bool, progress *FetchProgress) error {
+	partitionSizes, err := backup.GetTarSizes()
+	if err != nil {
+		return err
+	}
+	tarsToExtract, err = progress.filterExtracted(backup.Name, partitionSizes, tarsToExtract)
+	if err != nil {
+		return err
+	}
+	onExtracted := func(tarToExtract internal.ReaderMaker) {
+		progress.markExtracted(backup.Name, tarToExtract.Path())
+	}
+
+	err = internal.ExtractAllWithProgress(tarInterpreter, tarsToExtract, onExtracted)
+	if err != nil {
+		return err
+	}
+
+	if needPgControl && !progress.isExtracted(backup.Name, pgControlKey) {
+		err = internal.ExtractAllWithProgress(tarInterpreter, []internal.ReaderMaker{
+			backup.newTarPartitionReaderMaker(pgControlKey)}, onExtracted)
 		if err != nil {
 			return errors.Wrap(err, "failed to extract pg_control")
 		}
@@ -258,7 +329,7 @@ func (backup *Backup) getTarsToExtract(sentinelDto BackupSentinelDto, filesToUnw
 			continue
 		}
 
-		tarToExtract := internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), tarName)
+		tarToExtract := backup.newTarPartitionReaderMaker(tarName)
 		tarsToExtract = append(tarsToExtract, tarToExtract)
 	}
 	return tarsToExtract, pgControlKey, nil
@@ -307,13 +378,18 @@ func GetLastWalFilename(backup Backup) (string, error) {
 	}
 	timelineID, err := ParseTimelineFromBackupName(backup.Name)
 	if err != nil {
-		tracelog.InfoLogger.FatalError(err)
+		internal.FatalError(err)
 		return "", err
 	}
 	endWalSegmentNo := newWalSegmentNo(meta.FinishLsn - 1)
 	return endWalSegmentNo.getFilename(timelineID), nil
 }
 
+// FetchPgBackupName returns the backup name part of the object key.
+// The name isn't normalized, so it can be used to access the backup objects.
 func FetchPgBackupName(object storage.Object) string {
+	if utility.IsObjectKeyNormalizationEnabled() {
+		return regexpPgBackupNameIgnoreCase.FindString(object.GetName())
+	}
 	return regexpPgBackupName.FindString(object.GetName())
 }
@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	LiveDiffSizeReason     = "size"
+	LiveDiffChecksumReason = "checksum"
+)
+
+// liveCompareExcludedFilenames are the files expected to differ between the live cluster and the backup
+// on top of the ExcludedFilenames, which are never backed up
+var liveCompareExcludedFilenames = map[string]bool{
+	"pg_stat":              true,
+	BackupLabelFilename:    true,
+	TablespaceMapFilename:  true,
+	"backup_label.old":     true,
+	BackupManifestFilename: true,
+}
+
+type LiveFileDiff struct {
+	Path       string `json:"path"`
+	Change     string `json:"change"`
+	Reason     string `json:"reason,omitempty"`
+	BackupSize *int64 `json:"backup_size,omitempty"`
+	LiveSize   *int64 `json:"live_size,omitempty"`
+}
+
+type LiveCompareResult struct {
+	Backup    string `json:"backup"`
+	Directory string `json:"directory"`
+	// ComparedFiles are the files present in both, which size is recorded with WALG_BACKUP_FILE_CHECKSUMS,
+	// the presence is the only check for the UncomparedFiles
+	ComparedFiles   int            `json:"compared_files"`
+	UncomparedFiles int            `json:"uncompared_files"`
+	Files           []LiveFileDiff `json:"files"`
+}
+
+// isExcludedFromLiveCompare reports whether the file of the backup file list format (/base/1/1259)
+// is expected to differ between the live cluster and the backup
+func isExcludedFromLiveCompare(fileName string) bool {
+	if fileName == PgControlPath {
+		return true
+	}
+	for _, component := range strings.Split(strings.TrimPrefix(fileName, utility.PathSeparator), utility.PathSeparator) {
+		if _, excluded := ExcludedFilenames[component]; excluded || liveCompareExcludedFilenames[component] {
+			return true
+		}
+	}
+	return false
+}
+
+// liveFilesComposerMaker makes the composer used by backup-compare-live
+type liveFilesComposerMaker struct{}
+
+func (maker liveFilesComposerMaker) Make(bundle *Bundle) (TarBallComposer, error) {
+	return &liveFilesComposer{files: make(map[string]os.FileInfo), bundleFiles: &RegularBundleFiles{}}, nil
+}
+
+// liveFilesComposer collects the regular files of the bundle by the name instead of packing them
+type liveFilesComposer struct {
+	files       map[string]os.FileInfo
+	bundleFiles *RegularBundleFiles
+}
+
+func (c *liveFilesComposer) AddFile(info *ComposeFileInfo) {
+	c.files[info.header.Name] = info.fileInfo
+	c.bundleFiles.AddFile(info.header, info.fileInfo, false)
+}
+
+func (c *liveFilesComposer) AddHeader(header *tar.Header, fileInfo os.FileInfo) error {
+	c.bundleFiles.AddFile(header, fileInfo, false)
+	return nil
+}
+
+func (c *liveFilesComposer) SkipFile(tarHeader *tar.Header, fileInfo os.FileInfo) {
+	c.bundleFiles.AddSkippedFile(tarHeader, fileInfo)
+}
+
+func (c *liveFilesComposer) PackTarballs() (TarFileSets, error) {
+	return make(TarFileSets), nil
+}
+
+func (c *liveFilesComposer) GetFiles() BundleFiles {
+	return c.bundleFiles
+}
+
+// walkLiveFiles returns the regular files of the data directory by the name in the backup file list format.
+// The data directory is walked the way backup-push does, with the WALG_BACKUP_EXCLUDED_FILES
+// and the WALG_SYMLINK_POLICY, so the same files are found as would be backed up.
+func walkLiveFiles(dbDataDirectory string) (map[string]os.FileInfo, error) {
+	symlinkPolicy, err := ParseSymlinkPolicy(viper.GetString(internal.SymlinkPolicySetting))
+	if err != nil {
+		return nil, err
+	}
+	err = configureExcludedFilenames()
+	if err != nil {
+		return nil, err
+	}
+	bundle := NewBundle(dbDataDirectory, nil, nil, nil, false, 0)
+	bundle.SymlinkPolicy = symlinkPolicy
+	err = bundle.SetupComposer(liveFilesComposerMaker{})
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.Walk(dbDataDirectory, bundle.HandleWalkedFSObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "walkLiveFiles: walk failed")
+	}
+	files := bundle.TarBallComposer.(*liveFilesComposer).files
+	for fileName := range files {
+		if isExcludedFromLiveCompare(fileName) {
+			delete(files, fileName)
+		}
+	}
+	return files, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer utility.LoggedClose(file, "")
+	fileHash := sha256.New()
+	if _, err = io.Copy(fileHash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(fileHash.Sum(nil)), nil
+}
+
+// CompareBackupWithLive compares the files of the data directory with the file list of the backup.
+// The files are compared by the presence, and by the size and optionally the SHA256 checksum
+// if they are recorded with WALG_BACKUP_FILE_CHECKSUMS. The result is sorted by path.
+func CompareBackupWithLive(baseBackupFolder storage.Folder, backupName, dbDataDirectory string,
+	compareChecksums bool) (LiveCompareResult, error) {
+	result := LiveCompareResult{Backup: backupName, Directory: dbDataDirectory, Files: make([]LiveFileDiff, 0)}
+	chain, err := getDeltaChainSentinels(baseBackupFolder, backupName)
+	if err != nil {
+		return result, err
+	}
+	if chain[0].Files == nil {
+		return result, errors.Errorf("the file list is not recorded in the sentinel of backup %s", backupName)
+	}
+	liveFiles, err := walkLiveFiles(dbDataDirectory)
+	if err != nil {
+		return result, err
+	}
+
+	for fileName := range chain[0].Files {
+		if isExcludedFromLiveCompare(fileName) {
+			continue
+		}
+		info, ok := liveFiles[fileName]
+		if !ok {
+			result.Files = append(result.Files, LiveFileDiff{Path: fileName, Change: FileRemoved})
+			continue
+		}
+		description, ok := findRecordedFileDescription(chain, fileName)
+		if !ok || description.SHA256 == "" {
+			result.UncomparedFiles++
+			continue
+		}
+		result.ComparedFiles++
+		backupSize, liveSize := description.Size, info.Size()
+		if backupSize != liveSize {
+			result.Files = append(result.Files, LiveFileDiff{Path: fileName, Change: FileChanged,
+				Reason: LiveDiffSizeReason, BackupSize: &backupSize, LiveSize: &liveSize})
+			continue
+		}
+		if compareChecksums {
+			checksum, err := fileSHA256(filepath.Join(dbDataDirectory, strings.TrimPrefix(fileName, "/")))
+			if err != nil {
+				return result, err
+			}
+			if checksum != description.SHA256 {
+				result.Files = append(result.Files, LiveFileDiff{Path: fileName, Change: FileChanged,
+					Reason: LiveDiffChecksumReason, BackupSize: &backupSize, LiveSize: &liveSize})
+			}
+		}
+	}
+	for fileName, info := range liveFiles {
+		if _, ok := chain[0].Files[fileName]; !ok {
+			liveSize := info.Size()
+			result.Files = append(result.Files, LiveFileDiff{Path: fileName, Change: FileAdded, LiveSize: &liveSize})
+		}
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Path < result.Files[j].Path
+	})
+	return result, nil
+}
+
+// HandleBackupCompareLive prints the files of the data directory added, removed or changed since the backup
+func HandleBackupCompareLive(folder storage.Folder, backupName, dbDataDirectory string, compareChecksums bool,
+	output io.Writer, jsonOutput bool) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	internal.FatalOnError(err)
+	result, err := CompareBackupWithLive(folder.GetSubFolder(utility.BaseBackupPath), backup.Name,
+		utility.ResolveSymlink(dbDataDirectory), compareChecksums)
+	internal.FatalfOnError("Failed to compare the backup with the data directory: %v\n", err)
+	if result.ComparedFiles == 0 {
+		tracelog.WarningLogger.Printf("No file sizes are recorded, only the presence of the files is compared. "+
+			"Was the backup made with %s?\n", internal.BackupFileChecksumsSetting)
+	}
+	if jsonOutput {
+		err = internal.WriteAsJSON(result, output, true)
+		internal.FatalOnError(err)
+		return
+	}
+	writeLiveCompareTable(result, output)
+}
+
+func writeLiveCompareTable(result LiveCompareResult, output io.Writer) {
+	writer := table.NewWriter()
+	writer.SetOutputMirror(output)
+	writer.AppendHeader(table.Row{"Change", "Path", "Reason", "Backup size", "Live size"})
+	for _, fileDiff := range result.Files {
+		writer.AppendRow(table.Row{fileDiff.Change, fileDiff.Path, fileDiff.Reason,
+			formatOptionalSize(fileDiff.BackupSize), formatOptionalSize(fileDiff.LiveSize)})
+	}
+	writer.AppendFooter(table.Row{"", fmt.Sprintf("%d files differ, %d compared by size, %d by presence only",
+		len(result.Files), result.ComparedFiles, result.UncomparedFiles)})
+	writer.Render()
+}
+
+func formatOptionalSize(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *value)
+}
@@ -0,0 +1,107 @@
+package postgres_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func writeLiveFile(t *testing.T, root, name, content string) {
+	path := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func recordedDescription(content string) internal.BackupFileDescription {
+	checksum := sha256.Sum256([]byte(content))
+	return internal.BackupFileDescription{SHA256: hex.EncodeToString(checksum[:]), Size: int64(len(content))}
+}
+
+func TestCompareBackupWithLive(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "compare_live")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	tablespaceDir, err := ioutil.TempDir("", "compare_live_tablespace")
+	require.NoError(t, err)
+	defer os.RemoveAll(tablespaceDir)
+
+	writeLiveFile(t, dataDir, "base/1/1", "same")
+	writeLiveFile(t, dataDir, "base/1/2", "grown content")
+	writeLiveFile(t, dataDir, "base/1/3", "diff")
+	writeLiveFile(t, dataDir, "base/1/5", "new")
+	writeLiveFile(t, dataDir, "base/1/6", "unrecorded")
+	writeLiveFile(t, dataDir, "postmaster.pid", "123")
+	writeLiveFile(t, dataDir, "pg_stat/global.stat", "stats")
+	writeLiveFile(t, dataDir, "pg_wal/000000010000000000000002", "wal")
+	writeLiveFile(t, dataDir, "global/pg_control", "control")
+	writeLiveFile(t, tablespaceDir, "PG_13_202007201/1/7", "tablespace")
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "pg_tblspc"), 0700))
+	require.NoError(t, os.Symlink(tablespaceDir, filepath.Join(dataDir, "pg_tblspc", "16384")))
+
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	backupName := "base_000000010000000000000002"
+	sentinelDto := postgres.BackupSentinelDto{Files: internal.BackupFileList{
+		"/base/1/1":                            recordedDescription("same"),
+		"/base/1/2":                            recordedDescription("content"),
+		"/base/1/3":                            recordedDescription("same"),
+		"/base/1/4":                            recordedDescription("removed"),
+		"/base/1/6":                            {},
+		"/pg_stat/global.stat":                 recordedDescription("old stats"),
+		"/pg_tblspc/16384/PG_13_202007201/1/7": recordedDescription("tablespace"),
+	}}
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder), &sentinelDto, backupName))
+
+	result, err := postgres.CompareBackupWithLive(baseBackupFolder, backupName, dataDir, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.ComparedFiles)
+	assert.Equal(t, 1, result.UncomparedFiles)
+	require.Len(t, result.Files, 3)
+	assert.Equal(t, postgres.LiveFileDiff{Path: "/base/1/2", Change: postgres.FileChanged,
+		Reason: postgres.LiveDiffSizeReason, BackupSize: &[]int64{7}[0], LiveSize: &[]int64{13}[0]}, result.Files[0])
+	assert.Equal(t, "/base/1/4", result.Files[1].Path)
+	assert.Equal(t, postgres.FileRemoved, result.Files[1].Change)
+	assert.Equal(t, "/base/1/5", result.Files[2].Path)
+	assert.Equal(t, postgres.FileAdded, result.Files[2].Change)
+
+	result, err = postgres.CompareBackupWithLive(baseBackupFolder, backupName, dataDir, true)
+	require.NoError(t, err)
+	require.Len(t, result.Files, 4)
+	assert.Equal(t, "/base/1/3", result.Files[1].Path)
+	assert.Equal(t, postgres.LiveDiffChecksumReason, result.Files[1].Reason)
+}
+
+func TestCompareBackupWithLive_ExcludedFiles(t *testing.T) {
+	viper.Set(internal.BackupExcludedFilesSetting, "log")
+	defer viper.Set(internal.BackupExcludedFilesSetting, nil)
+	defer postgres.SetExcludedFilenames(postgres.DefaultExcludedFilenames)
+	dataDir, err := ioutil.TempDir("", "compare_live")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	writeLiveFile(t, dataDir, "base/1/1", "same")
+	writeLiveFile(t, dataDir, "log/postgresql.log", "excluded")
+
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	backupName := "base_000000010000000000000002"
+	sentinelDto := postgres.BackupSentinelDto{Files: internal.BackupFileList{
+		"/base/1/1": recordedDescription("same"),
+	}}
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder), &sentinelDto, backupName))
+
+	// the files excluded by WALG_BACKUP_EXCLUDED_FILES are not reported as added
+	result, err := postgres.CompareBackupWithLive(baseBackupFolder, backupName, dataDir, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ComparedFiles)
+	assert.Empty(t, result.Files)
+}
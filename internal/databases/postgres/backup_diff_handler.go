@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	FileAdded   = "added"
+	FileRemoved = "removed"
+	FileChanged = "changed"
+)
+
+// the reasons the file is considered changed
+const (
+	FileSizeChanged     = "size"
+	FileChecksumChanged = "checksum"
+	FileMTimeChanged    = "mtime"
+)
+
+type BackupFileDiff struct {
+	Path     string     `json:"path"`
+	Change   string     `json:"change"`
+	Reason   string     `json:"reason,omitempty"`
+	OldMTime *time.Time `json:"old_mtime,omitempty"`
+	NewMTime *time.Time `json:"new_mtime,omitempty"`
+}
+
+type BackupDiff struct {
+	From  string           `json:"from"`
+	To    string           `json:"to"`
+	Files []BackupFileDiff `json:"files"`
+}
+
+// GetEffectiveBackupFiles returns the file list of the backup. Files skipped by the delta backup
+// are resolved through the delta chain to the description from the backup which actually stores them.
+func GetEffectiveBackupFiles(backup Backup) (internal.BackupFileList, error) {
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return nil, err
+	}
+	files := make(internal.BackupFileList, len(sentinelDto.Files))
+	unresolved := make(map[string]bool)
+	for name, description := range sentinelDto.Files {
+		if description.IsSkipped {
+			unresolved[name] = true
+		} else {
+			files[name] = description
+		}
+	}
+
+	current := sentinelDto
+	for len(unresolved) > 0 && current.IncrementFrom != nil {
+		baseBackup := NewBackup(backup.Folder, *current.IncrementFrom)
+		current, err = baseBackup.GetSentinel()
+		if err != nil {
+			return nil, err
+		}
+		for name := range unresolved {
+			if description, ok := current.Files[name]; ok && !description.IsSkipped {
+				files[name] = description
+				delete(unresolved, name)
+			}
+		}
+	}
+	for name := range unresolved {
+		files[name] = sentinelDto.Files[name]
+	}
+	return files, nil
+}
+
+// DiffBackupFiles compares the file lists of two backups. Files are considered changed
+// if their size or checksum, recorded in both backups with WALG_BACKUP_FILE_CHECKSUMS, differs,
+// or if their modification time differs. The result is sorted by path.
+func DiffBackupFiles(fromFiles, toFiles internal.BackupFileList) []BackupFileDiff {
+	diffs := make([]BackupFileDiff, 0)
+	for name, fromDescription := range fromFiles {
+		oldMTime := fromDescription.MTime
+		toDescription, ok := toFiles[name]
+		if !ok {
+			diffs = append(diffs, BackupFileDiff{Path: name, Change: FileRemoved, OldMTime: &oldMTime})
+			continue
+		}
+		if reason := getFileChangeReason(fromDescription, toDescription); reason != "" {
+			newMTime := toDescription.MTime
+			diffs = append(diffs, BackupFileDiff{Path: name, Change: FileChanged, Reason: reason,
+				OldMTime: &oldMTime, NewMTime: &newMTime})
+		}
+	}
+	for name, toDescription := range toFiles {
+		if _, ok := fromFiles[name]; !ok {
+			newMTime := toDescription.MTime
+			diffs = append(diffs, BackupFileDiff{Path: name, Change: FileAdded, NewMTime: &newMTime})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs
+}
+
+// getFileChangeReason tells why the file is considered changed, it is empty if the file is not changed.
+// The size and the checksum are compared only if both backups recorded them.
+func getFileChangeReason(from, to internal.BackupFileDescription) string {
+	if from.SHA256 != "" && to.SHA256 != "" {
+		if from.Size != to.Size {
+			return FileSizeChanged
+		}
+		if from.SHA256 != to.SHA256 {
+			return FileChecksumChanged
+		}
+	}
+	if !to.MTime.Equal(from.MTime) {
+		return FileMTimeChanged
+	}
+	return ""
+}
+
+func GetBackupDiff(folder storage.Folder, fromName, toName string) (BackupDiff, error) {
+	fromFiles, fromName, err := getEffectiveBackupFilesByName(folder, fromName)
+	if err != nil {
+		return BackupDiff{}, err
+	}
+	toFiles, toName, err := getEffectiveBackupFilesByName(folder, toName)
+	if err != nil {
+		return BackupDiff{}, err
+	}
+	return BackupDiff{From: fromName, To: toName, Files: DiffBackupFiles(fromFiles, toFiles)}, nil
+}
+
+func getEffectiveBackupFilesByName(folder storage.Folder, backupName string) (internal.BackupFileList, string, error) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	if err != nil {
+		return nil, "", err
+	}
+	files, err := GetEffectiveBackupFiles(ToPgBackup(backup))
+	return files, backup.Name, err
+}
+
+// HandleBackupDiff prints the files added, removed or changed between two backups
+func HandleBackupDiff(folder storage.Folder, fromName, toName string, output io.Writer, jsonOutput bool) {
+	diff, err := GetBackupDiff(folder, fromName, toName)
+	internal.FatalfOnError("Failed to compare backups: %v\n", err)
+	if jsonOutput {
+		err = internal.WriteAsJSON(diff, output, true)
+		internal.FatalOnError(err)
+		return
+	}
+	writeBackupDiffTable(diff, output)
+}
+
+func writeBackupDiffTable(diff BackupDiff, output io.Writer) {
+	writer := table.NewWriter()
+	writer.SetOutputMirror(output)
+	writer.AppendHeader(table.Row{"Change", "Path", "Reason", diff.From + " modified", diff.To + " modified"})
+	for _, fileDiff := range diff.Files {
+		writer.AppendRow(table.Row{fileDiff.Change, fileDiff.Path, fileDiff.Reason,
+			formatOptionalTime(fileDiff.OldMTime), formatOptionalTime(fileDiff.NewMTime)})
+	}
+	writer.AppendFooter(table.Row{"", fmt.Sprintf("%d files differ", len(diff.Files))})
+	writer.Render()
+}
+
+func formatOptionalTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
@@ -0,0 +1,87 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestDiffBackupFiles(t *testing.T) {
+	oldTime := time.Unix(1600000000, 0)
+	newTime := oldTime.Add(time.Hour)
+	fromFiles := internal.BackupFileList{
+		"base/1/1": {MTime: oldTime},
+		"base/1/2": {MTime: oldTime},
+		"base/1/3": {MTime: oldTime},
+	}
+	toFiles := internal.BackupFileList{
+		"base/1/1": {MTime: oldTime},
+		"base/1/2": {MTime: newTime},
+		"base/1/4": {MTime: newTime},
+	}
+
+	diffs := postgres.DiffBackupFiles(fromFiles, toFiles)
+	require.Len(t, diffs, 3)
+	assert.Equal(t, "base/1/2", diffs[0].Path)
+	assert.Equal(t, postgres.FileChanged, diffs[0].Change)
+	assert.Equal(t, postgres.FileMTimeChanged, diffs[0].Reason)
+	assert.Equal(t, "base/1/3", diffs[1].Path)
+	assert.Equal(t, postgres.FileRemoved, diffs[1].Change)
+	assert.Equal(t, "base/1/4", diffs[2].Path)
+	assert.Equal(t, postgres.FileAdded, diffs[2].Change)
+}
+
+func TestDiffBackupFiles_ComparesRecordedChecksums(t *testing.T) {
+	mTime := time.Unix(1600000000, 0)
+	fromFiles := internal.BackupFileList{
+		"base/1/1": {MTime: mTime, SHA256: "aa", Size: 8192},
+		"base/1/2": {MTime: mTime, SHA256: "aa", Size: 8192},
+		"base/1/3": {MTime: mTime, SHA256: "aa", Size: 8192},
+		"base/1/4": {MTime: mTime, SHA256: "aa", Size: 8192},
+		"base/1/5": {MTime: mTime},
+	}
+	toFiles := internal.BackupFileList{
+		"base/1/1": {MTime: mTime, SHA256: "aa", Size: 8192},
+		"base/1/2": {MTime: mTime, SHA256: "bb", Size: 16384},
+		"base/1/3": {MTime: mTime, SHA256: "bb", Size: 8192},
+		"base/1/4": {MTime: mTime.Add(time.Hour), SHA256: "aa", Size: 8192},
+		// the checksum recorded in one backup only is not compared
+		"base/1/5": {MTime: mTime, SHA256: "bb", Size: 16384},
+	}
+
+	diffs := postgres.DiffBackupFiles(fromFiles, toFiles)
+	require.Len(t, diffs, 3)
+	assert.Equal(t, postgres.BackupFileDiff{Path: "base/1/2", Change: postgres.FileChanged,
+		Reason: postgres.FileSizeChanged, OldMTime: diffs[0].OldMTime, NewMTime: diffs[0].NewMTime}, diffs[0])
+	assert.Equal(t, "base/1/3", diffs[1].Path)
+	assert.Equal(t, postgres.FileChecksumChanged, diffs[1].Reason)
+	assert.Equal(t, "base/1/4", diffs[2].Path)
+	assert.Equal(t, postgres.FileMTimeChanged, diffs[2].Reason)
+}
+
+func TestGetEffectiveBackupFiles_ResolvesDeltaChain(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	fullName := "base_000000010000000000000002"
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	fullTime := time.Unix(1600000000, 0)
+
+	fullSentinel := postgres.BackupSentinelDto{Files: internal.BackupFileList{
+		"base/1/1": {MTime: fullTime},
+	}}
+	deltaSentinel := postgres.BackupSentinelDto{IncrementFrom: &fullName, Files: internal.BackupFileList{
+		"base/1/1": {IsSkipped: true, MTime: fullTime.Add(time.Minute)},
+	}}
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder), &fullSentinel, fullName))
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder), &deltaSentinel, deltaName))
+
+	files, err := postgres.GetEffectiveBackupFiles(postgres.NewBackup(baseBackupFolder, deltaName))
+	require.NoError(t, err)
+	assert.True(t, fullTime.Equal(files["base/1/1"].MTime))
+	assert.False(t, files["base/1/1"].IsSkipped)
+}
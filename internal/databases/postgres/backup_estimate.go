@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// the total amount of the file data compressed to sample the compression ratio
+	backupEstimateSampleSize = 64 * 1024 * 1024
+	// the amount of data sampled from a single file, so the sample covers many files
+	backupEstimateFileSampleSize = 1024 * 1024
+)
+
+// BackupEstimate holds the estimated size of the backup of the data directory
+type BackupEstimate struct {
+	DeltaBaseName        string
+	FileCount            int64
+	SkippedFileCount     int64
+	IncrementedFileCount int64
+	ChangedBlockCount    uint64
+	// the size of the data to archive, the increments are counted instead of the incremented files
+	LogicalSize      int64
+	CompressionRatio float64
+	CompressedSize   int64
+}
+
+// EstimatingTarBallComposerMaker makes the composer used by backup-estimate
+type EstimatingTarBallComposerMaker struct {
+	compressor compression.Compressor
+}
+
+func NewEstimatingTarBallComposerMaker(compressor compression.Compressor) *EstimatingTarBallComposerMaker {
+	return &EstimatingTarBallComposerMaker{compressor: compressor}
+}
+
+func (maker *EstimatingTarBallComposerMaker) Make(bundle *Bundle) (TarBallComposer, error) {
+	return &EstimatingTarBallComposer{
+		incrementBaseLsn: bundle.IncrementFromLsn,
+		compressor:       maker.compressor,
+		files:            &RegularBundleFiles{},
+	}, nil
+}
+
+// EstimatingTarBallComposer accounts the files of the bundle instead of packing them.
+// It reads only the pages of the incremented files and the compression sample.
+type EstimatingTarBallComposer struct {
+	incrementBaseLsn *uint64
+	compressor       compression.Compressor
+	files            *RegularBundleFiles
+
+	estimate             BackupEstimate
+	sampleSize           int64
+	compressedSampleSize int64
+	err                  error
+}
+
+func (c *EstimatingTarBallComposer) AddFile(info *ComposeFileInfo) {
+	if c.err != nil {
+		return
+	}
+	size := info.fileInfo.Size()
+	isIncremented := info.isIncremented
+	if isIncremented {
+		changedBlockCount, err := countChangedBlocks(info.path, size, *c.incrementBaseLsn)
+		switch err.(type) {
+		case nil:
+			c.estimate.IncrementedFileCount++
+			c.estimate.ChangedBlockCount += uint64(changedBlockCount)
+			size = estimateIncrementSize(changedBlockCount)
+		case InvalidBlockError:
+			// the same as backup-push does, the file is archived as a whole
+			isIncremented = false
+		default:
+			c.err = errors.Wrapf(err, "failed to estimate the increment of '%s'", info.path)
+			return
+		}
+	}
+	if !isIncremented {
+		err := c.addToCompressionSample(info.path, size)
+		if err != nil {
+			c.err = errors.Wrapf(err, "failed to sample the compression of '%s'", info.path)
+			return
+		}
+	}
+	c.estimate.FileCount++
+	c.estimate.LogicalSize += size
+	c.files.AddFile(info.header, info.fileInfo, isIncremented)
+}
+
+func (c *EstimatingTarBallComposer) AddHeader(header *tar.Header, fileInfo os.FileInfo) error {
+	c.files.AddFile(header, fileInfo, false)
+	return nil
+}
+
+func (c *EstimatingTarBallComposer) SkipFile(tarHeader *tar.Header, fileInfo os.FileInfo) {
+	c.estimate.SkippedFileCount++
+	c.files.AddSkippedFile(tarHeader, fileInfo)
+}
+
+func (c *EstimatingTarBallComposer) PackTarballs() (TarFileSets, error) {
+	return make(TarFileSets), c.err
+}
+
+func (c *EstimatingTarBallComposer) GetFiles() BundleFiles {
+	return c.files
+}
+
+// GetEstimate returns the estimate of the files added so far
+func (c *EstimatingTarBallComposer) GetEstimate() BackupEstimate {
+	estimate := c.estimate
+	estimate.CompressionRatio = 1
+	if c.sampleSize > 0 {
+		estimate.CompressionRatio = float64(c.compressedSampleSize) / float64(c.sampleSize)
+	}
+	estimate.CompressedSize = int64(float64(estimate.LogicalSize) * estimate.CompressionRatio)
+	return estimate
+}
+
+// addToCompressionSample compresses the head of the file until the sample is big enough
+func (c *EstimatingTarBallComposer) addToCompressionSample(path string, size int64) error {
+	sampleSize := utility.Min(int(size), backupEstimateFileSampleSize)
+	sampleSize = utility.Min(sampleSize, int(backupEstimateSampleSize-c.sampleSize))
+	if sampleSize <= 0 {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+
+	counter := &sizeCountingWriter{}
+	compressingWriter := c.compressor.NewWriter(counter)
+	readSize, err := io.Copy(compressingWriter, io.LimitReader(file, int64(sampleSize)))
+	if err != nil {
+		return err
+	}
+	err = compressingWriter.Close()
+	if err != nil {
+		return err
+	}
+	c.sampleSize += readSize
+	c.compressedSampleSize += counter.size
+	return nil
+}
+
+// sizeCountingWriter discards the data and counts its size
+type sizeCountingWriter struct {
+	size int64
+}
+
+func (writer *sizeCountingWriter) Write(p []byte) (int, error) {
+	writer.size += int64(len(p))
+	return len(p), nil
+}
+
+// countChangedBlocks scans the paged file for the pages changed since the lsn
+func countChangedBlocks(path string, fileSize int64, lsn uint64) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	pageReader := &IncrementalPageReader{PagedFile: file, FileSize: fileSize, Lsn: lsn}
+	defer utility.LoggedClose(pageReader, "")
+	err = pageReader.FullScanInitialize()
+	if err != nil {
+		return 0, err
+	}
+	return len(pageReader.Blocks), nil
+}
+
+func estimateIncrementSize(changedBlockCount int) int64 {
+	headerSize := int64(len(IncrementFileHeader)) + sizeofInt64 + sizeofInt32 + int64(changedBlockCount)*sizeofInt32
+	return headerSize + int64(changedBlockCount)*DatabasePageSize + incrementFileFooterSize
+}
+
+// EstimateBackup walks the data directory the way backup-push does, but nothing is uploaded
+// and postgres is not contacted. The delta is estimated by the full scan of the paged files.
+func EstimateBackup(folder storage.Folder, pgDataDirectory string, isFullBackup bool,
+	compressor compression.Compressor) (BackupEstimate, error) {
+	var deltaBase PrevBackupInfo
+	if !isFullBackup {
+		// the delta base is selected the way backup-push does it
+		var err error
+		deltaBase, _, err = selectDeltaBase(folder, internal.NewLatestBackupSelector(), false, false)
+		if err != nil {
+			return BackupEstimate{}, err
+		}
+	}
+	if deltaBase.name != "" {
+		tracelog.InfoLogger.Printf("Estimating delta backup from %s\n", deltaBase.name)
+	}
+
+	bundle := NewBundle(pgDataDirectory, nil, deltaBase.sentinelDto.BackupStartLSN, deltaBase.sentinelDto.Files,
+		false, 0)
+	composerMaker := NewEstimatingTarBallComposerMaker(compressor)
+	err := bundle.SetupComposer(composerMaker)
+	if err != nil {
+		return BackupEstimate{}, err
+	}
+	err = filepath.Walk(pgDataDirectory, bundle.HandleWalkedFSObject)
+	if err != nil {
+		return BackupEstimate{}, err
+	}
+	_, err = bundle.PackTarballs()
+	if err != nil {
+		return BackupEstimate{}, err
+	}
+	composer := bundle.TarBallComposer.(*EstimatingTarBallComposer)
+	if bundle.Sentinel != nil {
+		composer.estimate.FileCount++
+		composer.estimate.LogicalSize += bundle.Sentinel.Info.Size()
+	}
+	estimate := composer.GetEstimate()
+	estimate.DeltaBaseName = deltaBase.name
+	return estimate, nil
+}
+
+// HandleBackupEstimate prints the estimated size of the backup of the data directory
+func HandleBackupEstimate(folder storage.Folder, pgDataDirectory string, isFullBackup bool, output io.Writer) {
+	compressor, err := internal.ConfigurePostgresCompressorWithSetting(internal.BackupCompressionSetting)
+	internal.FatalOnError(err)
+
+	estimate, err := EstimateBackup(folder, utility.ResolveSymlink(pgDataDirectory), isFullBackup, compressor)
+	internal.FatalfOnError("Failed to estimate the backup: %v\n", err)
+
+	err = writeBackupEstimate(estimate, output)
+	internal.FatalfOnError("Failed to write the estimate: %v\n", err)
+}
+
+func writeBackupEstimate(estimate BackupEstimate, output io.Writer) error {
+	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
+	deltaBase := estimate.DeltaBaseName
+	if deltaBase == "" {
+		deltaBase = "none, full backup"
+	}
+	fmt.Fprintf(writer, "delta base:\t%s\n", deltaBase)
+	fmt.Fprintf(writer, "files:\t%d\n", estimate.FileCount)
+	fmt.Fprintf(writer, "skipped unchanged files:\t%d\n", estimate.SkippedFileCount)
+	fmt.Fprintf(writer, "incremented files:\t%d\n", estimate.IncrementedFileCount)
+	fmt.Fprintf(writer, "changed blocks:\t%d\n", estimate.ChangedBlockCount)
+	fmt.Fprintf(writer, "logical size:\t%d\n", estimate.LogicalSize)
+	fmt.Fprintf(writer, "compression ratio:\t%.3f\n", estimate.CompressionRatio)
+	fmt.Fprintf(writer, "estimated compressed size:\t%d\n", estimate.CompressedSize)
+	return writer.Flush()
+}
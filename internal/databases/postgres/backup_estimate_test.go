@@ -0,0 +1,109 @@
+package postgres_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func makeEstimatedDirectory(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "backup_estimate")
+	require.NoError(t, err)
+	for _, subDir := range []string{"global", "base/1", "pg_wal"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, subDir), 0755))
+	}
+	files := map[string]int{
+		"global/pg_control":         8192,
+		"base/1/1000":               4 * 8192,
+		"PG_VERSION":                3,
+		"postmaster.pid":            10,
+		"pg_wal/000000010000000000": 1024,
+	}
+	for name, size := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644))
+	}
+	return dir
+}
+
+func TestEstimateBackup_Full(t *testing.T) {
+	dir := makeEstimatedDirectory(t)
+	defer os.RemoveAll(dir)
+
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	estimate, err := postgres.EstimateBackup(folder, dir, false, lz4.Compressor{})
+	require.NoError(t, err)
+
+	assert.Empty(t, estimate.DeltaBaseName)
+	// postmaster.pid and the pg_wal contents are excluded
+	assert.Equal(t, int64(3), estimate.FileCount)
+	assert.Equal(t, int64(8192+4*8192+3), estimate.LogicalSize)
+	assert.True(t, estimate.CompressionRatio < 1)
+	assert.True(t, estimate.CompressedSize < estimate.LogicalSize)
+
+	var output bytes.Buffer
+	postgres.HandleBackupEstimate(folder, dir, false, &output)
+	assert.Contains(t, output.String(), "none, full backup")
+}
+
+func TestEstimateBackup_Delta(t *testing.T) {
+	dir := makeEstimatedDirectory(t)
+	defer os.RemoveAll(dir)
+	info, err := os.Stat(filepath.Join(dir, "PG_VERSION"))
+	require.NoError(t, err)
+
+	viper.Set(internal.DeltaMaxStepsSetting, 1)
+	defer viper.Set(internal.DeltaMaxStepsSetting, 0)
+
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	backupName := "base_000000010000000000000002"
+	lsn := uint64(0x2000028)
+	sentinelDto := postgres.BackupSentinelDto{BackupStartLSN: &lsn, BackupFinishLSN: &lsn,
+		Files: internal.BackupFileList{
+			"/PG_VERSION":  {MTime: info.ModTime()},
+			"/base/1/1000": {},
+		}}
+	data, err := json.Marshal(sentinelDto)
+	require.NoError(t, err)
+	require.NoError(t, folder.PutObject(utility.BaseBackupPath+backupName+utility.SentinelSuffix,
+		bytes.NewReader(data)))
+	putEstimateDeltaBaseMeta(t, folder, backupName, false)
+
+	estimate, err := postgres.EstimateBackup(folder, dir, false, lz4.Compressor{})
+	require.NoError(t, err)
+	assert.Equal(t, backupName, estimate.DeltaBaseName)
+	assert.Equal(t, int64(1), estimate.SkippedFileCount)
+	// the zero pages of base/1/1000 are new, so all of them are in the increment
+	assert.Equal(t, int64(1), estimate.IncrementedFileCount)
+	assert.Equal(t, uint64(4), estimate.ChangedBlockCount)
+
+	estimate, err = postgres.EstimateBackup(folder, dir, true, lz4.Compressor{})
+	require.NoError(t, err)
+	assert.Empty(t, estimate.DeltaBaseName)
+	assert.Equal(t, int64(0), estimate.SkippedFileCount)
+
+	// backup-push doesn't make the deltas from the permanent backups
+	putEstimateDeltaBaseMeta(t, folder, backupName, true)
+	estimate, err = postgres.EstimateBackup(folder, dir, false, lz4.Compressor{})
+	require.NoError(t, err)
+	assert.Empty(t, estimate.DeltaBaseName)
+}
+
+func putEstimateDeltaBaseMeta(t *testing.T, folder *memory.Folder, backupName string, isPermanent bool) {
+	data, err := json.Marshal(postgres.ExtendedMetadataDto{IsPermanent: isPermanent})
+	require.NoError(t, err)
+	require.NoError(t, folder.PutObject(utility.BaseBackupPath+backupName+"/"+utility.MetadataFileName,
+		bytes.NewReader(data)))
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// neverExcludedFilenames are required to start the restored cluster, so they are backed up
+// even if listed in WALG_BACKUP_EXCLUDED_FILES
+var neverExcludedFilenames = map[string]bool{
+	BackupLabelFilename:   true,
+	TablespaceMapFilename: true,
+}
+
+// alwaysExcludedFilenames are excluded even if WALG_BACKUP_EXCLUDED_FILES removes them from the defaults:
+// the restored postmaster.pid prevents the server from starting, the WAL directory is not a part of the backup
+var alwaysExcludedFilenames = []string{"postmaster.pid", "pg_wal", "pg_xlog"}
+
+// SetExcludedFilenames replaces the ExcludedFilenames with the names and the alwaysExcludedFilenames,
+// except for the backup_label and the tablespace_map
+func SetExcludedFilenames(filenames []string) {
+	ExcludedFilenames = make(map[string]utility.Empty)
+	for _, filename := range filenames {
+		if neverExcludedFilenames[filename] {
+			tracelog.WarningLogger.Printf("%s can't be excluded from the backup, ignoring it\n", filename)
+			continue
+		}
+		ExcludedFilenames[filename] = utility.Empty{}
+	}
+	for _, filename := range alwaysExcludedFilenames {
+		ExcludedFilenames[filename] = utility.Empty{}
+	}
+}
+
+// GetExcludedFilenamesSetting applies the comma separated list of WALG_BACKUP_EXCLUDED_FILES
+// to the DefaultExcludedFilenames: the names are excluded in addition to the defaults,
+// the names prefixed with '-' are removed from them
+func GetExcludedFilenamesSetting() ([]string, error) {
+	if !viper.IsSet(internal.BackupExcludedFilesSetting) {
+		return DefaultExcludedFilenames, nil
+	}
+	added := make([]string, 0)
+	removed := make(map[string]bool)
+	for _, filename := range strings.Split(viper.GetString(internal.BackupExcludedFilesSetting), ",") {
+		filename = strings.TrimSpace(filename)
+		isRemoved := strings.HasPrefix(filename, "-")
+		filename = strings.TrimSpace(strings.TrimPrefix(filename, "-"))
+		if filename == "" {
+			continue
+		}
+		if strings.ContainsRune(filename, '/') {
+			return nil, errors.Errorf("%s must contain file or directory names without paths, got '%s'",
+				internal.BackupExcludedFilesSetting, filename)
+		}
+		if isRemoved {
+			removed[filename] = true
+		} else {
+			added = append(added, filename)
+		}
+	}
+	for _, filename := range alwaysExcludedFilenames {
+		if removed[filename] {
+			tracelog.WarningLogger.Printf("%s is always excluded from the backup, ignoring '-%s'\n", filename, filename)
+		}
+	}
+
+	filenames := make([]string, 0, len(DefaultExcludedFilenames)+len(added))
+	listed := make(map[string]bool)
+	addFilenames := func(names []string) {
+		for _, filename := range names {
+			if !removed[filename] && !listed[filename] {
+				filenames = append(filenames, filename)
+				listed[filename] = true
+			}
+		}
+	}
+	addFilenames(DefaultExcludedFilenames)
+	addFilenames(added)
+	return filenames, nil
+}
+
+// configureExcludedFilenames applies WALG_BACKUP_EXCLUDED_FILES to the ExcludedFilenames
+func configureExcludedFilenames() error {
+	filenames, err := GetExcludedFilenamesSetting()
+	if err != nil {
+		return err
+	}
+	SetExcludedFilenames(filenames)
+	tracelog.DebugLogger.Printf("Excluded from the backup: %s\n", strings.Join(filenames, ", "))
+	return nil
+}
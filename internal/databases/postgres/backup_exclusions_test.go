@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestDefaultExcludedFilenames(t *testing.T) {
+	for _, filename := range []string{"postmaster.pid", "postmaster.opts", "pg_replslot", "pg_stat_tmp", "pg_dynshmem"} {
+		assert.Contains(t, ExcludedFilenames, filename)
+	}
+	assert.NotContains(t, ExcludedFilenames, BackupLabelFilename)
+	assert.NotContains(t, ExcludedFilenames, TablespaceMapFilename)
+}
+
+func TestSetExcludedFilenames_NeverExcluded(t *testing.T) {
+	defer SetExcludedFilenames(DefaultExcludedFilenames)
+	SetExcludedFilenames([]string{"log", BackupLabelFilename, TablespaceMapFilename})
+	assert.Len(t, ExcludedFilenames, 4)
+	for _, filename := range []string{"log", "postmaster.pid", "pg_wal", "pg_xlog"} {
+		assert.Contains(t, ExcludedFilenames, filename)
+	}
+}
+
+func TestGetExcludedFilenamesSetting(t *testing.T) {
+	filenames, err := GetExcludedFilenamesSetting()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultExcludedFilenames, filenames)
+
+	viper.Set(internal.BackupExcludedFilesSetting, "postmaster.pid, pg_stat_tmp, cache,,")
+	defer viper.Set(internal.BackupExcludedFilesSetting, nil)
+	filenames, err = GetExcludedFilenamesSetting()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultExcludedFilenames, filenames[:len(DefaultExcludedFilenames)])
+	assert.Equal(t, []string{"cache"}, filenames[len(DefaultExcludedFilenames):])
+
+	viper.Set(internal.BackupExcludedFilesSetting, "-log, -pg_log, -postmaster.pid, -pg_wal")
+	filenames, err = GetExcludedFilenamesSetting()
+	require.NoError(t, err)
+	assert.NotContains(t, filenames, "log")
+	assert.NotContains(t, filenames, "pg_log")
+	assert.Contains(t, filenames, "pg_replslot")
+	SetExcludedFilenames(filenames)
+	defer SetExcludedFilenames(DefaultExcludedFilenames)
+	// postmaster.pid and the WAL directory stay excluded
+	for _, filename := range []string{"postmaster.pid", "pg_wal", "pg_xlog"} {
+		assert.Contains(t, ExcludedFilenames, filename)
+	}
+
+	viper.Set(internal.BackupExcludedFilesSetting, "base/pgsql_tmp")
+	_, err = GetExcludedFilenamesSetting()
+	assert.Error(t, err)
+}
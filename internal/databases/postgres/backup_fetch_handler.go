@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -61,14 +62,23 @@ func chooseTablespaceSpecification(sentinelDtoSpec, spec *TablespaceSpec) *Table
 }
 
 // TODO : unit tests
-// deltaFetchRecursion function composes Backup object and recursively searches for necessary base backup
+// deltaFetchRecursion function composes Backup object and recursively searches for necessary base backup.
+// If ontoBackupName is set, the recursion stops at this backup, since it is already restored in dbDataDirectory.
+// If chainDownload is set, the tar partitions are read from its staging directory.
 func deltaFetchRecursionOld(backupName string, folder storage.Folder, dbDataDirectory string,
-	tablespaceSpec *TablespaceSpec, filesToUnwrap map[string]bool) error {
+	tablespaceSpec *TablespaceSpec, filesToUnwrap map[string]bool, progress *FetchProgress,
+	checksumVerifier *FetchChecksumVerifier, ontoBackupName string, chainDownload *ChainDownload) error {
+	if backupName == ontoBackupName {
+		tracelog.InfoLogger.Printf("%v is already restored in %v\n", backupName, dbDataDirectory)
+		return nil
+	}
 	backup := NewBackup(folder.GetSubFolder(utility.BaseBackupPath), backupName)
+	backup.chainDownload = chainDownload
 	sentinelDto, err := backup.GetSentinel()
 	if err != nil {
 		return err
 	}
+	warnIfSentinelReconstructed(backupName, sentinelDto)
 	tablespaceSpec = chooseTablespaceSpecification(sentinelDto.TablespaceSpec, tablespaceSpec)
 	sentinelDto.TablespaceSpec = tablespaceSpec
 
@@ -78,7 +88,8 @@ func deltaFetchRecursionOld(backupName string, folder storage.Folder, dbDataDire
 		if err != nil {
 			return err
 		}
-		err = deltaFetchRecursionOld(*sentinelDto.IncrementFrom, folder, dbDataDirectory, tablespaceSpec, baseFilesToUnwrap)
+		err = deltaFetchRecursionOld(*sentinelDto.IncrementFrom, folder, dbDataDirectory, tablespaceSpec,
+			baseFilesToUnwrap, progress, checksumVerifier, ontoBackupName, chainDownload)
 		if err != nil {
 			return err
 		}
@@ -86,24 +97,82 @@ func deltaFetchRecursionOld(backupName string, folder storage.Folder, dbDataDire
 			*(sentinelDto.IncrementFrom), *(sentinelDto.IncrementFromLSN), *(sentinelDto.BackupStartLSN))
 	}
 
-	return backup.unwrapToEmptyDirectory(dbDataDirectory, sentinelDto, filesToUnwrap, false)
+	err = backup.unwrapToEmptyDirectory(dbDataDirectory, sentinelDto, filesToUnwrap, false, progress, checksumVerifier)
+	if chainDownload != nil {
+		chainDownload.releaseUnread(backupName)
+	}
+	return err
+}
+
+// startChainDownload starts the download of the delta chain ahead of its extraction
+// if WALG_PARALLEL_CHAIN_DOWNLOAD is set and the backup is a delta
+func startChainDownload(baseBackupFolder storage.Folder, backupName string, resume bool) (*ChainDownload, error) {
+	if !viper.GetBool(internal.ChainDownloadSetting) {
+		return nil, nil
+	}
+	if resume {
+		tracelog.WarningLogger.Printf("%s is ignored for the resumed fetch\n", internal.ChainDownloadSetting)
+		return nil, nil
+	}
+	backup := NewBackup(baseBackupFolder, backupName)
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil || !sentinelDto.IsIncremental() {
+		return nil, err
+	}
+	return NewChainDownload(baseBackupFolder, backupName)
 }
 
-func GetPgFetcherOld(dbDataDirectory, fileMask, restoreSpecPath string) func(rootFolder storage.Folder, backup internal.Backup) {
+// GetPgFetcherOld returns the backup fetcher. If resume is set, the progress of the fetch
+// is recorded in the destination directory, so the interrupted fetch can be continued.
+// If relocateRoot is set, the tablespaces are restored under it. If verifyChecksums is set,
+// the extracted files are checked against the checksums recorded at backup-push.
+func GetPgFetcherOld(dbDataDirectory, fileMask, restoreSpecPath, relocateRoot string,
+	resume, verifyChecksums bool) func(rootFolder storage.Folder, backup internal.Backup) {
 	return func(rootFolder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
 		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(fileMask)
-		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
 
 		var spec *TablespaceSpec
 		if restoreSpecPath != "" {
 			spec = &TablespaceSpec{}
 			err := readRestoreSpec(restoreSpecPath, spec)
 			errMessege := fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath)
-			tracelog.ErrorLogger.FatalfOnError(errMessege, err)
+			internal.FatalfOnError(errMessege, err)
+		}
+		dbDataDirectory = utility.ResolveSymlink(dbDataDirectory)
+		if relocateRoot != "" {
+			spec, err = relocateRestoreSpec(pgBackup, spec, relocateRoot, dbDataDirectory)
+			internal.FatalfOnError("Failed to relocate tablespaces: %v\n", err)
+		}
+		var progress *FetchProgress
+		if resume {
+			progress, err = LoadFetchProgress(dbDataDirectory, backup.Name)
+			internal.FatalOnError(err)
+		}
+		var checksumVerifier *FetchChecksumVerifier
+		if verifyChecksums {
+			checksumVerifier = NewFetchChecksumVerifier()
+		}
+		err = checkDeltaChainPageSize(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+		chainDownload, err := startChainDownload(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name, resume)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+		err = deltaFetchRecursionOld(backup.Name, rootFolder, dbDataDirectory, spec, filesToUnwrap,
+			progress, checksumVerifier, "", chainDownload)
+		if chainDownload != nil {
+			if closeErr := chainDownload.Close(); closeErr != nil {
+				tracelog.WarningLogger.Printf("Failed to remove the staging directory of the delta chain: %v\n", closeErr)
+			}
+		}
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+		if checksumVerifier != nil {
+			checksumVerifier.logSummary()
+		}
+		if progress != nil {
+			err = progress.Remove()
+			internal.FatalfOnError("Failed to remove fetch progress marker: %v\n", err)
 		}
-		err = deltaFetchRecursionOld(backup.Name, rootFolder, utility.ResolveSymlink(dbDataDirectory), spec, filesToUnwrap)
-		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
 	}
 }
 
@@ -3,39 +3,48 @@ package postgres
 import (
 	"fmt"
 
+	"github.com/spf13/viper"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
 
-func GetPgFetcherNew(dbDataDirectory, fileMask, restoreSpecPath string, skipRedundantTars bool,
+func GetPgFetcherNew(dbDataDirectory, fileMask, restoreSpecPath, relocateRoot string, skipRedundantTars bool,
 ) func(folder storage.Folder, backup internal.Backup) {
 	return func(folder storage.Folder, backup internal.Backup) {
 		pgBackup := ToPgBackup(backup)
 		filesToUnwrap, err := pgBackup.GetFilesToUnwrap(fileMask)
-		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
 
 		var spec *TablespaceSpec
 		if restoreSpecPath != "" {
 			spec = &TablespaceSpec{}
 			err := readRestoreSpec(restoreSpecPath, spec)
 			errMessage := fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath)
-			tracelog.ErrorLogger.FatalfOnError(errMessage, err)
+			internal.FatalfOnError(errMessage, err)
 		}
 
 		// directory must be empty before starting a deltaFetch
 		isEmpty, err := isDirectoryEmpty(dbDataDirectory)
-		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
 
 		if !isEmpty {
-			tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n",
+			internal.FatalfOnError("Failed to fetch backup: %v\n",
 				NewNonEmptyDBDataDirectoryError(dbDataDirectory))
 		}
+		if relocateRoot != "" {
+			spec, err = relocateRestoreSpec(pgBackup, spec, relocateRoot, utility.ResolveSymlink(dbDataDirectory))
+			internal.FatalfOnError("Failed to relocate tablespaces: %v\n", err)
+		}
+		err = checkDeltaChainPageSize(folder.GetSubFolder(utility.BaseBackupPath), pgBackup.Name)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+		truncatedPolicy, err := ParseTruncatedPolicy(viper.GetString(internal.RestoreTruncatedPolicy))
+		internal.FatalOnError(err)
 		config := NewFetchConfig(pgBackup.Name,
-			utility.ResolveSymlink(dbDataDirectory), folder, spec, filesToUnwrap, skipRedundantTars)
+			utility.ResolveSymlink(dbDataDirectory), folder, spec, filesToUnwrap, skipRedundantTars, truncatedPolicy)
 		err = deltaFetchRecursionNew(config)
-		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
 	}
 }
 
@@ -47,6 +56,7 @@ func deltaFetchRecursionNew(cfg *FetchConfig) error {
 	if err != nil {
 		return err
 	}
+	warnIfSentinelReconstructed(cfg.backupName, sentinelDto)
 	cfg.tablespaceSpec = chooseTablespaceSpecification(sentinelDto.TablespaceSpec, cfg.tablespaceSpec)
 	sentinelDto.TablespaceSpec = cfg.tablespaceSpec
 
@@ -57,7 +67,7 @@ func deltaFetchRecursionNew(cfg *FetchConfig) error {
 			return err
 		}
 		unwrapResult, err := backup.unwrapNew(cfg.dbDataDirectory, sentinelDto, cfg.filesToUnwrap,
-			false, cfg.skipRedundantTars)
+			false, cfg.skipRedundantTars, cfg.truncatedPolicy)
 		if err != nil {
 			return err
 		}
@@ -80,6 +90,6 @@ func deltaFetchRecursionNew(cfg *FetchConfig) error {
 
 	tracelog.InfoLogger.Printf("%x reached. Applying base backup... \n", *(sentinelDto.BackupStartLSN))
 	_, err = backup.unwrapNew(cfg.dbDataDirectory, sentinelDto, cfg.filesToUnwrap,
-		false, cfg.skipRedundantTars)
+		false, cfg.skipRedundantTars, cfg.truncatedPolicy)
 	return err
 }
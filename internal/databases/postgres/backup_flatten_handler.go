@@ -0,0 +1,324 @@
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type BackupFlattenError struct {
+	error
+}
+
+func newBackupFlattenError(backupName, reason string) BackupFlattenError {
+	return BackupFlattenError{errors.Errorf("can't flatten backup %s: %s", backupName, reason)}
+}
+
+func (err BackupFlattenError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// FlattenedBackupName is the name of the full backup made of the delta backup:
+// the name of the delta without its increment suffix, as the full backup taken at the same LSN would be named
+func FlattenedBackupName(deltaName string) string {
+	return strings.SplitN(deltaName, "_D_", 2)[0]
+}
+
+// checkFlattenable checks that the backup is the delta which can be restored into a single directory
+func checkFlattenable(backupName string, sentinelDto BackupSentinelDto) error {
+	if !sentinelDto.IsIncremental() {
+		return newBackupFlattenError(backupName, "it is a full backup already")
+	}
+	if sentinelDto.TablespaceSpec != nil && !sentinelDto.TablespaceSpec.empty() {
+		return newBackupFlattenError(backupName, "the backups with tablespaces are not supported")
+	}
+	if sentinelDto.BackupFinishLSN == nil {
+		return newBackupFlattenError(backupName, "the finish LSN is not recorded in the sentinel")
+	}
+	return nil
+}
+
+// NewFlattenedSentinelDto makes the sentinel of the full backup from the sentinel of the delta:
+// the system identifier, the LSNs and the rest of the server information are preserved
+func NewFlattenedSentinelDto(deltaSentinelDto BackupSentinelDto, tarFileSets TarFileSets,
+	uncompressedSize, compressedSize int64) BackupSentinelDto {
+	sentinelDto := deltaSentinelDto
+	sentinelDto.IncrementFromLSN = nil
+	sentinelDto.IncrementFrom = nil
+	sentinelDto.IncrementFullName = nil
+	sentinelDto.IncrementCount = nil
+	sentinelDto.IncrementFromTarSizes = nil
+	sentinelDto.TarFileSets = tarFileSets
+	sentinelDto.UncompressedSize = uncompressedSize
+	sentinelDto.CompressedSize = compressedSize
+	sentinelDto.Reconstructed = false
+	sentinelDto.Finished = false
+	return sentinelDto
+}
+
+// findDependantDeltas returns the backups which are the deltas made from the backup
+func findDependantDeltas(baseBackupFolder storage.Folder, backupName string) ([]string, error) {
+	backups, err := internal.GetBackups(baseBackupFolder)
+	if err != nil {
+		return nil, err
+	}
+	dependants := make([]string, 0)
+	for _, backupTime := range backups {
+		if backupTime.BackupName == backupName {
+			continue
+		}
+		backup := NewBackup(baseBackupFolder, backupTime.BackupName)
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return nil, err
+		}
+		if sentinelDto.IncrementFrom != nil && *sentinelDto.IncrementFrom == backupName {
+			dependants = append(dependants, backupTime.BackupName)
+		}
+	}
+	return dependants, nil
+}
+
+// readRestoredLabelFiles reads the label files restored into the directory and removes them,
+// so they are uploaded with the label files tarball instead of the tar partitions.
+// The label is empty if the backup has no label files.
+func readRestoredLabelFiles(dbDataDirectory string) (label, offsetMap string, hasOffsetMap bool, err error) {
+	labelPath := filepath.Join(dbDataDirectory, BackupLabelFilename)
+	labelContent, err := ioutil.ReadFile(labelPath)
+	if os.IsNotExist(err) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, errors.Wrapf(err, "failed to read the restored %s", BackupLabelFilename)
+	}
+	mapPath := filepath.Join(dbDataDirectory, TablespaceMapFilename)
+	mapContent, err := ioutil.ReadFile(mapPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", false, errors.Wrapf(err, "failed to read the restored %s", TablespaceMapFilename)
+	}
+	hasOffsetMap = err == nil
+	for _, labelFilePath := range []string{labelPath, mapPath} {
+		if err = os.Remove(labelFilePath); err != nil && !os.IsNotExist(err) {
+			return "", "", false, err
+		}
+	}
+	return string(labelContent), string(mapContent), hasOffsetMap, nil
+}
+
+// copyExtraFiles copies the tar of WALG_BACKUP_EXTRA_FILES captured with the delta to the flattened backup
+func copyExtraFiles(baseBackupFolder storage.Folder, deltaName, fullName string, extraFiles *ExtraFilesDto) error {
+	content, err := baseBackupFolder.ReadObject(path.Join(deltaName, extraFiles.TarName))
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(content, "")
+	return baseBackupFolder.PutObject(path.Join(fullName, extraFiles.TarName), content)
+}
+
+// uploadFlattenedBackup uploads the backup restored into the directory as the new full backup
+func uploadFlattenedBackup(uploader *WalUploader, dbDataDirectory, fullName string) (*Bundle, TarFileSets, error) {
+	label, offsetMap, hasOffsetMap, err := readRestoredLabelFiles(dbDataDirectory)
+	if err != nil {
+		return nil, nil, err
+	}
+	bundle := NewBundle(dbDataDirectory, internal.ConfigureCrypter(), nil, nil, false,
+		viper.GetInt64(internal.TarSizeThresholdSetting))
+	err = bundle.StartQueue(internal.NewStorageTarBallMaker(fullName, uploader.Uploader))
+	if err != nil {
+		return nil, nil, err
+	}
+	fileChangePolicy, err := ParseFileChangePolicy(viper.GetString(internal.BackupFileChangePolicy))
+	if err != nil {
+		return nil, nil, err
+	}
+	tarBallComposerMaker, err := NewTarBallComposerMaker(RegularComposer, nil,
+		NewTarBallFilePackerOptions(false, false, viper.GetBool(internal.BackupFileChecksumsSetting),
+			fileChangePolicy))
+	if err != nil {
+		return nil, nil, err
+	}
+	err = bundle.SetupComposer(tarBallComposerMaker)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracelog.InfoLogger.Println("Walking ...")
+	err = filepath.Walk(dbDataDirectory, bundle.HandleWalkedFSObject)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracelog.InfoLogger.Println("Packing ...")
+	tarFileSets, err := bundle.PackTarballs()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = bundle.FinishQueue()
+	if err != nil {
+		return nil, nil, err
+	}
+	if bundle.Sentinel == nil {
+		return nil, nil, newPgControlNotFoundError()
+	}
+	err = bundle.UploadPgControl(uploader.Compressor.FileExtension())
+	if err != nil {
+		return nil, nil, err
+	}
+	if label != "" {
+		labelFilesTarBallName, labelFilesList, err := bundle.packLabelFiles(label, offsetMap, hasOffsetMap)
+		if err != nil {
+			return nil, nil, err
+		}
+		tarFileSets[labelFilesTarBallName] = append(tarFileSets[labelFilesTarBallName], labelFilesList...)
+	}
+
+	uploader.Finish()
+	if uploader.Failed.Load().(bool) {
+		return nil, nil, errors.Errorf("uploading failed during '%s' backup", fullName)
+	}
+	return bundle, tarFileSets, nil
+}
+
+// FlattenBackup restores the delta backup with its delta chain into the temporary directory
+// and uploads the result as the new full backup, which is returned
+func FlattenBackup(uploader *WalUploader, backupName, tempDirectory string) (string, error) {
+	rootFolder := uploader.UploadingFolder
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	backup := NewBackup(baseBackupFolder, backupName)
+	deltaSentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return "", err
+	}
+	if err = checkFlattenable(backupName, deltaSentinelDto); err != nil {
+		return "", err
+	}
+	fullName := FlattenedBackupName(backupName)
+	exists, err := baseBackupFolder.Exists(internal.SentinelNameFromBackup(fullName))
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", newBackupFlattenError(backupName, fmt.Sprintf("backup %s already exists", fullName))
+	}
+
+	dbDataDirectory, err := ioutil.TempDir(tempDirectory, "wal-g-flatten-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create the temporary directory")
+	}
+	defer func() {
+		tracelog.ErrorLogger.PrintOnError(os.RemoveAll(dbDataDirectory))
+	}()
+
+	tracelog.InfoLogger.Printf("Restoring backup %s into %s\n", backupName, dbDataDirectory)
+	filesToUnwrap, err := backup.GetFilesToUnwrap("")
+	if err != nil {
+		return "", err
+	}
+	err = checkDeltaChainPageSize(baseBackupFolder, backupName)
+	if err != nil {
+		return "", err
+	}
+	truncatedPolicy, err := ParseTruncatedPolicy(viper.GetString(internal.RestoreTruncatedPolicy))
+	if err != nil {
+		return "", err
+	}
+	config := NewFetchConfig(backupName, dbDataDirectory, rootFolder, nil, filesToUnwrap, false, truncatedPolicy)
+	err = deltaFetchRecursionNew(config)
+	if err != nil {
+		return "", err
+	}
+	if _, err = CheckRestoredFiles(baseBackupFolder, backupName, dbDataDirectory); err != nil {
+		return "", err
+	}
+
+	tracelog.InfoLogger.Printf("Uploading backup %s\n", fullName)
+	uploader.UploadingFolder = baseBackupFolder
+	bundle, tarFileSets, err := uploadFlattenedBackup(uploader, dbDataDirectory, fullName)
+	if err != nil {
+		return "", err
+	}
+	compressedSize, err := uploader.UploadedDataSize()
+	if err != nil {
+		return "", err
+	}
+	sentinelDto := NewFlattenedSentinelDto(deltaSentinelDto, tarFileSets,
+		atomic.LoadInt64(bundle.TarBallQueue.AllTarballsSize), compressedSize)
+	sentinelDto.setFiles(bundle.GetFiles())
+	if sentinelDto.ExtraFiles != nil {
+		if err = copyExtraFiles(baseBackupFolder, backupName, fullName, sentinelDto.ExtraFiles); err != nil {
+			return "", errors.Wrap(err, "failed to copy the extra files")
+		}
+	}
+
+	meta, err := backup.FetchMeta()
+	if err != nil {
+		return "", err
+	}
+	meta.UncompressedSize = sentinelDto.UncompressedSize
+	meta.CompressedSize = sentinelDto.CompressedSize
+	if err = uploadExtendedMetadata(uploader, fullName, meta); err != nil {
+		return "", err
+	}
+	sentinelDto.Finished = true
+	return fullName, internal.UploadSentinel(uploader, sentinelDto, fullName)
+}
+
+// deleteFlattenedDelta deletes the delta backup replaced by the flattened one,
+// unless it is permanent or the other deltas are made from it
+func deleteFlattenedDelta(baseBackupFolder storage.Folder, backupName string) error {
+	backup := NewBackup(baseBackupFolder, backupName)
+	meta, err := backup.FetchMeta()
+	if err == nil && meta.IsPermanent {
+		tracelog.WarningLogger.Printf("Keeping the permanent backup %s\n", backupName)
+		return nil
+	}
+	dependants, err := findDependantDeltas(baseBackupFolder, backupName)
+	if err != nil {
+		return err
+	}
+	if len(dependants) > 0 {
+		tracelog.WarningLogger.Printf("Keeping backup %s, the delta backups %v are made from it\n",
+			backupName, dependants)
+		return nil
+	}
+	tracelog.InfoLogger.Printf("Deleting the flattened delta backup %s\n", backupName)
+	return storage.DeleteObjectsWhere(baseBackupFolder, true, func(object storage.Object) bool {
+		return utility.StripLeftmostBackupName(object.GetName()) == backupName
+	})
+}
+
+// HandleBackupFlatten materializes the delta backup as the new full backup,
+// the delta is deleted afterwards unless keepChain is set.
+// The free space for the restored delta is checked in the temporary directory unless force is set.
+func HandleBackupFlatten(uploader *WalUploader, backupName, tempDirectory string, keepChain, force bool) {
+	baseBackupFolder := uploader.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, uploader.UploadingFolder)
+	internal.FatalOnError(err)
+
+	if !force {
+		restoreDirectory := tempDirectory
+		if restoreDirectory == "" {
+			restoreDirectory = os.TempDir()
+		}
+		internal.FatalOnError(checkRestoreFreeSpace(NewBackup(baseBackupFolder, backup.Name), restoreDirectory))
+	}
+
+	fullName, err := FlattenBackup(uploader, backup.Name, tempDirectory)
+	internal.FatalfOnError("Failed to flatten the backup: %v\n", err)
+	tracelog.InfoLogger.Printf("Wrote backup with name %s\n", fullName)
+
+	if keepChain {
+		return
+	}
+	internal.FatalOnError(deleteFlattenedDelta(baseBackupFolder, backup.Name))
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	flattenFullName  = "base_000000010000000000000002"
+	flattenDeltaName = "base_000000010000000000000004_D_000000010000000000000002"
+)
+
+func prepareFlattenChain(t *testing.T, withDependant bool) storage.Folder {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	lsn := uint64(0x2000028)
+	fullName, deltaName := flattenFullName, flattenDeltaName
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn}, fullName))
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn,
+		IncrementFrom: &fullName}, deltaName))
+	require.NoError(t, baseBackupFolder.PutObject(deltaName+"/tar_partitions/part_1.tar.lz4", &bytes.Buffer{}))
+	if withDependant {
+		require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn,
+			IncrementFrom: &deltaName}, "base_000000010000000000000006_D_000000010000000000000004"))
+	}
+	return baseBackupFolder
+}
+
+func TestFlattenedBackupName(t *testing.T) {
+	assert.Equal(t, "base_000000010000000000000004", FlattenedBackupName(flattenDeltaName))
+	assert.Equal(t, flattenFullName, FlattenedBackupName(flattenFullName))
+}
+
+func TestCheckFlattenable(t *testing.T) {
+	lsn := uint64(0x2000028)
+	fullName := flattenFullName
+	incrementCount := 1
+	delta := BackupSentinelDto{BackupStartLSN: &lsn, BackupFinishLSN: &lsn, IncrementFrom: &fullName,
+		IncrementFullName: &fullName, IncrementFromLSN: &lsn, IncrementCount: &incrementCount}
+	assert.NoError(t, checkFlattenable(flattenDeltaName, delta))
+
+	assert.IsType(t, BackupFlattenError{}, checkFlattenable(flattenFullName, BackupSentinelDto{BackupStartLSN: &lsn}))
+	withoutFinishLSN := delta
+	withoutFinishLSN.BackupFinishLSN = nil
+	assert.IsType(t, BackupFlattenError{}, checkFlattenable(flattenDeltaName, withoutFinishLSN))
+	withTablespaces := delta
+	spec := NewTablespaceSpec("/data")
+	spec.addTablespace("16384", "/tablespaces/one")
+	withTablespaces.TablespaceSpec = &spec
+	assert.IsType(t, BackupFlattenError{}, checkFlattenable(flattenDeltaName, withTablespaces))
+}
+
+func TestNewFlattenedSentinelDto(t *testing.T) {
+	startLSN, finishLSN, systemIdentifier := uint64(0x4000028), uint64(0x4000100), uint64(6914636324547135908)
+	fullName := flattenFullName
+	incrementCount := 1
+	delta := BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &finishLSN,
+		SystemIdentifier: &systemIdentifier, PgVersion: 130002, IncrementFrom: &fullName,
+		IncrementFullName: &fullName, IncrementFromLSN: &startLSN, IncrementCount: &incrementCount,
+		IncrementFromTarSizes: map[string]int64{"part_1.tar.lz4": 10}, Finished: true}
+	tarFileSets := TarFileSets{"part_1.tar.lz4": {"/base/1/1"}}
+
+	sentinelDto := NewFlattenedSentinelDto(delta, tarFileSets, 100, 10)
+	assert.False(t, sentinelDto.IsIncremental())
+	assert.Nil(t, sentinelDto.IncrementFullName)
+	assert.Nil(t, sentinelDto.IncrementFromLSN)
+	assert.Nil(t, sentinelDto.IncrementCount)
+	assert.Nil(t, sentinelDto.IncrementFromTarSizes)
+	assert.Equal(t, startLSN, *sentinelDto.BackupStartLSN)
+	assert.Equal(t, finishLSN, *sentinelDto.BackupFinishLSN)
+	assert.Equal(t, systemIdentifier, *sentinelDto.SystemIdentifier)
+	assert.Equal(t, 130002, sentinelDto.PgVersion)
+	assert.Equal(t, tarFileSets, sentinelDto.TarFileSets)
+	assert.Equal(t, int64(100), sentinelDto.UncompressedSize)
+	assert.Equal(t, int64(10), sentinelDto.CompressedSize)
+	assert.False(t, sentinelDto.Finished)
+}
+
+func TestReadRestoredLabelFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flatten")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, BackupLabelFilename), []byte("label"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, TablespaceMapFilename), []byte(""), 0600))
+
+	label, offsetMap, hasOffsetMap, err := readRestoredLabelFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "label", label)
+	assert.Equal(t, "", offsetMap)
+	assert.True(t, hasOffsetMap)
+	assert.NoFileExists(t, filepath.Join(dir, BackupLabelFilename))
+	assert.NoFileExists(t, filepath.Join(dir, TablespaceMapFilename))
+
+	label, _, hasOffsetMap, err = readRestoredLabelFiles(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "", label)
+	assert.False(t, hasOffsetMap)
+}
+
+func TestDeleteFlattenedDelta(t *testing.T) {
+	baseBackupFolder := prepareFlattenChain(t, false)
+	require.NoError(t, deleteFlattenedDelta(baseBackupFolder, flattenDeltaName))
+
+	exists, err := baseBackupFolder.Exists(internal.SentinelNameFromBackup(flattenDeltaName))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = baseBackupFolder.Exists(flattenDeltaName + "/tar_partitions/part_1.tar.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = baseBackupFolder.Exists(internal.SentinelNameFromBackup(flattenFullName))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestDeleteFlattenedDelta_KeepsDeltaWithDependants(t *testing.T) {
+	baseBackupFolder := prepareFlattenChain(t, true)
+	require.NoError(t, deleteFlattenedDelta(baseBackupFolder, flattenDeltaName))
+
+	exists, err := baseBackupFolder.Exists(internal.SentinelNameFromBackup(flattenDeltaName))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// interruptedExitCodeBase is added to the signal number to get the exit code
+// of the backup-push interrupted by SIGINT or SIGTERM, as shells do
+const interruptedExitCodeBase = 128
+
+// interruptionHandler runs the registered cleanups in reverse order and exits
+// when backup-push receives SIGINT or SIGTERM or fails in the background, e.g. loses
+// the backup lock, so the server isn't left in the backup mode and the storage isn't left locked.
+type interruptionHandler struct {
+	mutex    sync.Mutex
+	cleanups []func()
+	signals  chan os.Signal
+	failures chan error
+	done     chan struct{}
+	exit     func(code int)
+	fatal    func(err error)
+}
+
+func newInterruptionHandler() *interruptionHandler {
+	return &interruptionHandler{
+		signals:  make(chan os.Signal, 1),
+		failures: make(chan error, 1),
+		done:     make(chan struct{}),
+		exit:     os.Exit,
+		fatal:    internal.FatalOnError,
+	}
+}
+
+func (handler *interruptionHandler) start() {
+	signal.Notify(handler.signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-handler.signals:
+			handler.handle(sig)
+		case err := <-handler.failures:
+			handler.handleFailure(err)
+		case <-handler.done:
+		}
+	}()
+}
+
+func (handler *interruptionHandler) stop() {
+	signal.Stop(handler.signals)
+	close(handler.done)
+}
+
+// addCleanup registers the cleanup to run on interruption, the returned func unregisters it
+func (handler *interruptionHandler) addCleanup(cleanup func()) (remove func()) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	index := len(handler.cleanups)
+	handler.cleanups = append(handler.cleanups, cleanup)
+	return func() {
+		handler.mutex.Lock()
+		defer handler.mutex.Unlock()
+		handler.cleanups[index] = nil
+	}
+}
+
+// fail makes the handler abort the backup and exit on the error, the later errors are ignored
+func (handler *interruptionHandler) fail(err error) {
+	select {
+	case handler.failures <- err:
+	default:
+	}
+}
+
+func (handler *interruptionHandler) handle(sig os.Signal) {
+	tracelog.WarningLogger.Printf("Received %s signal, aborting the backup\n", sig)
+	handler.runCleanups()
+
+	code := interruptedExitCodeBase
+	if sysSignal, ok := sig.(syscall.Signal); ok {
+		code += int(sysSignal)
+	}
+	handler.exit(code)
+}
+
+func (handler *interruptionHandler) handleFailure(err error) {
+	tracelog.WarningLogger.Printf("Aborting the backup: %v\n", err)
+	handler.runCleanups()
+	handler.fatal(err)
+}
+
+func (handler *interruptionHandler) runCleanups() {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	for i := len(handler.cleanups) - 1; i >= 0; i-- {
+		if handler.cleanups[i] != nil {
+			handler.cleanups[i]()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterruptionHandler_RunsCleanupsInReverseOrder(t *testing.T) {
+	handler := newInterruptionHandler()
+	exitCode := 0
+	handler.exit = func(code int) {
+		exitCode = code
+	}
+
+	var calls []string
+	handler.addCleanup(func() {
+		calls = append(calls, "release lock")
+	})
+	removeAbort := handler.addCleanup(func() {
+		calls = append(calls, "abort backup")
+	})
+	handler.addCleanup(func() {
+		calls = append(calls, "stop uploads")
+	})
+
+	handler.handle(syscall.SIGTERM)
+	assert.Equal(t, []string{"stop uploads", "abort backup", "release lock"}, calls)
+	assert.Equal(t, 128+int(syscall.SIGTERM), exitCode)
+
+	calls = nil
+	removeAbort()
+	handler.handle(syscall.SIGINT)
+	assert.Equal(t, []string{"stop uploads", "release lock"}, calls)
+	assert.Equal(t, 128+int(syscall.SIGINT), exitCode)
+}
+
+func TestInterruptionHandler_FailsOnBackgroundError(t *testing.T) {
+	handler := newInterruptionHandler()
+	var fatalErr error
+	handler.fatal = func(err error) {
+		fatalErr = err
+	}
+
+	var calls []string
+	handler.addCleanup(func() {
+		calls = append(calls, "release lock")
+	})
+	handler.addCleanup(func() {
+		calls = append(calls, "abort backup")
+	})
+
+	lostErr := errors.New("the backup lock is lost")
+	handler.fail(lostErr)
+	handler.fail(errors.New("ignored"))
+	handler.handleFailure(<-handler.failures)
+	assert.Equal(t, []string{"abort backup", "release lock"}, calls)
+	assert.Equal(t, lostErr, fatalErr)
+}
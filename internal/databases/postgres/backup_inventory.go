@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const (
+	inventoryStatusDone = "done"
+	// inventoryConnectTimeout bounds the connection to the inventory database if the DSN sets no connect_timeout,
+	// so the unreachable inventory doesn't hang backup-push after the backup is done
+	inventoryConnectTimeout = 10 * time.Second
+)
+
+// BackupInventoryRecord is the row describing the pushed backup in the WALG_INVENTORY_TABLE
+type BackupInventoryRecord struct {
+	ClusterID        string
+	BackupName       string
+	StartTime        time.Time
+	FinishTime       time.Time
+	UncompressedSize int64
+	CompressedSize   int64
+	IsPermanent      bool
+	Status           string
+}
+
+func NewBackupInventoryRecord(backupName string, meta ExtendedMetadataDto) BackupInventoryRecord {
+	record := BackupInventoryRecord{
+		BackupName:       backupName,
+		StartTime:        meta.StartTime,
+		FinishTime:       meta.FinishTime,
+		UncompressedSize: meta.UncompressedSize,
+		CompressedSize:   meta.CompressedSize,
+		IsPermanent:      meta.IsPermanent,
+		Status:           inventoryStatusDone,
+	}
+	if meta.SystemIdentifier != nil {
+		// the system identifier may not fit into bigint, so it is stored as text
+		record.ClusterID = strconv.FormatUint(*meta.SystemIdentifier, 10)
+	}
+	return record
+}
+
+// sanitizeInventoryTable quotes the optionally schema-qualified table name
+func sanitizeInventoryTable(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
+
+func makeInventoryCreateQuery(table string) string {
+	return "CREATE TABLE IF NOT EXISTS " + sanitizeInventoryTable(table) + " (" +
+		"cluster_id text NOT NULL, " +
+		"backup_name text NOT NULL, " +
+		"start_time timestamptz, " +
+		"finish_time timestamptz, " +
+		"uncompressed_size bigint, " +
+		"compressed_size bigint, " +
+		"is_permanent boolean, " +
+		"status text, " +
+		"PRIMARY KEY (cluster_id, backup_name))"
+}
+
+func makeInventoryInsertQuery(table string) string {
+	return "INSERT INTO " + sanitizeInventoryTable(table) + " (cluster_id, backup_name, start_time, finish_time, " +
+		"uncompressed_size, compressed_size, is_permanent, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) " +
+		"ON CONFLICT (cluster_id, backup_name) DO UPDATE SET start_time = EXCLUDED.start_time, " +
+		"finish_time = EXCLUDED.finish_time, uncompressed_size = EXCLUDED.uncompressed_size, " +
+		"compressed_size = EXCLUDED.compressed_size, is_permanent = EXCLUDED.is_permanent, status = EXCLUDED.status"
+}
+
+// parseInventoryConnConfig parses the inventory DSN, the connect_timeout of the DSN takes precedence
+// over inventoryConnectTimeout
+func parseInventoryConnConfig(dsn string) (pgx.ConnConfig, error) {
+	config, err := pgx.ParseConnectionString(dsn)
+	if err != nil {
+		return pgx.ConnConfig{}, errors.Wrapf(err, "failed to parse %s", internal.InventoryDSNSetting)
+	}
+	if config.Dial == nil {
+		config.Dial = (&net.Dialer{KeepAlive: 5 * time.Minute, Timeout: inventoryConnectTimeout}).Dial
+	}
+	return config, nil
+}
+
+// WriteBackupInventoryRecord inserts the record into the inventory table at dsn,
+// the table is created if it does not exist
+func WriteBackupInventoryRecord(dsn, table string, record BackupInventoryRecord) error {
+	config, err := parseInventoryConnConfig(dsn)
+	if err != nil {
+		return err
+	}
+	conn, err := pgx.Connect(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the inventory database")
+	}
+	defer conn.Close()
+
+	_, err = conn.Exec(makeInventoryCreateQuery(table))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the inventory table %s", table)
+	}
+	_, err = conn.Exec(makeInventoryInsertQuery(table), record.ClusterID, record.BackupName, record.StartTime,
+		record.FinishTime, record.UncompressedSize, record.CompressedSize, record.IsPermanent, record.Status)
+	return errors.Wrapf(err, "failed to insert into the inventory table %s", table)
+}
+
+// reportBackupToInventory writes the pushed backup to WALG_INVENTORY_TABLE if WALG_INVENTORY_DSN is set.
+// The inventory is best-effort: the failures are only warned about, so they never fail the backup.
+func reportBackupToInventory(backupName string, meta ExtendedMetadataDto) {
+	dsn := viper.GetString(internal.InventoryDSNSetting)
+	if dsn == "" {
+		return
+	}
+	table := viper.GetString(internal.InventoryTableSetting)
+	err := WriteBackupInventoryRecord(dsn, table, NewBackupInventoryRecord(backupName, meta))
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to report backup %s to the inventory: %v\n", backupName, err)
+		return
+	}
+	tracelog.InfoLogger.Printf("Reported backup %s to the inventory table %s\n", backupName, table)
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBackupInventoryRecord(t *testing.T) {
+	systemIdentifier := uint64(18446744073709551615)
+	startTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	meta := ExtendedMetadataDto{StartTime: startTime, FinishTime: startTime.Add(time.Hour),
+		SystemIdentifier: &systemIdentifier, UncompressedSize: 20, CompressedSize: 10, IsPermanent: true}
+
+	record := NewBackupInventoryRecord("base_000000010000000000000002", meta)
+	assert.Equal(t, BackupInventoryRecord{ClusterID: "18446744073709551615",
+		BackupName: "base_000000010000000000000002", StartTime: startTime, FinishTime: startTime.Add(time.Hour),
+		UncompressedSize: 20, CompressedSize: 10, IsPermanent: true, Status: inventoryStatusDone}, record)
+}
+
+func TestNewBackupInventoryRecord_NoSystemIdentifier(t *testing.T) {
+	record := NewBackupInventoryRecord("base_000000010000000000000002", ExtendedMetadataDto{})
+	assert.Equal(t, "", record.ClusterID)
+}
+
+func TestSanitizeInventoryTable(t *testing.T) {
+	assert.Equal(t, `"walg_backups"`, sanitizeInventoryTable("walg_backups"))
+	assert.Equal(t, `"monitoring"."backups"`, sanitizeInventoryTable("monitoring.backups"))
+	assert.Equal(t, `"backups""; DROP TABLE x; --"`, sanitizeInventoryTable(`backups"; DROP TABLE x; --`))
+}
+
+func TestMakeInventoryInsertQuery(t *testing.T) {
+	query := makeInventoryInsertQuery("monitoring.backups")
+	assert.Contains(t, query, `INSERT INTO "monitoring"."backups" (cluster_id, backup_name,`)
+	assert.Contains(t, query, "ON CONFLICT (cluster_id, backup_name) DO UPDATE")
+}
+
+func TestParseInventoryConnConfig(t *testing.T) {
+	config, err := parseInventoryConnConfig("postgres://monitor@inventory.example.com/fleet")
+	assert.NoError(t, err)
+	assert.NotNil(t, config.Dial)
+	assert.Equal(t, "inventory.example.com", config.Host)
+
+	config, err = parseInventoryConnConfig("postgres://monitor@inventory.example.com/fleet?connect_timeout=3")
+	assert.NoError(t, err)
+	assert.NotNil(t, config.Dial)
+
+	_, err = parseInventoryConnConfig("postgres://monitor@inventory.example.com/fleet?connect_timeout=soon")
+	assert.Error(t, err)
+}
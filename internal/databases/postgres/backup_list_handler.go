@@ -1,9 +1,12 @@
 package postgres
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"text/tabwriter"
 
 	"github.com/jedib0t/go-pretty/table"
@@ -14,44 +17,71 @@ import (
 
 // TODO : unit tests
 func HandleDetailedBackupList(folder storage.Folder, pretty bool, json bool) {
-	backups, err := internal.GetBackups(folder)
+	format := internal.BackupListFormatTable
+	if json {
+		format = internal.BackupListFormatJSON
+	}
+	HandleDetailedBackupListWithFormat(folder, format, pretty)
+}
+
+// HandleDetailedBackupListWithFormat prints the backups with the details from their metadata
+// in one of the internal.BackupListFormat* formats
+func HandleDetailedBackupListWithFormat(folder storage.Folder, format string, pretty bool) {
+	backups, err := internal.GetCompleteBackups(folder)
 
 	if len(backups) == 0 {
 		tracelog.InfoLogger.Println("No backups found")
 		return
 	}
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	// if details are requested we append content of metadata.json to each line
 
 	backupDetails, err := GetBackupsDetails(folder, backups)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	SortBackupDetails(backupDetails)
 
 	switch {
-	case json:
+	case format == internal.BackupListFormatJSON:
 		err = internal.WriteAsJSON(backupDetails, os.Stdout, pretty)
+	case format == internal.BackupListFormatCSV:
+		err = WriteBackupListDetailsCSV(backupDetails, os.Stdout)
 	case pretty:
 		WritePrettyBackupListDetails(backupDetails, os.Stdout)
 	default:
 		err = WriteBackupListDetails(backupDetails, os.Stdout)
 	}
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 // TODO : unit tests
 func WriteBackupListDetails(backupDetails []BackupDetail, output io.Writer) error {
 	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
 	defer writer.Flush()
+	tiered := hasTieredBackupDetails(backupDetails)
 	//nolint:lll
-	_, err := fmt.Fprintln(writer, "name\tmodified\twal_segment_backup_start\tstart_time\tfinish_time\thostname\tdata_dir\tpg_version\tstart_lsn\tfinish_lsn\tis_permanent")
+	header := "name\tmodified\twal_segment_backup_start\tstart_time\tfinish_time\thostname\tdata_dir\tpg_version\tstart_lsn\tfinish_lsn\tis_permanent"
+	if tiered {
+		header += "\ttier"
+	}
+	_, err := fmt.Fprintln(writer, header)
 	if err != nil {
 		return err
 	}
 	for i := 0; i < len(backupDetails); i++ {
 		b := backupDetails[i]
 		//nolint:lll
-		_, err = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", b.BackupName, internal.FormatTime(b.Time), b.WalFileName, internal.FormatTime(b.StartTime), internal.FormatTime(b.FinishTime), b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent)
+		_, err = fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v", b.BackupName, internal.FormatTime(b.Time), b.WalFileName, internal.FormatTime(b.StartTime), internal.FormatTime(b.FinishTime), b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent)
+		if err != nil {
+			return err
+		}
+		if tiered {
+			_, err = fmt.Fprintf(writer, "\t%v", b.Tier)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(writer)
 		if err != nil {
 			return err
 		}
@@ -64,13 +94,63 @@ func WritePrettyBackupListDetails(backupDetails []BackupDetail, output io.Writer
 	writer := table.NewWriter()
 	writer.SetOutputMirror(output)
 	defer writer.Render()
+	tiered := hasTieredBackupDetails(backupDetails)
 	//nolint:lll
-	writer.AppendHeader(table.Row{"#", "Name", "Modified", "WAL segment backup start", "Start time", "Finish time", "Hostname", "Datadir", "PG Version", "Start LSN", "Finish LSN", "Permanent"})
+	header := table.Row{"#", "Name", "Modified", "WAL segment backup start", "Start time", "Finish time", "Hostname", "Datadir", "PG Version", "Start LSN", "Finish LSN", "Permanent"}
+	if tiered {
+		header = append(header, "Tier")
+	}
+	writer.AppendHeader(header)
 	for idx := range backupDetails {
 		b := &backupDetails[idx]
-		writer.AppendRow(
-			table.Row{idx, b.BackupName, internal.PrettyFormatTime(b.Time), b.WalFileName,
-				internal.PrettyFormatTime(b.StartTime), internal.PrettyFormatTime(b.FinishTime),
-				b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent})
+		row := table.Row{idx, b.BackupName, internal.PrettyFormatTime(b.Time), b.WalFileName,
+			internal.PrettyFormatTime(b.StartTime), internal.PrettyFormatTime(b.FinishTime),
+			b.Hostname, b.DataDir, b.PgVersion, b.StartLsn, b.FinishLsn, b.IsPermanent}
+		if tiered {
+			row = append(row, b.Tier)
+		}
+		writer.AppendRow(row)
+	}
+}
+
+// WriteBackupListDetailsCSV writes the backup details as CSV with the columns of WriteBackupListDetails
+// and the user data of the backups as JSON, the fields containing commas or quotes are quoted
+func WriteBackupListDetailsCSV(backupDetails []BackupDetail, output io.Writer) error {
+	tiered := hasTieredBackupDetails(backupDetails)
+	header := []string{"name", "modified", "wal_segment_backup_start", "start_time", "finish_time",
+		"hostname", "data_dir", "pg_version", "start_lsn", "finish_lsn", "is_permanent", "user_data"}
+	if tiered {
+		header = append(header, "tier")
+	}
+	records := make([][]string, 0, len(backupDetails)+1)
+	records = append(records, header)
+	for idx := range backupDetails {
+		b := &backupDetails[idx]
+		userData := ""
+		if b.UserData != nil {
+			rawUserData, err := json.Marshal(b.UserData)
+			if err != nil {
+				return err
+			}
+			userData = string(rawUserData)
+		}
+		record := []string{b.BackupName, internal.FormatTime(b.Time), b.WalFileName,
+			internal.FormatTime(b.StartTime), internal.FormatTime(b.FinishTime), b.Hostname, b.DataDir,
+			strconv.Itoa(b.PgVersion), strconv.FormatUint(b.StartLsn, 10), strconv.FormatUint(b.FinishLsn, 10),
+			strconv.FormatBool(b.IsPermanent), userData}
+		if tiered {
+			record = append(record, b.Tier)
+		}
+		records = append(records, record)
+	}
+	return csv.NewWriter(output).WriteAll(records)
+}
+
+func hasTieredBackupDetails(backupDetails []BackupDetail) bool {
+	for idx := range backupDetails {
+		if backupDetails[idx].Tier != "" {
+			return true
+		}
 	}
+	return false
 }
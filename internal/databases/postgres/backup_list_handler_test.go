@@ -166,3 +166,31 @@ func TestWriteBackupList_LongColumnsValues(t *testing.T) {
 
 	assert.Equal(t, expectedRes, b.String())
 }
+
+func TestWriteBackupListDetailsCSV(t *testing.T) {
+	backups := []postgres.BackupDetail{
+		shortBackups[0],
+		{
+			internal.BackupTime{
+				BackupName:  "b1",
+				Time:        time.Time{},
+				WalFileName: "shortWallName1",
+			},
+			postgres.ExtendedMetadataDto{
+				PgVersion:   130000,
+				StartLsn:    16,
+				IsPermanent: true,
+				UserData:    map[string]string{"label": "nightly, full"},
+			},
+		},
+	}
+	expectedRes := "name,modified,wal_segment_backup_start,start_time,finish_time,hostname,data_dir," +
+		"pg_version,start_lsn,finish_lsn,is_permanent,user_data\n" +
+		"b0,-,shortWallName0,-,-,,,0,0,0,false,\n" +
+		"b1,-,shortWallName1,-,-,,,130000,16,0,true,\"{\"\"label\"\":\"\"nightly, full\"\"}\"\n"
+	b := bytes.Buffer{}
+	err := postgres.WriteBackupListDetailsCSV(backups, &b)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRes, b.String())
+}
@@ -0,0 +1,242 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	BackupLockObjectName = "backup_lock.json"
+	// BackupLockStaleTimeout is the heartbeat age after which the lock is considered abandoned
+	BackupLockStaleTimeout = 10 * time.Minute
+
+	backupLockHeartbeatInterval = time.Minute
+)
+
+type BackupLockHeldError struct {
+	error
+}
+
+func newBackupLockHeldError(dto BackupLockDto) BackupLockHeldError {
+	return BackupLockHeldError{errors.Errorf(
+		"Another backup-push (host %s, pid %d) holds the backup lock since %s, last heartbeat at %s. "+
+			"The lock is considered stale if there was no heartbeat for %s.",
+		dto.Hostname, dto.Pid, dto.StartTime.Format(time.RFC3339), dto.HeartbeatTime.Format(time.RFC3339),
+		BackupLockStaleTimeout)}
+}
+
+func (err BackupLockHeldError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type BackupLockLostError struct {
+	error
+}
+
+func newBackupLockLostError(dto BackupLockDto, exists bool) BackupLockLostError {
+	if !exists {
+		return BackupLockLostError{errors.New("The backup lock was removed by another process")}
+	}
+	return BackupLockLostError{errors.Errorf(
+		"The backup lock was taken over by another backup-push (host %s, pid %d) since %s",
+		dto.Hostname, dto.Pid, dto.StartTime.Format(time.RFC3339))}
+}
+
+func (err BackupLockLostError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// BackupLockDto describes the backup-push process holding the backup lock
+type BackupLockDto struct {
+	ID            string    `json:"id"`
+	Hostname      string    `json:"hostname"`
+	Pid           int       `json:"pid"`
+	StartTime     time.Time `json:"start_time"`
+	HeartbeatTime time.Time `json:"heartbeat_time"`
+}
+
+func (dto BackupLockDto) isStale(now time.Time) bool {
+	return now.Sub(dto.HeartbeatTime) > BackupLockStaleTimeout
+}
+
+// BackupLock is the advisory lock preventing the concurrent backup-push runs
+// against the same storage folder. It is stored as the object in the folder and
+// is kept alive by the periodical heartbeat updates.
+type BackupLock struct {
+	folder  storage.Folder
+	dto     BackupLockDto
+	stop    chan struct{}
+	done    chan struct{}
+	lost    chan struct{}
+	lostErr error
+
+	releaseOnce sync.Once
+	releaseErr  error
+}
+
+// AcquireBackupLock takes the backup lock in the folder. It fails with BackupLockHeldError
+// if the lock is held by another process and is not stale.
+// Storages don't provide the conditional writes, so the lock is best effort:
+// the processes starting at the same moment may still both succeed.
+func AcquireBackupLock(folder storage.Folder) (*BackupLock, error) {
+	now := utility.TimeNowCrossPlatformUTC()
+	currentDto, exists, err := fetchBackupLock(folder)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if !currentDto.isStale(now) {
+			return nil, newBackupLockHeldError(currentDto)
+		}
+		tracelog.WarningLogger.Printf("Taking over the stale backup lock of host %s, pid %d, last heartbeat at %s\n",
+			currentDto.Hostname, currentDto.Pid, currentDto.HeartbeatTime.Format(time.RFC3339))
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to fetch the hostname for the backup lock, leaving empty: %v", err)
+	}
+	lock := &BackupLock{
+		folder: folder,
+		dto: BackupLockDto{
+			ID:            fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), now.UnixNano()),
+			Hostname:      hostname,
+			Pid:           os.Getpid(),
+			StartTime:     now,
+			HeartbeatTime: now,
+		},
+	}
+	err = lock.upload()
+	if err != nil {
+		return nil, err
+	}
+
+	// check that no one has overwritten the lock in the meantime
+	currentDto, exists, err = fetchBackupLock(folder)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || currentDto.ID != lock.dto.ID {
+		return nil, newBackupLockHeldError(currentDto)
+	}
+
+	lock.stop = make(chan struct{})
+	lock.done = make(chan struct{})
+	lock.lost = make(chan struct{})
+	go lock.heartbeat(backupLockHeartbeatInterval)
+	return lock, nil
+}
+
+// Release stops the heartbeat and removes the lock if it is still held by this process.
+// The lock is released by the first call only, the later calls return its result.
+func (lock *BackupLock) Release() error {
+	lock.releaseOnce.Do(func() {
+		lock.releaseErr = lock.release()
+	})
+	return lock.releaseErr
+}
+
+// Lost is closed when the heartbeat finds the lock taken over or removed by another process,
+// the heartbeat stops then
+func (lock *BackupLock) Lost() <-chan struct{} {
+	return lock.lost
+}
+
+// LostError returns the BackupLockLostError describing the loss of the lock, after Lost is closed
+func (lock *BackupLock) LostError() error {
+	<-lock.lost
+	return lock.lostErr
+}
+
+func (lock *BackupLock) release() error {
+	close(lock.stop)
+	<-lock.done
+
+	currentDto, exists, err := fetchBackupLock(lock.folder)
+	if err != nil {
+		return err
+	}
+	if !exists || currentDto.ID != lock.dto.ID {
+		tracelog.WarningLogger.Println("The backup lock was taken over by another process, leaving it as is")
+		return nil
+	}
+	return lock.folder.DeleteObjects([]string{BackupLockObjectName})
+}
+
+func (lock *BackupLock) heartbeat(interval time.Duration) {
+	defer close(lock.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lock.stop:
+			return
+		case <-ticker.C:
+			if !lock.beat() {
+				return
+			}
+		}
+	}
+}
+
+// beat updates the heartbeat of the lock if it is still held by this process,
+// otherwise it closes lost and returns false. The lock is re-read before each update,
+// so the lock taken over by another process is not overwritten back.
+func (lock *BackupLock) beat() bool {
+	currentDto, exists, err := fetchBackupLock(lock.folder)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to check the backup lock before the heartbeat: %v\n", err)
+		return true
+	}
+	if !exists || currentDto.ID != lock.dto.ID {
+		lock.lostErr = newBackupLockLostError(currentDto, exists)
+		close(lock.lost)
+		return false
+	}
+	lock.dto.HeartbeatTime = utility.TimeNowCrossPlatformUTC()
+	err = lock.upload()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to update the backup lock heartbeat: %v\n", err)
+	}
+	return true
+}
+
+func (lock *BackupLock) upload() error {
+	body, err := json.Marshal(lock.dto)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the backup lock")
+	}
+	return errors.Wrap(lock.folder.PutObject(BackupLockObjectName, bytes.NewReader(body)),
+		"failed to upload the backup lock")
+}
+
+func fetchBackupLock(folder storage.Folder) (dto BackupLockDto, exists bool, err error) {
+	reader, err := folder.ReadObject(BackupLockObjectName)
+	if _, notFound := errors.Cause(err).(storage.ObjectNotFoundError); notFound {
+		return dto, false, nil
+	}
+	if err != nil {
+		return dto, false, errors.Wrap(err, "failed to read the backup lock")
+	}
+	defer reader.Close()
+
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return dto, false, errors.Wrap(err, "failed to read the backup lock")
+	}
+	err = json.Unmarshal(body, &dto)
+	if err != nil {
+		return dto, false, errors.Wrap(err, "failed to unmarshal the backup lock")
+	}
+	return dto, true, nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestBackupLock_RefusesConcurrentBackup(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+
+	lock, err := AcquireBackupLock(folder)
+	require.NoError(t, err)
+
+	_, err = AcquireBackupLock(folder)
+	assert.IsType(t, BackupLockHeldError{}, err)
+
+	require.NoError(t, lock.Release())
+	exists, err := folder.Exists(BackupLockObjectName)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	lock, err = AcquireBackupLock(folder)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestBackupLock_ReleasedOnce(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+
+	lock, err := AcquireBackupLock(folder)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+
+	// the lock of the next backup is not removed by the exit hook of the previous one
+	nextLock, err := AcquireBackupLock(folder)
+	require.NoError(t, err)
+	assert.NoError(t, lock.Release())
+	exists, err := folder.Exists(BackupLockObjectName)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, nextLock.Release())
+}
+
+func TestBackupLock_TakesOverStaleLock(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	heartbeatTime := utility.TimeNowCrossPlatformUTC().Add(-2 * BackupLockStaleTimeout)
+	staleDto := BackupLockDto{ID: "stale", Hostname: "other", Pid: 1,
+		StartTime: heartbeatTime.Add(-time.Hour), HeartbeatTime: heartbeatTime}
+	body, err := json.Marshal(staleDto)
+	require.NoError(t, err)
+	require.NoError(t, folder.PutObject(BackupLockObjectName, bytes.NewReader(body)))
+
+	lock, err := AcquireBackupLock(folder)
+	require.NoError(t, err)
+	currentDto, exists, err := fetchBackupLock(folder)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, lock.dto.ID, currentDto.ID)
+	assert.NoError(t, lock.Release())
+}
+
+func TestBackupLock_LostToAnotherProcess(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	lock, err := AcquireBackupLock(folder)
+	require.NoError(t, err)
+	assert.True(t, lock.beat())
+
+	now := utility.TimeNowCrossPlatformUTC()
+	otherDto := BackupLockDto{ID: "other", Hostname: "other", Pid: 1, StartTime: now, HeartbeatTime: now}
+	body, err := json.Marshal(otherDto)
+	require.NoError(t, err)
+	require.NoError(t, folder.PutObject(BackupLockObjectName, bytes.NewReader(body)))
+
+	assert.False(t, lock.beat())
+	select {
+	case <-lock.Lost():
+	default:
+		t.Fatal("the lost lock is not reported")
+	}
+	assert.IsType(t, BackupLockLostError{}, lock.LostError())
+
+	// the lock of the other process is neither overwritten by the heartbeat nor removed
+	currentDto, exists, err := fetchBackupLock(folder)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, otherDto.ID, currentDto.ID)
+	assert.NoError(t, lock.Release())
+	exists, err = folder.Exists(BackupLockObjectName)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	BackupManifestFilename      = "backup_manifest"
+	backupManifestVersion       = 1
+	backupManifestTimeFormat    = "2006-01-02 15:04:05 GMT"
+	backupManifestChecksumField = "\"Manifest-Checksum\": "
+)
+
+// BackupManifestFile describes the single file entry of the backup_manifest
+type BackupManifestFile struct {
+	Path         string
+	Size         int64
+	LastModified string
+	SHA256       string
+}
+
+// BackupManifest is the backup_manifest in the format of PostgreSQL 13+,
+// so the restored directory can be checked by pg_verifybackup
+type BackupManifest struct {
+	Files    []BackupManifestFile
+	Timeline uint32
+	StartLSN uint64
+	EndLSN   uint64
+}
+
+// Marshal formats the manifest the way pg_basebackup does: pg_verifybackup computes
+// the manifest checksum over everything up to the line with the checksum itself
+func (manifest *BackupManifest) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "{ \"PostgreSQL-Backup-Manifest-Version\": %d,\n\"Files\": [", backupManifestVersion)
+	for i, file := range manifest.Files {
+		path, err := json.Marshal(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		fmt.Fprintf(&buffer, "\n{ \"Path\": %s, \"Size\": %d, \"Last-Modified\": \"%s\"",
+			path, file.Size, file.LastModified)
+		if file.SHA256 != "" {
+			fmt.Fprintf(&buffer, ", \"Checksum-Algorithm\": \"SHA256\", \"Checksum\": \"%s\"", file.SHA256)
+		}
+		buffer.WriteString(" }")
+	}
+	fmt.Fprintf(&buffer, " ],\n\"WAL-Ranges\": [\n{ \"Timeline\": %d, \"Start-LSN\": \"%s\", \"End-LSN\": \"%s\" }\n],\n",
+		manifest.Timeline, pglogrepl.LSN(manifest.StartLSN), pglogrepl.LSN(manifest.EndLSN))
+	checksum := sha256.Sum256(buffer.Bytes())
+	fmt.Fprintf(&buffer, "%s\"%s\"}\n", backupManifestChecksumField, hex.EncodeToString(checksum[:]))
+	return buffer.Bytes(), nil
+}
+
+// NewBackupManifest builds the manifest of the backup restored in dbDataDirectory.
+// The checksums and sizes are taken from the descriptions recorded at backup-push
+// in the delta chain. The files without the recorded checksum, e.g. the incremented ones,
+// are listed with the size of the restored file only.
+func NewBackupManifest(baseBackupFolder storage.Folder, backupName, dbDataDirectory string) (*BackupManifest, error) {
+	chain, err := getDeltaChainSentinels(baseBackupFolder, backupName)
+	if err != nil {
+		return nil, err
+	}
+	sentinelDto := chain[0]
+	if sentinelDto.Files == nil {
+		return nil, errors.Errorf("backup %s has no file list, can not build the manifest", backupName)
+	}
+	if sentinelDto.BackupStartLSN == nil || sentinelDto.BackupFinishLSN == nil {
+		return nil, errors.Errorf("backup %s has no LSN range, can not build the manifest", backupName)
+	}
+	timeline, err := ParseTimelineFromBackupName(backupName)
+	if err != nil {
+		return nil, err
+	}
+
+	fileNames := make([]string, 0, len(sentinelDto.Files)+len(UtilityFilePaths))
+	for fileName := range sentinelDto.Files {
+		fileNames = append(fileNames, fileName)
+	}
+	for fileName := range UtilityFilePaths {
+		if _, ok := sentinelDto.Files[fileName]; !ok {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+	sort.Strings(fileNames)
+
+	manifest := &BackupManifest{
+		Files:    make([]BackupManifestFile, 0, len(fileNames)),
+		Timeline: timeline,
+		StartLSN: *sentinelDto.BackupStartLSN,
+		EndLSN:   *sentinelDto.BackupFinishLSN,
+	}
+	for _, fileName := range fileNames {
+		relativePath := strings.TrimPrefix(fileName, "/")
+		info, err := os.Stat(filepath.Join(dbDataDirectory, relativePath))
+		if os.IsNotExist(err) {
+			tracelog.WarningLogger.Printf("File %s is not restored, skipping it in the manifest\n", relativePath)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		file := BackupManifestFile{
+			Path:         relativePath,
+			Size:         info.Size(),
+			LastModified: info.ModTime().UTC().Format(backupManifestTimeFormat),
+		}
+		if description, ok := findRecordedFileDescription(chain, fileName); ok && description.SHA256 != "" {
+			file.Size = description.Size
+			file.LastModified = description.MTime.UTC().Format(backupManifestTimeFormat)
+			file.SHA256 = description.SHA256
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	return manifest, nil
+}
+
+// getDeltaChainSentinels returns the sentinels of the backup and all its delta bases, the backup goes first
+func getDeltaChainSentinels(baseBackupFolder storage.Folder, backupName string) ([]BackupSentinelDto, error) {
+	chain := make([]BackupSentinelDto, 0)
+	for name := backupName; ; {
+		backup := NewBackup(baseBackupFolder, name)
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, sentinelDto)
+		if !sentinelDto.IsIncremental() {
+			return chain, nil
+		}
+		name = *sentinelDto.IncrementFrom
+	}
+}
+
+// findRecordedFileDescription returns the description of the file from the backup where
+// it was packed last, i.e. skips the backups where the file was unchanged
+func findRecordedFileDescription(chain []BackupSentinelDto, fileName string) (internal.BackupFileDescription, bool) {
+	for _, sentinelDto := range chain {
+		description, ok := sentinelDto.Files[fileName]
+		if !ok {
+			return internal.BackupFileDescription{}, false
+		}
+		if !description.IsSkipped {
+			return description, !description.IsIncremented
+		}
+	}
+	return internal.BackupFileDescription{}, false
+}
+
+// HandleBackupManifestWrite writes the backup_manifest into the restored directory
+func HandleBackupManifestWrite(folder storage.Folder, backup internal.Backup, dbDataDirectory string) {
+	manifest, err := NewBackupManifest(folder.GetSubFolder(utility.BaseBackupPath), backup.Name, dbDataDirectory)
+	internal.FatalfOnError("Failed to build the backup manifest: %v\n", err)
+
+	data, err := manifest.Marshal()
+	internal.FatalfOnError("Failed to build the backup manifest: %v\n", err)
+
+	manifestPath := filepath.Join(dbDataDirectory, BackupManifestFilename)
+	err = ioutil.WriteFile(manifestPath, data, 0600)
+	internal.FatalfOnError("Failed to write the backup manifest: %v\n", err)
+	tracelog.InfoLogger.Printf("Backup manifest is written to %s\n", manifestPath)
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestBackupManifest_Marshal(t *testing.T) {
+	manifest := BackupManifest{
+		Files: []BackupManifestFile{
+			{Path: "backup_label", Size: 10, LastModified: "2021-01-02 03:04:05 GMT"},
+			{Path: "base/1/1000", Size: 8192, LastModified: "2021-01-02 03:04:05 GMT", SHA256: "abcd"},
+		},
+		Timeline: 1,
+		StartLSN: 0x2000028,
+		EndLSN:   0x2000100,
+	}
+	data, err := manifest.Marshal()
+	require.NoError(t, err)
+	assert.True(t, json.Valid(data))
+
+	checksumStart := bytes.Index(data, []byte(backupManifestChecksumField))
+	require.True(t, checksumStart > 0)
+	checksum := sha256.Sum256(data[:checksumStart])
+	assert.Equal(t, backupManifestChecksumField+"\""+hex.EncodeToString(checksum[:])+"\"}\n",
+		string(data[checksumStart:]))
+
+	var parsed struct {
+		Files []map[string]interface{}
+		WAL   []map[string]interface{} `json:"WAL-Ranges"`
+	}
+	require.NoError(t, json.Unmarshal(data, &parsed))
+	assert.Len(t, parsed.Files, 2)
+	assert.Equal(t, "SHA256", parsed.Files[1]["Checksum-Algorithm"])
+	assert.NotContains(t, parsed.Files[0], "Checksum-Algorithm")
+	assert.Equal(t, "0/2000028", parsed.WAL[0]["Start-LSN"])
+	assert.Equal(t, "0/2000100", parsed.WAL[0]["End-LSN"])
+}
+
+func TestNewBackupManifest_DeltaChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup_manifest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "base", "1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "base/1/1000"), []byte("full"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "base/1/1001"), []byte("incremented"), 0644))
+
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	fullName := "base_000000010000000000000002"
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	fullLSN, deltaLSN, finishLSN, incrementCount := uint64(0x2000028), uint64(0x4000028), uint64(0x4000100), 1
+	mtime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	putTestSentinel(t, folder, fullName, BackupSentinelDto{BackupStartLSN: &fullLSN, BackupFinishLSN: &fullLSN,
+		Files: internal.BackupFileList{
+			"/base/1/1000": {MTime: mtime, SHA256: "abcd", Size: 4},
+			"/base/1/1001": {MTime: mtime, SHA256: "ef01", Size: 4},
+		}})
+	putTestSentinel(t, folder, deltaName, BackupSentinelDto{BackupStartLSN: &deltaLSN, BackupFinishLSN: &finishLSN,
+		IncrementFromLSN: &fullLSN, IncrementFrom: &fullName, IncrementFullName: &fullName, IncrementCount: &incrementCount,
+		Files: internal.BackupFileList{
+			"/base/1/1000": {IsSkipped: true},
+			"/base/1/1001": {IsIncremented: true},
+		}})
+
+	manifest, err := NewBackupManifest(folder, deltaName, dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), manifest.Timeline)
+	assert.Equal(t, deltaLSN, manifest.StartLSN)
+	assert.Equal(t, finishLSN, manifest.EndLSN)
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, BackupManifestFile{Path: "base/1/1000", Size: 4,
+		LastModified: "2021-01-02 03:04:05 GMT", SHA256: "abcd"}, manifest.Files[0])
+	assert.Equal(t, "base/1/1001", manifest.Files[1].Path)
+	assert.Equal(t, int64(len("incremented")), manifest.Files[1].Size)
+	assert.Empty(t, manifest.Files[1].SHA256)
+}
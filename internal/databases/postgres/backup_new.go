@@ -69,7 +69,7 @@ func checkDBDirectoryForUnwrapNew(dbDataDirectory string, sentinelDto BackupSent
 // Do the job of unpacking Backup object
 func (backup *Backup) unwrapNew(
 	dbDataDirectory string, sentinelDto BackupSentinelDto, filesToUnwrap map[string]bool,
-	createIncrementalFiles, skipRedundantTars bool) (*UnwrapResult, error) {
+	createIncrementalFiles, skipRedundantTars bool, truncatedPolicy TruncatedPolicy) (*UnwrapResult, error) {
 	useNewUnwrapImplementation = true
 	err := checkDBDirectoryForUnwrapNew(dbDataDirectory, sentinelDto)
 	if err != nil {
@@ -77,6 +77,7 @@ func (backup *Backup) unwrapNew(
 	}
 
 	tarInterpreter := NewFileTarInterpreter(dbDataDirectory, sentinelDto, filesToUnwrap, createIncrementalFiles)
+	tarInterpreter.truncatedPolicy = truncatedPolicy
 	tarsToExtract, pgControlKey, err := backup.getTarsToExtract(sentinelDto, filesToUnwrap, skipRedundantTars)
 	if err != nil {
 		return nil, err
@@ -100,7 +101,7 @@ func (backup *Backup) unwrapNew(
 	}
 
 	if needPgControl {
-		readerMakers := []internal.ReaderMaker{internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), pgControlKey)}
+		readerMakers := []internal.ReaderMaker{backup.newTarPartitionReaderMaker(pgControlKey)}
 		err = internal.ExtractAll(tarInterpreter, readerMakers)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to extract pg_control")
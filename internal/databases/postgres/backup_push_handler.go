@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path"
 	"path/filepath"
 	"sync/atomic"
 	"time"
@@ -23,6 +24,11 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
+const (
+	FastCheckpointMode   = "fast"
+	SpreadCheckpointMode = "spread"
+)
+
 type backupFromFuture struct {
 	error
 }
@@ -35,6 +41,32 @@ func (err backupFromFuture) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type backupWithoutFinishLSNError struct {
+	error
+}
+
+func newBackupWithoutFinishLSNError(backupName string) backupWithoutFinishLSNError {
+	return backupWithoutFinishLSNError{errors.Errorf(
+		"Finish LSN of backup %v is unknown, it can't be the delta base, use --full", backupName)}
+}
+
+func (err backupWithoutFinishLSNError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type deltaBaseIsIncrementalError struct {
+	error
+}
+
+func newDeltaBaseIsIncrementalError(backupName string) deltaBaseIsIncrementalError {
+	return deltaBaseIsIncrementalError{errors.Errorf(
+		"Selected delta base %s is a delta backup itself. Use --allow-delta-base to make a delta from it.", backupName)}
+}
+
+func (err deltaBaseIsIncrementalError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 type backupFromOtherBD struct {
 	error
 }
@@ -54,11 +86,19 @@ type BackupArguments struct {
 	storeAllCorruptBlocks bool
 	tarBallComposerType   TarBallComposerType
 	userData              string
+	retentionClass        string
 	forceIncremental      bool
 	backupsFolder         string
 	pgDataDirectory       string
 	isFullBackup          bool
 	deltaBaseSelector     internal.BackupSelector
+	fastCheckpoint        bool
+	guaranteedConsistent  bool
+	consistencyTimeout    time.Duration
+	noWaitForWal          bool
+	allowDeltaBase        bool
+	// limitedMode tolerates the failures of the queries restricted on managed Postgres
+	limitedMode bool
 }
 
 // CurBackupInfo holds all information that is harvest during the backup process
@@ -76,6 +116,7 @@ type CurBackupInfo struct {
 type PrevBackupInfo struct {
 	name        string
 	sentinelDto BackupSentinelDto
+	tarSizes    map[string]int64
 }
 
 // BackupWorkers holds the external objects that the handler uses to get the backup data / write the backup data
@@ -90,6 +131,8 @@ type BackupPgInfo struct {
 	pgVersion        int
 	pgDataDirectory  string
 	systemIdentifier *uint64
+	walSegmentBytes  *uint64
+	pageSize         *uint64
 }
 
 // BackupHandler is the main struct which is handling the backup process
@@ -99,12 +142,19 @@ type BackupHandler struct {
 	arguments      BackupArguments
 	workers        BackupWorkers
 	pgInfo         BackupPgInfo
+	// aborts the backup on the server if backup-push is interrupted
+	interruption      *interruptionHandler
+	removeAbortBackup func()
+	fileChangePolicy  FileChangePolicy
+	symlinkPolicy     SymlinkPolicy
 }
 
 // NewBackupArguments creates a BackupArgument object to hold the arguments from the cmd
 func NewBackupArguments(pgDataDirectory string, backupsFolder string, isPermanent bool, verifyPageChecksums bool,
 	isFullBackup bool, storeAllCorruptBlocks bool, tarBallComposerType TarBallComposerType,
-	deltaBaseSelector internal.BackupSelector, userData string) BackupArguments {
+	deltaBaseSelector internal.BackupSelector, userData string, fastCheckpoint bool,
+	guaranteedConsistent bool, consistencyTimeout time.Duration, noWaitForWal bool, allowDeltaBase bool,
+	limitedMode bool, retentionClass string) BackupArguments {
 	return BackupArguments{
 		pgDataDirectory:       pgDataDirectory,
 		backupsFolder:         backupsFolder,
@@ -115,6 +165,27 @@ func NewBackupArguments(pgDataDirectory string, backupsFolder string, isPermanen
 		tarBallComposerType:   tarBallComposerType,
 		deltaBaseSelector:     deltaBaseSelector,
 		userData:              userData,
+		fastCheckpoint:        fastCheckpoint,
+		guaranteedConsistent:  guaranteedConsistent,
+		consistencyTimeout:    consistencyTimeout,
+		noWaitForWal:          noWaitForWal,
+		allowDeltaBase:        allowDeltaBase,
+		limitedMode:           limitedMode,
+		retentionClass:        retentionClass,
+	}
+}
+
+// ParseCheckpointMode checks the backup start checkpoint mode
+// and returns whether the fast checkpoint is requested
+func ParseCheckpointMode(mode string) (fastCheckpoint bool, err error) {
+	switch mode {
+	case FastCheckpointMode:
+		return true, nil
+	case SpreadCheckpointMode:
+		return false, nil
+	default:
+		return false, errors.Errorf("got incorrect checkpoint mode: '%s', expected one of: '%v'",
+			mode, []string{FastCheckpointMode, SpreadCheckpointMode})
 	}
 }
 
@@ -127,7 +198,7 @@ func getDeltaConfig() (maxDeltas int, fromFull bool) {
 		case "LATEST_FULL":
 			fromFull = true
 		default:
-			tracelog.ErrorLogger.Fatalf("Unknown %s: %s\n", internal.DeltaOriginSetting, origin)
+			internal.Fatalf("Unknown %s: %s\n", internal.DeltaOriginSetting, origin)
 		}
 	}
 	return
@@ -146,12 +217,20 @@ func (bh *BackupHandler) createAndPushBackup() {
 	bh.workers.bundle = NewBundle(bh.pgInfo.pgDataDirectory, crypter, bh.prevBackupInfo.sentinelDto.BackupStartLSN,
 		bh.prevBackupInfo.sentinelDto.Files, arguments.forceIncremental,
 		viper.GetInt64(internal.TarSizeThresholdSetting))
+	bh.workers.bundle.SymlinkPolicy = bh.symlinkPolicy
+	bh.workers.bundle.NoWaitForWal = arguments.noWaitForWal
 
+	// the uploads are cancelled after the server is taken out of the backup mode
+	removeCancelUploads := bh.interruption.addCleanup(bh.workers.uploader.Cancel)
+	defer removeCancelUploads()
 	err = bh.startBackup()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	bh.handleDeltaBackup(folder)
 	tarFileSets := bh.uploadBackup()
 	sentinelDto := bh.setupDTO(tarFileSets)
+	bh.uploadExtraFiles(&sentinelDto)
+	bh.captureReplicationSlots(&sentinelDto)
+	bh.waitForConsistency(folder, bh.workers.uploader.UploadingFolder, &sentinelDto)
 	bh.markBackups(folder, sentinelDto)
 	bh.uploadMetadata(sentinelDto)
 
@@ -169,10 +248,11 @@ func (bh *BackupHandler) startBackup() (err error) {
 
 	tracelog.DebugLogger.Println("Running StartBackup.")
 	backupName, backupStartLSN, err := bh.workers.bundle.StartBackup(
-		bh.workers.conn, utility.CeilTimeUpToMicroseconds(time.Now()).String())
+		bh.workers.conn, utility.CeilTimeUpToMicroseconds(time.Now()).String(), bh.arguments.fastCheckpoint)
 	if err != nil {
 		return
 	}
+	bh.removeAbortBackup = bh.interruption.addCleanup(bh.abortBackup)
 	bh.curBackupInfo.startLSN = backupStartLSN
 	bh.curBackupInfo.name = backupName
 	tracelog.DebugLogger.Printf("Backup name: %s\nBackup start LSN: %d", backupName, backupStartLSN)
@@ -180,18 +260,44 @@ func (bh *BackupHandler) startBackup() (err error) {
 	return
 }
 
+// abortBackup takes the server out of the backup mode when backup-push is interrupted
+func (bh *BackupHandler) abortBackup() {
+	if bh.pgInfo.pgVersion >= 90600 {
+		// the non-exclusive backup is aborted by the server when its session ends
+		tracelog.InfoLogger.Println("Closing the backup connection to abort the backup")
+		tracelog.ErrorLogger.PrintOnError(bh.workers.conn.Close())
+		return
+	}
+
+	conn, err := Connect()
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Failed to connect to Postgres to abort the backup: %v\n", err)
+		return
+	}
+	defer utility.LoggedClose(conn, "")
+	queryRunner, err := NewPgQueryRunner(conn)
+	if err != nil {
+		tracelog.ErrorLogger.Printf("Failed to build query runner to abort the backup: %v\n", err)
+		return
+	}
+	tracelog.ErrorLogger.PrintOnError(queryRunner.abortExclusiveBackup())
+}
+
 func (bh *BackupHandler) handleDeltaBackup(folder storage.Folder) {
 	if len(bh.prevBackupInfo.name) > 0 && bh.prevBackupInfo.sentinelDto.BackupStartLSN != nil {
 		tracelog.InfoLogger.Println("Delta backup enabled")
 		tracelog.DebugLogger.Printf("Previous backup: %s\nBackup start LSN: %d", bh.prevBackupInfo.name,
 			bh.prevBackupInfo.sentinelDto.BackupStartLSN)
+		if bh.prevBackupInfo.sentinelDto.BackupFinishLSN == nil {
+			internal.FatalOnError(newBackupWithoutFinishLSNError(bh.prevBackupInfo.name))
+		}
 		if *bh.prevBackupInfo.sentinelDto.BackupFinishLSN > bh.curBackupInfo.startLSN {
-			tracelog.ErrorLogger.FatalOnError(newBackupFromFuture(bh.prevBackupInfo.name))
+			internal.FatalOnError(newBackupFromFuture(bh.prevBackupInfo.name))
 		}
 		if bh.prevBackupInfo.sentinelDto.SystemIdentifier != nil &&
 			bh.pgInfo.systemIdentifier != nil &&
 			*bh.pgInfo.systemIdentifier != *bh.prevBackupInfo.sentinelDto.SystemIdentifier {
-			tracelog.ErrorLogger.FatalOnError(newBackupFromOtherBD())
+			internal.FatalOnError(newBackupFromOtherBD())
 		}
 		if bh.workers.uploader.getUseWalDelta() {
 			err := bh.workers.bundle.DownloadDeltaMap(folder.GetSubFolder(utility.WalPath), bh.curBackupInfo.startLSN)
@@ -231,40 +337,49 @@ func (bh *BackupHandler) uploadBackup() TarFileSets {
 	bundle := bh.workers.bundle
 	// Start a new tar bundle, walk the pgDataDirectory and upload everything there.
 	tracelog.InfoLogger.Println("Starting a new tar bundle")
-	err := bundle.StartQueue(internal.NewStorageTarBallMaker(bh.curBackupInfo.name, bh.workers.uploader.Uploader))
-	tracelog.ErrorLogger.FatalOnError(err)
+	var tarBallMaker internal.TarBallMaker
+	if viper.GetBool(internal.DedupChunkingSetting) {
+		tracelog.InfoLogger.Println("Dedup chunking is enabled (experimental)")
+		tarBallMaker = internal.NewChunkedStorageTarBallMaker(bh.curBackupInfo.name, bh.workers.uploader.Uploader)
+	} else {
+		tarBallMaker = internal.NewStorageTarBallMaker(bh.curBackupInfo.name, bh.workers.uploader.Uploader)
+	}
+	err := bundle.StartQueue(tarBallMaker)
+	internal.FatalOnError(err)
 
 	tarBallComposerMaker, err := NewTarBallComposerMaker(bh.arguments.tarBallComposerType, bh.workers.conn,
-		NewTarBallFilePackerOptions(bh.arguments.verifyPageChecksums, bh.arguments.storeAllCorruptBlocks))
-	tracelog.ErrorLogger.FatalOnError(err)
+		NewTarBallFilePackerOptions(bh.arguments.verifyPageChecksums, bh.arguments.storeAllCorruptBlocks,
+			viper.GetBool(internal.BackupFileChecksumsSetting), bh.fileChangePolicy))
+	internal.FatalOnError(err)
 
 	err = bundle.SetupComposer(tarBallComposerMaker)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.InfoLogger.Println("Walking ...")
 	err = filepath.Walk(bh.pgInfo.pgDataDirectory, bundle.HandleWalkedFSObject)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.InfoLogger.Println("Packing ...")
 	tarFileSets, err := bundle.PackTarballs()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.DebugLogger.Println("Finishing queue ...")
 	err = bundle.FinishQueue()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.DebugLogger.Println("Uploading pg_control ...")
 	err = bundle.UploadPgControl(bh.workers.uploader.Compressor.FileExtension())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	// Stops backup and write/upload postgres `backup_label` and `tablespace_map` Files
 	tracelog.DebugLogger.Println("Stop backup and upload backup_label and tablespace_map")
 	labelFilesTarBallName, labelFilesList, finishLsn, err := bundle.uploadLabelFiles(bh.workers.conn)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
+	bh.removeAbortBackup()
 	bh.curBackupInfo.endLSN = finishLsn
 	bh.curBackupInfo.uncompressedSize = atomic.LoadInt64(bundle.TarBallQueue.AllTarballsSize)
 	bh.curBackupInfo.compressedSize, err = bh.workers.uploader.UploadedDataSize()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	tarFileSets[labelFilesTarBallName] = append(tarFileSets[labelFilesTarBallName], labelFilesList...)
 	timelineChanged := bundle.checkTimelineChanged(bh.workers.conn)
 	tracelog.DebugLogger.Printf("Labelfiles tarball name: %s", labelFilesTarBallName)
@@ -277,10 +392,10 @@ func (bh *BackupHandler) uploadBackup() TarFileSets {
 	tracelog.DebugLogger.Println("Waiting for all uploads to finish")
 	bh.workers.uploader.Finish()
 	if bh.workers.uploader.Failed.Load().(bool) {
-		tracelog.ErrorLogger.Fatalf("Uploading failed during '%s' backup.\n", bh.curBackupInfo.name)
+		internal.Fatalf("Uploading failed during '%s' backup.\n", bh.curBackupInfo.name)
 	}
 	if timelineChanged {
-		tracelog.ErrorLogger.Fatalf("Cannot finish backup because of changed timeline.")
+		internal.Fatalf("Cannot finish backup because of changed timeline.")
 	}
 	return tarFileSets
 }
@@ -294,10 +409,39 @@ func (bh *BackupHandler) HandleBackupPush() {
 
 	bh.curBackupInfo.startTime = utility.TimeNowCrossPlatformUTC()
 
+	bh.interruption.start()
+	defer bh.interruption.stop()
+
+	if viper.GetBool(internal.BackupLockSetting) {
+		lock, err := AcquireBackupLock(folder)
+		internal.FatalOnError(err)
+		// the lock is released on the fatal errors too, where the deferred calls don't run,
+		// so the failure is logged as the warning: the error logger is held by the fatal error then
+		releaseLock := func() {
+			if err := lock.Release(); err != nil {
+				tracelog.WarningLogger.Printf("Failed to release the backup lock: %v\n", err)
+			}
+		}
+		internal.RegisterExitHook(releaseLock)
+		removeReleaseLock := bh.interruption.addCleanup(releaseLock)
+		defer func() {
+			removeReleaseLock()
+			releaseLock()
+		}()
+		// the backup made without the lock would race with the backup-push holding it now
+		go func() {
+			select {
+			case <-lock.Lost():
+				bh.interruption.fail(lock.LostError())
+			case <-bh.interruption.done:
+			}
+		}()
+	}
+
 	if bh.arguments.pgDataDirectory == "" {
 		if bh.arguments.forceIncremental {
 			tracelog.ErrorLogger.Println("Delta backup not available for remote backup.")
-			tracelog.ErrorLogger.Fatal("To run delta backup, supply [db_directory].")
+			internal.Fatal("To run delta backup, supply [db_directory].")
 		}
 		// If no arg is parsed, try to run remote backup using pglogrepl's BASE_BACKUP functionality
 		tracelog.InfoLogger.Println("Running remote backup through Postgres connection.")
@@ -316,12 +460,16 @@ func (bh *BackupHandler) HandleBackupPush() {
 			bh.arguments.pgDataDirectory, bh.pgInfo.pgDataDirectory)
 	}
 	bh.checkPgVersionAndPgControl()
+	hasDefaultPageSize := bh.checkPageSize()
 
 	if bh.arguments.isFullBackup {
 		tracelog.InfoLogger.Println("Doing full backup.")
+	} else if !hasDefaultPageSize {
+		tracelog.WarningLogger.Printf("Doing full backup: delta backups are supported only for the page size %d\n",
+			DatabasePageSize)
 	} else {
 		err := bh.configureDeltaBackup()
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	}
 
 	bh.createAndPushBackup()
@@ -331,8 +479,14 @@ func (bh *BackupHandler) createAndPushRemoteBackup() {
 	var err error
 	uploader := *bh.workers.uploader
 	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.BaseBackupPath)
+	removeCancelUploads := bh.interruption.addCleanup(uploader.Cancel)
+	defer removeCancelUploads()
 	tracelog.DebugLogger.Printf("Uploading folder: %s", uploader.UploadingFolder)
 
+	if paths, _ := GetExtraFilesSetting(); len(paths) > 0 {
+		tracelog.WarningLogger.Printf("%s is not supported for remote backup, extra files are not captured\n",
+			internal.BackupExtraFilesSetting)
+	}
 	baseBackup := bh.runRemoteBackup()
 	tracelog.InfoLogger.Println("Updating metadata")
 	bh.curBackupInfo.startLSN = uint64(baseBackup.StartLSN)
@@ -340,28 +494,93 @@ func (bh *BackupHandler) createAndPushRemoteBackup() {
 
 	bh.curBackupInfo.uncompressedSize = baseBackup.UncompressedSize
 	bh.curBackupInfo.compressedSize, err = bh.workers.uploader.UploadedDataSize()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	sentinelDto := NewBackupSentinelDto(bh, baseBackup.GetTablespaceSpec(), TarFileSets{})
 	sentinelDto.Files = baseBackup.Files
 	bh.curBackupInfo.name = baseBackup.BackupName()
+	bh.captureReplicationSlots(&sentinelDto)
+	bh.waitForConsistency(bh.workers.uploader.UploadingFolder, uploader.UploadingFolder, &sentinelDto)
 	tracelog.InfoLogger.Println("Uploading metadata")
 	bh.uploadMetadata(sentinelDto)
 	// logging backup set name
 	tracelog.InfoLogger.Printf("Wrote backup with name %s", bh.curBackupInfo.name)
 }
 
+// uploadExtraFiles captures the files outside PGDATA listed in WALG_BACKUP_EXTRA_FILES
+func (bh *BackupHandler) uploadExtraFiles(sentinelDto *BackupSentinelDto) {
+	paths, err := GetExtraFilesSetting()
+	internal.FatalOnError(err)
+	if len(paths) == 0 {
+		return
+	}
+	sentinelDto.ExtraFiles, err = UploadExtraFiles(bh.workers.uploader.Uploader,
+		bh.workers.bundle.Crypter, bh.curBackupInfo.name, paths)
+	internal.FatalOnError(err)
+}
+
+// captureReplicationSlots records the replication slots of the server to the sentinel
+// if WALG_BACKUP_REPLICATION_SLOTS is set
+func (bh *BackupHandler) captureReplicationSlots(sentinelDto *BackupSentinelDto) {
+	if !viper.GetBool(internal.BackupSlotsSetting) {
+		return
+	}
+	slots, err := FetchReplicationSlots()
+	internal.FatalfOnError("Failed to read replication slots: %v\n", err)
+	tracelog.InfoLogger.Printf("Recording %d replication slots to the backup\n", len(slots))
+	sentinelDto.ReplicationSlots = slots
+}
+
+// waitForConsistency waits until the WAL required to restore the backup is archived
+// and marks the sentinel as guaranteed consistent, if requested. If the WAL is not archived in time,
+// the uploaded data of the backup is deleted from the backupFolder, since the backup never gets the sentinel.
+func (bh *BackupHandler) waitForConsistency(folder, backupFolder storage.Folder, sentinelDto *BackupSentinelDto) {
+	if !bh.arguments.guaranteedConsistent {
+		return
+	}
+	timeline, err := ParseTimelineFromBackupName(bh.curBackupInfo.name)
+	internal.FatalOnError(err)
+	err = WaitForBackupWalArchived(folder.GetSubFolder(utility.WalPath),
+		timeline, bh.curBackupInfo.endLSN, bh.arguments.consistencyTimeout)
+	if err != nil {
+		deleteUnfinishedBackup(backupFolder, bh.curBackupInfo.name)
+		internal.FatalOnError(err)
+	}
+	sentinelDto.GuaranteedConsistent = true
+}
+
+// deleteUnfinishedBackup deletes the objects uploaded for the backup without the sentinel
+func deleteUnfinishedBackup(backupFolder storage.Folder, backupName string) {
+	tracelog.InfoLogger.Printf("Deleting the data of the unfinished backup %s\n", backupName)
+	objects, err := storage.ListFolderRecursively(backupFolder.GetSubFolder(backupName))
+	if err == nil {
+		keys := make([]string, 0, len(objects))
+		for _, object := range objects {
+			keys = append(keys, path.Join(backupName, object.GetName()))
+		}
+		err = backupFolder.DeleteObjects(keys)
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to delete the data of the unfinished backup %s, "+
+			"delete the %s folder manually: %v\n", backupName, backupName, err)
+	}
+}
+
 func (bh *BackupHandler) uploadMetadata(sentinelDto BackupSentinelDto) {
 	curBackupName := bh.curBackupInfo.name
-	err := bh.uploadExtendedMetadata(sentinelDto)
+	meta := NewExtendedMetadataDto(bh.arguments.isPermanent, bh.pgInfo.pgDataDirectory,
+		bh.curBackupInfo.startTime, sentinelDto)
+	err := bh.uploadExtendedMetadata(meta)
 	if err != nil {
 		tracelog.ErrorLogger.Printf("Failed to upload metadata file for backup: %s %v", curBackupName, err)
-		tracelog.ErrorLogger.FatalError(err)
+		internal.FatalError(err)
 	}
+	sentinelDto.Finished = true
 	err = internal.UploadSentinel(bh.workers.uploader, sentinelDto, bh.curBackupInfo.name)
 	if err != nil {
 		tracelog.ErrorLogger.Printf("Failed to upload sentinel file for backup: %s", curBackupName)
-		tracelog.ErrorLogger.FatalError(err)
+		internal.FatalError(err)
 	}
+	reportBackupToInventory(curBackupName, meta)
 }
 
 // NewBackupHandler returns a backup handler object, which can handle the backup
@@ -370,11 +589,37 @@ func NewBackupHandler(arguments BackupArguments) (bh *BackupHandler, err error)
 	// and version cannot be read easily using replication connection.
 	// Retrieve both with this helper function which uses a temp connection to postgres.
 
-	uploader, err := ConfigureWalUploader()
+	if arguments.limitedMode {
+		if arguments.pgDataDirectory != "" {
+			return bh, errors.New("the limited mode is supported only for the remote backup, " +
+				"do not supply [db_directory]")
+		}
+		tracelog.WarningLogger.Println("Running in the limited mode for managed Postgres: " +
+			"the system identifier and the data directory are not required")
+	}
+	if arguments.retentionClass != "" {
+		if err = internal.ValidateRetentionClass(arguments.retentionClass); err != nil {
+			return bh, err
+		}
+	}
+
+	uploader, err := ConfigureBackupUploader()
+	if err != nil {
+		return bh, err
+	}
+	fileChangePolicy, err := ParseFileChangePolicy(viper.GetString(internal.BackupFileChangePolicy))
+	if err != nil {
+		return bh, err
+	}
+	symlinkPolicy, err := ParseSymlinkPolicy(viper.GetString(internal.SymlinkPolicySetting))
+	if err != nil {
+		return bh, err
+	}
+	err = configureExcludedFilenames()
 	if err != nil {
 		return bh, err
 	}
-	pgInfo, err := getPgServerInfo()
+	pgInfo, err := getPgServerInfo(arguments.limitedMode)
 	if err != nil {
 		return bh, err
 	}
@@ -390,7 +635,10 @@ func NewBackupHandler(arguments BackupArguments) (bh *BackupHandler, err error)
 		workers: BackupWorkers{
 			uploader: uploader,
 		},
-		pgInfo: pgInfo,
+		pgInfo:           pgInfo,
+		fileChangePolicy: fileChangePolicy,
+		symlinkPolicy:    symlinkPolicy,
+		interruption:     newInterruptionHandler(),
 	}
 
 	return bh, err
@@ -409,29 +657,31 @@ func (bh *BackupHandler) runRemoteBackup() *StreamingBaseBackup {
 	// Connect to postgres and start/finish a nonexclusive backup.
 	tracelog.DebugLogger.Println("Connecting to Postgres (replication connection)")
 	conn, err := pgconn.Connect(context.Background(), "replication=yes")
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	baseBackup := NewStreamingBaseBackup(bh.pgInfo.pgDataDirectory, viper.GetInt64(internal.TarSizeThresholdSetting), conn)
 	tracelog.InfoLogger.Println("Starting remote backup")
 	err = baseBackup.Start(bh.arguments.verifyPageChecksums, diskLimit)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.InfoLogger.Println("Streaming remote backup")
 	err = baseBackup.Upload(bh.workers.uploader)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.InfoLogger.Println("Finishing backup")
 	tracelog.InfoLogger.Println("If wal-g hangs during this step, please Postgres log file for details.")
 	err = baseBackup.Finish()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	tracelog.DebugLogger.Println("Closing Postgres connection (replication connection)")
 	err = conn.Close(context.Background())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	return baseBackup
 }
 
-func getPgServerInfo() (pgInfo BackupPgInfo, err error) {
+// getPgServerInfo reads the server info. In the limited mode the queries
+// which are unavailable on managed Postgres are allowed to fail.
+func getPgServerInfo(limitedMode bool) (pgInfo BackupPgInfo, err error) {
 	// Creating a temporary connection to read slot info and wal_segment_size
 	tracelog.DebugLogger.Println("Initializing tmp connection to read Postgres info")
 	tmpConn, err := Connect()
@@ -445,6 +695,10 @@ func getPgServerInfo() (pgInfo BackupPgInfo, err error) {
 	}
 
 	pgInfo.pgDataDirectory, err = queryRunner.GetDataDir()
+	if err != nil && limitedMode {
+		tracelog.WarningLogger.Printf("Limited mode: couldn't get data directory: '%v'\n", err)
+		pgInfo.pgDataDirectory, err = "", nil
+	}
 	if err != nil {
 		return pgInfo, err
 	}
@@ -458,12 +712,32 @@ func getPgServerInfo() (pgInfo BackupPgInfo, err error) {
 	tracelog.DebugLogger.Printf("Postgres version: %d", queryRunner.Version)
 
 	err = queryRunner.getSystemIdentifier()
+	if err != nil && limitedMode {
+		tracelog.WarningLogger.Printf("Limited mode: couldn't get system identifier: '%v'\n", err)
+		queryRunner.SystemIdentifier, err = nil, nil
+	}
 	if err != nil {
 		return pgInfo, err
 	}
 	pgInfo.systemIdentifier = queryRunner.SystemIdentifier
 	tracelog.DebugLogger.Printf("Postgres SystemIdentifier: %d", queryRunner.Version)
 
+	walSegmentBytes, err := queryRunner.GetWalSegmentBytes()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to read wal_segment_size, it won't be stored in the sentinel: %v\n", err)
+	} else {
+		pgInfo.walSegmentBytes = &walSegmentBytes
+		tracelog.DebugLogger.Printf("Postgres WAL segment size: %d", walSegmentBytes)
+	}
+
+	pageSize, err := queryRunner.GetPageSize()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to read block_size, it won't be stored in the sentinel: %v\n", err)
+	} else {
+		pgInfo.pageSize = &pageSize
+		tracelog.DebugLogger.Printf("Postgres page size: %d", pageSize)
+	}
+
 	err = tmpConn.Close()
 	if err != nil {
 		return pgInfo, err
@@ -472,54 +746,95 @@ func getPgServerInfo() (pgInfo BackupPgInfo, err error) {
 	return pgInfo, err
 }
 
-func (bh *BackupHandler) configureDeltaBackup() (err error) {
+// checkPageSize tells whether the server is compiled with the default BLCKSZ. Otherwise the files are still
+// copied as is, but the increments and the page checksums, which assume the default page size, are disabled.
+func (bh *BackupHandler) checkPageSize() bool {
+	if bh.pgInfo.pageSize == nil || int64(*bh.pgInfo.pageSize) == DatabasePageSize {
+		return true
+	}
+	tracelog.WarningLogger.Printf("The server page size is %d instead of %d\n", *bh.pgInfo.pageSize, DatabasePageSize)
+	if bh.arguments.verifyPageChecksums {
+		tracelog.WarningLogger.Println("Page checksums verification is disabled for the non-default page size")
+		bh.arguments.verifyPageChecksums = false
+	}
+	return false
+}
+
+func (bh *BackupHandler) configureDeltaBackup() error {
+	prevBackupInfo, incrementCount, err := selectDeltaBase(bh.workers.uploader.UploadingFolder,
+		bh.arguments.deltaBaseSelector, bh.arguments.isPermanent, bh.arguments.allowDeltaBase)
+	if err != nil || prevBackupInfo.name == "" {
+		return err
+	}
+	bh.prevBackupInfo = prevBackupInfo
+	bh.curBackupInfo.incrementCount = incrementCount
+	return nil
+}
+
+// selectDeltaBase selects the base of the delta backup according to WALG_DELTA_MAX_STEPS and WALG_DELTA_ORIGIN,
+// incrementCount is the increment count of the delta. The empty base name is returned if the full backup
+// should be made.
+func selectDeltaBase(folder storage.Folder, selector internal.BackupSelector,
+	isPermanent, allowDeltaBase bool) (base PrevBackupInfo, incrementCount int, err error) {
 	maxDeltas, fromFull := getDeltaConfig()
 	if maxDeltas == 0 {
-		return nil
+		return PrevBackupInfo{}, 0, nil
 	}
 
-	folder := bh.workers.uploader.UploadingFolder
 	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
-	previousBackupName, err := bh.arguments.deltaBaseSelector.Select(folder)
+	previousBackupName, err := selector.Select(folder)
 	if err != nil {
 		if _, ok := err.(internal.NoBackupsFoundError); ok {
 			tracelog.InfoLogger.Println("Couldn't find previous backup. Doing full backup.")
-			return nil
+			return PrevBackupInfo{}, 0, nil
 		}
-		return err
+		return PrevBackupInfo{}, 0, err
 	}
 
 	previousBackup := NewBackup(baseBackupFolder, previousBackupName)
 	prevBackupSentinelDto, err := previousBackup.GetSentinel()
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return PrevBackupInfo{}, 0, err
+	}
 
+	// with WALG_DELTA_ORIGIN=LATEST_FULL the delta is made from the full backup of the chain anyway
+	err = checkExplicitDeltaBase(selector, previousBackupName, prevBackupSentinelDto, allowDeltaBase || fromFull)
+	if err != nil {
+		return PrevBackupInfo{}, 0, err
+	}
+
+	incrementCount = 1
 	if prevBackupSentinelDto.IncrementCount != nil {
-		bh.curBackupInfo.incrementCount = *prevBackupSentinelDto.IncrementCount + 1
-	} else {
-		bh.curBackupInfo.incrementCount = 1
+		incrementCount = *prevBackupSentinelDto.IncrementCount + 1
 	}
 
-	if bh.curBackupInfo.incrementCount > maxDeltas {
+	if incrementCount > maxDeltas {
 		tracelog.InfoLogger.Println("Reached max delta steps. Doing full backup.")
-		return nil
+		return PrevBackupInfo{}, 0, nil
+	}
+
+	if prevBackupSentinelDto.GetPageSize() != DatabasePageSize {
+		tracelog.InfoLogger.Printf("Previous backup page size is %d. Doing full backup.\n",
+			prevBackupSentinelDto.GetPageSize())
+		return PrevBackupInfo{}, 0, nil
 	}
 
 	if prevBackupSentinelDto.BackupStartLSN == nil {
 		tracelog.InfoLogger.Println("LATEST backup was made without support for delta feature. " +
 			"Fallback to full backup with LSN marker for future deltas.")
-		return nil
+		return PrevBackupInfo{}, 0, nil
 	}
 
 	previousBackupMeta, err := previousBackup.FetchMeta()
 	if err != nil {
 		tracelog.InfoLogger.Printf(
 			"Failed to get previous backup metadata: %s. Doing full backup.\n", err.Error())
-		return nil
+		return PrevBackupInfo{}, 0, nil
 	}
 
-	if !bh.arguments.isPermanent && !fromFull && previousBackupMeta.IsPermanent {
+	if !isPermanent && !fromFull && previousBackupMeta.IsPermanent {
 		tracelog.InfoLogger.Println("Can't do a delta backup from permanent backup. Doing full backup.")
-		return nil
+		return PrevBackupInfo{}, 0, nil
 	}
 
 	if fromFull {
@@ -532,35 +847,53 @@ func (bh *BackupHandler) configureDeltaBackup() (err error) {
 		previousBackup := NewBackup(baseBackupFolder, previousBackupName)
 		prevBackupSentinelDto, err = previousBackup.GetSentinel()
 		if err != nil {
-			return err
+			return PrevBackupInfo{}, 0, err
 		}
 	}
+	// the delta records the tar partitions of its base to detect the base modified later
+	prevBackup := NewBackup(baseBackupFolder, previousBackupName)
+	prevBackupTarSizes, err := prevBackup.GetTarSizes()
+	if err != nil {
+		return PrevBackupInfo{}, 0, err
+	}
 	tracelog.InfoLogger.Printf("Delta backup from %v with LSN %x.\n", previousBackupName,
 		*prevBackupSentinelDto.BackupStartLSN)
-	bh.prevBackupInfo.name = previousBackupName
-	bh.prevBackupInfo.sentinelDto = prevBackupSentinelDto
+	return PrevBackupInfo{previousBackupName, prevBackupSentinelDto, prevBackupTarSizes}, incrementCount, nil
+}
+
+// checkExplicitDeltaBase forbids the delta from the delta backup pinned by name
+// unless it is explicitly allowed, to avoid building deep delta chains by mistake
+func checkExplicitDeltaBase(selector internal.BackupSelector, backupName string,
+	sentinelDto BackupSentinelDto, allowDeltaBase bool) error {
+	if _, isPinned := selector.(internal.BackupNameSelector); !isPinned || allowDeltaBase {
+		return nil
+	}
+	if sentinelDto.IsIncremental() {
+		return newDeltaBaseIsIncrementalError(backupName)
+	}
 	return nil
 }
 
 // TODO : unit tests
-func (bh *BackupHandler) uploadExtendedMetadata(sentinelDto BackupSentinelDto) (err error) {
-	meta := NewExtendedMetadataDto(bh.arguments.isPermanent, bh.pgInfo.pgDataDirectory,
-		bh.curBackupInfo.startTime, sentinelDto)
+func (bh *BackupHandler) uploadExtendedMetadata(meta ExtendedMetadataDto) (err error) {
+	return uploadExtendedMetadata(bh.workers.uploader, bh.curBackupInfo.name, meta)
+}
 
-	metaFile := storage.JoinPath(bh.curBackupInfo.name, utility.MetadataFileName)
+func uploadExtendedMetadata(uploader internal.UploaderProvider, backupName string, meta ExtendedMetadataDto) error {
+	metaFile := storage.JoinPath(backupName, utility.MetadataFileName)
 	dtoBody, err := json.Marshal(meta)
 	if err != nil {
 		return internal.NewSentinelMarshallingError(metaFile, err)
 	}
 	tracelog.DebugLogger.Printf("Uploading metadata file (%s):\n%s", metaFile, dtoBody)
-	return bh.workers.uploader.Upload(metaFile, bytes.NewReader(dtoBody))
+	return uploader.Upload(metaFile, bytes.NewReader(dtoBody))
 }
 
 func (bh *BackupHandler) checkPgVersionAndPgControl() {
 	_, err := ioutil.ReadFile(filepath.Join(bh.pgInfo.pgDataDirectory, PgControlPath))
-	tracelog.ErrorLogger.FatalfOnError(
+	internal.FatalfOnError(
 		"It looks like you are trying to backup not pg_data. PgControl file not found: %v\n", err)
 	_, err = ioutil.ReadFile(filepath.Join(bh.pgInfo.pgDataDirectory, "PG_VERSION"))
-	tracelog.ErrorLogger.FatalfOnError(
+	internal.FatalfOnError(
 		"It looks like you are trying to backup not pg_data. PG_VERSION file not found: %v\n", err)
 }
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestCheckExplicitDeltaBase(t *testing.T) {
+	fullName := "base_000000010000000000000002"
+	incrementCount := 1
+	incrementFromLSN := uint64(0x2000028)
+	deltaSentinel := BackupSentinelDto{IncrementFrom: &fullName, IncrementFullName: &fullName,
+		IncrementFromLSN: &incrementFromLSN, IncrementCount: &incrementCount}
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	nameSelector, _ := internal.NewBackupNameSelector(deltaName)
+
+	err := checkExplicitDeltaBase(nameSelector, deltaName, deltaSentinel, false)
+	assert.IsType(t, deltaBaseIsIncrementalError{}, err)
+	assert.NoError(t, checkExplicitDeltaBase(nameSelector, deltaName, deltaSentinel, true))
+	assert.NoError(t, checkExplicitDeltaBase(internal.NewLatestBackupSelector(), deltaName, deltaSentinel, false))
+	assert.NoError(t, checkExplicitDeltaBase(nameSelector, fullName, BackupSentinelDto{}, false))
+}
+
+func TestNewBackupHandler_LimitedModeRequiresRemoteBackup(t *testing.T) {
+	arguments := BackupArguments{pgDataDirectory: "/var/lib/postgresql/data", limitedMode: true}
+
+	_, err := NewBackupHandler(arguments)
+
+	assert.Error(t, err)
+}
+
+func TestDeleteUnfinishedBackup(t *testing.T) {
+	backupFolder := memory.NewFolder("in_memory/basebackups_005/", memory.NewStorage())
+	unfinishedName := "base_000000010000000000000004"
+	finishedName := "base_000000010000000000000002"
+	for _, name := range []string{
+		unfinishedName + internal.TarPartitionFolderName + "part_1.tar.lz4",
+		unfinishedName + internal.TarPartitionFolderName + "part_2.tar.lz4",
+		finishedName + internal.TarPartitionFolderName + "part_1.tar.lz4",
+		internal.SentinelNameFromBackup(finishedName),
+	} {
+		require.NoError(t, backupFolder.PutObject(name, strings.NewReader("mock")))
+	}
+
+	deleteUnfinishedBackup(backupFolder, unfinishedName)
+
+	exists, err := backupFolder.Exists(unfinishedName + internal.TarPartitionFolderName + "part_1.tar.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = backupFolder.Exists(unfinishedName + internal.TarPartitionFolderName + "part_2.tar.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = backupFolder.Exists(finishedName + internal.TarPartitionFolderName + "part_1.tar.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
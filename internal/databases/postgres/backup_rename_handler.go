@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/copy"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type BackupRenameError struct {
+	error
+}
+
+func newBackupRenameError(backupName, newName, reason string) BackupRenameError {
+	return BackupRenameError{errors.Errorf("can't rename backup %s to %s: %s", backupName, newName, reason)}
+}
+
+func (err BackupRenameError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// checkRenameable checks that the new name can be told apart from the other backup objects
+// and keeps the WAL segment of the name, which is used to order the backups and locate their WAL
+func checkRenameable(baseBackupFolder storage.Folder, backupName, newName string) error {
+	switch {
+	case newName == "" || newName == internal.LatestString:
+		return newBackupRenameError(backupName, newName, "the new name is reserved")
+	case !regexpPgBackupNameWhole.MatchString(newName):
+		return newBackupRenameError(backupName, newName, fmt.Sprintf(
+			"the new name must match '%s' to be found in the storage", regexpPgBackupNameWhole))
+	case utility.StripWalFileName(newName) != utility.StripWalFileName(backupName):
+		return newBackupRenameError(backupName, newName, fmt.Sprintf(
+			"the new name must contain the WAL segment %s of the backup name", utility.StripWalFileName(backupName)))
+	}
+	objects, err := backupObjects(baseBackupFolder, newName)
+	if err != nil {
+		return err
+	}
+	if len(objects) > 0 {
+		return newBackupRenameError(backupName, newName, "the backup with the new name already exists")
+	}
+	return nil
+}
+
+// backupObjects returns the sentinel, the metadata and the tar partitions of the backup
+func backupObjects(baseBackupFolder storage.Folder, backupName string) ([]storage.Object, error) {
+	objects, err := storage.ListFolderRecursively(baseBackupFolder)
+	if err != nil {
+		return nil, err
+	}
+	backupObjects := make([]storage.Object, 0)
+	for _, object := range objects {
+		if utility.StripLeftmostBackupName(object.GetName()) == backupName {
+			backupObjects = append(backupObjects, object)
+		}
+	}
+	return backupObjects, nil
+}
+
+// renameIncrementBase points the deltas made from the backup, directly or through their delta chain,
+// to the new name
+func renameIncrementBase(baseBackupFolder storage.Folder, backupName, newName string) error {
+	backups, err := internal.GetBackups(baseBackupFolder)
+	if err != nil {
+		return err
+	}
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	for _, backupTime := range backups {
+		backup := NewBackup(baseBackupFolder, backupTime.BackupName)
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return err
+		}
+		renamed := false
+		for _, increment := range []*string{sentinelDto.IncrementFrom, sentinelDto.IncrementFullName} {
+			if increment != nil && *increment == backupName {
+				*increment = newName
+				renamed = true
+			}
+		}
+		if !renamed {
+			continue
+		}
+		tracelog.InfoLogger.Printf("Pointing delta backup %s to %s\n", backupTime.BackupName, newName)
+		if err = internal.UploadSentinel(uploader, sentinelDto, backupTime.BackupName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameBackup copies the objects of the backup to the new name, the sentinel last,
+// so the backup is complete under the new name before the objects of the old name are deleted
+func RenameBackup(baseBackupFolder storage.Folder, backupName, newName string) error {
+	if err := checkRenameable(baseBackupFolder, backupName, newName); err != nil {
+		return err
+	}
+	objects, err := backupObjects(baseBackupFolder, backupName)
+	if err != nil {
+		return err
+	}
+	sentinelName := internal.SentinelNameFromBackup(backupName)
+	rename := func(object storage.Object) string {
+		return newName + strings.TrimPrefix(object.GetName(), backupName)
+	}
+	isSentinel := func(object storage.Object) bool { return object.GetName() == sentinelName }
+	isNotSentinel := func(object storage.Object) bool { return !isSentinel(object) }
+
+	tracelog.InfoLogger.Printf("Copying backup %s to %s\n", backupName, newName)
+	err = copy.Infos(copy.BuildCopyingInfos(baseBackupFolder, baseBackupFolder, objects, isNotSentinel, rename))
+	if err != nil {
+		return errors.Wrap(err, "failed to copy the backup objects")
+	}
+	err = copy.Infos(copy.BuildCopyingInfos(baseBackupFolder, baseBackupFolder, objects, isSentinel, rename))
+	if err != nil {
+		return errors.Wrap(err, "failed to copy the backup sentinel")
+	}
+	if err = renameIncrementBase(baseBackupFolder, backupName, newName); err != nil {
+		return errors.Wrap(err, "failed to point the delta backups to the new name")
+	}
+
+	tracelog.InfoLogger.Printf("Deleting the objects of backup %s\n", backupName)
+	if err = baseBackupFolder.DeleteObjects([]string{sentinelName}); err != nil {
+		return err
+	}
+	return storage.DeleteObjectsWhere(baseBackupFolder, true, func(object storage.Object) bool {
+		return utility.StripLeftmostBackupName(object.GetName()) == backupName
+	})
+}
+
+// HandleBackupRename renames the backup, the deltas made from it are updated to the new name
+func HandleBackupRename(folder storage.Folder, backupName, newName string) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	internal.FatalOnError(err)
+	err = RenameBackup(folder.GetSubFolder(utility.BaseBackupPath), backup.Name, newName)
+	internal.FatalfOnError("Failed to rename the backup: %v\n", err)
+	tracelog.InfoLogger.Printf("Renamed backup %s to %s\n", backup.Name, newName)
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const renamedFullName = "nightly_000000010000000000000002"
+
+func TestRenameBackup(t *testing.T) {
+	baseBackupFolder := prepareFlattenChain(t, false)
+	require.NoError(t, baseBackupFolder.PutObject(flattenFullName+"/tar_partitions/part_1.tar.lz4", &bytes.Buffer{}))
+	require.NoError(t, RenameBackup(baseBackupFolder, flattenFullName, renamedFullName))
+
+	for _, name := range []string{internal.SentinelNameFromBackup(flattenFullName),
+		flattenFullName + "/tar_partitions/part_1.tar.lz4"} {
+		exists, err := baseBackupFolder.Exists(name)
+		require.NoError(t, err)
+		assert.False(t, exists, name)
+	}
+	for _, name := range []string{internal.SentinelNameFromBackup(renamedFullName),
+		renamedFullName + "/tar_partitions/part_1.tar.lz4"} {
+		exists, err := baseBackupFolder.Exists(name)
+		require.NoError(t, err)
+		assert.True(t, exists, name)
+	}
+
+	delta := NewBackup(baseBackupFolder, flattenDeltaName)
+	sentinelDto, err := delta.GetSentinel()
+	require.NoError(t, err)
+	assert.Equal(t, renamedFullName, *sentinelDto.IncrementFrom)
+}
+
+func TestRenameBackup_RefusesInvalidNames(t *testing.T) {
+	baseBackupFolder := prepareFlattenChain(t, false)
+	for _, newName := range []string{"", internal.LatestString, flattenFullName,
+		"nightly/000000010000000000000002", "nightly_000000010000000000000002_backup",
+		"nightly_000000010000000000000004", "night-ly_000000010000000000000002", "nightly_00000001000000000000002",
+		"nightly_000000010000000000000002X", "_000000010000000000000002"} {
+		err := RenameBackup(baseBackupFolder, flattenFullName, newName)
+		assert.IsType(t, BackupRenameError{}, err, newName)
+	}
+	exists, err := baseBackupFolder.Exists(internal.SentinelNameFromBackup(flattenFullName))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
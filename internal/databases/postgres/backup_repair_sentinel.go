@@ -0,0 +1,346 @@
+package postgres
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+const deltaBackupNameSeparator = "_D_"
+
+type SentinelExistsError struct {
+	error
+}
+
+func newSentinelExistsError(backupName string) SentinelExistsError {
+	return SentinelExistsError{errors.Errorf(
+		"backup %s has a readable sentinel, use --force to replace it with the reconstructed one", backupName)}
+}
+
+func (err SentinelExistsError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type NoTarPartitionsError struct {
+	error
+}
+
+func newNoTarPartitionsError(backupName string) NoTarPartitionsError {
+	return NoTarPartitionsError{errors.Errorf("backup %s has no tar partitions to reconstruct the sentinel from", backupName)}
+}
+
+func (err NoTarPartitionsError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// tarPartitionScanner collects what a single tar partition tells about the backup:
+// the entries and the values stored in backup_label, pg_control and PG_VERSION.
+// The entries are keyed by the name, so a retried extraction does not count them twice.
+type tarPartitionScanner struct {
+	files            internal.BackupFileList
+	sizes            map[string]int64
+	startLSN         *uint64
+	systemIdentifier *uint64
+	pgVersion        int
+}
+
+func newTarPartitionScanner() *tarPartitionScanner {
+	return &tarPartitionScanner{
+		files: make(internal.BackupFileList),
+		sizes: make(map[string]int64),
+	}
+}
+
+func (scanner *tarPartitionScanner) Interpret(reader io.Reader, header *tar.Header) error {
+	description := internal.BackupFileDescription{MTime: header.ModTime}
+	if header.Typeflag == tar.TypeReg {
+		switch "/" + strings.TrimPrefix(header.Name, "/") {
+		case "/" + BackupLabelFilename:
+			startLSN, err := parseBackupLabelStartLSN(reader, header.Name)
+			if err != nil {
+				return err
+			}
+			scanner.startLSN = &startLSN
+		case PgControlPath:
+			systemIdentifier, err := parsePgControlSystemIdentifier(reader, header.Name)
+			if err != nil {
+				return err
+			}
+			scanner.systemIdentifier = &systemIdentifier
+		case "/" + PgVersionFilename:
+			content, err := ioutil.ReadAll(reader)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %s", header.Name)
+			}
+			pgVersion, err := parsePgVersionFile(string(content))
+			if err != nil {
+				return err
+			}
+			scanner.pgVersion = pgVersion
+		default:
+			// the increments start with the increment file header, the files packed in full don't
+			if _, err := readIncrementFileVersion(reader); err == nil {
+				description.IsIncremented = true
+			}
+		}
+	}
+	scanner.files[header.Name] = description
+	scanner.sizes[header.Name] = header.Size
+	return nil
+}
+
+// parsePgVersionFile converts the content of PG_VERSION (e.g. 9.6 or 13)
+// to the numeric server version of its first release (90600 or 130000)
+func parsePgVersionFile(content string) (int, error) {
+	version := strings.TrimSpace(content)
+	parts := strings.SplitN(version, ".", 2)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse %s '%s'", PgVersionFilename, version)
+	}
+	minor := 0
+	if len(parts) == 2 {
+		minor, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse %s '%s'", PgVersionFilename, version)
+		}
+	}
+	if major >= 10 {
+		return major * 10000, nil
+	}
+	return major*10000 + minor*100, nil
+}
+
+// tarPartitionCompression returns the compression method of the tar partition by its extension
+func tarPartitionCompression(tarName string) (string, error) {
+	extension := strings.TrimPrefix(path.Ext(tarName), ".")
+	if extension == "tar" {
+		return "none", nil
+	}
+	if compression.FindDecompressor(extension) == nil {
+		return "", errors.Errorf("tar partition %s has unknown compression extension '%s'", tarName, extension)
+	}
+	return extension, nil
+}
+
+func isUtilityFile(name string) bool {
+	relativeName := strings.TrimPrefix(name, "/")
+	return UtilityFilePaths[relativeName] || UtilityFilePaths["/"+relativeName]
+}
+
+// ReconstructSentinel rebuilds the sentinel of the backup from the tar partitions actually stored:
+// the file list, the sizes, the start LSN, the system identifier and the PostgreSQL version.
+// The sentinel is marked as reconstructed. The returned warnings describe what could not be recovered.
+func ReconstructSentinel(baseBackupFolder storage.Folder, backupName string) (BackupSentinelDto, []string, error) {
+	backup := NewBackup(baseBackupFolder, backupName)
+	tarSizes, err := backup.GetTarSizes()
+	if err != nil {
+		return BackupSentinelDto{}, nil, err
+	}
+	if len(tarSizes) == 0 {
+		return BackupSentinelDto{}, nil, newNoTarPartitionsError(backupName)
+	}
+	tarNames := make([]string, 0, len(tarSizes))
+	compressionMethodSet := make(map[string]bool)
+	for tarName := range tarSizes {
+		method, err := tarPartitionCompression(tarName)
+		if err != nil {
+			return BackupSentinelDto{}, nil, err
+		}
+		compressionMethodSet[method] = true
+		tarNames = append(tarNames, tarName)
+	}
+	sort.Strings(tarNames)
+	compressionMethods := make([]string, 0, len(compressionMethodSet))
+	for method := range compressionMethodSet {
+		compressionMethods = append(compressionMethods, method)
+	}
+	sort.Strings(compressionMethods)
+	tracelog.InfoLogger.Printf("Scanning %d tar partitions of backup %s, compression: %s\n",
+		len(tarNames), backupName, strings.Join(compressionMethods, ", "))
+
+	scanners, err := scanTarPartitions(backup, tarNames)
+	if err != nil {
+		return BackupSentinelDto{}, nil, err
+	}
+
+	sentinelDto := BackupSentinelDto{
+		Files:         make(internal.BackupFileList),
+		TarFileSets:   make(TarFileSets),
+		Reconstructed: true,
+	}
+	var warnings []string
+	hasIncrements, hasTablespaces := false, false
+	for i, tarName := range tarNames {
+		scanner := scanners[i]
+		for name, description := range scanner.files {
+			sentinelDto.TarFileSets[tarName] = append(sentinelDto.TarFileSets[tarName], name)
+			sentinelDto.UncompressedSize += scanner.sizes[name]
+			hasIncrements = hasIncrements || description.IsIncremented
+			hasTablespaces = hasTablespaces || strings.HasPrefix(name, "/"+TablespaceFolder+"/")
+			if !isUtilityFile(name) {
+				sentinelDto.Files[name] = description
+			}
+		}
+		sort.Strings(sentinelDto.TarFileSets[tarName])
+		sentinelDto.CompressedSize += tarSizes[tarName]
+		if scanner.startLSN != nil {
+			sentinelDto.BackupStartLSN = scanner.startLSN
+		}
+		if scanner.systemIdentifier != nil {
+			sentinelDto.SystemIdentifier = scanner.systemIdentifier
+		}
+		if scanner.pgVersion != 0 {
+			sentinelDto.PgVersion = scanner.pgVersion
+		}
+	}
+
+	if sentinelDto.BackupStartLSN == nil {
+		startLSN, err := startLSNFromBackupName(backupName)
+		if err != nil {
+			return BackupSentinelDto{}, nil, errors.Wrapf(err, "%s is not found and the start LSN can't be "+
+				"derived from backup name %s", BackupLabelFilename, backupName)
+		}
+		sentinelDto.BackupStartLSN = &startLSN
+		warnings = append(warnings, fmt.Sprintf("%s is not found, the start LSN %x is the start of the WAL segment "+
+			"from the backup name", BackupLabelFilename, startLSN))
+	}
+	warnings = append(warnings, "finish LSN is unknown: backup-fetch --consistency-wal, the backup manifest "+
+		"and the delta backups based on this backup are not available")
+	if sentinelDto.SystemIdentifier == nil {
+		warnings = append(warnings, fmt.Sprintf("%s is not found, the system identifier is unknown", PgControlPath))
+	}
+	if sentinelDto.PgVersion == 0 {
+		warnings = append(warnings, fmt.Sprintf("%s is not found, the PostgreSQL version is unknown", PgVersionFilename))
+	}
+	if hasTablespaces {
+		warnings = append(warnings, "the tablespace specification is lost, "+
+			"provide it with backup-fetch --restore-spec to restore the tablespaces")
+	}
+	warnings = append(warnings, "the user data, the replication slots and the file checksums are lost")
+
+	if hasIncrements || strings.Contains(backupName, deltaBackupNameSeparator) {
+		warnings = append(warnings, reconstructDeltaBase(baseBackupFolder, backupName, &sentinelDto)...)
+	}
+	return sentinelDto, warnings, nil
+}
+
+// scanTarPartitions reads every tar partition of the backup, as many at once as the download concurrency allows
+func scanTarPartitions(backup Backup, tarNames []string) ([]*tarPartitionScanner, error) {
+	concurrency, err := internal.GetMaxDownloadConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	scanners := make([]*tarPartitionScanner, len(tarNames))
+	tickets := make(chan struct{}, concurrency)
+	errorGroup := new(errgroup.Group)
+	for i, tarName := range tarNames {
+		i, tarName := i, tarName
+		scanners[i] = newTarPartitionScanner()
+		tickets <- struct{}{}
+		errorGroup.Go(func() error {
+			defer func() { <-tickets }()
+			err := internal.ExtractAll(scanners[i], []internal.ReaderMaker{backup.newTarPartitionReaderMaker(tarName)})
+			return errors.Wrapf(err, "failed to scan tar partition %s", tarName)
+		})
+	}
+	return scanners, errorGroup.Wait()
+}
+
+func startLSNFromBackupName(backupName string) (uint64, error) {
+	_, logSegNo, err := ParseWALFilename(utility.StripWalFileName(backupName))
+	if err != nil {
+		return 0, err
+	}
+	return logSegNo * WalSegmentSize, nil
+}
+
+// reconstructDeltaBase fills the delta fields of the sentinel from the sentinel of the base backup named
+// in the delta backup name. The files of the base missing in the delta partitions are taken as unchanged.
+func reconstructDeltaBase(baseBackupFolder storage.Folder, backupName string, sentinelDto *BackupSentinelDto) []string {
+	separatorIndex := strings.Index(backupName, deltaBackupNameSeparator)
+	if separatorIndex < 0 {
+		return []string{"the backup has increments, but its name does not name the delta base: " +
+			"the incremented files can't be restored"}
+	}
+	baseName := utility.GetBackupNamePrefix() + backupName[separatorIndex+len(deltaBackupNameSeparator):]
+	baseBackup := NewBackup(baseBackupFolder, baseName)
+	baseSentinelDto, err := baseBackup.GetSentinel()
+	if err != nil || baseSentinelDto.BackupStartLSN == nil {
+		return []string{fmt.Sprintf("the sentinel of delta base %s is not readable, repair it first "+
+			"and repeat the repair of this backup: %v", baseName, err)}
+	}
+
+	sentinelDto.IncrementFrom = &baseName
+	sentinelDto.IncrementFromLSN = baseSentinelDto.BackupStartLSN
+	incrementCount := 1
+	if baseSentinelDto.IsIncremental() {
+		sentinelDto.IncrementFullName = baseSentinelDto.IncrementFullName
+		incrementCount = *baseSentinelDto.IncrementCount + 1
+	} else {
+		sentinelDto.IncrementFullName = &baseName
+	}
+	sentinelDto.IncrementCount = &incrementCount
+	if sentinelDto.SystemIdentifier == nil {
+		sentinelDto.SystemIdentifier = baseSentinelDto.SystemIdentifier
+	}
+	if sentinelDto.PgVersion == 0 {
+		sentinelDto.PgVersion = baseSentinelDto.PgVersion
+	}
+	sentinelDto.TablespaceSpec = baseSentinelDto.TablespaceSpec
+
+	for name, description := range baseSentinelDto.Files {
+		if _, ok := sentinelDto.Files[name]; !ok {
+			sentinelDto.Files[name] = internal.BackupFileDescription{IsSkipped: true, MTime: description.MTime}
+		}
+	}
+	return []string{fmt.Sprintf("the files of delta base %s missing in the tar partitions are taken as unchanged, "+
+		"the files deleted after the base backup would be restored", baseName)}
+}
+
+func warnIfSentinelReconstructed(backupName string, sentinelDto BackupSentinelDto) {
+	if sentinelDto.Reconstructed {
+		tracelog.WarningLogger.Printf("The sentinel of backup %s was reconstructed by backup-repair-sentinel, "+
+			"the finish LSN is unknown: make sure the WAL up to the consistent point is available\n", backupName)
+	}
+}
+
+// HandleBackupRepairSentinel reconstructs the sentinel of the backup from its tar partitions and uploads it.
+// The readable sentinel is replaced only if force is set.
+func HandleBackupRepairSentinel(folder storage.Folder, backupName string, force bool) {
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	backup := NewBackup(baseBackupFolder, backupName)
+	if _, err := backup.GetSentinel(); err == nil && !force {
+		internal.FatalOnError(newSentinelExistsError(backupName))
+	}
+
+	sentinelDto, warnings, err := ReconstructSentinel(baseBackupFolder, backupName)
+	internal.FatalfOnError("Failed to reconstruct the sentinel: %v\n", err)
+	for _, warning := range warnings {
+		tracelog.WarningLogger.Printf("Reconstructed sentinel of %s: %s\n", backupName, warning)
+	}
+	metadataExists, err := baseBackupFolder.Exists(backupName + "/" + utility.MetadataFileName)
+	internal.FatalOnError(err)
+	if !metadataExists {
+		tracelog.WarningLogger.Printf("Backup %s has no %s, backup-list --detail is not available for it\n",
+			backupName, utility.MetadataFileName)
+	}
+
+	err = internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder), sentinelDto, backupName)
+	internal.FatalfOnError("Failed to upload the reconstructed sentinel: %v\n", err)
+	tracelog.InfoLogger.Printf("Reconstructed sentinel of backup %s is uploaded: %d files in %d tar partitions\n",
+		backupName, len(sentinelDto.Files), len(sentinelDto.TarFileSets))
+}
@@ -0,0 +1,87 @@
+package postgres_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestReconstructSentinel(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	backupName := "base_000000010000000000000002"
+	pgControl := make([]byte, 16)
+	binary.LittleEndian.PutUint64(pgControl, 42)
+	tarFolder := baseBackupFolder.GetSubFolder(backupName + internal.TarPartitionFolderName)
+	require.NoError(t, tarFolder.PutObject("pg_control.tar",
+		makeTestTar(t, map[string]string{"/global/pg_control": string(pgControl)})))
+	require.NoError(t, tarFolder.PutObject("part_1.tar",
+		makeTestTar(t, map[string]string{"/base/1/1": "data1", "/PG_VERSION": "9.6\n"}, "/base")))
+	require.NoError(t, tarFolder.PutObject("part_2.tar", makeTestTar(t, map[string]string{
+		postgres.BackupLabelFilename: "START WAL LOCATION: 0/2000028 (file 000000010000000000000002)\n"})))
+
+	sentinelDto, warnings, err := postgres.ReconstructSentinel(baseBackupFolder, backupName)
+	require.NoError(t, err)
+
+	assert.True(t, sentinelDto.Reconstructed)
+	require.NotNil(t, sentinelDto.BackupStartLSN)
+	assert.Equal(t, uint64(0x2000028), *sentinelDto.BackupStartLSN)
+	assert.Nil(t, sentinelDto.BackupFinishLSN)
+	require.NotNil(t, sentinelDto.SystemIdentifier)
+	assert.Equal(t, uint64(42), *sentinelDto.SystemIdentifier)
+	assert.Equal(t, 90600, sentinelDto.PgVersion)
+	assert.False(t, sentinelDto.IsIncremental())
+
+	fileNames := make([]string, 0)
+	for name := range sentinelDto.Files {
+		fileNames = append(fileNames, name)
+	}
+	assert.ElementsMatch(t, []string{"/base", "/base/1/1", "/PG_VERSION"}, fileNames)
+	assert.Equal(t, postgres.TarFileSets{
+		"pg_control.tar": {"/global/pg_control"},
+		"part_1.tar":     {"/PG_VERSION", "/base", "/base/1/1"},
+		"part_2.tar":     {postgres.BackupLabelFilename},
+	}, sentinelDto.TarFileSets)
+	assert.Equal(t, int64(len(pgControl)+len("data1")+len("9.6\n")+
+		len("START WAL LOCATION: 0/2000028 (file 000000010000000000000002)\n")), sentinelDto.UncompressedSize)
+	assert.NotEmpty(t, warnings)
+}
+
+func TestReconstructSentinel_DeltaBackup(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	fullName := "base_000000010000000000000002"
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	fullLSN := uint64(0x2000028)
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder),
+		&postgres.BackupSentinelDto{BackupStartLSN: &fullLSN, PgVersion: 130000, Files: internal.BackupFileList{
+			"/base/1/1": {}, "/base/1/2": {},
+		}}, fullName))
+	tarFolder := baseBackupFolder.GetSubFolder(deltaName + internal.TarPartitionFolderName)
+	require.NoError(t, tarFolder.PutObject("part_1.tar",
+		makeTestTar(t, map[string]string{"/base/1/1": string(postgres.IncrementFileHeader) + "increment"})))
+
+	sentinelDto, _, err := postgres.ReconstructSentinel(baseBackupFolder, deltaName)
+	require.NoError(t, err)
+
+	require.True(t, sentinelDto.IsIncremental())
+	assert.Equal(t, fullName, *sentinelDto.IncrementFrom)
+	assert.Equal(t, fullName, *sentinelDto.IncrementFullName)
+	assert.Equal(t, fullLSN, *sentinelDto.IncrementFromLSN)
+	assert.Equal(t, 1, *sentinelDto.IncrementCount)
+	assert.Equal(t, 130000, sentinelDto.PgVersion)
+	// the start LSN is taken from the backup name without backup_label
+	assert.Equal(t, 4*postgres.WalSegmentSize, *sentinelDto.BackupStartLSN)
+	assert.True(t, sentinelDto.Files["/base/1/1"].IsIncremented)
+	assert.True(t, sentinelDto.Files["/base/1/2"].IsSkipped)
+}
+
+func TestReconstructSentinel_NoTarPartitions(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	_, _, err := postgres.ReconstructSentinel(baseBackupFolder, "base_000000010000000000000002")
+	assert.IsType(t, postgres.NoTarPartitionsError{}, err)
+}
@@ -20,6 +20,8 @@ type BackupSentinelDto struct {
 	IncrementFrom     *string `json:"DeltaFrom,omitempty"`
 	IncrementFullName *string `json:"DeltaFullName,omitempty"`
 	IncrementCount    *int    `json:"DeltaCount,omitempty"`
+	// IncrementFromTarSizes are the storage sizes of the delta base tar partitions when the delta was made
+	IncrementFromTarSizes map[string]int64 `json:"DeltaFromTarSizes,omitempty"`
 
 	Files       internal.BackupFileList `json:"Files"`
 	TarFileSets TarFileSets             `json:"TarFileSets"`
@@ -27,12 +29,26 @@ type BackupSentinelDto struct {
 	PgVersion        int     `json:"PgVersion"`
 	BackupFinishLSN  *uint64 `json:"FinishLSN"`
 	SystemIdentifier *uint64 `json:"SystemIdentifier,omitempty"`
+	WalSegmentSize   *uint64 `json:"WalSegmentSize,omitempty"`
+	PageSize         *uint64 `json:"PageSize,omitempty"`
 
 	UncompressedSize int64           `json:"UncompressedSize"`
 	CompressedSize   int64           `json:"CompressedSize"`
 	TablespaceSpec   *TablespaceSpec `json:"Spec"`
 
 	UserData interface{} `json:"UserData,omitempty"`
+	// RetentionClass is the class of the backup in the grandfather-father-son rotation of delete gfs
+	RetentionClass string `json:"RetentionClass,omitempty"`
+
+	GuaranteedConsistent bool           `json:"GuaranteedConsistent,omitempty"`
+	ExtraFiles           *ExtraFilesDto `json:"ExtraFiles,omitempty"`
+
+	ReplicationSlots []ReplicationSlotDto `json:"ReplicationSlots,omitempty"`
+
+	// Reconstructed is set if the sentinel was rebuilt by backup-repair-sentinel from the tar partitions
+	Reconstructed bool `json:"Reconstructed,omitempty"`
+	// Finished is set right before the upload of the sentinel, when all the other uploads succeeded
+	Finished bool `json:"Finished,omitempty"`
 }
 
 func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec, tarFileSets TarFileSets) BackupSentinelDto {
@@ -50,11 +66,15 @@ func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec, tarFileSet
 			sentinel.IncrementFullName = &bh.prevBackupInfo.name
 		}
 		sentinel.IncrementCount = &bh.curBackupInfo.incrementCount
+		sentinel.IncrementFromTarSizes = bh.prevBackupInfo.tarSizes
 	}
 
 	sentinel.BackupFinishLSN = &bh.curBackupInfo.endLSN
 	sentinel.UserData = internal.UnmarshalSentinelUserData(bh.arguments.userData)
+	sentinel.RetentionClass = bh.arguments.retentionClass
 	sentinel.SystemIdentifier = bh.pgInfo.systemIdentifier
+	sentinel.WalSegmentSize = bh.pgInfo.walSegmentBytes
+	sentinel.PageSize = bh.pgInfo.pageSize
 	sentinel.UncompressedSize = bh.curBackupInfo.uncompressedSize
 	sentinel.CompressedSize = bh.curBackupInfo.compressedSize
 	sentinel.TarFileSets = tarFileSets
@@ -77,7 +97,8 @@ type ExtendedMetadataDto struct {
 	UncompressedSize int64 `json:"uncompressed_size"`
 	CompressedSize   int64 `json:"compressed_size"`
 
-	UserData interface{} `json:"user_data,omitempty"`
+	UserData       interface{} `json:"user_data,omitempty"`
+	RetentionClass string      `json:"retention_class,omitempty"`
 }
 
 func NewExtendedMetadataDto(isPermanent bool, dataDir string, startTime time.Time,
@@ -99,6 +120,7 @@ func NewExtendedMetadataDto(isPermanent bool, dataDir string, startTime time.Tim
 	meta.PgVersion = sentinelDto.PgVersion
 	meta.SystemIdentifier = sentinelDto.SystemIdentifier
 	meta.UserData = sentinelDto.UserData
+	meta.RetentionClass = sentinelDto.RetentionClass
 	meta.UncompressedSize = sentinelDto.UncompressedSize
 	meta.CompressedSize = sentinelDto.CompressedSize
 	return meta
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupWalRange is the range of the WAL segments the backup needs to be consistent
+type BackupWalRange struct {
+	Backup   string `json:"backup"`
+	Timeline uint32 `json:"timeline"`
+	StartLSN string `json:"start_lsn"`
+	// FinishLSN is the LSN of the end of the backup, which points past the last WAL record of the backup
+	FinishLSN      string `json:"finish_lsn"`
+	WalSegmentSize uint64 `json:"wal_segment_size"`
+	// WalSegmentSizeAssumed is set for the backups without the WalSegmentSize in the sentinel,
+	// the configured segment size is used for them
+	WalSegmentSizeAssumed bool   `json:"wal_segment_size_assumed,omitempty"`
+	FirstSegment          string `json:"first_segment"`
+	LastSegment           string `json:"last_segment"`
+	SegmentCount          uint64 `json:"segment_count"`
+}
+
+// formatWALFileNameOfSize is the formatWALFileName for the segment size, which may differ from the configured one
+func formatWALFileNameOfSize(timeline uint32, logSegNo, segmentSize uint64) string {
+	segmentsPerXLogID := 0x100000000 / segmentSize
+	return fmt.Sprintf(walFileFormat, timeline, logSegNo/segmentsPerXLogID, logSegNo%segmentsPerXLogID)
+}
+
+// NewBackupWalRange computes the WAL segments from the start to the finish LSN of the backup.
+// The timeline is the one of the start WAL segment in the backup name.
+func NewBackupWalRange(backupName string, sentinelDto BackupSentinelDto) (BackupWalRange, error) {
+	if sentinelDto.BackupStartLSN == nil || sentinelDto.BackupFinishLSN == nil {
+		return BackupWalRange{}, errors.Errorf("the start or the finish LSN is not recorded in the sentinel of backup %s",
+			backupName)
+	}
+	timeline, _, err := ParseWALFilename(utility.StripWalFileName(backupName))
+	if err != nil {
+		return BackupWalRange{}, errors.Wrapf(err, "can't get the timeline from backup name %s", backupName)
+	}
+	walRange := BackupWalRange{
+		Backup:         backupName,
+		Timeline:       timeline,
+		StartLSN:       formatBackupLabelLsn(*sentinelDto.BackupStartLSN),
+		FinishLSN:      formatBackupLabelLsn(*sentinelDto.BackupFinishLSN),
+		WalSegmentSize: WalSegmentSize,
+	}
+	if sentinelDto.WalSegmentSize != nil {
+		walRange.WalSegmentSize = *sentinelDto.WalSegmentSize
+	} else {
+		walRange.WalSegmentSizeAssumed = true
+	}
+
+	firstLogSegNo := *sentinelDto.BackupStartLSN / walRange.WalSegmentSize
+	// the finish LSN at the segment boundary does not need the next segment, like in getWalFilename
+	lastLogSegNo := firstLogSegNo
+	if *sentinelDto.BackupFinishLSN > *sentinelDto.BackupStartLSN {
+		lastLogSegNo = (*sentinelDto.BackupFinishLSN - 1) / walRange.WalSegmentSize
+	}
+	walRange.FirstSegment = formatWALFileNameOfSize(timeline, firstLogSegNo, walRange.WalSegmentSize)
+	walRange.LastSegment = formatWALFileNameOfSize(timeline, lastLogSegNo, walRange.WalSegmentSize)
+	walRange.SegmentCount = lastLogSegNo - firstLogSegNo + 1
+	return walRange, nil
+}
+
+// HandleBackupShow prints the WAL segments the backup needs to be consistent
+func HandleBackupShow(folder storage.Folder, backupName string, output io.Writer, jsonOutput bool) {
+	backup, err := GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	internal.FatalOnError(err)
+	sentinelDto, err := backup.GetSentinel()
+	internal.FatalOnError(err)
+	walRange, err := NewBackupWalRange(backup.Name, sentinelDto)
+	internal.FatalfOnError("Failed to compute the WAL range of the backup: %v\n", err)
+	if walRange.WalSegmentSizeAssumed {
+		tracelog.WarningLogger.Printf("The WAL segment size is not recorded in the sentinel of backup %s, "+
+			"assuming the configured %d bytes\n", backup.Name, walRange.WalSegmentSize)
+	}
+	if jsonOutput {
+		err = internal.WriteAsJSON(walRange, output, true)
+		internal.FatalOnError(err)
+		return
+	}
+	writeBackupWalRangeTable(walRange, output)
+}
+
+func writeBackupWalRangeTable(walRange BackupWalRange, output io.Writer) {
+	writer := table.NewWriter()
+	writer.SetOutputMirror(output)
+	writer.AppendRows([]table.Row{
+		{"Backup", walRange.Backup},
+		{"Timeline", walRange.Timeline},
+		{"Start LSN", walRange.StartLSN},
+		{"Finish LSN", walRange.FinishLSN},
+		{"WAL segment size", walRange.WalSegmentSize},
+		{"First WAL segment", walRange.FirstSegment},
+		{"Last WAL segment", walRange.LastSegment},
+		{"WAL segments", walRange.SegmentCount},
+	})
+	writer.Render()
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWalRangeSentinel(startLSN, finishLSN uint64, walSegmentSize *uint64) BackupSentinelDto {
+	return BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &finishLSN, WalSegmentSize: walSegmentSize}
+}
+
+func TestNewBackupWalRange_CrossesXLogIDBoundary(t *testing.T) {
+	segmentSize := uint64(WalSegmentSize)
+	walRange, err := NewBackupWalRange("base_0000000100000001000000FF",
+		newWalRangeSentinel(0x1FF000028, 0x200000100, &segmentSize))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), walRange.Timeline)
+	assert.Equal(t, "1/FF000028", walRange.StartLSN)
+	assert.Equal(t, "2/100", walRange.FinishLSN)
+	assert.Equal(t, "0000000100000001000000FF", walRange.FirstSegment)
+	assert.Equal(t, "000000010000000200000000", walRange.LastSegment)
+	assert.Equal(t, uint64(2), walRange.SegmentCount)
+	assert.False(t, walRange.WalSegmentSizeAssumed)
+}
+
+func TestNewBackupWalRange_FinishAtSegmentBoundary(t *testing.T) {
+	segmentSize := uint64(WalSegmentSize)
+	walRange, err := NewBackupWalRange("base_000000020000000000000002",
+		newWalRangeSentinel(0x2000028, 0x4000000, &segmentSize))
+	require.NoError(t, err)
+	assert.Equal(t, "000000020000000000000002", walRange.FirstSegment)
+	assert.Equal(t, "000000020000000000000003", walRange.LastSegment)
+	assert.Equal(t, uint64(2), walRange.SegmentCount)
+}
+
+func TestNewBackupWalRange_SentinelSegmentSize(t *testing.T) {
+	segmentSize := uint64(64 * 1024 * 1024)
+	walRange, err := NewBackupWalRange("base_00000001000000010000003F",
+		newWalRangeSentinel(0x1FC000028, 0x200000100, &segmentSize))
+	require.NoError(t, err)
+	assert.Equal(t, "00000001000000010000003F", walRange.FirstSegment)
+	assert.Equal(t, "000000010000000200000000", walRange.LastSegment)
+	assert.Equal(t, uint64(2), walRange.SegmentCount)
+	assert.Equal(t, segmentSize, walRange.WalSegmentSize)
+}
+
+func TestNewBackupWalRange_SegmentSizeAssumed(t *testing.T) {
+	walRange, err := NewBackupWalRange("base_000000010000000000000002",
+		newWalRangeSentinel(0x2000028, 0x2000100, nil))
+	require.NoError(t, err)
+	assert.True(t, walRange.WalSegmentSizeAssumed)
+	assert.Equal(t, uint64(WalSegmentSize), walRange.WalSegmentSize)
+	assert.Equal(t, walRange.FirstSegment, walRange.LastSegment)
+	assert.Equal(t, uint64(1), walRange.SegmentCount)
+}
+
+func TestNewBackupWalRange_NoLSN(t *testing.T) {
+	_, err := NewBackupWalRange("base_000000010000000000000002", BackupSentinelDto{})
+	assert.Error(t, err)
+}
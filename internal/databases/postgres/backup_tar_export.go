@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type DeltaBackupTarExportError struct {
+	error
+}
+
+func newDeltaBackupTarExportError(backupName string) DeltaBackupTarExportError {
+	return DeltaBackupTarExportError{errors.Errorf(
+		"Backup %s is a delta backup, only full backups can be exported to a tar archive", backupName)}
+}
+
+func (err DeltaBackupTarExportError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ExportBackupToTar writes the contents of all the tar partitions of the full backup
+// to the output as a single tar archive. The partitions are decrypted and decompressed
+// as during the regular fetch. As in the regular fetch, pg_control goes last.
+func ExportBackupToTar(backup Backup, crypter crypto.Crypter, output io.Writer) error {
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return err
+	}
+	if sentinelDto.IsIncremental() {
+		return newDeltaBackupTarExportError(backup.Name)
+	}
+
+	tarNames, err := backup.GetTarNames()
+	if err != nil {
+		return err
+	}
+	sort.Strings(tarNames)
+	pgControlRe := regexp.MustCompile(`^.*?pg_control\.tar(\..+$|$)`)
+	sort.SliceStable(tarNames, func(i, j int) bool {
+		return !pgControlRe.MatchString(tarNames[i]) && pgControlRe.MatchString(tarNames[j])
+	})
+
+	tarWriter := tar.NewWriter(output)
+	writtenNames := make(map[string]bool)
+	for _, tarName := range tarNames {
+		tracelog.InfoLogger.Printf("Exporting tar partition %s\n", tarName)
+		err = copyTarPartition(backup.newTarPartitionReaderMaker(tarName), crypter, tarWriter, writtenNames)
+		if err != nil {
+			return errors.Wrapf(err, "failed to export tar partition %s", tarName)
+		}
+	}
+	return tarWriter.Close()
+}
+
+// copyTarPartition appends the entries of the partition to the tarWriter. The end-of-archive
+// marker of each partition is dropped by the tar reader, the entries already written
+// (e.g. directories present in several partitions) are skipped.
+func copyTarPartition(readerMaker internal.ReaderMaker, crypter crypto.Crypter,
+	tarWriter *tar.Writer, writtenNames map[string]bool) error {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		err := internal.DecryptAndDecompressTar(pipeWriter, readerMaker, crypter)
+		_ = pipeWriter.CloseWithError(err)
+	}()
+	defer utility.LoggedClose(pipeReader, "")
+
+	tarReader := tar.NewReader(pipeReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if writtenNames[header.Name] {
+			continue
+		}
+		writtenNames[header.Name] = true
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err = io.Copy(tarWriter, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// HandleBackupTarExport writes the backup to the local tar file,
+// compressed with compressionMethod if it is not empty
+func HandleBackupTarExport(rootFolder storage.Folder, backup internal.Backup, outputPath, compressionMethod string) {
+	var compressor compression.Compressor
+	if compressionMethod != "" {
+		var ok bool
+		compressor, ok = compression.PostgresCompressors()[compressionMethod]
+		if !ok {
+			internal.Fatalf("Unknown compression method %s, expected one of: %v\n",
+				compressionMethod, compression.PostgresCompressingAlgorithms())
+		}
+	}
+
+	file, err := os.Create(outputPath)
+	internal.FatalfOnError("Failed to create the tar file: %v\n", err)
+	var output io.WriteCloser = file
+	if compressor != nil {
+		output = compressor.NewWriter(file)
+	}
+
+	pgBackup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name)
+	err = ExportBackupToTar(pgBackup, internal.ConfigureCrypter(), output)
+	internal.FatalfOnError("Failed to export backup: %v\n", err)
+	if compressor != nil {
+		err = output.Close()
+		internal.FatalfOnError("Failed to finish the compression: %v\n", err)
+	}
+	err = file.Close()
+	internal.FatalfOnError("Failed to close the tar file: %v\n", err)
+	tracelog.InfoLogger.Printf("Backup %s is exported to %s\n", backup.Name, outputPath)
+}
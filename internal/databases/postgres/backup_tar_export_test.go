@@ -0,0 +1,80 @@
+package postgres_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func makeTestTar(t *testing.T, files map[string]string, dirs ...string) *bytes.Buffer {
+	var buffer bytes.Buffer
+	tarWriter := tar.NewWriter(&buffer)
+	for _, dir := range dirs {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: dir, Typeflag: tar.TypeDir, Mode: 0755}))
+	}
+	for name, content := range files {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Mode: 0600}))
+		_, err := tarWriter.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	return &buffer
+}
+
+func TestExportBackupToTar(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	backupName := "base_000000010000000000000002"
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder),
+		&postgres.BackupSentinelDto{}, backupName))
+	tarFolder := baseBackupFolder.GetSubFolder(backupName + internal.TarPartitionFolderName)
+	require.NoError(t, tarFolder.PutObject("pg_control.tar",
+		makeTestTar(t, map[string]string{"global/pg_control": "control"})))
+	require.NoError(t, tarFolder.PutObject("part_1.tar",
+		makeTestTar(t, map[string]string{"base/1/1": "data1"}, "base")))
+	require.NoError(t, tarFolder.PutObject("part_2.tar",
+		makeTestTar(t, map[string]string{"base/1/2": "data2"}, "base")))
+
+	var output bytes.Buffer
+	err := postgres.ExportBackupToTar(postgres.NewBackup(baseBackupFolder, backupName), nil, &output)
+	require.NoError(t, err)
+
+	var names []string
+	tarReader := tar.NewReader(&output)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+		if header.Name == "base/1/2" {
+			content, err := ioutil.ReadAll(tarReader)
+			require.NoError(t, err)
+			assert.Equal(t, "data2", string(content))
+		}
+	}
+	assert.Equal(t, []string{"base", "base/1/1", "base/1/2", "global/pg_control"}, names)
+}
+
+func TestExportBackupToTar_DeltaBackup(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	fullName := "base_000000010000000000000002"
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	lsn := uint64(0x2000028)
+	incrementCount := 1
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder),
+		&postgres.BackupSentinelDto{IncrementFrom: &fullName, IncrementFullName: &fullName,
+			IncrementFromLSN: &lsn, IncrementCount: &incrementCount}, deltaName))
+
+	err := postgres.ExportBackupToTar(postgres.NewBackup(baseBackupFolder, deltaName), nil, &bytes.Buffer{})
+	assert.IsType(t, postgres.DeltaBackupTarExportError{}, err)
+}
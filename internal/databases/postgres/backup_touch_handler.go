@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/copy"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BackupTouchFolderName is the folder of the base backups folder which holds the objects of the touched backup
+// while they are rewritten. It is not a backup name, so it is never listed as a backup.
+const BackupTouchFolderName = "touch_tmp/"
+
+// touchRewritingMarkerName is put into the touch folder when the objects are copied there completely
+// and the rewriting of the originals starts, from then on the copies may be the only intact objects
+const touchRewritingMarkerName = "walg_touch_rewriting"
+
+type TouchLeftoversError struct {
+	error
+}
+
+func newTouchLeftoversError(touchFolder storage.Folder, objectName string) TouchLeftoversError {
+	return TouchLeftoversError{errors.Errorf("failed to restore %s from the copy left in %s by the failed touch: "+
+		"check that the copy is intact, copy it over the original and delete it from %s, then touch the backup again",
+		objectName, touchFolder.GetPath(), touchFolder.GetPath())}
+}
+
+func (err TouchLeftoversError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// TouchBackup rewrites the objects of the backup to refresh their modification time: the WAL segments
+// the backup needs to be consistent first, then the backups of its delta chain from the base one, each sentinel last.
+// The storages can't copy an object onto itself, so every object is copied to the BackupTouchFolderName and back,
+// the WAL segments and the backup objects to the separate subfolders. The copies left by the previous failed touch
+// are restored over their originals first, see restoreTouchLeftovers.
+func TouchBackup(rootFolder storage.Folder, backupName string) error {
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	touchFolder := baseBackupFolder.GetSubFolder(BackupTouchFolderName)
+	walTouchFolder := touchFolder.GetSubFolder(utility.WalPath)
+	backupTouchFolder := touchFolder.GetSubFolder(utility.BaseBackupPath)
+	if err := restoreTouchLeftovers(walFolder, walTouchFolder); err != nil {
+		return err
+	}
+	if err := restoreTouchLeftovers(baseBackupFolder, backupTouchFolder); err != nil {
+		return err
+	}
+
+	walObjects, err := backupWalObjects(rootFolder, backupName)
+	if err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Touching %d WAL segments of backup %s\n", len(walObjects), backupName)
+	err = touchObjects(walFolder, walTouchFolder, walObjects, func(storage.Object) bool { return false })
+	if err != nil {
+		return errors.Wrap(err, "failed to touch the WAL segments")
+	}
+
+	chainNames, err := getDeltaChainNames(baseBackupFolder, backupName)
+	if err != nil {
+		return err
+	}
+	for i := len(chainNames) - 1; i >= 0; i-- {
+		objects, err := backupObjects(baseBackupFolder, chainNames[i])
+		if err != nil {
+			return err
+		}
+		sentinelName := internal.SentinelNameFromBackup(chainNames[i])
+		tracelog.InfoLogger.Printf("Touching %d objects of backup %s\n", len(objects), chainNames[i])
+		err = touchObjects(baseBackupFolder, backupTouchFolder, objects,
+			func(object storage.Object) bool { return object.GetName() == sentinelName })
+		if err != nil {
+			return errors.Wrapf(err, "failed to touch backup %s", chainNames[i])
+		}
+	}
+	return nil
+}
+
+// backupWalObjects lists the WAL segments of the WAL range of the backup, see NewBackupWalRange
+func backupWalObjects(rootFolder storage.Folder, backupName string) ([]storage.Object, error) {
+	backup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), backupName)
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return nil, err
+	}
+	walRange, err := NewBackupWalRange(backupName, sentinelDto)
+	if err != nil {
+		tracelog.WarningLogger.Printf("The WAL segments are not touched: %v\n", err)
+		return nil, nil
+	}
+	objects, _, err := rootFolder.GetSubFolder(utility.WalPath).ListFolder()
+	if err != nil {
+		return nil, err
+	}
+	walObjects := make([]storage.Object, 0, walRange.SegmentCount)
+	for _, object := range objects {
+		segmentName := utility.TrimFileExtension(object.GetName())
+		if len(segmentName) == len(walRange.FirstSegment) &&
+			segmentName >= walRange.FirstSegment && segmentName <= walRange.LastSegment {
+			walObjects = append(walObjects, object)
+		}
+	}
+	if uint64(len(walObjects)) < walRange.SegmentCount {
+		tracelog.WarningLogger.Printf("Found %d of %d WAL segments from %s to %s of backup %s\n",
+			len(walObjects), walRange.SegmentCount, walRange.FirstSegment, walRange.LastSegment, backupName)
+	}
+	return walObjects, nil
+}
+
+// touchObjects copies the objects of the folder to the touch folder and back, the last ones after the others.
+// If the copying to the touch folder fails, the copies are removed. If the rewriting fails, the copies are kept
+// with the touchRewritingMarkerName, the file system storage rewrites the objects in place.
+func touchObjects(folder, touchFolder storage.Folder, objects []storage.Object,
+	isLast func(storage.Object) bool) error {
+	isNotLast := func(object storage.Object) bool { return !isLast(object) }
+	all := func(object storage.Object) bool { return true }
+	copiedNames := make([]string, 0, len(objects))
+	for _, object := range objects {
+		copiedNames = append(copiedNames, object.GetName())
+	}
+
+	err := copy.Infos(copy.BuildCopyingInfos(folder, touchFolder, objects, all, copy.NoopRenameFunc))
+	if err != nil {
+		if deleteErr := touchFolder.DeleteObjects(copiedNames); deleteErr != nil {
+			tracelog.WarningLogger.Printf("Failed to remove the copies from %s: %v\n", touchFolder.GetPath(), deleteErr)
+		}
+		return errors.Wrapf(err, "failed to copy the objects to %s", touchFolder.GetPath())
+	}
+	err = touchFolder.PutObject(touchRewritingMarkerName, &bytes.Buffer{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to mark the copies in %s", touchFolder.GetPath())
+	}
+	err = copy.Infos(copy.BuildCopyingInfos(touchFolder, folder, objects, isNotLast, copy.NoopRenameFunc))
+	if err == nil {
+		err = copy.Infos(copy.BuildCopyingInfos(touchFolder, folder, objects, isLast, copy.NoopRenameFunc))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to rewrite the objects, the copies are kept in %s", touchFolder.GetPath())
+	}
+	if err = touchFolder.DeleteObjects(copiedNames); err != nil {
+		return err
+	}
+	return touchFolder.DeleteObjects([]string{touchRewritingMarkerName})
+}
+
+// restoreTouchLeftovers restores the originals in the folder from the copies left in the touch folder
+// by the failed touch and removes the copies. The originals are not rewritten before the touchRewritingMarkerName
+// is put, so without it the copies are just removed. With it, the copy of the size of its original is removed,
+// the other copies are copied over their originals first, and the touch is refused if the restored one
+// does not get the size of its copy. The copy of the deleted object is removed.
+func restoreTouchLeftovers(folder, touchFolder storage.Folder) error {
+	leftovers, err := storage.ListFolderRecursively(touchFolder)
+	if err != nil {
+		return err
+	}
+	if len(leftovers) == 0 {
+		return nil
+	}
+	isRewriting := false
+	copies := make([]storage.Object, 0, len(leftovers))
+	for _, object := range leftovers {
+		if object.GetName() == touchRewritingMarkerName {
+			isRewriting = true
+		} else {
+			copies = append(copies, object)
+		}
+	}
+
+	if isRewriting {
+		tracelog.WarningLogger.Printf("Restoring the objects of %s from %d copies left in %s by the previous touch\n",
+			folder.GetPath(), len(copies), touchFolder.GetPath())
+		originalSizes, err := objectSizes(folder)
+		if err != nil {
+			return err
+		}
+		needsRestore := func(object storage.Object) bool {
+			size, exists := originalSizes[object.GetName()]
+			return exists && size != object.GetSize()
+		}
+		err = copy.Infos(copy.BuildCopyingInfos(touchFolder, folder, copies, needsRestore, copy.NoopRenameFunc))
+		if err != nil {
+			return errors.Wrapf(err, "failed to restore the objects from %s", touchFolder.GetPath())
+		}
+		restoredSizes, err := objectSizes(folder)
+		if err != nil {
+			return err
+		}
+		for _, object := range copies {
+			if needsRestore(object) && restoredSizes[object.GetName()] != object.GetSize() {
+				return newTouchLeftoversError(touchFolder, object.GetName())
+			}
+		}
+	} else {
+		tracelog.WarningLogger.Printf("Removing %d objects left in %s by the previous touch\n",
+			len(copies), touchFolder.GetPath())
+	}
+
+	leftoverNames := make([]string, 0, len(copies))
+	for _, object := range copies {
+		leftoverNames = append(leftoverNames, object.GetName())
+	}
+	if err = touchFolder.DeleteObjects(leftoverNames); err != nil {
+		return err
+	}
+	return touchFolder.DeleteObjects([]string{touchRewritingMarkerName})
+}
+
+func objectSizes(folder storage.Folder) (map[string]int64, error) {
+	objects, err := storage.ListFolderRecursively(folder)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(objects))
+	for _, object := range objects {
+		sizes[object.GetName()] = object.GetSize()
+	}
+	return sizes, nil
+}
+
+// HandleBackupTouch refreshes the modification time of the objects of the backup
+func HandleBackupTouch(folder storage.Folder, backupName string) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	internal.FatalOnError(err)
+	err = TouchBackup(folder, backup.Name)
+	internal.FatalfOnError("Failed to touch the backup: %v\n", err)
+	tracelog.WarningLogger.Printf("Backup %s is touched, it is sorted as the latest one by the modification time "+
+		"in the backup listings, %s will choose it unless another backup is made or touched\n",
+		backup.Name, internal.LatestString)
+}
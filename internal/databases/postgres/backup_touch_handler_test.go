@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func objectsModificationTimes(t *testing.T, folder storage.Folder) map[string]time.Time {
+	objects, err := storage.ListFolderRecursively(folder)
+	require.NoError(t, err)
+	modificationTimes := make(map[string]time.Time)
+	for _, object := range objects {
+		modificationTimes[object.GetName()] = object.GetLastModified()
+	}
+	return modificationTimes
+}
+
+func TestTouchBackup(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	fullStartLSN, deltaStartLSN, deltaFinishLSN := uint64(0x2000028), uint64(0x4000028), uint64(0x5000010)
+	fullName := flattenFullName
+	incrementCount := 1
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &fullStartLSN,
+		BackupFinishLSN: &fullStartLSN}, fullName))
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &deltaStartLSN,
+		BackupFinishLSN: &deltaFinishLSN, IncrementFrom: &fullName, IncrementFullName: &fullName,
+		IncrementFromLSN: &fullStartLSN, IncrementCount: &incrementCount}, flattenDeltaName))
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &deltaStartLSN,
+		BackupFinishLSN: &deltaFinishLSN}, "base_000000010000000000000006"))
+	fullPartitionName := utility.BaseBackupPath + flattenFullName + "/tar_partitions/part_1.tar.lz4"
+	deltaPartitionName := utility.BaseBackupPath + flattenDeltaName + "/tar_partitions/part_1.tar.lz4"
+	for _, name := range []string{fullPartitionName, deltaPartitionName} {
+		require.NoError(t, rootFolder.PutObject(name, bytes.NewBufferString("partition")))
+	}
+	for _, name := range []string{"000000010000000000000003", "000000010000000000000004",
+		"000000010000000000000005", "000000010000000000000006"} {
+		require.NoError(t, rootFolder.PutObject(utility.WalPath+name+".lz4", bytes.NewBufferString(name)))
+	}
+	leftoverName := BackupTouchFolderName + utility.BaseBackupPath + "leftover"
+	require.NoError(t, baseBackupFolder.PutObject(leftoverName, &bytes.Buffer{}))
+	before := objectsModificationTimes(t, rootFolder)
+	time.Sleep(time.Millisecond)
+
+	require.NoError(t, TouchBackup(rootFolder, flattenDeltaName))
+
+	after := objectsModificationTimes(t, rootFolder)
+	assert.Len(t, after, len(before)-1)
+	assert.NotContains(t, after, utility.BaseBackupPath+leftoverName)
+	fullSentinelName := utility.BaseBackupPath + internal.SentinelNameFromBackup(flattenFullName)
+	deltaSentinelName := utility.BaseBackupPath + internal.SentinelNameFromBackup(flattenDeltaName)
+	for _, name := range []string{fullSentinelName, deltaSentinelName, fullPartitionName, deltaPartitionName,
+		utility.WalPath + "000000010000000000000004.lz4", utility.WalPath + "000000010000000000000005.lz4"} {
+		assert.True(t, after[name].After(before[name]), name)
+	}
+	for _, name := range []string{utility.WalPath + "000000010000000000000003.lz4",
+		utility.WalPath + "000000010000000000000006.lz4",
+		utility.BaseBackupPath + internal.SentinelNameFromBackup("base_000000010000000000000006")} {
+		assert.Equal(t, before[name], after[name], name)
+	}
+	// the sentinels are rewritten after the partitions, the delta after its base
+	assert.False(t, after[fullSentinelName].Before(after[fullPartitionName]))
+	assert.False(t, after[deltaSentinelName].Before(after[deltaPartitionName]))
+	assert.True(t, after[deltaSentinelName].After(after[fullSentinelName]))
+
+	reader, err := rootFolder.ReadObject(deltaPartitionName)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "partition", string(content))
+}
+
+func TestTouchBackup_RestoresLeftovers(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	startLSN := uint64(0x2000028)
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder),
+		&BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &startLSN}, flattenFullName))
+	partitionName := flattenFullName + "/tar_partitions/part_1.tar.lz4"
+	walName := "000000010000000000000002.lz4"
+	// the previous touch failed while rewriting the partition in place
+	require.NoError(t, baseBackupFolder.PutObject(partitionName, bytes.NewBufferString("part")))
+	require.NoError(t, rootFolder.PutObject(utility.WalPath+walName, bytes.NewBufferString("segment")))
+	backupTouchFolder := baseBackupFolder.GetSubFolder(BackupTouchFolderName + utility.BaseBackupPath)
+	walTouchFolder := baseBackupFolder.GetSubFolder(BackupTouchFolderName + utility.WalPath)
+	require.NoError(t, backupTouchFolder.PutObject(partitionName, bytes.NewBufferString("partition")))
+	require.NoError(t, backupTouchFolder.PutObject(touchRewritingMarkerName, &bytes.Buffer{}))
+	// the copying to the touch folder failed, the original is intact
+	require.NoError(t, walTouchFolder.PutObject(walName, bytes.NewBufferString("seg")))
+
+	require.NoError(t, TouchBackup(rootFolder, flattenFullName))
+
+	for name, expected := range map[string]string{utility.BaseBackupPath + partitionName: "partition",
+		utility.WalPath + walName: "segment"} {
+		reader, err := rootFolder.ReadObject(name)
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content), name)
+	}
+	leftovers, err := storage.ListFolderRecursively(baseBackupFolder.GetSubFolder(BackupTouchFolderName))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -27,6 +28,18 @@ const (
 	pollPauseDuration = 100 * time.Millisecond
 )
 
+// BgUploadSummary describes the work done by BgUploader
+type BgUploadSummary struct {
+	// Uploaded is the number of WAL files uploaded in the background
+	Uploaded int32
+	// Skipped is the number of ready WAL files found already uploaded
+	Skipped int32
+	// Failed is the number of WAL files the background upload failed for
+	Failed int32
+	// FailedFiles are the names of the WAL files the background upload failed for
+	FailedFiles []string
+}
+
 // BgUploader represents the state of concurrent WAL upload
 type BgUploader struct {
 	// pg_[wals|xlog]
@@ -58,20 +71,37 @@ type BgUploader struct {
 	// maxParallelWorkers higher than this setting.
 	maxNumUploaded int32
 
+	// maxLookahead bounds the number of the WAL segments following the pushed one
+	// that are uploaded, both by the lookups of the next segments and by the scans
+	// of the whole archive_status. Usually defined by WALG_WAL_LOOKAHEAD_COUNT.
+	maxLookahead int32
+	// lastLookaheadSegNo is the number of the last WAL segment within maxLookahead,
+	// the scans aren't bounded if the pushed file is not a WAL segment
+	lastLookaheadSegNo uint64
+	lookaheadBounded   bool
+
 	// started tracks filenames of ongoing and complete uploads to avoid
 	// repeating work
 	started map[string]struct{}
 
+	// numSkipped counts the files found already uploaded
+	numSkipped int32
+	// failedFiles are the WAL files the upload failed for
+	failedFiles      []string
+	failedFilesMutex sync.Mutex
+
 	// WAL name where we started.
 	firstWalName string
 }
 
 // NewBgUploader creates a new BgUploader which looks for WAL files adjacent to
 // walFilePath. maxParallelWorkers and maxNumUploaded limits maximum concurrency
-// and total work done by this BgUploader respectively.
+// and total work done by this BgUploader respectively, maxLookahead limits
+// how many WAL segments following walFilePath are looked for.
 func NewBgUploader(walFilePath string,
 	maxParallelWorkers int32,
 	maxNumUploaded int32,
+	maxLookahead int32,
 	uploader *WalUploader,
 	preventWalOverwrite bool,
 	readyRename bool) *BgUploader {
@@ -79,6 +109,7 @@ func NewBgUploader(walFilePath string,
 	firstWalName := filepath.Base(walFilePath)
 	started[firstWalName+readySuffix] = struct{}{}
 	ctx, cancelFunc := context.WithCancel(context.Background())
+	_, firstSegNo, err := ParseWALFilename(firstWalName)
 	return &BgUploader{
 		dir:                 filepath.Dir(walFilePath),
 		uploader:            uploader,
@@ -91,6 +122,9 @@ func NewBgUploader(walFilePath string,
 		maxParallelWorkers: maxParallelWorkers,
 		numUploaded:        0,
 		maxNumUploaded:     maxNumUploaded,
+		maxLookahead:       maxLookahead,
+		lastLookaheadSegNo: firstSegNo + uint64(maxLookahead),
+		lookaheadBounded:   err == nil,
 		started:            started,
 		firstWalName:       firstWalName,
 	}
@@ -106,12 +140,25 @@ func (b *BgUploader) Start() {
 	go b.scanAndProcessFiles()
 }
 
-// Stop pipeline. Stop can be safely called concurrently and repeatedly.
-func (b *BgUploader) Stop() error {
+// Stop pipeline and wait for the running uploads to finish. The returned summary
+// describes all the uploads started by BgUploader.
+func (b *BgUploader) Stop() (BgUploadSummary, error) {
 	// Send signal to stop scanning for and uploading new files
 	b.cancelFunc()
 	// Wait for all running uploads
-	return b.workerCountSem.Acquire(context.TODO(), int64(b.maxParallelWorkers))
+	err := b.workerCountSem.Acquire(context.TODO(), int64(b.maxParallelWorkers))
+	if err != nil {
+		return BgUploadSummary{}, err
+	}
+
+	b.failedFilesMutex.Lock()
+	defer b.failedFilesMutex.Unlock()
+	return BgUploadSummary{
+		Uploaded:    atomic.LoadInt32(&b.numUploaded),
+		Skipped:     atomic.LoadInt32(&b.numSkipped),
+		Failed:      int32(len(b.failedFiles)),
+		FailedFiles: append([]string(nil), b.failedFiles...),
+	}, nil
 }
 
 // scanAndProcessFiles scans directory for WAL segments and attempts to upload them. It
@@ -123,7 +170,7 @@ func (b *BgUploader) scanAndProcessFiles() {
 
 	walName := b.firstWalName
 
-	for i := int32(0); i < b.maxNumUploaded; i++ {
+	for i := int32(0); i < b.maxNumUploaded && i < b.maxLookahead; i++ {
 		var err error
 		walName, err = GetNextWalFilename(walName)
 		if err != nil {
@@ -148,6 +195,9 @@ func (b *BgUploader) scanAndProcessFiles() {
 		}
 
 		for _, f := range files {
+			if b.isBeyondLookahead(f.Name()) {
+				continue
+			}
 			select {
 			case <-b.ctx.Done():
 				return
@@ -165,6 +215,16 @@ func (b *BgUploader) scanAndProcessFiles() {
 	}
 }
 
+// isBeyondLookahead returns true when the status file is the one of the WAL segment
+// following the pushed one by more than maxLookahead segments
+func (b *BgUploader) isBeyondLookahead(filename string) bool {
+	if !b.lookaheadBounded {
+		return false
+	}
+	_, segNo, err := ParseWALFilename(strings.TrimSuffix(filename, readySuffix))
+	return err == nil && segNo > b.lastLookaheadSegNo
+}
+
 // processFiles reads from input channel and uploads relevant WAL files. Exits
 // when the input channel is closed. processFiles also tracks number of
 // successfully uploaded WAL files and signals to BgUploader when total count
@@ -173,7 +233,6 @@ func (b *BgUploader) scanAndProcessFiles() {
 //
 // This function should only be invoked once (in scanFiles)
 func (b *BgUploader) processFiles(fileChan <-chan os.FileInfo) {
-	var numUploaded int32 = 0
 	for {
 		f, ok := <-fileChan
 		if !ok {
@@ -182,10 +241,15 @@ func (b *BgUploader) processFiles(fileChan <-chan os.FileInfo) {
 
 		name := f.Name()
 
-		if b.shouldSkipFile(name) {
+		if _, ok := b.started[name]; ok {
 			continue
 		}
-		if _, ok := b.started[name]; ok {
+		if b.shouldSkipFile(name) {
+			if strings.HasSuffix(name, readySuffix) {
+				// the file is already uploaded, count it once
+				b.started[name] = struct{}{}
+				atomic.AddInt32(&b.numSkipped, 1)
+			}
 			continue
 		}
 
@@ -195,7 +259,7 @@ func (b *BgUploader) processFiles(fileChan <-chan os.FileInfo) {
 				uploadedFile := b.upload(name)
 				b.workerCountSem.Release(1)
 				if uploadedFile {
-					if atomic.AddInt32(&numUploaded, 1) >= b.maxNumUploaded {
+					if atomic.AddInt32(&b.numUploaded, 1) >= b.maxNumUploaded {
 						b.cancelFunc()
 					}
 				}
@@ -217,6 +281,9 @@ func (b *BgUploader) upload(walStatusFilename string) bool {
 	err := uploadWALFile(b.uploader.clone(), filepath.Join(b.dir, walFilename), b.preventWalOverwrite)
 	if err != nil {
 		tracelog.ErrorLogger.Print("Error of background uploader: ", err)
+		b.failedFilesMutex.Lock()
+		b.failedFiles = append(b.failedFiles, walFilename)
+		b.failedFilesMutex.Unlock()
 		return false
 	}
 
@@ -99,7 +99,8 @@ func TestBackgroundWALUpload(t *testing.T) {
 			fakeASM := asm.NewFakeASM()
 			tu.ArchiveStatusManager = fakeASM
 
-			bu := postgres.NewBgUploader(a, int32(tt.maxParallelism), int32(tt.maxNumFilesUploaded), tu, false, false)
+			bu := postgres.NewBgUploader(a, int32(tt.maxParallelism), int32(tt.maxNumFilesUploaded),
+				int32(tt.maxNumFilesUploaded), tu, false, false)
 			// Run BgUploader and wait 1 second before stopping
 			bu.Start()
 			// KLUDGE If maxParallelism=0, we expect to do no work. Therefore, do not wait.
@@ -119,6 +120,66 @@ func TestBackgroundWALUpload(t *testing.T) {
 	}
 }
 
+func TestBackgroundWALUploadSummary(t *testing.T) {
+	viper.Set(internal.UploadWalMetadata, "NOMETADATA")
+	defer testtools.Cleanup(t, internal.GetDataFolderPath())
+
+	dir, a := setupArchiveStatus(t, "")
+	dirName := filepath.Join(dir, "pg_wal")
+	for i := 0; i < 5; i++ {
+		addTestDataFile(t, dirName, fmt.Sprint(i))
+	}
+	defer testtools.Cleanup(t, dir)
+
+	fakeASM := asm.NewFakeASM()
+	err := fakeASM.MarkWalUploaded(testFilename("0") + ".ready")
+	assert.NoError(t, err)
+
+	// the uploads to the mock storage fail
+	tu := testtools.NewMockWalUploader(false, true)
+	tu.ArchiveStatusManager = fakeASM
+
+	bu := postgres.NewBgUploader(a, 2, 32, 32, tu, false, false)
+	bu.Start()
+	time.Sleep(time.Second)
+	summary, err := bu.Stop()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), summary.Uploaded)
+	assert.Equal(t, int32(1), summary.Skipped)
+	assert.Equal(t, int32(4), summary.Failed)
+	assert.ElementsMatch(t, []string{testFilename("1"), testFilename("2"), testFilename("3"), testFilename("4")},
+		summary.FailedFiles)
+}
+
+func TestBackgroundWALUploadLookahead(t *testing.T) {
+	viper.Set(internal.UploadWalMetadata, "NOMETADATA")
+	defer testtools.Cleanup(t, internal.GetDataFolderPath())
+
+	dir, _ := setupArchiveStatus(t, "")
+	dirName := filepath.Join(dir, "pg_wal")
+	for i := 0; i < 10; i++ {
+		addTestDataFile(t, dirName, fmt.Sprint(i))
+	}
+	defer testtools.Cleanup(t, dir)
+
+	tu := testtools.NewMockWalUploader(false, false)
+	fakeASM := asm.NewFakeASM()
+	tu.ArchiveStatusManager = fakeASM
+
+	// the segments 1-3 follow the pushed segment 0 within the lookahead, the segments 4-9 don't
+	bu := postgres.NewBgUploader(filepath.Join(dirName, testFilename("0")), 4, 32, 3, tu, false, false)
+	bu.Start()
+	time.Sleep(time.Second)
+	summary, err := bu.Stop()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), summary.Uploaded)
+	for i := 1; i < 10; i++ {
+		assert.Equal(t, i <= 3, fakeASM.WalAlreadyUploaded(testFilename(fmt.Sprint(i))), testFilename(fmt.Sprint(i)))
+	}
+}
+
 func setupArchiveStatus(t *testing.T, dir string) (string, string) {
 	cwd, err := filepath.Abs("./")
 	if err != nil {
@@ -43,16 +43,16 @@ func (err TarSizeError) Error() string {
 // ExcludedFilenames is a list of excluded members from the bundled backup.
 var ExcludedFilenames = make(map[string]utility.Empty)
 
-func init() {
-	filesToExclude := []string{
-		"log", "pg_log", "pg_xlog", "pg_wal", // Directories
-		"pgsql_tmp", "postgresql.auto.conf.tmp", "postmaster.pid", "postmaster.opts", "recovery.conf", // Files
-		"pg_dynshmem", "pg_notify", "pg_replslot", "pg_serial", "pg_stat_tmp", "pg_snapshots", "pg_subtrans", // Directories
-	}
+// DefaultExcludedFilenames are the transient files and directories excluded unless WALG_BACKUP_EXCLUDED_FILES is set.
+// The restored postmaster.pid would make the server refuse to start, the rest is recreated by the server.
+var DefaultExcludedFilenames = []string{
+	"log", "pg_log", "pg_xlog", "pg_wal", // Directories
+	"pgsql_tmp", "postgresql.auto.conf.tmp", "postmaster.pid", "postmaster.opts", "recovery.conf", // Files
+	"pg_dynshmem", "pg_notify", "pg_replslot", "pg_serial", "pg_stat_tmp", "pg_snapshots", "pg_subtrans", // Directories
+}
 
-	for _, filename := range filesToExclude {
-		ExcludedFilenames[filename] = utility.Empty{}
-	}
+func init() {
+	SetExcludedFilenames(DefaultExcludedFilenames)
 }
 
 // A Bundle represents the directory to
@@ -78,6 +78,16 @@ type Bundle struct {
 
 	forceIncremental bool
 	TarSizeThreshold int64
+	SymlinkPolicy    SymlinkPolicy
+	// NoWaitForWal makes the backup stop return without waiting for the WAL required by the backup to be archived
+	NoWaitForWal bool
+
+	// followedSymlinkTargets are the directories walked by the SymlinkPolicyFollow, to detect the loops
+	followedSymlinkTargets map[string]bool
+
+	// exclusiveBackupLabel is the backup_label of the exclusive backup (before 9.6),
+	// it is stored with the label files instead of the one in the data directory
+	exclusiveBackupLabel string
 }
 
 // TODO: use DiskDataFolder
@@ -94,6 +104,7 @@ func NewBundle(
 		TablespaceSpec:     NewTablespaceSpec(directory),
 		forceIncremental:   forceIncremental,
 		TarSizeThreshold:   tarSizeThreshold,
+		SymlinkPolicy:      SymlinkPolicyLegacy,
 	}
 }
 
@@ -154,18 +165,19 @@ func (bundle *Bundle) checkTimelineChanged(conn *pgx.Conn) bool {
 }
 
 // TODO : unit tests
-// StartBackup starts a non-exclusive base backup immediately. When finishing the backup,
+// StartBackup starts a non-exclusive base backup, immediately if fastCheckpoint is set,
+// otherwise after the spread checkpoint. When finishing the backup,
 // `backup_label` and `tablespace_map` contents are not immediately written to
 // a file but returned instead. Returns empty string and an error if backup
 // fails.
 func (bundle *Bundle) StartBackup(conn *pgx.Conn,
-	backup string) (backupName string, lsn uint64, err error) {
+	backup string, fastCheckpoint bool) (backupName string, lsn uint64, err error) {
 	var name, lsnStr string
 	queryRunner, err := NewPgQueryRunner(conn)
 	if err != nil {
 		return "", 0, errors.Wrap(err, "StartBackup: Failed to build query runner.")
 	}
-	name, lsnStr, bundle.Replica, err = queryRunner.startBackup(backup)
+	name, lsnStr, bundle.Replica, err = queryRunner.startBackup(backup, fastCheckpoint)
 
 	if err != nil {
 		return "", 0, err
@@ -186,7 +198,14 @@ func (bundle *Bundle) StartBackup(conn *pgx.Conn,
 			tracelog.WarningLogger.Printf("Couldn't get current timeline because of error: '%v'\n", err)
 		}
 	}
-	return "base_" + name, lsn, nil
+	if !queryRunner.IsTablespaceMapExists() && !bundle.Replica {
+		bundle.exclusiveBackupLabel, err = readExclusiveBackupLabel(bundle.Directory, lsn)
+		if err != nil {
+			// the backup_label of the data directory is backed up as the other files then
+			tracelog.WarningLogger.Printf("Couldn't read %s of the exclusive backup: '%v'\n", BackupLabelFilename, err)
+		}
+	}
+	return utility.GetBackupNamePrefix() + name, lsn, nil
 }
 
 // TODO : unit tests
@@ -216,6 +235,9 @@ func (bundle *Bundle) HandleWalkedFSObject(path string, info os.FileInfo, err er
 	if isSymlink {
 		return nil
 	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return bundle.handleSymlink(path, info)
+	}
 
 	// Resolve symlinks for tablespaces and save folder structure.
 	if filepath.Base(path) == TablespaceFolder {
@@ -275,6 +297,11 @@ func (bundle *Bundle) addToBundle(path string, info os.FileInfo) error {
 	fileInfoHeader.Name = bundle.getFileRelPath(path)
 	tracelog.DebugLogger.Println(fileInfoHeader.Name)
 
+	if bundle.exclusiveBackupLabel != "" && fileInfoHeader.Name == utility.PathSeparator+BackupLabelFilename {
+		// the backup_label is stored with the label files
+		return nil
+	}
+
 	if !excluded && info.Mode().IsRegular() {
 		baseFiles := bundle.getIncrementBaseFiles()
 		baseFile, wasInBase := baseFiles[fileInfoHeader.Name]
@@ -327,6 +354,7 @@ func (bundle *Bundle) UploadPgControl(compressorFileExtension string) error {
 	fileInfoHeader.Name = bundle.getFileRelPath(path)
 	tracelog.InfoLogger.Println(fileInfoHeader.Name)
 
+	internal.SetTarHeaderFormat(fileInfoHeader)
 	err = tarWriter.WriteHeader(fileInfoHeader) // TODO : what happens in case of irregular pg_control?
 	if err != nil {
 		return errors.Wrap(err, "UploadPgControl: failed to write header")
@@ -364,6 +392,7 @@ func (bundle *Bundle) uploadLabelFiles(conn *pgx.Conn) (string, []string, uint64
 	if err != nil {
 		return "", nil, 0, errors.Wrap(err, "UploadLabelFiles: Failed to build query runner.")
 	}
+	queryRunner.NoWaitForWal = bundle.NoWaitForWal
 	label, offsetMap, lsnStr, err := queryRunner.stopBackup()
 	if err != nil {
 		return "", nil, 0, errors.Wrap(err, "UploadLabelFiles: failed to stop backup")
@@ -375,9 +404,22 @@ func (bundle *Bundle) uploadLabelFiles(conn *pgx.Conn) (string, []string, uint64
 	}
 
 	if !queryRunner.IsTablespaceMapExists() {
-		return "", nil, lsn, nil
+		if bundle.exclusiveBackupLabel == "" {
+			return "", nil, lsn, nil
+		}
+		// pg_stop_backup returns no label files for the exclusive backup
+		label = bundle.exclusiveBackupLabel
 	}
 
+	tarBallName, labelFiles, err := bundle.packLabelFiles(label, offsetMap, queryRunner.IsTablespaceMapExists())
+	if err != nil {
+		return "", nil, 0, err
+	}
+	return tarBallName, labelFiles, lsn, nil
+}
+
+// packLabelFiles uploads the tarball of the `backup_label` and, if withTablespaceMap is set, the `tablespace_map`
+func (bundle *Bundle) packLabelFiles(label, offsetMap string, withTablespaceMap bool) (string, []string, error) {
 	tarBall := bundle.NewTarBall(false)
 	tarBall.SetUp(bundle.Crypter)
 
@@ -388,12 +430,20 @@ func (bundle *Bundle) uploadLabelFiles(conn *pgx.Conn) (string, []string, uint64
 		Typeflag: tar.TypeReg,
 	}
 
-	_, err = internal.PackFileTo(tarBall, labelHeader, strings.NewReader(label))
+	_, err := internal.PackFileTo(tarBall, labelHeader, strings.NewReader(label))
 	if err != nil {
-		return "", nil, 0, errors.Wrapf(err, "UploadLabelFiles: failed to put %s to tar", labelHeader.Name)
+		return "", nil, errors.Wrapf(err, "UploadLabelFiles: failed to put %s to tar", labelHeader.Name)
 	}
 	tracelog.InfoLogger.Println(labelHeader.Name)
 
+	if !withTablespaceMap {
+		err = bundle.TarBallQueue.CloseTarball(tarBall)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "UploadLabelFiles: failed to close tarball")
+		}
+		return tarBall.Name(), []string{BackupLabelFilename}, nil
+	}
+
 	offsetMapHeader := &tar.Header{
 		Name:     TablespaceMapFilename,
 		Mode:     int64(0600),
@@ -403,16 +453,16 @@ func (bundle *Bundle) uploadLabelFiles(conn *pgx.Conn) (string, []string, uint64
 
 	_, err = internal.PackFileTo(tarBall, offsetMapHeader, strings.NewReader(offsetMap))
 	if err != nil {
-		return "", nil, 0, errors.Wrapf(err, "UploadLabelFiles: failed to put %s to tar", offsetMapHeader.Name)
+		return "", nil, errors.Wrapf(err, "UploadLabelFiles: failed to put %s to tar", offsetMapHeader.Name)
 	}
 	tracelog.InfoLogger.Println(offsetMapHeader.Name)
 
 	err = bundle.TarBallQueue.CloseTarball(tarBall)
 	if err != nil {
-		return "", nil, 0, errors.Wrap(err, "UploadLabelFiles: failed to close tarball")
+		return "", nil, errors.Wrap(err, "UploadLabelFiles: failed to close tarball")
 	}
 
-	return tarBall.Name(), []string{TablespaceMapFilename, BackupLabelFilename}, lsn, nil
+	return tarBall.Name(), []string{TablespaceMapFilename, BackupLabelFilename}, nil
 }
 
 func (bundle *Bundle) getDeltaBitmapFor(filePath string) (*roaring.Bitmap, error) {
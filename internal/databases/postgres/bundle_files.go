@@ -11,6 +11,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
 )
 
 // BundleFiles represents the files in the backup that is going to be created
@@ -106,44 +108,97 @@ func (relStat *RelFileStatistics) getFileUpdateCount(filePath string) uint64 {
 }
 
 func newRelFileStatistics(conn *pgx.Conn) (RelFileStatistics, error) {
-	databases, err := getDatabaseInfos(conn)
+	statisticsConn, err := ConnectForStatistics()
+	if err != nil {
+		return nil, errors.Wrap(err, "CollectStatistics: Failed to open the statistics connection.")
+	}
+	defer utility.LoggedClose(statisticsConn, "")
+
+	databases, err := getDatabaseInfos(conn, statisticsConn)
 	if err != nil {
 		return nil, errors.Wrap(err, "CollectStatistics: Failed to get db names.")
 	}
 
-	result := make(map[walparser.RelFileNode]PgRelationStat)
-	// CollectStatistics collects statistics for each relFileNode
-	for _, db := range databases {
-		dbName := db.name
-		databaseOption := func(c *pgx.ConnConfig) error {
-			c.Database = dbName
+	concurrency, err := internal.GetMaxConcurrency(internal.StatisticsConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	databaseStatistics, err := collectStatisticsConcurrently(databases, concurrency, collectDatabaseStatistics)
+	if err != nil {
+		return nil, err
+	}
+	return mergeDatabaseStatistics(databaseStatistics), nil
+}
+
+// collectStatisticsConcurrently queries the statistics of up to concurrency databases at once.
+// The statistics of each database are stored at the index of the database,
+// so the order of the results does not depend on the order the queries complete in.
+func collectStatisticsConcurrently(databases []PgDatabaseInfo, concurrency int,
+	collect func(db PgDatabaseInfo) (map[walparser.RelFileNode]PgRelationStat, error),
+) ([]map[walparser.RelFileNode]PgRelationStat, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	databaseStatistics := make([]map[walparser.RelFileNode]PgRelationStat, len(databases))
+	errorGroup := new(errgroup.Group)
+	semaphore := make(chan struct{}, concurrency)
+	for i, db := range databases {
+		i, db := i, db
+		semaphore <- struct{}{}
+		errorGroup.Go(func() error {
+			defer func() { <-semaphore }()
+			pgStatRows, err := collect(db)
+			if err != nil {
+				return err
+			}
+			databaseStatistics[i] = pgStatRows
 			return nil
-		}
-		dbConn, err := Connect(databaseOption)
-		if err != nil {
-			tracelog.WarningLogger.Printf("Failed to collect statistics for database: %s\n'%v'\n", db.name, err)
-			continue
-		}
+		})
+	}
+	if err := errorGroup.Wait(); err != nil {
+		return nil, err
+	}
+	return databaseStatistics, nil
+}
 
-		queryRunner, err := NewPgQueryRunner(dbConn)
-		if err != nil {
-			return nil, errors.Wrap(err, "CollectStatistics: Failed to build query runner.")
-		}
-		pgStatRows, err := queryRunner.getStatistics(db)
-		if err != nil {
-			return nil, errors.Wrap(err, "CollectStatistics: Failed to collect statistics.")
-		}
+// mergeDatabaseStatistics merges the statistics in the order of the databases
+func mergeDatabaseStatistics(databaseStatistics []map[walparser.RelFileNode]PgRelationStat) RelFileStatistics {
+	result := make(map[walparser.RelFileNode]PgRelationStat)
+	for _, pgStatRows := range databaseStatistics {
 		for relFileNode, statRow := range pgStatRows {
 			result[relFileNode] = statRow
 		}
-		err = dbConn.Close()
-		tracelog.WarningLogger.PrintOnError(err)
 	}
-	return result, nil
+	return result
+}
+
+// collectDatabaseStatistics queries the relations statistics of the database
+// on its own statistics connection which is closed on return
+func collectDatabaseStatistics(db PgDatabaseInfo) (map[walparser.RelFileNode]PgRelationStat, error) {
+	databaseOption := func(c *pgx.ConnConfig) error {
+		c.Database = db.name
+		return nil
+	}
+	dbConn, err := ConnectForStatistics(databaseOption)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to collect statistics for database: %s\n'%v'\n", db.name, err)
+		return nil, nil
+	}
+	defer utility.LoggedClose(dbConn, "")
+
+	queryRunner, err := NewPgQueryRunner(dbConn)
+	if err != nil {
+		return nil, errors.Wrap(err, "CollectStatistics: Failed to build query runner.")
+	}
+	pgStatRows, err := queryRunner.getStatistics(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "CollectStatistics: Failed to collect statistics.")
+	}
+	return pgStatRows, nil
 }
 
-func getDatabaseInfos(conn *pgx.Conn) ([]PgDatabaseInfo, error) {
-	queryRunner, err := NewPgQueryRunner(conn)
+func getDatabaseInfos(conn, statisticsConn *pgx.Conn) ([]PgDatabaseInfo, error) {
+	queryRunner, err := NewPgQueryRunner(conn, WithStatisticsConnection(statisticsConn))
 	if err != nil {
 		return nil, errors.Wrap(err, "getDatabaseInfos: Failed to build query runner.")
 	}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"archive/tar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/walparser"
+)
+
+func TestCollectStatisticsConcurrently_KeepsDatabaseOrder(t *testing.T) {
+	databases := []PgDatabaseInfo{{name: "first", oid: 1}, {name: "second", oid: 2}, {name: "third", oid: 3}}
+	collect := func(db PgDatabaseInfo) (map[walparser.RelFileNode]PgRelationStat, error) {
+		// the first database completes last
+		time.Sleep(time.Duration(len(databases)-int(db.oid)) * 10 * time.Millisecond)
+		relFileNode := walparser.RelFileNode{DBNode: db.oid, RelNode: 100}
+		return map[walparser.RelFileNode]PgRelationStat{relFileNode: {insertedTuplesCount: uint64(db.oid)}}, nil
+	}
+
+	databaseStatistics, err := collectStatisticsConcurrently(databases, len(databases), collect)
+	require.NoError(t, err)
+	require.Len(t, databaseStatistics, len(databases))
+	for i, db := range databases {
+		assert.Contains(t, databaseStatistics[i], walparser.RelFileNode{DBNode: db.oid, RelNode: 100})
+	}
+
+	relFileStats := mergeDatabaseStatistics(databaseStatistics)
+	assert.Len(t, relFileStats, len(databases))
+}
+
+func TestRatingTarBallComposer_SortFilesIsDeterministic(t *testing.T) {
+	composer := &RatingTarBallComposer{filesToCompose: []*RatedComposeFileInfo{
+		{ComposeFileInfo: ComposeFileInfo{path: "base/1/3"}, updateRating: 5},
+		{ComposeFileInfo: ComposeFileInfo{path: "base/1/2"}, updateRating: 0},
+		{ComposeFileInfo: ComposeFileInfo{path: "base/1/1"}, updateRating: 5},
+		{ComposeFileInfo: ComposeFileInfo{path: "base/1/0"}, updateRating: 0},
+	}}
+	composer.sortFiles()
+
+	paths := make([]string, 0, len(composer.filesToCompose))
+	for _, file := range composer.filesToCompose {
+		paths = append(paths, file.path)
+	}
+	assert.Equal(t, []string{"base/1/0", "base/1/2", "base/1/1", "base/1/3"}, paths)
+}
+
+func TestTarFilesCollection_PartitionKey(t *testing.T) {
+	makeCollection := func(names []string, size int64, modTime time.Time, isIncremented bool) *TarFilesCollection {
+		collection := newTarFilesCollection()
+		for _, name := range names {
+			header := &tar.Header{Name: name, Size: size, ModTime: modTime, Typeflag: tar.TypeReg}
+			collection.AddFile(&RatedComposeFileInfo{ComposeFileInfo: ComposeFileInfo{
+				path: name, fileInfo: header.FileInfo(), header: header, isIncremented: isIncremented}})
+		}
+		return collection
+	}
+	names := []string{"base/1/1", "base/1/2"}
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	key := makeCollection(names, 8192, modTime, false).partitionKey(nil)
+	assert.Len(t, key, partitionKeyLength)
+	assert.Equal(t, key, makeCollection(names, 8192, modTime, false).partitionKey(nil))
+	// the partition of the changed files is not reused
+	assert.NotEqual(t, key, makeCollection(names, 16384, modTime, false).partitionKey(nil))
+	assert.NotEqual(t, key, makeCollection(names, 8192, modTime.Add(time.Second), false).partitionKey(nil))
+	assert.NotEqual(t, key, makeCollection([]string{"base/1/1", "base/1/3"}, 8192, modTime, false).partitionKey(nil))
+	assert.NotEqual(t, key, makeCollection(names, 8192, modTime, true).partitionKey(nil))
+	baseLsn, otherBaseLsn := uint64(0x3000000), uint64(0x5000000)
+	incrementKey := makeCollection(names, 8192, modTime, true).partitionKey(&baseLsn)
+	assert.NotEqual(t, incrementKey, makeCollection(names, 8192, modTime, true).partitionKey(&otherBaseLsn))
+}
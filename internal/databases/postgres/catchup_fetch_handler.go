@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"github.com/wal-g/storages/storage"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -12,21 +11,23 @@ func HandleCatchupFetch(folder storage.Folder, dbDirectory, backupName string, u
 	dbDirectory = utility.ResolveSymlink(dbDirectory)
 
 	backup, err := internal.GetBackupByName(backupName, utility.CatchupPath, folder)
-	tracelog.ErrorLogger.FatalfOnError("Failed get backup by name: %v", err)
+	internal.FatalfOnError("Failed get backup by name: %v", err)
 
 	pgBackup := ToPgBackup(backup)
 	filesToUnwrap, err := pgBackup.GetFilesToUnwrap("")
-	tracelog.ErrorLogger.FatalfOnError("Failed get files to unwrap from backup: %v", err)
+	internal.FatalfOnError("Failed get files to unwrap from backup: %v", err)
 
 	sentinelDto, err := pgBackup.GetSentinel()
-	tracelog.ErrorLogger.FatalfOnError("Failed get backup sentinel: %v", err)
+	internal.FatalfOnError("Failed get backup sentinel: %v", err)
+	err = checkTargetPageSize(dbDirectory, backup.Name, sentinelDto)
+	internal.FatalOnError(err)
 
 	// testing the new unwrap implementation
 	if useNewUnwrap {
-		_, err = pgBackup.unwrapNew(dbDirectory, sentinelDto, filesToUnwrap, true, false)
+		_, err = pgBackup.unwrapNew(dbDirectory, sentinelDto, filesToUnwrap, true, false, TruncatedPolicyIgnore)
 	} else {
-		err = pgBackup.unwrapOld(dbDirectory, sentinelDto, filesToUnwrap, true)
+		err = pgBackup.unwrapOld(dbDirectory, sentinelDto, filesToUnwrap, true, nil, nil)
 	}
 
-	tracelog.ErrorLogger.FatalfOnError("Failed unwrap backup: %v", err)
+	internal.FatalfOnError("Failed unwrap backup: %v", err)
 }
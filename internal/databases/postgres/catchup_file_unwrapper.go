@@ -20,7 +20,7 @@ func (u *CatchupFileUnwrapper) UnwrapNewFile(reader io.Reader, header *tar.Heade
 		if err != nil {
 			return nil, err
 		}
-		missingBlockCount, err := CreateFileFromIncrement(reader, targetReadWriterAt)
+		missingBlockCount, err := CreateFileFromIncrement(reader, targetReadWriterAt, u.options.pageSize)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Interpret: failed to create file from increment '%s'", file.Name())
 		}
@@ -40,7 +40,7 @@ func (u *CatchupFileUnwrapper) UnwrapExistingFile(reader io.Reader, header *tar.
 		if err != nil {
 			return nil, err
 		}
-		restoredBlockCount, err := WritePagesFromIncrement(reader, targetReadWriterAt, true)
+		restoredBlockCount, err := WritePagesFromIncrement(reader, targetReadWriterAt, u.options.pageSize, true)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Interpret: failed to write increment to file '%s'", file.Name())
 		}
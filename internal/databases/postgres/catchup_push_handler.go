@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"github.com/spf13/viper"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -21,8 +20,6 @@ func HandleCatchupPush(pgDataDirectory string, fromLSN uint64) {
 		BackupStartLSN: &fromLSN,
 	}
 
-	extendExcludedFiles()
-
 	backupArguments := BackupArguments{
 		isPermanent:         false,
 		verifyPageChecksums: false,
@@ -33,9 +30,13 @@ func HandleCatchupPush(pgDataDirectory string, fromLSN uint64) {
 		userData:            viper.GetString(internal.SentinelUserDataSetting),
 	}
 	backupConfig, err := NewBackupHandler(backupArguments)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
+	// the backup handler applies WALG_BACKUP_EXCLUDED_FILES, the catchup excludes the config files on top of them
+	extendExcludedFiles()
 	backupConfig.checkPgVersionAndPgControl()
 	backupConfig.prevBackupInfo.sentinelDto = fakePreviousBackupSentinelDto
 	backupConfig.curBackupInfo.startLSN = fromLSN
+	backupConfig.interruption.start()
+	defer backupConfig.interruption.stop()
 	backupConfig.createAndPushBackup()
 }
@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/semaphore"
+)
+
+// stagedTarPartition is the tar partition downloaded to the staging directory ahead of its extraction
+type stagedTarPartition struct {
+	storageReaderMaker internal.ReaderMaker
+	stagedPath         string
+	downloaded         chan struct{}
+	err                error
+	// started is set when the download ahead starts, claimed when the extraction reads the partition
+	started bool
+	claimed bool
+}
+
+// ChainDownload downloads the tar partitions of all the backups of the delta chain ahead of their extraction,
+// the partitions of the base backup first. The downloads share the WALG_DOWNLOAD_CONCURRENCY slots with
+// the extraction, so they use the slots left by the extraction, e.g. while the last partitions of a backup
+// are extracted. At most WALG_DOWNLOAD_CONCURRENCY partitions are staged at once, a partition leaves
+// the window when it is extracted. The backups are still extracted in order: the partition being downloaded
+// is read from the staging directory once downloaded, the one not started yet is read from the storage.
+// The partitions left unread by the extraction of a backup leave the window once the backup is extracted.
+// The partitions are downloaded as they are stored, they are decrypted and decompressed on the extraction.
+type ChainDownload struct {
+	stagingDirectory string
+	partitions       map[string]map[string]*stagedTarPartition
+	mutex            sync.Mutex
+	window           *semaphore.Weighted
+	cancel           context.CancelFunc
+	downloads        sync.WaitGroup
+}
+
+// NewChainDownload starts the download of the tar partitions of the delta chain of the backup,
+// the partitions of the base backup are downloaded first
+func NewChainDownload(baseBackupFolder storage.Folder, backupName string) (*ChainDownload, error) {
+	concurrency, err := internal.GetMaxDownloadConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	downloadSemaphore, err := internal.GetSharedDownloadSemaphore()
+	if err != nil {
+		return nil, err
+	}
+	chainNames, err := getDeltaChainNames(baseBackupFolder, backupName)
+	if err != nil {
+		return nil, err
+	}
+	stagingDirectory, err := ioutil.TempDir("", "wal-g-chain-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the staging directory of the delta chain download")
+	}
+
+	chainDownload := &ChainDownload{
+		stagingDirectory: stagingDirectory,
+		partitions:       make(map[string]map[string]*stagedTarPartition),
+		window:           semaphore.NewWeighted(int64(concurrency)),
+	}
+	queue := make([]*stagedTarPartition, 0)
+	for i := len(chainNames) - 1; i >= 0; i-- {
+		backup := NewBackup(baseBackupFolder, chainNames[i])
+		tarNames, err := backup.GetTarNames()
+		if err != nil {
+			_ = os.RemoveAll(stagingDirectory)
+			return nil, err
+		}
+		chainDownload.partitions[backup.Name] = make(map[string]*stagedTarPartition)
+		for _, tarName := range tarNames {
+			// the chunks of the chunked tar partitions are downloaded on the extraction
+			if internal.IsChunkManifest(tarName) {
+				continue
+			}
+			partition := &stagedTarPartition{
+				storageReaderMaker: internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), tarName),
+				stagedPath:         filepath.Join(stagingDirectory, strconv.Itoa(len(queue))),
+				downloaded:         make(chan struct{}),
+			}
+			chainDownload.partitions[backup.Name][tarName] = partition
+			queue = append(queue, partition)
+		}
+	}
+	tracelog.InfoLogger.Printf("Downloading up to %d of %d tar partitions of %d backups of the delta chain "+
+		"ahead to %s\n", concurrency, len(queue), len(chainNames), stagingDirectory)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chainDownload.cancel = cancel
+	chainDownload.downloads.Add(1)
+	go chainDownload.downloadAhead(ctx, queue, downloadSemaphore)
+	return chainDownload, nil
+}
+
+// downloadAhead starts the downloads of the queued partitions in order,
+// as the window and the download slots allow, skipping the ones already read by the extraction
+func (chainDownload *ChainDownload) downloadAhead(ctx context.Context, queue []*stagedTarPartition,
+	downloadSemaphore *semaphore.Weighted) {
+	defer chainDownload.downloads.Done()
+	for _, partition := range queue {
+		if chainDownload.window.Acquire(ctx, 1) != nil {
+			return
+		}
+		if downloadSemaphore.Acquire(ctx, 1) != nil {
+			chainDownload.window.Release(1)
+			return
+		}
+		chainDownload.mutex.Lock()
+		claimed := partition.claimed
+		partition.started = !claimed
+		chainDownload.mutex.Unlock()
+		if claimed {
+			downloadSemaphore.Release(1)
+			chainDownload.window.Release(1)
+			continue
+		}
+
+		chainDownload.downloads.Add(1)
+		go func(partition *stagedTarPartition) {
+			defer chainDownload.downloads.Done()
+			partition.err = partition.download()
+			downloadSemaphore.Release(1)
+			close(partition.downloaded)
+		}(partition)
+	}
+}
+
+// getDeltaChainNames returns the names of the backups of the delta chain, starting from the backup itself
+func getDeltaChainNames(baseBackupFolder storage.Folder, backupName string) ([]string, error) {
+	names := make([]string, 0)
+	for name := backupName; ; {
+		names = append(names, name)
+		backup := NewBackup(baseBackupFolder, name)
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return nil, err
+		}
+		if !sentinelDto.IsIncremental() {
+			return names, nil
+		}
+		name = *sentinelDto.IncrementFrom
+	}
+}
+
+func (partition *stagedTarPartition) download() error {
+	reader, err := partition.storageReaderMaker.Reader()
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	file, err := os.OpenFile(partition.stagedPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = utility.FastCopy(file, reader)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readerMaker returns the reader maker of the tar partition of the backup, which waits for
+// the staged download. Nil is returned if the tar partition is not downloaded ahead.
+func (chainDownload *ChainDownload) readerMaker(backupName, tarName string) internal.ReaderMaker {
+	partition, ok := chainDownload.partitions[backupName][tarName]
+	if !ok {
+		return nil
+	}
+	return &stagedReaderMaker{chainDownload, partition}
+}
+
+// releaseUnread releases the window slots of the tar partitions of the extracted backup which were
+// not read, e.g. the pg_control one of the backup not requiring it, and removes them from the staging directory
+func (chainDownload *ChainDownload) releaseUnread(backupName string) {
+	for _, partition := range chainDownload.partitions[backupName] {
+		chainDownload.mutex.Lock()
+		unread := partition.started && !partition.claimed
+		partition.claimed = true
+		chainDownload.mutex.Unlock()
+		if !unread {
+			continue
+		}
+
+		chainDownload.downloads.Add(1)
+		go func(partition *stagedTarPartition) {
+			defer chainDownload.downloads.Done()
+			<-partition.downloaded
+			if err := os.Remove(partition.stagedPath); err != nil && !os.IsNotExist(err) {
+				tracelog.WarningLogger.Printf("Failed to remove the unread tar partition %s: %v\n",
+					partition.storageReaderMaker.Path(), err)
+			}
+			chainDownload.window.Release(1)
+		}(partition)
+	}
+}
+
+// Close stops the downloads and removes the staging directory
+func (chainDownload *ChainDownload) Close() error {
+	chainDownload.cancel()
+	chainDownload.downloads.Wait()
+	return os.RemoveAll(chainDownload.stagingDirectory)
+}
+
+type stagedReaderMaker struct {
+	chainDownload *ChainDownload
+	partition     *stagedTarPartition
+}
+
+func (readerMaker *stagedReaderMaker) Path() string {
+	return readerMaker.partition.storageReaderMaker.Path()
+}
+
+// Reader reads the staged tar partition once, it is removed from the staging directory on close.
+// The partitions not downloaded ahead yet, the retries of the extraction and the failed downloads
+// read the tar partition from the storage.
+func (readerMaker *stagedReaderMaker) Reader() (io.ReadCloser, error) {
+	chainDownload, partition := readerMaker.chainDownload, readerMaker.partition
+	chainDownload.mutex.Lock()
+	staged := partition.started && !partition.claimed
+	partition.claimed = true
+	chainDownload.mutex.Unlock()
+	if !staged {
+		return partition.storageReaderMaker.Reader()
+	}
+
+	<-partition.downloaded
+	if partition.err != nil {
+		chainDownload.window.Release(1)
+		tracelog.WarningLogger.Printf("Failed to download %s ahead, reading it from the storage: %v\n",
+			readerMaker.Path(), partition.err)
+		return partition.storageReaderMaker.Reader()
+	}
+	file, err := os.Open(partition.stagedPath)
+	if err != nil {
+		chainDownload.window.Release(1)
+		return nil, err
+	}
+	return &stagedFileReadCloser{file, chainDownload.window}, nil
+}
+
+// stagedFileReadCloser removes the staged tar partition on close, so the next one may be downloaded ahead
+type stagedFileReadCloser struct {
+	*os.File
+	window *semaphore.Weighted
+}
+
+func (reader *stagedFileReadCloser) Close() error {
+	if reader.window != nil {
+		defer reader.window.Release(1)
+		reader.window = nil
+	}
+	err := reader.File.Close()
+	if removeErr := os.Remove(reader.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
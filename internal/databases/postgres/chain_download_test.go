@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func readStaged(t *testing.T, readerMaker internal.ReaderMaker) string {
+	reader, err := readerMaker.Reader()
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	return string(content)
+}
+
+func TestChainDownload(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	lsn := uint64(0x2000028)
+	fullName := flattenFullName
+	incrementCount := 1
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn}, fullName))
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn,
+		IncrementFrom: &fullName, IncrementFullName: &fullName, IncrementFromLSN: &lsn,
+		IncrementCount: &incrementCount}, flattenDeltaName))
+	require.NoError(t, baseBackupFolder.PutObject(flattenFullName+"/tar_partitions/part_1.tar.lz4",
+		bytes.NewBufferString("full")))
+	require.NoError(t, baseBackupFolder.PutObject(flattenDeltaName+"/tar_partitions/part_1.tar.lz4",
+		bytes.NewBufferString("delta")))
+
+	chainDownload, err := NewChainDownload(baseBackupFolder, flattenDeltaName)
+	require.NoError(t, err)
+	assert.Len(t, chainDownload.partitions, 2)
+	assert.Nil(t, chainDownload.readerMaker(flattenFullName, "part_2.tar.lz4"))
+
+	fullReaderMaker := chainDownload.readerMaker(flattenFullName, "part_1.tar.lz4")
+	require.NotNil(t, fullReaderMaker)
+	assert.Equal(t, "part_1.tar.lz4", fullReaderMaker.Path())
+	assert.Equal(t, "full", readStaged(t, fullReaderMaker))
+	assert.NoFileExists(t, chainDownload.partitions[flattenFullName]["part_1.tar.lz4"].stagedPath)
+	// the retry reads from the storage
+	assert.Equal(t, "full", readStaged(t, fullReaderMaker))
+	assert.Equal(t, "delta", readStaged(t, chainDownload.readerMaker(flattenDeltaName, "part_1.tar.lz4")))
+
+	require.NoError(t, chainDownload.Close())
+	assert.NoDirExists(t, chainDownload.stagingDirectory)
+}
+
+func TestChainDownload_Window(t *testing.T) {
+	os.Setenv(internal.DownloadConcurrencySetting, "1")
+	defer os.Unsetenv(internal.DownloadConcurrencySetting)
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	lsn := uint64(0x2000028)
+	fullName := flattenFullName
+	incrementCount := 1
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn}, fullName))
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn,
+		IncrementFrom: &fullName, IncrementFullName: &fullName, IncrementFromLSN: &lsn,
+		IncrementCount: &incrementCount}, flattenDeltaName))
+	for _, name := range []string{"part_1.tar.lz4", "part_2.tar.lz4"} {
+		require.NoError(t, baseBackupFolder.PutObject(flattenDeltaName+"/tar_partitions/"+name,
+			bytes.NewBufferString(name)))
+	}
+
+	chainDownload, err := NewChainDownload(baseBackupFolder, flattenDeltaName)
+	require.NoError(t, err)
+	defer chainDownload.Close()
+	// the tar partitions are listed in no particular order
+	firstName, secondName := "part_1.tar.lz4", "part_2.tar.lz4"
+	select {
+	case <-chainDownload.partitions[flattenDeltaName][firstName].downloaded:
+	case <-chainDownload.partitions[flattenDeltaName][secondName].downloaded:
+		firstName, secondName = secondName, firstName
+	}
+	first := chainDownload.partitions[flattenDeltaName][firstName]
+	second := chainDownload.partitions[flattenDeltaName][secondName]
+	require.NoError(t, first.err)
+	// the window of one partition is taken until the first one is extracted
+	chainDownload.mutex.Lock()
+	assert.False(t, second.started)
+	chainDownload.mutex.Unlock()
+
+	assert.Equal(t, firstName, readStaged(t, chainDownload.readerMaker(flattenDeltaName, firstName)))
+	<-second.downloaded
+	assert.FileExists(t, second.stagedPath)
+	assert.Equal(t, secondName, readStaged(t, chainDownload.readerMaker(flattenDeltaName, secondName)))
+}
+
+func TestChainDownload_ReleaseUnread(t *testing.T) {
+	os.Setenv(internal.DownloadConcurrencySetting, "1")
+	defer os.Unsetenv(internal.DownloadConcurrencySetting)
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	lsn := uint64(0x2000028)
+	fullName := flattenFullName
+	incrementCount := 1
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn}, fullName))
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn,
+		IncrementFrom: &fullName, IncrementFullName: &fullName, IncrementFromLSN: &lsn,
+		IncrementCount: &incrementCount}, flattenDeltaName))
+	require.NoError(t, baseBackupFolder.PutObject(flattenFullName+"/tar_partitions/pg_control.tar.lz4",
+		bytes.NewBufferString("full")))
+	require.NoError(t, baseBackupFolder.PutObject(flattenDeltaName+"/tar_partitions/part_1.tar.lz4",
+		bytes.NewBufferString("delta")))
+
+	chainDownload, err := NewChainDownload(baseBackupFolder, flattenDeltaName)
+	require.NoError(t, err)
+	defer chainDownload.Close()
+	unread := chainDownload.partitions[flattenFullName]["pg_control.tar.lz4"]
+	delta := chainDownload.partitions[flattenDeltaName]["part_1.tar.lz4"]
+	<-unread.downloaded
+	// the unread partition of the full backup takes the window of one partition until the backup is extracted
+	chainDownload.mutex.Lock()
+	assert.False(t, delta.started)
+	chainDownload.mutex.Unlock()
+
+	chainDownload.releaseUnread(flattenFullName)
+	<-delta.downloaded
+	assert.NoFileExists(t, unread.stagedPath)
+	assert.FileExists(t, delta.stagedPath)
+	assert.Equal(t, "delta", readStaged(t, chainDownload.readerMaker(flattenDeltaName, "part_1.tar.lz4")))
+}
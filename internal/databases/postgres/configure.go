@@ -10,8 +10,18 @@ import (
 
 // ConfigureWalUploader connects to storage and creates an uploader. It makes sure
 // that a valid session has started; if invalid, returns AWS error
-// and `<nil>` values.
+// and `<nil>` values. The WAL is compressed with WALG_WAL_COMPRESSION_METHOD.
 func ConfigureWalUploader() (uploader *WalUploader, err error) {
+	return configureWalUploaderWithCompression(internal.WalCompressionSetting)
+}
+
+// ConfigureBackupUploader is the same as ConfigureWalUploader,
+// but compresses with WALG_BACKUP_COMPRESSION_METHOD
+func ConfigureBackupUploader() (uploader *WalUploader, err error) {
+	return configureWalUploaderWithCompression(internal.BackupCompressionSetting)
+}
+
+func configureWalUploaderWithCompression(methodSetting string) (uploader *WalUploader, err error) {
 	uploader, err = ConfigureWalUploaderWithoutCompressMethod()
 	if err != nil {
 		return nil, err
@@ -20,10 +30,13 @@ func ConfigureWalUploader() (uploader *WalUploader, err error) {
 	folder := uploader.UploadingFolder
 	deltaFileManager := uploader.DeltaFileManager
 
-	compressor, err := internal.ConfigureCompressor()
+	compressor, err := internal.ConfigurePostgresCompressorWithSetting(methodSetting)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to configure compression")
 	}
+	if err = internal.UploadZstdDictionary(folder); err != nil {
+		return nil, err
+	}
 
 	uploader = NewWalUploader(compressor, folder, deltaFileManager)
 	return uploader, err
@@ -1,11 +1,16 @@
 package postgres
 
 import (
+	"strconv"
+
 	"github.com/jackc/pgx"
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 )
 
+const statisticsApplicationName = "wal-g statistics"
+
 // Connect establishes a connection to postgres using
 // a UNIX socket. Must export PGHOST and run with `sudo -E -u postgres`.
 // If PGHOST is not set or if the connection fails, an error is returned
@@ -73,6 +78,25 @@ func Connect(configOptions ...func(config *pgx.ConnConfig) error) (*pgx.Conn, er
 	return conn, nil
 }
 
+// ConnectForStatistics establishes the separate connection for the statistics queries
+// limited by WALG_STATISTICS_STATEMENT_TIMEOUT, so a slow statistics query
+// neither blocks the backup connection nor is cancelled together with it
+func ConnectForStatistics(configOptions ...func(config *pgx.ConnConfig) error) (*pgx.Conn, error) {
+	timeout, err := internal.GetDurationSetting(internal.StatisticsTimeoutSetting)
+	if err != nil {
+		return nil, err
+	}
+	statisticsOption := func(config *pgx.ConnConfig) error {
+		if config.RuntimeParams == nil {
+			config.RuntimeParams = make(map[string]string)
+		}
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+		config.RuntimeParams["application_name"] = statisticsApplicationName
+		return nil
+	}
+	return Connect(append(configOptions, statisticsOption)...)
+}
+
 // nolint:gocritic
 func tryConnectToGpSegment(config pgx.ConnConfig) (*pgx.Conn, error) {
 	config.RuntimeParams["gp_role"] = "utility"
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type ConsistencyWalSegmentsMissingError struct {
+	error
+}
+
+func newConsistencyWalSegmentsMissingError(backupName string, missing []string) ConsistencyWalSegmentsMissingError {
+	return ConsistencyWalSegmentsMissingError{errors.Errorf(
+		"WAL segments required to make backup %s consistent are missing in storage: %v", backupName, missing)}
+}
+
+func (err ConsistencyWalSegmentsMissingError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetConsistencyWalSegmentNames returns the names of WAL segments between the
+// backup start and finish LSN. These segments are sufficient to bring
+// the restored backup to the consistent state.
+func GetConsistencyWalSegmentNames(sentinelDto BackupSentinelDto, timeline uint32) ([]string, error) {
+	if sentinelDto.BackupStartLSN == nil || sentinelDto.BackupFinishLSN == nil {
+		return nil, errors.New("backup sentinel does not contain start and finish LSN")
+	}
+	if *sentinelDto.BackupFinishLSN <= *sentinelDto.BackupStartLSN {
+		return nil, errors.Errorf("backup finish LSN %x is not after start LSN %x",
+			*sentinelDto.BackupFinishLSN, *sentinelDto.BackupStartLSN)
+	}
+
+	firstSegmentNo := newWalSegmentNo(*sentinelDto.BackupStartLSN)
+	// finish LSN points right after the last record required
+	lastSegmentNo := newWalSegmentNo(*sentinelDto.BackupFinishLSN - 1)
+	names := make([]string, 0, uint64(lastSegmentNo-firstSegmentNo)+1)
+	for segmentNo := firstSegmentNo; segmentNo <= lastSegmentNo; segmentNo = segmentNo.next() {
+		names = append(names, segmentNo.getFilename(timeline))
+	}
+	return names, nil
+}
+
+// FetchConsistencyWals downloads only the WAL segments required to make the backup
+// consistent into the pg_wal (pg_xlog before PostgreSQL 10) of the restored data directory
+func FetchConsistencyWals(rootFolder storage.Folder, backupName, dbDataDirectory string) error {
+	backup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), backupName)
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return err
+	}
+	timeline, err := ParseTimelineFromBackupName(backupName)
+	if err != nil {
+		return err
+	}
+	ConfigureWalSegmentSize(sentinelDto)
+	segmentNames, err := GetConsistencyWalSegmentNames(sentinelDto, timeline)
+	if err != nil {
+		return err
+	}
+
+	walFolder := rootFolder.GetSubFolder(utility.WalPath)
+	missing := make([]string, 0)
+	for _, segmentName := range segmentNames {
+		exists, err := walSegmentExists(walFolder, segmentName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			missing = append(missing, segmentName)
+		}
+	}
+	if len(missing) > 0 {
+		return newConsistencyWalSegmentsMissingError(backupName, missing)
+	}
+
+	walDirectory := filepath.Join(dbDataDirectory, "pg_wal")
+	if sentinelDto.PgVersion > 0 && sentinelDto.PgVersion < 100000 {
+		walDirectory = filepath.Join(dbDataDirectory, "pg_xlog")
+	}
+	err = os.MkdirAll(walDirectory, 0700)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create WAL directory '%s'", walDirectory)
+	}
+	tracelog.InfoLogger.Printf("Fetching %d WAL segments from %s to %s required for consistency\n",
+		len(segmentNames), segmentNames[0], segmentNames[len(segmentNames)-1])
+	for _, segmentName := range segmentNames {
+		err = internal.DownloadFileTo(walFolder, segmentName, filepath.Join(walDirectory, segmentName))
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch WAL segment '%s'", segmentName)
+		}
+	}
+	return nil
+}
+
+func walSegmentExists(walFolder storage.Folder, segmentName string) (bool, error) {
+	for _, decompressor := range compression.Decompressors {
+		exists, err := walFolder.Exists(segmentName + "." + decompressor.FileExtension())
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+// HandleConsistencyWalFetch fetches the WAL segments required to make the backup consistent
+func HandleConsistencyWalFetch(rootFolder storage.Folder, backup internal.Backup, dbDataDirectory string) {
+	err := FetchConsistencyWals(rootFolder, backup.Name, utility.ResolveSymlink(dbDataDirectory))
+	internal.FatalfOnError("Failed to fetch WAL required for consistency: %v\n", err)
+}
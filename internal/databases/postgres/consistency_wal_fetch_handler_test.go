@@ -0,0 +1,27 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestGetConsistencyWalSegmentNames(t *testing.T) {
+	startLSN := uint64(0x2A000028)
+	finishLSN := uint64(0x2C000000)
+	sentinelDto := postgres.BackupSentinelDto{BackupStartLSN: &startLSN, BackupFinishLSN: &finishLSN}
+
+	names, err := postgres.GetConsistencyWalSegmentNames(sentinelDto, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"00000003000000000000002A", "00000003000000000000002B"}, names)
+}
+
+func TestGetConsistencyWalSegmentNames_NoFinishLSN(t *testing.T) {
+	startLSN := uint64(0x2A000028)
+	sentinelDto := postgres.BackupSentinelDto{BackupStartLSN: &startLSN}
+
+	_, err := postgres.GetConsistencyWalSegmentNames(sentinelDto, 1)
+	assert.Error(t, err)
+}
@@ -19,9 +19,9 @@ func HandleCopy(fromConfigFile string, toConfigFile string, backupName string, w
 		return
 	}
 	infos, err := getCopyingInfos(backupName, from, to, withoutHistory)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	err = copy.Infos(infos)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	tracelog.InfoLogger.Println("Success copy.")
 }
 
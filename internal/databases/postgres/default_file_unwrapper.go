@@ -20,7 +20,7 @@ func (u *DefaultFileUnwrapper) UnwrapNewFile(reader io.Reader, header *tar.Heade
 		if err != nil {
 			return nil, err
 		}
-		missingBlockCount, err := CreateFileFromIncrement(reader, targetReadWriterAt)
+		missingBlockCount, err := CreateFileFromIncrement(reader, targetReadWriterAt, u.options.pageSize)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Interpret: failed to create file from increment '%s'", file.Name())
 		}
@@ -40,7 +40,7 @@ func (u *DefaultFileUnwrapper) UnwrapExistingFile(reader io.Reader, header *tar.
 		return nil, err
 	}
 	if u.options.isIncremented {
-		restoredBlockCount, err := WritePagesFromIncrement(reader, targetReadWriterAt, false)
+		restoredBlockCount, err := WritePagesFromIncrement(reader, targetReadWriterAt, u.options.pageSize, false)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Interpret: failed to write increment to file '%s'", file.Name())
 		}
@@ -48,7 +48,7 @@ func (u *DefaultFileUnwrapper) UnwrapExistingFile(reader io.Reader, header *tar.
 	}
 
 	if u.options.isPageFile {
-		err := RestoreMissingPages(reader, targetReadWriterAt)
+		err := RestoreMissingPages(reader, targetReadWriterAt, u.options.pageSize, u.options.truncatedPolicy)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Interpret: failed to restore pages for file '%s'", file.Name())
 		}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/test/mocks"
@@ -280,6 +281,95 @@ func TestDeleteBeforeTargetWithPermanentBackups(t *testing.T) {
 	verifyThatExistBackupsAndWals(t, expectBackupExistAfterDelete, expectWalExistAfterDelete, folder)
 }
 
+type testDeltaBackupObject struct {
+	TestPostgresBackupObject
+	incrementFrom string
+}
+
+func (o testDeltaBackupObject) GetIncrementFromName() string {
+	return o.incrementFrom
+}
+
+func TestDeleteBeforeTarget_FailsOnOrphanedDeltas(t *testing.T) {
+	baseTime := utility.TimeNowCrossPlatformLocal()
+	newObject := func(name string, minute int) TestPostgresBackupObject {
+		return TestPostgresBackupObject{storage.NewLocalObject(name, baseTime.Add(time.Duration(minute)*time.Minute), 0)}
+	}
+	oldFull := newObject("base_000000010000000000000002", 0)
+	newFull := newObject("base_000000010000000000000004", 1)
+	// the delta taken from the older full backup after the newer one
+	delta := testDeltaBackupObject{newObject("base_000000010000000000000006_D_000000010000000000000002", 2),
+		oldFull.GetBackupName()}
+
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	backups := []internal.BackupObject{oldFull, newFull, delta}
+	deleteHandler := internal.NewDeleteHandler(folder, backups, lessByTime)
+	err := deleteHandler.DeleteBeforeTarget(newFull, false)
+	assert.IsType(t, utility.ForbiddenActionError{}, err)
+	assert.Contains(t, err.Error(), delta.GetBackupName())
+
+	isPermanent := func(object storage.Object) bool { return object.GetName() == oldFull.GetName() }
+	deleteHandler = internal.NewDeleteHandler(folder, backups, lessByTime, internal.IsPermanentFunc(isPermanent))
+	assert.NoError(t, deleteHandler.DeleteBeforeTarget(newFull, false))
+}
+
+func TestFindGFSTargets(t *testing.T) {
+	baseTime := utility.TimeNowCrossPlatformLocal()
+	newObject := func(name string, minute int) TestPostgresBackupObject {
+		return TestPostgresBackupObject{storage.NewLocalObject(name, baseTime.Add(time.Duration(minute)*time.Minute), 0)}
+	}
+	oldFull := newObject("base_000000010000000000000002", 0)
+	oldDelta := testDeltaBackupObject{newObject("base_000000010000000000000004_D_000000010000000000000002", 1),
+		oldFull.GetBackupName()}
+	oldDelta2 := testDeltaBackupObject{newObject("base_000000010000000000000006_D_000000010000000000000004", 2),
+		oldDelta.GetBackupName()}
+	full := newObject("base_000000010000000000000008", 3)
+	delta := testDeltaBackupObject{newObject("base_000000010000000000000010_D_000000010000000000000008", 4),
+		full.GetBackupName()}
+	unclassified := newObject("base_000000010000000000000012", 5)
+	retentionClasses := map[string]string{
+		oldFull.GetBackupName():   "weekly",
+		oldDelta.GetBackupName():  "daily",
+		oldDelta2.GetBackupName(): "daily",
+		full.GetBackupName():      "weekly",
+		delta.GetBackupName():     "daily",
+	}
+	backups := []internal.BackupObject{oldFull, oldDelta, oldDelta2, full, delta, unclassified}
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	targetNames := func(deleteHandler *internal.DeleteHandler, retentionCounts map[string]int) []string {
+		names := make([]string, 0)
+		for _, target := range deleteHandler.FindGFSTargets(retentionCounts, retentionClasses) {
+			names = append(names, target.GetBackupName())
+		}
+		return names
+	}
+
+	deleteHandler := internal.NewDeleteHandler(folder, backups, lessByTime)
+	assert.Equal(t, []string{oldDelta2.GetBackupName(), oldDelta.GetBackupName(), oldFull.GetBackupName()},
+		targetNames(deleteHandler, map[string]int{"daily": 1, "weekly": 1}))
+	// the retained delta keeps its increment chain regardless of the classes
+	assert.Empty(t, targetNames(deleteHandler, map[string]int{"daily": 2, "weekly": 1}))
+	// the backups of the classes not in the policy are retained
+	assert.Equal(t, []string{oldDelta2.GetBackupName(), oldDelta.GetBackupName()},
+		targetNames(deleteHandler, map[string]int{"daily": 1}))
+
+	isPermanent := func(object storage.Object) bool { return object.GetName() == oldDelta.GetName() }
+	deleteHandler = internal.NewDeleteHandler(folder, backups, lessByTime, internal.IsPermanentFunc(isPermanent))
+	assert.Equal(t, []string{oldDelta2.GetBackupName()},
+		targetNames(deleteHandler, map[string]int{"daily": 1, "weekly": 1}))
+}
+
+func TestParseGFSRetentionCounts(t *testing.T) {
+	retentionCounts, err := internal.ParseGFSRetentionCounts([]string{"daily=7", "monthly=0"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"daily": 7, "monthly": 0}, retentionCounts)
+
+	for _, args := range [][]string{{"daily"}, {"dayly=7"}, {"daily=-1"}, {"daily=x"}, {"daily=1", "daily=2"}} {
+		_, err = internal.ParseGFSRetentionCounts(args)
+		assert.Error(t, err, args)
+	}
+}
+
 func createMockFolderWithTime(t *testing.T, baseTime time.Time) *mocks.MockFolder {
 	baseNamePrefix := "base_"
 	deltaMark := "_D_"
@@ -379,3 +469,34 @@ func makeTestPermanentFunc(permanentBackups, permanentWals map[string]bool) func
 		return postgres.IsPermanent(object.GetName(), permanentBackups, permanentWals)
 	}
 }
+
+func TestWithWalRetentionMargin(t *testing.T) {
+	less := func(object1, object2 storage.Object) bool {
+		_, segNo1, _ := postgres.TryFetchTimelineAndLogSegNo(object1.GetName())
+		_, segNo2, _ := postgres.TryFetchTimelineAndLogSegNo(object2.GetName())
+		return segNo1 < segNo2
+	}
+	marginLess := postgres.WithWalRetentionMargin(less, 2)
+	target := storage.NewLocalObject(
+		utility.BaseBackupPath+"base_000000010000000000000010"+utility.SentinelSuffix, time.Now(), 0)
+	walObject := func(name string) storage.Object {
+		return storage.NewLocalObject(utility.WalPath+name+".lz4", time.Now(), 0)
+	}
+
+	assert.True(t, marginLess(walObject("00000001000000000000000D"), target))
+	assert.False(t, marginLess(walObject("00000001000000000000000E"), target))
+	assert.False(t, marginLess(walObject("00000001000000000000000F"), target))
+	assert.True(t, marginLess(storage.NewLocalObject(
+		utility.BaseBackupPath+"base_00000001000000000000000F"+utility.SentinelSuffix, time.Now(), 0), target))
+}
+
+func TestWithoutChunkStore(t *testing.T) {
+	less := postgres.WithoutChunkStore(func(object1, object2 storage.Object) bool { return true })
+	target := storage.NewLocalObject(
+		utility.BaseBackupPath+"base_000000010000000000000010"+utility.SentinelSuffix, time.Now(), 0)
+
+	// the chunk names may look like the WAL segment names, the chunks are deleted by the references
+	assert.False(t, less(storage.NewLocalObject(utility.BaseBackupPath+internal.ChunkStoreFolderName+
+		"000000010000000000000001aaaa.lz4", time.Now(), 0), target))
+	assert.True(t, less(storage.NewLocalObject(utility.WalPath+"000000010000000000000001.lz4", time.Now(), 0), target))
+}
@@ -1,6 +1,9 @@
 package postgres
 
 import (
+	"strings"
+
+	"github.com/pkg/errors"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -43,6 +46,63 @@ func GetPermanentBackupsAndWals(folder storage.Folder) (map[string]bool, map[str
 	return permanentBackups, permanentWals
 }
 
+// GetBackupRetentionClasses returns the retention classes set by backup-push --retention-class by the backup name
+func GetBackupRetentionClasses(folder storage.Folder) (map[string]string, error) {
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	backupTimes, err := internal.GetBackups(baseBackupFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	retentionClasses := make(map[string]string, len(backupTimes))
+	for _, backupTime := range backupTimes {
+		backup := NewBackup(baseBackupFolder, backupTime.BackupName)
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch the sentinel of backup %s", backupTime.BackupName)
+		}
+		if sentinelDto.RetentionClass != "" {
+			retentionClasses[backupTime.BackupName] = sentinelDto.RetentionClass
+		}
+	}
+	return retentionClasses, nil
+}
+
+// WithWalRetentionMargin wraps the delete handler less function so that the WAL segments
+// within the margin (in segments) before the backup start segment are never considered older than the backup
+func WithWalRetentionMargin(less func(storage.Object, storage.Object) bool,
+	margin uint64) func(storage.Object, storage.Object) bool {
+	return func(object1 storage.Object, object2 storage.Object) bool {
+		if strings.HasPrefix(object1.GetName(), utility.WalPath) {
+			_, segNo1, ok1 := TryFetchTimelineAndLogSegNo(object1.GetName())
+			_, segNo2, ok2 := TryFetchTimelineAndLogSegNo(object2.GetName())
+			if ok1 && ok2 && segNo1+margin >= segNo2 {
+				return false
+			}
+		}
+		return less(object1, object2)
+	}
+}
+
+// WithoutChunkStore wraps the delete handler less function so that the chunks of the chunk store
+// are never considered older than the backup, they are shared between the backups
+// and deleted by DeleteUnreferencedChunks once none of the backups references them
+func WithoutChunkStore(less func(storage.Object, storage.Object) bool) func(storage.Object, storage.Object) bool {
+	return func(object1 storage.Object, object2 storage.Object) bool {
+		if strings.HasPrefix(object1.GetName(), utility.BaseBackupPath+internal.ChunkStoreFolderName) {
+			return false
+		}
+		return less(object1, object2)
+	}
+}
+
+// DeleteUnreferencedChunks deletes the chunks of the dedup chunking which none of the backups references,
+// except the ones uploaded during the last internal.ChunkSweepGracePeriod
+func DeleteUnreferencedChunks(folder storage.Folder, confirmed bool) error {
+	return internal.DeleteUnreferencedChunks(folder.GetSubFolder(utility.BaseBackupPath),
+		internal.ChunkSweepGracePeriod, confirmed)
+}
+
 func IsPermanent(objectName string, permanentBackups, permanentWals map[string]bool) bool {
 	if objectName[:len(utility.WalPath)] == utility.WalPath {
 		wal := objectName[len(utility.WalPath) : len(utility.WalPath)+24]
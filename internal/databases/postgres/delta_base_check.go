@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type DeltaBaseModifiedError struct {
+	error
+}
+
+func newDeltaBaseModifiedError(backupName, baseName string, differences []string) DeltaBaseModifiedError {
+	return DeltaBaseModifiedError{errors.Errorf(
+		"delta base %s of backup %s was modified after the delta was made, the restore would be wrong: %s. "+
+			"Use --force to restore anyway.", baseName, backupName, strings.Join(differences, ", "))}
+}
+
+func (err DeltaBaseModifiedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// compareTarSizes lists the tar partitions which were added, removed or changed their size
+func compareTarSizes(recorded, actual map[string]int64) []string {
+	differences := make([]string, 0)
+	for tarName, recordedSize := range recorded {
+		actualSize, ok := actual[tarName]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s is missing", tarName))
+		} else if actualSize != recordedSize {
+			differences = append(differences, fmt.Sprintf("%s size is %d instead of %d", tarName, actualSize, recordedSize))
+		}
+	}
+	for tarName := range actual {
+		if _, ok := recorded[tarName]; !ok {
+			differences = append(differences, fmt.Sprintf("%s is added", tarName))
+		}
+	}
+	sort.Strings(differences)
+	return differences
+}
+
+// compareDeltaBaseSentinel lists the differences of the delta base sentinel from the base the delta was made from:
+// the start LSN of the base must be the one the delta was made from, and the base must still list the files
+// the delta skipped as unchanged, which are restored from the base
+func compareDeltaBaseSentinel(deltaSentinelDto, baseSentinelDto BackupSentinelDto) []string {
+	differences := make([]string, 0)
+	if baseSentinelDto.BackupStartLSN == nil || *baseSentinelDto.BackupStartLSN != *deltaSentinelDto.IncrementFromLSN {
+		startLSN := "unknown"
+		if baseSentinelDto.BackupStartLSN != nil {
+			startLSN = pglogrepl.LSN(*baseSentinelDto.BackupStartLSN).String()
+		}
+		differences = append(differences, fmt.Sprintf("start LSN is %s instead of %s",
+			startLSN, pglogrepl.LSN(*deltaSentinelDto.IncrementFromLSN)))
+	}
+	missingFiles := make([]string, 0)
+	for name, description := range deltaSentinelDto.Files {
+		if _, ok := baseSentinelDto.Files[name]; description.IsSkipped && !ok {
+			missingFiles = append(missingFiles, name)
+		}
+	}
+	if len(missingFiles) > 0 {
+		sort.Strings(missingFiles)
+		differences = append(differences, fmt.Sprintf("%d files skipped by the delta are missing, e.g. %s",
+			len(missingFiles), missingFiles[0]))
+	}
+	return differences
+}
+
+// CheckDeltaBases walks the delta chain of the backup and compares every delta base with the delta made from it:
+// the start LSN and the file list of the base sentinel, and the tar partitions recorded by the delta
+// when it was made. The tar partitions of the deltas made before they were recorded are not compared.
+// The number of checked delta bases is returned.
+func CheckDeltaBases(baseBackupFolder storage.Folder, backupName string) (int, error) {
+	backup := NewBackup(baseBackupFolder, backupName)
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return 0, err
+	}
+	checked := 0
+	for name := backupName; sentinelDto.IsIncremental(); {
+		baseName := *sentinelDto.IncrementFrom
+		baseBackup := NewBackup(baseBackupFolder, baseName)
+		baseSentinelDto, err := baseBackup.GetSentinel()
+		if err != nil {
+			return checked, err
+		}
+		differences := compareDeltaBaseSentinel(sentinelDto, baseSentinelDto)
+		if sentinelDto.IncrementFromTarSizes != nil {
+			tarSizes, err := baseBackup.GetTarSizes()
+			if err != nil {
+				return checked, err
+			}
+			differences = append(differences, compareTarSizes(sentinelDto.IncrementFromTarSizes, tarSizes)...)
+		}
+		if len(differences) > 0 {
+			return checked, newDeltaBaseModifiedError(name, baseName, differences)
+		}
+		checked++
+		name, sentinelDto = baseName, baseSentinelDto
+	}
+	return checked, nil
+}
+
+// HandleDeltaBasesCheck fails the fetch if any delta base of the backup was modified after its delta was made
+func HandleDeltaBasesCheck(rootFolder storage.Folder, backup internal.Backup) {
+	checked, err := CheckDeltaBases(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name)
+	internal.FatalOnError(err)
+	if checked > 0 {
+		tracelog.InfoLogger.Printf("Delta bases of backup %s are not modified, checked %d\n", backup.Name, checked)
+	}
+}
@@ -0,0 +1,78 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func prepareDeltaChain(t *testing.T, baseTarSizes map[string]int64) storage.Folder {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	fullName := "base_000000010000000000000002"
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	lsn := uint64(0x2000028)
+	incrementCount := 1
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	require.NoError(t, internal.UploadSentinel(uploader, &postgres.BackupSentinelDto{BackupStartLSN: &lsn}, fullName))
+	require.NoError(t, internal.UploadSentinel(uploader, &postgres.BackupSentinelDto{BackupStartLSN: &lsn,
+		IncrementFrom: &fullName, IncrementFullName: &fullName, IncrementFromLSN: &lsn,
+		IncrementCount: &incrementCount, IncrementFromTarSizes: baseTarSizes}, deltaName))
+	tarFolder := baseBackupFolder.GetSubFolder(fullName + internal.TarPartitionFolderName)
+	require.NoError(t, tarFolder.PutObject("part_1.tar.lz4", bytes.NewBufferString("data1")))
+	require.NoError(t, tarFolder.PutObject("part_2.tar.lz4", bytes.NewBufferString("data22")))
+	return baseBackupFolder
+}
+
+func TestCheckDeltaBases(t *testing.T) {
+	baseBackupFolder := prepareDeltaChain(t, map[string]int64{"part_1.tar.lz4": 5, "part_2.tar.lz4": 6})
+	checked, err := postgres.CheckDeltaBases(baseBackupFolder, "base_000000010000000000000004_D_000000010000000000000002")
+	require.NoError(t, err)
+	assert.Equal(t, 1, checked)
+}
+
+func TestCheckDeltaBases_Modified(t *testing.T) {
+	baseBackupFolder := prepareDeltaChain(t, map[string]int64{"part_1.tar.lz4": 5, "part_3.tar.lz4": 6})
+	_, err := postgres.CheckDeltaBases(baseBackupFolder, "base_000000010000000000000004_D_000000010000000000000002")
+	require.IsType(t, postgres.DeltaBaseModifiedError{}, err)
+	assert.Contains(t, err.Error(), "part_2.tar.lz4 is added, part_3.tar.lz4 is missing")
+}
+
+func TestCheckDeltaBases_NotRecorded(t *testing.T) {
+	// the tar partitions are not recorded by the older deltas, the base sentinel is still checked
+	baseBackupFolder := prepareDeltaChain(t, nil)
+	checked, err := postgres.CheckDeltaBases(baseBackupFolder, "base_000000010000000000000004_D_000000010000000000000002")
+	require.NoError(t, err)
+	assert.Equal(t, 1, checked)
+}
+
+func TestCheckDeltaBases_BaseSentinelModified(t *testing.T) {
+	baseBackupFolder := prepareDeltaChain(t, nil)
+	fullName := "base_000000010000000000000002"
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	deltaBackup := postgres.NewBackup(baseBackupFolder, deltaName)
+	deltaSentinel, err := deltaBackup.GetSentinel()
+	require.NoError(t, err)
+	deltaSentinel.Files = internal.BackupFileList{
+		"base/1/1": {IsSkipped: true},
+		"base/1/2": {IsSkipped: true},
+		"base/1/3": {IsIncremented: true},
+	}
+	require.NoError(t, internal.UploadSentinel(uploader, &deltaSentinel, deltaName))
+
+	// the base pushed again under the same name starts at another LSN and has the other files
+	lsn := uint64(0x2000060)
+	require.NoError(t, internal.UploadSentinel(uploader, &postgres.BackupSentinelDto{BackupStartLSN: &lsn,
+		Files: internal.BackupFileList{"base/1/2": {}}}, fullName))
+	_, err = postgres.CheckDeltaBases(baseBackupFolder, deltaName)
+	require.IsType(t, postgres.DeltaBaseModifiedError{}, err)
+	assert.Contains(t, err.Error(), "start LSN is 0/2000060 instead of 0/2000028, "+
+		"1 files skipped by the delta are missing, e.g. base/1/1")
+}
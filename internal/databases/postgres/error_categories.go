@@ -0,0 +1,14 @@
+package postgres
+
+import "github.com/wal-g/wal-g/internal"
+
+func init() {
+	internal.RegisterErrorCategory("unsupported_version", new(UnsupportedPostgresVersionError))
+	internal.RegisterErrorCategory("unsupported_version", new(PgVersionMismatchError))
+	internal.RegisterErrorCategory("insufficient_space", new(InsufficientFreeSpaceError))
+	internal.RegisterErrorCategory("non_empty_directory", new(NonEmptyDBDataDirectoryError))
+	internal.RegisterErrorCategory("corrupted_backup", new(FileChecksumMismatchError))
+	internal.RegisterErrorCategory("corrupted_backup", new(DeltaBaseModifiedError))
+	internal.RegisterErrorCategory("corrupted_backup", new(PageSizeMismatchError))
+	internal.RegisterErrorCategory("incomplete_restore", new(IncompleteRestoreError))
+}
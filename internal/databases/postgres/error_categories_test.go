@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestErrorCategories(t *testing.T) {
+	assert.Equal(t, "unsupported_version", internal.GetErrorCategory(newUnsupportedPostgresVersionError(80400)))
+	assert.Equal(t, "unsupported_version",
+		internal.GetErrorCategory(newPgVersionMismatchError("PG_VERSION", "13", "12")))
+	assert.Equal(t, "insufficient_space",
+		internal.GetErrorCategory(newInsufficientFreeSpaceError("/pgdata", 20, 10)))
+	assert.Equal(t, "non_empty_directory", internal.GetErrorCategory(NewNonEmptyDBDataDirectoryError("/pgdata")))
+	assert.Equal(t, "corrupted_backup",
+		internal.GetErrorCategory(newFileChecksumMismatchError("base/1/2", "aa", "bb")))
+	assert.Equal(t, "corrupted_backup",
+		internal.GetErrorCategory(newPageSizeMismatchError("base_1", 16384, "base_2", 8192)))
+}
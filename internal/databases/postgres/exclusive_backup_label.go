@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+type ExclusiveBackupLabelError struct {
+	error
+}
+
+func newExclusiveBackupLabelError(reason string) ExclusiveBackupLabelError {
+	return ExclusiveBackupLabelError{errors.Errorf("failed to read %s of the exclusive backup: %s",
+		BackupLabelFilename, reason)}
+}
+
+func (err ExclusiveBackupLabelError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func formatBackupLabelLsn(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", lsn>>32, uint32(lsn))
+}
+
+// readExclusiveBackupLabel reads the backup_label the exclusive backup started at startLsn wrote
+// to the data directory. pg_stop_backup removes it, so it must be read before the backup stops.
+func readExclusiveBackupLabel(dbDataDirectory string, startLsn uint64) (string, error) {
+	labelPath := filepath.Join(dbDataDirectory, BackupLabelFilename)
+	content, err := ioutil.ReadFile(labelPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s", labelPath)
+	}
+	label := string(content)
+	startLocation := fmt.Sprintf("START WAL LOCATION: %s ", formatBackupLabelLsn(startLsn))
+	if !strings.HasPrefix(label, startLocation) {
+		return "", newExclusiveBackupLabelError(fmt.Sprintf("it is not of the backup started at %s",
+			formatBackupLabelLsn(startLsn)))
+	}
+	return label, nil
+}
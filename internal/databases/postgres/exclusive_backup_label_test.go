@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadExclusiveBackupLabel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exclusive_backup_label")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	_, err = readExclusiveBackupLabel(dir, 0x102000028)
+	assert.Error(t, err)
+
+	label := "START WAL LOCATION: 1/2000028 (file 000000010000000100000002)\n" +
+		"CHECKPOINT LOCATION: 1/2000060\n" +
+		"BACKUP METHOD: pg_start_backup\n" +
+		"BACKUP FROM: master\n" +
+		"START TIME: 2021-03-04 05:06:07 UTC\n" +
+		"LABEL: 2021-03-04 05:06:07.000000+00:00\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, BackupLabelFilename), []byte(label), 0600))
+	read, err := readExclusiveBackupLabel(dir, 0x102000028)
+	require.NoError(t, err)
+	assert.Equal(t, label, read)
+
+	// the label left by another backup
+	_, err = readExclusiveBackupLabel(dir, 0x10200002)
+	assert.IsType(t, ExclusiveBackupLabelError{}, err)
+}
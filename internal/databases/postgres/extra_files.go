@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ExtraFilesFolderName is the folder inside the backup where the extra files are stored
+const ExtraFilesFolderName = "extra_config"
+
+// ExtraFilesDto describes the files outside PGDATA captured into the backup
+type ExtraFilesDto struct {
+	TarName string   `json:"TarName"`
+	Paths   []string `json:"Paths"`
+}
+
+// GetExtraFilesSetting parses the comma separated list of WALG_BACKUP_EXTRA_FILES
+func GetExtraFilesSetting() ([]string, error) {
+	paths := make([]string, 0)
+	for _, filePath := range strings.Split(viper.GetString(internal.BackupExtraFilesSetting), ",") {
+		filePath = strings.TrimSpace(filePath)
+		if filePath == "" {
+			continue
+		}
+		if !filepath.IsAbs(filePath) {
+			return nil, errors.Errorf("%s must contain absolute paths, got '%s'",
+				internal.BackupExtraFilesSetting, filePath)
+		}
+		paths = append(paths, filepath.Clean(filePath))
+	}
+	return paths, nil
+}
+
+// extraFileTarName maps the original absolute path to the tar member name
+func extraFileTarName(filePath string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filePath), "/")
+}
+
+// UploadExtraFiles packs the files into a single tar and uploads it into the extra_config folder of the backup
+func UploadExtraFiles(uploader *internal.Uploader, crypter crypto.Crypter,
+	backupName string, paths []string) (*ExtraFilesDto, error) {
+	var tarContent bytes.Buffer
+	tarWriter := tar.NewWriter(&tarContent)
+	for _, filePath := range paths {
+		err := packExtraFile(tarWriter, filePath)
+		if err != nil {
+			return nil, err
+		}
+		tracelog.InfoLogger.Printf("Added extra file %s to the backup\n", filePath)
+	}
+	err := tarWriter.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to close extra files tar")
+	}
+
+	tarName := path.Join(ExtraFilesFolderName, "extra_config.tar."+uploader.Compressor.FileExtension())
+	err = uploader.Upload(path.Join(backupName, tarName),
+		internal.CompressAndEncrypt(&tarContent, uploader.Compressor, crypter))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to upload extra files")
+	}
+	return &ExtraFilesDto{TarName: tarName, Paths: paths}, nil
+}
+
+func packExtraFile(tarWriter *tar.Writer, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open extra file %s", filePath)
+	}
+	defer utility.LoggedClose(file, "")
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat extra file %s", filePath)
+	}
+	if !info.Mode().IsRegular() {
+		return errors.Errorf("extra file %s is not a regular file", filePath)
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return errors.Wrapf(err, "failed to make tar header for extra file %s", filePath)
+	}
+	header.Name = extraFileTarName(filePath)
+	internal.SetTarHeaderFormat(header)
+	err = tarWriter.WriteHeader(header)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write tar header for extra file %s", filePath)
+	}
+	_, err = io.Copy(tarWriter, io.LimitReader(file, header.Size))
+	return errors.Wrapf(err, "failed to pack extra file %s", filePath)
+}
+
+// RestoreExtraFiles extracts the extra files of the backup into the target directory
+// keeping their original absolute paths relative to it. Use "/" to restore them to the original paths.
+func RestoreExtraFiles(baseBackupFolder storage.Folder, backupName string,
+	extraFiles ExtraFilesDto, crypter crypto.Crypter, targetDirectory string) error {
+	var tarContent bytes.Buffer
+	readerMaker := internal.NewStorageReaderMaker(baseBackupFolder, path.Join(backupName, extraFiles.TarName))
+	err := internal.DecryptAndDecompressTar(&tarContent, readerMaker, crypter)
+	if err != nil {
+		return errors.Wrap(err, "failed to download extra files")
+	}
+
+	tarReader := tar.NewReader(&tarContent)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read extra files tar")
+		}
+		targetPath := filepath.Join(targetDirectory, filepath.FromSlash(header.Name))
+		if !utility.IsInDirectory(targetPath, filepath.Clean(targetDirectory)) {
+			return errors.Errorf("extra file %s points outside of %s", header.Name, targetDirectory)
+		}
+		err = restoreExtraFile(tarReader, header, targetPath)
+		if err != nil {
+			return err
+		}
+		tracelog.InfoLogger.Printf("Restored extra file %s\n", targetPath)
+	}
+}
+
+func restoreExtraFile(reader io.Reader, header *tar.Header, targetPath string) error {
+	err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create directory for extra file %s", targetPath)
+	}
+	file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create extra file %s", targetPath)
+	}
+	_, err = io.Copy(file, reader)
+	if err != nil {
+		utility.LoggedClose(file, "")
+		return errors.Wrapf(err, "failed to write extra file %s", targetPath)
+	}
+	return file.Close()
+}
+
+// HandleExtraFilesRestore restores the extra files captured by WALG_BACKUP_EXTRA_FILES
+func HandleExtraFilesRestore(rootFolder storage.Folder, backup internal.Backup, targetDirectory string) {
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	pgBackup := NewBackup(baseBackupFolder, backup.Name)
+	sentinelDto, err := pgBackup.GetSentinel()
+	internal.FatalfOnError("Failed to fetch backup sentinel: %v\n", err)
+	if sentinelDto.ExtraFiles == nil {
+		tracelog.WarningLogger.Printf("Backup %s has no extra files to restore\n", backup.Name)
+		return
+	}
+	err = RestoreExtraFiles(baseBackupFolder, backup.Name, *sentinelDto.ExtraFiles,
+		internal.ConfigureCrypter(), targetDirectory)
+	internal.FatalfOnError("Failed to restore extra files: %v\n", err)
+}
@@ -0,0 +1,88 @@
+package postgres_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestGetExtraFilesSetting(t *testing.T) {
+	viper.Set(internal.BackupExtraFilesSetting, "/etc/postgresql/postgresql.conf, /etc/postgresql/pg_hba.conf")
+	defer viper.Set(internal.BackupExtraFilesSetting, "")
+	paths, err := postgres.GetExtraFilesSetting()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/etc/postgresql/postgresql.conf", "/etc/postgresql/pg_hba.conf"}, paths)
+
+	viper.Set(internal.BackupExtraFilesSetting, "pg_hba.conf")
+	_, err = postgres.GetExtraFilesSetting()
+	assert.Error(t, err)
+}
+
+func TestUploadAndRestoreExtraFiles(t *testing.T) {
+	sourceDir, err := ioutil.TempDir("", "extra_files_source")
+	require.NoError(t, err)
+	defer os.RemoveAll(sourceDir)
+	targetDir, err := ioutil.TempDir("", "extra_files_target")
+	require.NoError(t, err)
+	defer os.RemoveAll(targetDir)
+
+	confPath := filepath.Join(sourceDir, "postgresql.conf")
+	require.NoError(t, ioutil.WriteFile(confPath, []byte("shared_buffers = 128MB\n"), 0600))
+
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], baseBackupFolder)
+	backupName := "base_000000010000000000000002"
+	extraFiles, err := postgres.UploadExtraFiles(uploader, nil, backupName, []string{confPath})
+	require.NoError(t, err)
+	assert.Equal(t, []string{confPath}, extraFiles.Paths)
+
+	err = postgres.RestoreExtraFiles(baseBackupFolder, backupName, *extraFiles, nil, targetDir)
+	require.NoError(t, err)
+	content, err := ioutil.ReadFile(filepath.Join(targetDir, confPath))
+	require.NoError(t, err)
+	assert.Equal(t, "shared_buffers = 128MB\n", string(content))
+}
+
+func TestRestoreExtraFiles_OutsideOfTarget(t *testing.T) {
+	targetDir, err := ioutil.TempDir("", "extra_files_target")
+	require.NoError(t, err)
+	defer os.RemoveAll(targetDir)
+	defer os.RemoveAll(targetDir + "_sibling")
+
+	// the path of the sibling directory starts with the path of the target
+	var tarContent bytes.Buffer
+	tarWriter := tar.NewWriter(&tarContent)
+	content := []byte("host all all 0.0.0.0/0 trust\n")
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "../" + filepath.Base(targetDir) + "_sibling/pg_hba.conf",
+		Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err = tarWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	var compressed bytes.Buffer
+	compressedWriter := compression.Compressors[lz4.AlgorithmName].NewWriter(&compressed)
+	_, err = compressedWriter.Write(tarContent.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, compressedWriter.Close())
+
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	backupName := "base_000000010000000000000002"
+	extraFiles := postgres.ExtraFilesDto{TarName: "extra_files.tar.lz4", Paths: []string{"/etc/postgresql/pg_hba.conf"}}
+	require.NoError(t, baseBackupFolder.PutObject(backupName+"/"+extraFiles.TarName, &compressed))
+
+	err = postgres.RestoreExtraFiles(baseBackupFolder, backupName, extraFiles, nil, targetDir)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(targetDir+"_sibling", "pg_hba.conf"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const PreExtractHookName = "pre-extract hook"
+
+type ExtractHookError struct {
+	error
+}
+
+func newExtractHookError(hookName string, err error) ExtractHookError {
+	return ExtractHookError{errors.Wrapf(err, "%s failed", hookName)}
+}
+
+func (err ExtractHookError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RunPreExtractHook runs the backup-fetch --pre-extract-hook command, which prepares the directory
+// to extract the backup into (e.g. creates and mounts a ZFS clone or an LVM snapshot) and prints its path.
+// The last non-empty line of the stdout is returned as the extract target, the stderr is passed through.
+func RunPreExtractHook(commandLine, backupName string) (string, error) {
+	cmd := internal.NewShellCommandContext(context.Background(), commandLine)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", PostFetchHookBackupNameEnv, backupName))
+	var output bytes.Buffer
+	cmd.Stdout = &output
+
+	tracelog.InfoLogger.Printf("Running the %s for backup %s\n", PreExtractHookName, backupName)
+	if err := cmd.Run(); err != nil {
+		return "", newExtractHookError(PreExtractHookName, err)
+	}
+
+	extractTarget := ""
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			extractTarget = line
+		}
+	}
+	if extractTarget == "" {
+		return "", newExtractHookError(PreExtractHookName, errors.New("no extract target printed on stdout"))
+	}
+	info, err := os.Stat(extractTarget)
+	if err != nil {
+		return "", newExtractHookError(PreExtractHookName, errors.Wrap(err, "invalid extract target"))
+	}
+	if !info.IsDir() {
+		return "", newExtractHookError(PreExtractHookName,
+			errors.Errorf("extract target %s is not a directory", extractTarget))
+	}
+	tracelog.InfoLogger.Printf("Extracting backup %s into %s\n", backupName, extractTarget)
+	return extractTarget, nil
+}
+
+// HandlePreExtractHook resolves the backup to fetch and runs the pre-extract hook for it.
+// It returns the extract target and the selector of the resolved backup,
+// so the fetch gets the backup the hook was run for even if a newer one is uploaded meanwhile.
+func HandlePreExtractHook(folder storage.Folder, targetBackupSelector internal.BackupSelector,
+	commandLine string) (string, internal.BackupSelector) {
+	backupName, err := targetBackupSelector.Select(folder)
+	internal.FatalOnError(err)
+	extractTarget, err := RunPreExtractHook(commandLine, backupName)
+	internal.FatalOnError(err)
+	backupSelector, err := internal.NewBackupNameSelector(backupName)
+	internal.FatalOnError(err)
+	return extractTarget, backupSelector
+}
@@ -0,0 +1,34 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const extractHookBackupName = "base_000000010000000000000002"
+
+func TestRunPreExtractHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pre_extract_hook")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mountPath := filepath.Join(dir, "clone")
+
+	// the hook logs to stdout before printing the path, the last line is the extract target
+	commandLine := `mkdir "` + mountPath + `/$WALG_FETCH_BACKUP_NAME" && echo created && ` +
+		`echo "` + mountPath + `/$WALG_FETCH_BACKUP_NAME"`
+	require.NoError(t, os.Mkdir(mountPath, 0700))
+	extractTarget, err := postgres.RunPreExtractHook(commandLine, extractHookBackupName)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(mountPath, extractHookBackupName), extractTarget)
+
+	for _, commandLine := range []string{"exit 1", "true", `echo "` + filepath.Join(dir, "missing") + `"`} {
+		_, err = postgres.RunPreExtractHook(commandLine, extractHookBackupName)
+		assert.IsType(t, postgres.ExtractHookError{}, err, commandLine)
+	}
+}
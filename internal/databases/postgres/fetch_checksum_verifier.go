@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+type FileChecksumMismatchError struct {
+	error
+}
+
+func newFileChecksumMismatchError(fileName, expected, actual string) FileChecksumMismatchError {
+	return FileChecksumMismatchError{errors.Errorf(
+		"checksum of the extracted file %s is %s instead of %s recorded at backup-push, the backup is corrupted",
+		fileName, actual, expected)}
+}
+
+func (err FileChecksumMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// FetchChecksumVerifier checks the files as they are extracted against the SHA256 checksums
+// recorded in their descriptions with WALG_BACKUP_FILE_CHECKSUMS. The files without
+// the recorded checksum, e.g. the incremented ones, are counted as unverified.
+type FetchChecksumVerifier struct {
+	verified   int64
+	unverified int64
+}
+
+func NewFetchChecksumVerifier() *FetchChecksumVerifier {
+	return &FetchChecksumVerifier{}
+}
+
+// wrapReader returns the reader computing the checksum of the file content,
+// the returned hash is nil if the file has no recorded checksum
+func (verifier *FetchChecksumVerifier) wrapReader(fileReader io.Reader,
+	description internal.BackupFileDescription, haveDescription bool) (io.Reader, hash.Hash) {
+	if !haveDescription || description.SHA256 == "" {
+		atomic.AddInt64(&verifier.unverified, 1)
+		return fileReader, nil
+	}
+	fileHash := sha256.New()
+	return io.TeeReader(fileReader, fileHash), fileHash
+}
+
+// verify compares the checksum of the read file content with the recorded one
+func (verifier *FetchChecksumVerifier) verify(fileName string, description internal.BackupFileDescription,
+	fileHash hash.Hash) error {
+	checksum := hex.EncodeToString(fileHash.Sum(nil))
+	if checksum != description.SHA256 {
+		return newFileChecksumMismatchError(fileName, description.SHA256, checksum)
+	}
+	atomic.AddInt64(&verifier.verified, 1)
+	return nil
+}
+
+// Verified returns the number of files which checksums matched the recorded ones
+func (verifier *FetchChecksumVerifier) Verified() int64 {
+	return atomic.LoadInt64(&verifier.verified)
+}
+
+// Unverified returns the number of extracted files without the recorded checksum
+func (verifier *FetchChecksumVerifier) Unverified() int64 {
+	return atomic.LoadInt64(&verifier.unverified)
+}
+
+func (verifier *FetchChecksumVerifier) logSummary() {
+	tracelog.InfoLogger.Printf("Checksums of %d extracted files are verified, %d files have no recorded checksum\n",
+		verifier.Verified(), verifier.Unverified())
+	if verifier.Verified() == 0 {
+		tracelog.WarningLogger.Printf("No file was verified, was the backup made with %s?\n",
+			internal.BackupFileChecksumsSetting)
+	}
+}
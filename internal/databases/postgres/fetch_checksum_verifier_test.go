@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestFetchChecksumVerifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify_manifest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "relation data"
+	checksum := sha256.Sum256([]byte(content))
+	sentinel := BackupSentinelDto{Files: internal.BackupFileList{
+		"/base/1": {SHA256: hex.EncodeToString(checksum[:]), Size: int64(len(content))},
+		"/base/2": {SHA256: hex.EncodeToString(checksum[:]), Size: int64(len(content))},
+		"/base/3": {},
+	}}
+	verifier := NewFetchChecksumVerifier()
+	tarInterpreter := NewFileTarInterpreter(dir, sentinel, nil, false)
+	tarInterpreter.checksumVerifier = verifier
+
+	unwrap := func(name, content string) error {
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		return tarInterpreter.unwrapRegularFileOld(strings.NewReader(content), header, filepath.Join(dir, name))
+	}
+
+	assert.NoError(t, unwrap("/base/1", content))
+	assert.NoError(t, unwrap("/base/3", "no checksum"))
+
+	err = unwrap("/base/2", "corrupted data")
+	assert.IsType(t, FileChecksumMismatchError{}, err)
+	assert.Contains(t, err.Error(), "/base/2")
+	_, err = os.Stat(filepath.Join(dir, "base", "2"))
+	assert.True(t, os.IsNotExist(err), "the corrupted file must be removed")
+
+	assert.Equal(t, int64(1), verifier.Verified())
+	assert.Equal(t, int64(1), verifier.Unverified())
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const FetchProgressMarkerFilename = ".walg_fetch_progress.json"
+
+type FetchProgressMismatchError struct {
+	error
+}
+
+func newFetchProgressMismatchError(markerPath, reason string) FetchProgressMismatchError {
+	return FetchProgressMismatchError{errors.Errorf(
+		"Fetch progress marker '%s' can not be used to resume: %s. "+
+			"Please clean the destination directory and restart backup-fetch without --resume", markerPath, reason)}
+}
+
+func (err FetchProgressMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// FetchProgress describes the progress of the backup-fetch.
+// It is stored in the destination directory, so the interrupted
+// backup-fetch can be resumed without extracting the same tar partitions again.
+type FetchProgress struct {
+	BackupName string `json:"BackupName"`
+	// for each backup in the delta chain, the size of every tar partition
+	Partitions map[string]map[string]int64 `json:"Partitions"`
+	// for each backup in the delta chain, completely extracted tar partitions
+	Extracted map[string]map[string]bool `json:"Extracted"`
+
+	markerPath string
+	resumed    bool
+	mutex      sync.Mutex
+}
+
+// LoadFetchProgress reads the progress marker from the destination directory.
+// If it is missing, an empty progress for the specified backup is returned.
+func LoadFetchProgress(dbDataDirectory, backupName string) (*FetchProgress, error) {
+	progress := &FetchProgress{
+		BackupName: backupName,
+		Partitions: make(map[string]map[string]int64),
+		Extracted:  make(map[string]map[string]bool),
+		markerPath: filepath.Join(dbDataDirectory, FetchProgressMarkerFilename),
+	}
+	data, err := ioutil.ReadFile(progress.markerPath)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read fetch progress marker '%s'", progress.markerPath)
+	}
+
+	stored := FetchProgress{}
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, newFetchProgressMismatchError(progress.markerPath, err.Error())
+	}
+	if stored.BackupName != backupName {
+		return nil, newFetchProgressMismatchError(progress.markerPath,
+			fmt.Sprintf("it was created for backup %s", stored.BackupName))
+	}
+	if stored.Partitions != nil {
+		progress.Partitions = stored.Partitions
+	}
+	if stored.Extracted != nil {
+		progress.Extracted = stored.Extracted
+	}
+	progress.resumed = true
+	tracelog.InfoLogger.Printf("Resuming fetch of backup %s using progress marker %s\n",
+		backupName, progress.markerPath)
+	return progress, nil
+}
+
+// IsResumed reports whether the progress was loaded from the existing marker.
+func (progress *FetchProgress) IsResumed() bool {
+	return progress != nil && progress.resumed
+}
+
+// filterExtracted checks the backup partition set against the marker,
+// records it and returns only those partitions that still should be extracted.
+func (progress *FetchProgress) filterExtracted(backupName string, partitionSizes map[string]int64,
+	tarsToExtract []internal.ReaderMaker) ([]internal.ReaderMaker, error) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	if recorded, ok := progress.Partitions[backupName]; ok && !reflect.DeepEqual(recorded, partitionSizes) {
+		return nil, newFetchProgressMismatchError(progress.markerPath,
+			fmt.Sprintf("tar partitions of backup %s have changed", backupName))
+	}
+	progress.Partitions[backupName] = partitionSizes
+	if err := progress.save(); err != nil {
+		return nil, err
+	}
+
+	remaining := make([]internal.ReaderMaker, 0, len(tarsToExtract))
+	for _, tarToExtract := range tarsToExtract {
+		if progress.Extracted[backupName][tarToExtract.Path()] {
+			tracelog.InfoLogger.Printf("Skipping already extracted partition %s of backup %s\n",
+				tarToExtract.Path(), backupName)
+			continue
+		}
+		remaining = append(remaining, tarToExtract)
+	}
+	return remaining, nil
+}
+
+func (progress *FetchProgress) isExtracted(backupName, tarName string) bool {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	return progress.Extracted[backupName][tarName]
+}
+
+func (progress *FetchProgress) markExtracted(backupName, tarName string) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+	if progress.Extracted[backupName] == nil {
+		progress.Extracted[backupName] = make(map[string]bool)
+	}
+	progress.Extracted[backupName][tarName] = true
+	err := progress.save()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to update fetch progress marker: %v\n", err)
+	}
+}
+
+// save atomically rewrites the marker file, should be called under the mutex
+func (progress *FetchProgress) save() error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal fetch progress")
+	}
+	tmpPath := progress.markerPath + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write fetch progress marker '%s'", tmpPath)
+	}
+	return os.Rename(tmpPath, progress.markerPath)
+}
+
+// Remove deletes the marker after the successful fetch.
+func (progress *FetchProgress) Remove() error {
+	err := os.Remove(progress.markerPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
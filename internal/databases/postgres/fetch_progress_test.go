@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestFetchProgress_SkipsExtractedPartitions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fetch_progress")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tars := []internal.ReaderMaker{
+		internal.NewStorageReaderMaker(nil, "part_1.tar.lz4"),
+		internal.NewStorageReaderMaker(nil, "part_2.tar.lz4"),
+	}
+	sizes := map[string]int64{"part_1.tar.lz4": 10, "part_2.tar.lz4": 20}
+
+	progress, err := LoadFetchProgress(dir, "base_000")
+	require.NoError(t, err)
+	assert.False(t, progress.IsResumed())
+	remaining, err := progress.filterExtracted("base_000", sizes, tars)
+	require.NoError(t, err)
+	assert.Len(t, remaining, 2)
+	progress.markExtracted("base_000", "part_1.tar.lz4")
+
+	resumed, err := LoadFetchProgress(dir, "base_000")
+	require.NoError(t, err)
+	assert.True(t, resumed.IsResumed())
+	remaining, err = resumed.filterExtracted("base_000", sizes, tars)
+	require.NoError(t, err)
+	assert.Equal(t, []internal.ReaderMaker{tars[1]}, remaining)
+
+	_, err = resumed.filterExtracted("base_000", map[string]int64{"part_1.tar.lz4": 10}, tars)
+	assert.IsType(t, FetchProgressMismatchError{}, err)
+
+	_, err = LoadFetchProgress(dir, "base_001")
+	assert.IsType(t, FetchProgressMismatchError{}, err)
+
+	require.NoError(t, resumed.Remove())
+	_, err = os.Stat(resumed.markerPath)
+	assert.True(t, os.IsNotExist(err))
+}
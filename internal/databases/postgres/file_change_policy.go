@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// FileChangePolicy defines how backup-push handles the files
+// which shrink or grow between the stat and the end of the read
+type FileChangePolicy string
+
+const (
+	// FileChangePolicyPad pads the shrunk files with zeros up to the size written to the tar header
+	// and does not read the data appended to the grown files. The changes are recorded in the sentinel.
+	FileChangePolicyPad FileChangePolicy = "pad"
+	// FileChangePolicyWarn does the same as FileChangePolicyPad and logs a warning for each change
+	FileChangePolicyWarn FileChangePolicy = "warn"
+	// FileChangePolicyFail fails the backup on the first changed file
+	FileChangePolicyFail FileChangePolicy = "fail"
+
+	FileShrunkDuringBackup = "shrunk"
+	FileGrownDuringBackup  = "grown"
+)
+
+type UnknownFileChangePolicyError struct {
+	error
+}
+
+func newUnknownFileChangePolicyError(policy string) UnknownFileChangePolicyError {
+	return UnknownFileChangePolicyError{errors.Errorf("unknown file change policy '%s', expected one of: %s, %s, %s",
+		policy, FileChangePolicyPad, FileChangePolicyWarn, FileChangePolicyFail)}
+}
+
+func (err UnknownFileChangePolicyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type FileChangedDuringBackupError struct {
+	error
+}
+
+func newFileChangedDuringBackupError(path, change string) FileChangedDuringBackupError {
+	return FileChangedDuringBackupError{errors.Errorf("file '%s' has %s during the backup", path, change)}
+}
+
+func (err FileChangedDuringBackupError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ParseFileChangePolicy parses the WALG_BACKUP_FILE_CHANGE_POLICY value, empty value means the pad policy
+func ParseFileChangePolicy(policy string) (FileChangePolicy, error) {
+	switch FileChangePolicy(policy) {
+	case "", FileChangePolicyPad:
+		return FileChangePolicyPad, nil
+	case FileChangePolicyWarn, FileChangePolicyFail:
+		return FileChangePolicy(policy), nil
+	default:
+		return "", newUnknownFileChangePolicyError(policy)
+	}
+}
+
+// handleChange is called when the file is found changed, the error fails the file packing
+func (policy FileChangePolicy) handleChange(path, change string) error {
+	switch policy {
+	case FileChangePolicyFail:
+		return newFileChangedDuringBackupError(path, change)
+	case FileChangePolicyWarn:
+		tracelog.WarningLogger.Printf("File '%s' has %s during the backup, packing its size at the start of the read\n",
+			path, change)
+	}
+	return nil
+}
+
+// fileChangeDetectingReader reads exactly size bytes of the file: the shrunk file is padded with zeros,
+// the data appended to the grown file is not read. Both changes are reported to onChange.
+type fileChangeDetectingReader struct {
+	file      io.Reader
+	remaining int64
+	padding   bool
+	onChange  func(change string) error
+}
+
+func newFileChangeDetectingReader(file io.Reader, size int64,
+	onChange func(change string) error) *fileChangeDetectingReader {
+	return &fileChangeDetectingReader{file: file, remaining: size, onChange: onChange}
+}
+
+func (reader *fileChangeDetectingReader) Read(p []byte) (int, error) {
+	if reader.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > reader.remaining {
+		p = p[:reader.remaining]
+	}
+	if reader.padding {
+		for i := range p {
+			p[i] = 0
+		}
+		reader.remaining -= int64(len(p))
+		return len(p), nil
+	}
+
+	n, err := reader.file.Read(p)
+	reader.remaining -= int64(n)
+	if err == io.EOF {
+		if reader.remaining > 0 {
+			reader.padding = true
+			return n, reader.onChange(FileShrunkDuringBackup)
+		}
+		return n, nil
+	}
+	if err != nil {
+		return n, err
+	}
+	if reader.remaining == 0 {
+		// probe the file for the data appended since the stat
+		probe := make([]byte, 1)
+		probed, _ := io.ReadFull(reader.file, probe)
+		if probed > 0 {
+			return n, reader.onChange(FileGrownDuringBackup)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestParseFileChangePolicy(t *testing.T) {
+	for value, expected := range map[string]FileChangePolicy{
+		"":     FileChangePolicyPad,
+		"pad":  FileChangePolicyPad,
+		"warn": FileChangePolicyWarn,
+		"fail": FileChangePolicyFail,
+	} {
+		policy, err := ParseFileChangePolicy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+	_, err := ParseFileChangePolicy("ignore")
+	assert.IsType(t, UnknownFileChangePolicyError{}, err)
+}
+
+func TestFileChangeDetectingReader(t *testing.T) {
+	content := []byte("0123456789")
+	cases := []struct {
+		size           int64
+		expected       []byte
+		expectedChange string
+	}{
+		{size: 10, expected: content},
+		{size: 14, expected: append(append([]byte{}, content...), 0, 0, 0, 0), expectedChange: FileShrunkDuringBackup},
+		{size: 6, expected: content[:6], expectedChange: FileGrownDuringBackup},
+	}
+	for _, testCase := range cases {
+		change := ""
+		reader := newFileChangeDetectingReader(bytes.NewReader(content), testCase.size, func(c string) error {
+			change = c
+			return nil
+		})
+		data, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, data)
+		assert.Equal(t, testCase.expectedChange, change)
+	}
+}
+
+// packChangedFile stats the file, changes its size with resize and packs it the way backup-push does
+func packChangedFile(t *testing.T, policy FileChangePolicy, resize func(path string)) (internal.BackupFileDescription, error) {
+	dir, err := ioutil.TempDir("", "file_change")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "1000")
+	require.NoError(t, ioutil.WriteFile(path, bytes.Repeat([]byte{1}, 8192), 0644))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	header, err := tar.FileInfoHeader(info, info.Name())
+	require.NoError(t, err)
+	header.Name = "/base/1/1000"
+
+	resize(path)
+
+	files := &RegularBundleFiles{}
+	packer := newTarBallFilePacker(nil, nil, files, NewTarBallFilePackerOptions(false, false, false, policy))
+	tarBall := internal.NewNopTarBallMaker().Make(false)
+	err = packer.PackFileIntoTar(NewComposeFileInfo(path, info, false, false, header), tarBall)
+	value, _ := files.Load(header.Name)
+	description, _ := value.(internal.BackupFileDescription)
+	return description, err
+}
+
+func TestPackFileIntoTar_FileChangedDuringBackup(t *testing.T) {
+	truncate := func(path string) { require.NoError(t, os.Truncate(path, 4096)) }
+	grow := func(path string) { require.NoError(t, os.Truncate(path, 16384)) }
+
+	description, err := packChangedFile(t, FileChangePolicyPad, truncate)
+	require.NoError(t, err)
+	assert.Equal(t, FileShrunkDuringBackup, description.ChangedDuringBackup)
+
+	description, err = packChangedFile(t, FileChangePolicyWarn, grow)
+	require.NoError(t, err)
+	assert.Equal(t, FileGrownDuringBackup, description.ChangedDuringBackup)
+
+	description, err = packChangedFile(t, FileChangePolicyPad, func(string) {})
+	require.NoError(t, err)
+	assert.Empty(t, description.ChangedDuringBackup)
+
+	_, err = packChangedFile(t, FileChangePolicyFail, truncate)
+	assert.Error(t, err)
+	_, err = packChangedFile(t, FileChangePolicyFail, grow)
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 )
 
 type FileUnwrapperType int
@@ -59,6 +60,9 @@ func NewSkippedResult() *FileUnwrapResult {
 type BackupFileOptions struct {
 	isIncremented bool
 	isPageFile    bool
+	// pageSize is the page size of the backed up cluster
+	pageSize        int64
+	truncatedPolicy TruncatedPolicy
 }
 
 type IBackupFileUnwrapper interface {
@@ -91,7 +95,7 @@ func (u *BackupFileUnwrapper) writeLocalFile(fileReader io.Reader, header *tar.H
 		}
 		err1 = os.Remove(localFile.Name())
 		if err1 != nil {
-			tracelog.ErrorLogger.Fatalf("Interpret: failed to remove localFile '%s' because of error: %v",
+			internal.Fatalf("Interpret: failed to remove localFile '%s' because of error: %v",
 				localFile.Name(), err1)
 		}
 		return errors.Wrap(err, "Interpret: copy failed")
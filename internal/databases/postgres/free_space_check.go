@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/fsutil"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type InsufficientFreeSpaceError struct {
+	error
+}
+
+func newInsufficientFreeSpaceError(dir string, required, available uint64) InsufficientFreeSpaceError {
+	return InsufficientFreeSpaceError{errors.Errorf(
+		"Not enough free space to restore the backup into %s: %d bytes are required (including %s), "+
+			"but only %d bytes are available. Use --force to restore anyway.",
+		dir, required, internal.RestoreSpaceMarginSetting, available)}
+}
+
+func (err InsufficientFreeSpaceError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetRestoreRequiredSpace returns the upper bound of the restored backup size: the uncompressed size
+// of the backup plus the sizes of all the backups of its delta chain. known is false
+// if any of the backups was made before the size was recorded to the sentinel.
+func GetRestoreRequiredSpace(backup Backup) (size uint64, known bool, err error) {
+	for {
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return 0, false, err
+		}
+		if sentinelDto.UncompressedSize <= 0 {
+			return 0, false, nil
+		}
+		size += uint64(sentinelDto.UncompressedSize)
+		if !sentinelDto.IsIncremental() {
+			return size, true, nil
+		}
+		backup = NewBackup(backup.Folder, *sentinelDto.IncrementFrom)
+	}
+}
+
+// CheckFreeSpace fails if the available space is less than the required one increased by marginPercent
+func CheckFreeSpace(dir string, required, available uint64, marginPercent int) error {
+	requiredWithMargin := required + required*uint64(marginPercent)/100
+	if available < requiredWithMargin {
+		return newInsufficientFreeSpaceError(dir, requiredWithMargin, available)
+	}
+	return nil
+}
+
+// HandleFreeSpaceCheck verifies that the filesystem of the target directory
+// has enough free space to restore the backup
+func HandleFreeSpaceCheck(rootFolder storage.Folder, backup internal.Backup, dbDataDirectory string) {
+	pgBackup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name)
+	internal.FatalOnError(checkRestoreFreeSpace(pgBackup, dbDataDirectory))
+}
+
+// checkRestoreFreeSpace fails with InsufficientFreeSpaceError if the filesystem of the directory
+// has not enough free space to restore the backup. The check is skipped if the backup size is unknown.
+func checkRestoreFreeSpace(backup Backup, dbDataDirectory string) error {
+	marginPercent := viper.GetInt(internal.RestoreSpaceMarginSetting)
+	if marginPercent < 0 {
+		return errors.Errorf("%s must not be negative, got %d", internal.RestoreSpaceMarginSetting, marginPercent)
+	}
+
+	required, known, err := GetRestoreRequiredSpace(backup)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch backup sentinel")
+	}
+	if !known {
+		tracelog.WarningLogger.Println("The backup size is not recorded in the sentinel, skipping the free space check")
+		return nil
+	}
+
+	dir := getExistingParentDirectory(utility.ResolveSymlink(dbDataDirectory))
+	available, err := fsutil.GetAvailableSpace(dir)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Skipping the free space check: %v\n", err)
+		return nil
+	}
+	tracelog.InfoLogger.Printf("Backup requires up to %d bytes, %d bytes are available in %s\n",
+		required, available, dir)
+	return CheckFreeSpace(dir, required, available, marginPercent)
+}
+
+// the target directory is created during the fetch, so the space is checked on its nearest existing parent
+func getExistingParentDirectory(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
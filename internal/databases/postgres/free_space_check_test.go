@@ -0,0 +1,49 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestGetRestoreRequiredSpace_SumsDeltaChain(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	fullName := "base_000000010000000000000002"
+	deltaName := "base_000000010000000000000004_D_000000010000000000000002"
+	lsn := uint64(0x2000028)
+	incrementCount := 1
+
+	fullSentinel := postgres.BackupSentinelDto{UncompressedSize: 1000}
+	deltaSentinel := postgres.BackupSentinelDto{UncompressedSize: 100, IncrementFrom: &fullName,
+		IncrementFullName: &fullName, IncrementFromLSN: &lsn, IncrementCount: &incrementCount}
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder), &fullSentinel, fullName))
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder), &deltaSentinel, deltaName))
+
+	size, known, err := postgres.GetRestoreRequiredSpace(postgres.NewBackup(baseBackupFolder, deltaName))
+	require.NoError(t, err)
+	assert.True(t, known)
+	assert.Equal(t, uint64(1100), size)
+}
+
+func TestGetRestoreRequiredSpace_UnknownSize(t *testing.T) {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	backupName := "base_000000010000000000000002"
+	require.NoError(t, internal.UploadSentinel(internal.NewUploader(nil, baseBackupFolder),
+		&postgres.BackupSentinelDto{}, backupName))
+
+	_, known, err := postgres.GetRestoreRequiredSpace(postgres.NewBackup(baseBackupFolder, backupName))
+	require.NoError(t, err)
+	assert.False(t, known)
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	assert.NoError(t, postgres.CheckFreeSpace("/restore", 1000, 1100, 10))
+	err := postgres.CheckFreeSpace("/restore", 1000, 1099, 10)
+	assert.IsType(t, postgres.InsufficientFreeSpaceError{}, err)
+	assert.NoError(t, postgres.CheckFreeSpace("/restore", 1000, 1000, 0))
+}
@@ -0,0 +1,219 @@
+package postgres
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const backupLabelStartWalLocation = "START WAL LOCATION:"
+
+// directories holding the relation files, the ones missing in the target backup are removed
+var incrementalOntoCleanupDirs = []string{"base", "global"}
+
+type IncrementalOntoMismatchError struct {
+	error
+}
+
+func newIncrementalOntoMismatchError(dbDataDirectory, backupName, reason string) IncrementalOntoMismatchError {
+	return IncrementalOntoMismatchError{errors.Errorf(
+		"Directory %s can not be updated to backup %s incrementally: %s", dbDataDirectory, backupName, reason)}
+}
+
+func (err IncrementalOntoMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RestoredDirectoryState describes the backup restored in the data directory
+// which was not started since then
+type RestoredDirectoryState struct {
+	StartLSN         uint64
+	SystemIdentifier uint64
+}
+
+// ReadRestoredDirectoryState reads the start LSN from the backup_label
+// and the system identifier from the pg_control of the data directory
+func ReadRestoredDirectoryState(dbDataDirectory string) (RestoredDirectoryState, error) {
+	startLSN, err := readBackupLabelStartLSN(filepath.Join(dbDataDirectory, BackupLabelFilename))
+	if err != nil {
+		return RestoredDirectoryState{}, err
+	}
+	systemIdentifier, err := readPgControlSystemIdentifier(filepath.Join(dbDataDirectory, PgControlPath))
+	if err != nil {
+		return RestoredDirectoryState{}, err
+	}
+	return RestoredDirectoryState{StartLSN: startLSN, SystemIdentifier: systemIdentifier}, nil
+}
+
+func readBackupLabelStartLSN(backupLabelPath string) (uint64, error) {
+	file, err := os.Open(backupLabelPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, errors.Errorf("%s is not found, the cluster was started after the restore "+
+				"or the directory was not restored by backup-fetch", backupLabelPath)
+		}
+		return 0, err
+	}
+	defer utility.LoggedClose(file, "")
+	return parseBackupLabelStartLSN(file, backupLabelPath)
+}
+
+func parseBackupLabelStartLSN(reader io.Reader, source string) (uint64, error) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, backupLabelStartWalLocation) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, backupLabelStartWalLocation))
+		if len(fields) == 0 {
+			break
+		}
+		lsn, err := pglogrepl.ParseLSN(fields[0])
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse start LSN in %s", source)
+		}
+		return uint64(lsn), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.Errorf("start WAL location is not found in %s", source)
+}
+
+// system identifier is the first field of the pg_control
+func readPgControlSystemIdentifier(pgControlPath string) (uint64, error) {
+	file, err := os.Open(pgControlPath)
+	if err != nil {
+		return 0, err
+	}
+	defer utility.LoggedClose(file, "")
+	return parsePgControlSystemIdentifier(file, pgControlPath)
+}
+
+func parsePgControlSystemIdentifier(reader io.Reader, source string) (uint64, error) {
+	systemIdentifier := make([]byte, 8)
+	_, err := io.ReadFull(reader, systemIdentifier)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read system identifier from %s", source)
+	}
+	return binary.LittleEndian.Uint64(systemIdentifier), nil
+}
+
+// FindRestoredAncestor walks the delta chain of the backup down to the backup
+// restored in the directory and returns its name
+func FindRestoredAncestor(baseBackupFolder storage.Folder, backupName, dbDataDirectory string,
+	state RestoredDirectoryState) (string, error) {
+	for name := backupName; ; {
+		backup := NewBackup(baseBackupFolder, name)
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return "", err
+		}
+		if sentinelDto.SystemIdentifier != nil && *sentinelDto.SystemIdentifier != state.SystemIdentifier {
+			return "", newIncrementalOntoMismatchError(dbDataDirectory, backupName,
+				fmt.Sprintf("system identifier %d differs from %d of backup %s",
+					state.SystemIdentifier, *sentinelDto.SystemIdentifier, name))
+		}
+		if sentinelDto.BackupStartLSN != nil && *sentinelDto.BackupStartLSN == state.StartLSN {
+			return name, nil
+		}
+		if !sentinelDto.IsIncremental() {
+			return "", newIncrementalOntoMismatchError(dbDataDirectory, backupName,
+				fmt.Sprintf("no backup in its delta chain starts at LSN %s", pglogrepl.LSN(state.StartLSN)))
+		}
+		name = *sentinelDto.IncrementFrom
+	}
+}
+
+// GetPgFetcherIncrementalOnto returns the fetcher which applies the deltas onto
+// the data directory holding the restored ancestor of the backup
+func GetPgFetcherIncrementalOnto(dbDataDirectory, restoreSpecPath string) func(rootFolder storage.Folder,
+	backup internal.Backup) {
+	return func(rootFolder storage.Folder, backup internal.Backup) {
+		dbDataDirectory = utility.ResolveSymlink(dbDataDirectory)
+		state, err := ReadRestoredDirectoryState(dbDataDirectory)
+		internal.FatalfOnError("Failed to check the restored backup: %v\n", err)
+
+		baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+		ancestorName, err := FindRestoredAncestor(baseBackupFolder, backup.Name, dbDataDirectory, state)
+		internal.FatalOnError(err)
+		if ancestorName == backup.Name {
+			tracelog.InfoLogger.Printf("Backup %s is already restored in %s\n", backup.Name, dbDataDirectory)
+			return
+		}
+		tracelog.InfoLogger.Printf("Applying deltas from %s to %s onto %s\n", ancestorName, backup.Name, dbDataDirectory)
+
+		pgBackup := ToPgBackup(backup)
+		filesToUnwrap, err := pgBackup.GetFilesToUnwrap("")
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+
+		var spec *TablespaceSpec
+		if restoreSpecPath != "" {
+			spec = &TablespaceSpec{}
+			err := readRestoreSpec(restoreSpecPath, spec)
+			internal.FatalfOnError(fmt.Sprintf("Invalid restore specification path %s\n", restoreSpecPath), err)
+		}
+		err = checkDeltaChainPageSize(baseBackupFolder, backup.Name)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+		sentinelDto, err := pgBackup.GetSentinel()
+		internal.FatalOnError(err)
+		err = checkTargetPageSize(dbDataDirectory, backup.Name, sentinelDto)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+		err = deltaFetchRecursionOld(backup.Name, rootFolder, dbDataDirectory, spec, filesToUnwrap, nil, nil, ancestorName, nil)
+		internal.FatalfOnError("Failed to fetch backup: %v\n", err)
+
+		err = removeFilesMissingInBackup(dbDataDirectory, sentinelDto)
+		internal.FatalfOnError("Failed to remove the files dropped since the restored backup: %v\n", err)
+	}
+}
+
+// removeFilesMissingInBackup removes the relation files left from the restored ancestor
+// which were dropped before the backup was taken
+func removeFilesMissingInBackup(dbDataDirectory string, sentinelDto BackupSentinelDto) error {
+	if sentinelDto.Files == nil {
+		return nil
+	}
+	for _, dir := range incrementalOntoCleanupDirs {
+		root := filepath.Join(dbDataDirectory, dir)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return nil
+				}
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			relativePath, err := filepath.Rel(dbDataDirectory, path)
+			if err != nil {
+				return err
+			}
+			fileName := "/" + filepath.ToSlash(relativePath)
+			if _, ok := sentinelDto.Files[fileName]; ok {
+				return nil
+			}
+			if _, ok := UtilityFilePaths[fileName]; ok {
+				return nil
+			}
+			tracelog.DebugLogger.Printf("Removing %s missing in the backup\n", path)
+			return os.Remove(path)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func putTestSentinel(t *testing.T, folder storage.Folder, name string, sentinelDto BackupSentinelDto) {
+	data, err := json.Marshal(sentinelDto)
+	require.NoError(t, err)
+	require.NoError(t, folder.PutObject(name+utility.SentinelSuffix, bytes.NewReader(data)))
+}
+
+func makeRestoredDirectory(t *testing.T, backupLabel string, systemIdentifier uint64) string {
+	dir, err := ioutil.TempDir("", "incremental_onto")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "global"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, BackupLabelFilename), []byte(backupLabel), 0644))
+	pgControl := make([]byte, 16)
+	binary.LittleEndian.PutUint64(pgControl, systemIdentifier)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, PgControlPath), pgControl, 0644))
+	return dir
+}
+
+func TestFindRestoredAncestor(t *testing.T) {
+	dir := makeRestoredDirectory(t, "START WAL LOCATION: 0/2000028 (file 000000010000000000000002)\n"+
+		"CHECKPOINT LOCATION: 0/2000060\n", 42)
+	defer os.RemoveAll(dir)
+
+	state, err := ReadRestoredDirectoryState(dir)
+	require.NoError(t, err)
+	assert.Equal(t, RestoredDirectoryState{StartLSN: 0x2000028, SystemIdentifier: 42}, state)
+
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	fullLSN, deltaLSN, systemIdentifier := uint64(0x2000028), uint64(0x4000028), uint64(42)
+	fullName := "base_000000010000000000000002"
+	putTestSentinel(t, folder, fullName, BackupSentinelDto{BackupStartLSN: &fullLSN, SystemIdentifier: &systemIdentifier})
+	incrementCount := 1
+	putTestSentinel(t, folder, "base_000000010000000000000004_D_000000010000000000000002", BackupSentinelDto{
+		BackupStartLSN: &deltaLSN, IncrementFromLSN: &fullLSN, IncrementFrom: &fullName, IncrementFullName: &fullName,
+		IncrementCount: &incrementCount, SystemIdentifier: &systemIdentifier})
+
+	ancestor, err := FindRestoredAncestor(folder, "base_000000010000000000000004_D_000000010000000000000002", dir, state)
+	require.NoError(t, err)
+	assert.Equal(t, fullName, ancestor)
+
+	state.StartLSN = 0x3000028
+	_, err = FindRestoredAncestor(folder, "base_000000010000000000000004_D_000000010000000000000002", dir, state)
+	assert.IsType(t, IncrementalOntoMismatchError{}, err)
+
+	state = RestoredDirectoryState{StartLSN: fullLSN, SystemIdentifier: 7}
+	_, err = FindRestoredAncestor(folder, "base_000000010000000000000004_D_000000010000000000000002", dir, state)
+	assert.IsType(t, IncrementalOntoMismatchError{}, err)
+}
+
+func TestReadRestoredDirectoryState_NoBackupLabel(t *testing.T) {
+	dir := makeRestoredDirectory(t, "", 42)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Remove(filepath.Join(dir, BackupLabelFilename)))
+
+	_, err := ReadRestoredDirectoryState(dir)
+	assert.Error(t, err)
+}
+
+func TestRemoveFilesMissingInBackup(t *testing.T) {
+	dir := makeRestoredDirectory(t, "", 42)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "base", "1"), 0755))
+	for _, name := range []string{"base/1/1000", "base/1/1001", "global/1262"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644))
+	}
+
+	err := removeFilesMissingInBackup(dir, BackupSentinelDto{Files: internal.BackupFileList{
+		"/base/1/1000": {},
+		"/global/1262": {},
+	}})
+	require.NoError(t, err)
+
+	for _, name := range []string{"base/1/1000", "global/1262", "global/pg_control"} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	_, err = os.Stat(filepath.Join(dir, "base/1/1001"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -11,9 +11,22 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
+const (
+	// IncrementFileVersion is the version of the increment format written by wal-g
+	IncrementFileVersion byte = '2'
+	// increments of the version "1" have no footer
+	incrementFileVersionWithoutFooter byte = '1'
+	// footer designation with format version + uint32 count of the written blocks
+	incrementFileFooterSize = sizeofInt32 + sizeofInt32
+)
+
 // IncrementFileHeader contains "wi" at the head which stands for "wal-g increment"
-// format version "1", signature magic number
-var IncrementFileHeader = []byte{'w', 'i', '1', SignatureMagicNumber}
+// format version, signature magic number
+var IncrementFileHeader = []byte{'w', 'i', IncrementFileVersion, SignatureMagicNumber}
+
+// IncrementFileFooter contains "we" at the head which stands for "wal-g increment end"
+// format version, signature magic number. It is followed by the count of the written blocks
+var IncrementFileFooter = []byte{'w', 'e', IncrementFileVersion, SignatureMagicNumber}
 
 // IncrementalPageReader constructs difference map during initialization and than re-read file
 // Diff map may consist of 1Gb/PostgresBlockSize elements == 512Kb
@@ -23,6 +36,9 @@ type IncrementalPageReader struct {
 	Lsn       uint64
 	Next      []byte
 	Blocks    []uint32
+
+	writtenBlockCount uint32
+	footerPending     bool
 }
 
 func (pageReader *IncrementalPageReader) Read(p []byte) (n int, err error) {
@@ -46,7 +62,12 @@ func (pageReader *IncrementalPageReader) Read(p []byte) (n int, err error) {
 
 func (pageReader *IncrementalPageReader) DrainMoreData() (succeed bool, err error) {
 	if len(pageReader.Blocks) == 0 {
-		return false, nil
+		if !pageReader.footerPending {
+			return false, nil
+		}
+		pageReader.footerPending = false
+		pageReader.Next = pageReader.makeFooter()
+		return true, nil
 	}
 	err = pageReader.AdvanceFileReader()
 	if err != nil {
@@ -68,10 +89,19 @@ func (pageReader *IncrementalPageReader) AdvanceFileReader() error {
 	_, err = io.ReadFull(pageReader.PagedFile, pageBytes)
 	if err == nil {
 		pageReader.Next = pageBytes
+		pageReader.writtenBlockCount++
 	}
 	return err
 }
 
+// makeFooter records the count of the blocks actually written,
+// so the reader can tell the complete increment from the truncated one
+func (pageReader *IncrementalPageReader) makeFooter() []byte {
+	footer := make([]byte, 0, incrementFileFooterSize)
+	footer = append(footer, IncrementFileFooter...)
+	return append(footer, utility.ToBytes(pageReader.writtenBlockCount)...)
+}
+
 // Close IncrementalPageReader
 func (pageReader *IncrementalPageReader) Close() error {
 	return pageReader.PagedFile.Close()
@@ -97,8 +127,9 @@ func (pageReader *IncrementalPageReader) initialize(deltaBitmap *roaring.Bitmap)
 
 	pageReader.WriteDiffMapToHeader(&headerBuffer)
 	pageReader.Next = headerBuffer.Bytes()
+	pageReader.footerPending = true
 	pageDataSize := int64(len(pageReader.Blocks)) * DatabasePageSize
-	size = int64(headerBuffer.Len()) + pageDataSize
+	size = int64(headerBuffer.Len()) + pageDataSize + incrementFileFooterSize
 	return
 }
 
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"io"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/wal-g/wal-g/internal"
 
@@ -43,12 +45,17 @@ type IntegrityCheckRunner struct {
 	delayedSegmentRangeSize   int
 	walFolderFilenames        []string
 	timelineSwitchMap         map[WalSegmentNo]*TimelineHistoryRecord
+	// if set, the existence of each found segment is confirmed by a separate request to the storage
+	// and the segments with the checksum in the WAL metadata are verified against it
+	checkContent bool
+	walFolder    storage.Folder
 }
 
 func NewIntegrityCheckRunner(
 	rootFolder storage.Folder,
 	walFolderFilenames []string,
 	currentWalSegment WalSegmentDescription,
+	checkContent bool,
 ) (IntegrityCheckRunner, error) {
 	walFolder := rootFolder.GetSubFolder(utility.WalPath)
 
@@ -78,6 +85,8 @@ func NewIntegrityCheckRunner(
 		delayedSegmentRangeSize:   viper.GetInt(internal.MaxDelayedSegmentsCount),
 		walFolderFilenames:        walFolderFilenames,
 		timelineSwitchMap:         timelineSwitchMap,
+		checkContent:              checkContent,
+		walFolder:                 walFolder,
 	}, nil
 }
 
@@ -94,6 +103,18 @@ func (check IntegrityCheckRunner) Run() (WalVerifyCheckResult, error) {
 		return WalVerifyCheckResult{}, err
 	}
 
+	if check.checkContent {
+		concurrency, err := internal.GetMaxDownloadConcurrency()
+		if err != nil {
+			return WalVerifyCheckResult{}, errors.Wrap(err, "Failed to resolve MaxDownloadConcurrency")
+		}
+		err = checkFoundSegmentsContent(check.walFolder, check.walFolderFilenames,
+			segmentScanner.ScannedSegments, concurrency)
+		if err != nil {
+			return WalVerifyCheckResult{}, err
+		}
+	}
+
 	integrityScanSegmentSequences := collapseSegmentsByStatusAndTimeline(segmentScanner.ScannedSegments)
 
 	return newWalIntegrityCheckResult(integrityScanSegmentSequences), nil
@@ -103,6 +124,117 @@ func (check IntegrityCheckRunner) Type() WalVerifyCheckType {
 	return WalVerifyIntegrityCheck
 }
 
+// checkFoundSegmentsContent confirms that each segment found in the folder listing exists in storage
+// using a pool of concurrent checks. Segments which do not exist are marked as lost. If the WAL metadata
+// of the segment holds its checksum, the segment is downloaded and marked as corrupted on mismatch.
+func checkFoundSegmentsContent(walFolder storage.Folder, walFolderFilenames []string,
+	scannedSegments []ScannedSegmentDescription, concurrency int) error {
+	filenameBySegment := make(map[WalSegmentDescription]string, len(walFolderFilenames))
+	listedFilenames := make(map[string]bool, len(walFolderFilenames))
+	for _, filename := range walFolderFilenames {
+		listedFilenames[filename] = true
+		if utility.GetFileExtension(filename) == "json" {
+			// WAL metadata file
+			continue
+		}
+		segment, err := NewWalSegmentDescription(utility.TrimFileExtension(filename))
+		if err == nil {
+			filenameBySegment[segment] = filename
+		}
+	}
+
+	startTime := time.Now()
+	indexes := make(chan int)
+	errs := make([]error, len(scannedSegments))
+	statuses := make([]ScannedSegmentStatus, len(scannedSegments))
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				filename := filenameBySegment[scannedSegments[index].WalSegmentDescription]
+				statuses[index], errs[index] = checkFoundSegment(walFolder, filename, listedFilenames)
+			}
+		}()
+	}
+	checkedCount := 0
+	for i, segment := range scannedSegments {
+		if segment.status == Found {
+			checkedCount++
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, segment := range scannedSegments {
+		if segment.status != Found {
+			continue
+		}
+		if errs[i] != nil {
+			return errors.Wrapf(errs[i], "Failed to check WAL segment %s",
+				segment.Number.getFilename(segment.Timeline))
+		}
+		scannedSegments[i].status = statuses[i]
+	}
+	tracelog.InfoLogger.Printf("Checked %d WAL segments in %v\n", checkedCount, time.Since(startTime))
+	return nil
+}
+
+// checkFoundSegment checks the existence of the segment file and, if the checksum is recorded
+// in the WAL metadata, compares it with the checksum of the downloaded and decompressed segment
+func checkFoundSegment(walFolder storage.Folder, filename string,
+	listedFilenames map[string]bool) (ScannedSegmentStatus, error) {
+	exists, err := walFolder.Exists(filename)
+	if err != nil || !exists {
+		return Lost, err
+	}
+
+	segmentName := utility.TrimFileExtension(filename)
+	expectedChecksum, err := fetchSegmentChecksum(walFolder, segmentName, listedFilenames)
+	if err != nil || expectedChecksum == "" {
+		return Found, err
+	}
+
+	reader, err := internal.DownloadAndDecompressStorageFile(walFolder, segmentName)
+	if err != nil {
+		return Found, err
+	}
+	defer utility.LoggedClose(reader, "")
+	checksum, err := computeChecksum(reader)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to read WAL segment %s: %v\n", filename, err)
+		return Corrupted, nil
+	}
+	if checksum != expectedChecksum {
+		tracelog.WarningLogger.Printf("WAL segment %s checksum %s does not match %s from the WAL metadata\n",
+			filename, checksum, expectedChecksum)
+		return Corrupted, nil
+	}
+	return Found, nil
+}
+
+// fetchSegmentChecksum looks up the segment checksum in its individual WAL metadata file
+// or in the bulk one of its series. The checksum is empty if there is no metadata for the segment.
+func fetchSegmentChecksum(walFolder storage.Folder, segmentName string,
+	listedFilenames map[string]bool) (string, error) {
+	metadataNames := []string{segmentName + ".json", segmentName[:len(segmentName)-1] + ".json"}
+	for _, metadataName := range metadataNames {
+		if !listedFilenames[metadataName] {
+			continue
+		}
+		walMetadata, err := FetchWalMetadata(walFolder, metadataName)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to fetch WAL metadata %s", metadataName)
+		}
+		if description, ok := walMetadata[segmentName]; ok {
+			return description.SHA256, nil
+		}
+	}
+	return "", nil
+}
+
 // newWalIntegrityCheckResult check produces the WalVerifyCheckResult with status:
 // StatusOk if there are no missing segments in storage
 // StatusWarning if storage contains some ProbablyUploading or ProbablyDelayed segments
@@ -114,7 +246,7 @@ func newWalIntegrityCheckResult(segmentSequences []*IntegrityScanSegmentSequence
 	}
 	for _, row := range segmentSequences {
 		switch row.Status {
-		case Lost:
+		case Lost, Corrupted:
 			result.Status = StatusFailure
 			return result
 		case ProbablyDelayed, ProbablyUploading:
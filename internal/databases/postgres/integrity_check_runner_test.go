@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal/compression"
+)
+
+func TestCheckFoundSegmentsExist(t *testing.T) {
+	walFolder := memory.NewFolder("wal_005/", memory.NewStorage())
+	require.NoError(t, walFolder.PutObject("000000010000000000000001.lz4", strings.NewReader("wal")))
+	require.NoError(t, walFolder.PutObject("000000010000000000000003.lz4", strings.NewReader("wal")))
+	// the listing is stale: segment 2 is listed, but does not exist anymore
+	listedFilenames := []string{
+		"000000010000000000000001.lz4", "000000010000000000000002.lz4", "000000010000000000000003.lz4",
+	}
+
+	scannedSegments := make([]ScannedSegmentDescription, 0)
+	for segmentNo := WalSegmentNo(1); segmentNo <= 4; segmentNo++ {
+		status := Found
+		if segmentNo == 4 {
+			status = ProbablyUploading
+		}
+		scannedSegments = append(scannedSegments,
+			newScannedSegmentDescription(WalSegmentDescription{Timeline: 1, Number: segmentNo}, status))
+	}
+
+	err := checkFoundSegmentsContent(walFolder, listedFilenames, scannedSegments, 2)
+	require.NoError(t, err)
+	statuses := make([]ScannedSegmentStatus, 0, len(scannedSegments))
+	for _, segment := range scannedSegments {
+		statuses = append(statuses, segment.status)
+	}
+	assert.Equal(t, []ScannedSegmentStatus{Found, Lost, Found, ProbablyUploading}, statuses)
+}
+
+func TestCheckFoundSegmentsContent_Checksum(t *testing.T) {
+	walFolder := memory.NewFolder("wal_005/", memory.NewStorage())
+	putCompressedSegment(t, walFolder, "000000010000000000000001", "wal 1")
+	putCompressedSegment(t, walFolder, "000000010000000000000002", "corrupted wal 2")
+	putCompressedSegment(t, walFolder, "000000010000000000000003", "wal 3")
+	checksum1, err := computeChecksum(strings.NewReader("wal 1"))
+	require.NoError(t, err)
+	checksum2, err := computeChecksum(strings.NewReader("wal 2"))
+	require.NoError(t, err)
+	// the bulk metadata of the series, segment 3 has no checksum recorded
+	walMetadata, err := json.Marshal(map[string]WalMetadataDescription{
+		"000000010000000000000001": {SHA256: checksum1},
+		"000000010000000000000002": {SHA256: checksum2},
+		"000000010000000000000003": {},
+	})
+	require.NoError(t, err)
+	require.NoError(t, walFolder.PutObject("00000001000000000000000.json", bytes.NewReader(walMetadata)))
+	listedFilenames := []string{
+		"000000010000000000000001.lz4", "000000010000000000000002.lz4", "000000010000000000000003.lz4",
+		"00000001000000000000000.json",
+	}
+
+	scannedSegments := make([]ScannedSegmentDescription, 0)
+	for segmentNo := WalSegmentNo(1); segmentNo <= 3; segmentNo++ {
+		scannedSegments = append(scannedSegments,
+			newScannedSegmentDescription(WalSegmentDescription{Timeline: 1, Number: segmentNo}, Found))
+	}
+
+	err = checkFoundSegmentsContent(walFolder, listedFilenames, scannedSegments, 2)
+	require.NoError(t, err)
+	statuses := make([]ScannedSegmentStatus, 0, len(scannedSegments))
+	for _, segment := range scannedSegments {
+		statuses = append(statuses, segment.status)
+	}
+	assert.Equal(t, []ScannedSegmentStatus{Found, Corrupted, Found}, statuses)
+}
+
+func putCompressedSegment(t *testing.T, walFolder storage.Folder, segmentName, content string) {
+	var compressed bytes.Buffer
+	writer := compression.Compressors["lz4"].NewWriter(&compressed)
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, walFolder.PutObject(segmentName+".lz4", &compressed))
+}
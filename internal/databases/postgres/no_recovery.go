@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	StandbySignalFilename    = "standby.signal"
+	NoRecoveryNoteFilename   = "WALG_NO_RECOVERY"
+	noRecoveryDisabledSuffix = ".walg-no-recovery"
+	// the setting is unknown to PostgreSQL, so the server refuses to start while it is in postgresql.auto.conf
+	noRecoveryGuardSetting = "walg_restored_for_inspection_only"
+)
+
+// the files which make PostgreSQL start the archive recovery or the standby mode
+var recoveryTriggerFilenames = []string{RecoveryConfFilename, RecoverySignalFilename, StandbySignalFilename}
+
+// DisableRecovery prepares the restored data directory for the inspection of the files:
+// the recovery triggering files are renamed, postgresql.auto.conf gets a setting unknown
+// to PostgreSQL so an accidental start fails at once instead of the WAL replay,
+// the note describes how to undo that. The backup_label is left intact.
+// The names of the renamed files are returned.
+func DisableRecovery(dbDataDirectory string) ([]string, error) {
+	disabledFiles := make([]string, 0)
+	for _, filename := range recoveryTriggerFilenames {
+		path := filepath.Join(dbDataDirectory, filename)
+		if _, err := os.Lstat(path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to stat '%s'", path)
+		}
+		if err := os.Rename(path, path+noRecoveryDisabledSuffix); err != nil {
+			return nil, errors.Wrapf(err, "failed to rename '%s'", path)
+		}
+		disabledFiles = append(disabledFiles, filename)
+	}
+
+	autoConfPath := filepath.Join(dbDataDirectory, AutoConfFilename)
+	autoConf, err := os.OpenFile(autoConfPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open '%s'", autoConfPath)
+	}
+	defer utility.LoggedClose(autoConf, "")
+	guard := fmt.Sprintf("\n# added by wal-g backup-fetch --no-recovery, see %s\n%s = 'remove this line to start the server'\n",
+		NoRecoveryNoteFilename, noRecoveryGuardSetting)
+	if _, err = autoConf.WriteString(guard); err != nil {
+		return nil, errors.Wrapf(err, "failed to write '%s'", autoConfPath)
+	}
+
+	notePath := filepath.Join(dbDataDirectory, NoRecoveryNoteFilename)
+	err = ioutil.WriteFile(notePath, []byte(noRecoveryNote(disabledFiles)), 0600)
+	return disabledFiles, errors.Wrapf(err, "failed to write '%s'", notePath)
+}
+
+func noRecoveryNote(disabledFiles []string) string {
+	lines := []string{
+		"This data directory was restored by wal-g backup-fetch --no-recovery for the inspection of the files only.",
+		"The WAL required to make it consistent was not set up, so the server must not be started on it.",
+		"",
+		fmt.Sprintf("%s is kept as is, it holds the start WAL location and the checkpoint of the backup.",
+			BackupLabelFilename),
+		fmt.Sprintf("%s has the '%s' line, PostgreSQL refuses to start while it is there.",
+			AutoConfFilename, noRecoveryGuardSetting),
+	}
+	for _, filename := range disabledFiles {
+		lines = append(lines, fmt.Sprintf("%s was renamed to %s%s.", filename, filename, noRecoveryDisabledSuffix))
+	}
+	lines = append(lines, "",
+		"To recover this directory instead, remove the line from "+AutoConfFilename+", restore the renamed files,",
+		"provide the restore_command and remove this file.")
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// HandleNoRecovery disables the recovery of the fetched backup and prints the guidance
+func HandleNoRecovery(backup internal.Backup, dbDataDirectory string) {
+	dbDataDirectory = utility.ResolveSymlink(dbDataDirectory)
+	disabledFiles, err := DisableRecovery(dbDataDirectory)
+	internal.FatalfOnError("Failed to disable the recovery: %v\n", err)
+
+	if len(disabledFiles) > 0 {
+		tracelog.InfoLogger.Printf("Renamed the recovery files with the %s suffix: %s\n",
+			noRecoveryDisabledSuffix, strings.Join(disabledFiles, ", "))
+	}
+	tracelog.WarningLogger.Printf("Backup %s is restored to %s for the inspection only: "+
+		"the WAL replay is not set up and the server refuses to start, see %s\n",
+		backup.Name, dbDataDirectory, filepath.Join(dbDataDirectory, NoRecoveryNoteFilename))
+}
@@ -0,0 +1,40 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestDisableRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "no_recovery")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, filename := range []string{postgres.StandbySignalFilename, postgres.BackupLabelFilename} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filename), []byte("content"), 0600))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, postgres.AutoConfFilename), []byte("work_mem = '4MB'\n"), 0600))
+
+	disabledFiles, err := postgres.DisableRecovery(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{postgres.StandbySignalFilename}, disabledFiles)
+
+	assert.NoFileExists(t, filepath.Join(dir, postgres.StandbySignalFilename))
+	assert.FileExists(t, filepath.Join(dir, postgres.StandbySignalFilename+".walg-no-recovery"))
+	assert.FileExists(t, filepath.Join(dir, postgres.BackupLabelFilename))
+	assert.NoFileExists(t, filepath.Join(dir, postgres.RecoverySignalFilename))
+
+	autoConf, err := ioutil.ReadFile(filepath.Join(dir, postgres.AutoConfFilename))
+	require.NoError(t, err)
+	assert.Contains(t, string(autoConf), "work_mem = '4MB'\n")
+	assert.Contains(t, string(autoConf), "walg_restored_for_inspection_only = ")
+
+	note, err := ioutil.ReadFile(filepath.Join(dir, postgres.NoRecoveryNoteFilename))
+	require.NoError(t, err)
+	assert.Contains(t, string(note), "standby.signal was renamed to standby.signal.walg-no-recovery")
+}
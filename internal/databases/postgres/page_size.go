@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+// pgControlFloatFormat is the floatFormat field of the pg_control, the blcksz field follows it
+// in all the versions of the ControlFileData
+const pgControlFloatFormat = 1234567.0
+
+type PageSizeMismatchError struct {
+	error
+}
+
+func newPageSizeMismatchError(backupName string, pageSize int64, baseName string, basePageSize int64) PageSizeMismatchError {
+	return PageSizeMismatchError{errors.Errorf(
+		"page size %d of backup %s differs from page size %d of its base %s, the increments can't be applied",
+		pageSize, backupName, basePageSize, baseName)}
+}
+
+func newTargetPageSizeMismatchError(dbDataDirectory string, targetPageSize int64,
+	backupName string, pageSize int64) PageSizeMismatchError {
+	return PageSizeMismatchError{errors.Errorf(
+		"page size %d of the cluster in %s differs from page size %d of backup %s, the increments can't be applied",
+		targetPageSize, dbDataDirectory, pageSize, backupName)}
+}
+
+func (err PageSizeMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetPageSize returns the page size of the backed up cluster,
+// the sentinels without the recorded one are of the default BLCKSZ
+func (dto *BackupSentinelDto) GetPageSize() int64 {
+	if dto.PageSize == nil {
+		return DatabasePageSize
+	}
+	return int64(*dto.PageSize)
+}
+
+// checkDeltaChainPageSize checks that the backup and all its delta bases are of the same page size,
+// otherwise the pages of the increments would be written at the wrong offsets
+func checkDeltaChainPageSize(baseBackupFolder storage.Folder, backupName string) error {
+	backup := NewBackup(baseBackupFolder, backupName)
+	sentinelDto, err := backup.GetSentinel()
+	if err != nil {
+		return err
+	}
+	for sentinelDto.IsIncremental() {
+		baseName := *sentinelDto.IncrementFrom
+		baseBackup := NewBackup(baseBackupFolder, baseName)
+		baseSentinelDto, err := baseBackup.GetSentinel()
+		if err != nil {
+			return err
+		}
+		if sentinelDto.GetPageSize() != baseSentinelDto.GetPageSize() {
+			return newPageSizeMismatchError(backupName, sentinelDto.GetPageSize(),
+				baseName, baseSentinelDto.GetPageSize())
+		}
+		backupName, sentinelDto = baseName, baseSentinelDto
+	}
+	return nil
+}
+
+// checkTargetPageSize checks that the cluster in the data directory, which the increments of the backup
+// are applied onto, is of the page size of the backup
+func checkTargetPageSize(dbDataDirectory, backupName string, sentinelDto BackupSentinelDto) error {
+	targetPageSize, err := readPgControlPageSize(filepath.Join(dbDataDirectory, PgControlPath))
+	if err != nil {
+		return err
+	}
+	if targetPageSize != sentinelDto.GetPageSize() {
+		return newTargetPageSizeMismatchError(dbDataDirectory, targetPageSize, backupName, sentinelDto.GetPageSize())
+	}
+	return nil
+}
+
+func readPgControlPageSize(pgControlPath string) (int64, error) {
+	pgControl, err := ioutil.ReadFile(pgControlPath)
+	if err != nil {
+		return 0, err
+	}
+	return parsePgControlPageSize(pgControl, pgControlPath)
+}
+
+// parsePgControlPageSize finds the blcksz field after the floatFormat one, whose offset changes with the versions
+func parsePgControlPageSize(pgControl []byte, source string) (int64, error) {
+	floatFormat := make([]byte, 8)
+	binary.LittleEndian.PutUint64(floatFormat, math.Float64bits(pgControlFloatFormat))
+	for offset := 0; offset+len(floatFormat)+sizeofInt32 <= len(pgControl); offset += 8 {
+		if !bytes.Equal(pgControl[offset:offset+len(floatFormat)], floatFormat) {
+			continue
+		}
+		blockSizeOffset := offset + len(floatFormat)
+		pageSize := int64(binary.LittleEndian.Uint32(pgControl[blockSizeOffset : blockSizeOffset+sizeofInt32]))
+		if pageSize < 1024 || pageSize > 32768 || pageSize&(pageSize-1) != 0 {
+			return 0, errors.Errorf("invalid page size %d in %s", pageSize, source)
+		}
+		return pageSize, nil
+	}
+	return 0, errors.Errorf("failed to find the page size in %s", source)
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func preparePageSizeChain(t *testing.T, fullPageSize, deltaPageSize *uint64) storage.Folder {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	fullName := "base_000000010000000000000002"
+	lsn := uint64(0x2000028)
+	incrementCount := 1
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn,
+		PageSize: fullPageSize}, fullName))
+	require.NoError(t, internal.UploadSentinel(uploader, &BackupSentinelDto{BackupStartLSN: &lsn, PageSize: deltaPageSize,
+		IncrementFrom: &fullName, IncrementFullName: &fullName, IncrementFromLSN: &lsn,
+		IncrementCount: &incrementCount}, "base_000000010000000000000004_D_000000010000000000000002"))
+	return baseBackupFolder
+}
+
+func TestGetPageSize_NotRecorded(t *testing.T) {
+	assert.Equal(t, int64(DatabasePageSize), (&BackupSentinelDto{}).GetPageSize())
+}
+
+func TestCheckDeltaChainPageSize(t *testing.T) {
+	pageSize := uint64(16384)
+	baseBackupFolder := preparePageSizeChain(t, &pageSize, &pageSize)
+	err := checkDeltaChainPageSize(baseBackupFolder, "base_000000010000000000000004_D_000000010000000000000002")
+	assert.NoError(t, err)
+}
+
+func TestCheckDeltaChainPageSize_DefaultNotRecorded(t *testing.T) {
+	pageSize := uint64(DatabasePageSize)
+	baseBackupFolder := preparePageSizeChain(t, nil, &pageSize)
+	err := checkDeltaChainPageSize(baseBackupFolder, "base_000000010000000000000004_D_000000010000000000000002")
+	assert.NoError(t, err)
+}
+
+func TestCheckDeltaChainPageSize_Mismatch(t *testing.T) {
+	pageSize := uint64(16384)
+	baseBackupFolder := preparePageSizeChain(t, nil, &pageSize)
+	err := checkDeltaChainPageSize(baseBackupFolder, "base_000000010000000000000004_D_000000010000000000000002")
+	assert.IsType(t, PageSizeMismatchError{}, err)
+}
+
+// writeTargetPgControl writes the pg_control of the cluster of the page size into the data directory
+func writeTargetPgControl(t *testing.T, pageSize uint32) string {
+	dbDataDirectory, err := ioutil.TempDir("", "page_size")
+	require.NoError(t, err)
+	pgControl := make([]byte, 8192)
+	binary.LittleEndian.PutUint64(pgControl, 6942142069420)
+	binary.LittleEndian.PutUint32(pgControl[244:], 8)
+	binary.LittleEndian.PutUint64(pgControl[248:], math.Float64bits(pgControlFloatFormat))
+	binary.LittleEndian.PutUint32(pgControl[256:], pageSize)
+	binary.LittleEndian.PutUint32(pgControl[260:], 131072)
+	require.NoError(t, os.MkdirAll(filepath.Join(dbDataDirectory, "global"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dbDataDirectory, PgControlPath), pgControl, 0600))
+	return dbDataDirectory
+}
+
+func TestCheckTargetPageSize(t *testing.T) {
+	pageSize := uint64(16384)
+	dbDataDirectory := writeTargetPgControl(t, 16384)
+	defer os.RemoveAll(dbDataDirectory)
+
+	assert.NoError(t, checkTargetPageSize(dbDataDirectory, "base_000000010000000000000004",
+		BackupSentinelDto{PageSize: &pageSize}))
+	err := checkTargetPageSize(dbDataDirectory, "base_000000010000000000000004", BackupSentinelDto{})
+	assert.IsType(t, PageSizeMismatchError{}, err)
+}
+
+func TestCheckTargetPageSize_DefaultNotRecorded(t *testing.T) {
+	dbDataDirectory := writeTargetPgControl(t, uint32(DatabasePageSize))
+	defer os.RemoveAll(dbDataDirectory)
+
+	assert.NoError(t, checkTargetPageSize(dbDataDirectory, "base_000000010000000000000004", BackupSentinelDto{}))
+}
+
+func TestParsePgControlPageSize_Invalid(t *testing.T) {
+	_, err := parsePgControlPageSize(make([]byte, 8192), "pg_control")
+	assert.Error(t, err)
+
+	pgControl := make([]byte, 8192)
+	binary.LittleEndian.PutUint64(pgControl[248:], math.Float64bits(pgControlFloatFormat))
+	binary.LittleEndian.PutUint32(pgControl[256:], 12345)
+	_, err = parsePgControlPageSize(pgControl, "pg_control")
+	assert.Error(t, err)
+}
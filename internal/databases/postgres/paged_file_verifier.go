@@ -176,16 +176,21 @@ func isPageCorrupted(path string, blockNo uint32, page *PgDatabasePage) (bool, e
 
 // VerifyPagedFileIncrement verifies pages of an increment
 func VerifyPagedFileIncrement(path string, fileInfo os.FileInfo, increment io.Reader) ([]uint32, error) {
-	_, diffBlockCount, diffMap, err := GetIncrementHeaderFields(increment)
+	header, err := readIncrementHeader(increment)
 	if err != nil {
 		return nil, err
 	}
-	blockNumbers := make([]uint32, 0, diffBlockCount)
-	for i := uint32(0); i < diffBlockCount; i++ {
-		blockNo := binary.LittleEndian.Uint32(diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
+	blockNumbers := make([]uint32, 0, header.diffBlockCount)
+	for i := uint32(0); i < header.diffBlockCount; i++ {
+		blockNo := binary.LittleEndian.Uint32(header.diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
 		blockNumbers = append(blockNumbers, blockNo)
 	}
-	return verifyPageBlocks(path, fileInfo, increment, blockNumbers)
+	pageBlocks := io.LimitReader(increment, int64(header.diffBlockCount)*DatabasePageSize)
+	corruptBlockNumbers, err := verifyPageBlocks(path, fileInfo, pageBlocks, blockNumbers)
+	if err != nil {
+		return nil, err
+	}
+	return corruptBlockNumbers, header.readFooter(increment)
 }
 
 // VerifyPagedFileBase verifies pages of a standard paged file
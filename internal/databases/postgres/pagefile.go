@@ -6,6 +6,8 @@
 // 4 bytes uint changed pages count N
 // (N * 4) bytes for Block Numbers of changed pages
 // (N * DatabasePageSize) bytes for changed page data
+// 4 bytes footer with designation information, format version and magic number (since version "2")
+// 4 bytes uint count of the written pages, should be equal to N (since version "2")
 //
 
 package postgres
@@ -25,7 +27,6 @@ import (
 	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/internal/limiters"
 	"github.com/wal-g/wal-g/internal/walparser"
-	"github.com/wal-g/wal-g/internal/walparser/parsingutil"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -81,6 +82,18 @@ func (err UnknownIncrementFileHeaderError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type IncompleteIncrementError struct {
+	error
+}
+
+func newIncompleteIncrementError(reason string) IncompleteIncrementError {
+	return IncompleteIncrementError{errors.Errorf("Increment is incomplete: %s", reason)}
+}
+
+func (err IncompleteIncrementError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 type UnexpectedTarDataError struct {
 	error
 }
@@ -129,7 +142,7 @@ func ReadIncrementalFile(filePath string,
 		Closer: file,
 	}
 
-	pageReader := &IncrementalPageReader{fileReadSeekCloser, fileSize, lsn, nil, nil}
+	pageReader := &IncrementalPageReader{PagedFile: fileReadSeekCloser, FileSize: fileSize, Lsn: lsn}
 	incrementSize, err := pageReader.initialize(deltaBitmap)
 	if err != nil {
 		return nil, 0, err
@@ -148,7 +161,7 @@ func ReadIncrementLocations(filePath string, fileSize int64, lsn uint64) ([]walp
 		Seeker: file,
 		Closer: file,
 	}
-	pageReader := &IncrementalPageReader{fileReadSeekCloser, fileSize, lsn, nil, nil}
+	pageReader := &IncrementalPageReader{PagedFile: fileReadSeekCloser, FileSize: fileSize, Lsn: lsn}
 	err = pageReader.FullScanInitialize()
 	if err != nil {
 		return nil, err
@@ -169,27 +182,11 @@ func convertBlocksToLocations(filePath string, blocks []uint32) ([]walparser.Blo
 	return locations, nil
 }
 
-// ApplyFileIncrement changes pages according to supplied change map file
-func ApplyFileIncrement(fileName string, increment io.Reader, createNewIncrementalFiles bool) error {
+// ApplyFileIncrement changes pages according to supplied change map file,
+// the pageSize is the one of the backed up cluster
+func ApplyFileIncrement(fileName string, increment io.Reader, createNewIncrementalFiles bool, pageSize int64) error {
 	tracelog.DebugLogger.Printf("Incrementing %s\n", fileName)
-	err := ReadIncrementFileHeader(increment)
-	if err != nil {
-		return err
-	}
-
-	var fileSize uint64
-	var diffBlockCount uint32
-	err = parsingutil.ParseMultipleFieldsFromReader([]parsingutil.FieldToParse{
-		{Field: &fileSize, Name: "fileSize"},
-		{Field: &diffBlockCount, Name: "diffBlockCount"},
-	}, increment)
-	if err != nil {
-		return err
-	}
-
-	diffMap := make([]byte, diffBlockCount*sizeofInt32)
-
-	_, err = io.ReadFull(increment, diffMap)
+	header, err := readIncrementHeader(increment)
 	if err != nil {
 		return err
 	}
@@ -209,25 +206,33 @@ func ApplyFileIncrement(fileName string, increment io.Reader, createNewIncrement
 	defer utility.LoggedClose(file, "")
 	defer utility.LoggedSync(file, "")
 
-	err = file.Truncate(int64(fileSize))
+	err = file.Truncate(int64(header.fileSize))
 	if err != nil {
 		return err
 	}
 
-	page := make([]byte, DatabasePageSize)
-	for i := uint32(0); i < diffBlockCount; i++ {
-		blockNo := binary.LittleEndian.Uint32(diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
+	page := make([]byte, pageSize)
+	for i := uint32(0); i < header.diffBlockCount; i++ {
+		blockNo := binary.LittleEndian.Uint32(header.diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
 		_, err = io.ReadFull(increment, page)
 		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return newIncompleteIncrementError(fmt.Sprintf("%d of %d blocks are found", i, header.diffBlockCount))
+			}
 			return err
 		}
 
-		_, err = file.WriteAt(page, int64(blockNo)*DatabasePageSize)
+		_, err = file.WriteAt(page, int64(blockNo)*pageSize)
 		if err != nil {
 			return err
 		}
 	}
 
+	err = header.readFooter(increment)
+	if err != nil {
+		return err
+	}
+
 	all, _ := increment.Read(make([]byte, 1))
 	if all > 0 {
 		return newUnexpectedTarDataError()
@@ -237,17 +242,23 @@ func ApplyFileIncrement(fileName string, increment io.Reader, createNewIncrement
 }
 
 func ReadIncrementFileHeader(reader io.Reader) error {
+	_, err := readIncrementFileVersion(reader)
+	return err
+}
+
+// readIncrementFileVersion reads the increment file header and returns the format version
+func readIncrementFileVersion(reader io.Reader) (byte, error) {
 	header := make([]byte, sizeofInt32)
 	_, err := io.ReadFull(reader, header)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if header[0] != 'w' || header[1] != 'i' || header[3] != SignatureMagicNumber {
-		return newInvalidIncrementFileHeaderError()
+		return 0, newInvalidIncrementFileHeaderError()
 	}
-	if header[2] != '1' {
-		return newUnknownIncrementFileHeaderError()
+	if header[2] != IncrementFileVersion && header[2] != incrementFileVersionWithoutFooter {
+		return 0, newUnknownIncrementFileHeaderError()
 	}
-	return nil
+	return header[2], nil
 }
@@ -7,6 +7,7 @@ package postgres
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -42,12 +43,12 @@ func (rw *ReadWriterAtFileImpl) Size() int64 {
 
 // RestoreMissingPages restores missing pages (zero blocks)
 // of local file with their base backup version
-func RestoreMissingPages(base io.Reader, target ReadWriterAt) error {
+func RestoreMissingPages(base io.Reader, target ReadWriterAt, pageSize int64, truncatedPolicy TruncatedPolicy) error {
 	tracelog.DebugLogger.Printf("Restoring missing pages from base backup: %s\n", target.Name())
 
-	targetPageCount := target.Size() / DatabasePageSize
+	targetPageCount := target.Size() / pageSize
 	for i := int64(0); i < targetPageCount; i++ {
-		_, err := writePage(target, i, base, false)
+		_, err := writePage(target, i, pageSize, base, false)
 		if err == io.EOF {
 			break
 		}
@@ -55,74 +56,116 @@ func RestoreMissingPages(base io.Reader, target ReadWriterAt) error {
 			return err
 		}
 	}
+	if truncatedPolicy == TruncatedPolicyExtend {
+		return extendFromBase(base, target, targetPageCount, pageSize)
+	}
 	// check if some extra pages left in base reader
 	if isEmpty := isTarReaderEmpty(base); !isEmpty {
+		if truncatedPolicy == TruncatedPolicyFail {
+			return newFileTruncatedError(target.Name())
+		}
 		tracelog.DebugLogger.Printf("Skipping pages after end of the local target %s, "+
 			"possibly the pagefile was truncated.\n", target.Name())
 	}
 	return nil
 }
 
+// extendFromBase appends the pages left in the base reader to the target
+// which has targetPageCount pages
+func extendFromBase(base io.Reader, target ReadWriterAt, targetPageCount, pageSize int64) error {
+	extendedPageCount := int64(0)
+	for i := targetPageCount; ; i++ {
+		_, err := writePage(target, i, pageSize, base, true)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		extendedPageCount++
+	}
+	if extendedPageCount > 0 {
+		tracelog.DebugLogger.Printf("Extended the local target %s with %d pages after its end\n",
+			target.Name(), extendedPageCount)
+	}
+	return nil
+}
+
 // CreateFileFromIncrement writes the pages from the increment to local file
-// and write empty blocks in place of pages which are not present in the increment
-func CreateFileFromIncrement(increment io.Reader, target ReadWriterAt) (int64, error) {
+// and write empty blocks in place of pages which are not present in the increment.
+// The pageSize is the one of the backed up cluster.
+func CreateFileFromIncrement(increment io.Reader, target ReadWriterAt, pageSize int64) (int64, error) {
 	tracelog.DebugLogger.Printf("Creating from increment: %s\n", target.Name())
 
-	fileSize, diffBlockCount, diffMap, err := GetIncrementHeaderFields(increment)
+	header, err := readIncrementHeader(increment)
 	if err != nil {
 		return 0, err
 	}
 
 	// set represents all block numbers with non-empty pages
-	deltaBlockNumbers := make(map[int64]bool, diffBlockCount)
-	for i := uint32(0); i < diffBlockCount; i++ {
-		blockNo := binary.LittleEndian.Uint32(diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
+	deltaBlockNumbers := make(map[int64]bool, header.diffBlockCount)
+	for i := uint32(0); i < header.diffBlockCount; i++ {
+		blockNo := binary.LittleEndian.Uint32(header.diffMap[i*sizeofInt32 : (i+1)*sizeofInt32])
 		deltaBlockNumbers[int64(blockNo)] = true
 	}
-	pageCount := int64(fileSize / uint64(DatabasePageSize))
-	emptyPage := make([]byte, DatabasePageSize)
+	pageCount := int64(header.fileSize / uint64(pageSize))
+	emptyPage := make([]byte, pageSize)
 	missingBlockCount := pageCount
+	readBlockCount := int64(0)
 	for i := int64(0); i < pageCount; i++ {
 		if deltaBlockNumbers[i] {
-			_, err = writePage(target, i, increment, true)
+			_, err = writePage(target, i, pageSize, increment, true)
 			if err != nil {
 				return 0, err
 			}
 			missingBlockCount--
+			readBlockCount++
 		} else {
-			_, err = target.WriteAt(emptyPage, i*DatabasePageSize)
+			_, err = target.WriteAt(emptyPage, i*pageSize)
 			if err != nil {
 				return 0, err
 			}
 		}
 	}
 	// check if some extra delta blocks left in increment
-	if isEmpty := isTarReaderEmpty(increment); !isEmpty {
+	if extraBlockCount := int64(header.diffBlockCount) - readBlockCount; extraBlockCount > 0 {
 		tracelog.DebugLogger.Printf("Skipping extra increment blocks, target: %s\n", target.Name())
+		_, err = io.CopyN(ioutil.Discard, increment, extraBlockCount*pageSize)
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = header.readFooter(increment)
+	if err != nil {
+		return 0, err
+	}
+	if isEmpty := isTarReaderEmpty(increment); !isEmpty {
+		tracelog.DebugLogger.Printf("Skipping unexpected data after the increment, target: %s\n", target.Name())
 	}
 	return missingBlockCount, nil
 }
 
 // WritePagesFromIncrement writes pages from delta backup according to diffMap
-func WritePagesFromIncrement(increment io.Reader, target ReadWriterAt, overwriteExisting bool) (int64, error) {
+func WritePagesFromIncrement(increment io.Reader, target ReadWriterAt, pageSize int64,
+	overwriteExisting bool) (int64, error) {
 	tracelog.DebugLogger.Printf("Writing pages from increment: %s\n", target.Name())
 
-	_, diffBlockCount, diffMap, err := GetIncrementHeaderFields(increment)
+	header, err := readIncrementHeader(increment)
 	if err != nil {
 		return 0, err
 	}
-	targetPageCount := target.Size() / DatabasePageSize
+	targetPageCount := target.Size() / pageSize
 	restoredBlockCount := int64(0)
-	for i := uint32(0); i < diffBlockCount; i++ {
-		blockNo := int64(binary.LittleEndian.Uint32(diffMap[i*sizeofInt32 : (i+1)*sizeofInt32]))
+	for i := uint32(0); i < header.diffBlockCount; i++ {
+		blockNo := int64(binary.LittleEndian.Uint32(header.diffMap[i*sizeofInt32 : (i+1)*sizeofInt32]))
 		if blockNo >= targetPageCount {
-			_, err := io.CopyN(ioutil.Discard, increment, DatabasePageSize)
+			_, err := io.CopyN(ioutil.Discard, increment, pageSize)
 			if err != nil {
 				return 0, err
 			}
 			continue
 		}
-		wrotePage, err := writePage(target, blockNo, increment, overwriteExisting)
+		wrotePage, err := writePage(target, blockNo, pageSize, increment, overwriteExisting)
 		if err != nil {
 			return 0, err
 		}
@@ -130,6 +173,10 @@ func WritePagesFromIncrement(increment io.Reader, target ReadWriterAt, overwrite
 			restoredBlockCount++
 		}
 	}
+	err = header.readFooter(increment)
+	if err != nil {
+		return 0, err
+	}
 	// at this point, we should have empty increment reader
 	if isEmpty := isTarReaderEmpty(increment); !isEmpty {
 		return 0, newUnexpectedTarDataError()
@@ -138,15 +185,15 @@ func WritePagesFromIncrement(increment io.Reader, target ReadWriterAt, overwrite
 }
 
 // write page to local file
-func writePage(target ReadWriterAt, blockNo int64, content io.Reader, overwrite bool) (bool, error) {
-	page := make([]byte, DatabasePageSize)
+func writePage(target ReadWriterAt, blockNo, pageSize int64, content io.Reader, overwrite bool) (bool, error) {
+	page := make([]byte, pageSize)
 	_, err := io.ReadFull(content, page)
 	if err != nil {
 		return false, err
 	}
 
 	if !overwrite {
-		isMissingPage, err := checkIfMissingPage(target, blockNo)
+		isMissingPage, err := checkIfMissingPage(target, blockNo, pageSize)
 		if err != nil {
 			return false, err
 		}
@@ -154,7 +201,7 @@ func writePage(target ReadWriterAt, blockNo int64, content io.Reader, overwrite
 			return false, nil
 		}
 	}
-	_, err = target.WriteAt(page, blockNo*DatabasePageSize)
+	_, err = target.WriteAt(page, blockNo*pageSize)
 	if err != nil {
 		return false, err
 	}
@@ -162,10 +209,10 @@ func writePage(target ReadWriterAt, blockNo int64, content io.Reader, overwrite
 }
 
 // check if page is missing (block of zeros) in local file
-func checkIfMissingPage(target io.ReaderAt, blockNo int64) (bool, error) {
+func checkIfMissingPage(target io.ReaderAt, blockNo, pageSize int64) (bool, error) {
 	emptyPageHeader := make([]byte, headerSize)
 	pageHeader := make([]byte, headerSize)
-	_, err := target.ReadAt(pageHeader, blockNo*DatabasePageSize)
+	_, err := target.ReadAt(pageHeader, blockNo*pageSize)
 	if err != nil {
 		return false, err
 	}
@@ -179,27 +226,68 @@ func isTarReaderEmpty(reader io.Reader) bool {
 	return all == 0
 }
 
+// incrementHeader holds the fields of the increment file header
+type incrementHeader struct {
+	version        byte
+	fileSize       uint64
+	diffBlockCount uint32
+	diffMap        []byte
+}
+
 func GetIncrementHeaderFields(increment io.Reader) (uint64, uint32, []byte, error) {
-	err := ReadIncrementFileHeader(increment)
+	header, err := readIncrementHeader(increment)
 	if err != nil {
 		return 0, 0, nil, err
 	}
+	return header.fileSize, header.diffBlockCount, header.diffMap, nil
+}
 
-	var fileSize uint64
-	var diffBlockCount uint32
+func readIncrementHeader(increment io.Reader) (incrementHeader, error) {
+	version, err := readIncrementFileVersion(increment)
+	if err != nil {
+		return incrementHeader{}, err
+	}
+
+	header := incrementHeader{version: version}
 	err = parsingutil.ParseMultipleFieldsFromReader([]parsingutil.FieldToParse{
-		{Field: &fileSize, Name: "fileSize"},
-		{Field: &diffBlockCount, Name: "diffBlockCount"},
+		{Field: &header.fileSize, Name: "fileSize"},
+		{Field: &header.diffBlockCount, Name: "diffBlockCount"},
 	}, increment)
 	if err != nil {
-		return 0, 0, nil, err
+		return incrementHeader{}, err
 	}
 
-	diffMap := make([]byte, diffBlockCount*sizeofInt32)
+	header.diffMap = make([]byte, header.diffBlockCount*sizeofInt32)
 
-	_, err = io.ReadFull(increment, diffMap)
+	_, err = io.ReadFull(increment, header.diffMap)
 	if err != nil {
-		return 0, 0, nil, err
+		return incrementHeader{}, err
+	}
+	return header, nil
+}
+
+// readFooter should be called after all the pages of the increment are read.
+// It checks that the increment holds all the pages declared in the header.
+// The increments of the first format version have no footer and are not checked
+func (header incrementHeader) readFooter(increment io.Reader) error {
+	if header.version == incrementFileVersionWithoutFooter {
+		return nil
+	}
+	footer := make([]byte, incrementFileFooterSize)
+	_, err := io.ReadFull(increment, footer)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return newIncompleteIncrementError("the footer is missing")
 	}
-	return fileSize, diffBlockCount, diffMap, nil
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(footer[:len(IncrementFileFooter)], IncrementFileFooter) {
+		return newIncompleteIncrementError("invalid footer")
+	}
+	writtenBlockCount := binary.LittleEndian.Uint32(footer[len(IncrementFileFooter):])
+	if writtenBlockCount != header.diffBlockCount {
+		return newIncompleteIncrementError(fmt.Sprintf("%d of %d blocks are written",
+			writtenBlockCount, header.diffBlockCount))
+	}
+	return nil
 }
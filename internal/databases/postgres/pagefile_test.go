@@ -84,7 +84,7 @@ func postgresApplyIncrementTest(testIncrement *TestIncrement, t *testing.T) {
 	tmpFile, _ := os.OpenFile(tmpFileName, os.O_RDWR, 0666)
 	tmpFile.WriteAt(make([]byte, 12345), 477421568-12345)
 	tmpFile.Close()
-	err := postgres.ApplyFileIncrement(tmpFileName, incrementReader, false)
+	err := postgres.ApplyFileIncrement(tmpFileName, incrementReader, false, postgres.DatabasePageSize)
 	assert.NoError(t, err)
 	_, err = incrementReader.Read(make([]byte, 1))
 	assert.Equalf(t, io.EOF, err, "Not read to the end")
@@ -149,7 +149,41 @@ func TestReadIncrementFileHeader_InvalidIncrementFileHeaderError(t *testing.T) {
 // Should return UnknownIncrementFileHeaderError
 // when reading increment with not supported header version
 func TestReadIncrementFileHeader_UnknownIncrementFileHeaderError(t *testing.T) {
-	readIncrementFileHeaderTest(t, []byte{'w', 'i', '2', postgres.SignatureMagicNumber}, postgres.UnknownIncrementFileHeaderError{})
+	readIncrementFileHeaderTest(t, []byte{'w', 'i', '3', postgres.SignatureMagicNumber}, postgres.UnknownIncrementFileHeaderError{})
+}
+
+// Increments of the first format version have no footer and should still be read
+func TestReadIncrementFileHeader_VersionWithoutFooter(t *testing.T) {
+	readIncrementFileHeaderTest(t, []byte{'w', 'i', '1', postgres.SignatureMagicNumber}, nil)
+}
+
+// Increment without the footer is the truncated one unless it has the first format version
+func TestWritingIncrementWithoutFooter(t *testing.T) {
+	incrementBytes := regularTestIncrement.incrementBytes
+	truncated := incrementBytes[:len(incrementBytes)-len(postgres.IncrementFileFooter)-sizeofInt32]
+
+	mockContent := make([]byte, postgres.DatabasePageSize*pagedFileBlockCount)
+	_, err := postgres.WritePagesFromIncrement(bytes.NewReader(truncated), NewMockReadWriterAt(mockContent),
+		postgres.DatabasePageSize, false)
+	assert.IsType(t, postgres.IncompleteIncrementError{}, err)
+
+	versionWithoutFooter := append([]byte{}, truncated...)
+	versionWithoutFooter[2] = '1'
+	mockContent = make([]byte, postgres.DatabasePageSize*pagedFileBlockCount)
+	_, err = postgres.WritePagesFromIncrement(bytes.NewReader(versionWithoutFooter), NewMockReadWriterAt(mockContent),
+		postgres.DatabasePageSize, false)
+	assert.NoError(t, err)
+}
+
+// Should return IncompleteIncrementError when the footer
+// does not match the block count declared in the header
+func TestCreatingFileFromIncrementWithWrongFooter(t *testing.T) {
+	incrementBytes := append([]byte{}, regularTestIncrement.incrementBytes...)
+	binary.LittleEndian.PutUint32(incrementBytes[len(incrementBytes)-sizeofInt32:], regularTestIncrement.diffBlockCount-1)
+
+	_, err := postgres.CreateFileFromIncrement(bytes.NewReader(incrementBytes), NewMockReadWriterAt(make([]byte, 0)),
+		postgres.DatabasePageSize)
+	assert.IsType(t, postgres.IncompleteIncrementError{}, err)
 }
 
 func readIncrementFileHeaderTest(t *testing.T, headerData []byte, expectedErr error) {
@@ -176,7 +210,7 @@ func postgresCreateFileFromIncrementTest(testIncrement *TestIncrement, t *testin
 	incrementReader := testIncrement.NewReader()
 	mockFile := NewMockReadWriterAt(make([]byte, 0))
 
-	_, err := postgres.CreateFileFromIncrement(incrementReader, mockFile)
+	_, err := postgres.CreateFileFromIncrement(incrementReader, mockFile, postgres.DatabasePageSize)
 	assert.NoError(t, err, "Expected no errors after creating file from increment")
 	assert.Equal(t, testIncrement.fileSize, uint64(len(mockFile.content)),
 		"Result file size should match the size specified in the increment header")
@@ -206,7 +240,7 @@ func postgresWriteIncrementTestCompletedFile(testIncrement *TestIncrement, t *te
 	mockContent, _ := ioutil.ReadFile(pagedFileName)
 	mockFile := NewMockReadWriterAt(mockContent)
 
-	_, err := postgres.WritePagesFromIncrement(testIncrement.NewReader(), mockFile, false)
+	_, err := postgres.WritePagesFromIncrement(testIncrement.NewReader(), mockFile, postgres.DatabasePageSize, false)
 
 	assert.NoError(t, err, "Expected no errors after writing increment")
 	// check that no bytes were written to the mock file
@@ -232,7 +266,7 @@ func TestWritingZeroBlocksIncrementToEmptyFile(t *testing.T) {
 func postgresWritePagesTestEmptyFile(testIncrement *TestIncrement, t *testing.T) {
 	mockContent := make([]byte, postgres.DatabasePageSize*pagedFileBlockCount)
 	mockFile := NewMockReadWriterAt(mockContent)
-	_, err := postgres.WritePagesFromIncrement(testIncrement.NewReader(), mockFile, false)
+	_, err := postgres.WritePagesFromIncrement(testIncrement.NewReader(), mockFile, postgres.DatabasePageSize, false)
 	assert.NoError(t, err, "Expected no errors after writing increment")
 	assert.Equal(t, testIncrement.fileSize, uint64(len(mockFile.content)),
 		"Result file size should match the size specified in the increment header")
@@ -261,7 +295,7 @@ func TestWritingRegularIncrementToIncompleteFile(t *testing.T) {
 		mockContent[i] = 0
 	}
 	mockFile := NewMockReadWriterAt(mockContent)
-	_, err := postgres.WritePagesFromIncrement(incrementReader, mockFile, false)
+	_, err := postgres.WritePagesFromIncrement(incrementReader, mockFile, postgres.DatabasePageSize, false)
 
 	assert.NoError(t, err, "Expected no errors after writing increment")
 	assert.Equal(t, regularTestIncrement.fileSize, uint64(len(mockFile.content)),
@@ -299,7 +333,7 @@ func TestWritingAllBlocksIncrementToIncompleteFile(t *testing.T) {
 	}
 	mockFile := NewMockReadWriterAt(mockContent)
 
-	_, err := postgres.WritePagesFromIncrement(incrementReader, mockFile, false)
+	_, err := postgres.WritePagesFromIncrement(incrementReader, mockFile, postgres.DatabasePageSize, false)
 
 	assert.NoError(t, err, "Expected no errors after writing increment")
 	assert.Equal(t, allBlocksTestIncrement.fileSize, uint64(len(mockFile.content)),
@@ -318,7 +352,7 @@ func TestRestoringPagesToCompletedFile(t *testing.T) {
 	mockContent, _ := ioutil.ReadFile(pagedFileName)
 	mockFile := NewMockReadWriterAt(mockContent)
 
-	err := postgres.RestoreMissingPages(fileReader, mockFile)
+	err := postgres.RestoreMissingPages(fileReader, mockFile, postgres.DatabasePageSize, postgres.TruncatedPolicyIgnore)
 
 	assert.NoError(t, err, "Expected no errors after restoring missing pages")
 	// check that no bytes were written to the mock file
@@ -337,7 +371,7 @@ func TestRestoringPagesToIncompleteFile(t *testing.T) {
 	}
 	mockFile := NewMockReadWriterAt(mockContent)
 
-	err := postgres.RestoreMissingPages(fileReader, mockFile)
+	err := postgres.RestoreMissingPages(fileReader, mockFile, postgres.DatabasePageSize, postgres.TruncatedPolicyIgnore)
 
 	assert.NoError(t, err, "Expected no errors after restoring missing pages")
 	pagedFile.Seek(0, 0)
@@ -354,7 +388,7 @@ func TestRestoringPagesToEmptyFile(t *testing.T) {
 	mockContent := make([]byte, postgres.DatabasePageSize*pagedFileBlockCount)
 	mockFile := NewMockReadWriterAt(mockContent)
 
-	err := postgres.RestoreMissingPages(fileReader, mockFile)
+	err := postgres.RestoreMissingPages(fileReader, mockFile, postgres.DatabasePageSize, postgres.TruncatedPolicyIgnore)
 
 	assert.NoError(t, err, "Expected no errors after restoring missing pages")
 	mockFileReader := bytes.NewReader(mockFile.content)
@@ -363,6 +397,48 @@ func TestRestoringPagesToEmptyFile(t *testing.T) {
 	assert.Truef(t, compareResult, "Increment could not restore file")
 }
 
+// The base pages after the end of the truncated local file are handled according to the policy
+func TestRestoringPagesToTruncatedFile(t *testing.T) {
+	baseContent, _ := ioutil.ReadFile(pagedFileName)
+	truncatedSize := postgres.DatabasePageSize * (pagedFileBlockCount / 2)
+	restoreTruncated := func(policy postgres.TruncatedPolicy) (*MockReadWriterAt, error) {
+		mockFile := NewMockReadWriterAt(append([]byte{}, baseContent[:truncatedSize]...))
+		err := postgres.RestoreMissingPages(bytes.NewReader(baseContent), mockFile, postgres.DatabasePageSize, policy)
+		return mockFile, err
+	}
+
+	t.Run("ignore", func(t *testing.T) {
+		mockFile, err := restoreTruncated(postgres.TruncatedPolicyIgnore)
+		assert.NoError(t, err)
+		assert.Equal(t, baseContent[:truncatedSize], mockFile.content)
+		assert.Equal(t, 0, mockFile.bytesWritten)
+	})
+	t.Run("extend", func(t *testing.T) {
+		mockFile, err := restoreTruncated(postgres.TruncatedPolicyExtend)
+		assert.NoError(t, err)
+		assert.Equal(t, baseContent, mockFile.content)
+	})
+	t.Run("fail", func(t *testing.T) {
+		mockFile, err := restoreTruncated(postgres.TruncatedPolicyFail)
+		assert.IsType(t, postgres.FileTruncatedError{}, err)
+		assert.Equal(t, baseContent[:truncatedSize], mockFile.content)
+		assert.Equal(t, 0, mockFile.bytesWritten)
+	})
+}
+
+// The policy doesn't change the restored file which is not truncated
+func TestRestoringPagesToNotTruncatedFile(t *testing.T) {
+	baseContent, _ := ioutil.ReadFile(pagedFileName)
+	for _, policy := range []postgres.TruncatedPolicy{postgres.TruncatedPolicyIgnore,
+		postgres.TruncatedPolicyExtend, postgres.TruncatedPolicyFail} {
+		mockFile := NewMockReadWriterAt(append([]byte{}, baseContent...))
+		err := postgres.RestoreMissingPages(bytes.NewReader(baseContent), mockFile, postgres.DatabasePageSize, policy)
+		assert.NoError(t, err, policy)
+		assert.Equal(t, baseContent, mockFile.content, policy)
+		assert.Equal(t, 0, mockFile.bytesWritten, policy)
+	}
+}
+
 // Verify that all increment blocks exist in the resulting file
 // and that each block has been written to the right place
 func checkAllWrittenBlocksCorrect(mockFile *MockReadWriterAt, sourceFile io.ReaderAt,
@@ -506,3 +582,49 @@ func (mrw *MockReadWriterAt) Size() int64 {
 func (mrw *MockReadWriterAt) Name() string {
 	return "mock_file"
 }
+
+// makePageSizeIncrement makes the increment of the file of fileSize bytes holding the blocks of the page size,
+// each block is filled with its number + 1
+func makePageSizeIncrement(pageSize int64, fileSize uint64, blocks []uint32) []byte {
+	increment := &bytes.Buffer{}
+	increment.Write(postgres.IncrementFileHeader)
+	_ = binary.Write(increment, binary.LittleEndian, fileSize)
+	_ = binary.Write(increment, binary.LittleEndian, uint32(len(blocks)))
+	for _, blockNo := range blocks {
+		_ = binary.Write(increment, binary.LittleEndian, blockNo)
+	}
+	for _, blockNo := range blocks {
+		increment.Write(bytes.Repeat([]byte{byte(blockNo + 1)}, int(pageSize)))
+	}
+	increment.Write(postgres.IncrementFileFooter)
+	_ = binary.Write(increment, binary.LittleEndian, uint32(len(blocks)))
+	return increment.Bytes()
+}
+
+func TestCreatingFileFromIncrement_NonDefaultPageSize(t *testing.T) {
+	pageSize := int64(16384)
+	increment := makePageSizeIncrement(pageSize, uint64(3*pageSize), []uint32{1})
+	mockFile := NewMockReadWriterAt(make([]byte, 0))
+
+	missingBlockCount, err := postgres.CreateFileFromIncrement(bytes.NewReader(increment), mockFile, pageSize)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), missingBlockCount)
+	assert.Equal(t, 3*pageSize, int64(len(mockFile.content)))
+	assert.Equal(t, make([]byte, pageSize), mockFile.content[:pageSize])
+	assert.Equal(t, bytes.Repeat([]byte{2}, int(pageSize)), mockFile.content[pageSize:2*pageSize])
+	assert.Equal(t, make([]byte, pageSize), mockFile.content[2*pageSize:])
+}
+
+func TestWritingIncrement_NonDefaultPageSize(t *testing.T) {
+	pageSize := int64(16384)
+	increment := makePageSizeIncrement(pageSize, uint64(2*pageSize), []uint32{0, 1})
+	// the first block is missing, the second one is restored already
+	content := append(make([]byte, pageSize), bytes.Repeat([]byte{7}, int(pageSize))...)
+	mockFile := NewMockReadWriterAt(content)
+
+	restoredBlockCount, err := postgres.WritePagesFromIncrement(bytes.NewReader(increment), mockFile, pageSize, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), restoredBlockCount)
+	assert.Equal(t, bytes.Repeat([]byte{1}, int(pageSize)), mockFile.content[:pageSize])
+	assert.Equal(t, bytes.Repeat([]byte{7}, int(pageSize)), mockFile.content[pageSize:])
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const PgVersionFilename = "PG_VERSION"
+
+var pgCtlVersionRegexp = regexp.MustCompile(`(\d+)(?:\.(\d+))?`)
+
+type PgVersionMismatchError struct {
+	error
+}
+
+func newPgVersionMismatchError(source, actual, expected string) PgVersionMismatchError {
+	return PgVersionMismatchError{errors.Errorf(
+		"%s has PostgreSQL version %s, but version %s is expected", source, actual, expected)}
+}
+
+func (err PgVersionMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// PgMajorVersionString formats the numeric server version (e.g. 90624 or 130002)
+// the same way as the PG_VERSION file does (9.6 or 13)
+func PgMajorVersionString(pgVersion int) string {
+	if pgVersion >= 100000 {
+		return strconv.Itoa(pgVersion / 10000)
+	}
+	return fmt.Sprintf("%d.%d", pgVersion/10000, pgVersion/100%100)
+}
+
+// ParsePgCtlVersion extracts the major version from the `pg_ctl --version` output
+func ParsePgCtlVersion(output string) (string, error) {
+	match := pgCtlVersionRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return "", errors.Errorf("failed to parse PostgreSQL version from '%s'", strings.TrimSpace(output))
+	}
+	major, _ := strconv.Atoi(match[1])
+	if major >= 10 || match[2] == "" {
+		return match[1], nil
+	}
+	return match[1] + "." + match[2], nil
+}
+
+// PgVersionChecker validates the PostgreSQL version of the fetched backup against the expected one.
+// Mismatches are fatal if the version is specified explicitly,
+// if it is detected from pg_ctl on PATH, only warnings are logged, as for the failures of the check.
+type PgVersionChecker struct {
+	expected string
+	strict   bool
+}
+
+// NewPgVersionChecker returns nil if the expected version is not specified
+// and it can't be detected from pg_ctl on PATH
+func NewPgVersionChecker(expectedVersion string) (*PgVersionChecker, error) {
+	if expectedVersion != "" {
+		return &PgVersionChecker{expected: expectedVersion, strict: true}, nil
+	}
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		tracelog.DebugLogger.Println("pg_ctl is not found on PATH, skipping PostgreSQL version check")
+		return nil, nil
+	}
+	output, err := exec.Command(pgCtl, "--version").Output()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to run '%s --version', skipping PostgreSQL version check: %v\n",
+			pgCtl, err)
+		return nil, nil
+	}
+	version, err := ParsePgCtlVersion(string(output))
+	if err != nil {
+		tracelog.WarningLogger.Printf("Skipping PostgreSQL version check: %v\n", err)
+		return nil, nil
+	}
+	return &PgVersionChecker{expected: version}, nil
+}
+
+// CheckSentinel validates the version the backup was taken from
+func (checker *PgVersionChecker) CheckSentinel(backupName string, sentinelDto BackupSentinelDto) error {
+	if sentinelDto.PgVersion == 0 {
+		return nil
+	}
+	return checker.check("backup "+backupName, PgMajorVersionString(sentinelDto.PgVersion))
+}
+
+// CheckDataDirectory validates the PG_VERSION file of the restored data directory
+func (checker *PgVersionChecker) CheckDataDirectory(dbDataDirectory string) error {
+	versionPath := filepath.Join(dbDataDirectory, PgVersionFilename)
+	content, err := ioutil.ReadFile(versionPath)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to read %s", versionPath)
+		if checker.strict {
+			return err
+		}
+		tracelog.WarningLogger.Printf("Skipping PostgreSQL version check of the restored data directory: %v\n", err)
+		return nil
+	}
+	return checker.check("restored data directory", strings.TrimSpace(string(content)))
+}
+
+func (checker *PgVersionChecker) check(source, actual string) error {
+	if actual == checker.expected {
+		return nil
+	}
+	err := newPgVersionMismatchError(source, actual, checker.expected)
+	if checker.strict {
+		return err
+	}
+	tracelog.WarningLogger.Printf("%v (detected from pg_ctl on PATH)\n", err)
+	return nil
+}
+
+// HandlePgVersionCheckBeforeFetch validates the backup version before the extraction starts
+func HandlePgVersionCheckBeforeFetch(rootFolder storage.Folder, backup internal.Backup, checker *PgVersionChecker) {
+	pgBackup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name)
+	sentinelDto, err := pgBackup.GetSentinel()
+	internal.FatalfOnError("Failed to fetch backup sentinel: %v\n", err)
+	internal.FatalOnError(checker.CheckSentinel(backup.Name, sentinelDto))
+}
+
+// HandlePgVersionCheckAfterFetch validates the PG_VERSION of the restored data directory
+func HandlePgVersionCheckAfterFetch(dbDataDirectory string, checker *PgVersionChecker) {
+	internal.FatalOnError(checker.CheckDataDirectory(utility.ResolveSymlink(dbDataDirectory)))
+}
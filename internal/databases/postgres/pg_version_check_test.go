@@ -0,0 +1,82 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestPgMajorVersionString(t *testing.T) {
+	assert.Equal(t, "9.6", postgres.PgMajorVersionString(90624))
+	assert.Equal(t, "13", postgres.PgMajorVersionString(130002))
+}
+
+func TestParsePgCtlVersion(t *testing.T) {
+	version, err := postgres.ParsePgCtlVersion("pg_ctl (PostgreSQL) 13.2\n")
+	require.NoError(t, err)
+	assert.Equal(t, "13", version)
+
+	version, err = postgres.ParsePgCtlVersion("pg_ctl (PostgreSQL) 9.6.21\n")
+	require.NoError(t, err)
+	assert.Equal(t, "9.6", version)
+
+	_, err = postgres.ParsePgCtlVersion("pg_ctl")
+	assert.Error(t, err)
+}
+
+func TestPgVersionChecker(t *testing.T) {
+	checker, err := postgres.NewPgVersionChecker("13")
+	require.NoError(t, err)
+
+	assert.NoError(t, checker.CheckSentinel("base_000000010000000000000002", postgres.BackupSentinelDto{PgVersion: 130002}))
+	err = checker.CheckSentinel("base_000000010000000000000002", postgres.BackupSentinelDto{PgVersion: 120005})
+	assert.IsType(t, postgres.PgVersionMismatchError{}, err)
+
+	dir, err := ioutil.TempDir("", "pg_version")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, postgres.PgVersionFilename), []byte("12\n"), 0600))
+	err = checker.CheckDataDirectory(dir)
+	assert.IsType(t, postgres.PgVersionMismatchError{}, err)
+}
+
+// withPgCtl puts the pg_ctl script into PATH
+func withPgCtl(t *testing.T, dir, script string) func() {
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pg_ctl"), []byte("#!/bin/sh\n"+script+"\n"), 0700))
+	path := os.Getenv("PATH")
+	require.NoError(t, os.Setenv("PATH", dir))
+	return func() { _ = os.Setenv("PATH", path) }
+}
+
+func TestPgVersionChecker_DetectedFromPgCtl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pg_ctl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the failures of the detection don't fail the fetch
+	for _, script := range []string{"exit 1", "echo pg_ctl"} {
+		restorePath := withPgCtl(t, dir, script)
+		checker, err := postgres.NewPgVersionChecker("")
+		restorePath()
+		assert.NoError(t, err, script)
+		assert.Nil(t, checker, script)
+	}
+
+	restorePath := withPgCtl(t, dir, "echo 'pg_ctl (PostgreSQL) 13.2'")
+	checker, err := postgres.NewPgVersionChecker("")
+	restorePath()
+	require.NoError(t, err)
+	require.NotNil(t, checker)
+	// the mismatches and the failures of the detected version check are only logged
+	assert.NoError(t, checker.CheckSentinel("base_000000010000000000000002", postgres.BackupSentinelDto{PgVersion: 120005}))
+	assert.NoError(t, checker.CheckDataDirectory(dir))
+
+	checker, err = postgres.NewPgVersionChecker("13")
+	require.NoError(t, err)
+	assert.Error(t, checker.CheckDataDirectory(dir))
+}
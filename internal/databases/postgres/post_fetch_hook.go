@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	PostFetchHookName = "post-fetch hook"
+	// the environment variables passed to the post-fetch hook command
+	PostFetchHookDirectoryEnv  = "WALG_FETCH_DIRECTORY"
+	PostFetchHookBackupNameEnv = "WALG_FETCH_BACKUP_NAME"
+)
+
+type PostFetchHookError struct {
+	error
+}
+
+func newPostFetchHookError(err error) PostFetchHookError {
+	return PostFetchHookError{errors.Wrapf(err, "%s failed", PostFetchHookName)}
+}
+
+func (err PostFetchHookError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetPostFetchHook returns the command run after the successful fetch: the backup-fetch --post-extract-hook
+// command overrides WALG_POST_FETCH_HOOK, so only one of them is run. It is empty if neither is set.
+func GetPostFetchHook(postExtractHook string) string {
+	if postExtractHook != "" {
+		return postExtractHook
+	}
+	hook, _ := internal.GetSetting(internal.PostFetchHookSetting)
+	return hook
+}
+
+// RunPostFetchHook runs the post-fetch hook command with the restored directory and the backup name
+// in the environment. The output of the command is logged, the non-zero exit status is returned as the error.
+func RunPostFetchHook(commandLine, backupName, dbDataDirectory string) error {
+	cmd := internal.NewShellCommandContext(context.Background(), commandLine)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", PostFetchHookDirectoryEnv, dbDataDirectory),
+		fmt.Sprintf("%s=%s", PostFetchHookBackupNameEnv, backupName))
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	tracelog.InfoLogger.Printf("Running the %s for backup %s\n", PostFetchHookName, backupName)
+	err := cmd.Run()
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		tracelog.InfoLogger.Printf("%s: %s\n", PostFetchHookName, scanner.Text())
+	}
+	if err != nil {
+		return newPostFetchHookError(err)
+	}
+	return nil
+}
+
+// HandlePostFetchHook runs the post-fetch hook command after the successful fetch
+func HandlePostFetchHook(backup internal.Backup, dbDataDirectory, commandLine string) {
+	err := RunPostFetchHook(commandLine, backup.Name, utility.ResolveSymlink(dbDataDirectory))
+	internal.FatalOnError(err)
+}
@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestRunPostFetchHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "post_fetch_hook")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	commandLine := `echo "$WALG_FETCH_BACKUP_NAME" > "$WALG_FETCH_DIRECTORY/hook"`
+	require.NoError(t, postgres.RunPostFetchHook(commandLine, "base_000000010000000000000002", dir))
+	content, err := ioutil.ReadFile(filepath.Join(dir, "hook"))
+	require.NoError(t, err)
+	assert.Equal(t, "base_000000010000000000000002\n", string(content))
+
+	err = postgres.RunPostFetchHook("echo verification failed; exit 3", "base_000000010000000000000002", dir)
+	assert.IsType(t, postgres.PostFetchHookError{}, err)
+}
+
+func TestGetPostFetchHook(t *testing.T) {
+	defer viper.Set(internal.PostFetchHookSetting, "")
+
+	viper.Set(internal.PostFetchHookSetting, "")
+	assert.Empty(t, postgres.GetPostFetchHook(""))
+
+	viper.Set(internal.PostFetchHookSetting, `pg_verifybackup "$WALG_FETCH_DIRECTORY"`)
+	assert.Equal(t, `pg_verifybackup "$WALG_FETCH_DIRECTORY"`, postgres.GetPostFetchHook(""))
+	// the --post-extract-hook command is run instead of WALG_POST_FETCH_HOOK, not after it
+	assert.Equal(t, "zfs snapshot tank/restore@restored", postgres.GetPostFetchHook("zfs snapshot tank/restore@restored"))
+}
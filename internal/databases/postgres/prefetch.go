@@ -30,7 +30,7 @@ func HandleWALPrefetch(uploader *WalUploader, walFileName string, location strin
 	location = path.Dir(location)
 	waitGroup := &sync.WaitGroup{}
 	concurrency, err := internal.GetMaxDownloadConcurrency()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	for i := 0; i < concurrency; i++ {
 		fileName, err = GetNextWalFilename(fileName)
@@ -89,9 +89,9 @@ func prefaultData(prefaultStartLsn uint64, timelineID uint32, waitGroup *sync.Wa
 	}
 	tracelog.InfoLogger.Println("Walking for prefault...")
 	err = filepath.Walk(archiveDirectory, bundle.prefaultWalkedFSObject)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	err = bundle.FinishQueue()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 // TODO : unit tests
@@ -240,7 +240,7 @@ func forkPrefetch(walFileName string, location string) {
 		tracelog.ErrorLogger.Println("WAL-prefetch failed: ", err)
 	}
 	if strings.Contains(walFileName, "history") ||
-		strings.Contains(walFileName, "partial") ||
+		strings.Contains(walFileName, PartialWalFileSuffix) ||
 		concurrency == 1 {
 		return // There will be nothing ot prefetch anyway
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/jackc/pgx"
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/tracing"
 	"github.com/wal-g/wal-g/internal/walparser"
 )
 
@@ -60,6 +61,31 @@ type PgQueryRunner struct {
 	Connection       *pgx.Conn
 	Version          int
 	SystemIdentifier *uint64
+	// NoWaitForWal makes the stop backup query return without waiting for the WAL required by the backup
+	// to be archived
+	NoWaitForWal bool
+
+	// statisticsConnection runs the heavy statistics queries apart from the backup coordination
+	statisticsConnection *pgx.Conn
+}
+
+type PgQueryRunnerOption func(queryRunner *PgQueryRunner)
+
+// WithStatisticsConnection makes the query runner use the separate connection for the statistics queries.
+// The caller owns the connection and closes it when done.
+func WithStatisticsConnection(conn *pgx.Conn) PgQueryRunnerOption {
+	return func(queryRunner *PgQueryRunner) {
+		queryRunner.statisticsConnection = conn
+	}
+}
+
+// getStatisticsConnection returns the connection for the statistics queries,
+// the main one is used if no separate connection is given
+func (queryRunner *PgQueryRunner) getStatisticsConnection() *pgx.Conn {
+	if queryRunner.statisticsConnection != nil {
+		return queryRunner.statisticsConnection
+	}
+	return queryRunner.Connection
 }
 
 // BuildGetVersion formats a query to retrieve PostgreSQL numeric version
@@ -83,23 +109,45 @@ func (queryRunner *PgQueryRunner) buildGetCurrentLsn() string {
 		"END"
 }
 
-// BuildStartBackup formats a query that starts backup according to server features and version
+// backupStartFunction is the name of the function which starts the backup,
+// it is renamed in 15 along with the removal of the exclusive backups
+func (queryRunner *PgQueryRunner) backupStartFunction() string {
+	if queryRunner.Version >= 150000 {
+		return "pg_backup_start"
+	}
+	return "pg_start_backup"
+}
+
+// backupStopFunction is the name of the function which stops the backup
+func (queryRunner *PgQueryRunner) backupStopFunction() string {
+	if queryRunner.Version >= 150000 {
+		return "pg_backup_stop"
+	}
+	return "pg_stop_backup"
+}
+
+// BuildStartBackup formats a query that starts backup according to server features and version.
+// Since 15 the backups are always non-exclusive, so pg_backup_start() has no exclusive argument.
 func (queryRunner *PgQueryRunner) BuildStartBackup() (string, error) {
 	// TODO: rewrite queries for older versions to remove pg_is_in_recovery()
 	// where pg_start_backup() will fail on standby anyway
 	switch {
+	case queryRunner.Version >= 150000:
+		return "SELECT case when pg_is_in_recovery()" +
+			" then '' else (pg_walfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery()" +
+			" FROM pg_backup_start($1, $2) lsn", nil
 	case queryRunner.Version >= 100000:
 		return "SELECT case when pg_is_in_recovery()" +
 			" then '' else (pg_walfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery()" +
-			" FROM pg_start_backup($1, true, false) lsn", nil
+			" FROM pg_start_backup($1, $2, false) lsn", nil
 	case queryRunner.Version >= 90600:
 		return "SELECT case when pg_is_in_recovery() " +
 			"then '' else (pg_xlogfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery()" +
-			" FROM pg_start_backup($1, true, false) lsn", nil
+			" FROM pg_start_backup($1, $2, false) lsn", nil
 	case queryRunner.Version >= 90000:
 		return "SELECT case when pg_is_in_recovery() " +
 			"then '' else (pg_xlogfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery()" +
-			" FROM pg_start_backup($1, true) lsn", nil
+			" FROM pg_start_backup($1, $2) lsn", nil
 	case queryRunner.Version == 0:
 		return "", newNoPostgresVersionError()
 	default:
@@ -107,9 +155,15 @@ func (queryRunner *PgQueryRunner) BuildStartBackup() (string, error) {
 	}
 }
 
-// BuildStopBackup formats a query that stops backup according to server features and version
+// BuildStopBackup formats a query that stops backup according to server features and version.
+// The backup stop waits for the WAL archival unless NoWaitForWal is set, as pg_stop_backup() does by default.
+// The versions before 10 can't be asked not to wait, they always wait for the WAL archival, if it is enabled.
 func (queryRunner *PgQueryRunner) BuildStopBackup() (string, error) {
 	switch {
+	case queryRunner.Version >= 150000:
+		return fmt.Sprintf("SELECT labelfile, spcmapfile, lsn FROM pg_backup_stop(%t)", !queryRunner.NoWaitForWal), nil
+	case queryRunner.Version >= 100000 && queryRunner.NoWaitForWal:
+		return "SELECT labelfile, spcmapfile, lsn FROM pg_stop_backup(false, false)", nil
 	case queryRunner.Version >= 90600:
 		return "SELECT labelfile, spcmapfile, lsn FROM pg_stop_backup(false)", nil
 	case queryRunner.Version >= 90000:
@@ -124,8 +178,11 @@ func (queryRunner *PgQueryRunner) BuildStopBackup() (string, error) {
 }
 
 // NewPgQueryRunner builds QueryRunner from available connection
-func NewPgQueryRunner(conn *pgx.Conn) (*PgQueryRunner, error) {
+func NewPgQueryRunner(conn *pgx.Conn, options ...PgQueryRunnerOption) (*PgQueryRunner, error) {
 	r := &PgQueryRunner{Connection: conn}
+	for _, option := range options {
+		option(r)
+	}
 	err := r.getVersion()
 	if err != nil {
 		return nil, err
@@ -184,17 +241,21 @@ func (queryRunner *PgQueryRunner) getSystemIdentifier() (err error) {
 }
 
 // StartBackup informs the database that we are starting copy of cluster contents
-func (queryRunner *PgQueryRunner) startBackup(backup string) (backupName string,
+// If fastCheckpoint is false, the checkpoint at the backup start is spread over time
+func (queryRunner *PgQueryRunner) startBackup(backup string, fastCheckpoint bool) (backupName string,
 	lsnString string, inRecovery bool, err error) {
-	tracelog.InfoLogger.Println("Calling pg_start_backup()")
+	tracelog.InfoLogger.Printf("Calling %s()\n", queryRunner.backupStartFunction())
+	span := tracing.Start(queryRunner.backupStartFunction())
+	defer func() { tracing.End(span, err) }()
 	startBackupQuery, err := queryRunner.BuildStartBackup()
 	conn := queryRunner.Connection
 	if err != nil {
 		return "", "", false, errors.Wrap(err, "QueryRunner StartBackup: Building start backup query failed")
 	}
 
-	if err = conn.QueryRow(startBackupQuery, backup).Scan(&backupName, &lsnString, &inRecovery); err != nil {
-		return "", "", false, errors.Wrap(err, "QueryRunner StartBackup: pg_start_backup() failed")
+	if err = conn.QueryRow(startBackupQuery, backup, fastCheckpoint).Scan(&backupName, &lsnString, &inRecovery); err != nil {
+		return "", "", false, errors.Wrapf(err, "QueryRunner StartBackup: %s() failed",
+			queryRunner.backupStartFunction())
 	}
 
 	return backupName, lsnString, inRecovery, nil
@@ -202,7 +263,9 @@ func (queryRunner *PgQueryRunner) startBackup(backup string) (backupName string,
 
 // StopBackup informs the database that copy is over
 func (queryRunner *PgQueryRunner) stopBackup() (label string, offsetMap string, lsnStr string, err error) {
-	tracelog.InfoLogger.Println("Calling pg_stop_backup()")
+	tracelog.InfoLogger.Printf("Calling %s()\n", queryRunner.backupStopFunction())
+	span := tracing.Start(queryRunner.backupStopFunction())
+	defer func() { tracing.End(span, err) }()
 	conn := queryRunner.Connection
 
 	tx, err := conn.Begin()
@@ -223,6 +286,12 @@ func (queryRunner *PgQueryRunner) stopBackup() (label string, offsetMap string,
 	if err != nil {
 		return "", "", "", errors.Wrap(err, "QueryRunner StopBackup: Building stop backup query failed")
 	}
+	if !queryRunner.NoWaitForWal {
+		tracelog.InfoLogger.Println("Waiting for the WAL required by the backup to be archived")
+	} else if queryRunner.Version < 100000 {
+		tracelog.WarningLogger.Printf("Postgres %d can't be asked not to wait for the WAL archival, "+
+			"it waits if the archiving is enabled\n", queryRunner.Version)
+	}
 
 	err = tx.QueryRow(stopBackupQuery).Scan(&label, &offsetMap, &lsnStr)
 	if err != nil {
@@ -237,6 +306,32 @@ func (queryRunner *PgQueryRunner) stopBackup() (label string, offsetMap string,
 	return label, offsetMap, lsnStr, nil
 }
 
+// BuildAbortExclusiveBackup formats a query that stops the exclusive backup of the interrupted backup-push.
+// Since 9.6 the backups are non-exclusive and are aborted by the server when the session ends.
+func (queryRunner *PgQueryRunner) BuildAbortExclusiveBackup() (string, error) {
+	switch {
+	case queryRunner.Version >= 90600:
+		return "", errors.Errorf("Backup started on version %d is non-exclusive", queryRunner.Version)
+	case queryRunner.Version >= 90000:
+		return "SELECT pg_stop_backup()", nil
+	case queryRunner.Version == 0:
+		return "", newNoPostgresVersionError()
+	default:
+		return "", newUnsupportedPostgresVersionError(queryRunner.Version)
+	}
+}
+
+// abortExclusiveBackup takes the server out of the backup mode without finishing the backup
+func (queryRunner *PgQueryRunner) abortExclusiveBackup() error {
+	tracelog.InfoLogger.Println("Calling pg_stop_backup() to abort the backup")
+	abortBackupQuery, err := queryRunner.BuildAbortExclusiveBackup()
+	if err != nil {
+		return errors.Wrap(err, "QueryRunner AbortExclusiveBackup: Building abort backup query failed")
+	}
+	_, err = queryRunner.Connection.Exec(abortBackupQuery)
+	return errors.Wrap(err, "QueryRunner AbortExclusiveBackup: abort backup failed")
+}
+
 // BuildStatisticsQuery formats a query that fetch relations statistics from database
 func (queryRunner *PgQueryRunner) BuildStatisticsQuery() (string, error) {
 	switch {
@@ -259,7 +354,7 @@ func (queryRunner *PgQueryRunner) getStatistics(
 	dbInfo PgDatabaseInfo) (map[walparser.RelFileNode]PgRelationStat, error) {
 	tracelog.InfoLogger.Println("Querying pg_stat_all_tables")
 	getStatQuery, err := queryRunner.BuildStatisticsQuery()
-	conn := queryRunner.Connection
+	conn := queryRunner.getStatisticsConnection()
 	if err != nil {
 		return nil, errors.Wrap(err, "QueryRunner GetStatistics: Building get statistics query failed")
 	}
@@ -311,7 +406,7 @@ func (queryRunner *PgQueryRunner) BuildGetDatabasesQuery() (string, error) {
 func (queryRunner *PgQueryRunner) getDatabaseInfos() ([]PgDatabaseInfo, error) {
 	tracelog.InfoLogger.Println("Querying pg_database")
 	getDBInfoQuery, err := queryRunner.BuildGetDatabasesQuery()
-	conn := queryRunner.Connection
+	conn := queryRunner.getStatisticsConnection()
 	if err != nil {
 		return nil, errors.Wrap(err, "QueryRunner GetDatabases: Building db names query failed")
 	}
@@ -358,15 +453,30 @@ func (queryRunner *PgQueryRunner) GetWalSegmentBytes() (segBlocks uint64, err er
 	if err != nil {
 		return 0, err
 	}
-	segBlocks, err = strconv.ParseUint(strValue, 10, 64)
+	return parseWalSegmentBytes(strValue, queryRunner.Version)
+}
+
+// GetPageSize reads the block_size the server is compiled with
+// TODO: Unittest
+func (queryRunner *PgQueryRunner) GetPageSize() (uint64, error) {
+	value, err := queryRunner.GetParameter("block_size")
 	if err != nil {
 		return 0, err
 	}
-	if queryRunner.Version < 110000 {
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// parseWalSegmentBytes converts the wal_segment_size setting value to bytes
+func parseWalSegmentBytes(value string, pgVersion int) (uint64, error) {
+	segBlocks, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if pgVersion < 110000 {
 		// For PG 10 and below, wal_segment_size is in 8k blocks
 		segBlocks *= 8192
 	}
-	return
+	return segBlocks, nil
 }
 
 // GetDataDir reads the wals segment size (in bytes) and converts it to uint64
@@ -394,6 +504,84 @@ func (queryRunner *PgQueryRunner) GetPhysicalSlotInfo(slotName string) (Physical
 	return NewPhysicalSlot(slotName, true, active, restartLSN)
 }
 
+// BuildGetReplicationSlotsQuery formats a query to get the definitions of the replication slots.
+// The temporary slots, since 10, are dropped at the end of their session, so they are skipped.
+func (queryRunner *PgQueryRunner) BuildGetReplicationSlotsQuery() (string, error) {
+	switch {
+	case queryRunner.Version >= 100000:
+		return "SELECT slot_name, slot_type, coalesce(plugin, ''), coalesce(database, ''), " +
+			"coalesce(restart_lsn::text, '') FROM pg_replication_slots WHERE NOT temporary", nil
+	case queryRunner.Version >= 90400:
+		return "SELECT slot_name, slot_type, coalesce(plugin, ''), coalesce(database, ''), " +
+			"coalesce(restart_lsn::text, '') FROM pg_replication_slots", nil
+	case queryRunner.Version == 0:
+		return "", newNoPostgresVersionError()
+	default:
+		return "", newUnsupportedPostgresVersionError(queryRunner.Version)
+	}
+}
+
+// GetReplicationSlots reads the definitions of all replication slots
+func (queryRunner *PgQueryRunner) GetReplicationSlots() ([]ReplicationSlotDto, error) {
+	getSlotsQuery, err := queryRunner.BuildGetReplicationSlotsQuery()
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryRunner GetReplicationSlots: Building slots query failed")
+	}
+
+	rows, err := queryRunner.Connection.Query(getSlotsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryRunner GetReplicationSlots: Query failed")
+	}
+	defer rows.Close()
+
+	slots := make([]ReplicationSlotDto, 0)
+	for rows.Next() {
+		var slot ReplicationSlotDto
+		if err := rows.Scan(&slot.Name, &slot.Type, &slot.Plugin, &slot.Database, &slot.RestartLSN); err != nil {
+			return nil, errors.Wrap(err, "QueryRunner GetReplicationSlots: Scan failed")
+		}
+		slots = append(slots, slot)
+	}
+	return slots, rows.Err()
+}
+
+// BuildCreateReplicationSlotQuery formats a query to create the replication slot.
+// The query parameters are the slot name and, for a logical slot, the output plugin.
+func (queryRunner *PgQueryRunner) BuildCreateReplicationSlotQuery(slotType string) (string, error) {
+	if queryRunner.Version == 0 {
+		return "", newNoPostgresVersionError()
+	}
+	if queryRunner.Version < 90400 {
+		return "", newUnsupportedPostgresVersionError(queryRunner.Version)
+	}
+	switch slotType {
+	case PhysicalSlotType:
+		if queryRunner.Version >= 90600 {
+			// reserve WAL immediately, as the slot had it reserved in the backed up cluster
+			return "SELECT pg_create_physical_replication_slot($1, true)", nil
+		}
+		return "SELECT pg_create_physical_replication_slot($1)", nil
+	case LogicalSlotType:
+		return "SELECT pg_create_logical_replication_slot($1, $2)", nil
+	default:
+		return "", errors.Errorf("unknown replication slot type '%s'", slotType)
+	}
+}
+
+// createReplicationSlot creates the slot, a logical slot is created in the database of the connection
+func (queryRunner *PgQueryRunner) createReplicationSlot(slot ReplicationSlotDto) error {
+	createSlotQuery, err := queryRunner.BuildCreateReplicationSlotQuery(slot.Type)
+	if err != nil {
+		return errors.Wrap(err, "QueryRunner CreateReplicationSlot: Building create slot query failed")
+	}
+	if slot.Type == LogicalSlotType {
+		_, err = queryRunner.Connection.Exec(createSlotQuery, slot.Name, slot.Plugin)
+	} else {
+		_, err = queryRunner.Connection.Exec(createSlotQuery, slot.Name)
+	}
+	return errors.Wrapf(err, "QueryRunner CreateReplicationSlot: creating slot %s failed", slot.Name)
+}
+
 // tablespace map does not exist in < 9.6
 // TODO: Unittest
 func (queryRunner *PgQueryRunner) IsTablespaceMapExists() bool {
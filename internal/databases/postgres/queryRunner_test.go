@@ -20,15 +20,23 @@ func TestBuildStartBackup(t *testing.T) {
 
 	queryBuilder.Version = 90321
 	queryString, err := queryBuilder.BuildStartBackup()
-	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_xlogfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_start_backup($1, true) lsn", queryString)
+	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_xlogfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_start_backup($1, $2) lsn", queryString)
 
 	queryBuilder.Version = 90600
 	queryString, err = queryBuilder.BuildStartBackup()
-	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_xlogfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_start_backup($1, true, false) lsn", queryString)
+	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_xlogfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_start_backup($1, $2, false) lsn", queryString)
 
 	queryBuilder.Version = 100000
 	queryString, err = queryBuilder.BuildStartBackup()
-	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_walfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_start_backup($1, true, false) lsn", queryString)
+	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_walfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_start_backup($1, $2, false) lsn", queryString)
+
+	queryBuilder.Version = 150000
+	queryString, err = queryBuilder.BuildStartBackup()
+	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_walfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_backup_start($1, $2) lsn", queryString)
+
+	queryBuilder.Version = 160002
+	queryString, err = queryBuilder.BuildStartBackup()
+	assert.Equal(t, "SELECT case when pg_is_in_recovery() then '' else (pg_walfile_name_offset(lsn)).file_name end, lsn::text, pg_is_in_recovery() FROM pg_backup_start($1, $2) lsn", queryString)
 }
 
 // Tests building stop backup query
@@ -52,4 +60,93 @@ func TestBuildStopBackup(t *testing.T) {
 	queryBuilder.Version = 100000
 	queryString, err = queryBuilder.BuildStopBackup()
 	assert.Equal(t, "SELECT labelfile, spcmapfile, lsn FROM pg_stop_backup(false)", queryString)
+
+	queryBuilder.Version = 150000
+	queryString, err = queryBuilder.BuildStopBackup()
+	assert.Equal(t, "SELECT labelfile, spcmapfile, lsn FROM pg_backup_stop(true)", queryString)
+}
+
+// Tests building stop backup query not waiting for the WAL archival
+func TestBuildStopBackup_NoWaitForWal(t *testing.T) {
+	queryBuilder := &postgres.PgQueryRunner{Version: 90600, NoWaitForWal: true}
+	queryString, err := queryBuilder.BuildStopBackup()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT labelfile, spcmapfile, lsn FROM pg_stop_backup(false)", queryString)
+
+	queryBuilder.Version = 100000
+	queryString, err = queryBuilder.BuildStopBackup()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT labelfile, spcmapfile, lsn FROM pg_stop_backup(false, false)", queryString)
+
+	queryBuilder.Version = 150000
+	queryString, err = queryBuilder.BuildStopBackup()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT labelfile, spcmapfile, lsn FROM pg_backup_stop(false)", queryString)
+}
+
+// Tests building abort exclusive backup query
+func TestBuildAbortExclusiveBackup(t *testing.T) {
+	queryBuilder := &postgres.PgQueryRunner{Version: 0}
+	_, err := queryBuilder.BuildAbortExclusiveBackup()
+	assert.Error(t, err)
+
+	queryBuilder.Version = 90500
+	queryString, err := queryBuilder.BuildAbortExclusiveBackup()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT pg_stop_backup()", queryString)
+
+	// non-exclusive backups must not be stopped this way
+	queryBuilder.Version = 90600
+	_, err = queryBuilder.BuildAbortExclusiveBackup()
+	assert.Error(t, err)
+
+	// there are no exclusive backups since 15
+	queryBuilder.Version = 150000
+	_, err = queryBuilder.BuildAbortExclusiveBackup()
+	assert.Error(t, err)
+}
+
+// Tests building replication slots queries
+func TestBuildGetReplicationSlotsQuery(t *testing.T) {
+	queryBuilder := &postgres.PgQueryRunner{Version: 0}
+	_, err := queryBuilder.BuildGetReplicationSlotsQuery()
+	assert.Error(t, err)
+
+	queryBuilder.Version = 90300
+	_, err = queryBuilder.BuildGetReplicationSlotsQuery()
+	assert.Error(t, err)
+
+	queryBuilder.Version = 90600
+	queryString, err := queryBuilder.BuildGetReplicationSlotsQuery()
+	assert.NoError(t, err)
+	assert.Contains(t, queryString, "FROM pg_replication_slots")
+	assert.NotContains(t, queryString, "temporary")
+
+	queryBuilder.Version = 130000
+	queryString, err = queryBuilder.BuildGetReplicationSlotsQuery()
+	assert.NoError(t, err)
+	assert.Contains(t, queryString, "FROM pg_replication_slots WHERE NOT temporary")
+}
+
+func TestBuildCreateReplicationSlotQuery(t *testing.T) {
+	queryBuilder := &postgres.PgQueryRunner{Version: 90500}
+	queryString, err := queryBuilder.BuildCreateReplicationSlotQuery(postgres.PhysicalSlotType)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT pg_create_physical_replication_slot($1)", queryString)
+
+	queryBuilder.Version = 90600
+	queryString, err = queryBuilder.BuildCreateReplicationSlotQuery(postgres.PhysicalSlotType)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT pg_create_physical_replication_slot($1, true)", queryString)
+
+	queryString, err = queryBuilder.BuildCreateReplicationSlotQuery(postgres.LogicalSlotType)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT pg_create_logical_replication_slot($1, $2)", queryString)
+
+	_, err = queryBuilder.BuildCreateReplicationSlotQuery("unknown")
+	assert.Error(t, err)
+
+	queryBuilder.Version = 90300
+	_, err = queryBuilder.BuildCreateReplicationSlotQuery(postgres.PhysicalSlotType)
+	assert.Error(t, err)
 }
@@ -3,6 +3,9 @@ package postgres
 import (
 	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"sort"
 	"sync"
@@ -44,6 +47,8 @@ func (maker *RatingTarBallComposerMaker) Make(bundle *Bundle) (TarBallComposer,
 		filePacker)
 }
 
+const partitionKeyLength = 16
+
 type RatedComposeFileInfo struct {
 	ComposeFileInfo
 	updateRating uint64
@@ -69,10 +74,27 @@ func (collection *TarFilesCollection) AddFile(file *RatedComposeFileInfo) {
 	collection.expectedSize += file.expectedSize
 }
 
+// partitionKey identifies the collection by the names, sizes and modification times of its files
+// and whether they are packed as increments from incrementBaseLsn, so the unchanged collection is packed
+// to the partition of the same name when the backup is pushed again, and a partition left by an earlier push
+// of the changed files is never taken for the current one
+func (collection *TarFilesCollection) partitionKey(incrementBaseLsn *uint64) string {
+	keyHash := sha256.New()
+	if incrementBaseLsn != nil {
+		_, _ = fmt.Fprintf(keyHash, "%d\x00", *incrementBaseLsn)
+	}
+	for _, file := range collection.files {
+		_, _ = fmt.Fprintf(keyHash, "%s\x00%d\x00%d\x00%t\x00", file.header.Name, file.fileInfo.Size(),
+			file.fileInfo.ModTime().UnixNano(), file.isIncremented)
+	}
+	return hex.EncodeToString(keyHash.Sum(nil))[:partitionKeyLength]
+}
+
 // RatingTarBallComposer receives all files and tar headers
 // that are going to be written to the backup,
 // and composes the tarballs by placing the files
-// with similar update rating in the same tarballs
+// with similar update rating in the same tarballs.
+// The tarballs are named by their content, see TarFilesCollection.partitionKey.
 type RatingTarBallComposer struct {
 	filesToCompose         []*RatedComposeFileInfo
 	filesToComposeMutex    sync.Mutex
@@ -175,7 +197,8 @@ func (c *RatingTarBallComposer) PackTarballs() (TarFileSets, error) {
 
 	for _, tarFilesCollection := range tarFilesCollections {
 		tarBall := c.tarBallQueue.Deque()
-		tarBall.SetUp(c.crypter)
+		// the tarball holding the headers is set up already and keeps its name
+		tarBall.SetUp(c.crypter, internal.GetTarPartitionName(tarFilesCollection.partitionKey(c.incrementBaseLsn)))
 		for _, composeFileInfo := range tarFilesCollection.files {
 			tarFileSets[tarBall.Name()] = append(tarFileSets[tarBall.Name()], composeFileInfo.header.Name)
 		}
@@ -227,9 +250,14 @@ func (c *RatingTarBallComposer) addFile(cfi *ComposeFileInfo) error {
 	return nil
 }
 
+// sortFiles orders the files by the update rating and then by path. The files are added
+// by concurrent workers, so the path makes the composition of the unchanged cluster reproducible.
 func (c *RatingTarBallComposer) sortFiles() {
 	sort.Slice(c.filesToCompose, func(i, j int) bool {
-		return c.filesToCompose[i].updateRating < c.filesToCompose[j].updateRating
+		if c.filesToCompose[i].updateRating != c.filesToCompose[j].updateRating {
+			return c.filesToCompose[i].updateRating < c.filesToCompose[j].updateRating
+		}
+		return c.filesToCompose[i].path < c.filesToCompose[j].path
 	})
 }
 
@@ -284,7 +312,7 @@ func (c *RatingTarBallComposer) getExpectedFileSize(cfi *ComposeFileInfo) (uint6
 	incrementHeaderSize := uint64(len(IncrementFileHeader)) +
 		sizeofInt64 + sizeofInt32 + (incrementBlocksCount * sizeofInt32)
 	incrementPageDataSize := incrementBlocksCount * uint64(DatabasePageSize)
-	return incrementHeaderSize + incrementPageDataSize, nil
+	return incrementHeaderSize + incrementPageDataSize + incrementFileFooterSize, nil
 }
 
 func (c *RatingTarBallComposer) scanDeltaMapFor(filePath string, fileSize int64) error {
@@ -306,6 +334,7 @@ func (c *RatingTarBallComposer) writeHeaders(headers []*tar.Header) (string, []s
 	headersTarBall.SetUp(c.crypter)
 	headersNames := make([]string, 0, len(headers))
 	for _, header := range headers {
+		internal.SetTarHeaderFormat(header)
 		err := headersTarBall.TarWriter().WriteHeader(header)
 		headersNames = append(headersNames, header.Name)
 		if err != nil {
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pglogrepl"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	RecoveryTargetActionPause    = "pause"
+	RecoveryTargetActionPromote  = "promote"
+	RecoveryTargetActionShutdown = "shutdown"
+
+	RecoveryConfFilename   = "recovery.conf"
+	RecoverySignalFilename = "recovery.signal"
+	AutoConfFilename       = "postgresql.auto.conf"
+
+	// starting from PostgreSQL 12 recovery settings are regular GUCs
+	recoverySignalPgVersion = 120000
+)
+
+var RecoveryTargetActions = []string{RecoveryTargetActionPause, RecoveryTargetActionPromote, RecoveryTargetActionShutdown}
+
+// the value of the time setting: the integer with an optional unit, ms are assumed without the unit
+var recoveryApplyDelayRegexp = regexp.MustCompile(`^\d+\s*(us|ms|s|min|h|d)?$`)
+
+type InvalidRecoveryConfigError struct {
+	error
+}
+
+func newInvalidRecoveryConfigError(format string, args ...interface{}) InvalidRecoveryConfigError {
+	return InvalidRecoveryConfigError{errors.Errorf(format, args...)}
+}
+
+func (err InvalidRecoveryConfigError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RecoveryConfig describes the recovery settings written
+// to the restored data directory after the backup-fetch
+type RecoveryConfig struct {
+	TargetTime      string
+	TargetLsn       string
+	TargetInclusive *bool
+	TargetAction    string
+	ApplyDelay      string
+}
+
+// NewRecoveryConfig validates the recovery target options and the recovery_min_apply_delay.
+// Empty targetInclusive, targetAction and applyDelay mean the PostgreSQL defaults.
+func NewRecoveryConfig(targetTime, targetLsn, targetInclusive, targetAction,
+	applyDelay string) (*RecoveryConfig, error) {
+	config := &RecoveryConfig{TargetTime: targetTime, TargetLsn: targetLsn, TargetAction: targetAction,
+		ApplyDelay: strings.TrimSpace(applyDelay)}
+	if targetTime != "" && targetLsn != "" {
+		return nil, newInvalidRecoveryConfigError("recovery target time and target LSN are mutually exclusive")
+	}
+	if targetLsn != "" {
+		if _, err := pglogrepl.ParseLSN(targetLsn); err != nil {
+			return nil, newInvalidRecoveryConfigError("invalid recovery target LSN '%s': %v", targetLsn, err)
+		}
+	}
+	if targetInclusive != "" {
+		if !config.HasTarget() {
+			return nil, newInvalidRecoveryConfigError("recovery target inclusive requires target time or target LSN")
+		}
+		inclusive, err := strconv.ParseBool(targetInclusive)
+		if err != nil {
+			return nil, newInvalidRecoveryConfigError("invalid recovery target inclusive '%s'", targetInclusive)
+		}
+		config.TargetInclusive = &inclusive
+	}
+	if targetAction != "" {
+		if !config.HasTarget() {
+			return nil, newInvalidRecoveryConfigError("recovery target action requires target time or target LSN")
+		}
+		if !isRecoveryTargetAction(targetAction) {
+			return nil, newInvalidRecoveryConfigError("got incorrect recovery target action: '%s', expected one of: '%v'",
+				targetAction, RecoveryTargetActions)
+		}
+	}
+	if config.ApplyDelay != "" {
+		if !recoveryApplyDelayRegexp.MatchString(config.ApplyDelay) {
+			return nil, newInvalidRecoveryConfigError("invalid recovery apply delay '%s', "+
+				"expected an integer with an optional unit: us, ms, s, min, h, d", applyDelay)
+		}
+		tracelog.WarningLogger.Printf("recovery_min_apply_delay is set to '%s', the server lags behind "+
+			"the primary intentionally, so its promotion on failover takes longer to replay the delayed WAL\n",
+			config.ApplyDelay)
+	}
+	return config, nil
+}
+
+func isRecoveryTargetAction(action string) bool {
+	for _, targetAction := range RecoveryTargetActions {
+		if action == targetAction {
+			return true
+		}
+	}
+	return false
+}
+
+func (config *RecoveryConfig) HasTarget() bool {
+	return config.TargetTime != "" || config.TargetLsn != ""
+}
+
+// IsStandby reports whether the server is restored as the delayed standby:
+// the apply delay without a recovery target keeps it replaying the WAL instead of promoting at the end of it
+func (config *RecoveryConfig) IsStandby() bool {
+	return config.ApplyDelay != "" && !config.HasTarget()
+}
+
+// HasSettings reports whether there is anything besides the restore_command to write
+func (config *RecoveryConfig) HasSettings() bool {
+	return config.HasTarget() || config.ApplyDelay != ""
+}
+
+// Lines returns the recovery settings in the PostgreSQL configuration file format
+func (config *RecoveryConfig) Lines() []string {
+	restoreCommand := `wal-g wal-fetch "%f" "%p"`
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		restoreCommand = fmt.Sprintf(`wal-g --config %s wal-fetch "%%f" "%%p"`, configFile)
+	}
+	lines := []string{formatRecoverySetting("restore_command", restoreCommand)}
+	if config.TargetTime != "" {
+		lines = append(lines, formatRecoverySetting("recovery_target_time", config.TargetTime))
+	}
+	if config.TargetLsn != "" {
+		lines = append(lines, formatRecoverySetting("recovery_target_lsn", config.TargetLsn))
+	}
+	if config.TargetInclusive != nil {
+		lines = append(lines, formatRecoverySetting("recovery_target_inclusive", strconv.FormatBool(*config.TargetInclusive)))
+	}
+	if config.TargetAction != "" {
+		lines = append(lines, formatRecoverySetting("recovery_target_action", config.TargetAction))
+	}
+	if config.ApplyDelay != "" {
+		lines = append(lines, formatRecoverySetting("recovery_min_apply_delay", config.ApplyDelay))
+	}
+	return lines
+}
+
+func formatRecoverySetting(name, value string) string {
+	return fmt.Sprintf("%s = '%s'", name, strings.ReplaceAll(value, "'", "''"))
+}
+
+// Write writes the recovery settings to the data directory: recovery.conf before
+// PostgreSQL 12, postgresql.auto.conf and recovery.signal starting from PostgreSQL 12.
+// The standby is configured with standby_mode in recovery.conf or with standby.signal.
+func (config *RecoveryConfig) Write(dbDataDirectory string, pgVersion int) error {
+	content := strings.Join(config.Lines(), "\n") + "\n"
+	if pgVersion > 0 && pgVersion < recoverySignalPgVersion {
+		if config.IsStandby() {
+			content += formatRecoverySetting("standby_mode", "on") + "\n"
+		}
+		confPath := filepath.Join(dbDataDirectory, RecoveryConfFilename)
+		tracelog.InfoLogger.Printf("Writing recovery settings to %s\n", confPath)
+		return errors.Wrapf(ioutil.WriteFile(confPath, []byte(content), 0600), "failed to write '%s'", confPath)
+	}
+
+	autoConfPath := filepath.Join(dbDataDirectory, AutoConfFilename)
+	tracelog.InfoLogger.Printf("Writing recovery settings to %s\n", autoConfPath)
+	autoConf, err := os.OpenFile(autoConfPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open '%s'", autoConfPath)
+	}
+	defer utility.LoggedClose(autoConf, "")
+	if _, err = autoConf.WriteString("\n# recovery settings added by wal-g backup-fetch\n" + content); err != nil {
+		return errors.Wrapf(err, "failed to write '%s'", autoConfPath)
+	}
+
+	signalPath := filepath.Join(dbDataDirectory, RecoverySignalFilename)
+	if config.IsStandby() {
+		signalPath = filepath.Join(dbDataDirectory, StandbySignalFilename)
+	}
+	return errors.Wrapf(ioutil.WriteFile(signalPath, nil, 0600), "failed to create '%s'", signalPath)
+}
+
+// HandleRecoveryConfigWrite writes the recovery settings for the fetched backup
+func HandleRecoveryConfigWrite(rootFolder storage.Folder, backup internal.Backup,
+	dbDataDirectory string, config *RecoveryConfig) {
+	pgBackup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name)
+	sentinelDto, err := pgBackup.GetSentinel()
+	internal.FatalfOnError("Failed to fetch backup sentinel: %v\n", err)
+	err = config.Write(utility.ResolveSymlink(dbDataDirectory), sentinelDto.PgVersion)
+	internal.FatalfOnError("Failed to write recovery config: %v\n", err)
+}
@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestNewRecoveryConfig_Validation(t *testing.T) {
+	_, err := postgres.NewRecoveryConfig("2021-03-01 12:00:00+00", "0/2A000028", "", "", "")
+	assert.IsType(t, postgres.InvalidRecoveryConfigError{}, err)
+
+	_, err = postgres.NewRecoveryConfig("", "not an lsn", "", "", "")
+	assert.IsType(t, postgres.InvalidRecoveryConfigError{}, err)
+
+	_, err = postgres.NewRecoveryConfig("", "", "true", "", "")
+	assert.IsType(t, postgres.InvalidRecoveryConfigError{}, err)
+
+	_, err = postgres.NewRecoveryConfig("", "0/2A000028", "maybe", "", "")
+	assert.IsType(t, postgres.InvalidRecoveryConfigError{}, err)
+
+	_, err = postgres.NewRecoveryConfig("", "0/2A000028", "", "restart", "")
+	assert.IsType(t, postgres.InvalidRecoveryConfigError{}, err)
+
+	_, err = postgres.NewRecoveryConfig("", "", "", "", "1 hour")
+	assert.IsType(t, postgres.InvalidRecoveryConfigError{}, err)
+
+	config, err := postgres.NewRecoveryConfig("", "", "", "", "")
+	require.NoError(t, err)
+	assert.False(t, config.HasTarget())
+	assert.False(t, config.HasSettings())
+
+	config, err = postgres.NewRecoveryConfig("", "", "", "", "30min")
+	require.NoError(t, err)
+	assert.False(t, config.HasTarget())
+	assert.True(t, config.HasSettings())
+}
+
+func TestRecoveryConfig_LinesWithApplyDelay(t *testing.T) {
+	config, err := postgres.NewRecoveryConfig("2021-03-01 12:00:00+00", "", "", "", "1h")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`restore_command = 'wal-g wal-fetch "%f" "%p"'`,
+		"recovery_target_time = '2021-03-01 12:00:00+00'",
+		"recovery_min_apply_delay = '1h'",
+	}, config.Lines())
+}
+
+func TestRecoveryConfig_Lines(t *testing.T) {
+	config, err := postgres.NewRecoveryConfig("", "0/2A000028", "false", postgres.RecoveryTargetActionPromote, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`restore_command = 'wal-g wal-fetch "%f" "%p"'`,
+		"recovery_target_lsn = '0/2A000028'",
+		"recovery_target_inclusive = 'false'",
+		"recovery_target_action = 'promote'",
+	}, config.Lines())
+}
+
+func TestRecoveryConfig_Write(t *testing.T) {
+	config, err := postgres.NewRecoveryConfig("2021-03-01 12:00:00+00", "", "", "", "")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "recovery_config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, config.Write(dir, 130002))
+	autoConf, err := ioutil.ReadFile(filepath.Join(dir, postgres.AutoConfFilename))
+	require.NoError(t, err)
+	assert.Contains(t, string(autoConf), "recovery_target_time = '2021-03-01 12:00:00+00'")
+	_, err = os.Stat(filepath.Join(dir, postgres.RecoverySignalFilename))
+	assert.NoError(t, err)
+
+	require.NoError(t, config.Write(dir, 110005))
+	recoveryConf, err := ioutil.ReadFile(filepath.Join(dir, postgres.RecoveryConfFilename))
+	require.NoError(t, err)
+	assert.Contains(t, string(recoveryConf), "recovery_target_time = '2021-03-01 12:00:00+00'")
+}
+
+func TestRecoveryConfig_WriteStandby(t *testing.T) {
+	config, err := postgres.NewRecoveryConfig("", "", "", "", "1h")
+	require.NoError(t, err)
+	assert.True(t, config.IsStandby())
+
+	dir, err := ioutil.TempDir("", "recovery_config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, config.Write(dir, 130002))
+	assert.FileExists(t, filepath.Join(dir, postgres.StandbySignalFilename))
+	assert.NoFileExists(t, filepath.Join(dir, postgres.RecoverySignalFilename))
+
+	require.NoError(t, config.Write(dir, 110005))
+	recoveryConf, err := ioutil.ReadFile(filepath.Join(dir, postgres.RecoveryConfFilename))
+	require.NoError(t, err)
+	assert.Contains(t, string(recoveryConf), "recovery_min_apply_delay = '1h'")
+	assert.Contains(t, string(recoveryConf), "standby_mode = 'on'")
+
+	// the apply delay with a recovery target is the delayed point-in-time recovery
+	config, err = postgres.NewRecoveryConfig("2021-03-01 12:00:00+00", "", "", "", "1h")
+	require.NoError(t, err)
+	assert.False(t, config.IsStandby())
+}
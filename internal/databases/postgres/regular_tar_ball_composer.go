@@ -79,6 +79,7 @@ func (c *RegularTarBallComposer) AddHeader(fileInfoHeader *tar.Header, info os.F
 	defer c.tarBallQueue.EnqueueBack(tarBall)
 	c.tarFileSets[tarBall.Name()] = append(c.tarFileSets[tarBall.Name()], fileInfoHeader.Name)
 	c.files.AddFile(fileInfoHeader, info, false)
+	internal.SetTarHeaderFormat(fileInfoHeader)
 	return tarBall.TarWriter().WriteHeader(fileInfoHeader)
 }
 
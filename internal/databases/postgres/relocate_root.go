@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type PathOutsideRelocationRootError struct {
+	error
+}
+
+func newPathOutsideRelocationRootError(path, root string) PathOutsideRelocationRootError {
+	return PathOutsideRelocationRootError{errors.Errorf("Path %s is outside of the relocation root %s", path, root)}
+}
+
+func (err PathOutsideRelocationRootError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// RelocatePath moves the absolute path of the backed up cluster under the root,
+// e.g. /var/lib/tblspc becomes /mnt/restore/var/lib/tblspc
+func RelocatePath(path, root string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", errors.Errorf("path %s to relocate is not absolute", path)
+	}
+	relocatedPath := filepath.Join(root, path)
+	if !utility.IsInDirectory(relocatedPath, root) {
+		return "", newPathOutsideRelocationRootError(path, root)
+	}
+	return relocatedPath, nil
+}
+
+// RelocateTablespaceSpec returns the spec restoring the tablespaces under the root
+// and creating their symlinks in dbDataDirectory
+func RelocateTablespaceSpec(spec TablespaceSpec, root, dbDataDirectory string) (TablespaceSpec, error) {
+	relocatedSpec := NewTablespaceSpec(dbDataDirectory)
+	for _, name := range spec.TablespaceNames() {
+		location, _ := spec.location(name)
+		relocatedLocation, err := RelocatePath(location.Location, root)
+		if err != nil {
+			return TablespaceSpec{}, errors.Wrapf(err, "failed to relocate tablespace %s", name)
+		}
+		relocatedSpec.addTablespace(name, relocatedLocation)
+	}
+	return relocatedSpec, nil
+}
+
+// CheckRelocationRoot verifies that the relocation root is absolute and contains the destination directory
+func CheckRelocationRoot(root, dbDataDirectory string) error {
+	if !filepath.IsAbs(root) {
+		return errors.Errorf("relocation root %s is not an absolute path", root)
+	}
+	absDirectory, err := filepath.Abs(dbDataDirectory)
+	if err != nil {
+		return err
+	}
+	if !utility.IsInDirectory(absDirectory, filepath.Clean(root)) {
+		return newPathOutsideRelocationRootError(dbDataDirectory, root)
+	}
+	return nil
+}
+
+// relocateRestoreSpec chooses the tablespace spec for the fetch as the fetcher does
+// and moves it under the root. If there are no tablespaces, spec is returned as is.
+func relocateRestoreSpec(backup Backup, spec *TablespaceSpec, root, dbDataDirectory string) (*TablespaceSpec, error) {
+	if spec == nil {
+		sentinelDto, err := backup.GetSentinel()
+		if err != nil {
+			return nil, err
+		}
+		spec = sentinelDto.TablespaceSpec
+	}
+	if spec == nil || spec.empty() {
+		return spec, nil
+	}
+	relocatedSpec, err := RelocateTablespaceSpec(*spec, filepath.Clean(root), dbDataDirectory)
+	if err != nil {
+		return nil, err
+	}
+	return &relocatedSpec, nil
+}
+
+// RelocateTablespaceMap rewrites the tablespace paths in the tablespace_map of the restored backup,
+// so the server recreates the tablespace symlinks under the root. Each line of the file
+// is the tablespace oid followed by its path.
+func RelocateTablespaceMap(dbDataDirectory, root string) error {
+	mapPath := filepath.Join(dbDataDirectory, TablespaceMapFilename)
+	fileInfo, err := os.Stat(mapPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(mapPath)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return errors.Errorf("unexpected %s line: '%s'", TablespaceMapFilename, line)
+		}
+		relocatedPath, err := RelocatePath(fields[1], filepath.Clean(root))
+		if err != nil {
+			return errors.Wrapf(err, "failed to relocate tablespace %s", fields[0])
+		}
+		lines[i] = fields[0] + " " + relocatedPath
+	}
+	return ioutil.WriteFile(mapPath, []byte(strings.Join(lines, "\n")), fileInfo.Mode())
+}
+
+// HandleTablespaceMapRelocation relocates the tablespace_map of the fetched backup
+func HandleTablespaceMapRelocation(dbDataDirectory, root string) {
+	err := RelocateTablespaceMap(utility.ResolveSymlink(dbDataDirectory), root)
+	internal.FatalfOnError("Failed to relocate tablespace_map: %v\n", err)
+}
@@ -0,0 +1,74 @@
+package postgres_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestRelocatePath(t *testing.T) {
+	path, err := postgres.RelocatePath("/var/lib/tblspc", "/mnt/restore")
+	assert.NoError(t, err)
+	assert.Equal(t, "/mnt/restore/var/lib/tblspc", path)
+}
+
+func TestRelocatePath_Traversal(t *testing.T) {
+	_, err := postgres.RelocatePath("/../../etc", "/mnt/restore")
+	assert.Error(t, err)
+	assert.IsType(t, postgres.PathOutsideRelocationRootError{}, err)
+}
+
+func TestRelocatePath_Relative(t *testing.T) {
+	_, err := postgres.RelocatePath("var/lib/tblspc", "/mnt/restore")
+	assert.Error(t, err)
+}
+
+func TestRelocateTablespaceSpec(t *testing.T) {
+	spec := postgres.NewTablespaceSpec("/var/lib/postgresql/data")
+	require.NoError(t, json.Unmarshal([]byte(`{"base_prefix":"/var/lib/postgresql/data","tablespaces":["16384"],`+
+		`"16384":{"loc":"/ssd/tblspc","link":"pg_tblspc/16384"}}`), &spec))
+
+	relocated, err := postgres.RelocateTablespaceSpec(spec, "/mnt/restore", "/mnt/restore/data")
+	require.NoError(t, err)
+
+	basePrefix, _ := relocated.BasePrefix()
+	assert.Equal(t, "/mnt/restore/data", basePrefix)
+	bytes, err := json.Marshal(&relocated)
+	require.NoError(t, err)
+	assert.Contains(t, string(bytes), `"loc":"/mnt/restore/ssd/tblspc"`)
+	assert.Contains(t, string(bytes), `"link":"pg_tblspc/16384"`)
+}
+
+func TestCheckRelocationRoot(t *testing.T) {
+	assert.NoError(t, postgres.CheckRelocationRoot("/mnt/restore", "/mnt/restore/data"))
+	assert.Error(t, postgres.CheckRelocationRoot("/mnt/restore", "/var/lib/postgresql/data"))
+	assert.Error(t, postgres.CheckRelocationRoot("mnt/restore", "mnt/restore/data"))
+}
+
+func TestRelocateTablespaceMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relocate_root")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mapPath := filepath.Join(dir, postgres.TablespaceMapFilename)
+	require.NoError(t, ioutil.WriteFile(mapPath, []byte("16384 /ssd/tblspc\n16385 /hdd/my tblspc\n"), 0600))
+
+	require.NoError(t, postgres.RelocateTablespaceMap(dir, "/mnt/restore"))
+
+	content, err := ioutil.ReadFile(mapPath)
+	require.NoError(t, err)
+	assert.Equal(t, "16384 /mnt/restore/ssd/tblspc\n16385 /mnt/restore/hdd/my tblspc\n", string(content))
+}
+
+func TestRelocateTablespaceMap_NoMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relocate_root")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, postgres.RelocateTablespaceMap(dir, "/mnt/restore"))
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	PhysicalSlotType = "physical"
+	LogicalSlotType  = "logical"
+)
+
+// ReplicationSlotDto describes the replication slot recorded to the backup sentinel
+// with WALG_BACKUP_REPLICATION_SLOTS. Plugin and Database are set for the logical slots only.
+type ReplicationSlotDto struct {
+	Name       string `json:"Name"`
+	Type       string `json:"Type"`
+	Plugin     string `json:"Plugin,omitempty"`
+	Database   string `json:"Database,omitempty"`
+	RestartLSN string `json:"RestartLSN,omitempty"`
+}
+
+// FetchReplicationSlots reads the replication slots of the server
+func FetchReplicationSlots() ([]ReplicationSlotDto, error) {
+	conn, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(conn, "")
+	queryRunner, err := NewPgQueryRunner(conn)
+	if err != nil {
+		return nil, err
+	}
+	return queryRunner.GetReplicationSlots()
+}
+
+// RecreateReplicationSlots creates the recorded slots which don't exist on the server.
+// The logical slots are created in their databases. The slots start at the current
+// position of the server, they can't be moved back to their recorded restart LSN.
+func RecreateReplicationSlots(slots []ReplicationSlotDto) error {
+	for _, slot := range slots {
+		database := ""
+		if slot.Type == LogicalSlotType {
+			database = slot.Database
+		}
+		created, err := recreateReplicationSlot(slot, database)
+		if err != nil {
+			return err
+		}
+		if !created {
+			tracelog.InfoLogger.Printf("Replication slot %s already exists, skipping\n", slot.Name)
+			continue
+		}
+		tracelog.InfoLogger.Printf("Recreated %s replication slot %s\n", slot.Type, slot.Name)
+		if slot.Type == LogicalSlotType {
+			tracelog.WarningLogger.Printf("Logical replication slot %s is created at the current position "+
+				"instead of %s, the changes since the backup will not be decoded by its consumer\n",
+				slot.Name, slot.RestartLSN)
+		}
+	}
+	return nil
+}
+
+func recreateReplicationSlot(slot ReplicationSlotDto, database string) (created bool, err error) {
+	conn, err := Connect(func(config *pgx.ConnConfig) error {
+		if database != "" {
+			config.Database = database
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	defer utility.LoggedClose(conn, "")
+	queryRunner, err := NewPgQueryRunner(conn)
+	if err != nil {
+		return false, err
+	}
+
+	existingSlots, err := queryRunner.GetReplicationSlots()
+	if err != nil {
+		return false, err
+	}
+	for _, existingSlot := range existingSlots {
+		if existingSlot.Name == slot.Name {
+			return false, nil
+		}
+	}
+	return true, queryRunner.createReplicationSlot(slot)
+}
+
+// HandleReplicationSlotsRecreate recreates the replication slots recorded in the backup
+// on the server configured with the PG* settings
+func HandleReplicationSlotsRecreate(rootFolder storage.Folder, backup internal.Backup) {
+	pgBackup := NewBackup(rootFolder.GetSubFolder(utility.BaseBackupPath), backup.Name)
+	sentinelDto, err := pgBackup.GetSentinel()
+	internal.FatalfOnError("Failed to fetch backup sentinel: %v\n", err)
+	if len(sentinelDto.ReplicationSlots) == 0 {
+		tracelog.WarningLogger.Printf("No replication slots are recorded in backup %s. "+
+			"Slots are recorded only if %s is set\n", backup.Name, internal.BackupSlotsSetting)
+		return
+	}
+	err = RecreateReplicationSlots(sentinelDto.ReplicationSlots)
+	internal.FatalfOnError("Failed to recreate replication slots: %v\n", err)
+}
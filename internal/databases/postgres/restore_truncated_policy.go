@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// TruncatedPolicy defines how the reverse delta unpack handles the pages of the base backup
+// beyond the end of the file already restored from the newer backup, i.e. the file truncated in between
+type TruncatedPolicy string
+
+const (
+	// TruncatedPolicyIgnore skips the extra pages of the base backup, the restored file keeps the size
+	// recorded by the newer backup, which the WAL replay from the start of the newest backup expects
+	TruncatedPolicyIgnore TruncatedPolicy = "ignore"
+	// TruncatedPolicyExtend appends the extra pages of the base backup, the restored file gets the size
+	// it had in the base backup. Only the truncation replayed from the WAL truncates the file again
+	TruncatedPolicyExtend TruncatedPolicy = "extend"
+	// TruncatedPolicyFail fails the restore on the first truncated file
+	TruncatedPolicyFail TruncatedPolicy = "fail"
+)
+
+type UnknownTruncatedPolicyError struct {
+	error
+}
+
+func newUnknownTruncatedPolicyError(policy string) UnknownTruncatedPolicyError {
+	return UnknownTruncatedPolicyError{errors.Errorf("unknown truncated file policy '%s', expected one of: %s, %s, %s",
+		policy, TruncatedPolicyIgnore, TruncatedPolicyExtend, TruncatedPolicyFail)}
+}
+
+func (err UnknownTruncatedPolicyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type FileTruncatedError struct {
+	error
+}
+
+func newFileTruncatedError(path string) FileTruncatedError {
+	return FileTruncatedError{errors.Errorf(
+		"base backup has pages after the end of the restored file '%s', possibly the file was truncated", path)}
+}
+
+func (err FileTruncatedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ParseTruncatedPolicy parses the WALG_RESTORE_TRUNCATED_POLICY value, empty value means the ignore policy
+func ParseTruncatedPolicy(policy string) (TruncatedPolicy, error) {
+	switch TruncatedPolicy(policy) {
+	case "", TruncatedPolicyIgnore:
+		return TruncatedPolicyIgnore, nil
+	case TruncatedPolicyExtend, TruncatedPolicyFail:
+		return TruncatedPolicy(policy), nil
+	default:
+		return "", newUnknownTruncatedPolicyError(policy)
+	}
+}
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTruncatedPolicy(t *testing.T) {
+	for value, expected := range map[string]TruncatedPolicy{
+		"":       TruncatedPolicyIgnore,
+		"ignore": TruncatedPolicyIgnore,
+		"extend": TruncatedPolicyExtend,
+		"fail":   TruncatedPolicyFail,
+	} {
+		policy, err := ParseTruncatedPolicy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+	_, err := ParseTruncatedPolicy("pad")
+	assert.IsType(t, UnknownTruncatedPolicyError{}, err)
+}
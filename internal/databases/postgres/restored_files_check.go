@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// maxReportedMissingFiles limits the number of the missing files named in the error
+const maxReportedMissingFiles = 10
+
+type IncompleteRestoreError struct {
+	error
+}
+
+func newIncompleteRestoreError(dbDataDirectory, reason string) IncompleteRestoreError {
+	return IncompleteRestoreError{errors.Errorf("restore into %s is incomplete: %s. Use --force to ignore it.",
+		dbDataDirectory, reason)}
+}
+
+func (err IncompleteRestoreError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// describeMissingFiles names the first of the sorted missing files
+func describeMissingFiles(missingFiles []string) string {
+	reported := missingFiles
+	if len(reported) > maxReportedMissingFiles {
+		reported = reported[:maxReportedMissingFiles]
+	}
+	description := fmt.Sprintf("%d files of the backup are missing: %s", len(missingFiles), strings.Join(reported, ", "))
+	if len(missingFiles) > len(reported) {
+		description += fmt.Sprintf(" and %d more", len(missingFiles)-len(reported))
+	}
+	return description
+}
+
+// RestoredFilesSummary describes the files of the backup found in the restored directory
+type RestoredFilesSummary struct {
+	FileCount int
+	// TotalBytes is the size of the restored files which size is recorded in the sentinel
+	TotalBytes    int64
+	RecordedBytes int64
+}
+
+// CheckRestoredFiles checks that every file listed in the sentinel of the backup is in the restored directory.
+// The total size of the files packed in full with WALG_BACKUP_FILE_CHECKSUMS is compared with the recorded one too.
+func CheckRestoredFiles(baseBackupFolder storage.Folder, backupName,
+	dbDataDirectory string) (RestoredFilesSummary, error) {
+	summary := RestoredFilesSummary{}
+	chain, err := getDeltaChainSentinels(baseBackupFolder, backupName)
+	if err != nil {
+		return summary, err
+	}
+	missingFiles := make([]string, 0)
+	for fileName := range chain[0].Files {
+		relativePath := strings.TrimPrefix(fileName, "/")
+		info, err := os.Stat(filepath.Join(dbDataDirectory, relativePath))
+		if os.IsNotExist(err) {
+			missingFiles = append(missingFiles, relativePath)
+			continue
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.FileCount++
+		if description, ok := findRecordedFileDescription(chain, fileName); ok && description.SHA256 != "" {
+			summary.TotalBytes += info.Size()
+			summary.RecordedBytes += description.Size
+		}
+	}
+	if len(missingFiles) > 0 {
+		sort.Strings(missingFiles)
+		return summary, newIncompleteRestoreError(dbDataDirectory, describeMissingFiles(missingFiles))
+	}
+	if summary.TotalBytes != summary.RecordedBytes {
+		return summary, newIncompleteRestoreError(dbDataDirectory, fmt.Sprintf(
+			"the restored files are %d bytes instead of %d bytes recorded in the backup",
+			summary.TotalBytes, summary.RecordedBytes))
+	}
+	return summary, nil
+}
+
+// HandleRestoredFilesCheck fails the fetch if any file of the backup is missing in the restored directory,
+// unless force is set
+func HandleRestoredFilesCheck(rootFolder storage.Folder, backup internal.Backup, dbDataDirectory string, force bool) {
+	baseBackupFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+	pgBackup := NewBackup(baseBackupFolder, backup.Name)
+	sentinelDto, err := pgBackup.GetSentinel()
+	internal.FatalfOnError("Failed to fetch backup sentinel: %v\n", err)
+	if sentinelDto.Files == nil {
+		tracelog.WarningLogger.Println("The file list is not recorded in the sentinel, skipping the restored files check")
+		return
+	}
+
+	summary, err := CheckRestoredFiles(baseBackupFolder, backup.Name, utility.ResolveSymlink(dbDataDirectory))
+	if _, ok := err.(IncompleteRestoreError); ok && force {
+		tracelog.WarningLogger.Printf("%v\n", err)
+		return
+	}
+	internal.FatalOnError(err)
+	tracelog.InfoLogger.Printf("Restored files check passed: %d files of backup %s are restored\n",
+		summary.FileCount, backup.Name)
+}
@@ -0,0 +1,79 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const restoredFilesBackupName = "base_000000010000000000000002"
+
+func prepareRestoredFilesBackup(t *testing.T, files internal.BackupFileList) storage.Folder {
+	baseBackupFolder := memory.NewFolder("in_memory/", memory.NewStorage()).GetSubFolder(utility.BaseBackupPath)
+	lsn := uint64(0x2000028)
+	uploader := internal.NewUploader(nil, baseBackupFolder)
+	require.NoError(t, internal.UploadSentinel(uploader, &postgres.BackupSentinelDto{BackupStartLSN: &lsn,
+		Files: files}, restoredFilesBackupName))
+	return baseBackupFolder
+}
+
+func writeRestoredFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func TestCheckRestoredFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restored_files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeRestoredFile(t, dir, "PG_VERSION", "13\n")
+	writeRestoredFile(t, dir, "base/1/1259", "data")
+
+	baseBackupFolder := prepareRestoredFilesBackup(t, internal.BackupFileList{
+		"/PG_VERSION":  {SHA256: "aa", Size: 3},
+		"/base/1/1259": {IsIncremented: true},
+	})
+	summary, err := postgres.CheckRestoredFiles(baseBackupFolder, restoredFilesBackupName, dir)
+	require.NoError(t, err)
+	assert.Equal(t, postgres.RestoredFilesSummary{FileCount: 2, TotalBytes: 3, RecordedBytes: 3}, summary)
+}
+
+func TestCheckRestoredFiles_Missing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restored_files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeRestoredFile(t, dir, "PG_VERSION", "13\n")
+
+	baseBackupFolder := prepareRestoredFilesBackup(t, internal.BackupFileList{
+		"/PG_VERSION":  {},
+		"/base/1/1259": {},
+		"/base/1/1249": {},
+	})
+	_, err = postgres.CheckRestoredFiles(baseBackupFolder, restoredFilesBackupName, dir)
+	require.IsType(t, postgres.IncompleteRestoreError{}, err)
+	assert.Contains(t, err.Error(), "2 files of the backup are missing: base/1/1249, base/1/1259")
+}
+
+func TestCheckRestoredFiles_SizeMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restored_files")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeRestoredFile(t, dir, "PG_VERSION", "1")
+
+	baseBackupFolder := prepareRestoredFilesBackup(t, internal.BackupFileList{
+		"/PG_VERSION": {SHA256: "aa", Size: 3},
+	})
+	_, err = postgres.CheckRestoredFiles(baseBackupFolder, restoredFilesBackupName, dir)
+	require.IsType(t, postgres.IncompleteRestoreError{}, err)
+	assert.Contains(t, err.Error(), "the restored files are 1 bytes instead of 3 bytes")
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/utility"
 )
 
 var (
@@ -168,7 +169,7 @@ func (bb *StreamingBaseBackup) BackupName() string {
 	// Example base_00000001000000000000006A
 	// For now this is hardcoded to wal segments of 16MB, which is probably fine in all cases for backup path
 	segID := uint64(bb.StartLSN) / WalSegmentSize
-	return fmt.Sprintf("base_%08X%016X", bb.TimeLine, segID)
+	return fmt.Sprintf("%s%08X%016X", utility.GetBackupNamePrefix(), bb.TimeLine, segID)
 }
 
 // Name returns the filename of a tablespace backup file.
@@ -219,7 +220,7 @@ func (bb *StreamingBaseBackup) streamFromPostgres() (err error) {
 		if pgconn.Timeout(err) {
 			continue
 		}
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		switch msg := message.(type) {
 		case *pgproto3.CopyData:
 			bb.buffer = msg.Data
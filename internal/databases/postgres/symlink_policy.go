@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"archive/tar"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// SymlinkPolicy defines how backup-push handles the symlinks of the data directory.
+// The tablespace symlinks of pg_tblspc are always recorded in the tablespace specification instead.
+type SymlinkPolicy string
+
+const (
+	// SymlinkPolicyLegacy handles the symlinks as before WALG_SYMLINK_POLICY: the symlinks of ExcludedFilenames,
+	// e.g. the relocated pg_wal, are not stored, the others are stored without their targets
+	SymlinkPolicyLegacy SymlinkPolicy = "legacy"
+	// SymlinkPolicyStore stores the symlink as the symlink entry with its target,
+	// e.g. the relocated pg_wal is restored as the symlink to the same location
+	SymlinkPolicyStore SymlinkPolicy = "store"
+	// SymlinkPolicyFollow stores the file or the directory the symlink points to under the name of the symlink,
+	// e.g. the relocated pg_wal is restored as the directory of the data directory
+	SymlinkPolicyFollow SymlinkPolicy = "follow"
+	// SymlinkPolicySkip does not store the symlinks
+	SymlinkPolicySkip SymlinkPolicy = "skip"
+)
+
+type UnknownSymlinkPolicyError struct {
+	error
+}
+
+func newUnknownSymlinkPolicyError(policy string) UnknownSymlinkPolicyError {
+	return UnknownSymlinkPolicyError{errors.Errorf("unknown symlink policy '%s', expected one of: %s, %s, %s, %s",
+		policy, SymlinkPolicyLegacy, SymlinkPolicyStore, SymlinkPolicyFollow, SymlinkPolicySkip)}
+}
+
+func (err UnknownSymlinkPolicyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type SymlinkLoopError struct {
+	error
+}
+
+func newSymlinkLoopError(path, target string) SymlinkLoopError {
+	return SymlinkLoopError{errors.Errorf("symlink '%s' to '%s' can't be followed, it makes a loop", path, target)}
+}
+
+func (err SymlinkLoopError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ParseSymlinkPolicy parses the WALG_SYMLINK_POLICY value, empty value means the legacy policy
+func ParseSymlinkPolicy(policy string) (SymlinkPolicy, error) {
+	switch SymlinkPolicy(policy) {
+	case "", SymlinkPolicyLegacy:
+		return SymlinkPolicyLegacy, nil
+	case SymlinkPolicyStore, SymlinkPolicyFollow, SymlinkPolicySkip:
+		return SymlinkPolicy(policy), nil
+	default:
+		return "", newUnknownSymlinkPolicyError(policy)
+	}
+}
+
+// renamedFileInfo is the info of the symlink target under the name of the symlink
+type renamedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (info renamedFileInfo) Name() string { return info.name }
+
+// handleSymlink handles the symlink of the data directory, which is not a tablespace one, by the SymlinkPolicy.
+// Like the other excluded files, the symlinks of ExcludedFilenames are stored only if they point to a directory,
+// the contents of the directory are never stored.
+func (bundle *Bundle) handleSymlink(path string, info os.FileInfo) error {
+	if bundle.SymlinkPolicy == SymlinkPolicyLegacy {
+		return bundle.addToBundle(path, info)
+	}
+	if bundle.SymlinkPolicy == SymlinkPolicySkip {
+		tracelog.DebugLogger.Println("Skipped symlink: " + path)
+		return nil
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return errors.Wrapf(err, "handleSymlink: could not read symlink %s", path)
+	}
+	targetInfo, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		tracelog.WarningLogger.Printf("Skipped symlink '%s' to not existing '%s'\n", path, target)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "handleSymlink: could not stat the target of symlink %s", path)
+	}
+	if _, excluded := ExcludedFilenames[info.Name()]; excluded && !targetInfo.IsDir() {
+		return nil
+	}
+
+	if bundle.SymlinkPolicy != SymlinkPolicyFollow {
+		fileInfoHeader, err := tar.FileInfoHeader(info, target)
+		if err != nil {
+			return errors.Wrap(err, "handleSymlink: could not grab header info")
+		}
+		fileInfoHeader.Name = bundle.getFileRelPath(path)
+		// backup-fetch refuses the unsafe symlinks, so the backup with them could not be restored
+		if err = checkTarEntry(fileInfoHeader, bundle.Directory); err != nil {
+			return errors.Wrapf(err, "handleSymlink: symlink %s can't be restored, use the %s or %s symlink policy",
+				path, SymlinkPolicyFollow, SymlinkPolicySkip)
+		}
+		tracelog.DebugLogger.Printf("%s -> %s\n", fileInfoHeader.Name, target)
+		return bundle.TarBallComposer.AddHeader(fileInfoHeader, info)
+	}
+	return bundle.followSymlink(path, target, renamedFileInfo{targetInfo, info.Name()})
+}
+
+// followSymlink walks the file or the directory the symlink points to under the path of the symlink
+func (bundle *Bundle) followSymlink(path, target string, targetInfo os.FileInfo) error {
+	if !targetInfo.IsDir() {
+		return bundle.addToBundle(path, targetInfo)
+	}
+	resolvedTarget, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return errors.Wrapf(err, "handleSymlink: could not resolve symlink %s", path)
+	}
+	if bundle.followedSymlinkTargets[resolvedTarget] {
+		return newSymlinkLoopError(path, target)
+	}
+	if bundle.followedSymlinkTargets == nil {
+		bundle.followedSymlinkTargets = make(map[string]bool)
+	}
+	bundle.followedSymlinkTargets[resolvedTarget] = true
+	defer delete(bundle.followedSymlinkTargets, resolvedTarget)
+
+	err = bundle.addToBundle(path, targetInfo)
+	if err == filepath.SkipDir {
+		// the directory is excluded, the symlink itself is not a directory for the walk of the data directory
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(resolvedTarget, func(targetPath string, info os.FileInfo, err error) error {
+		if targetPath == resolvedTarget {
+			return nil
+		}
+		relativePath, relErr := filepath.Rel(resolvedTarget, targetPath)
+		if relErr != nil {
+			return relErr
+		}
+		return bundle.HandleWalkedFSObject(filepath.Join(path, relativePath), info, err)
+	})
+}
@@ -0,0 +1,150 @@
+package postgres_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestParseSymlinkPolicy(t *testing.T) {
+	for value, expected := range map[string]postgres.SymlinkPolicy{
+		"":       postgres.SymlinkPolicyLegacy,
+		"legacy": postgres.SymlinkPolicyLegacy,
+		"store":  postgres.SymlinkPolicyStore,
+		"follow": postgres.SymlinkPolicyFollow,
+		"skip":   postgres.SymlinkPolicySkip,
+	} {
+		policy, err := postgres.ParseSymlinkPolicy(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+	_, err := postgres.ParseSymlinkPolicy("copy")
+	assert.IsType(t, postgres.UnknownSymlinkPolicyError{}, err)
+}
+
+// prepareRelocatedWalDataDirectory makes the data directory with pg_wal relocated out of it
+// and postgresql.conf linked to the file of conf.d
+func prepareRelocatedWalDataDirectory(t *testing.T, root string) (dataDirectory, walDirectory string) {
+	dataDirectory = filepath.Join(root, "data")
+	walDirectory = filepath.Join(root, "wal")
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDirectory, "conf.d"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(walDirectory, "archive_status"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(walDirectory, "000000010000000000000001"), []byte("wal"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dataDirectory, "conf.d", "main.conf"), []byte("conf"), 0600))
+	require.NoError(t, os.Symlink(walDirectory, filepath.Join(dataDirectory, "pg_wal")))
+	require.NoError(t, os.Symlink("conf.d/main.conf", filepath.Join(dataDirectory, "postgresql.conf")))
+	return dataDirectory, walDirectory
+}
+
+// backupAndExtractWithSymlinkPolicy walks the data directory with the policy and extracts the tarballs
+func backupAndExtractWithSymlinkPolicy(t *testing.T, root, dataDirectory string,
+	policy postgres.SymlinkPolicy) string {
+	compressed := filepath.Join(root, "compressed_"+string(policy))
+	extracted := filepath.Join(root, "extracted_"+string(policy))
+	require.NoError(t, os.MkdirAll(compressed, 0755))
+	require.NoError(t, os.MkdirAll(extracted, 0755))
+
+	bundle := postgres.NewBundle(dataDirectory, nil, nil, nil, false, 1024)
+	bundle.SymlinkPolicy = policy
+	size := int64(0)
+	require.NoError(t, bundle.StartQueue(&testtools.FileTarBallMaker{Out: compressed, Size: &size}))
+	require.NoError(t, bundle.SetupComposer(setupTestTarBallComposerMaker(false)))
+	require.NoError(t, filepath.Walk(dataDirectory, bundle.HandleWalkedFSObject))
+	_, err := bundle.PackTarballs()
+	require.NoError(t, err)
+	require.NoError(t, bundle.FinishQueue())
+
+	files, err := ioutil.ReadDir(compressed)
+	require.NoError(t, err)
+	readerMakers := make([]internal.ReaderMaker, 0, len(files))
+	for _, file := range files {
+		readerMakers = append(readerMakers, &testtools.FileReaderMaker{Key: filepath.Join(compressed, file.Name())})
+	}
+	tarInterpreter := postgres.NewFileTarInterpreter(extracted, postgres.BackupSentinelDto{}, postgres.UnwrapAll, false)
+	require.NoError(t, internal.ExtractAll(tarInterpreter, readerMakers))
+	return extracted
+}
+
+func TestSymlinkPolicy_RelocatedWal(t *testing.T) {
+	root, err := ioutil.TempDir("", "symlink_policy")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	dataDirectory, walDirectory := prepareRelocatedWalDataDirectory(t, root)
+
+	extracted := backupAndExtractWithSymlinkPolicy(t, root, dataDirectory, postgres.SymlinkPolicyLegacy)
+	_, err = os.Lstat(filepath.Join(extracted, "pg_wal"))
+	assert.True(t, os.IsNotExist(err))
+	target, err := os.Readlink(filepath.Join(extracted, "postgresql.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "postgresql.conf", target)
+
+	extracted = backupAndExtractWithSymlinkPolicy(t, root, dataDirectory, postgres.SymlinkPolicyStore)
+	target, err = os.Readlink(filepath.Join(extracted, "pg_wal"))
+	require.NoError(t, err)
+	assert.Equal(t, walDirectory, target)
+	target, err = os.Readlink(filepath.Join(extracted, "postgresql.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "conf.d/main.conf", target)
+
+	extracted = backupAndExtractWithSymlinkPolicy(t, root, dataDirectory, postgres.SymlinkPolicyFollow)
+	info, err := os.Lstat(filepath.Join(extracted, "pg_wal"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	// the contents of pg_wal are never backed up
+	assert.NoFileExists(t, filepath.Join(extracted, "pg_wal", "000000010000000000000001"))
+	content, err := ioutil.ReadFile(filepath.Join(extracted, "postgresql.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "conf", string(content))
+	info, err = os.Lstat(filepath.Join(extracted, "postgresql.conf"))
+	require.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+
+	extracted = backupAndExtractWithSymlinkPolicy(t, root, dataDirectory, postgres.SymlinkPolicySkip)
+	_, err = os.Lstat(filepath.Join(extracted, "pg_wal"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Lstat(filepath.Join(extracted, "postgresql.conf"))
+	assert.True(t, os.IsNotExist(err))
+	assert.FileExists(t, filepath.Join(extracted, "conf.d", "main.conf"))
+}
+
+func TestSymlinkPolicy_FollowDetectsLoop(t *testing.T) {
+	root, err := ioutil.TempDir("", "symlink_loop")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	dataDirectory := filepath.Join(root, "data")
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDirectory, "base"), 0755))
+	require.NoError(t, os.Symlink("..", filepath.Join(dataDirectory, "base", "parent")))
+
+	bundle := postgres.NewBundle(dataDirectory, nil, nil, nil, false, 1024)
+	bundle.SymlinkPolicy = postgres.SymlinkPolicyFollow
+	size := int64(0)
+	require.NoError(t, bundle.StartQueue(&testtools.FileTarBallMaker{Out: root, Size: &size}))
+	require.NoError(t, bundle.SetupComposer(setupTestTarBallComposerMaker(false)))
+	err = filepath.Walk(dataDirectory, bundle.HandleWalkedFSObject)
+	assert.IsType(t, postgres.SymlinkLoopError{}, errors.Cause(err))
+}
+
+func TestSymlinkPolicy_StoreRefusesUnsafeSymlink(t *testing.T) {
+	root, err := ioutil.TempDir("", "symlink_unsafe")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	dataDirectory, _ := prepareRelocatedWalDataDirectory(t, root)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc"), 0755))
+	require.NoError(t, os.Symlink(filepath.Join(root, "etc"), filepath.Join(dataDirectory, "etc")))
+
+	bundle := postgres.NewBundle(dataDirectory, nil, nil, nil, false, 1024)
+	bundle.SymlinkPolicy = postgres.SymlinkPolicyStore
+	size := int64(0)
+	require.NoError(t, bundle.StartQueue(&testtools.FileTarBallMaker{Out: root, Size: &size}))
+	require.NoError(t, bundle.SetupComposer(setupTestTarBallComposerMaker(false)))
+	err = filepath.Walk(dataDirectory, bundle.HandleWalkedFSObject)
+	assert.IsType(t, postgres.UnsafeTarEntryError{}, errors.Cause(err))
+}
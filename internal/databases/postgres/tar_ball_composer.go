@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/jackc/pgx"
+	"github.com/wal-g/tracelog"
 )
 
 // TarBallComposer is used to compose files into tarballs.
@@ -54,7 +55,10 @@ func NewTarBallComposerMaker(composerType TarBallComposerType, conn *pgx.Conn,
 	case RatingComposer:
 		relFileStats, err := newRelFileStatistics(conn)
 		if err != nil {
-			return nil, err
+			// e.g. the statistics queries hit WALG_STATISTICS_STATEMENT_TIMEOUT, the backup does not need them
+			tracelog.WarningLogger.Printf("Failed to collect the relation statistics for the rating composer, "+
+				"falling back to the regular composer: %v\n", err)
+			return NewRegularTarBallComposerMaker(filePackOptions), nil
 		}
 		return NewRatingTarBallComposerMaker(relFileStats, filePackOptions)
 	default:
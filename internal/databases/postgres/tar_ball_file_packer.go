@@ -3,7 +3,10 @@ package postgres
 import (
 	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
@@ -47,12 +50,17 @@ func (err FileNotExistError) Error() string {
 type TarBallFilePackerOptions struct {
 	verifyPageChecksums   bool
 	storeAllCorruptBlocks bool
+	recordFileChecksums   bool
+	fileChangePolicy      FileChangePolicy
 }
 
-func NewTarBallFilePackerOptions(verifyPageChecksums, storeAllCorruptBlocks bool) TarBallFilePackerOptions {
+func NewTarBallFilePackerOptions(verifyPageChecksums, storeAllCorruptBlocks,
+	recordFileChecksums bool, fileChangePolicy FileChangePolicy) TarBallFilePackerOptions {
 	return TarBallFilePackerOptions{
 		verifyPageChecksums:   verifyPageChecksums,
 		storeAllCorruptBlocks: storeAllCorruptBlocks,
+		recordFileChecksums:   recordFileChecksums,
+		fileChangePolicy:      fileChangePolicy,
 	}
 }
 
@@ -87,7 +95,12 @@ func (p *TarBallFilePacker) UpdateDeltaMap(deltaMap PagedFileDeltaMap) {
 
 // TODO : unit tests
 func (p *TarBallFilePacker) PackFileIntoTar(cfi *ComposeFileInfo, tarBall internal.TarBall) error {
-	fileReadCloser, err := p.createFileReadCloser(cfi)
+	// the change is reported by the packing goroutine and recorded after it is finished
+	var fileChange string
+	fileReadCloser, err := p.createFileReadCloser(cfi, func(change string) error {
+		fileChange = change
+		return p.options.fileChangePolicy.handleChange(cfi.path, change)
+	})
 	if err != nil {
 		switch err.(type) {
 		case SkippedFileError:
@@ -104,6 +117,15 @@ func (p *TarBallFilePacker) PackFileIntoTar(cfi *ComposeFileInfo, tarBall intern
 	}
 	errorGroup, _ := errgroup.WithContext(context.Background())
 
+	var fileHash hash.Hash
+	if p.options.recordFileChecksums && !cfi.isIncremented {
+		fileHash = sha256.New()
+		fileReadCloser = &ioextensions.ReadCascadeCloser{
+			Reader: io.TeeReader(fileReadCloser, fileHash),
+			Closer: fileReadCloser,
+		}
+	}
+
 	if p.options.verifyPageChecksums {
 		var secondReadCloser io.ReadCloser
 		// newTeeReadCloser is used to provide the fileReadCloser to two consumers:
@@ -134,10 +156,46 @@ func (p *TarBallFilePacker) PackFileIntoTar(cfi *ComposeFileInfo, tarBall intern
 		return nil
 	})
 
-	return errorGroup.Wait()
+	err = errorGroup.Wait()
+	if err != nil {
+		return err
+	}
+	if fileChange != "" {
+		p.recordFileChange(cfi.header, fileChange)
+	}
+	if fileHash != nil {
+		p.recordFileChecksum(cfi.header, hex.EncodeToString(fileHash.Sum(nil)))
+	}
+	return nil
+}
+
+// recordFileChange stores the change of the file found during the packing in its description
+func (p *TarBallFilePacker) recordFileChange(header *tar.Header, change string) {
+	files := p.files.GetUnderlyingMap()
+	value, ok := files.Load(header.Name)
+	if !ok {
+		return
+	}
+	description := value.(internal.BackupFileDescription)
+	description.ChangedDuringBackup = change
+	files.Store(header.Name, description)
+}
+
+// recordFileChecksum stores the checksum of the packed file in its description
+func (p *TarBallFilePacker) recordFileChecksum(header *tar.Header, checksum string) {
+	files := p.files.GetUnderlyingMap()
+	value, ok := files.Load(header.Name)
+	if !ok {
+		return
+	}
+	description := value.(internal.BackupFileDescription)
+	description.SHA256 = checksum
+	description.Size = header.Size
+	files.Store(header.Name, description)
 }
 
-func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo) (io.ReadCloser, error) {
+func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo,
+	onChange func(change string) error) (io.ReadCloser, error) {
 	var fileReadCloser io.ReadCloser
 	if cfi.isIncremented {
 		bitmap, err := p.getDeltaBitmapFor(cfi.path)
@@ -162,7 +220,7 @@ func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo) (io.ReadC
 		case InvalidBlockError: // fallback to full file backup
 			tracelog.WarningLogger.Printf("failed to read file '%s' as incremented\n", cfi.header.Name)
 			cfi.isIncremented = false
-			fileReadCloser, err = startReadingFile(cfi.header, cfi.fileInfo, cfi.path)
+			fileReadCloser, err = startReadingFile(cfi.header, cfi.fileInfo, cfi.path, onChange)
 			if err != nil {
 				return nil, err
 			}
@@ -171,7 +229,7 @@ func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo) (io.ReadC
 		}
 	} else {
 		var err error
-		fileReadCloser, err = startReadingFile(cfi.header, cfi.fileInfo, cfi.path)
+		fileReadCloser, err = startReadingFile(cfi.header, cfi.fileInfo, cfi.path, onChange)
 		if err != nil {
 			return nil, err
 		}
@@ -180,7 +238,8 @@ func (p *TarBallFilePacker) createFileReadCloser(cfi *ComposeFileInfo) (io.ReadC
 }
 
 // TODO : unit tests
-func startReadingFile(fileInfoHeader *tar.Header, info os.FileInfo, path string) (io.ReadCloser, error) {
+func startReadingFile(fileInfoHeader *tar.Header, info os.FileInfo, path string,
+	onChange func(change string) error) (io.ReadCloser, error) {
 	fileInfoHeader.Size = info.Size()
 	file, err := os.Open(path)
 	if err != nil {
@@ -191,10 +250,7 @@ func startReadingFile(fileInfoHeader *tar.Header, info os.FileInfo, path string)
 	}
 	diskLimitedFileReader := limiters.NewDiskLimitReader(file)
 	fileReader := &ioextensions.ReadCascadeCloser{
-		Reader: &io.LimitedReader{
-			R: io.MultiReader(diskLimitedFileReader, &ioextensions.ZeroReader{}),
-			N: fileInfoHeader.Size,
-		},
+		Reader: newFileChangeDetectingReader(diskLimitedFileReader, fileInfoHeader.Size, onChange),
 		Closer: file,
 	}
 	return fileReader, nil
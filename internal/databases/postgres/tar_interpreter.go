@@ -2,6 +2,8 @@ package postgres
 
 import (
 	"archive/tar"
+	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path"
@@ -10,9 +12,22 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
 
+type UnsafeTarEntryError struct {
+	error
+}
+
+func newUnsafeTarEntryError(entryName, reason string) UnsafeTarEntryError {
+	return UnsafeTarEntryError{errors.Errorf("Refusing to extract tar entry '%s': %s", entryName, reason)}
+}
+
+func (err UnsafeTarEntryError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 // FileTarInterpreter extracts input to disk.
 type FileTarInterpreter struct {
 	DBDataDirectory string
@@ -21,13 +36,17 @@ type FileTarInterpreter struct {
 	UnwrapResult    *UnwrapResult
 
 	createNewIncrementalFiles bool
+	// checksumVerifier checks the extracted files if set
+	checksumVerifier *FetchChecksumVerifier
+	// truncatedPolicy handles the base pages after the end of the restored files in the reverse delta unpack
+	truncatedPolicy TruncatedPolicy
 }
 
 func NewFileTarInterpreter(
 	dbDataDirectory string, sentinel BackupSentinelDto, filesToUnwrap map[string]bool, createNewIncrementalFiles bool,
 ) *FileTarInterpreter {
 	return &FileTarInterpreter{dbDataDirectory, sentinel,
-		filesToUnwrap, newUnwrapResult(), createNewIncrementalFiles}
+		filesToUnwrap, newUnwrapResult(), createNewIncrementalFiles, nil, TruncatedPolicyIgnore}
 }
 
 // TODO : unit tests
@@ -45,7 +64,8 @@ func (tarInterpreter *FileTarInterpreter) unwrapRegularFileOld(fileReader io.Rea
 
 	// If this file is incremental we use it's base version from incremental path
 	if haveFileDescription && tarInterpreter.Sentinel.IsIncremental() && fileDescription.IsIncremented {
-		err := ApplyFileIncrement(targetPath, fileReader, tarInterpreter.createNewIncrementalFiles)
+		err := ApplyFileIncrement(targetPath, fileReader, tarInterpreter.createNewIncrementalFiles,
+			tarInterpreter.Sentinel.GetPageSize())
 		return errors.Wrapf(err, "Interpret: failed to apply increment for '%s'", targetPath)
 	}
 	err := PrepareDirs(fileInfo.Name, targetPath)
@@ -57,7 +77,14 @@ func (tarInterpreter *FileTarInterpreter) unwrapRegularFileOld(fileReader io.Rea
 		return errors.Wrapf(err, "failed to create new file: '%s'", targetPath)
 	}
 
+	var fileHash hash.Hash
+	if tarInterpreter.checksumVerifier != nil {
+		fileReader, fileHash = tarInterpreter.checksumVerifier.wrapReader(fileReader, fileDescription, haveFileDescription)
+	}
 	_, err = io.Copy(file, fileReader)
+	if err == nil && fileHash != nil {
+		err = tarInterpreter.checksumVerifier.verify(fileInfo.Name, fileDescription, fileHash)
+	}
 	if err != nil {
 		err1 := file.Close()
 		if err1 != nil {
@@ -65,7 +92,10 @@ func (tarInterpreter *FileTarInterpreter) unwrapRegularFileOld(fileReader io.Rea
 		}
 		err1 = os.Remove(targetPath)
 		if err1 != nil {
-			tracelog.ErrorLogger.Fatalf("Interpret: failed to remove file '%s' because of error: %v", targetPath, err1)
+			internal.Fatalf("Interpret: failed to remove file '%s' because of error: %v", targetPath, err1)
+		}
+		if _, mismatch := err.(FileChecksumMismatchError); mismatch {
+			return err
 		}
 		return errors.Wrap(err, "Interpret: copy failed")
 	}
@@ -85,6 +115,10 @@ func (tarInterpreter *FileTarInterpreter) unwrapRegularFileOld(fileReader io.Rea
 // is written successfully.
 func (tarInterpreter *FileTarInterpreter) Interpret(fileReader io.Reader, fileInfo *tar.Header) error {
 	tracelog.DebugLogger.Println("Interpreting: ", fileInfo.Name)
+	if err := checkTarEntry(fileInfo, tarInterpreter.DBDataDirectory); err != nil {
+		tracelog.ErrorLogger.Println(err)
+		return err
+	}
 	targetPath := path.Join(tarInterpreter.DBDataDirectory, fileInfo.Name)
 	switch fileInfo.Typeflag {
 	case tar.TypeReg, tar.TypeRegA:
@@ -106,13 +140,96 @@ func (tarInterpreter *FileTarInterpreter) Interpret(fileReader io.Reader, fileIn
 			return errors.Wrapf(err, "Interpret: failed to create hardlink %s", targetPath)
 		}
 	case tar.TypeSymlink:
-		if err := os.Symlink(fileInfo.Name, targetPath); err != nil {
+		// the symlink entries without the target are linked to their names, as before WALG_SYMLINK_POLICY
+		linkTarget := fileInfo.Linkname
+		if linkTarget == "" {
+			linkTarget = fileInfo.Name
+		}
+		if err := os.Symlink(linkTarget, targetPath); err != nil && !isExistingSymlink(targetPath, linkTarget) {
 			return errors.Wrapf(err, "Interpret: failed to create symlink %s", targetPath)
 		}
 	}
 	return nil
 }
 
+// checkTarEntry guards against the path traversal by a corrupted or crafted backup:
+// the entry must be extracted inside the root and its link must not point outside it.
+// Absolute symlinks are allowed only for the tablespace links in pg_tblspc, the symlink to the relocated
+// WAL directory may point to any directory, but nothing is extracted through it.
+func checkTarEntry(header *tar.Header, root string) error {
+	root = filepath.Clean(root)
+	// the entry names are relative to the root even if they start with a slash, e.g. /global/pg_control
+	targetPath := filepath.Join(root, header.Name)
+	if !utility.IsInDirectory(targetPath, root) {
+		return newUnsafeTarEntryError(header.Name, "path is outside of "+root)
+	}
+	if isUnderWalDirectoryLink(header.Name, root) {
+		return newUnsafeTarEntryError(header.Name, "path is under the symlink to the relocated WAL directory")
+	}
+
+	if header.Linkname == "" {
+		return nil
+	}
+	switch header.Typeflag {
+	case tar.TypeSymlink:
+		if isWalDirectoryLinkEntry(header.Name) {
+			// the WAL directory may be relocated anywhere, like the tablespaces, but it must be a directory
+			linkTarget := header.Linkname
+			if !filepath.IsAbs(linkTarget) {
+				linkTarget = filepath.Join(filepath.Dir(targetPath), linkTarget)
+			}
+			if info, err := os.Stat(linkTarget); err == nil && !info.IsDir() {
+				return newUnsafeTarEntryError(header.Name, "symlink to not a directory "+header.Linkname)
+			}
+			return nil
+		}
+		if filepath.IsAbs(header.Linkname) {
+			if isTablespaceLinkEntry(header.Name) {
+				return nil
+			}
+			return newUnsafeTarEntryError(header.Name, "symlink to absolute path "+header.Linkname)
+		}
+		// relative symlink target is resolved from the directory of the symlink
+		if !utility.IsInDirectory(filepath.Join(filepath.Dir(targetPath), header.Linkname), root) {
+			return newUnsafeTarEntryError(header.Name, "symlink to "+header.Linkname+" is outside of "+root)
+		}
+	case tar.TypeLink:
+		if !utility.IsInDirectory(filepath.Join(root, header.Linkname), root) {
+			return newUnsafeTarEntryError(header.Name, "hardlink to "+header.Linkname+" is outside of "+root)
+		}
+	}
+	return nil
+}
+
+// isTablespaceLinkEntry checks if the entry is a tablespace symlink, e.g. pg_tblspc/16384 or /pg_tblspc/16384
+func isTablespaceLinkEntry(name string) bool {
+	return filepath.Dir(strings.TrimPrefix(filepath.Clean(name), "/")) == TablespaceFolder
+}
+
+// isWalDirectoryLinkEntry checks if the entry is the symlink to the relocated WAL directory, pg_wal or pg_xlog
+func isWalDirectoryLinkEntry(name string) bool {
+	name = strings.TrimPrefix(filepath.Clean(name), "/")
+	return name == "pg_wal" || name == "pg_xlog"
+}
+
+// isUnderWalDirectoryLink checks if the entry is inside pg_wal or pg_xlog already extracted as the symlink,
+// such entry would be written out of the root through the symlink
+func isUnderWalDirectoryLink(name, root string) bool {
+	parts := strings.SplitN(strings.TrimPrefix(filepath.Clean(name), "/"), "/", 2)
+	if len(parts) < 2 || !isWalDirectoryLinkEntry(parts[0]) {
+		return false
+	}
+	info, err := os.Lstat(filepath.Join(root, parts[0]))
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// isExistingSymlink checks if the symlink to the target is already present,
+// e.g. it was created by the interrupted backup-fetch
+func isExistingSymlink(symlinkPath, target string) bool {
+	existingTarget, err := os.Readlink(symlinkPath)
+	return err == nil && existingTarget == target
+}
+
 // PrepareDirs makes sure all dirs exist
 func PrepareDirs(fileName string, targetPath string) error {
 	if fileName == targetPath {
@@ -3,8 +3,10 @@ package postgres_test
 import (
 	"archive/tar"
 	"bytes"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"github.com/wal-g/wal-g/internal/databases/postgres"
@@ -159,3 +161,86 @@ func TestPrepareDirsForLocalDirectory(t *testing.T) {
 	err := postgres.PrepareDirs("filename", "filename")
 	assert.NoError(t, err)
 }
+
+func TestInterpretRejectsUnsafeEntries(t *testing.T) {
+	dbDataDirectory, err := ioutil.TempDir("", "unsafe_entries")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dbDataDirectory)
+	tarInterpreter := &postgres.FileTarInterpreter{DBDataDirectory: dbDataDirectory}
+
+	headers := []*tar.Header{
+		{Name: "../escaped_file", Typeflag: tar.TypeReg},
+		{Name: "base/../../escaped_dir", Typeflag: tar.TypeDir},
+		{Name: "/../etc/escaped_file", Typeflag: tar.TypeReg},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		{Name: "base/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"},
+		{Name: "hardlink", Typeflag: tar.TypeLink, Linkname: "../escaped_file"},
+	}
+	for _, header := range headers {
+		err = tarInterpreter.Interpret(&bytes.Buffer{}, header)
+		assert.IsType(t, postgres.UnsafeTarEntryError{}, err, header.Name)
+		assert.Contains(t, err.Error(), header.Name)
+	}
+	_, err = os.Stat(filepath.Join(filepath.Dir(dbDataDirectory), "escaped_dir"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInterpretAllowsTablespaceSymlink(t *testing.T) {
+	dbDataDirectory, err := ioutil.TempDir("", "tablespace_symlink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dbDataDirectory)
+	assert.NoError(t, os.Mkdir(filepath.Join(dbDataDirectory, postgres.TablespaceFolder), 0755))
+	tarInterpreter := &postgres.FileTarInterpreter{DBDataDirectory: dbDataDirectory}
+
+	err = tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     postgres.TablespaceFolder + "/16384",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "/ssd/tblspc",
+	})
+	assert.NoError(t, err)
+	// wal-g stores the entry names with the leading slash
+	err = tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "/" + postgres.TablespaceFolder + "/16385",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "/ssd/tblspc2",
+	})
+	assert.NoError(t, err)
+}
+
+func TestInterpretWalDirectorySymlink(t *testing.T) {
+	root, err := ioutil.TempDir("", "wal_symlink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	dbDataDirectory := filepath.Join(root, "data")
+	walDirectory := filepath.Join(root, "wal")
+	assert.NoError(t, os.MkdirAll(dbDataDirectory, 0755))
+	assert.NoError(t, os.MkdirAll(walDirectory, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "file"), []byte("file"), 0600))
+	tarInterpreter := &postgres.FileTarInterpreter{DBDataDirectory: dbDataDirectory}
+
+	err = tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "/pg_xlog",
+		Typeflag: tar.TypeSymlink,
+		Linkname: filepath.Join(root, "file"),
+	})
+	assert.IsType(t, postgres.UnsafeTarEntryError{}, err)
+
+	err = tarInterpreter.Interpret(&bytes.Buffer{}, &tar.Header{
+		Name:     "/pg_wal",
+		Typeflag: tar.TypeSymlink,
+		Linkname: walDirectory,
+	})
+	assert.NoError(t, err)
+
+	// nothing is extracted through the symlink
+	for _, header := range []*tar.Header{
+		{Name: "/pg_wal/archive_status", Typeflag: tar.TypeDir},
+		{Name: "pg_wal/000000010000000000000001", Typeflag: tar.TypeReg},
+	} {
+		err = tarInterpreter.Interpret(&bytes.Buffer{}, header)
+		assert.IsType(t, postgres.UnsafeTarEntryError{}, err, header.Name)
+	}
+	files, err := ioutil.ReadDir(walDirectory)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
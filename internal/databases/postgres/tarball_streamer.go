@@ -116,6 +116,7 @@ func (streamer *TarballStreamer) NextInputFile() (err error) {
 	streamer.fileReadIndex = 0
 
 	streamer.remap()
+	internal.SetTarHeaderFormat(streamer.curHeader)
 
 	if streamer.tarFileReadIndex == 0 && streamer.curHeader.Size >= streamer.maxTarSize {
 		tracelog.WarningLogger.Printf("This file %s is larger than max tar size. "+
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/copy"
+)
+
+// MinTierOlderThan is the least age of the objects to move to the cold storage tier,
+// it keeps the tier command from moving the backups and WAL which are just being uploaded
+const MinTierOlderThan = time.Hour
+
+type TierThresholdTooSmallError struct {
+	error
+}
+
+func newTierThresholdTooSmallError(olderThan time.Duration) TierThresholdTooSmallError {
+	return TierThresholdTooSmallError{errors.Errorf("the tier threshold %v is less than the minimum of %v",
+		olderThan, MinTierOlderThan)}
+}
+
+func (err TierThresholdTooSmallError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// CheckTierOlderThan returns an error if the tier threshold is less than MinTierOlderThan
+func CheckTierOlderThan(olderThan time.Duration) error {
+	if olderThan < MinTierOlderThan {
+		return newTierThresholdTooSmallError(olderThan)
+	}
+	return nil
+}
+
+// HandleTier moves the objects older than the threshold from the hot storage tier to the cold one
+func HandleTier(folder storage.Folder, olderThan time.Duration, confirmed bool) {
+	internal.FatalOnError(CheckTierOlderThan(olderThan))
+	tieredFolder, ok := folder.(*internal.TieredFolder)
+	if !ok {
+		internal.Fatalf("%s is not set, there is no cold storage tier to move objects to",
+			internal.ColdStorageConfigSetting)
+	}
+	infos, err := GetTieringInfos(tieredFolder, time.Now().Add(-olderThan))
+	internal.FatalOnError(err)
+	if !confirmed {
+		for _, info := range infos {
+			tracelog.InfoLogger.Printf("Will move '%s' to the cold storage tier\n", info.SrcObj.GetName())
+		}
+		tracelog.InfoLogger.Printf("%d objects will be moved, run with --%s to actually move them\n",
+			len(infos), internal.ConfirmFlag)
+		return
+	}
+	err = MoveToColdTier(tieredFolder, infos)
+	internal.FatalOnError(err)
+	tracelog.InfoLogger.Printf("Moved %d objects to the cold storage tier\n", len(infos))
+}
+
+// GetTieringInfos selects the hot tier objects last modified before the threshold
+func GetTieringInfos(folder *internal.TieredFolder, threshold time.Time) ([]copy.InfoProvider, error) {
+	objects, err := storage.ListFolderRecursively(folder.Hot())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list hot storage tier")
+	}
+	isOld := func(object storage.Object) bool { return object.GetLastModified().Before(threshold) }
+	return copy.BuildCopyingInfos(folder.Hot(), folder.Cold(), objects, isOld, copy.NoopRenameFunc), nil
+}
+
+// MoveToColdTier copies the objects to the cold tier and deletes them from the hot one.
+// Objects are deleted only after all of them have been copied successfully.
+func MoveToColdTier(folder *internal.TieredFolder, infos []copy.InfoProvider) error {
+	if len(infos) == 0 {
+		return nil
+	}
+	err := copy.Infos(infos)
+	if err != nil {
+		return errors.Wrap(err, "failed to copy objects to the cold storage tier")
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.SrcObj.GetName())
+	}
+	return errors.Wrap(folder.Hot().DeleteObjects(names), "failed to delete moved objects from the hot storage tier")
+}
+
+// CountColdTierTars returns how many of the tar partitions of the backup are read from the cold storage tier
+func CountColdTierTars(backup Backup) (cold, total int, err error) {
+	objects, _, err := backup.getTarPartitionFolder().ListFolder()
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "unable to list tar partitions of backup '%s'", backup.Name)
+	}
+	for _, object := range objects {
+		if internal.GetObjectTier(object) == internal.ColdTierName {
+			cold++
+		}
+	}
+	return cold, len(objects), nil
+}
+
+// HandleColdTierReport logs the backups of the delta chain to fetch which are read from the cold storage tier,
+// the reads fall back to the cold tier transparently, but they may be slower and cost more
+func HandleColdTierReport(rootFolder storage.Folder, backup internal.Backup) {
+	if _, ok := rootFolder.(*internal.TieredFolder); !ok {
+		return
+	}
+	for pgBackup := ToPgBackup(backup); ; {
+		cold, total, err := CountColdTierTars(pgBackup)
+		internal.FatalOnError(err)
+		if cold > 0 {
+			tracelog.InfoLogger.Printf("%d of %d tar partitions of backup %s are read from the cold storage tier\n",
+				cold, total, pgBackup.Name)
+		}
+		sentinelDto, err := pgBackup.GetSentinel()
+		internal.FatalOnError(err)
+		if !sentinelDto.IsIncremental() {
+			return
+		}
+		pgBackup = NewBackup(pgBackup.Folder, *sentinelDto.IncrementFrom)
+	}
+}
@@ -0,0 +1,63 @@
+package postgres_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestMoveToColdTier(t *testing.T) {
+	hot := memory.NewFolder("hot/", memory.NewStorage())
+	cold := memory.NewFolder("cold/", memory.NewStorage())
+	require.NoError(t, hot.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("wal")))
+	folder := internal.NewTieredFolder(hot, cold)
+
+	infos, err := postgres.GetTieringInfos(folder, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, infos)
+
+	infos, err = postgres.GetTieringInfos(folder, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	require.NoError(t, postgres.MoveToColdTier(folder, infos))
+
+	exists, err := hot.Exists("wal_005/000000010000000000000001.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = cold.Exists("wal_005/000000010000000000000001.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = folder.Exists("wal_005/000000010000000000000001.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestCountColdTierTars(t *testing.T) {
+	hot := memory.NewFolder("hot/", memory.NewStorage())
+	cold := memory.NewFolder("cold/", memory.NewStorage())
+	tarPath := utility.BaseBackupPath + "base_000000010000000000000002" + internal.TarPartitionFolderName
+	require.NoError(t, hot.PutObject(tarPath+"part_1.tar.lz4", strings.NewReader("hot")))
+	require.NoError(t, cold.PutObject(tarPath+"part_2.tar.lz4", strings.NewReader("cold")))
+	require.NoError(t, cold.PutObject(tarPath+"part_3.tar.lz4", strings.NewReader("cold")))
+	folder := internal.NewTieredFolder(hot, cold)
+
+	coldTars, totalTars, err := postgres.CountColdTierTars(
+		postgres.NewBackup(folder.GetSubFolder(utility.BaseBackupPath), "base_000000010000000000000002"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, coldTars)
+	assert.Equal(t, 3, totalTars)
+}
+
+func TestCheckTierOlderThan(t *testing.T) {
+	assert.NoError(t, postgres.CheckTierOlderThan(720*time.Hour))
+	assert.NoError(t, postgres.CheckTierOlderThan(postgres.MinTierOlderThan))
+	assert.IsType(t, postgres.TierThresholdTooSmallError{}, postgres.CheckTierOlderThan(0))
+	assert.IsType(t, postgres.TierThresholdTooSmallError{}, postgres.CheckTierOlderThan(-time.Hour))
+}
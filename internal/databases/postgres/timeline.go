@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/jackc/pgx"
 	"github.com/pkg/errors"
@@ -14,9 +15,14 @@ import (
 const PatternTimelineAndLogSegNo = "[0-9A-F]{24}"
 
 var regexpTimelineAndLogSegNo = regexp.MustCompile(PatternTimelineAndLogSegNo)
+var regexpTimelineAndLogSegNoIgnoreCase = regexp.MustCompile("(?i)" + PatternTimelineAndLogSegNo)
 
 const maxCountOfLSN = 2
 
+// PartialWalFileSuffix marks the WAL segment which is not complete, like the last segment
+// of the old timeline archived on the promotion or the one streamed by wal-receive
+const PartialWalFileSuffix = ".partial"
+
 type BytesPerWalSegmentError struct {
 	error
 }
@@ -32,6 +38,18 @@ func (err BytesPerWalSegmentError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type InvalidWalSegmentSizeError struct {
+	error
+}
+
+func newInvalidWalSegmentSizeError(value, reason string) InvalidWalSegmentSizeError {
+	return InvalidWalSegmentSizeError{errors.Errorf("Invalid WAL segment size '%s': %s", value, reason)}
+}
+
+func (err InvalidWalSegmentSizeError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 type IncorrectLogSegNoError struct {
 	error
 }
@@ -71,8 +89,20 @@ func readTimeline(conn *pgx.Conn) (timeline uint32, err error) {
 const (
 	sizeofInt32bits = sizeofInt32 * 8
 	hexadecimal     = 16
+
+	// the range of the WAL segment size allowed by initdb --wal-segsize
+	minWalSegmentSize = uint64(1024 * 1024)
+	maxWalSegmentSize = uint64(1024 * 1024 * 1024)
 )
 
+var walSegmentSizeUnits = map[string]uint64{
+	"":   1,
+	"B":  1,
+	"KB": 1024,
+	"MB": 1024 * 1024,
+	"GB": 1024 * 1024 * 1024,
+}
+
 var (
 	// WalSegmentSize is the size of one WAL file
 	WalSegmentSize        = uint64(16 * 1024 * 1024)
@@ -80,6 +110,9 @@ var (
 	// .history file name regexp. For more details, see
 	// https://doxygen.postgresql.org/backend_2access_2transam_2timeline_8c_source.html
 	timelineHistoryFileRegexp *regexp.Regexp
+	// walSegmentSizeOverridden is set when the segment size is given explicitly,
+	// then it is not replaced by the one from the backup sentinel
+	walSegmentSizeOverridden bool
 )
 
 func init() {
@@ -92,10 +125,71 @@ const (
 )
 
 func SetWalSize(sizeMb uint64) {
-	WalSegmentSize = sizeMb * 1024 * 1024
+	SetWalSegmentBytes(sizeMb * 1024 * 1024)
+}
+
+func SetWalSegmentBytes(segmentBytes uint64) {
+	WalSegmentSize = segmentBytes
 	xLogSegmentsPerXLogID = 0x100000000 / WalSegmentSize
 }
 
+// ConfigureWalSegmentSize switches to the WAL segment size the backup was taken with,
+// so the restore-side segment arithmetic does not require the live cluster connection
+func ConfigureWalSegmentSize(sentinelDto BackupSentinelDto) {
+	if sentinelDto.WalSegmentSize == nil || *sentinelDto.WalSegmentSize == WalSegmentSize {
+		return
+	}
+	if walSegmentSizeOverridden {
+		tracelog.WarningLogger.Printf("The backup sentinel records the WAL segment size of %d bytes, "+
+			"using the overridden size of %d bytes\n", *sentinelDto.WalSegmentSize, WalSegmentSize)
+		return
+	}
+	tracelog.InfoLogger.Printf("Using the WAL segment size of %d bytes recorded in the backup sentinel\n",
+		*sentinelDto.WalSegmentSize)
+	SetWalSegmentBytes(*sentinelDto.WalSegmentSize)
+}
+
+// OverrideWalSegmentSize sets the WAL segment size given by the human-readable value, e.g. 16MB,
+// for the cases when neither the backup sentinel nor the cluster connection is available
+func OverrideWalSegmentSize(value string) error {
+	segmentBytes, err := ParseWalSegmentSize(value)
+	if err != nil {
+		return err
+	}
+	SetWalSegmentBytes(segmentBytes)
+	walSegmentSizeOverridden = true
+	return nil
+}
+
+// ParseWalSegmentSize converts the value like 16MB or 1GB to bytes, the plain number is treated as bytes.
+// The size must be a power of two between 1MB and 1GB as required by PostgreSQL.
+func ParseWalSegmentSize(value string) (uint64, error) {
+	trimmed := strings.TrimSpace(value)
+	numberEnd := strings.IndexFunc(trimmed, func(r rune) bool { return r < '0' || r > '9' })
+	if numberEnd == -1 {
+		numberEnd = len(trimmed)
+	}
+	if numberEnd == 0 {
+		return 0, newInvalidWalSegmentSizeError(value, "the size should start with a number")
+	}
+	unit, ok := walSegmentSizeUnits[strings.ToUpper(strings.TrimSpace(trimmed[numberEnd:]))]
+	if !ok {
+		return 0, newInvalidWalSegmentSizeError(value, "unknown unit, expected one of B, kB, MB, GB")
+	}
+	number, err := strconv.ParseUint(trimmed[:numberEnd], 10, 64)
+	if err != nil || number > maxWalSegmentSize {
+		return 0, newInvalidWalSegmentSizeError(value, "the size is out of range")
+	}
+	segmentBytes := number * unit
+	if segmentBytes < minWalSegmentSize || segmentBytes > maxWalSegmentSize {
+		return 0, newInvalidWalSegmentSizeError(value, "the size should be between 1MB and 1GB")
+	}
+	if segmentBytes&(segmentBytes-1) != 0 {
+		return 0, newInvalidWalSegmentSizeError(value, "the size should be a power of two")
+	}
+	return segmentBytes, nil
+}
+
 // getWalFilename formats WAL file name using PostgreSQL connection. Essentially reads timeline of the server.
 func getWalFilename(lsn uint64, conn *pgx.Conn) (walFilename string, timeline uint32, err error) {
 	timeline, err = readTimeline(conn)
@@ -141,7 +235,11 @@ func ParseWALFilename(name string) (timelineID uint32, logSegNo uint64, err erro
 }
 
 func TryFetchTimelineAndLogSegNo(objectName string) (uint32, uint64, bool) {
-	foundLsn := regexpTimelineAndLogSegNo.FindAllString(objectName, maxCountOfLSN)
+	segNoRegexp := regexpTimelineAndLogSegNo
+	if utility.IsObjectKeyNormalizationEnabled() {
+		segNoRegexp = regexpTimelineAndLogSegNoIgnoreCase
+	}
+	foundLsn := segNoRegexp.FindAllString(objectName, maxCountOfLSN)
 	if len(foundLsn) > 0 {
 		timelineID, logSegNo, err := ParseWALFilename(foundLsn[0])
 
@@ -157,12 +255,20 @@ func isWalFilename(filename string) bool {
 	return err == nil
 }
 
+// isPartialWalFilename reports whether the name is of a partial WAL segment, like 000000010000000000000002.partial
+func isPartialWalFilename(filename string) bool {
+	return strings.HasSuffix(filename, PartialWalFileSuffix) &&
+		isWalFilename(strings.TrimSuffix(filename, PartialWalFileSuffix))
+}
+
+// ParseTimelineFromBackupName parses the timeline of the start WAL segment in the backup name,
+// whatever the backup name prefix is
 func ParseTimelineFromBackupName(backupName string) (uint32, error) {
-	if len(backupName) == 0 {
+	timeline, err := ParseTimelineFromString(utility.StripWalFileName(backupName)[:8])
+	if err != nil {
 		return 0, newIncorrectBackupNameError(backupName)
 	}
-	prefixLength := len(utility.BackupNamePrefix)
-	return ParseTimelineFromString(backupName[prefixLength : prefixLength+8])
+	return timeline, nil
 }
 
 func ParseTimelineFromString(timelineString string) (uint32, error) {
@@ -2,8 +2,11 @@ package postgres
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/utility"
 )
 
 func TestNextWALFileName(t *testing.T) {
@@ -79,3 +82,123 @@ func TestParseWALFilenameDifferentSize(t *testing.T) {
 	SetWalSize(16)
 	assert.Equal(t, WalSegmentSize, uint64(16*1024*1024))
 }
+
+func TestIsPartialWalFilename(t *testing.T) {
+	assert.True(t, isPartialWalFilename("000000010000000000000051.partial"))
+	assert.False(t, isPartialWalFilename("000000010000000000000051"))
+	assert.False(t, isPartialWalFilename("00000002.history.partial"))
+}
+
+func TestParseWalSegmentBytes(t *testing.T) {
+	segmentBytes, err := parseWalSegmentBytes("2048", 100010)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(16*1024*1024), segmentBytes)
+
+	segmentBytes, err = parseWalSegmentBytes("67108864", 130002)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(64*1024*1024), segmentBytes)
+}
+
+func TestConfigureWalSegmentSize(t *testing.T) {
+	ConfigureWalSegmentSize(BackupSentinelDto{})
+	assert.Equal(t, uint64(16*1024*1024), WalSegmentSize)
+
+	segmentBytes := uint64(64 * 1024 * 1024)
+	ConfigureWalSegmentSize(BackupSentinelDto{WalSegmentSize: &segmentBytes})
+	assert.Equal(t, segmentBytes, WalSegmentSize)
+	assert.Equal(t, WalSegmentNo(1), newWalSegmentNo(segmentBytes))
+	SetWalSize(16)
+}
+
+func TestParseWalSegmentSize(t *testing.T) {
+	for value, expected := range map[string]uint64{
+		"16MB":     16 * 1024 * 1024,
+		"1GB":      1024 * 1024 * 1024,
+		"1024kB":   1024 * 1024,
+		"64 mb":    64 * 1024 * 1024,
+		"16777216": 16 * 1024 * 1024,
+	} {
+		segmentBytes, err := ParseWalSegmentSize(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, segmentBytes, value)
+	}
+	for _, value := range []string{"", "MB", "24MB", "512kB", "2GB", "16TB", "16 M B", "99999999999999999999GB"} {
+		_, err := ParseWalSegmentSize(value)
+		assert.IsType(t, InvalidWalSegmentSizeError{}, err, value)
+	}
+}
+
+func TestOverrideWalSegmentSize(t *testing.T) {
+	defer func() {
+		walSegmentSizeOverridden = false
+		SetWalSize(16)
+	}()
+	assert.Error(t, OverrideWalSegmentSize("3MB"))
+	assert.Equal(t, uint64(16*1024*1024), WalSegmentSize)
+
+	assert.NoError(t, OverrideWalSegmentSize("32MB"))
+	segmentBytes := uint64(64 * 1024 * 1024)
+	ConfigureWalSegmentSize(BackupSentinelDto{WalSegmentSize: &segmentBytes})
+	assert.Equal(t, uint64(32*1024*1024), WalSegmentSize)
+}
+
+func TestTryFetchTimelineAndLogSegNo_MixedCase(t *testing.T) {
+	// WAL-E style key with the lower case segment name
+	objectName := "00000002000000010000000a.lzo"
+
+	_, _, ok := TryFetchTimelineAndLogSegNo(objectName)
+	assert.False(t, ok)
+
+	utility.SetObjectKeyNormalization(true)
+	defer utility.SetObjectKeyNormalization(false)
+	timeline, logSegNo, ok := TryFetchTimelineAndLogSegNo(objectName)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), timeline)
+	assert.Equal(t, uint64(0x10a), logSegNo)
+}
+
+func TestFetchPgBackupName_MixedCase(t *testing.T) {
+	object := storage.NewLocalObject("base_00000001000000000000000a_00000040/tar_partitions/part_1.tar.lzo",
+		time.Now(), 0)
+
+	assert.Equal(t, "", FetchPgBackupName(object))
+
+	utility.SetObjectKeyNormalization(true)
+	defer utility.SetObjectKeyNormalization(false)
+	// the name is not converted, so the backup objects can still be accessed with it
+	assert.Equal(t, "base_00000001000000000000000a", FetchPgBackupName(object))
+}
+
+func TestParseTimelineFromBackupName_Prefixes(t *testing.T) {
+	assert.NoError(t, utility.SetBackupNamePrefix("nightly_"))
+	defer utility.SetBackupNamePrefix(utility.BackupNamePrefix)
+
+	// the backups made before the prefix was changed are parsed as well
+	for name, expected := range map[string]uint32{
+		"base_000000010000000000000002":                               1,
+		"nightly_0000000A0000000000000002":                            10,
+		"nightly_000000020000000000000004_D_000000020000000000000002": 2,
+	} {
+		timeline, err := ParseTimelineFromBackupName(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, timeline, name)
+	}
+	for _, name := range []string{"", "nightly_", "base_0001", "LATEST"} {
+		_, err := ParseTimelineFromBackupName(name)
+		assert.IsType(t, IncorrectBackupNameError{}, err, name)
+	}
+}
+
+func TestFetchPgBackupName_Prefixes(t *testing.T) {
+	assert.NoError(t, utility.SetBackupNamePrefix("nightly_"))
+	defer utility.SetBackupNamePrefix(utility.BackupNamePrefix)
+
+	for objectName, expected := range map[string]string{
+		"base_000000010000000000000002" + utility.SentinelSuffix:                               "base_000000010000000000000002",
+		"nightly_000000010000000000000004/tar_partitions/part_1.tar":                           "nightly_000000010000000000000004",
+		"nightly_000000010000000000000006_D_000000010000000000000004" + utility.SentinelSuffix: "nightly_000000010000000000000006_D_000000010000000000000004",
+	} {
+		object := storage.NewLocalObject(objectName, time.Now(), 0)
+		assert.Equal(t, expected, FetchPgBackupName(object), objectName)
+	}
+}
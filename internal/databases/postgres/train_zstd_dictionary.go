@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// DefaultZstdDictionarySize is the dictionary size the zstd command line tool trains by default
+	DefaultZstdDictionarySize = 112640
+
+	// the beginning of a bigger file is sampled, the dictionary helps the small files mostly
+	dictionarySampleSizeLimit = 16 << 10
+	// zstd recommends the samples of about 100 times the dictionary size
+	dictionarySamplesPerByte = 100
+)
+
+// CollectDictionarySamples samples the regular files of the data directory in random order until
+// the total size limit. Each sample is a tar entry of the file, as the compressor sees it in a backup.
+func CollectDictionarySamples(dbDataDirectory string, totalSizeLimit int) ([][]byte, error) {
+	paths := make([]string, 0)
+	err := filepath.Walk(dbDataDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if _, excluded := ExcludedFilenames[info.Name()]; excluded {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() && info.Size() > 0 {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to walk %s", dbDataDirectory)
+	}
+
+	rand.Shuffle(len(paths), func(i, j int) { paths[i], paths[j] = paths[j], paths[i] })
+	samples := make([][]byte, 0)
+	totalSize := 0
+	for _, path := range paths {
+		if totalSize >= totalSizeLimit {
+			break
+		}
+		sample, err := makeDictionarySample(dbDataDirectory, path)
+		if err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				continue
+			}
+			return nil, err
+		}
+		samples = append(samples, sample)
+		totalSize += len(sample)
+	}
+	return samples, nil
+}
+
+func makeDictionarySample(dbDataDirectory, path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(file, "")
+	content, err := ioutil.ReadAll(io.LimitReader(file, dictionarySampleSizeLimit))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	var sample bytes.Buffer
+	tarWriter := tar.NewWriter(&sample)
+	err = tarWriter.WriteHeader(&tar.Header{
+		Name:     utility.PathSeparator + utility.GetSubdirectoryRelativePath(path, dbDataDirectory),
+		Mode:     0600,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tarWriter.Write(content); err != nil {
+		return nil, err
+	}
+	return sample.Bytes(), tarWriter.Flush()
+}
+
+// HandleTrainZstdDictionary trains the zstd dictionary of at most dictSize bytes
+// on the files of the data directory and writes it to outputPath
+func HandleTrainZstdDictionary(dbDataDirectory, outputPath string, dictSize int) {
+	if dictSize <= 0 {
+		internal.Fatalf("Dictionary size must be positive, got %d\n", dictSize)
+	}
+	samples, err := CollectDictionarySamples(dbDataDirectory, dictSize*dictionarySamplesPerByte)
+	internal.FatalfOnError("Failed to sample the data directory: %v\n", err)
+	tracelog.InfoLogger.Printf("Training zstd dictionary on %d files of %s\n", len(samples), dbDataDirectory)
+
+	dict, err := compression.TrainDictionary(samples, dictSize)
+	internal.FatalOnError(err)
+	err = ioutil.WriteFile(outputPath, dict, 0600)
+	internal.FatalfOnError("Failed to write the dictionary: %v\n", err)
+	tracelog.InfoLogger.Printf("Zstd dictionary %d of %d bytes is written to %s\n",
+		compression.DictionaryID(dict), len(dict), outputPath)
+}
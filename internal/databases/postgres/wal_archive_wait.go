@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+const walArchivePollInterval = 5 * time.Second
+
+type WalArchiveTimeoutError struct {
+	error
+}
+
+func newWalArchiveTimeoutError(segmentName string, timeout time.Duration) WalArchiveTimeoutError {
+	return WalArchiveTimeoutError{errors.Errorf(
+		"WAL segment %s required for the backup consistency was not archived in %v", segmentName, timeout)}
+}
+
+func (err WalArchiveTimeoutError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// WaitForBackupWalArchived waits until the WAL segment containing the backup finish LSN appears in storage
+func WaitForBackupWalArchived(walFolder storage.Folder, timeline uint32, finishLSN uint64, timeout time.Duration) error {
+	// finish LSN points right after the last record required
+	segmentName := newWalSegmentNo(finishLSN - 1).getFilename(timeline)
+	return waitForWalSegment(walFolder, segmentName, timeout, walArchivePollInterval)
+}
+
+func waitForWalSegment(walFolder storage.Folder, segmentName string, timeout, pollInterval time.Duration) error {
+	tracelog.InfoLogger.Printf("Waiting for WAL segment %s to be archived\n", segmentName)
+	deadline := time.Now().Add(timeout)
+	for {
+		exists, err := walSegmentExists(walFolder, segmentName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check WAL segment '%s' existence", segmentName)
+		}
+		if exists {
+			tracelog.InfoLogger.Printf("WAL segment %s is archived\n", segmentName)
+			return nil
+		}
+		if time.Now().Add(pollInterval).After(deadline) {
+			return newWalArchiveTimeoutError(segmentName, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
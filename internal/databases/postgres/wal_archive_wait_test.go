@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/memory"
+)
+
+func TestWaitForWalSegment(t *testing.T) {
+	walFolder := memory.NewFolder("wal_005/", memory.NewStorage())
+
+	err := waitForWalSegment(walFolder, "000000010000000000000002", 30*time.Millisecond, 10*time.Millisecond)
+	assert.IsType(t, WalArchiveTimeoutError{}, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = walFolder.PutObject("000000010000000000000002.lz4", strings.NewReader("wal"))
+	}()
+	err = waitForWalSegment(walFolder, "000000010000000000000002", time.Second, 10*time.Millisecond)
+	assert.NoError(t, err)
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"time"
@@ -56,7 +57,7 @@ func HandleWALFetch(folder storage.Folder, walFileName string, location string,
 			}
 
 			err = os.Rename(prefetched, location)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 
 			err := checkWALFileMagic(location)
 			if err != nil {
@@ -67,7 +68,7 @@ func HandleWALFetch(folder storage.Folder, walFileName string, location string,
 
 			return
 		} else if !os.IsNotExist(err) {
-			tracelog.ErrorLogger.FatalError(err)
+			internal.FatalError(err)
 		}
 
 		// We have race condition here, if running is renamed here, but it's OK
@@ -95,8 +96,72 @@ func HandleWALFetch(folder storage.Folder, walFileName string, location string,
 		time.Sleep(2 * time.Millisecond)
 	}
 
-	err := internal.DownloadFileTo(folder, walFileName, location)
-	tracelog.ErrorLogger.FatalOnError(err)
+	err := downloadWALFile(folder, walFileName, location)
+	internal.FatalOnError(err)
+}
+
+// fetchesPartialWalFile reports whether the partial segment is fetched if the WAL file is not archived
+func fetchesPartialWalFile(walFileName string) bool {
+	return viper.GetBool(internal.PartialWalSegmentsSetting) && isWalFilename(walFileName)
+}
+
+// openWALFile opens the decompressed and decrypted WAL file. The archived full segment supersedes the partial one,
+// which is opened only if the full segment is not archived and WALG_PARTIAL_WAL_SEGMENTS is set.
+func openWALFile(folder storage.Folder, walFileName string) (io.ReadCloser, error) {
+	reader, err := internal.DownloadAndDecompressStorageFile(folder, walFileName)
+	if _, ok := err.(internal.ArchiveNonExistenceError); !ok || !fetchesPartialWalFile(walFileName) {
+		return reader, err
+	}
+	reader, partialErr := internal.DownloadAndDecompressStorageFile(folder, walFileName+PartialWalFileSuffix)
+	if _, ok := partialErr.(internal.ArchiveNonExistenceError); ok {
+		return nil, err
+	}
+	if partialErr == nil {
+		tracelog.WarningLogger.Printf("WAL file '%s' is not archived, fetched its partial segment\n", walFileName)
+	}
+	return reader, partialErr
+}
+
+// downloadWALFile is the internal.DownloadFileTo of the openWALFile
+func downloadWALFile(folder storage.Folder, walFileName string, location string) error {
+	// Create file as soon as possible, like internal.DownloadFileTo does for the prefetch
+	file, err := os.OpenFile(location, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+
+	reader, err := openWALFile(folder, walFileName)
+	if err != nil {
+		_ = os.Remove(location)
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	_, err = utility.FastCopy(file, reader)
+	return err
+}
+
+// FetchWALToWriter writes the decompressed and decrypted WAL file to the output
+func FetchWALToWriter(folder storage.Folder, walFileName string, output io.Writer) error {
+	reader, err := openWALFile(folder.GetSubFolder(utility.WalPath), walFileName)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	_, err = utility.FastCopy(output, reader)
+	return err
+}
+
+// HandleWALFetchToStdout is invoked to perform wal-g wal-fetch with "-" destination.
+// Unless force is set, it refuses to dump the binary WAL file to the terminal.
+func HandleWALFetchToStdout(folder storage.Folder, walFileName string, force bool) {
+	if !force && !internal.FileIsPiped(os.Stdout) {
+		internal.Fatal("Refusing to write WAL file to the terminal, " +
+			"redirect the output or use --force\n")
+	}
+	err := FetchWALToWriter(folder, walFileName, os.Stdout)
+	internal.FatalfOnError("Failed to fetch WAL file: %v\n", err)
 }
 
 // TODO : unit tests
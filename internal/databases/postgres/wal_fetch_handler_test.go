@@ -5,11 +5,15 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/compression/lz4"
 	"github.com/wal-g/wal-g/internal/compression/lzma"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
 	"github.com/wal-g/wal-g/testtools"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -70,3 +74,60 @@ func TestTryDownloadWALFile_NotExist(t *testing.T) {
 	assert.False(t, exist)
 	assert.NoError(t, err)
 }
+
+func TestFetchWALToWriter(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	var compressed bytes.Buffer
+	writer := lz4.Compressor{}.NewWriter(&compressed)
+	_, err := writer.Write([]byte("test data"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, folder.GetSubFolder(utility.WalPath).PutObject(WalFilename+"."+lz4.FileExtension, &compressed))
+
+	var output bytes.Buffer
+	err = postgres.FetchWALToWriter(folder, WalFilename, &output)
+	assert.NoError(t, err)
+	assert.Equal(t, "test data", output.String())
+}
+
+func TestFetchWALToWriter_NotExist(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	var output bytes.Buffer
+	err := postgres.FetchWALToWriter(folder, WalFilename, &output)
+	assert.Error(t, err)
+	assert.Empty(t, output.Bytes())
+}
+
+func putLz4WALFile(t *testing.T, folder storage.Folder, walFileName string, content string) {
+	var compressed bytes.Buffer
+	writer := lz4.Compressor{}.NewWriter(&compressed)
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, folder.GetSubFolder(utility.WalPath).PutObject(walFileName+"."+lz4.FileExtension, &compressed))
+}
+
+func TestFetchWALToWriter_PartialSegment(t *testing.T) {
+	viper.Set(internal.PartialWalSegmentsSetting, true)
+	defer viper.Set(internal.PartialWalSegmentsSetting, nil)
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	putLz4WALFile(t, folder, WalFilename+postgres.PartialWalFileSuffix, "partial data")
+
+	var output bytes.Buffer
+	require.NoError(t, postgres.FetchWALToWriter(folder, WalFilename, &output))
+	assert.Equal(t, "partial data", output.String())
+
+	putLz4WALFile(t, folder, WalFilename, "full data")
+	output.Reset()
+	require.NoError(t, postgres.FetchWALToWriter(folder, WalFilename, &output))
+	assert.Equal(t, "full data", output.String())
+}
+
+func TestFetchWALToWriter_PartialSegmentDisabled(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	putLz4WALFile(t, folder, WalFilename+postgres.PartialWalFileSuffix, "partial data")
+
+	var output bytes.Buffer
+	err := postgres.FetchWALToWriter(folder, WalFilename, &output)
+	assert.IsType(t, internal.ArchiveNonExistenceError{}, err)
+}
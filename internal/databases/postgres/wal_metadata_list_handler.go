@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	walMetadataSuffix = ".json"
+	walSegmentNameLen = 24
+	// bulk metadata file is named after the segment name without the last digit
+	walBulkMetadataNameLen = walSegmentNameLen - 1
+)
+
+type WalMetadataListEntry struct {
+	SegmentName string    `json:"segment_name"`
+	CreatedTime time.Time `json:"created_time"`
+}
+
+// GetWalMetadataList collects the WAL metadata uploaded with WALG_UPLOAD_WAL_METADATA
+// from both the INDIVIDUAL and the BULK metadata files. Only the segments
+// in the [from, to] name range are returned, the empty bound is not checked.
+func GetWalMetadataList(walFolder storage.Folder, from, to string) ([]WalMetadataListEntry, error) {
+	objects, _, err := walFolder.ListFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	inRange := func(segmentName string) bool {
+		return (from == "" || segmentName >= from) && (to == "" || segmentName <= to)
+	}
+	crypter := internal.ConfigureCrypter()
+	entries := make(map[string]WalMetadataListEntry)
+	for _, object := range objects {
+		objectName := object.GetName()
+		if !strings.HasSuffix(objectName, walMetadataSuffix) {
+			continue
+		}
+		name := strings.TrimSuffix(objectName, walMetadataSuffix)
+		switch len(name) {
+		case walBulkMetadataNameLen:
+			// the bulk file covers the segments from name+"0" to name+"F"
+			if (to != "" && name+"0" > to) || (from != "" && name+"F" < from) {
+				continue
+			}
+		case walSegmentNameLen:
+			if !inRange(name) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		walMetadata, err := fetchWalMetadata(walFolder, objectName, crypter)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Skipping the WAL metadata file %s: %v\n", objectName, err)
+			continue
+		}
+		for segmentName, description := range walMetadata {
+			if inRange(segmentName) {
+				entries[segmentName] = WalMetadataListEntry{segmentName, description.CreatedTime}
+			}
+		}
+	}
+
+	list := make([]WalMetadataListEntry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SegmentName < list[j].SegmentName
+	})
+	return list, nil
+}
+
+func HandleWalMetadataList(folder storage.Folder, from, to string, output io.Writer, jsonOutput bool) {
+	list, err := GetWalMetadataList(folder.GetSubFolder(utility.WalPath), from, to)
+	internal.FatalfOnError("Failed to list WAL metadata: %v\n", err)
+	if jsonOutput {
+		err = internal.WriteAsJSON(list, output, true)
+		internal.FatalOnError(err)
+		return
+	}
+	writeWalMetadataTable(list, output)
+}
+
+func writeWalMetadataTable(list []WalMetadataListEntry, output io.Writer) {
+	writer := table.NewWriter()
+	writer.SetOutputMirror(output)
+	writer.AppendHeader(table.Row{"Segment", "Created time", "Since previous"})
+	for i, entry := range list {
+		sincePrevious := ""
+		if i > 0 {
+			sincePrevious = entry.CreatedTime.Sub(list[i-1].CreatedTime).String()
+		}
+		writer.AppendRow(table.Row{entry.SegmentName, entry.CreatedTime.Format(time.RFC3339), sincePrevious})
+	}
+	writer.AppendFooter(table.Row{fmt.Sprintf("%d segments", len(list))})
+	writer.Render()
+}
@@ -0,0 +1,43 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestGetWalMetadataList(t *testing.T) {
+	walFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	putObject := func(name, body string) {
+		require.NoError(t, walFolder.PutObject(name, bytes.NewBufferString(body)))
+	}
+	// bulk metadata of the 00000001000000000000000X series
+	putObject("00000001000000000000000.json", `{
+		"00000001000000000000000E":{"created_time":"2021-02-23T00:51:14Z","date_fmt":"%Y"},
+		"00000001000000000000000F":{"created_time":"2021-02-23T00:52:14Z","date_fmt":"%Y"}}`)
+	// individual metadata
+	putObject("000000010000000000000010.json",
+		`{"000000010000000000000010":{"created_time":"2021-02-23T00:55:14Z","date_fmt":"%Y"}}`)
+	putObject("000000010000000000000011.json",
+		`{"000000010000000000000011":{"created_time":"2021-02-23T00:56:14Z","date_fmt":"%Y"}}`)
+	putObject("000000010000000000000010.lz4", "wal")
+	putObject("00000002.history.json", "not a metadata")
+
+	list, err := postgres.GetWalMetadataList(walFolder, "", "")
+	require.NoError(t, err)
+	require.Len(t, list, 4)
+	assert.Equal(t, "00000001000000000000000E", list[0].SegmentName)
+	assert.True(t, time.Date(2021, 2, 23, 0, 51, 14, 0, time.UTC).Equal(list[0].CreatedTime))
+	assert.Equal(t, "000000010000000000000011", list[3].SegmentName)
+
+	list, err = postgres.GetWalMetadataList(walFolder, "00000001000000000000000F", "000000010000000000000010")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "00000001000000000000000F", list[0].SegmentName)
+	assert.Equal(t, "000000010000000000000010", list[1].SegmentName)
+}
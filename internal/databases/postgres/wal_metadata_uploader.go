@@ -2,19 +2,27 @@ package postgres
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"github.com/wal-g/storages/fs"
+	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -28,11 +36,17 @@ var WalMetadataLevels = []string{WalBulkMetadataLevel, WalIndividualMetadataLeve
 type WalMetadataDescription struct {
 	CreatedTime    time.Time `json:"created_time"`
 	DatetimeFormat string    `json:"date_fmt"`
+	// SHA256 is the hex checksum of the uncompressed WAL file, empty if it was not computed at upload
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 type WalMetadataUploader struct {
 	useBulkMetadataUpload bool
 	walMetadataFolder     *fs.Folder
+	// the number of the metadata files read in parallel during the bulk merge
+	mergeConcurrency int
+	// if set, the metadata files are encrypted before upload
+	crypter crypto.Crypter
 }
 
 func NewWalMetadataUploader(walMetadataSetting string) (*WalMetadataUploader, error) {
@@ -45,20 +59,34 @@ func NewWalMetadataUploader(walMetadataSetting string) (*WalMetadataUploader, er
 	if walMetadataSetting == WalBulkMetadataLevel {
 		walMetadataUploader.useBulkMetadataUpload = true
 		walMetadataUploader.walMetadataFolder = fs.NewFolder(internal.GetRelativeArchiveDataFolderPath(), "")
+		mergeConcurrency, err := internal.GetMaxConcurrency(internal.WalMetadataMergeConcurrency)
+		if err != nil {
+			return nil, err
+		}
+		walMetadataUploader.mergeConcurrency = mergeConcurrency
+	}
+
+	if viper.GetBool(internal.EncryptWalMetadataSetting) {
+		walMetadataUploader.crypter = internal.ConfigureCrypter()
+		if walMetadataUploader.crypter == nil {
+			return nil, errors.Errorf("%s is set, but no crypter is configured", internal.EncryptWalMetadataSetting)
+		}
 	}
 
 	return walMetadataUploader, nil
 }
 
-func (u *WalMetadataUploader) UploadWalMetadata(walFileName string, createdTime time.Time, uploader *internal.Uploader) error {
+func (u *WalMetadataUploader) UploadWalMetadata(walFileName string, createdTime time.Time, checksum string,
+	uploader *internal.Uploader) error {
 	var walMetadata WalMetadataDescription
 	walMetadataMap := make(map[string]WalMetadataDescription)
 	walMetadataName := walFileName + ".json"
 	walMetadata.DatetimeFormat = MetadataDatetimeFormat
 	walMetadata.CreatedTime = createdTime
+	walMetadata.SHA256 = checksum
 	walMetadataMap[walFileName] = walMetadata
 
-	dtoBody, err := json.Marshal(walMetadataMap)
+	dtoBody, err := u.encodeWalMetadata(walMetadataMap)
 	if err != nil {
 		return errors.Wrapf(err, "Unable to marshal walmetadata")
 	}
@@ -69,11 +97,17 @@ func (u *WalMetadataUploader) UploadWalMetadata(walFileName string, createdTime
 		}
 		err = u.uploadBulkMetadataFile(walFileName, uploader)
 	} else {
-		err = uploader.Upload(walMetadataName, bytes.NewReader(dtoBody))
+		err = putWalMetadata(walMetadataName, dtoBody, uploader)
 	}
 	return errors.Wrapf(err, "upload: could not Upload metadata'%s'\n", walFileName)
 }
 
+// putWalMetadata stores the metadata file as is under the .json key. Metadata files are tiny,
+// so both individual and bulk ones bypass compression and are not accounted in the upload size.
+func putWalMetadata(walMetadataName string, dtoBody []byte, uploader *internal.Uploader) error {
+	return uploader.UploadingFolder.PutObject(walMetadataName, bytes.NewReader(dtoBody))
+}
+
 func (u *WalMetadataUploader) uploadBulkMetadataFile(walFileName string, uploader *internal.Uploader) error {
 	// Creating consolidated wal metadata only for bulk option
 	// Checking if the walfile name ends with "F" (last file in the series) and consolidating all
@@ -92,36 +126,141 @@ func (u *WalMetadataUploader) uploadBulkMetadataFile(walFileName string, uploade
 		return err
 	}
 
-	walMetadata := make(map[string]WalMetadataDescription)
+	walMetadataArray, err := u.readBulkMetadataFiles(walMetadataFiles)
+	if err != nil {
+		return err
+	}
+	dtoBody, err := u.encodeWalMetadata(walMetadataArray)
+	if err != nil {
+		return err
+	}
+	if err = putWalMetadata(walSearchString+".json", dtoBody, uploader); err != nil {
+		return errors.Wrapf(err, "Unable to upload bulk wal metadata %s", walFileName)
+	}
+	removeWalMetadataFiles(walMetadataFiles)
+	return nil
+}
+
+// readBulkMetadataFiles reads and merges the temporary metadata files using up to mergeConcurrency workers.
+// The malformed files are logged and skipped.
+func (u *WalMetadataUploader) readBulkMetadataFiles(
+	walMetadataFiles []string) (map[string]WalMetadataDescription, error) {
+	concurrency := u.mergeConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
 	walMetadataArray := make(map[string]WalMetadataDescription)
+	var mutex sync.Mutex
+	errorGroup := new(errgroup.Group)
+	semaphore := make(chan struct{}, concurrency)
+	for _, walMetadataFile := range walMetadataFiles {
+		walMetadataFile := walMetadataFile
+		semaphore <- struct{}{}
+		errorGroup.Go(func() error {
+			defer func() { <-semaphore }()
+			file, err := ioutil.ReadFile(walMetadataFile)
+			if err != nil {
+				return errors.Wrapf(err, "Unable to read walmetadata file %s", walMetadataFile)
+			}
+			walMetadata, err := decodeWalMetadata(file, u.crypter)
+			if err != nil {
+				tracelog.WarningLogger.Printf("Skipping malformed walmetadata file %s: %v", walMetadataFile, err)
+				return nil
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			for k := range walMetadata {
+				walMetadataArray[k] = walMetadata[k]
+			}
+			return nil
+		})
+	}
+	if err := errorGroup.Wait(); err != nil {
+		return nil, err
+	}
+	return walMetadataArray, nil
+}
 
+// removeWalMetadataFiles deletes the temporary metadata files merged into the bulk one.
+// The metadata is already uploaded, so the failures are only reported.
+func removeWalMetadataFiles(walMetadataFiles []string) {
+	failedFiles := make([]string, 0)
 	for _, walMetadataFile := range walMetadataFiles {
-		file, err := ioutil.ReadFile(walMetadataFile)
-		if err != nil {
-			return err
-		}
-		if err = json.Unmarshal(file, &walMetadata); err != nil {
-			return err
+		if err := os.Remove(walMetadataFile); err != nil {
+			tracelog.DebugLogger.Printf("Unable to remove walmetadata file %s: %v", walMetadataFile, err)
+			failedFiles = append(failedFiles, walMetadataFile)
 		}
+	}
+	if len(failedFiles) > 0 {
+		tracelog.WarningLogger.Printf("Unable to remove %d of %d walmetadata files: %v",
+			len(failedFiles), len(walMetadataFiles), failedFiles)
+	}
+}
 
-		for k := range walMetadata {
-			walMetadataArray[k] = walMetadata[k]
-		}
+// encodeWalMetadata marshals the metadata and encrypts it if the crypter is set
+func (u *WalMetadataUploader) encodeWalMetadata(walMetadata map[string]WalMetadataDescription) ([]byte, error) {
+	dtoBody, err := json.Marshal(walMetadata)
+	if err != nil || u.crypter == nil {
+		return dtoBody, err
 	}
-	dtoBody, err := json.Marshal(walMetadataArray)
+
+	var encrypted bytes.Buffer
+	encryptingWriter, err := u.crypter.Encrypt(&encrypted)
 	if err != nil {
-		return err
+		return nil, errors.Wrap(err, "failed to encrypt walmetadata")
 	}
-	if err = uploader.Upload(walSearchString+".json", bytes.NewReader(dtoBody)); err != nil {
-		return err
+	if _, err = encryptingWriter.Write(dtoBody); err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt walmetadata")
 	}
-	//Deleting the temporary metadata files created
-	for _, walMetadataFile := range walMetadataFiles {
-		if err = os.Remove(walMetadataFile); err != nil {
-			tracelog.InfoLogger.Printf("Unable to remove walmetadata file %s", walMetadataFile)
-		}
+	if err = encryptingWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt walmetadata")
+	}
+	return encrypted.Bytes(), nil
+}
+
+// decodeWalMetadata unmarshals the metadata file content. Plaintext JSON
+// is accepted as is, otherwise the content is decrypted with the crypter first.
+func decodeWalMetadata(body []byte, crypter crypto.Crypter) (map[string]WalMetadataDescription, error) {
+	walMetadata := make(map[string]WalMetadataDescription)
+	err := json.Unmarshal(body, &walMetadata)
+	if err == nil {
+		return walMetadata, nil
+	}
+	if crypter == nil {
+		return nil, errors.Wrap(err, "walmetadata is neither valid JSON nor can be decrypted: no crypter configured")
+	}
+
+	decryptingReader, err := crypter.Decrypt(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt walmetadata")
+	}
+	decrypted, err := ioutil.ReadAll(decryptingReader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt walmetadata")
+	}
+	walMetadata = make(map[string]WalMetadataDescription)
+	err = json.Unmarshal(decrypted, &walMetadata)
+	return walMetadata, errors.Wrap(err, "failed to unmarshal walmetadata")
+}
+
+// FetchWalMetadata downloads the metadata file from the storage folder,
+// decrypting it if it was uploaded with WALG_ENCRYPT_WAL_METADATA.
+func FetchWalMetadata(folder storage.Folder, walMetadataName string) (map[string]WalMetadataDescription, error) {
+	return fetchWalMetadata(folder, walMetadataName, internal.ConfigureCrypter())
+}
+
+func fetchWalMetadata(folder storage.Folder, walMetadataName string,
+	crypter crypto.Crypter) (map[string]WalMetadataDescription, error) {
+	reader, err := folder.ReadObject(walMetadataName)
+	if err != nil {
+		return nil, err
 	}
-	return errors.Wrapf(err, "Unable to upload bulk wal metadata %s", walFileName)
+	defer utility.LoggedClose(reader, "")
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read walmetadata '%s'", walMetadataName)
+	}
+	return decodeWalMetadata(body, crypter)
 }
 
 func checkWalMetadataLevel(walMetadataLevel string) error {
@@ -156,7 +295,30 @@ func uploadLocalWalMetadata(walFilePath string, uploader *internal.Uploader) err
 	createdTime := fileStat.ModTime().UTC()
 	walFileName := path.Base(walFilePath)
 
-	return walMetadataUploader.UploadWalMetadata(walFileName, createdTime, uploader)
+	checksum, err := computeFileChecksum(walFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "upload: could not compute checksum of wal file'%s'\n", walFilePath)
+	}
+
+	return walMetadataUploader.UploadWalMetadata(walFileName, createdTime, checksum, uploader)
+}
+
+// computeFileChecksum returns the hex SHA256 checksum of the file content
+func computeFileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer utility.LoggedClose(file, "")
+	return computeChecksum(file)
+}
+
+func computeChecksum(reader io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 func uploadRemoteWalMetadata(walFileName string, uploader *internal.Uploader) error {
@@ -174,5 +336,5 @@ func uploadRemoteWalMetadata(walFileName string, uploader *internal.Uploader) er
 	//machine and may not have access to the pg_wal/pg_xlog folder on the postgres cluster machine.
 	createdTime := time.Now().UTC()
 
-	return walMetadataUploader.UploadWalMetadata(walFileName, createdTime, uploader)
+	return walMetadataUploader.UploadWalMetadata(walFileName, createdTime, "", uploader)
 }
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/fs"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/crypto/openpgp"
+)
+
+const walMetadataTestKeyPath = "../../../test/testdata/waleGpgKey"
+
+func noWalMetadataTestPassphrase() (string, bool) {
+	return "", false
+}
+
+func TestWalMetadataUploader_EncryptedBulkMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal_metadata")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	storageFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	uploader := internal.NewUploader(nil, storageFolder)
+	walMetadataUploader := &WalMetadataUploader{
+		useBulkMetadataUpload: true,
+		walMetadataFolder:     fs.NewFolder(dir, ""),
+		crypter:               openpgp.CrypterFromKeyPath(walMetadataTestKeyPath, noWalMetadataTestPassphrase),
+	}
+
+	createdTime := time.Now().UTC()
+	for _, walFileName := range []string{"00000001000000000000000E", "00000001000000000000000F"} {
+		err = walMetadataUploader.UploadWalMetadata(walFileName, createdTime, "", uploader)
+		require.NoError(t, err)
+	}
+
+	reader, err := storageFolder.ReadObject("00000001000000000000000.json")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.False(t, json.Valid(body), "metadata must not be stored as plaintext JSON")
+
+	walMetadata, err := decodeWalMetadata(body, walMetadataUploader.crypter)
+	require.NoError(t, err)
+	assert.Len(t, walMetadata, 2)
+	assert.True(t, createdTime.Equal(walMetadata["00000001000000000000000E"].CreatedTime))
+}
+
+func TestDecodeWalMetadata_Plaintext(t *testing.T) {
+	walMetadata, err := decodeWalMetadata([]byte(`{"00000001000000000000000E":{"date_fmt":"%Y"}}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "%Y", walMetadata["00000001000000000000000E"].DatetimeFormat)
+
+	_, err = decodeWalMetadata([]byte("not a json"), nil)
+	assert.Error(t, err)
+}
+
+func TestWalMetadataUploader_IndividualMetadataIsRawJSON(t *testing.T) {
+	storageFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	uploader := internal.NewUploader(nil, storageFolder)
+	walMetadataUploader := &WalMetadataUploader{}
+
+	err := walMetadataUploader.UploadWalMetadata("00000001000000000000000E", time.Now().UTC(), "", uploader)
+	require.NoError(t, err)
+
+	walMetadata, err := FetchWalMetadata(storageFolder, "00000001000000000000000E.json")
+	require.NoError(t, err)
+	assert.Contains(t, walMetadata, "00000001000000000000000E")
+	uploadedSize, err := uploader.UploadedDataSize()
+	require.NoError(t, err)
+	assert.Zero(t, uploadedSize, "metadata must not be accounted as uploaded WAL data")
+}
+
+func TestWalMetadataUploader_BulkMergeSkipsMalformedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal_metadata")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	storageFolder := memory.NewFolder("in_memory/", memory.NewStorage())
+	uploader := internal.NewUploader(nil, storageFolder)
+	walMetadataUploader := &WalMetadataUploader{
+		useBulkMetadataUpload: true,
+		walMetadataFolder:     fs.NewFolder(dir, ""),
+		mergeConcurrency:      4,
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "00000001000000000000000A.json"), []byte("not a json"), 0644)
+	require.NoError(t, err)
+
+	for _, walFileName := range []string{"00000001000000000000000D", "00000001000000000000000E", "00000001000000000000000F"} {
+		err = walMetadataUploader.UploadWalMetadata(walFileName, time.Now().UTC(), "", uploader)
+		require.NoError(t, err)
+	}
+
+	walMetadata, err := FetchWalMetadata(storageFolder, "00000001000000000000000.json")
+	require.NoError(t, err)
+	assert.Len(t, walMetadata, 3)
+	tempFiles, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, tempFiles)
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/wal-g/wal-g/internal"
 
@@ -29,6 +30,32 @@ func (err CantOverwriteWalFileError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type WalRoundtripMismatchError struct {
+	error
+}
+
+func newWalRoundtripMismatchError(walFilePath string) WalRoundtripMismatchError {
+	return WalRoundtripMismatchError{
+		errors.Errorf("WAL file '%s' downloaded after upload differs from the local one", walFilePath)}
+}
+
+func (err WalRoundtripMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type BgUploadFailedError struct {
+	error
+}
+
+func newBgUploadFailedError(walFilenames []string) BgUploadFailedError {
+	return BgUploadFailedError{errors.Errorf("background upload failed for WAL files: %s",
+		strings.Join(walFilenames, ", "))}
+}
+
+func (err BgUploadFailedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
 // TODO : unit tests
 // HandleWALPush is invoked to perform wal-g wal-push
 func HandleWALPush(uploader *WalUploader, walFilePath string) {
@@ -40,38 +67,76 @@ func HandleWALPush(uploader *WalUploader, walFilePath string) {
 			tracelog.ErrorLogger.Printf("unmark wal-g status for %s file failed due following error %+v", walFilePath, err)
 		}
 		err = uploadLocalWalMetadata(walFilePath, uploader.Uploader)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		return
 	}
 
 	concurrency, err := internal.GetMaxUploadConcurrency()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	totalBgUploadedLimit := viper.GetInt32(internal.TotalBgUploadedLimit)
 	preventWalOverwrite := viper.GetBool(internal.PreventWalOverwriteSetting)
 	readyRename := viper.GetBool(internal.PgReadyRename)
+	lookaheadCount, err := getWalLookaheadCount(totalBgUploadedLimit - 1)
+	internal.FatalOnError(err)
 
-	bgUploader := NewBgUploader(walFilePath, int32(concurrency-1), totalBgUploadedLimit-1, uploader, preventWalOverwrite, readyRename)
+	bgUploader := NewBgUploader(walFilePath, int32(concurrency-1), totalBgUploadedLimit-1, lookaheadCount,
+		uploader, preventWalOverwrite, readyRename)
 	// Look for new WALs while doing main upload
 	bgUploader.Start()
 
 	err = uploadWALFile(uploader, walFilePath, bgUploader.preventWalOverwrite)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	err = uploadLocalWalMetadata(walFilePath, uploader.Uploader)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
-	err = bgUploader.Stop()
-	tracelog.ErrorLogger.FatalOnError(err)
+	summary, err := bgUploader.Stop()
+	internal.FatalOnError(err)
+	handleBgUploadSummary(summary)
 
 	if uploader.getUseWalDelta() {
 		uploader.FlushFiles()
 	}
 }
 
+// getWalLookaheadCount returns WALG_WAL_LOOKAHEAD_COUNT, the background uploader looks for
+// as many segments as it may upload if the setting is not set
+func getWalLookaheadCount(maxNumUploaded int32) (int32, error) {
+	if !viper.IsSet(internal.WalLookaheadCountSetting) {
+		return maxNumUploaded, nil
+	}
+	lookaheadCount := viper.GetInt32(internal.WalLookaheadCountSetting)
+	if lookaheadCount < 0 {
+		return 0, errors.Errorf("%s must not be negative, got %d", internal.WalLookaheadCountSetting, lookaheadCount)
+	}
+	return lookaheadCount, nil
+}
+
+// handleBgUploadSummary logs the work done by the background uploader and fails wal-push
+// if any background upload failed and WALG_FAIL_ON_BG_UPLOAD_ERROR is set
+func handleBgUploadSummary(summary BgUploadSummary) {
+	if summary.Uploaded == 0 && summary.Skipped == 0 && summary.Failed == 0 {
+		return
+	}
+	tracelog.InfoLogger.Printf("Background upload: %d WAL files uploaded, %d skipped, %d failed\n",
+		summary.Uploaded, summary.Skipped, summary.Failed)
+	if summary.Failed == 0 {
+		return
+	}
+	err := newBgUploadFailedError(summary.FailedFiles)
+	if viper.GetBool(internal.FailOnBgUploadErrorSetting) {
+		internal.FatalOnError(err)
+	}
+	tracelog.WarningLogger.Println(err)
+}
+
 // TODO : unit tests
 // uploadWALFile from FS to the cloud
 func uploadWALFile(uploader *WalUploader, walFilePath string, preventWalOverwrite bool) error {
-	if preventWalOverwrite {
+	// the partial segment grows until it is complete, its archived copy may be overwritten
+	partialSegmentOverwrite := viper.GetBool(internal.PartialWalSegmentsSetting) &&
+		isPartialWalFilename(filepath.Base(walFilePath))
+	if preventWalOverwrite && !partialSegmentOverwrite {
 		overwriteAttempt, err := checkWALOverwrite(uploader, walFilePath)
 		if overwriteAttempt {
 			return err
@@ -84,32 +149,66 @@ func uploadWALFile(uploader *WalUploader, walFilePath string, preventWalOverwrit
 		return errors.Wrapf(err, "upload: could not open '%s'\n", walFilePath)
 	}
 	err = uploader.UploadWalFile(walFile)
-	return errors.Wrapf(err, "upload: could not Upload '%s'\n", walFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "upload: could not Upload '%s'\n", walFilePath)
+	}
+	if viper.GetBool(internal.WalVerifyRoundtripSetting) {
+		return verifyWALRoundtrip(uploader, walFilePath)
+	}
+	return nil
+}
+
+// verifyWALRoundtrip downloads just uploaded WAL file back and compares it with the local one
+func verifyWALRoundtrip(uploader *WalUploader, walFilePath string) error {
+	startTime := utility.TimeNowCrossPlatformUTC()
+	archived, equal, err := compareWALWithArchived(uploader, walFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "upload: could not verify '%s' by round-trip download\n", walFilePath)
+	}
+	if !archived {
+		return errors.Errorf("upload: WAL file '%s' is not found in storage after upload", walFilePath)
+	}
+	if !equal {
+		return newWalRoundtripMismatchError(walFilePath)
+	}
+	tracelog.InfoLogger.Printf("WAL file '%s' round-trip verification took %v",
+		walFilePath, utility.TimeNowCrossPlatformUTC().Sub(startTime))
+	return nil
 }
 
 // TODO : unit tests
 func checkWALOverwrite(uploader *WalUploader, walFilePath string) (overwriteAttempt bool, err error) {
+	archived, equal, err := compareWALWithArchived(uploader, walFilePath)
+	if err != nil || !archived {
+		return false, err
+	}
+
+	if !equal {
+		return true, newCantOverwriteWalFileError(walFilePath)
+	}
+	tracelog.InfoLogger.Printf("WAL file '%s' already archived with equal content, skipping", walFilePath)
+	return true, nil
+}
+
+// compareWALWithArchived downloads and decompresses archived WAL file and compares its content with the local one
+func compareWALWithArchived(uploader *WalUploader, walFilePath string) (archived bool, equal bool, err error) {
 	walFileReader, err := internal.DownloadAndDecompressStorageFile(uploader.UploadingFolder, filepath.Base(walFilePath))
 	if err != nil {
 		if _, ok := err.(internal.ArchiveNonExistenceError); ok {
 			err = nil
 		}
-		return false, err
+		return false, false, err
 	}
 
-	archived, err := ioutil.ReadAll(walFileReader)
+	archivedBytes, err := ioutil.ReadAll(walFileReader)
 	if err != nil {
-		return false, err
+		return true, false, err
 	}
 
 	localBytes, err := ioutil.ReadFile(walFilePath)
 	if err != nil {
-		return false, err
+		return true, false, err
 	}
 
-	if !bytes.Equal(archived, localBytes) {
-		return true, newCantOverwriteWalFileError(walFilePath)
-	}
-	tracelog.InfoLogger.Printf("WAL file '%s' already archived with equal content, skipping", walFilePath)
-	return true, nil
+	return true, bytes.Equal(archivedBytes, localBytes), nil
 }
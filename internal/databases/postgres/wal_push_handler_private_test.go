@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestGetWalLookaheadCount(t *testing.T) {
+	defer viper.Set(internal.WalLookaheadCountSetting, nil)
+
+	lookaheadCount, err := getWalLookaheadCount(31)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(31), lookaheadCount)
+
+	viper.Set(internal.WalLookaheadCountSetting, "4")
+	lookaheadCount, err = getWalLookaheadCount(31)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), lookaheadCount)
+
+	viper.Set(internal.WalLookaheadCountSetting, "-1")
+	_, err = getWalLookaheadCount(31)
+	assert.Error(t, err)
+}
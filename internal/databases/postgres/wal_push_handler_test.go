@@ -49,6 +49,15 @@ func TestWalPush_IndividualMetadataUploader(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestWalPush_MetadataChecksum(t *testing.T) {
+	viper.Set(internal.UploadWalMetadata, postgres.WalIndividualMetadataLevel)
+	uploader, _, dir, testFileName := generateAndUploadWalFile(t, "1")
+	defer testtools.Cleanup(t, dir)
+	walMetadata, err := postgres.FetchWalMetadata(uploader.UploadingFolder, testFileName+".json")
+	assert.NoError(t, err)
+	assert.Len(t, walMetadata[testFileName].SHA256, 64)
+}
+
 func TestWalPush_BulkMetadataUploader(t *testing.T) {
 	viper.Set(internal.UploadWalMetadata, postgres.WalBulkMetadataLevel)
 	uploader, _, dir, testFileName := generateAndUploadWalFile(t, "F")
@@ -57,66 +57,71 @@ func HandleWALReceive(uploader *WalUploader) {
 	uploader.UploadingFolder = uploader.UploadingFolder.GetSubFolder(utility.WalPath)
 
 	slot, walSegmentBytes, err := getCurrentWalInfo()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	tracelog.DebugLogger.Printf("WAL segment bytes: %d", walSegmentBytes)
 
 	conn, err := pgconn.Connect(context.Background(), "replication=yes")
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer conn.Close(context.Background())
 
 	sysident, err := pglogrepl.IdentifySystem(context.Background(), conn)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	if slot.Exists {
+		if slot.Active {
+			internal.FatalOnError(genericWalReceiveError{
+				errors.Errorf("Replication slot %s is used by another connection, is wal-receive running already?", slot.Name)})
+		}
 		XLogPos = slot.RestartLSN
 	} else {
 		tracelog.InfoLogger.Println("Trying to create the replication slot")
 		_, err = pglogrepl.CreateReplicationSlot(context.Background(), conn, slot.Name, "",
 			pglogrepl.CreateReplicationSlotOptions{Mode: pglogrepl.PhysicalReplication})
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		XLogPos = sysident.XLogPos
 	}
 
 	// Get timeline for XLogPos from historyfile with helper function
 	timeline, err := getStartTimeline(conn, uploader, uint32(sysident.Timeline), XLogPos)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	segment = NewWalSegment(timeline, XLogPos, walSegmentBytes)
 	startReplication(conn, segment, slot.Name)
 	for {
 		streamResult, err := segment.Stream(conn, StandbyMessageTimeout)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		tracelog.DebugLogger.Printf("Successfully received wal segment %s: ", segment.Name())
 
 		switch streamResult {
 		case ProcessMessageOK:
 			// segment is a regular segemnt. Write, and create a new for this timeline.
 			err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(segment, segment.Name()))
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			err = uploadRemoteWalMetadata(segment.Name(), uploader.Uploader)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			XLogPos = segment.endLSN
 			segment, err = segment.NextWalSegment()
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 		case ProcessMessageCopyDone:
 			// segment is a partial. Write, and create a new for the next timeline.
 			err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(segment, segment.Name()))
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			err = uploadRemoteWalMetadata(segment.Name(), uploader.Uploader)
-			tracelog.ErrorLogger.FatalOnError(err)
-			timeline++
+			internal.FatalOnError(err)
+			timeline = getNextTimeline(timeline, segment)
+			tracelog.InfoLogger.Printf("Switching to timeline %d at %s\n", timeline, XLogPos)
 			timelinehistfile, err := pglogrepl.TimelineHistory(context.Background(), conn, int32(timeline))
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			tlh, err := NewTimeLineHistFile(timeline, timelinehistfile.FileName, timelinehistfile.Content)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(tlh, tlh.Name()))
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			err = uploadRemoteWalMetadata(tlh.Name(), uploader.Uploader)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			segment = NewWalSegment(timeline, XLogPos, walSegmentBytes)
 			startReplication(conn, segment, slot.Name)
 		default:
-			tracelog.ErrorLogger.FatalOnError(errors.Errorf("Unexpected result from WalSegment.Stream() %v", streamResult))
+			internal.FatalOnError(errors.Errorf("Unexpected result from WalSegment.Stream() %v", streamResult))
 		}
 	}
 }
@@ -131,9 +136,9 @@ func getStartTimeline(conn *pgconn.PgConn,
 	timelinehistfile, err := pglogrepl.TimelineHistory(context.Background(), conn, int32(systemTimeline))
 	if err == nil {
 		tlh, err := NewTimeLineHistFile(systemTimeline, timelinehistfile.FileName, timelinehistfile.Content)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		err = uploader.UploadWalFile(ioextensions.NewNamedReaderImpl(tlh, tlh.Name()))
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		return tlh.LSNToTimeLine(xLogPos)
 	}
 	if pgErr, ok := err.(*pgconn.PgError); ok {
@@ -141,14 +146,23 @@ func getStartTimeline(conn *pgconn.PgConn,
 			return systemTimeline, nil
 		}
 	}
-	return 0, nil
+	return 0, errors.Wrapf(err, "failed to read the history of timeline %d", systemTimeline)
+}
+
+// getNextTimeline returns the timeline to continue the streaming after the server ended
+// the streaming of the segment timeline, the next one if the server did not report it
+func getNextTimeline(timeline uint32, segment *WalSegment) uint32 {
+	if segment.nextTimeline > timeline {
+		return segment.nextTimeline
+	}
+	return timeline + 1
 }
 
 func startReplication(conn *pgconn.PgConn, segment *WalSegment, slotName string) {
 	tracelog.DebugLogger.Printf("Starting replication from %s: ", segment.StartLSN)
 	err := pglogrepl.StartReplication(context.Background(), conn, slotName, segment.StartLSN,
 		pglogrepl.StartReplicationOptions{Timeline: int32(segment.TimeLine), Mode: pglogrepl.PhysicalReplication})
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	tracelog.DebugLogger.Println("Started replication")
 }
 
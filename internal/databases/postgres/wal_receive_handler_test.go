@@ -0,0 +1,15 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNextTimeline(t *testing.T) {
+	segment := NewWalSegment(2, 0x1000000, 0x1000000)
+	assert.Equal(t, uint32(3), getNextTimeline(2, segment))
+
+	segment.nextTimeline = 5
+	assert.Equal(t, uint32(5), getNextTimeline(2, segment))
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/jackc/pgproto3/v2"
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 )
 
 type segmentError struct {
@@ -32,6 +33,8 @@ type WalSegment struct {
 	readIndex       int
 	writeIndex      int
 	lastMsg         *pgproto3.BackendMessage
+	// nextTimeline is the timeline reported by the server when it ended the streaming of this one
+	nextTimeline uint32
 }
 
 // The ProcessMessageResult is an enum representing possible results from the methods
@@ -99,7 +102,7 @@ func (seg *WalSegment) Name() string {
 	if seg.isComplete() {
 		return formatWALFileName(seg.TimeLine, segID)
 	}
-	return formatWALFileName(seg.TimeLine, segID) + ".partial"
+	return formatWALFileName(seg.TimeLine, segID) + PartialWalFileSuffix
 }
 
 // processMessage is a method that processes a message from Postgres and copies its data
@@ -111,7 +114,7 @@ func (seg *WalSegment) processMessage(message pgproto3.BackendMessage) (ProcessM
 		switch msg.Data[0] {
 		case pglogrepl.PrimaryKeepaliveMessageByteID:
 			pkm, err := pglogrepl.ParsePrimaryKeepaliveMessage(msg.Data[1:])
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			tracelog.DebugLogger.Println("Primary Keepalive Message =>",
 				"ServerWALEnd:", pkm.ServerWALEnd, "ServerTime:", pkm.ServerTime,
 				"ReplyRequested:", pkm.ReplyRequested)
@@ -121,7 +124,7 @@ func (seg *WalSegment) processMessage(message pgproto3.BackendMessage) (ProcessM
 			}
 		case pglogrepl.XLogDataByteID:
 			xld, err := pglogrepl.ParseXLogData(msg.Data[1:])
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			if xld.WALStart > seg.endLSN {
 				// This message started after this segment ended
 				return ProcessMessageMismatch, segmentError{
@@ -168,10 +171,12 @@ func (seg *WalSegment) Stream(conn *pgconn.PgConn, standbyMessageTimeout time.Du
 	nextStandbyMessageDeadline := time.Now()
 	for {
 		if time.Now().After(nextStandbyMessageDeadline) {
+			// The WAL before this segment is uploaded already, reporting it as flushed
+			// advances the restart_lsn of the slot, so the next wal-receive resumes from here
 			err = pglogrepl.SendStandbyStatusUpdate(context.Background(),
 				conn,
 				pglogrepl.StandbyStatusUpdate{WALWritePosition: seg.StartLSN})
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			tracelog.DebugLogger.Println("Sent Standby status message")
 			nextStandbyMessageDeadline = time.Now().Add(standbyMessageTimeout)
 		}
@@ -182,7 +187,7 @@ func (seg *WalSegment) Stream(conn *pgconn.PgConn, standbyMessageTimeout time.Du
 		if pgconn.Timeout(err) {
 			continue
 		}
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 
 		result, err := seg.processMessage(msg)
 		switch result {
@@ -194,8 +199,11 @@ func (seg *WalSegment) Stream(conn *pgconn.PgConn, standbyMessageTimeout time.Du
 			return result, err
 		case ProcessMessageCopyDone:
 			cdr, err := pglogrepl.SendStandbyCopyDone(context.Background(), conn)
-			tracelog.ErrorLogger.FatalOnError(err)
+			internal.FatalOnError(err)
 			tracelog.DebugLogger.Printf("CopyDoneResult => %v", cdr)
+			if cdr != nil && cdr.Timeline > 0 {
+				seg.nextTimeline = uint32(cdr.Timeline)
+			}
 			return result, nil
 		case ProcessMessageReplyRequested:
 			if seg.isComplete() {
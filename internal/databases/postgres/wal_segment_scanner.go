@@ -13,6 +13,8 @@ const (
 	ProbablyDelayed
 	// Segment exists in storage
 	Found
+	// Segment exists in storage, but its content does not match the checksum from the WAL metadata
+	Corrupted
 )
 
 type ScannedSegmentDescription struct {
@@ -26,7 +28,7 @@ func newScannedSegmentDescription(description WalSegmentDescription,
 }
 
 func (status ScannedSegmentStatus) String() string {
-	return [...]string{"", "MISSING_LOST", "MISSING_UPLOADING", "MISSING_DELAYED", "FOUND"}[status]
+	return [...]string{"", "MISSING_LOST", "MISSING_UPLOADING", "MISSING_DELAYED", "FOUND", "CORRUPTED"}[status]
 }
 
 // MarshalText marshals the ScannedSegmentStatus enum as a string
@@ -26,6 +26,8 @@ type TimelineInfo struct {
 	Backups          []*BackupDetail `json:"backups,omitempty"`
 	SegmentRangeSize uint64          `json:"segment_range_size"`
 	Status           string          `json:"status"`
+	// ColdSegmentsCount is the number of the segments stored in the cold storage tier
+	ColdSegmentsCount int `json:"cold_segments_count,omitempty"`
 }
 
 func NewTimelineInfo(walSegments *WalSegmentsSequence, historyRecords []*TimelineHistoryRecord) (*TimelineInfo, error) {
@@ -118,10 +120,11 @@ func (seq *WalSegmentsSequence) FindMissingSegments() ([]WalSegmentDescription,
 // groups WAL segments by the timeline and shows detailed info about each timeline stored in storage
 func HandleWalShow(rootFolder storage.Folder, showBackups bool, outputWriter WalShowOutputWriter) {
 	walFolder := rootFolder.GetSubFolder(utility.WalPath)
-	filenames, err := getFolderFilenames(walFolder)
-	tracelog.ErrorLogger.FatalfOnError("Failed to get the WAL folder filenames %v\n", err)
+	filenames, coldFilenames, err := getWalFolderFilenames(walFolder)
+	internal.FatalfOnError("Failed to get the WAL folder filenames %v\n", err)
 
 	walSegments := getSegmentsFromFiles(filenames)
+	coldSegments := getSegmentsFromFiles(coldFilenames)
 	segmentsByTimelines := groupSegmentsByTimelines(walSegments)
 
 	timelineInfos := make([]*TimelineInfo, 0, len(segmentsByTimelines))
@@ -129,18 +132,23 @@ func HandleWalShow(rootFolder storage.Folder, showBackups bool, outputWriter Wal
 		historyRecords, err := getTimeLineHistoryRecords(segmentsSequence.timelineID, walFolder)
 		if err != nil {
 			if _, ok := err.(HistoryFileNotFoundError); !ok {
-				tracelog.ErrorLogger.Fatalf("Error while loading .history file %v\n", err)
+				internal.Fatalf("Error while loading .history file %v\n", err)
 			}
 		}
 
 		info, err := NewTimelineInfo(segmentsSequence, historyRecords)
-		tracelog.ErrorLogger.FatalfOnError("Error while creating TimeLineInfo %v\n", err)
+		internal.FatalfOnError("Error while creating TimeLineInfo %v\n", err)
+		for segment := range coldSegments {
+			if segment.Timeline == info.ID {
+				info.ColdSegmentsCount++
+			}
+		}
 		timelineInfos = append(timelineInfos, info)
 	}
 
 	if showBackups {
 		timelineInfos, err = addBackupsInfo(timelineInfos, rootFolder)
-		tracelog.ErrorLogger.FatalfOnError("Failed to add backups info: %v\n", err)
+		internal.FatalfOnError("Failed to add backups info: %v\n", err)
 	}
 
 	// order timelines by ID
@@ -149,7 +157,24 @@ func HandleWalShow(rootFolder storage.Folder, showBackups bool, outputWriter Wal
 	})
 
 	err = outputWriter.Write(timelineInfos)
-	tracelog.ErrorLogger.FatalfOnError("Error writing output: %v\n", err)
+	internal.FatalfOnError("Error writing output: %v\n", err)
+}
+
+// getWalFolderFilenames returns the filenames of the WAL folder
+// and the ones of them stored in the cold storage tier
+func getWalFolderFilenames(walFolder storage.Folder) (filenames, coldFilenames []string, err error) {
+	objects, _, err := walFolder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	filenames = make([]string, 0, len(objects))
+	for _, object := range objects {
+		filenames = append(filenames, object.GetName())
+		if internal.GetObjectTier(object) == internal.ColdTierName {
+			coldFilenames = append(coldFilenames, object.GetName())
+		}
+	}
+	return filenames, coldFilenames, nil
 }
 
 func groupSegmentsByTimelines(segments map[WalSegmentDescription]bool) map[uint32]*WalSegmentsSequence {
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/wal-g/storages/memory"
 	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/compression/lz4"
 	"github.com/wal-g/wal-g/utility"
@@ -243,6 +244,21 @@ func TestWalShow_MultipleTimelines(t *testing.T) {
 }
 
 // testSingleTimeline is used to test wal-show with only one timeline in WAL storage
+func TestWalShow_ColdSegments(t *testing.T) {
+	hot := setupTestStorageFolder()
+	cold := setupTestStorageFolder()
+	putWalSegments([]string{"000000010000000000000002.lz4", "000000010000000000000003.lz4"},
+		hot.GetSubFolder(utility.WalPath))
+	putWalSegments([]string{"000000010000000000000001.lz4"}, cold.GetSubFolder(utility.WalPath))
+
+	mockOutputWriter := &MockWalShowOutputWriter{}
+	postgres.HandleWalShow(internal.NewTieredFolder(hot, cold), false, mockOutputWriter)
+
+	assert.Len(t, mockOutputWriter.timelineInfos, 1)
+	assert.Equal(t, 3, mockOutputWriter.timelineInfos[0].SegmentsCount)
+	assert.Equal(t, 1, mockOutputWriter.timelineInfos[0].ColdSegmentsCount)
+}
+
 func testSingleTimeline(t *testing.T, setup *TestTimelineSetup, walFolderFiles map[string]*bytes.Buffer) {
 	timelines := executeWalShow(setup.GetWalFilenames(), walFolderFiles)
 	assert.Len(t, timelines, 1)
@@ -44,8 +44,17 @@ func (writer *WalShowTableOutputWriter) Write(timelineInfos []*TimelineInfo) err
 	tableWriter.SetOutputMirror(writer.output)
 	defer tableWriter.Render()
 
+	// the cold segments are shown only if some of the segments were moved to the cold storage tier
+	includeColdSegments := false
+	for _, tl := range timelineInfos {
+		includeColdSegments = includeColdSegments || tl.ColdSegmentsCount > 0
+	}
+
 	header := table.Row{"TLI", "Parent TLI", "Switchpoint LSN", "Start segment",
 		"End segment", "Segment range", "Segments count", "Status"}
+	if includeColdSegments {
+		header = append(header, "Cold segments count")
+	}
 	if writer.includeBackups {
 		header = append(header, "Backups count")
 	}
@@ -54,6 +63,9 @@ func (writer *WalShowTableOutputWriter) Write(timelineInfos []*TimelineInfo) err
 	for _, tl := range timelineInfos {
 		row := table.Row{tl.ID, tl.ParentID, tl.SwitchPointLsn, tl.StartSegment,
 			tl.EndSegment, tl.SegmentRangeSize, tl.SegmentsCount, tl.Status}
+		if includeColdSegments {
+			row = append(row, tl.ColdSegmentsCount)
+		}
 		if writer.includeBackups {
 			row = append(row, len(tl.Backups))
 		}
@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -86,16 +87,16 @@ func (err NoCorrectBackupFoundError) Error() string {
 // QueryCurrentWalSegment() gets start WAL segment from Postgres cluster
 func QueryCurrentWalSegment() WalSegmentDescription {
 	conn, err := Connect()
-	tracelog.ErrorLogger.FatalfOnError("Failed to establish a connection to Postgres cluster %v", err)
+	internal.FatalfOnError("Failed to establish a connection to Postgres cluster %v", err)
 
 	queryRunner, err := NewPgQueryRunner(conn)
-	tracelog.ErrorLogger.FatalfOnError("Failed to initialize PgQueryRunner %v", err)
+	internal.FatalfOnError("Failed to initialize PgQueryRunner %v", err)
 
 	currentSegmentNo, err := getCurrentWalSegmentNo(queryRunner)
-	tracelog.ErrorLogger.FatalfOnError("Failed to get current WAL segment number %v", err)
+	internal.FatalfOnError("Failed to get current WAL segment number %v", err)
 
 	currentTimeline, err := getCurrentTimeline(conn)
-	tracelog.ErrorLogger.FatalfOnError("Failed to get current timeline %v", err)
+	internal.FatalfOnError("Failed to get current timeline %v", err)
 
 	tracelog.InfoLogger.Printf("Current WAL segment: %s\n", currentSegmentNo.getFilename(currentTimeline))
 
@@ -111,6 +112,7 @@ func BuildWalVerifyCheckRunner(
 	rootFolder storage.Folder,
 	walFolderFilenames []string,
 	currentWalSegment WalSegmentDescription,
+	checkContent bool,
 ) (WalVerifyCheckRunner, error) {
 	var checkRunner WalVerifyCheckRunner
 	var err error
@@ -118,7 +120,7 @@ func BuildWalVerifyCheckRunner(
 	case WalVerifyTimelineCheck:
 		checkRunner, err = NewTimelineCheckRunner(walFolderFilenames, currentWalSegment)
 	case WalVerifyIntegrityCheck:
-		checkRunner, err = NewIntegrityCheckRunner(rootFolder, walFolderFilenames, currentWalSegment)
+		checkRunner, err = NewIntegrityCheckRunner(rootFolder, walFolderFilenames, currentWalSegment, checkContent)
 	default:
 		return nil, NewUnknownWalVerifyCheckError(checkType)
 	}
@@ -130,35 +132,39 @@ func BuildWalVerifyCheckRunner(
 }
 
 // HandleWalVerify builds a check runner for each check type
-// and writes the check results to the provided output writer
+// and writes the check results to the provided output writer.
+// If checkContent is set, the integrity check confirms the existence of each found segment in storage
+// and verifies the segments against the checksums from the WAL metadata.
 func HandleWalVerify(
 	checkTypes []WalVerifyCheckType,
 	rootFolder storage.Folder,
 	currentWalSegment WalSegmentDescription,
 	outputWriter WalVerifyOutputWriter,
+	checkContent bool,
 ) {
 	checkResults := make(map[WalVerifyCheckType]WalVerifyCheckResult, len(checkTypes))
 
 	// pre-fetch WAL folder filenames to reduce storage load
 	walFolderFilenames, err := getFolderFilenames(rootFolder.GetSubFolder(utility.WalPath))
-	tracelog.ErrorLogger.FatalfOnError("Failed to fetch WAL folder filenames: %v", err)
+	internal.FatalfOnError("Failed to fetch WAL folder filenames: %v", err)
 
 	for _, checkType := range checkTypes {
 		tracelog.InfoLogger.Printf("Building check runner: %s\n", checkType)
-		runner, err := BuildWalVerifyCheckRunner(checkType, rootFolder, walFolderFilenames, currentWalSegment)
-		tracelog.ErrorLogger.FatalfOnError(
+		runner, err := BuildWalVerifyCheckRunner(checkType, rootFolder, walFolderFilenames,
+			currentWalSegment, checkContent)
+		internal.FatalfOnError(
 			fmt.Sprintf("Failed to build check runner %s:", checkType), err)
 
 		tracelog.InfoLogger.Printf("Running the check: %s\n", runner.Type().String())
 		result, err := runner.Run()
-		tracelog.ErrorLogger.FatalfOnError(
+		internal.FatalfOnError(
 			fmt.Sprintf("Failed to run the check %s:", checkType), err)
 
 		checkResults[runner.Type()] = result
 	}
 
 	err = outputWriter.Write(checkResults)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 // get the current wal segment number of the cluster
@@ -653,7 +653,7 @@ func executeWalVerify(
 	checkTypes := []postgres.WalVerifyCheckType{
 		postgres.WalVerifyTimelineCheck, postgres.WalVerifyIntegrityCheck}
 
-	postgres.HandleWalVerify(checkTypes, rootFolder, currentWalSegment, mockOutputWriter)
+	postgres.HandleWalVerify(checkTypes, rootFolder, currentWalSegment, mockOutputWriter, false)
 
 	return mockOutputWriter.lastResult, mockOutputWriter.writeCallsCount
 }
@@ -413,7 +413,7 @@ func testWalk(t *testing.T, useRatingComposer bool) {
 }
 
 func setupTestTarBallComposerMaker(useRatingComposer bool) postgres.TarBallComposerMaker {
-	filePackOptions := postgres.NewTarBallFilePackerOptions(false, false)
+	filePackOptions := postgres.NewTarBallFilePackerOptions(false, false, false, postgres.FileChangePolicyPad)
 	if !useRatingComposer {
 		return postgres.NewRegularTarBallComposerMaker(filePackOptions)
 	}
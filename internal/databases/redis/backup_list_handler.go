@@ -16,16 +16,16 @@ import (
 
 // TODO : unit tests
 func HandleDetailedBackupList(folder storage.Folder, pretty bool, json bool) {
-	backups, err := internal.GetBackups(folder)
+	backups, err := internal.GetCompleteBackups(folder)
 	if len(backups) == 0 {
 		tracelog.InfoLogger.Println("No backups found")
 		return
 	}
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	// if details are requested we append content of metadata.json to each line
 
 	backupDetails, err := GetBackupsDetails(folder, backups)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	switch {
 	case json:
@@ -35,7 +35,7 @@ func HandleDetailedBackupList(folder storage.Folder, pretty bool, json bool) {
 	default:
 		err = writeBackupListDetails(backupDetails, os.Stdout)
 	}
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 }
 
 func GetBackupsDetails(folder storage.Folder, backups []internal.BackupTime) ([]archive.Backup, error) {
@@ -80,7 +80,7 @@ func writeBackupListDetails(backupDetails []archive.Backup, output io.Writer) er
 	return nil
 }
 
-//TODO : unit tests
+// TODO : unit tests
 func writePrettyBackupListDetails(backupDetails []archive.Backup, output io.Writer) {
 	writer := table.NewWriter()
 	writer.SetOutputMirror(output)
@@ -3,7 +3,6 @@ package redis
 import (
 	"os/exec"
 
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/redis/archive"
 	"github.com/wal-g/wal-g/utility"
@@ -11,7 +10,7 @@ import (
 
 func HandleBackupPush(uploader *internal.Uploader, backupCmd *exec.Cmd, metaConstructor internal.MetaConstructor) error {
 	stdout, err := utility.StartCommandWithStdoutPipe(backupCmd)
-	tracelog.ErrorLogger.FatalfOnError("failed to start backup create command: %v", err)
+	internal.FatalfOnError("failed to start backup create command: %v", err)
 
 	redisUploader := archive.NewRedisStorageUploader(uploader)
 
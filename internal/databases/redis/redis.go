@@ -4,7 +4,6 @@ import (
 	"strconv"
 
 	"github.com/go-redis/redis"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -18,7 +17,7 @@ func GetSettingWithLocalDefault(key string, defaultValue string) string {
 	return defaultValue
 }
 
-//getRedisConnection
+// getRedisConnection
 func _() *redis.Client {
 	redisAddr := GetSettingWithLocalDefault("WALG_REDIS_HOST", "localhost")
 	redisPort := GetSettingWithLocalDefault("WALG_REDIS_PORT", "6379")
@@ -28,7 +27,7 @@ func _() *redis.Client {
 	if ok {
 		redisDBValue, err := strconv.Atoi(redisDBStr)
 		// DISCUSS: could redisDB changed on success without additional variable redisDBValue?
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		redisDB = redisDBValue
 	}
 	return redis.NewClient(&redis.Options{
@@ -19,25 +19,25 @@ func HandleBackupPush(dbnames []string, updateLatest bool, compression bool) {
 	defer func() { _ = signalHandler.Close() }()
 
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	db, err := getSQLServerConnection()
-	tracelog.ErrorLogger.FatalfOnError("failed to connect to SQLServer: %v", err)
+	internal.FatalfOnError("failed to connect to SQLServer: %v", err)
 
 	dbnames, err = getDatabasesToBackup(db, dbnames)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
-	tracelog.ErrorLogger.FatalfOnError("failed to list databases to backup: %v", err)
+	internal.FatalfOnError("failed to list databases to backup: %v", err)
 
 	bs, err := blob.NewServer(folder)
-	tracelog.ErrorLogger.FatalfOnError("proxy create error: %v", err)
+	internal.FatalfOnError("proxy create error: %v", err)
 
 	lock, err := bs.AcquireLock()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer func() { tracelog.ErrorLogger.PrintOnError(lock.Unlock()) }()
 
 	err = bs.RunBackground(ctx, cancel)
-	tracelog.ErrorLogger.FatalfOnError("proxy run error: %v", err)
+	internal.FatalfOnError("proxy run error: %v", err)
 
 	server, _ := os.Hostname()
 	timeStart := utility.TimeNowCrossPlatformLocal()
@@ -45,11 +45,11 @@ func HandleBackupPush(dbnames []string, updateLatest bool, compression bool) {
 	var sentinel *SentinelDto
 	if updateLatest {
 		backup, err := internal.GetBackupByName(internal.LatestString, utility.BaseBackupPath, folder)
-		tracelog.ErrorLogger.FatalfOnError("can't find latest backup: %v", err)
+		internal.FatalfOnError("can't find latest backup: %v", err)
 		backupName = backup.Name
 		sentinel = new(SentinelDto)
 		err = backup.FetchSentinel(&sentinel)
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 		sentinel.Databases = uniq(append(sentinel.Databases, dbnames...))
 	} else {
 		backupName = generateDatabaseBackupName()
@@ -62,13 +62,13 @@ func HandleBackupPush(dbnames []string, updateLatest bool, compression bool) {
 	err = runParallel(func(i int) error {
 		return backupSingleDatabase(ctx, db, backupName, dbnames[i], compression)
 	}, len(dbnames))
-	tracelog.ErrorLogger.FatalfOnError("overall backup failed: %v", err)
+	internal.FatalfOnError("overall backup failed: %v", err)
 
 	sentinel.StopLocalTime = utility.TimeNowCrossPlatformLocal()
 	uploader := internal.NewUploader(nil, folder.GetSubFolder(utility.BaseBackupPath))
 	tracelog.InfoLogger.Printf("uploading sentinel: %s", sentinel)
 	err = internal.UploadSentinel(uploader, sentinel, backupName)
-	tracelog.ErrorLogger.FatalfOnError("failed to save sentinel: %v", err)
+	internal.FatalfOnError("failed to save sentinel: %v", err)
 
 	tracelog.InfoLogger.Printf("backup finished")
 }
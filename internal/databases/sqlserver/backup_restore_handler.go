@@ -21,30 +21,30 @@ func HandleBackupRestore(backupName string, dbnames []string, fromnames []string
 	defer func() { _ = signalHandler.Close() }()
 
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	sentinel := new(SentinelDto)
 	err = backup.FetchSentinel(&sentinel)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	db, err := getSQLServerConnection()
-	tracelog.ErrorLogger.FatalfOnError("failed to connect to SQLServer: %v", err)
+	internal.FatalfOnError("failed to connect to SQLServer: %v", err)
 
 	dbnames, fromnames, err = getDatabasesToRestore(sentinel, dbnames, fromnames)
-	tracelog.ErrorLogger.FatalfOnError("failed to list databases to restore: %v", err)
+	internal.FatalfOnError("failed to list databases to restore: %v", err)
 
 	bs, err := blob.NewServer(folder)
-	tracelog.ErrorLogger.FatalfOnError("proxy create error: %v", err)
+	internal.FatalfOnError("proxy create error: %v", err)
 
 	lock, err := bs.AcquireLock()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer func() { tracelog.ErrorLogger.PrintOnError(lock.Unlock()) }()
 
 	err = bs.RunBackground(ctx, cancel)
-	tracelog.ErrorLogger.FatalfOnError("proxy run error: %v", err)
+	internal.FatalfOnError("proxy run error: %v", err)
 
 	backupName = backup.Name
 
@@ -60,7 +60,7 @@ func HandleBackupRestore(backupName string, dbnames []string, fromnames []string
 		}
 		return nil
 	}, len(dbnames))
-	tracelog.ErrorLogger.FatalfOnError("overall restore failed: %v", err)
+	internal.FatalfOnError("overall restore failed: %v", err)
 
 	tracelog.InfoLogger.Printf("restore finished")
 }
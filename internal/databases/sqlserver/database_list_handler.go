@@ -6,7 +6,6 @@ import (
 	"os"
 	"syscall"
 
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -16,14 +15,14 @@ func HandleDatabaseList(backupName string) {
 	signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
 	defer func() { _ = signalHandler.Close() }()
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
 	if err != nil {
-		tracelog.ErrorLogger.Fatalf("can't find backup %s: %v", backupName, err)
+		internal.Fatalf("can't find backup %s: %v", backupName, err)
 	}
 	sentinel := new(SentinelDto)
 	err = backup.FetchSentinel(&sentinel)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	for _, name := range sentinel.Databases {
 		fmt.Println(name)
 	}
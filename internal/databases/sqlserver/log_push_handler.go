@@ -19,31 +19,31 @@ func HandleLogPush(dbnames []string, compression bool) {
 	defer func() { _ = signalHandler.Close() }()
 
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	db, err := getSQLServerConnection()
-	tracelog.ErrorLogger.FatalfOnError("failed to connect to SQLServer: %v", err)
+	internal.FatalfOnError("failed to connect to SQLServer: %v", err)
 
 	dbnames, err = getDatabasesToBackup(db, dbnames)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
-	tracelog.ErrorLogger.FatalfOnError("failed to list databases to backup: %v", err)
+	internal.FatalfOnError("failed to list databases to backup: %v", err)
 
 	bs, err := blob.NewServer(folder)
-	tracelog.ErrorLogger.FatalfOnError("proxy create error: %v", err)
+	internal.FatalfOnError("proxy create error: %v", err)
 
 	lock, err := bs.AcquireLock()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer func() { tracelog.ErrorLogger.PrintOnError(lock.Unlock()) }()
 
 	err = bs.RunBackground(ctx, cancel)
-	tracelog.ErrorLogger.FatalfOnError("proxy run error: %v", err)
+	internal.FatalfOnError("proxy run error: %v", err)
 
 	logBackupName := generateLogBackupName()
 	err = runParallel(func(i int) error {
 		return backupSingleLog(ctx, db, logBackupName, dbnames[i], compression)
 	}, len(dbnames))
-	tracelog.ErrorLogger.FatalfOnError("overall log backup failed: %v", err)
+	internal.FatalfOnError("overall log backup failed: %v", err)
 
 	tracelog.InfoLogger.Printf("log backup finished")
 }
@@ -22,36 +22,36 @@ func HandleLogRestore(backupName string, untilTS string, dbnames []string, fromn
 	defer func() { _ = signalHandler.Close() }()
 
 	folder, err := internal.ConfigureFolder()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	sentinel := new(SentinelDto)
 	err = backup.FetchSentinel(&sentinel)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 
 	db, err := getSQLServerConnection()
-	tracelog.ErrorLogger.FatalfOnError("failed to connect to SQLServer: %v", err)
+	internal.FatalfOnError("failed to connect to SQLServer: %v", err)
 
 	dbnames, fromnames, err = getDatabasesToRestore(sentinel, dbnames, fromnames)
-	tracelog.ErrorLogger.FatalfOnError("failed to list databases to restore logs: %v", err)
+	internal.FatalfOnError("failed to list databases to restore logs: %v", err)
 
 	bs, err := blob.NewServer(folder)
-	tracelog.ErrorLogger.FatalfOnError("proxy create error: %v", err)
+	internal.FatalfOnError("proxy create error: %v", err)
 
 	lock, err := bs.AcquireLock()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer func() { tracelog.ErrorLogger.PrintOnError(lock.Unlock()) }()
 
 	err = bs.RunBackground(ctx, cancel)
-	tracelog.ErrorLogger.FatalfOnError("proxy run error: %v", err)
+	internal.FatalfOnError("proxy run error: %v", err)
 
 	stopAt, err := utility.ParseUntilTS(untilTS)
-	tracelog.ErrorLogger.FatalfOnError("invalid util timestamp: %v", err)
+	internal.FatalfOnError("invalid util timestamp: %v", err)
 
 	logs, err := getLogsSinceBackup(folder, backup.Name, stopAt)
-	tracelog.ErrorLogger.FatalfOnError("failed to list log backups: %v", err)
+	internal.FatalfOnError("failed to list log backups: %v", err)
 
 	err = runParallel(func(i int) error {
 		dbname := dbnames[i]
@@ -78,7 +78,7 @@ func HandleLogRestore(backupName string, untilTS string, dbnames []string, fromn
 		}
 		return nil
 	}, len(dbnames))
-	tracelog.ErrorLogger.FatalfOnError("overall log restore failed: %v", err)
+	internal.FatalfOnError("overall log restore failed: %v", err)
 
 	tracelog.InfoLogger.Printf("log restore finished")
 }
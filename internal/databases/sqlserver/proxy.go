@@ -7,6 +7,7 @@ import (
 
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/sqlserver/blob"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -16,10 +17,10 @@ func RunProxy(folder storage.Folder) {
 	signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
 	defer func() { _ = signalHandler.Close() }()
 	bs, err := blob.NewServer(folder)
-	tracelog.ErrorLogger.FatalfOnError("proxy create error: %v", err)
+	internal.FatalfOnError("proxy create error: %v", err)
 	lock, err := bs.AcquireLock()
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnError(err)
 	defer func() { tracelog.ErrorLogger.PrintOnError(lock.Unlock()) }()
 	err = bs.Run(ctx)
-	tracelog.ErrorLogger.FatalfOnError("proxy run error: %v", err)
+	internal.FatalfOnError("proxy run error: %v", err)
 }
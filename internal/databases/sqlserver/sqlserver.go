@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"github.com/wal-g/storages/storage"
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/sqlserver/blob"
 	"github.com/wal-g/wal-g/utility"
@@ -274,7 +273,7 @@ func quoteValue(val string) string {
 }
 
 func generateDatabaseBackupName() string {
-	return utility.BackupNamePrefix + utility.TimeNowCrossPlatformUTC().Format(utility.BackupTimeFormat)
+	return utility.GetBackupNamePrefix() + utility.TimeNowCrossPlatformUTC().Format(utility.BackupTimeFormat)
 }
 
 func getDatabaseBackupPath(backupName, dbname string) string {
@@ -284,7 +283,7 @@ func getDatabaseBackupPath(backupName, dbname string) string {
 func getDatabaseBackupURL(backupName, dbname string) string {
 	hostname, err := internal.GetRequiredSetting(internal.SQLServerBlobHostname)
 	if err != nil {
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	}
 	backupName = url.QueryEscape(backupName)
 	dbname = url.QueryEscape(dbname)
@@ -302,7 +301,7 @@ func getLogBackupPath(logBackupName, dbname string) string {
 func getLogBackupURL(logBackupName, dbname string) string {
 	hostname, err := internal.GetRequiredSetting(internal.SQLServerBlobHostname)
 	if err != nil {
-		tracelog.ErrorLogger.FatalOnError(err)
+		internal.FatalOnError(err)
 	}
 	logBackupName = url.QueryEscape(logBackupName)
 	dbname = url.QueryEscape(dbname)
@@ -348,10 +347,10 @@ func listBackupBlobs(folder storage.Folder) ([]string, error) {
 }
 
 func getLogsSinceBackup(folder storage.Folder, backupName string, stopAt time.Time) ([]string, error) {
-	if !strings.HasPrefix(backupName, utility.BackupNamePrefix) {
+	if !strings.HasPrefix(backupName, utility.GetBackupNamePrefix()) {
 		return nil, fmt.Errorf("unexpected backup name: %s", backupName)
 	}
-	startTS := backupName[len(utility.BackupNamePrefix):]
+	startTS := backupName[len(utility.GetBackupNamePrefix()):]
 	endTS := stopAt.Format(utility.BackupTimeFormat)
 	_, logBackups, err := folder.GetSubFolder(utility.WalPath).ListFolder()
 	if err != nil {
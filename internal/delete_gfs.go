@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	DeleteGFSUsageExample = "gfs class=count..."
+	DeleteGFSExamples     = `  gfs daily=7 weekly=4 monthly=12   keep 7 daily, 4 weekly and 12 monthly backups
+  gfs daily=7 yearly=3              keep 7 daily and 3 yearly backups, backups of the other classes are kept`
+)
+
+// RetentionClasses are the classes of backups rotated by delete gfs
+var RetentionClasses = []string{"hourly", "daily", "weekly", "monthly", "yearly"}
+
+type InvalidRetentionClassError struct {
+	error
+}
+
+func NewInvalidRetentionClassError(retentionClass string) InvalidRetentionClassError {
+	return InvalidRetentionClassError{errors.Errorf("invalid retention class '%s', expected one of: %s",
+		retentionClass, strings.Join(RetentionClasses, ", "))}
+}
+
+func (err InvalidRetentionClassError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+func ValidateRetentionClass(retentionClass string) error {
+	for _, class := range RetentionClasses {
+		if retentionClass == class {
+			return nil
+		}
+	}
+	return NewInvalidRetentionClassError(retentionClass)
+}
+
+// ParseGFSRetentionCounts parses the class=count arguments of delete gfs
+func ParseGFSRetentionCounts(args []string) (map[string]int, error) {
+	retentionCounts := make(map[string]int, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected the class=count argument, but got: '%s'", arg)
+		}
+		class, countStr := parts[0], parts[1]
+		if err := ValidateRetentionClass(class); err != nil {
+			return nil, err
+		}
+		if _, ok := retentionCounts[class]; ok {
+			return nil, fmt.Errorf("retention class '%s' is specified more than once", class)
+		}
+		count, err := strconv.Atoi(countStr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "expected to get a number as retention count of '%s', but got: '%s'",
+				class, countStr)
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("cannot retain a negative number of '%s' backups", class)
+		}
+		retentionCounts[class] = count
+	}
+	return retentionCounts, nil
+}
+
+func DeleteGFSArgsValidator(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one class=count argument")
+	}
+	_, err := ParseGFSRetentionCounts(args)
+	return err
+}
+
+func (h *DeleteHandler) HandleDeleteGFS(args []string, retentionClasses map[string]string, confirmed bool) {
+	retentionCounts, err := ParseGFSRetentionCounts(args)
+	FatalOnError(err)
+
+	targets := h.FindGFSTargets(retentionCounts, retentionClasses)
+	if len(targets) == 0 {
+		tracelog.InfoLogger.Printf("No backup found for deletion")
+		return
+	}
+	err = h.DeleteGFSTargets(targets, confirmed)
+	FatalOnError(err)
+}
+
+// FindGFSTargets returns the backups rotated out by the grandfather-father-son policy:
+// the backups of each class in retentionCounts except the most recent ones of the class.
+// Backups without a class or of a class not in retentionCounts, permanent backups
+// and the increment chains of the retained backups are never returned.
+func (h *DeleteHandler) FindGFSTargets(retentionCounts map[string]int,
+	retentionClasses map[string]string) []BackupObject {
+	sort.Slice(h.backups, func(i, j int) bool {
+		return h.greater(h.backups[i], h.backups[j])
+	})
+
+	backupsByName := make(map[string]BackupObject, len(h.backups))
+	for _, backup := range h.backups {
+		backupsByName[backup.GetBackupName()] = backup
+	}
+
+	retained := make(map[string]bool, len(h.backups))
+	retainWithChain := func(backup BackupObject) {
+		for curr, ok := backup, true; ok && !retained[curr.GetBackupName()]; {
+			retained[curr.GetBackupName()] = true
+			if curr.IsFullBackup() {
+				break
+			}
+			curr, ok = backupsByName[curr.GetIncrementFromName()]
+		}
+	}
+
+	classCounts := make(map[string]int, len(retentionCounts))
+	for _, backup := range h.backups {
+		class := retentionClasses[backup.GetBackupName()]
+		retentionCount, isRotated := retentionCounts[class]
+		if !isRotated || h.isPermanent(backup) || classCounts[class] < retentionCount {
+			retainWithChain(backup)
+		}
+		if isRotated {
+			classCounts[class]++
+		}
+	}
+
+	targets := make([]BackupObject, 0)
+	for _, backup := range h.backups {
+		if !retained[backup.GetBackupName()] {
+			targets = append(targets, backup)
+		}
+	}
+	return targets
+}
+
+// DeleteGFSTargets deletes the targets and the WAL segments before the oldest retained backup
+func (h *DeleteHandler) DeleteGFSTargets(targets []BackupObject, confirmed bool) error {
+	targetNames := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		targetNames[target.GetBackupName()] = true
+	}
+	var oldestRetained BackupObject
+	for _, backup := range h.backups {
+		if !targetNames[backup.GetBackupName()] && (oldestRetained == nil || h.less(backup, oldestRetained)) {
+			oldestRetained = backup
+		}
+	}
+
+	tracelog.InfoLogger.Println("Start delete")
+	err := h.DeleteTargets(targets, confirmed)
+	if err != nil || oldestRetained == nil {
+		return err
+	}
+	return storage.DeleteObjectsWhere(h.Folder, confirmed, func(object storage.Object) bool {
+		return strings.HasPrefix(object.GetName(), utility.WalPath) &&
+			h.less(object, oldestRetained) && !h.isPermanent(object)
+	})
+}
@@ -2,7 +2,6 @@ package internal
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,6 +20,7 @@ const (
 	FindFullDeleteModifier
 	ForceDeleteModifier
 	ConfirmFlag            = "confirm"
+	DryRunFlag             = "dry-run"
 	DeleteShortDescription = "Clears old backups and WALs"
 
 	DeleteRetainExamples = `  retain 5                      keep 5 backups
@@ -29,7 +29,8 @@ const (
   retain 5 --after 2019-12-12T12:12:12   keep 5 most recent backups and backups made after 2019-12-12 12:12:12`
 
 	DeleteBeforeExamples = `  before base_0123              keep everything after base_0123 including itself
-  before FIND_FULL base_0123    keep everything after the base of base_0123`
+  before FIND_FULL base_0123    keep everything after the base of base_0123
+  before 2019-12-12T12:12:12Z   keep the backups made after 2019-12-12 12:12:12 and everything after them`
 
 	DeleteEverythingExamples = `  everything                
 	delete every backup only if there is no permanent backups
@@ -38,7 +39,7 @@ const (
 	DeleteTargetExamples = `  target base_0000000100000000000000C4	delete base backup by name
   target --target-user-data "{ \"x\": [3], \"y\": 4 }"	delete backup specified by user data
   target base_0000000100000000000000C9_D_0000000100000000000000C4	delete delta backup and all dependant delta backups 
-  target FIND_FULL base_0000000100000000000000C9_D_0000000100000000000000C4	delete delta backup and all delta backups with the same base backup`  //nolint:lll
+  target FIND_FULL base_0000000100000000000000C9_D_0000000100000000000000C4	delete delta backup and all delta backups with the same base backup` //nolint:lll
 
 	DeleteEverythingUsageExample = "everything [FORCE]"
 	DeleteRetainUsageExample     = "retain [FULL|FIND_FULL] backup_count"
@@ -75,6 +76,14 @@ func IsPermanentFunc(isPermanent func(storage.Object) bool) DeleteHandlerOption
 	}
 }
 
+// AfterDeleteFunc makes the handler call afterDelete once the backups are deleted, e.g. to delete
+// the objects shared by the backups which none of the remaining backups references
+func AfterDeleteFunc(afterDelete func(confirmed bool) error) DeleteHandlerOption {
+	return func(h *DeleteHandler) {
+		h.afterDelete = afterDelete
+	}
+}
+
 func NewDeleteHandler(
 	folder storage.Folder,
 	backups []BackupObject,
@@ -90,6 +99,7 @@ func NewDeleteHandler(
 		},
 		// by default, all storage objects are impermanent
 		isPermanent: func(storage.Object) bool { return false },
+		afterDelete: func(bool) error { return nil },
 	}
 
 	for _, option := range options {
@@ -107,6 +117,7 @@ type DeleteHandler struct {
 	greater func(object1, object2 storage.Object) bool
 
 	isPermanent func(object storage.Object) bool
+	afterDelete func(confirmed bool) error
 }
 
 func (h *DeleteHandler) HandleDeleteBefore(args []string, confirmed bool) {
@@ -120,35 +131,35 @@ func (h *DeleteHandler) HandleDeleteBefore(args []string, confirmed bool) {
 		target, err = h.FindTargetBeforeName(beforeStr, modifier)
 	}
 
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		Exit(0)
 	}
 
 	err = h.DeleteBeforeTarget(target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetain(args []string, confirmed bool) {
 	modifier, retentionStr := extractDeleteModifierFromArgs(args)
 	retentionCount, err := strconv.Atoi(retentionStr)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 
 	target, err := h.FindTargetRetain(retentionCount, modifier)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		Exit(0)
 	}
 	err = h.DeleteBeforeTarget(target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetainAfter(args []string, confirmed bool) {
 	modifier, retentionSir, afterStr := extractDeleteRetainModifierFromArgs(args)
 	retentionCount, err := strconv.Atoi(retentionSir)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 
 	var target BackupObject
 	timeLine, err := time.Parse(time.RFC3339, afterStr)
@@ -157,20 +168,20 @@ func (h *DeleteHandler) HandleDeleteRetainAfter(args []string, confirmed bool) {
 	} else {
 		target, err = h.FindTargetRetainAfterName(retentionCount, afterStr, modifier)
 	}
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		Exit(0)
 	}
 
 	err = h.DeleteBeforeTarget(target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteTarget(targetSelector BackupSelector, confirmed, findFull bool) {
 	targetName, err := targetSelector.Select(h.Folder)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 
 	var target BackupObject
 	for idx := range h.backups {
@@ -182,7 +193,7 @@ func (h *DeleteHandler) HandleDeleteTarget(targetSelector BackupSelector, confir
 
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		Exit(0)
 	}
 
 	var backupsToDelete []BackupObject
@@ -195,7 +206,7 @@ func (h *DeleteHandler) HandleDeleteTarget(targetSelector BackupSelector, confir
 	}
 
 	err = h.DeleteTargets(backupsToDelete, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 }
 
 func (h *DeleteHandler) HandleDeleteEverything(args []string, permanentBackups map[string]bool, confirmed bool) {
@@ -207,7 +218,7 @@ func (h *DeleteHandler) HandleDeleteEverything(args []string, permanentBackups m
 
 	if len(permanentBackups) > 0 {
 		if !forceModifier {
-			tracelog.ErrorLogger.Fatalf("Found permanent backups=%v\n", permanentBackups)
+			Fatalf("Found permanent backups=%v\n", permanentBackups)
 		}
 		tracelog.InfoLogger.Printf("Found permanent backups=%v\n", permanentBackups)
 	}
@@ -325,7 +336,7 @@ func (h *DeleteHandler) FindTargetRetainAfterTime(retentionCount int, timeLine t
 func (h *DeleteHandler) DeleteEverything(confirmed bool) {
 	filter := func(object storage.Object) bool { return true }
 	err := storage.DeleteObjectsWhere(h.Folder, confirmed, filter)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnError(err)
 }
 
 func (h *DeleteHandler) DeleteBeforeTarget(target BackupObject, confirmed bool) error {
@@ -333,26 +344,69 @@ func (h *DeleteHandler) DeleteBeforeTarget(target BackupObject, confirmed bool)
 		errorMessage := "%v is incremental and it's predecessors cannot be deleted. Consider FIND_FULL option."
 		return utility.NewForbiddenActionError(fmt.Sprintf(errorMessage, target.GetName()))
 	}
+	if orphaned := h.findOrphanedDeltas(target); len(orphaned) > 0 {
+		errorMessage := "deleting the backups before %v would orphan the retained delta backups %v"
+		return utility.NewForbiddenActionError(fmt.Sprintf(errorMessage, target.GetName(), orphaned))
+	}
 	tracelog.InfoLogger.Println("Start delete")
 
-	return storage.DeleteObjectsWhere(h.Folder, confirmed, func(object storage.Object) bool {
+	err := storage.DeleteObjectsWhere(h.Folder, confirmed, func(object storage.Object) bool {
 		return h.less(object, target) && !h.isPermanent(object)
 	})
+	if err != nil {
+		return err
+	}
+	return h.afterDelete(confirmed)
 }
 
 func (h *DeleteHandler) DeleteTargets(targets []BackupObject, confirmed bool) error {
 	backupNamesToDelete := make(map[string]bool)
 	for _, target := range targets {
 		if h.isPermanent(target) {
-			tracelog.ErrorLogger.Fatalf("Unable to delete permanent backup %s\n", target.GetName())
+			Fatalf("Unable to delete permanent backup %s\n", target.GetName())
 		}
 		backupNamesToDelete[target.GetBackupName()] = true
 	}
 
-	return storage.DeleteObjectsWhere(h.Folder.GetSubFolder(utility.BaseBackupPath),
+	err := storage.DeleteObjectsWhere(h.Folder.GetSubFolder(utility.BaseBackupPath),
 		confirmed, func(object storage.Object) bool {
 			return backupNamesToDelete[utility.StripLeftmostBackupName(object.GetName())] && !h.isPermanent(object)
 		})
+	if err != nil {
+		return err
+	}
+	return h.afterDelete(confirmed)
+}
+
+// Find the retained delta backups which increment chain
+// includes the backup that would be deleted before the target.
+func (h *DeleteHandler) findOrphanedDeltas(target BackupObject) []string {
+	backupsByName := make(map[string]BackupObject, len(h.backups))
+	for _, backup := range h.backups {
+		backupsByName[backup.GetBackupName()] = backup
+	}
+
+	orphaned := make([]string, 0)
+	for _, backup := range h.backups {
+		if backup.IsFullBackup() || h.less(backup, target) {
+			continue
+		}
+		visited := map[string]bool{backup.GetBackupName(): true}
+		for curr := backup; !curr.IsFullBackup(); {
+			incrementFrom, ok := backupsByName[curr.GetIncrementFromName()]
+			if !ok || visited[incrementFrom.GetBackupName()] {
+				break
+			}
+			if h.less(incrementFrom, target) && !h.isPermanent(incrementFrom) {
+				orphaned = append(orphaned, backup.GetBackupName())
+				break
+			}
+			visited[incrementFrom.GetBackupName()] = true
+			curr = incrementFrom
+		}
+	}
+	sort.Strings(orphaned)
+	return orphaned
 }
 
 // Find all backups related to the target.
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/tracing"
+)
+
+const (
+	ErrorOutputText = "text"
+	ErrorOutputJSON = "json"
+
+	UnknownErrorCategory = "unknown"
+
+	// the prefix and the time format of the text messages of tracelog.ErrorLogger
+	errorLogPrefix     = "ERROR: "
+	errorLogTimeFormat = "2006/01/02 15:04:05.000000"
+)
+
+type errorCategoryType struct {
+	category  string
+	errorType reflect.Type
+}
+
+// errorCategoryTypes map the typed errors to their categories, the error chain is searched for them with errors.As
+var errorCategoryTypes []errorCategoryType
+
+func init() {
+	RegisterErrorCategory("backup_not_found", new(BackupNonExistenceError))
+	RegisterErrorCategory("backup_not_found", new(NoBackupsFoundError))
+	RegisterErrorCategory("object_not_found", new(storage.ObjectNotFoundError))
+	RegisterErrorCategory("storage_read_only", new(StorageReadOnlyError))
+	RegisterErrorCategory("configuration", new(UnsetRequiredSettingError))
+	RegisterErrorCategory("configuration", new(InvalidConcurrencyValueError))
+	RegisterErrorCategory("configuration", new(UnknownCompressionMethodError))
+	RegisterErrorCategory("configuration", new(UnconfiguredStorageError))
+}
+
+// RegisterErrorCategory makes the errors of the target type reported with the category in the JSON error output.
+// The target is a pointer to the error type, as for errors.As, e.g. new(BackupNonExistenceError).
+func RegisterErrorCategory(category string, target interface{}) {
+	errorCategoryTypes = append(errorCategoryTypes, errorCategoryType{category, reflect.TypeOf(target).Elem()})
+}
+
+// GetErrorCategory returns the category of the first registered error type found in the error chain
+func GetErrorCategory(err error) string {
+	if err == nil {
+		return UnknownErrorCategory
+	}
+	for _, categoryType := range errorCategoryTypes {
+		if errors.As(err, reflect.New(categoryType.errorType).Interface()) {
+			return categoryType.category
+		}
+	}
+	return UnknownErrorCategory
+}
+
+// fatalError is the error WAL-G exits on, it is set by FatalError to be categorized in the JSON error output
+var fatalError error
+
+// FatalError logs the error and exits like tracelog.ErrorLogger.FatalError,
+// the JSON error output reports the error with the category of its type
+func FatalError(err error) {
+	logFatal(err, fmt.Sprintf(tracelog.GetErrorFormatter(), err))
+}
+
+// FatalOnError logs the error and exits like tracelog.ErrorLogger.FatalOnError,
+// the JSON error output reports the error with the category of its type
+func FatalOnError(err error) {
+	if err != nil {
+		FatalError(err)
+	}
+}
+
+// FatalfOnError logs the error and exits like tracelog.ErrorLogger.FatalfOnError,
+// the JSON error output reports the error with the category of its type
+func FatalfOnError(format string, err error) {
+	if err != nil {
+		logFatal(err, fmt.Sprintf(format, err))
+	}
+}
+
+// Fatal logs the message and exits like tracelog.ErrorLogger.Fatal
+func Fatal(v ...interface{}) {
+	logFatal(nil, fmt.Sprint(v...))
+}
+
+// Fatalf logs the message and exits like tracelog.ErrorLogger.Fatalf
+func Fatalf(format string, v ...interface{}) {
+	logFatal(nil, fmt.Sprintf(format, v...))
+}
+
+// logFatal writes the message WAL-G exits on with the error logger, runs the exit hooks and exits.
+// Every fatal error of WAL-G goes through it, tracelog.ErrorLogger.Fatal* skip the exit hooks.
+func logFatal(err error, message string) {
+	fatalError = err
+	if writer, ok := tracelog.ErrorLogger.Writer().(*jsonErrorWriter); ok {
+		_, _ = writer.write([]byte(message), true)
+	} else {
+		_ = tracelog.ErrorLogger.Output(3, message)
+	}
+	Exit(1)
+}
+
+// ErrorRecord is the single-line JSON object written for each error in the JSON error output
+type ErrorRecord struct {
+	Time      string `json:"time"`
+	Category  string `json:"category"`
+	Message   string `json:"message"`
+	Operation string `json:"operation,omitempty"`
+	// TraceID is the trace ID of the command if its spans are exported to WALG_OTEL_ENDPOINT
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// jsonErrorWriter writes the message WAL-G exits on as an ErrorRecord, the other messages
+// are written as text, the way the error logger writes them. The logger passes each message to a single Write.
+type jsonErrorWriter struct {
+	output    io.Writer
+	operation *string
+}
+
+func (writer *jsonErrorWriter) Write(message []byte) (int, error) {
+	return writer.write(message, false)
+}
+
+func (writer *jsonErrorWriter) write(message []byte, isFatal bool) (int, error) {
+	if !isFatal {
+		_, err := fmt.Fprintf(writer.output, "%s%s %s", errorLogPrefix, time.Now().Format(errorLogTimeFormat), message)
+		if err != nil {
+			return 0, err
+		}
+		return len(message), nil
+	}
+	text := strings.TrimSpace(string(message))
+	record, err := json.Marshal(ErrorRecord{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Category:  GetErrorCategory(fatalError),
+		Message:   text,
+		Operation: *writer.operation,
+		TraceID:   tracing.TraceID(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	_, err = fmt.Fprintf(writer.output, "%s\n", record)
+	if err != nil {
+		return 0, err
+	}
+	return len(message), nil
+}
+
+// errorOperation is the command reported in the JSON error output
+var errorOperation string
+
+// SetErrorOperation sets the command reported in the JSON error output
+func SetErrorOperation(operation string) {
+	errorOperation = operation
+}
+
+// configureErrorOutput makes the error logger write the error WAL-G exits on as JSON if WALG_ERROR_OUTPUT is json
+func configureErrorOutput() error {
+	switch errorOutput := viper.GetString(ErrorOutputSetting); errorOutput {
+	case ErrorOutputText:
+		return nil
+	case ErrorOutputJSON:
+		setJSONErrorOutput(os.Stderr)
+		return nil
+	default:
+		return errors.Errorf("unknown %s value '%s', expected '%s' or '%s'",
+			ErrorOutputSetting, errorOutput, ErrorOutputText, ErrorOutputJSON)
+	}
+}
+
+func setJSONErrorOutput(output io.Writer) {
+	tracelog.ErrorLogger = tracelog.NewErrorLogger(&jsonErrorWriter{output, &errorOperation}, "")
+	tracelog.ErrorLogger.SetFlags(0)
+}
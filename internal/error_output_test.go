@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+func TestGetErrorCategory(t *testing.T) {
+	assert.Equal(t, "backup_not_found",
+		GetErrorCategory(errors.Wrap(NewBackupNonExistenceError("base_000"), "failed to fetch backup")))
+	assert.Equal(t, "backup_not_found", GetErrorCategory(NewNoBackupsFoundError()))
+	assert.Equal(t, "object_not_found", GetErrorCategory(storage.NewObjectNotFoundError("a/b")))
+	assert.Equal(t, "storage_read_only", GetErrorCategory(newStorageReadOnlyError("delete", "a/b")))
+	assert.Equal(t, "configuration", GetErrorCategory(NewUnsetRequiredSettingError(PgDataSetting)))
+	// the category is taken from the type, not from the message
+	assert.Equal(t, UnknownErrorCategory, GetErrorCategory(errors.New(NewNoBackupsFoundError().Error())))
+	assert.Equal(t, UnknownErrorCategory, GetErrorCategory(errors.New("something went wrong")))
+	assert.Equal(t, UnknownErrorCategory, GetErrorCategory(nil))
+}
+
+func TestJSONErrorOutput(t *testing.T) {
+	errorLogger := tracelog.ErrorLogger
+	defer func() { tracelog.ErrorLogger = errorLogger }()
+	SetErrorOperation("backup-fetch")
+	defer SetErrorOperation("")
+	defer func() { fatalError = nil }()
+
+	var output bytes.Buffer
+	setJSONErrorOutput(&output)
+	// the messages WAL-G doesn't exit on are written as text
+	tracelog.ErrorLogger.Printf("Failed to delete the temporary file: %v\n", errors.New("busy"))
+	assert.Contains(t, output.String(), "ERROR: ")
+	assert.Contains(t, output.String(), " Failed to delete the temporary file: busy\n")
+
+	output.Reset()
+	fatalError = errors.Wrap(NewBackupNonExistenceError("base_000"), "Failed to fetch backup")
+	_, err := tracelog.ErrorLogger.Writer().(*jsonErrorWriter).write(
+		[]byte(fatalError.Error()+"\n"), true)
+	require.NoError(t, err)
+
+	var record ErrorRecord
+	require.NoError(t, json.Unmarshal(output.Bytes(), &record))
+	assert.Equal(t, "backup_not_found", record.Category)
+	assert.Equal(t, "Failed to fetch backup: Backup 'base_000' does not exist.", record.Message)
+	assert.Equal(t, "backup-fetch", record.Operation)
+	assert.NotEmpty(t, record.Time)
+	assert.Equal(t, 1, bytes.Count(output.Bytes(), []byte("\n")))
+}
+
+func TestFatalError(t *testing.T) {
+	if os.Getenv("WALG_TEST_FATAL_ERROR") != "" {
+		FatalOnError(NewBackupNonExistenceError("base_000"))
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalError$")
+	cmd.Env = append(os.Environ(), "WALG_TEST_FATAL_ERROR=1")
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr), "the process must exit with the error: %v", err)
+	assert.Equal(t, 1, exitErr.ExitCode())
+	assert.Contains(t, string(output), "Backup 'base_000' does not exist.")
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"os"
+	"sync"
+)
+
+var (
+	exitHooksMutex sync.Mutex
+	// exitHooks run before WAL-G exits on the fatal error, when the deferred calls don't run
+	exitHooks []func()
+)
+
+// RegisterExitHook makes the hook run before WAL-G exits on the fatal error logged by FatalError,
+// FatalOnError, FatalfOnError, Fatal or Fatalf or with Exit, e.g. to flush the trace spans or to release
+// the locks. The hooks run in the reverse order of the registration, once.
+func RegisterExitHook(hook func()) {
+	exitHooksMutex.Lock()
+	defer exitHooksMutex.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+func runExitHooks() {
+	exitHooksMutex.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitHooksMutex.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// Exit runs the exit hooks and exits with the code
+func Exit(code int) {
+	runExitHooks()
+	os.Exit(code)
+}
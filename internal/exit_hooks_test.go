@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunExitHooks(t *testing.T) {
+	var calls []string
+	RegisterExitHook(func() { calls = append(calls, "first") })
+	RegisterExitHook(func() { calls = append(calls, "second") })
+
+	runExitHooks()
+	assert.Equal(t, []string{"second", "first"}, calls)
+	runExitHooks()
+	assert.Len(t, calls, 2)
+}
+
+func TestExitHooksRunOnFatalError(t *testing.T) {
+	if os.Getenv("WALG_TEST_EXIT_HOOKS") != "" {
+		viper.Set(ErrorOutputSetting, os.Getenv("WALG_TEST_EXIT_HOOKS"))
+		if err := ConfigureLogging(); err != nil {
+			panic(err)
+		}
+		RegisterExitHook(func() { fmt.Println("exit hook ran") })
+		if os.Getenv("WALG_TEST_EXIT_HOOKS_FATALF") != "" {
+			Fatalf("failed to fetch backup %s", "base_000")
+		}
+		FatalOnError(errors.New("failed to fetch backup"))
+		return
+	}
+	for _, errorOutput := range []string{"text", "json"} {
+		for _, fatalf := range []string{"", "1"} {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestExitHooksRunOnFatalError$")
+			cmd.Env = append(os.Environ(), "WALG_TEST_EXIT_HOOKS="+errorOutput, "WALG_TEST_EXIT_HOOKS_FATALF="+fatalf)
+			output, err := cmd.CombinedOutput()
+			require.Error(t, err)
+			assert.Contains(t, string(output), "failed to fetch backup", errorOutput)
+			assert.Contains(t, string(output), "exit hook ran", errorOutput)
+			if errorOutput == ErrorOutputJSON {
+				assert.Contains(t, string(output), `"message":"failed to fetch backup`, errorOutput)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"archive/tar"
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/tracing"
 	"github.com/wal-g/wal-g/utility"
 	"golang.org/x/sync/semaphore"
 )
@@ -21,6 +23,27 @@ import (
 var MinExtractRetryWait = time.Minute
 var MaxExtractRetryWait = 5 * time.Minute
 
+// decompressReadAheadSize is the size of the file buffered while it waits for a decompressing slot
+const decompressReadAheadSize = 4 << 20
+
+var (
+	sharedDownloadSemaphore     *semaphore.Weighted
+	sharedDownloadSemaphoreOnce sync.Once
+)
+
+// GetSharedDownloadSemaphore returns the semaphore bounding the downloads of the whole process
+// by WALG_DOWNLOAD_CONCURRENCY, the extraction and the downloads ahead of it share it
+func GetSharedDownloadSemaphore() (*semaphore.Weighted, error) {
+	concurrency, err := GetMaxDownloadConcurrency()
+	if err != nil {
+		return nil, err
+	}
+	sharedDownloadSemaphoreOnce.Do(func() {
+		sharedDownloadSemaphore = semaphore.NewWeighted(int64(concurrency))
+	})
+	return sharedDownloadSemaphore, nil
+}
+
 type NoFilesToExtractError struct {
 	error
 }
@@ -100,46 +123,64 @@ func extractOne(tarInterpreter TarInterpreter, source io.Reader) error {
 // If it's tar, a decompression is not needed.
 // Otherwise it uses corresponding decompressor. If none found an error will be returned.
 func DecryptAndDecompressTar(writer io.Writer, readerMaker ReaderMaker, crypter crypto.Crypter) error {
-	readCloser, err := readerMaker.Reader()
+	return decryptAndDecompressTar(writer, readerMaker, crypter, nil)
+}
 
+// decryptAndDecompressTar is DecryptAndDecompressTar holding a slot of the decompressing semaphore, if any,
+// while the file is decrypted or decompressed. The slot is acquired after the first decompressReadAheadSize bytes
+// of the file are downloaded, so the files waiting for a free slot are downloaded meanwhile
+// and the downloading concurrency is not limited by the decompressing one.
+func decryptAndDecompressTar(writer io.Writer, readerMaker ReaderMaker, crypter crypto.Crypter,
+	decompressingSemaphore *semaphore.Weighted) error {
+	fileExtension := utility.GetFileExtension(readerMaker.Path())
+	// chunks are decrypted and decompressed one by one while reassembling
+	isChunked := fileExtension == ChunkManifestExtension
+
+	var readCloser io.ReadCloser
+	var err error
+	if chunkedReaderMaker, ok := readerMaker.(*ChunkedReaderMaker); ok {
+		readCloser, err = chunkedReaderMaker.DecryptingReader(crypter)
+	} else {
+		readCloser, err = readerMaker.Reader()
+	}
 	if err != nil {
 		return errors.Wrap(err, "DecryptAndDecompressTar: failed to create new reader")
 	}
 	defer utility.LoggedClose(readCloser, "")
 
-	if crypter != nil {
-		var reader io.Reader
-		reader, err = crypter.Decrypt(readCloser)
-		if err != nil {
-			return errors.Wrap(err, "DecryptAndDecompressTar: decrypt failed")
-		}
-		readCloser = ioextensions.ReadCascadeCloser{
-			Reader: reader,
-			Closer: readCloser,
+	if decompressingSemaphore != nil && !isChunked && (crypter != nil || fileExtension != "tar") {
+		readAheadReader := bufio.NewReaderSize(readCloser, decompressReadAheadSize)
+		if _, err = readAheadReader.Peek(decompressReadAheadSize); err != nil && err != io.EOF {
+			return errors.Wrap(err, "DecryptAndDecompressTar: failed to read the file")
 		}
+		_ = decompressingSemaphore.Acquire(context.TODO(), 1)
+		defer decompressingSemaphore.Release(1)
+		readCloser = ioextensions.ReadCascadeCloser{Reader: readAheadReader, Closer: readCloser}
 	}
 
-	fileExtension := utility.GetFileExtension(readerMaker.Path())
-	if fileExtension == "tar" {
+	if isChunked {
 		_, err = io.Copy(writer, readCloser)
-		return errors.Wrap(err, "DecryptAndDecompressTar: tar extract failed")
+		return errors.Wrap(err, "DecryptAndDecompressTar: chunked tar extract failed")
 	}
 
-	for _, decompressor := range compression.Decompressors {
-		if fileExtension != decompressor.FileExtension() {
-			continue
-		}
-		err = decompressor.Decompress(writer, readCloser)
-		if err == nil {
-			return nil
+	if fileExtension == "tar" {
+		var reader io.Reader = readCloser
+		if crypter != nil {
+			reader, err = crypter.Decrypt(readCloser)
+			if err != nil {
+				return errors.Wrap(err, "DecryptAndDecompressTar: decrypt failed")
+			}
 		}
-		decompressionError := newDecompressionError(err)
-		return errors.Wrapf(decompressionError,
-			"DecryptAndDecompressTar: %v decompress failed. Is archive encrypted?",
-			decompressor.FileExtension())
+		_, err = io.Copy(writer, reader)
+		return errors.Wrap(err, "DecryptAndDecompressTar: tar extract failed")
 	}
 
-	return newUnsupportedFileTypeError(readerMaker.Path(), fileExtension)
+	decompressor := compression.FindDecompressor(fileExtension)
+	if decompressor == nil {
+		return newUnsupportedFileTypeError(readerMaker.Path(), fileExtension)
+	}
+	err = decryptAndDecompress(writer, readCloser, crypter, decompressor, readerMaker.Path())
+	return errors.Wrap(err, "DecryptAndDecompressTar")
 }
 
 // ExtractAll Handles all files passed in. Supports `.lzo`, `.lz4`, `.lzma`, and `.tar`.
@@ -150,7 +191,19 @@ func ExtractAll(tarInterpreter TarInterpreter, files []ReaderMaker) error {
 	return ExtractAllWithSleeper(tarInterpreter, files, NewExponentialSleeper(MinExtractRetryWait, MaxExtractRetryWait))
 }
 
+// ExtractAllWithProgress is the same as ExtractAll, but calls onExtracted
+// for each file as soon as it is completely extracted. onExtracted may be called concurrently.
+func ExtractAllWithProgress(tarInterpreter TarInterpreter, files []ReaderMaker, onExtracted func(ReaderMaker)) error {
+	return extractAll(tarInterpreter, files,
+		NewExponentialSleeper(MinExtractRetryWait, MaxExtractRetryWait), onExtracted)
+}
+
 func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, sleeper Sleeper) error {
+	return extractAll(tarInterpreter, files, sleeper, nil)
+}
+
+func extractAll(tarInterpreter TarInterpreter, files []ReaderMaker, sleeper Sleeper,
+	onExtracted func(ReaderMaker)) error {
 	if len(files) == 0 {
 		return newNoFilesToExtractError()
 	}
@@ -160,8 +213,21 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 	if err != nil {
 		return err
 	}
+	decompressingConcurrency, err := GetMaxDecompressConcurrency()
+	if err != nil {
+		return err
+	}
+	var decompressingSemaphore *semaphore.Weighted
+	if decompressingConcurrency > 0 {
+		decompressingSemaphore = semaphore.NewWeighted(int64(decompressingConcurrency))
+	}
+	sharedSemaphore, err := GetSharedDownloadSemaphore()
+	if err != nil {
+		return err
+	}
 	for currentRun := files; len(currentRun) > 0; {
-		failed := tryExtractFiles(currentRun, tarInterpreter, downloadingConcurrency)
+		failed := tryExtractFiles(currentRun, tarInterpreter, downloadingConcurrency, sharedSemaphore,
+			decompressingSemaphore, onExtracted)
 		if downloadingConcurrency > 1 {
 			downloadingConcurrency /= 2
 		} else if len(failed) == len(currentRun) {
@@ -180,7 +246,10 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 // TODO : unit tests
 func tryExtractFiles(files []ReaderMaker,
 	tarInterpreter TarInterpreter,
-	downloadingConcurrency int) (failed []ReaderMaker) {
+	downloadingConcurrency int,
+	sharedSemaphore *semaphore.Weighted,
+	decompressingSemaphore *semaphore.Weighted,
+	onExtracted func(ReaderMaker)) (failed []ReaderMaker) {
 	downloadingContext := context.TODO()
 	downloadingSemaphore := semaphore.NewWeighted(int64(downloadingConcurrency))
 	crypter := ConfigureCrypter()
@@ -188,22 +257,30 @@ func tryExtractFiles(files []ReaderMaker,
 
 	for _, file := range files {
 		_ = downloadingSemaphore.Acquire(downloadingContext, 1)
+		_ = sharedSemaphore.Acquire(downloadingContext, 1)
 		fileClosure := file
 
 		extractingReader, pipeWriter := io.Pipe()
 		decompressingWriter := &EmptyWriteIgnorer{pipeWriter}
+		decompressionFailed := make(chan bool, 1)
 		go func() {
-			err := DecryptAndDecompressTar(decompressingWriter, fileClosure, crypter)
+			span := tracing.Start("decompress", tracing.PathAttribute.String(fileClosure.Path()))
+			err := decryptAndDecompressTar(decompressingWriter, fileClosure, crypter, decompressingSemaphore)
+			tracing.End(span, err)
 			utility.LoggedClose(decompressingWriter, "")
 			tracelog.InfoLogger.Printf("Finished decompression of %s", fileClosure.Path())
 			if err != nil {
 				isFailed.Store(fileClosure, true)
 				tracelog.ErrorLogger.Println(fileClosure.Path(), err)
 			}
+			decompressionFailed <- err != nil
 		}()
 		go func() {
 			defer downloadingSemaphore.Release(1)
+			defer sharedSemaphore.Release(1)
+			span := tracing.Start("extract", tracing.PathAttribute.String(fileClosure.Path()))
 			err := extractOne(tarInterpreter, extractingReader)
+			tracing.End(span, err)
 			err = errors.Wrapf(err, "Extraction error in %s", fileClosure.Path())
 			utility.LoggedClose(extractingReader, "")
 			tracelog.InfoLogger.Printf("Finished extraction of %s", fileClosure.Path())
@@ -211,6 +288,9 @@ func tryExtractFiles(files []ReaderMaker,
 				isFailed.Store(fileClosure, true)
 				tracelog.ErrorLogger.Println(err)
 			}
+			if !<-decompressionFailed && err == nil && onExtracted != nil {
+				onExtracted(fileClosure)
+			}
 		}()
 	}
 
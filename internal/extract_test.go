@@ -1,13 +1,16 @@
 package internal_test
 
 import (
+	"archive/tar"
 	"bufio"
 	"bytes"
 	"io"
 	"io/ioutil"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -144,6 +147,94 @@ func TestExtractAll_multipleConcurrentTars(t *testing.T) {
 	}
 }
 
+// concurrencyCountingReaderMaker tracks how many of the readers are being read at once
+type concurrencyCountingReaderMaker struct {
+	BufferReaderMaker
+	active    *int32
+	maxActive *int32
+}
+
+// Reader counts the open readers, the files waiting for the decompression are downloaded meanwhile
+func (r *concurrencyCountingReaderMaker) Reader() (io.ReadCloser, error) {
+	countActive(r.active, r.maxActive)
+	return &concurrencyCountingReader{r.Buf, r.active, false}, nil
+}
+
+func countActive(active, maxActive *int32) {
+	current := atomic.AddInt32(active, 1)
+	for max := atomic.LoadInt32(maxActive); current > max; max = atomic.LoadInt32(maxActive) {
+		if atomic.CompareAndSwapInt32(maxActive, max, current) {
+			break
+		}
+	}
+}
+
+// concurrencyCountingTarInterpreter tracks how many of the files are being decompressed at once,
+// the file is interpreted while its decompressor holds the decompressing slot
+type concurrencyCountingTarInterpreter struct {
+	*testtools.ConcurrentConcatBufferTarInterpreter
+	active    int32
+	maxActive int32
+}
+
+func (interpreter *concurrencyCountingTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	countActive(&interpreter.active, &interpreter.maxActive)
+	defer atomic.AddInt32(&interpreter.active, -1)
+	time.Sleep(10 * time.Millisecond)
+	return interpreter.ConcurrentConcatBufferTarInterpreter.Interpret(reader, header)
+}
+
+type concurrencyCountingReader struct {
+	io.Reader
+	active  *int32
+	started bool
+}
+
+func (r *concurrencyCountingReader) Read(p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		time.Sleep(10 * time.Millisecond)
+	}
+	return r.Reader.Read(p)
+}
+
+func (r *concurrencyCountingReader) Close() error {
+	atomic.AddInt32(r.active, -1)
+	return nil
+}
+
+func TestExtractAll_decompressConcurrency(t *testing.T) {
+	os.Setenv(internal.DownloadConcurrencySetting, "4")
+	defer os.Unsetenv(internal.DownloadConcurrencySetting)
+	os.Setenv(internal.DecompressConcurrencySetting, "2")
+	defer os.Unsetenv(internal.DecompressConcurrencySetting)
+
+	fileAmount := 8
+	var active, maxActive int32
+	bufs := [][]byte{}
+	brms := []internal.ReaderMaker{}
+	for i := 0; i < fileAmount; i++ {
+		brm, b := makeTar(strconv.Itoa(i))
+		compressed := &bytes.Buffer{}
+		_, err := compressed.ReadFrom(internal.CompressAndEncrypt(brm.Buf, GetLz4Compressor(), nil))
+		assert.NoError(t, err)
+		bufs = append(bufs, b)
+		brms = append(brms, &concurrencyCountingReaderMaker{
+			BufferReaderMaker{compressed, "/usr/local/" + strconv.Itoa(i) + ".tar.lz4"}, &active, &maxActive})
+	}
+
+	buf := &concurrencyCountingTarInterpreter{
+		ConcurrentConcatBufferTarInterpreter: testtools.NewConcurrentConcatBufferTarInterpreter()}
+	err := internal.ExtractAllWithSleeper(buf, brms, NOPSleeper{})
+	assert.NoError(t, err)
+	for i := 0; i < fileAmount; i++ {
+		assert.Equal(t, bufs[i], buf.Out[strconv.Itoa(i)], "Some of outputs do not match input")
+	}
+	// the downloads are not limited by the decompression
+	assert.Equal(t, int32(4), maxActive)
+	assert.Equal(t, int32(2), buf.maxActive)
+}
+
 func noPassphrase() (string, bool) {
 	return "", false
 }
@@ -194,6 +285,44 @@ func TestDecryptAndDecompressTar_encrypted(t *testing.T) {
 	assert.Equalf(t, bCopy, decompressed.Bytes(), "decompressed tar does not match the input")
 }
 
+func TestDecryptAndDecompressTar_compressedAfterEncryption(t *testing.T) {
+	b := generateRandomBytes()
+	crypter := openpgp.CrypterFromKeyPath(PrivateKeyFilePath, noPassphrase)
+
+	encrypted := internal.CompressAndEncrypt(bytes.NewReader(b), nil, crypter)
+	compressed := internal.CompressAndEncrypt(encrypted, GetLz4Compressor(), nil)
+	compressedBuffer, _ := ioutil.ReadAll(compressed)
+	brm := &BufferReaderMaker{bytes.NewBuffer(compressedBuffer), "/usr/local/test.tar.lz4"}
+
+	decompressed := &bytes.Buffer{}
+	err := internal.DecryptAndDecompressTar(decompressed, brm, crypter)
+	assert.NoError(t, err)
+	assert.Equal(t, b, decompressed.Bytes())
+}
+
+func TestDecryptAndDecompressTar_notEncrypted(t *testing.T) {
+	crypter := openpgp.CrypterFromKeyPath(PrivateKeyFilePath, noPassphrase)
+	compressed := internal.CompressAndEncrypt(bytes.NewReader(generateRandomBytes()), GetLz4Compressor(), nil)
+	compressedBuffer, _ := ioutil.ReadAll(compressed)
+	brm := &BufferReaderMaker{bytes.NewBuffer(compressedBuffer), "/usr/local/test.tar.lz4"}
+
+	err := internal.DecryptAndDecompressTar(&bytes.Buffer{}, brm, crypter)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "it's either not encrypted or encrypted with another key")
+}
+
+func TestDecryptAndDecompressTar_decryptedNotCompressed(t *testing.T) {
+	crypter := openpgp.CrypterFromKeyPath(PrivateKeyFilePath, noPassphrase)
+	encrypted := internal.CompressAndEncrypt(bytes.NewReader(generateRandomBytes()), nil, crypter)
+	encryptedBuffer, _ := ioutil.ReadAll(encrypted)
+	brm := &BufferReaderMaker{bytes.NewBuffer(encryptedBuffer), "/usr/local/test.tar.lz4"}
+
+	decompressed := &bytes.Buffer{}
+	err := internal.DecryptAndDecompressTar(decompressed, brm, crypter)
+	assert.IsType(t, internal.DecompressionError{}, errors.Cause(err))
+	assert.Empty(t, decompressed.Bytes())
+}
+
 func TestDecryptAndDecompressTar_noCrypter(t *testing.T) {
 	b := generateRandomBytes()
 
@@ -13,7 +13,6 @@ import (
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/compression"
-	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/utility"
 )
 
@@ -69,22 +68,13 @@ func DecompressDecryptBytes(dst io.Writer, archiveReader io.ReadCloser, decompre
 	crypter := ConfigureCrypter()
 	if crypter != nil {
 		tracelog.DebugLogger.Printf("Selected crypter: %s", crypter.Name())
-
-		reader, err := crypter.Decrypt(archiveReader)
-		if err != nil {
-			return fmt.Errorf("failed to init decrypt reader: %w", err)
-		}
-		archiveReader = ioextensions.ReadCascadeCloser{
-			Reader: reader,
-			Closer: archiveReader,
-		}
 	} else {
 		tracelog.DebugLogger.Printf("No crypter has been selected")
 	}
 
-	err := decompressor.Decompress(dst, archiveReader)
+	err := decryptAndDecompress(dst, archiveReader, crypter, decompressor, "archive")
 	if err != nil {
-		return fmt.Errorf("failed to decompress archive reader: %w", err)
+		return fmt.Errorf("failed to decrypt and decompress archive reader: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,20 @@
+// +build !windows
+
+package fsutil
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// GetAvailableSpace returns the number of bytes available to the unprivileged user
+// on the filesystem containing the path
+func GetAvailableSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get the filesystem stats of %s", path)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,12 @@
+// +build windows
+
+package fsutil
+
+import (
+	"github.com/pkg/errors"
+)
+
+// GetAvailableSpace is not implemented on Windows
+func GetAvailableSpace(path string) (uint64, error) {
+	return 0, errors.New("getting the available disk space is not supported on Windows")
+}
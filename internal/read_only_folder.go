@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+type StorageReadOnlyError struct {
+	error
+}
+
+func newStorageReadOnlyError(operation string, objectPaths ...string) StorageReadOnlyError {
+	return StorageReadOnlyError{errors.Errorf("storage configured read-only: can't %s %v", operation, objectPaths)}
+}
+
+func (err StorageReadOnlyError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// IsStorageReadOnly reports whether the writes to the storage are rejected
+// by the --read-only flag or WALG_STORAGE_READ_ONLY
+func IsStorageReadOnly() bool {
+	return isStorageReadOnlyIn(viper.GetViper())
+}
+
+func isStorageReadOnlyIn(config *viper.Viper) bool {
+	return ReadOnly || config.GetBool(StorageReadOnlySetting)
+}
+
+// ReadOnlyFolder rejects PutObject and DeleteObjects on the folder and its subfolders,
+// so the restore tooling can be pointed at the production storage safely
+type ReadOnlyFolder struct {
+	folder storage.Folder
+}
+
+func NewReadOnlyFolder(folder storage.Folder) *ReadOnlyFolder {
+	return &ReadOnlyFolder{folder}
+}
+
+func (folder *ReadOnlyFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *ReadOnlyFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	objects, subFolders, err = folder.folder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, subFolder := range subFolders {
+		subFolders[i] = NewReadOnlyFolder(subFolder)
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *ReadOnlyFolder) DeleteObjects(objectRelativePaths []string) error {
+	return newStorageReadOnlyError("delete", objectRelativePaths...)
+}
+
+func (folder *ReadOnlyFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.folder.Exists(objectRelativePath)
+}
+
+func (folder *ReadOnlyFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewReadOnlyFolder(folder.folder.GetSubFolder(subFolderRelativePath))
+}
+
+func (folder *ReadOnlyFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return folder.folder.ReadObject(objectRelativePath)
+}
+
+func (folder *ReadOnlyFolder) ReadObjectFrom(objectRelativePath string, offset int64,
+	eTag *string) (io.ReadCloser, error) {
+	return ReadObjectFrom(folder.folder, objectRelativePath, offset, eTag)
+}
+
+func (folder *ReadOnlyFolder) PutObject(name string, content io.Reader) error {
+	return newStorageReadOnlyError("put", name)
+}
@@ -0,0 +1,69 @@
+package internal_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestReadOnlyFolder_RejectsWrites(t *testing.T) {
+	base := memory.NewFolder("in_memory/", memory.NewStorage())
+	require.NoError(t, base.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("wal")))
+	folder := internal.NewReadOnlyFolder(base)
+
+	walFolder := folder.GetSubFolder("wal_005/")
+	reader, err := walFolder.ReadObject("000000010000000000000001.lz4")
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "wal", string(content))
+	exists, err := walFolder.Exists("000000010000000000000001.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	err = walFolder.PutObject("000000010000000000000002.lz4", strings.NewReader("wal"))
+	assert.IsType(t, internal.StorageReadOnlyError{}, err)
+	assert.Contains(t, err.Error(), "storage configured read-only")
+	assert.IsType(t, internal.StorageReadOnlyError{}, walFolder.DeleteObjects([]string{"000000010000000000000001.lz4"}))
+
+	_, subFolders, err := folder.ListFolder()
+	require.NoError(t, err)
+	require.Len(t, subFolders, 1)
+	assert.IsType(t, internal.StorageReadOnlyError{}, subFolders[0].PutObject("x", strings.NewReader("x")))
+
+	exists, err = base.Exists("wal_005/000000010000000000000002.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestConfigureFolderForSpecificConfig_ReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "read_only")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := viper.New()
+	config.Set("WALG_FILE_PREFIX", dir)
+	folder, err := internal.ConfigureFolderForSpecificConfig(config)
+	require.NoError(t, err)
+	assert.NoError(t, folder.PutObject("x", strings.NewReader("x")))
+
+	// the setting is read from the config of the folder, not the global one
+	viper.Set(internal.StorageReadOnlySetting, true)
+	folder, err = internal.ConfigureFolderForSpecificConfig(config)
+	viper.Set(internal.StorageReadOnlySetting, false)
+	require.NoError(t, err)
+	assert.NoError(t, folder.PutObject("x", strings.NewReader("x")))
+
+	config.Set(internal.StorageReadOnlySetting, true)
+	folder, err = internal.ConfigureFolderForSpecificConfig(config)
+	require.NoError(t, err)
+	assert.IsType(t, &internal.ReadOnlyFolder{}, folder)
+	assert.IsType(t, internal.StorageReadOnlyError{}, folder.PutObject("y", strings.NewReader("y")))
+}
@@ -0,0 +1,277 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/azure"
+	"github.com/wal-g/storages/s3"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"google.golang.org/api/googleapi"
+)
+
+// MinDownloadRetryInterval is the backoff before the first download retry, it doubles with every next retry
+var MinDownloadRetryInterval = time.Second
+
+// statusCodeError is the storage error with the HTTP status code of the response, e.g. awserr.RequestFailure
+type statusCodeError interface {
+	StatusCode() int
+}
+
+// IsRetryableDownloadError tells the transient download failures, like the timeouts and the server errors,
+// from the permanent ones, like the missing object or the denied access, which are not retried.
+// The errors not known to be transient are permanent.
+func IsRetryableDownloadError(err error) bool {
+	cause := errors.Cause(err)
+	if _, ok := cause.(storage.ObjectNotFoundError); ok {
+		return false
+	}
+	if cause == context.Canceled || os.IsPermission(cause) || os.IsNotExist(cause) {
+		return false
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return isRetryableStatusCode(googleErr.Code)
+	}
+	var azureErr azblob.StorageError
+	if errors.As(err, &azureErr) {
+		return azureErr.Response() == nil || isRetryableStatusCode(azureErr.Response().StatusCode)
+	}
+	if statusCodeErr, ok := cause.(statusCodeError); ok {
+		return isRetryableStatusCode(statusCodeErr.StatusCode())
+	}
+	if awsErr, ok := cause.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case request.ErrCodeRequestError, request.ErrCodeResponseTimeout, request.ErrCodeRead:
+			return true
+		}
+		return false
+	}
+	var opErr *net.OpError
+	var urlErr *url.Error
+	if errors.As(err, &opErr) || errors.As(err, &urlErr) {
+		return true
+	}
+	return cause == io.ErrUnexpectedEOF || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+func isRetryableStatusCode(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusRequestTimeout ||
+		code == http.StatusTooManyRequests
+}
+
+// ObjectRangeReader is the folder wrapping another one which reads the object from the offset
+// through the wrapped folder, so the downloads resumed through the wrappers still get the ranged GET of S3
+type ObjectRangeReader interface {
+	ReadObjectFrom(objectRelativePath string, offset int64, eTag *string) (io.ReadCloser, error)
+}
+
+// RetryingFolder retries the failed downloads of the objects with the exponential backoff.
+// The backoff is per object. The interrupted read is resumed from the read offset, see ReadObjectFrom.
+type RetryingFolder struct {
+	folder      storage.Folder
+	retries     int
+	maxInterval time.Duration
+}
+
+func NewRetryingFolder(folder storage.Folder, retries int, maxInterval time.Duration) *RetryingFolder {
+	return &RetryingFolder{folder, retries, maxInterval}
+}
+
+func (folder *RetryingFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *RetryingFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	objects, subFolders, err = folder.folder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, subFolder := range subFolders {
+		subFolders[i] = NewRetryingFolder(subFolder, folder.retries, folder.maxInterval)
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *RetryingFolder) DeleteObjects(objectRelativePaths []string) error {
+	return folder.folder.DeleteObjects(objectRelativePaths)
+}
+
+func (folder *RetryingFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.folder.Exists(objectRelativePath)
+}
+
+func (folder *RetryingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewRetryingFolder(folder.folder.GetSubFolder(subFolderRelativePath), folder.retries, folder.maxInterval)
+}
+
+func (folder *RetryingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader := &retryingReader{
+		folder:             folder,
+		objectRelativePath: objectRelativePath,
+		sleeper:            NewExponentialSleeper(MinDownloadRetryInterval, folder.maxInterval),
+		eTag:               new(string),
+	}
+	if err := reader.open(); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (folder *RetryingFolder) PutObject(name string, content io.Reader) error {
+	return folder.folder.PutObject(name, content)
+}
+
+// isRetryable tells if the download failure is transient. The storages library wraps the failures
+// of the Azure and the file system downloads into storage.Error hiding their cause, so it is classified by the storage:
+// the missing blob is reported as ObjectNotFoundError, the other Azure failures are the server or the network ones.
+// The file system failures, like the denied access, are permanent.
+func (folder *RetryingFolder) isRetryable(err error) bool {
+	if _, ok := errors.Cause(err).(storage.Error); ok {
+		_, isAzure := folder.folder.(*azure.Folder)
+		return isAzure
+	}
+	return IsRetryableDownloadError(err)
+}
+
+// ReadObjectFrom opens the object of the folder to read it from the offset. S3 gets the ranged GET
+// of the same object version: the ETag of the S3 object is recorded on the first read, the resumed reads fail
+// if the object is replaced since then. The ObjectRangeReader wrappers read it through the wrapped folder,
+// the other storages reopen the object and skip the bytes already read.
+func ReadObjectFrom(folder storage.Folder, objectRelativePath string, offset int64,
+	eTag *string) (io.ReadCloser, error) {
+	switch typedFolder := folder.(type) {
+	case *s3.Folder:
+		return readS3ObjectFrom(typedFolder, objectRelativePath, offset, eTag)
+	case ObjectRangeReader:
+		return typedFolder.ReadObjectFrom(objectRelativePath, offset, eTag)
+	}
+	if offset == 0 {
+		return folder.ReadObject(objectRelativePath)
+	}
+	reader, err := folder.ReadObject(objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.CopyN(ioutil.Discard, reader, offset); err != nil {
+		_ = reader.Close()
+		return nil, errors.Wrapf(err, "failed to skip %d read bytes of object '%s'", offset, objectRelativePath)
+	}
+	return reader, nil
+}
+
+func readS3ObjectFrom(folder *s3.Folder, objectRelativePath string, offset int64,
+	eTag *string) (io.ReadCloser, error) {
+	objectPath := folder.Path + objectRelativePath
+	input := &awss3.GetObjectInput{
+		Bucket: folder.Bucket,
+		Key:    aws.String(objectPath),
+	}
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		input.IfMatch = eTag
+	}
+	object, err := folder.S3API.GetObject(input)
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok &&
+			(awsErr.Code() == s3.NotFoundAWSErrorCode || awsErr.Code() == s3.NoSuchKeyAWSErrorCode) {
+			return nil, storage.NewObjectNotFoundError(objectPath)
+		}
+		if requestFailure, ok := err.(awserr.RequestFailure); ok &&
+			requestFailure.StatusCode() == http.StatusPreconditionFailed {
+			return nil, errors.Wrapf(err, "object '%s' in S3 has changed since the read started", objectPath)
+		}
+		return nil, errors.Wrapf(err, "failed to read object: '%s' from S3 from offset %d", objectPath, offset)
+	}
+	if offset == 0 {
+		*eTag = aws.StringValue(object.ETag)
+	}
+	return object.Body, nil
+}
+
+// retryingReader reopens the object from the read offset when the read fails
+type retryingReader struct {
+	folder             *RetryingFolder
+	objectRelativePath string
+	reader             io.ReadCloser
+	offset             int64
+	// eTag is the ETag of the S3 object read, the resumed reads are pinned to it
+	eTag     *string
+	attempts int
+	sleeper  Sleeper
+	// err is the failure the read has been given up on
+	err error
+}
+
+// open opens the object from the read offset, retrying the retryable failures
+func (reader *retryingReader) open() error {
+	for {
+		objectReader, err := ReadObjectFrom(reader.folder.folder, reader.objectRelativePath, reader.offset, reader.eTag)
+		if err == nil {
+			reader.reader = objectReader
+			return nil
+		}
+		if err = reader.backoff(err); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff waits before the next attempt, the error is returned as is if it is permanent or the retries are exhausted
+func (reader *retryingReader) backoff(err error) error {
+	if !reader.folder.isRetryable(err) || reader.attempts >= reader.folder.retries {
+		return err
+	}
+	reader.attempts++
+	tracelog.WarningLogger.Printf("Failed to download '%s' at offset %d, retry %d of %d: %v\n",
+		reader.objectRelativePath, reader.offset, reader.attempts, reader.folder.retries, err)
+	reader.sleeper.Sleep()
+	return nil
+}
+
+func (reader *retryingReader) Read(p []byte) (n int, err error) {
+	for {
+		if reader.reader == nil {
+			return 0, reader.err
+		}
+		n, err = reader.reader.Read(p)
+		reader.offset += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		_ = reader.reader.Close()
+		reader.reader = nil
+		if err = reader.backoff(err); err == nil {
+			err = reader.open()
+		}
+		if err != nil {
+			reader.err = err
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (reader *retryingReader) Close() error {
+	if reader.reader == nil {
+		return nil
+	}
+	return reader.reader.Close()
+}
@@ -0,0 +1,254 @@
+package internal_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/fs"
+	"github.com/wal-g/storages/memory"
+	walgs3 "github.com/wal-g/storages/s3"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+	"google.golang.org/api/googleapi"
+)
+
+func newRequestFailure(statusCode int) error {
+	return errors.Wrap(awserr.NewRequestFailure(awserr.New("code", "message", nil), statusCode, "id"),
+		"failed to read object")
+}
+
+// flakyFolder fails the first openFailures reads of the objects with openErr
+// and breaks the first reader after readLimit bytes
+type flakyFolder struct {
+	storage.Folder
+	openFailures int
+	openErr      error
+	readLimit    int64
+	opened       int
+}
+
+func (folder *flakyFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	folder.opened++
+	if folder.opened <= folder.openFailures {
+		return nil, folder.openErr
+	}
+	reader, err := folder.Folder.ReadObject(objectRelativePath)
+	if err != nil || folder.readLimit == 0 {
+		return reader, err
+	}
+	limit := folder.readLimit
+	folder.readLimit = 0
+	return ioutil.NopCloser(io.MultiReader(io.LimitReader(reader, limit), &brokenReader{})), nil
+}
+
+type brokenReader struct{}
+
+func (reader *brokenReader) Read(p []byte) (int, error) {
+	return 0, &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}
+}
+
+func TestIsRetryableDownloadError(t *testing.T) {
+	assert.True(t, internal.IsRetryableDownloadError(newRequestFailure(http.StatusServiceUnavailable)))
+	assert.True(t, internal.IsRetryableDownloadError(newRequestFailure(http.StatusTooManyRequests)))
+	assert.True(t, internal.IsRetryableDownloadError(&net.OpError{Op: "read", Err: errors.New("i/o timeout")}))
+	assert.True(t, internal.IsRetryableDownloadError(io.ErrUnexpectedEOF))
+	assert.False(t, internal.IsRetryableDownloadError(newRequestFailure(http.StatusForbidden)))
+	assert.False(t, internal.IsRetryableDownloadError(newRequestFailure(http.StatusNotFound)))
+	assert.False(t, internal.IsRetryableDownloadError(storage.NewObjectNotFoundError("wal_005/x")))
+	assert.True(t, internal.IsRetryableDownloadError(errors.Wrap(&googleapi.Error{Code: http.StatusBadGateway}, "read")))
+	assert.False(t, internal.IsRetryableDownloadError(&googleapi.Error{Code: http.StatusForbidden}))
+	assert.True(t, internal.IsRetryableDownloadError(awserr.New(request.ErrCodeRequestError, "send request failed", nil)))
+	assert.False(t, internal.IsRetryableDownloadError(awserr.New("AccessDenied", "Access Denied", nil)))
+	assert.False(t, internal.IsRetryableDownloadError(&os.PathError{Op: "open", Path: "/x", Err: os.ErrPermission}))
+	// the errors not known to be transient are permanent
+	assert.False(t, internal.IsRetryableDownloadError(errors.New("invalid header")))
+}
+
+func TestRetryingFolder_FileSystemFailuresArePermanent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retrying_folder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "part_1.tar.lz4"), 0755))
+
+	// reading the directory fails with storage.Error hiding the cause
+	flaky := &flakyFolder{Folder: fs.NewFolder(dir, "")}
+	reader, err := internal.NewRetryingFolder(flaky, 3, time.Millisecond).ReadObject("part_1.tar.lz4")
+	if err == nil {
+		_, err = ioutil.ReadAll(reader)
+	}
+	assert.Error(t, err)
+	assert.Equal(t, 1, flaky.opened)
+
+	// the storages library hides the cause of the file system failures
+	flaky = &flakyFolder{Folder: fs.NewFolder(dir, ""), openFailures: 10,
+		openErr: storage.NewError(os.ErrPermission, "FS", "Unable to read object %v", "part_2.tar.lz4")}
+	_, err = internal.NewRetryingFolder(flaky, 3, time.Millisecond).ReadObject("part_2.tar.lz4")
+	assert.Error(t, err)
+	assert.Equal(t, 1, flaky.opened)
+}
+
+// rangeS3Client serves the object content from the requested offset, the first read breaks after readLimit bytes.
+// The object is replaced with the new version after the first read when replaced is set.
+type rangeS3Client struct {
+	s3iface.S3API
+	content   string
+	readLimit int64
+	replaced  bool
+	inputs    []*awss3.GetObjectInput
+}
+
+func (client *rangeS3Client) GetObject(input *awss3.GetObjectInput) (*awss3.GetObjectOutput, error) {
+	client.inputs = append(client.inputs, input)
+	eTag := `"v1"`
+	if client.replaced && len(client.inputs) > 1 {
+		eTag = `"v2"`
+	}
+	if input.IfMatch != nil && *input.IfMatch != eTag {
+		return nil, awserr.NewRequestFailure(awserr.New("PreconditionFailed", "At least one of the pre-conditions "+
+			"you specified did not hold", nil), http.StatusPreconditionFailed, "id")
+	}
+	var offset int64
+	if input.Range != nil {
+		_, err := fmt.Sscanf(*input.Range, "bytes=%d-", &offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var body io.Reader = strings.NewReader(client.content[offset:])
+	if client.readLimit > 0 {
+		body = io.MultiReader(io.LimitReader(body, client.readLimit), &brokenReader{})
+		client.readLimit = 0
+	}
+	return &awss3.GetObjectOutput{Body: ioutil.NopCloser(body), ETag: aws.String(eTag)}, nil
+}
+
+func newRangeS3Folder(client *rangeS3Client) storage.Folder {
+	uploader := walgs3.NewUploader(testtools.NewMockS3Uploader(false, false, memory.NewStorage()), "", "", "STANDARD")
+	return walgs3.NewFolder(*uploader, client, "bucket", "server/", false)
+}
+
+func TestRetryingFolder_ResumesS3ReadOfSameVersion(t *testing.T) {
+	defer func(interval time.Duration) { internal.MinDownloadRetryInterval = interval }(internal.MinDownloadRetryInterval)
+	internal.MinDownloadRetryInterval = time.Millisecond
+	content := strings.Repeat("0123456789", 100)
+
+	client := &rangeS3Client{content: content, readLimit: 512}
+	reader, err := internal.NewRetryingFolder(newRangeS3Folder(client), 3, time.Millisecond).ReadObject("part_1.tar.lz4")
+	require.NoError(t, err)
+	read, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(read))
+	require.Len(t, client.inputs, 2)
+	assert.Nil(t, client.inputs[0].IfMatch)
+	assert.Equal(t, "bytes=512-", *client.inputs[1].Range)
+	assert.Equal(t, `"v1"`, *client.inputs[1].IfMatch)
+
+	// the object replaced during the read is not mixed with the new version
+	client = &rangeS3Client{content: content, readLimit: 512, replaced: true}
+	reader, err = internal.NewRetryingFolder(newRangeS3Folder(client), 3, time.Millisecond).ReadObject("part_1.tar.lz4")
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(reader)
+	assert.Error(t, err)
+	assert.Len(t, client.inputs, 2)
+}
+
+func TestRetryingFolder_ResumesS3ReadThroughWrappers(t *testing.T) {
+	defer func(interval time.Duration) { internal.MinDownloadRetryInterval = interval }(internal.MinDownloadRetryInterval)
+	internal.MinDownloadRetryInterval = time.Millisecond
+	content := strings.Repeat("0123456789", 100)
+
+	client := &rangeS3Client{content: content, readLimit: 512}
+	folder := internal.NewReadOnlyFolder(internal.NewTieredFolder(newRangeS3Folder(client),
+		memory.NewFolder("cold/", memory.NewStorage())))
+	reader, err := internal.NewRetryingFolder(folder, 3, time.Millisecond).ReadObject("part_1.tar.lz4")
+	require.NoError(t, err)
+	read, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(read))
+	require.Len(t, client.inputs, 2)
+	assert.Equal(t, "bytes=512-", *client.inputs[1].Range)
+	assert.Equal(t, `"v1"`, *client.inputs[1].IfMatch)
+}
+
+func TestRetryingFolder_ResumesRead(t *testing.T) {
+	defer func(interval time.Duration) { internal.MinDownloadRetryInterval = interval }(internal.MinDownloadRetryInterval)
+	internal.MinDownloadRetryInterval = time.Millisecond
+	base := memory.NewFolder("in_memory/", memory.NewStorage())
+	content := strings.Repeat("0123456789", 100)
+	require.NoError(t, base.PutObject("basebackups_005/part_1.tar.lz4", strings.NewReader(content)))
+	flaky := &flakyFolder{Folder: base, openFailures: 2, openErr: newRequestFailure(http.StatusBadGateway),
+		readLimit: 512}
+	folder := internal.NewRetryingFolder(flaky, 3, time.Millisecond)
+
+	assert.IsType(t, &internal.RetryingFolder{}, folder.GetSubFolder("basebackups_005/"))
+
+	reader, err := folder.ReadObject("basebackups_005/part_1.tar.lz4")
+	require.NoError(t, err)
+	read, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(read))
+	assert.NoError(t, reader.Close())
+	// two failed opens, the broken one and the resumed one
+	assert.Equal(t, 4, flaky.opened)
+}
+
+func TestRetryingFolder_GivesUp(t *testing.T) {
+	defer func(interval time.Duration) { internal.MinDownloadRetryInterval = interval }(internal.MinDownloadRetryInterval)
+	internal.MinDownloadRetryInterval = time.Millisecond
+	base := memory.NewFolder("in_memory/", memory.NewStorage())
+	require.NoError(t, base.PutObject("part_1.tar.lz4", strings.NewReader("content")))
+
+	flaky := &flakyFolder{Folder: base, openFailures: 10, openErr: newRequestFailure(http.StatusInternalServerError)}
+	_, err := internal.NewRetryingFolder(flaky, 3, time.Millisecond).ReadObject("part_1.tar.lz4")
+	assert.Error(t, err)
+	assert.Equal(t, 4, flaky.opened)
+
+	flaky = &flakyFolder{Folder: base, openFailures: 10, openErr: newRequestFailure(http.StatusForbidden)}
+	_, err = internal.NewRetryingFolder(flaky, 3, time.Millisecond).ReadObject("part_1.tar.lz4")
+	assert.Error(t, err)
+	assert.Equal(t, 1, flaky.opened)
+
+	_, err = internal.NewRetryingFolder(base, 3, time.Millisecond).ReadObject("part_2.tar.lz4")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
+
+func TestConfigureFolderForSpecificConfig_Retries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retries")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the settings are read from the config of the folder, not the global one
+	config := viper.New()
+	internal.SetDefaultValues(config)
+	config.Set("WALG_FILE_PREFIX", dir)
+	config.Set(internal.DownloadRetriesSetting, 2)
+	folder, err := internal.ConfigureFolderForSpecificConfig(config)
+	require.NoError(t, err)
+	assert.IsType(t, &internal.RetryingFolder{}, folder)
+
+	config.Set(internal.DownloadRetriesSetting, 0)
+	viper.Set(internal.DownloadRetriesSetting, 2)
+	defer viper.Set(internal.DownloadRetriesSetting, 0)
+	folder, err = internal.ConfigureFolderForSpecificConfig(config)
+	require.NoError(t, err)
+	_, isRetrying := folder.(*internal.RetryingFolder)
+	assert.False(t, isRetrying)
+}
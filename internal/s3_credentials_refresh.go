@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/s3"
+	"github.com/wal-g/tracelog"
+)
+
+type InvalidS3CredentialsRefreshError struct {
+	error
+}
+
+func newInvalidS3CredentialsRefreshError(interval string) InvalidS3CredentialsRefreshError {
+	return InvalidS3CredentialsRefreshError{errors.Errorf("Invalid %s '%s', expected a positive duration (i.e., 15m)",
+		S3CredentialsRefreshSetting, interval)}
+}
+
+func (err InvalidS3CredentialsRefreshError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ParseS3CredentialsRefreshInterval parses the positive duration of WALG_S3_CREDENTIALS_REFRESH_INTERVAL
+func ParseS3CredentialsRefreshInterval(interval string) (time.Duration, error) {
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration <= 0 {
+		return 0, newInvalidS3CredentialsRefreshError(interval)
+	}
+	return duration, nil
+}
+
+// refreshingCredentialsProvider retrieves the credentials from the source again once the interval passes,
+// even if the source still considers them valid: the shared credentials file, the environment
+// and the mounted secrets are read only once by the SDK otherwise.
+type refreshingCredentialsProvider struct {
+	credentials.Expiry
+	source   *credentials.Credentials
+	interval time.Duration
+}
+
+func newRefreshingCredentialsProvider(source *credentials.Credentials,
+	interval time.Duration) *refreshingCredentialsProvider {
+	return &refreshingCredentialsProvider{source: source, interval: interval}
+}
+
+func (provider *refreshingCredentialsProvider) Retrieve() (credentials.Value, error) {
+	provider.source.Expire()
+	value, err := provider.source.Get()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to refresh the S3 credentials: %v\n", err)
+		return credentials.Value{}, err
+	}
+	expiration := provider.currentTime().Add(provider.interval)
+	// the temporary credentials of the source may expire before the refresh interval passes,
+	// the zero expiration is reported by the sources which credentials don't expire
+	sourceExpiration, err := provider.source.ExpiresAt()
+	if err == nil && !sourceExpiration.IsZero() && sourceExpiration.Before(expiration) {
+		expiration = sourceExpiration
+	}
+	provider.SetExpiration(expiration, 0)
+	tracelog.DebugLogger.Printf("Refreshed the S3 credentials from %s, the next refresh is at %s\n",
+		value.ProviderName, expiration.Format(time.RFC3339))
+	return value, nil
+}
+
+func (provider *refreshingCredentialsProvider) currentTime() time.Time {
+	if provider.CurrentTime != nil {
+		return provider.CurrentTime()
+	}
+	return time.Now()
+}
+
+const (
+	awsWebIdentityTokenFileEnv = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	awsRoleARNEnv              = "AWS_ROLE_ARN"
+	awsRoleSessionNameEnv      = "AWS_ROLE_SESSION_NAME"
+)
+
+// getS3CredentialsProviders returns the credentials chain of the S3 storage session: the keys from the settings
+// followed by the default chain of the SDK, that is the environment, the shared credentials file and the container
+// or the instance role. The web identity token goes before the role if AWS_WEB_IDENTITY_TOKEN_FILE
+// and AWS_ROLE_ARN are set, e.g. for the IAM roles of the Kubernetes service accounts.
+func getS3CredentialsProviders(config *aws.Config, settings map[string]string) ([]credentials.Provider, error) {
+	providers := append([]credentials.Provider{
+		&credentials.StaticProvider{Value: credentials.Value{
+			AccessKeyID:     getFirstS3Setting(settings, s3.AccessKeyIdSetting, s3.AccessKeySetting),
+			SecretAccessKey: getFirstS3Setting(settings, s3.SecretAccessKeySetting, s3.SecretKeySetting),
+			SessionToken:    settings[s3.SessionTokenSetting],
+		}},
+	}, defaults.CredProviders(config, defaults.Handlers())...)
+	tokenFile, roleARN := os.Getenv(awsWebIdentityTokenFileEnv), os.Getenv(awsRoleARNEnv)
+	if tokenFile == "" || roleARN == "" {
+		return providers, nil
+	}
+	stsSession, err := session.NewSession(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the STS session for the web identity credentials")
+	}
+	roleProvider := providers[len(providers)-1]
+	return append(providers[:len(providers)-1:len(providers)-1],
+		stscreds.NewWebIdentityRoleProvider(sts.New(stsSession), roleARN, os.Getenv(awsRoleSessionNameEnv), tokenFile),
+		roleProvider), nil
+}
+
+func getFirstS3Setting(settings map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := settings[key]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// SetS3CredentialsRefresh makes the S3 client of the folder retrieve its credentials again at the interval,
+// so the long-running commands keep working when the credentials rotate. The credentials chain
+// is made again from the settings, with the web identity token, which the chain of the storage lacks.
+func SetS3CredentialsRefresh(folder *s3.Folder, settings map[string]string, interval time.Duration) error {
+	client, ok := folder.S3API.(*awss3.S3)
+	if !ok {
+		return errors.Errorf("%s is not supported by the S3 client %T", S3CredentialsRefreshSetting, folder.S3API)
+	}
+	providers, err := getS3CredentialsProviders(&client.Config, settings)
+	if err != nil {
+		return err
+	}
+	source := credentials.NewCredentials(&credentials.ChainProvider{
+		VerboseErrors: aws.BoolValue(client.Config.CredentialsChainVerboseErrors),
+		Providers:     providers,
+	})
+	client.Config.Credentials = credentials.NewCredentials(newRefreshingCredentialsProvider(source, interval))
+	return nil
+}
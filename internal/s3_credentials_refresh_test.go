@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/s3"
+)
+
+// rotatingProvider returns the new access key on each retrieval
+type rotatingProvider struct {
+	credentials.Expiry
+	retrievals int
+	lifetime   time.Duration
+}
+
+func (provider *rotatingProvider) Retrieve() (credentials.Value, error) {
+	provider.retrievals++
+	if provider.lifetime > 0 {
+		provider.SetExpiration(time.Now().Add(provider.lifetime), 0)
+	}
+	return credentials.Value{AccessKeyID: strconv.Itoa(provider.retrievals), SecretAccessKey: "secret",
+		ProviderName: "rotatingProvider"}, nil
+}
+
+func newTestRefreshingCredentials(source *rotatingProvider, interval time.Duration,
+	now *time.Time) *credentials.Credentials {
+	provider := newRefreshingCredentialsProvider(credentials.NewCredentials(source), interval)
+	provider.CurrentTime = func() time.Time { return *now }
+	return credentials.NewCredentials(provider)
+}
+
+func getAccessKeyID(t *testing.T, creds *credentials.Credentials) string {
+	value, err := creds.Get()
+	require.NoError(t, err)
+	return value.AccessKeyID
+}
+
+func TestParseS3CredentialsRefreshInterval(t *testing.T) {
+	interval, err := ParseS3CredentialsRefreshInterval("15m")
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, interval)
+	for _, value := range []string{"", "0s", "-1m", "15"} {
+		_, err = ParseS3CredentialsRefreshInterval(value)
+		assert.IsType(t, InvalidS3CredentialsRefreshError{}, err, value)
+	}
+}
+
+func TestRefreshingCredentialsProvider_RefreshesAfterInterval(t *testing.T) {
+	now := time.Now()
+	creds := newTestRefreshingCredentials(&rotatingProvider{}, time.Hour, &now)
+	assert.Equal(t, "1", getAccessKeyID(t, creds))
+	now = now.Add(30 * time.Minute)
+	assert.Equal(t, "1", getAccessKeyID(t, creds))
+	now = now.Add(time.Hour)
+	assert.Equal(t, "2", getAccessKeyID(t, creds))
+}
+
+func TestRefreshingCredentialsProvider_SourceExpiresFirst(t *testing.T) {
+	now := time.Now()
+	creds := newTestRefreshingCredentials(&rotatingProvider{lifetime: 10 * time.Minute}, time.Hour, &now)
+	assert.Equal(t, "1", getAccessKeyID(t, creds))
+	now = now.Add(15 * time.Minute)
+	assert.Equal(t, "2", getAccessKeyID(t, creds))
+}
+
+func TestSetS3CredentialsRefresh(t *testing.T) {
+	client := awss3.New(unit.Session)
+	uploader := s3.NewUploader(nil, "", "", "STANDARD")
+	folder := s3.NewFolder(*uploader, client, "bucket", "server/", false)
+	source := client.Config.Credentials
+	settings := map[string]string{s3.AccessKeyIdSetting: "AKID", s3.SecretAccessKeySetting: "SECRET"}
+	require.NoError(t, SetS3CredentialsRefresh(folder, settings, time.Hour))
+	assert.NotSame(t, source, client.Config.Credentials)
+
+	value, err := client.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", value.AccessKeyID)
+}
+
+func TestGetS3CredentialsProviders_WebIdentity(t *testing.T) {
+	config := unit.Session.Config
+	providers, err := getS3CredentialsProviders(config, map[string]string{})
+	require.NoError(t, err)
+	assert.Len(t, providers, 4)
+
+	os.Setenv(awsWebIdentityTokenFileEnv, "/var/run/secrets/token")
+	defer os.Unsetenv(awsWebIdentityTokenFileEnv)
+	os.Setenv(awsRoleARNEnv, "arn:aws:iam::123456789012:role/wal-g")
+	defer os.Unsetenv(awsRoleARNEnv)
+	providers, err = getS3CredentialsProviders(config, map[string]string{})
+	require.NoError(t, err)
+	require.Len(t, providers, 5)
+	// the web identity token goes before the instance role, as in the default chain of the SDK
+	assert.IsType(t, &stscreds.WebIdentityRoleProvider{}, providers[3])
+}
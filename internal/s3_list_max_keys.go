@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/s3"
+	"github.com/wal-g/tracelog"
+)
+
+// s3MaxListKeys is the most keys S3 returns in one page of the listing
+const s3MaxListKeys = 1000
+
+type InvalidS3ListMaxKeysError struct {
+	error
+}
+
+func newInvalidS3ListMaxKeysError(maxKeys string) InvalidS3ListMaxKeysError {
+	return InvalidS3ListMaxKeysError{errors.Errorf("Invalid %s '%s', expected an integer from 1 to %d",
+		S3ListMaxKeysSetting, maxKeys, s3MaxListKeys)}
+}
+
+func (err InvalidS3ListMaxKeysError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ParseS3ListMaxKeys parses the page size of the listing of WALG_S3_LIST_MAX_KEYS
+func ParseS3ListMaxKeys(maxKeysStr string) (int64, error) {
+	maxKeys, err := strconv.ParseInt(maxKeysStr, 10, 64)
+	if err != nil || maxKeys < 1 || maxKeys > s3MaxListKeys {
+		return 0, newInvalidS3ListMaxKeysError(maxKeysStr)
+	}
+	return maxKeys, nil
+}
+
+// SetS3ListMaxKeys makes the S3 client of the folder request the pages of maxKeys keys when it lists the folders.
+// The storage lists them with the default page size, so MaxKeys is set to the request parameters
+// before they are marshalled, like the canned ACL.
+func SetS3ListMaxKeys(folder *s3.Folder, maxKeys int64) error {
+	client, ok := folder.S3API.(*awss3.S3)
+	if !ok {
+		return errors.Errorf("%s is not supported by the S3 client %T", S3ListMaxKeysSetting, folder.S3API)
+	}
+	client.Handlers.Build.PushFrontNamed(request.NamedHandler{
+		Name: "walg.SetS3ListMaxKeys",
+		Fn: func(r *request.Request) {
+			switch input := r.Params.(type) {
+			case *awss3.ListObjectsV2Input:
+				input.MaxKeys = aws.Int64(maxKeys)
+			case *awss3.ListObjectsInput:
+				input.MaxKeys = aws.Int64(maxKeys)
+			}
+		},
+	})
+	return nil
+}
@@ -0,0 +1,51 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	walgs3 "github.com/wal-g/storages/s3"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestParseS3ListMaxKeys(t *testing.T) {
+	maxKeys, err := internal.ParseS3ListMaxKeys("250")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), maxKeys)
+	for _, maxKeysStr := range []string{"", "0", "-1", "1001", "ten"} {
+		_, err = internal.ParseS3ListMaxKeys(maxKeysStr)
+		assert.IsType(t, internal.InvalidS3ListMaxKeysError{}, err, maxKeysStr)
+	}
+}
+
+func TestSetS3ListMaxKeys(t *testing.T) {
+	client := s3.New(unit.Session)
+	uploader := walgs3.NewUploader(testtools.NewMockS3Uploader(false, false, memory.NewStorage()), "", "", "STANDARD")
+	folder := walgs3.NewFolder(*uploader, client, "bucket", "server/", false)
+	require.NoError(t, internal.SetS3ListMaxKeys(folder, 250))
+
+	listV2Request, _ := client.ListObjectsV2Request(&s3.ListObjectsV2Input{Bucket: aws.String("bucket"),
+		Prefix: aws.String("server/wal_005/")})
+	require.NoError(t, listV2Request.Build())
+	assert.Equal(t, "250", listV2Request.HTTPRequest.URL.Query().Get("max-keys"))
+
+	listRequest, _ := client.ListObjectsRequest(&s3.ListObjectsInput{Bucket: aws.String("bucket")})
+	require.NoError(t, listRequest.Build())
+	assert.Equal(t, "250", listRequest.HTTPRequest.URL.Query().Get("max-keys"))
+
+	getRequest, _ := client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("wal")})
+	require.NoError(t, getRequest.Build())
+	assert.Empty(t, getRequest.HTTPRequest.URL.Query().Get("max-keys"))
+}
+
+func TestSetS3ListMaxKeys_UnsupportedClient(t *testing.T) {
+	uploader := walgs3.NewUploader(testtools.NewMockS3Uploader(false, false, memory.NewStorage()), "", "", "STANDARD")
+	folder := walgs3.NewFolder(*uploader, &taggingS3Client{}, "bucket", "server/", false)
+	assert.Error(t, internal.SetS3ListMaxKeys(folder, 250))
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/s3"
+	"github.com/wal-g/tracelog"
+)
+
+type InvalidS3ObjectACLError struct {
+	error
+}
+
+func newInvalidS3ObjectACLError(acl string) InvalidS3ObjectACLError {
+	return InvalidS3ObjectACLError{errors.Errorf("Invalid %s '%s', expected one of the canned ACLs: %s",
+		S3ACLSetting, acl, strings.Join(awss3.ObjectCannedACL_Values(), ", "))}
+}
+
+func (err InvalidS3ObjectACLError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ValidateS3ObjectACL checks that the ACL is one of the canned ACLs S3 accepts for the objects
+func ValidateS3ObjectACL(acl string) error {
+	for _, cannedACL := range awss3.ObjectCannedACL_Values() {
+		if acl == cannedACL {
+			return nil
+		}
+	}
+	return newInvalidS3ObjectACLError(acl)
+}
+
+// SetS3ObjectACL makes the S3 client of the folder send the canned ACL with every object it creates.
+// The storage uploader has no way to pass the ACL, and setting it by a separate request after
+// the upload is not enough: the bucket policy may deny the uploads without the ACL.
+// So the ACL is set to the request parameters before they are marshalled, which covers both
+// the single part and the multipart uploads of the backups and the WAL files alike.
+func SetS3ObjectACL(folder *s3.Folder, acl string) error {
+	client, ok := folder.S3API.(*awss3.S3)
+	if !ok {
+		return errors.Errorf("%s is not supported by the S3 client %T", S3ACLSetting, folder.S3API)
+	}
+	client.Handlers.Build.PushFrontNamed(request.NamedHandler{
+		Name: "walg.SetS3ObjectACL",
+		Fn: func(r *request.Request) {
+			switch input := r.Params.(type) {
+			case *awss3.PutObjectInput:
+				input.ACL = aws.String(acl)
+			case *awss3.CreateMultipartUploadInput:
+				input.ACL = aws.String(acl)
+			case *awss3.CopyObjectInput:
+				input.ACL = aws.String(acl)
+			}
+		},
+	})
+	return nil
+}
@@ -0,0 +1,48 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	walgs3 "github.com/wal-g/storages/s3"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestValidateS3ObjectACL(t *testing.T) {
+	assert.NoError(t, internal.ValidateS3ObjectACL("bucket-owner-full-control"))
+	for _, acl := range []string{"", "log-delivery-write", "Private"} {
+		assert.IsType(t, internal.InvalidS3ObjectACLError{}, internal.ValidateS3ObjectACL(acl), acl)
+	}
+}
+
+func TestSetS3ObjectACL(t *testing.T) {
+	client := s3.New(unit.Session)
+	uploader := walgs3.NewUploader(testtools.NewMockS3Uploader(false, false, memory.NewStorage()), "", "", "STANDARD")
+	folder := walgs3.NewFolder(*uploader, client, "bucket", "server/", false)
+	require.NoError(t, internal.SetS3ObjectACL(folder, "bucket-owner-full-control"))
+
+	putRequest, _ := client.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("wal")})
+	require.NoError(t, putRequest.Build())
+	assert.Equal(t, "bucket-owner-full-control", putRequest.HTTPRequest.Header.Get("x-amz-acl"))
+
+	multipartRequest, _ := client.CreateMultipartUploadRequest(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String("bucket"), Key: aws.String("backup")})
+	require.NoError(t, multipartRequest.Build())
+	assert.Equal(t, "bucket-owner-full-control", multipartRequest.HTTPRequest.Header.Get("x-amz-acl"))
+
+	getRequest, _ := client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("wal")})
+	require.NoError(t, getRequest.Build())
+	assert.Empty(t, getRequest.HTTPRequest.Header.Get("x-amz-acl"))
+}
+
+func TestSetS3ObjectACL_UnsupportedClient(t *testing.T) {
+	uploader := walgs3.NewUploader(testtools.NewMockS3Uploader(false, false, memory.NewStorage()), "", "", "STANDARD")
+	folder := walgs3.NewFolder(*uploader, &taggingS3Client{}, "bucket", "server/", false)
+	assert.Error(t, internal.SetS3ObjectACL(folder, "private"))
+}
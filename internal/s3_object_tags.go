@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/s3"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	S3ObjectTypeTag      = "walg-type"
+	S3ObjectTypeBackup   = "backup"
+	S3ObjectTypeWal      = "wal"
+	S3ObjectTypeOther    = "other"
+	s3MaxObjectTags      = 10
+	s3MaxTagKeyLength    = 128
+	s3MaxTagValueLength  = 256
+	s3ReservedTagsPrefix = "aws:"
+)
+
+type InvalidS3ObjectTagsError struct {
+	error
+}
+
+func newInvalidS3ObjectTagsError(format string, args ...interface{}) InvalidS3ObjectTagsError {
+	return InvalidS3ObjectTagsError{errors.Errorf("Invalid %s: "+format,
+		append([]interface{}{S3ObjectTagsSetting}, args...)...)}
+}
+
+func (err InvalidS3ObjectTagsError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ParseS3ObjectTags parses the comma separated list of key=value pairs
+// and validates it against the S3 object tagging limits
+func ParseS3ObjectTags(tagsStr string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(tagsStr, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		keyValue := strings.SplitN(pair, "=", 2)
+		if len(keyValue) != 2 {
+			return nil, newInvalidS3ObjectTagsError("'%s' is not a key=value pair", pair)
+		}
+		key, value := strings.TrimSpace(keyValue[0]), strings.TrimSpace(keyValue[1])
+		switch {
+		case key == "":
+			return nil, newInvalidS3ObjectTagsError("empty tag key in '%s'", pair)
+		case utf8.RuneCountInString(key) > s3MaxTagKeyLength:
+			return nil, newInvalidS3ObjectTagsError("tag key '%s' is longer than %d characters", key, s3MaxTagKeyLength)
+		case utf8.RuneCountInString(value) > s3MaxTagValueLength:
+			return nil, newInvalidS3ObjectTagsError("value of tag '%s' is longer than %d characters",
+				key, s3MaxTagValueLength)
+		case strings.HasPrefix(strings.ToLower(key), s3ReservedTagsPrefix):
+			return nil, newInvalidS3ObjectTagsError("tag key '%s' uses the reserved prefix '%s'", key, s3ReservedTagsPrefix)
+		case key == S3ObjectTypeTag:
+			return nil, newInvalidS3ObjectTagsError("tag '%s' is set by WAL-G automatically", S3ObjectTypeTag)
+		}
+		if _, ok := tags[key]; ok {
+			return nil, newInvalidS3ObjectTagsError("duplicate tag key '%s'", key)
+		}
+		tags[key] = value
+	}
+	// one tag is reserved for the object type
+	if len(tags) > s3MaxObjectTags-1 {
+		return nil, newInvalidS3ObjectTagsError("at most %d tags are allowed, got %d", s3MaxObjectTags-1, len(tags))
+	}
+	return tags, nil
+}
+
+// SetS3ObjectTags makes the S3 client of the folder tag every object it creates for the cost allocation.
+// Besides the configured tags, the object type tag tells the backups from the WAL.
+// Like the canned ACL, the tags are set to the upload request parameters before they are marshalled,
+// so the single part and the multipart uploads are tagged by the upload itself, without a separate request.
+func SetS3ObjectTags(folder *s3.Folder, tags map[string]string) error {
+	client, ok := folder.S3API.(*awss3.S3)
+	if !ok {
+		return errors.Errorf("%s is not supported by the S3 client %T", S3ObjectTagsSetting, folder.S3API)
+	}
+	rootPath := folder.GetPath()
+	client.Handlers.Build.PushFrontNamed(request.NamedHandler{
+		Name: "walg.SetS3ObjectTags",
+		Fn: func(r *request.Request) {
+			switch input := r.Params.(type) {
+			case *awss3.PutObjectInput:
+				input.Tagging = aws.String(encodeS3ObjectTags(tags, rootPath, aws.StringValue(input.Key)))
+			case *awss3.CreateMultipartUploadInput:
+				input.Tagging = aws.String(encodeS3ObjectTags(tags, rootPath, aws.StringValue(input.Key)))
+			}
+		},
+	})
+	return nil
+}
+
+// encodeS3ObjectTags encodes the tags of the object as the URL query parameters the x-amz-tagging header expects
+func encodeS3ObjectTags(tags map[string]string, rootPath, objectPath string) string {
+	values := make(url.Values, len(tags)+1)
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	values.Set(S3ObjectTypeTag, getS3ObjectType(strings.TrimPrefix(objectPath, rootPath)))
+	// the spaces are encoded as %20, a plus sign is taken as is
+	return strings.ReplaceAll(values.Encode(), "+", "%20")
+}
+
+// getS3ObjectType tells the object type by the storage directory it is placed in,
+// the directories may be preceded by WALG_STORAGE_PREFIX
+func getS3ObjectType(relativePath string) string {
+	relativePath = "/" + relativePath
+	switch {
+	case strings.Contains(relativePath, "/"+utility.BaseBackupPath):
+		return S3ObjectTypeBackup
+	case strings.Contains(relativePath, "/"+utility.WalPath):
+		return S3ObjectTypeWal
+	default:
+		return S3ObjectTypeOther
+	}
+}
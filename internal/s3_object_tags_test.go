@@ -0,0 +1,77 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	walgs3 "github.com/wal-g/storages/s3"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+// taggingS3Client is the S3 client not built by the AWS SDK
+type taggingS3Client struct {
+	s3iface.S3API
+}
+
+func TestParseS3ObjectTags(t *testing.T) {
+	tags, err := internal.ParseS3ObjectTags("env=prod, cluster = main,team=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "cluster": "main", "team": ""}, tags)
+
+	for _, tagsStr := range []string{
+		"env",
+		"=prod",
+		"env=prod,env=test",
+		"aws:env=prod",
+		"walg-type=wal",
+		strings.Repeat("k", 129) + "=v",
+		"k=" + strings.Repeat("v", 257),
+		"a=1,b=2,c=3,d=4,e=5,f=6,g=7,h=8,i=9,j=10",
+	} {
+		_, err := internal.ParseS3ObjectTags(tagsStr)
+		assert.IsType(t, internal.InvalidS3ObjectTagsError{}, err, tagsStr)
+	}
+}
+
+func TestSetS3ObjectTags(t *testing.T) {
+	client := s3.New(unit.Session)
+	uploader := walgs3.NewUploader(testtools.NewMockS3Uploader(false, false, memory.NewStorage()), "", "", "STANDARD")
+	folder := walgs3.NewFolder(*uploader, client, "bucket", "server/", false)
+	require.NoError(t, internal.SetS3ObjectTags(folder, map[string]string{"env": "prod", "team": "data platform"}))
+
+	putRequest, _ := client.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String("bucket"),
+		Key: aws.String("server/wal_005/000000010000000000000001.lz4")})
+	require.NoError(t, putRequest.Build())
+	assert.Equal(t, "env=prod&team=data%20platform&walg-type=wal", putRequest.HTTPRequest.Header.Get("x-amz-tagging"))
+
+	multipartRequest, _ := client.CreateMultipartUploadRequest(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String("bucket"), Key: aws.String("server/prefix/basebackups_005/base_000000010000000000000001/" +
+			"tar_partitions/part_1.tar.lz4")})
+	require.NoError(t, multipartRequest.Build())
+	assert.Equal(t, "env=prod&team=data%20platform&walg-type=backup",
+		multipartRequest.HTTPRequest.Header.Get("x-amz-tagging"))
+
+	putRequest, _ = client.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String("bucket"),
+		Key: aws.String("server/garbage")})
+	require.NoError(t, putRequest.Build())
+	assert.Equal(t, "env=prod&team=data%20platform&walg-type=other", putRequest.HTTPRequest.Header.Get("x-amz-tagging"))
+
+	getRequest, _ := client.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String("bucket"),
+		Key: aws.String("server/garbage")})
+	require.NoError(t, getRequest.Build())
+	assert.Empty(t, getRequest.HTTPRequest.Header.Get("x-amz-tagging"))
+}
+
+func TestSetS3ObjectTags_UnsupportedClient(t *testing.T) {
+	uploader := walgs3.NewUploader(testtools.NewMockS3Uploader(false, false, memory.NewStorage()), "", "", "STANDARD")
+	folder := walgs3.NewFolder(*uploader, &taggingS3Client{}, "bucket", "server/", false)
+	assert.Error(t, internal.SetS3ObjectTags(folder, map[string]string{"env": "prod"}))
+}
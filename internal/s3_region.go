@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/s3"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	// s3CACertFileSetting is the CA certificate of the S3 endpoint, it is read by the storage too
+	s3CACertFileSetting = "S3_CA_CERT_FILE"
+	// s3DefaultCustomEndpointRegion is the region the storage uses for the custom endpoints
+	s3DefaultCustomEndpointRegion = "us-east-1"
+	s3RegionDetectionTimeout      = 10 * time.Second
+)
+
+// withDetectedS3Region returns the settings with the region of the bucket at the custom S3 endpoint,
+// if AWS_REGION is not set. The storage looks up the region by s3:GetBucketLocation for the AWS
+// endpoints only and uses us-east-1 for the others, the S3-compatible services reply to the requests
+// signed for the wrong region with redirects or errors. The storage falls back to us-east-1
+// if the detection fails.
+func withDetectedS3Region(prefix string, settings map[string]string) map[string]string {
+	endpoint := settings[s3.EndpointSetting]
+	if _, ok := settings[s3.RegionSetting]; ok || endpoint == "" || strings.HasSuffix(endpoint, ".amazonaws.com") {
+		return settings
+	}
+	bucket, _, err := storage.GetPathFromPrefix(prefix)
+	if err != nil {
+		return settings
+	}
+	region, err := DetectS3BucketRegion(bucket, endpoint, settings[s3CACertFileSetting])
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to detect the region of the bucket '%s' at %s, using %s: %v\n",
+			bucket, endpoint, s3DefaultCustomEndpointRegion, err)
+		return settings
+	}
+	detectedSettings := make(map[string]string, len(settings)+1)
+	for name, value := range settings {
+		detectedSettings[name] = value
+	}
+	detectedSettings[s3.RegionSetting] = region
+	return detectedSettings
+}
+
+// DetectS3BucketRegion returns the region of the bucket from the X-Amz-Bucket-Region header
+// of the HeadBucket response of the S3 endpoint
+func DetectS3BucketRegion(bucket, endpoint, caCertFile string) (string, error) {
+	options := session.Options{
+		Config: *aws.NewConfig().WithEndpoint(endpoint).WithRegion(s3DefaultCustomEndpointRegion),
+	}
+	if caCertFile != "" {
+		file, err := os.Open(caCertFile)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		options.CustomCABundle = file
+	}
+	sess, err := session.NewSessionWithOptions(options)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3RegionDetectionTimeout)
+	defer cancel()
+	region, err := s3manager.GetBucketRegion(ctx, sess, bucket, s3DefaultCustomEndpointRegion)
+	return region, errors.Wrap(err, "HeadBucket did not return the bucket region")
+}
+
+// logS3Endpoint logs the endpoint and the region the S3 client of the folder is configured with
+func logS3Endpoint(folder *s3.Folder) {
+	client, ok := folder.S3API.(*awss3.S3)
+	if !ok {
+		return
+	}
+	tracelog.DebugLogger.Printf("S3 endpoint %s, region %s\n", client.Endpoint, aws.StringValue(client.Config.Region))
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/s3"
+)
+
+func newTestS3RegionServer(region string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == "/bucket" && region != "" {
+			w.Header().Set("X-Amz-Bucket-Region", region)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+}
+
+func TestDetectS3BucketRegion(t *testing.T) {
+	server := newTestS3RegionServer("eu-central-1")
+	defer server.Close()
+
+	region, err := DetectS3BucketRegion("bucket", server.URL, "")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-central-1", region)
+
+	_, err = DetectS3BucketRegion("other-bucket", server.URL, "")
+	assert.Error(t, err)
+}
+
+func TestWithDetectedS3Region(t *testing.T) {
+	server := newTestS3RegionServer("eu-central-1")
+	defer server.Close()
+
+	settings := map[string]string{s3.EndpointSetting: server.URL}
+	detected := withDetectedS3Region("s3://bucket/path", settings)
+	assert.Equal(t, "eu-central-1", detected[s3.RegionSetting])
+	assert.NotContains(t, settings, s3.RegionSetting)
+
+	// the region set explicitly and the failed detection are left to the storage
+	settings[s3.RegionSetting] = "us-west-2"
+	assert.Equal(t, "us-west-2", withDetectedS3Region("s3://bucket/path", settings)[s3.RegionSetting])
+	settings = map[string]string{s3.EndpointSetting: server.URL}
+	assert.NotContains(t, withDetectedS3Region("s3://other-bucket/path", settings), s3.RegionSetting)
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/wal-g/storages/azure"
@@ -42,15 +43,83 @@ func (adapter *StorageAdapter) loadSettings(config *viper.Viper) map[string]stri
 	return settings
 }
 
+var s3SettingList = append(append([]string{}, s3.SettingList...), S3ObjectTagsSetting, S3ACLSetting,
+	S3CredentialsRefreshSetting, S3ListMaxKeysSetting)
+
+// configureS3Folder configures the S3 folder, which tags the uploaded objects if WALG_S3_OBJECT_TAGS is set
+// and sets their canned ACL if WALG_S3_ACL is set. Its credentials are refreshed
+// if WALG_S3_CREDENTIALS_REFRESH_INTERVAL is set, and its listing pages are of WALG_S3_LIST_MAX_KEYS keys.
+// The region of the bucket at the custom endpoint is detected if AWS_REGION is not set.
+func configureS3Folder(prefix string, settings map[string]string) (storage.Folder, error) {
+	acl, hasACL := settings[S3ACLSetting]
+	if hasACL {
+		if err := ValidateS3ObjectACL(acl); err != nil {
+			return nil, err
+		}
+	}
+	var refreshInterval time.Duration
+	if intervalStr, ok := settings[S3CredentialsRefreshSetting]; ok {
+		var err error
+		refreshInterval, err = ParseS3CredentialsRefreshInterval(intervalStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var maxKeys int64
+	if maxKeysStr, ok := settings[S3ListMaxKeysSetting]; ok {
+		var err error
+		maxKeys, err = ParseS3ListMaxKeys(maxKeysStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var tags map[string]string
+	if tagsStr, ok := settings[S3ObjectTagsSetting]; ok {
+		var err error
+		tags, err = ParseS3ObjectTags(tagsStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	settings = withDetectedS3Region(prefix, settings)
+	folder, err := s3.ConfigureFolder(prefix, settings)
+	if err != nil {
+		return nil, err
+	}
+	logS3Endpoint(folder.(*s3.Folder))
+	if hasACL {
+		if err = SetS3ObjectACL(folder.(*s3.Folder), acl); err != nil {
+			return nil, err
+		}
+	}
+	if refreshInterval > 0 {
+		if err = SetS3CredentialsRefresh(folder.(*s3.Folder), settings, refreshInterval); err != nil {
+			return nil, err
+		}
+	}
+	if maxKeys > 0 {
+		if err = SetS3ListMaxKeys(folder.(*s3.Folder), maxKeys); err != nil {
+			return nil, err
+		}
+	}
+	if len(tags) > 0 {
+		if err = SetS3ObjectTags(folder.(*s3.Folder), tags); err != nil {
+			return nil, err
+		}
+	}
+	return folder, nil
+}
+
 func preprocessFilePrefix(prefix string) string {
 	return strings.TrimPrefix(prefix, WaleFileHost) // WAL-E backward compatibility
 }
 
 var StorageAdapters = []StorageAdapter{
-	{"S3_PREFIX", s3.SettingList, s3.ConfigureFolder, nil},
+	{"S3_PREFIX", s3SettingList, withPrefixURL(configureS3Folder, []string{"s3"}, s3PrefixQuerySettings), nil},
 	{"FILE_PREFIX", nil, fs.ConfigureFolder, preprocessFilePrefix},
-	{"GS_PREFIX", gcs.SettingList, gcs.ConfigureFolder, nil},
-	{"AZ_PREFIX", azure.SettingList, azure.ConfigureFolder, nil},
+	{"GS_PREFIX", gcs.SettingList, withPrefixURL(gcs.ConfigureFolder, []string{"gs"}, gsPrefixQuerySettings), nil},
+	{"AZ_PREFIX", azure.SettingList,
+		withPrefixURL(configureAzureFolder, []string{"azure", "https"}, azPrefixQuerySettings), nil},
 	{"SWIFT_PREFIX", swift.SettingList, swift.ConfigureFolder, nil},
 	{"SSH_PREFIX", sh.SettingsList, sh.ConfigureFolder, nil},
 }
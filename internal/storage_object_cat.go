@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// isDecodableObject checks if the object is written by the compressing and encrypting uploader,
+// judging by its extension: tar partitions, WAL segments and other compressed files.
+func isDecodableObject(objectPath string) bool {
+	fileExtension := utility.GetFileExtension(objectPath)
+	return fileExtension == "tar" || compression.FindDecompressor(fileExtension) != nil
+}
+
+// CatStorageObject writes the contents of the storage object to the output.
+// Compressed objects and tar partitions are decrypted and decompressed as during the fetch,
+// other objects (e.g. sentinels or chunk manifests) are written as is.
+func CatStorageObject(folder storage.Folder, objectPath string, crypter crypto.Crypter, output io.Writer) error {
+	readerMaker := NewStorageReaderMaker(folder, objectPath)
+	if isDecodableObject(objectPath) {
+		return DecryptAndDecompressTar(output, readerMaker, crypter)
+	}
+
+	reader, err := readerMaker.Reader()
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(reader, "")
+	_, err = io.Copy(output, reader)
+	return errors.Wrapf(err, "failed to read %s", objectPath)
+}
+
+// HandleStorageObjectCat writes the storage object to the file at outputPath or to stdout if it is empty
+func HandleStorageObjectCat(folder storage.Folder, objectPath, outputPath string) {
+	var output io.Writer = os.Stdout
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		FatalfOnError("Failed to create the output file: %v\n", err)
+		defer utility.LoggedClose(file, "")
+		output = file
+	}
+
+	err := CatStorageObject(folder, objectPath, ConfigureCrypter(), output)
+	FatalfOnError("Failed to read the storage object: %v\n", err)
+}
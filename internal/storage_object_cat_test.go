@@ -0,0 +1,49 @@
+package internal_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestCatStorageObject_Compressed(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	content := []byte("tar partition content")
+	var compressed bytes.Buffer
+	writer := compression.Compressors["lz4"].NewWriter(&compressed)
+	_, err := writer.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, folder.PutObject("part_1.tar.lz4", &compressed))
+
+	var output bytes.Buffer
+	err = internal.CatStorageObject(folder, "part_1.tar.lz4", nil, &output)
+
+	assert.NoError(t, err)
+	assert.Equal(t, content, output.Bytes())
+}
+
+func TestCatStorageObject_Plain(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	content := []byte(`{"LSN":1}`)
+	require.NoError(t, folder.PutObject("base_000_backup_stop_sentinel.json", bytes.NewReader(content)))
+
+	var output bytes.Buffer
+	err := internal.CatStorageObject(folder, "base_000_backup_stop_sentinel.json", nil, &output)
+
+	assert.NoError(t, err)
+	assert.Equal(t, content, output.Bytes())
+}
+
+func TestCatStorageObject_NotFound(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+
+	err := internal.CatStorageObject(folder, "missing.json", nil, &bytes.Buffer{})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/azure"
+	"github.com/wal-g/storages/gcs"
+	"github.com/wal-g/storages/s3"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+)
+
+const azureBlobHostSuffix = ".blob.core."
+
+// the query parameters of the storage prefix URL and the settings they override
+var (
+	s3PrefixQuerySettings = map[string]string{
+		"endpoint":         s3.EndpointSetting,
+		"endpoint-source":  s3.EndpointSourceSetting,
+		"endpoint-port":    s3.EndpointPortSetting,
+		"region":           s3.RegionSetting,
+		"force-path-style": s3.ForcePathStyleSetting,
+		"storage-class":    s3.StorageClassSetting,
+	}
+	gsPrefixQuerySettings = map[string]string{
+		"normalize-prefix": gcs.NormalizePrefix,
+	}
+	azPrefixQuerySettings = map[string]string{
+		"account":     azure.AccountSetting,
+		"environment": azure.EnvironmentName,
+	}
+)
+
+// the Azure environments by the domain of the https://<account>.blob.core.<domain>/ endpoint
+var azureEnvironmentsByDomain = map[string]string{
+	"windows.net":       "AzurePublicCloud",
+	"usgovcloudapi.net": "AzureUSGovernmentCloud",
+	"chinacloudapi.cn":  "AzureChinaCloud",
+	"cloudapi.de":       "AzureGermanCloud",
+}
+
+type InvalidStoragePrefixError struct {
+	error
+}
+
+func newInvalidStoragePrefixError(prefix string, reason string) InvalidStoragePrefixError {
+	return InvalidStoragePrefixError{errors.Errorf("invalid storage prefix '%s': %s", prefix, reason)}
+}
+
+func (err InvalidStoragePrefixError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// withPrefixURL validates the bucket of the prefix URL before configuring the folder, the unexpected scheme
+// is only warned about.
+// The query parameters of the URL are applied to the settings taking precedence over the separate
+// settings, the folder is configured with the prefix without the query.
+func withPrefixURL(configureFolder func(string, map[string]string) (storage.Folder, error),
+	schemes []string, querySettings map[string]string) func(string, map[string]string) (storage.Folder, error) {
+	return func(prefix string, settings map[string]string) (storage.Folder, error) {
+		prefix, err := parsePrefixURL(prefix, schemes, querySettings, settings)
+		if err != nil {
+			return nil, err
+		}
+		return configureFolder(prefix, settings)
+	}
+}
+
+func parsePrefixURL(prefix string, schemes []string, querySettings map[string]string,
+	settings map[string]string) (string, error) {
+	prefixURL, err := url.Parse(prefix)
+	if err != nil {
+		return "", newInvalidStoragePrefixError(prefix, err.Error())
+	}
+	if prefixURL.Scheme == "" {
+		return "", newInvalidStoragePrefixError(prefix, "the scheme is not specified")
+	}
+	if !containsScheme(schemes, prefixURL.Scheme) {
+		// the storages ignore the scheme, so the prefixes which used to work keep working
+		tracelog.WarningLogger.Printf("The storage prefix '%s' is expected to have the %s scheme\n",
+			prefix, strings.Join(schemes, ":// or ")+"://")
+	}
+	if prefixURL.Host == "" {
+		return "", newInvalidStoragePrefixError(prefix, "the bucket is not specified")
+	}
+	if prefixURL.User != nil || prefixURL.Fragment != "" {
+		return "", newInvalidStoragePrefixError(prefix, "the user info and the fragment are not supported")
+	}
+
+	query, err := url.ParseQuery(prefixURL.RawQuery)
+	if err != nil {
+		return "", newInvalidStoragePrefixError(prefix, err.Error())
+	}
+	for parameter, values := range query {
+		settingName, ok := querySettings[parameter]
+		if !ok {
+			return "", newInvalidStoragePrefixError(prefix, fmt.Sprintf("unknown query parameter '%s'", parameter))
+		}
+		if len(values) != 1 || values[0] == "" {
+			return "", newInvalidStoragePrefixError(prefix,
+				fmt.Sprintf("expected a single value of the query parameter '%s'", parameter))
+		}
+		settings[settingName] = values[0]
+	}
+	// the prefix is passed as is except the query, so the path is not re-encoded
+	return strings.SplitN(prefix, "?", 2)[0], nil
+}
+
+func containsScheme(schemes []string, scheme string) bool {
+	for _, expected := range schemes {
+		if scheme == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// configureAzureFolder additionally accepts the https://<account>.blob.core.windows.net/<container>/<path>
+// blob endpoint URL, the account and the environment are derived from the host
+func configureAzureFolder(prefix string, settings map[string]string) (storage.Folder, error) {
+	if strings.HasPrefix(prefix, "https://") {
+		var err error
+		prefix, err = parseAzureBlobEndpointPrefix(prefix, settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return azure.ConfigureFolder(prefix, settings)
+}
+
+func parseAzureBlobEndpointPrefix(prefix string, settings map[string]string) (string, error) {
+	prefixURL, err := url.Parse(prefix)
+	if err != nil {
+		return "", newInvalidStoragePrefixError(prefix, err.Error())
+	}
+	suffixIndex := strings.Index(prefixURL.Host, azureBlobHostSuffix)
+	if suffixIndex <= 0 {
+		return "", newInvalidStoragePrefixError(prefix,
+			"expected the https://<account>.blob.core.windows.net/<container> blob endpoint")
+	}
+	environment, ok := azureEnvironmentsByDomain[prefixURL.Host[suffixIndex+len(azureBlobHostSuffix):]]
+	if !ok {
+		return "", newInvalidStoragePrefixError(prefix, "unknown Azure blob endpoint domain")
+	}
+	container := strings.SplitN(strings.TrimPrefix(prefixURL.Path, "/"), "/", 2)
+	if container[0] == "" {
+		return "", newInvalidStoragePrefixError(prefix, "the container is not specified")
+	}
+
+	settings[azure.AccountSetting] = prefixURL.Host[:suffixIndex]
+	settings[azure.EnvironmentName] = environment
+	path := ""
+	if len(container) > 1 {
+		path = container[1]
+	}
+	return "azure://" + container[0] + "/" + path, nil
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/storages/azure"
+	"github.com/wal-g/storages/s3"
+)
+
+func TestParsePrefixURL_AppliesQuery(t *testing.T) {
+	settings := map[string]string{s3.RegionSetting: "us-west-2", s3.SseSetting: "AES256"}
+	prefix, err := parsePrefixURL("s3://bucket/path/to/folder?endpoint=http://minio:9000&region=us-east-1",
+		[]string{"s3"}, s3PrefixQuerySettings, settings)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "s3://bucket/path/to/folder", prefix)
+	assert.Equal(t, map[string]string{
+		s3.EndpointSetting: "http://minio:9000",
+		s3.RegionSetting:   "us-east-1",
+		s3.SseSetting:      "AES256",
+	}, settings)
+}
+
+func TestParsePrefixURL_WithoutQuery(t *testing.T) {
+	settings := map[string]string{s3.RegionSetting: "us-west-2"}
+	prefix, err := parsePrefixURL("gs://bucket//wal-e//folder", []string{"gs"}, gsPrefixQuerySettings, settings)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://bucket//wal-e//folder", prefix)
+	assert.Equal(t, map[string]string{s3.RegionSetting: "us-west-2"}, settings)
+}
+
+func TestParsePrefixURL_UnexpectedScheme(t *testing.T) {
+	prefix, err := parsePrefixURL("gs://bucket/path?region=us-east-1", []string{"s3"}, s3PrefixQuerySettings,
+		map[string]string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://bucket/path", prefix)
+}
+
+func TestParsePrefixURL_Invalid(t *testing.T) {
+	for _, prefix := range []string{
+		"bucket/path",
+		"s3:///path",
+		"s3://bucket/path?unknown=1",
+		"s3://bucket/path?region=us-east-1&region=us-west-2",
+		"s3://bucket/path?region=",
+		"s3://user@bucket/path",
+	} {
+		_, err := parsePrefixURL(prefix, []string{"s3"}, s3PrefixQuerySettings, map[string]string{})
+		assert.IsType(t, InvalidStoragePrefixError{}, err, prefix)
+	}
+}
+
+func TestParseAzureBlobEndpointPrefix(t *testing.T) {
+	settings := map[string]string{azure.AccountSetting: "other", azure.EnvironmentName: "AzurePublicCloud"}
+	prefix, err := parseAzureBlobEndpointPrefix("https://account.blob.core.chinacloudapi.cn/container/walg-folder", settings)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "azure://container/walg-folder", prefix)
+	assert.Equal(t, "account", settings[azure.AccountSetting])
+	assert.Equal(t, "AzureChinaCloud", settings[azure.EnvironmentName])
+
+	prefix, err = parseAzureBlobEndpointPrefix("https://account.blob.core.windows.net/container", settings)
+	assert.NoError(t, err)
+	assert.Equal(t, "azure://container/", prefix)
+
+	for _, invalid := range []string{
+		"https://account.blob.core.windows.net/",
+		"https://example.com/container/path",
+		"https://account.blob.core.example.com/container",
+	} {
+		_, err = parseAzureBlobEndpointPrefix(invalid, map[string]string{})
+		assert.IsType(t, InvalidStoragePrefixError{}, err, invalid)
+	}
+}
@@ -4,16 +4,34 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"strings"
 	"sync/atomic"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/crypto"
 	"github.com/wal-g/wal-g/internal/limiters"
 	"github.com/wal-g/wal-g/utility"
 )
 
-const TarPartitionFolderName = "/tar_partitions/"
+const (
+	TarPartitionFolderName = "/tar_partitions/"
+	// tarPartitionKeySuffix ends the names of the partitions named by their content,
+	// the tarball appends its own extension to them
+	tarPartitionKeySuffix = ".tar"
+)
+
+// GetTarPartitionName names the tar partition by the key, which is derived from the partition content,
+// so the same partition gets the same name when the backup is pushed again
+func GetTarPartitionName(key string) string {
+	return "part_" + key + tarPartitionKeySuffix
+}
+
+func isTarPartitionKeyName(name string) bool {
+	return strings.HasSuffix(name, tarPartitionKeySuffix)
+}
 
 // StorageTarBall represents a tar file that is
 // going to be uploaded to storage.
@@ -25,6 +43,8 @@ type StorageTarBall struct {
 	tarWriter   *tar.Writer
 	uploader    *Uploader
 	name        string
+	// the partition is named by its content, so it needs no upload if it is already in the storage
+	skipExisting bool
 }
 
 func (tarBall *StorageTarBall) Name() string {
@@ -34,10 +54,14 @@ func (tarBall *StorageTarBall) Name() string {
 // SetUp creates a new tar writer and starts upload to storage.
 // Upload will block until the tar file is finished writing.
 // If a name for the file is not given, default name is of
-// the form `part_....tar.[Compressor file extension]`.
+// the form `part_....tar.[Compressor file extension]`. The name
+// made by GetTarPartitionName gets the compressor file extension too.
 func (tarBall *StorageTarBall) SetUp(crypter crypto.Crypter, names ...string) {
 	if tarBall.tarWriter == nil {
-		if len(names) > 0 {
+		if len(names) > 0 && isTarPartitionKeyName(names[0]) {
+			tarBall.name = names[0] + "." + tarBall.uploader.Compressor.FileExtension()
+			tarBall.skipExisting = viper.GetBool(SkipExistingPartsSetting)
+		} else if len(names) > 0 {
 			tarBall.name = names[0]
 		} else {
 			tarBall.name = fmt.Sprintf("part_%0.3d.tar.%v", tarBall.partNumber, tarBall.uploader.Compressor.FileExtension())
@@ -68,7 +92,7 @@ func (tarBall *StorageTarBall) CloseTar() error {
 func (tarBall *StorageTarBall) AwaitUploads() {
 	tarBall.uploader.waitGroup.Wait()
 	if tarBall.uploader.Failed.Load().(bool) {
-		tracelog.ErrorLogger.Fatal("Unable to complete uploads")
+		Fatal("Unable to complete uploads")
 	}
 }
 
@@ -81,6 +105,12 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 
 	path := tarBall.backupName + TarPartitionFolderName + name
 
+	if tarBall.skipExisting && tarBall.isUploaded(path) {
+		tracelog.InfoLogger.Printf("Part %d is already uploaded as '%s', skipping\n", tarBall.partNumber, path)
+		// the content is still read to describe the files in the sentinel
+		return discardWriteCloser{ioutil.Discard}
+	}
+
 	tracelog.InfoLogger.Printf("Starting part %d ...\n", tarBall.partNumber)
 
 	uploader.waitGroup.Add(1)
@@ -88,6 +118,11 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 		defer uploader.waitGroup.Done()
 
 		err := uploader.Upload(path, limiters.NewNetworkLimitReader(pipeReader))
+		if err != nil && uploader.Cancelled() {
+			// the backup is being aborted, the writer of the part waits for the exit
+			tracelog.WarningLogger.Printf("Upload of part %d is cancelled\n", tarBall.partNumber)
+			return
+		}
 		if compressingError, ok := err.(CompressAndEncryptError); ok {
 			tracelog.ErrorLogger.Printf("could not upload '%s' due to compression error\n%+v\n", path, compressingError)
 		}
@@ -95,8 +130,8 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 			tracelog.ErrorLogger.Printf("upload: could not upload '%s'\n", path)
 			tracelog.ErrorLogger.Printf("%v\n", err)
 			err = pipeReader.Close()
-			tracelog.ErrorLogger.FatalfOnError("Failed to close pipe: %v", err)
-			tracelog.ErrorLogger.Fatalf(
+			FatalfOnError("Failed to close pipe: %v", err)
+			Fatalf(
 				"Unable to continue the backup process because of the loss of a part %d.\n",
 				tarBall.partNumber)
 		}
@@ -108,7 +143,7 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 		encryptedWriter, err := crypter.Encrypt(pipeWriter)
 
 		if err != nil {
-			tracelog.ErrorLogger.Fatal("upload: encryption error ", err)
+			Fatal("upload: encryption error ", err)
 		}
 
 		writerToCompress = &utility.CascadeWriteCloser{WriteCloser: encryptedWriter, Underlying: pipeWriter}
@@ -118,6 +153,23 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 		Underlying: writerToCompress}
 }
 
+type discardWriteCloser struct {
+	io.Writer
+}
+
+func (discardWriteCloser) Close() error { return nil }
+
+// isUploaded checks whether the partition is already in the storage,
+// it is uploaded anyway if the check fails
+func (tarBall *StorageTarBall) isUploaded(path string) bool {
+	exists, err := tarBall.uploader.UploadingFolder.Exists(path)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to check whether '%s' is already uploaded: %v\n", path, err)
+		return false
+	}
+	return exists
+}
+
 // Size accumulated in this tarball
 func (tarBall *StorageTarBall) Size() int64 { return atomic.LoadInt64(tarBall.partSize) }
 
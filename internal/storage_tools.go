@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ListStorageFolder writes the objects and the subfolders of the storage folder at the prefix
+// with the object sizes and modification times. The recursive listing writes the objects
+// of the subfolders too, with their paths relative to the prefix. The objects of the TieredFolder
+// are written with the tier they are stored in.
+func ListStorageFolder(folder storage.Folder, prefix string, recursive bool, output io.Writer) error {
+	folder = folder.GetSubFolder(storage.AddDelimiterToPath(prefix))
+	var objects []storage.Object
+	var subFolders []storage.Folder
+	var err error
+	tieredFolder, tiered := folder.(*TieredFolder)
+	switch {
+	case recursive && tiered:
+		objects, err = tieredFolder.ListFolderRecursively()
+	case recursive:
+		objects, err = storage.ListFolderRecursively(folder)
+	default:
+		objects, subFolders, err = folder.ListFolder()
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to list '%s'", prefix)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].GetName() < objects[j].GetName()
+	})
+
+	writer := tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
+	defer writer.Flush()
+	if tiered {
+		fmt.Fprintln(writer, "name\tmodified\tsize\ttier")
+	} else {
+		fmt.Fprintln(writer, "name\tmodified\tsize")
+	}
+	for _, subFolder := range subFolders {
+		fmt.Fprintf(writer, "%v/\t\t\n", path.Base(subFolder.GetPath()))
+	}
+	for _, object := range objects {
+		fmt.Fprintf(writer, "%v\t%v\t%v", object.GetName(), FormatTime(object.GetLastModified()), object.GetSize())
+		if tiered {
+			fmt.Fprintf(writer, "\t%v", GetObjectTier(object))
+		}
+		fmt.Fprintln(writer)
+	}
+	return nil
+}
+
+type UnsupportedCompressionExtensionError struct {
+	error
+}
+
+func newUnsupportedCompressionExtensionError(objectPath string) UnsupportedCompressionExtensionError {
+	return UnsupportedCompressionExtensionError{errors.Errorf(
+		"Can't compress %s by its extension, supported methods are: %v", objectPath, compression.CompressingAlgorithms)}
+}
+
+func (err UnsupportedCompressionExtensionError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// findCompressorByExtension returns the compressor of the file extension or nil
+func findCompressorByExtension(fileExtension string) compression.Compressor {
+	for _, compressor := range compression.Compressors {
+		if compressor.FileExtension() == fileExtension {
+			return compressor
+		}
+	}
+	return nil
+}
+
+// PutStorageObject uploads the content to the storage object. As the reverse of CatStorageObject,
+// it is compressed and encrypted according to the object extension, other objects are uploaded as is.
+// The uncompressed tar partitions are only encrypted.
+func PutStorageObject(folder storage.Folder, objectPath string, content io.Reader, crypter crypto.Crypter) error {
+	if isDecodableObject(objectPath) {
+		var compressor compression.Compressor
+		if fileExtension := utility.GetFileExtension(objectPath); fileExtension != "tar" {
+			compressor = findCompressorByExtension(fileExtension)
+			if compressor == nil {
+				// the decompression only methods, e.g. lzo, would store the content uncompressed
+				return newUnsupportedCompressionExtensionError(objectPath)
+			}
+		}
+		content = CompressAndEncrypt(content, compressor, crypter)
+	}
+	return errors.Wrapf(folder.PutObject(objectPath, content), "failed to upload %s", objectPath)
+}
+
+// DeleteStorageObject deletes the storage object, reporting the missing one
+// since the storages don't tell the deletion of the missing object from the successful one
+func DeleteStorageObject(folder storage.Folder, objectPath string) error {
+	exists, err := folder.Exists(objectPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check %s", objectPath)
+	}
+	if !exists {
+		return storage.NewObjectNotFoundError(objectPath)
+	}
+	return folder.DeleteObjects([]string{objectPath})
+}
+
+func fatalOnStorageReadOnly(operation, objectPath string) {
+	if IsStorageReadOnly() {
+		FatalOnError(newStorageReadOnlyError(operation, objectPath))
+	}
+}
+
+// HandleStorageList writes the listing of the storage folder at the prefix to stdout
+func HandleStorageList(folder storage.Folder, prefix string, recursive bool) {
+	err := ListStorageFolder(folder, prefix, recursive, os.Stdout)
+	FatalOnError(err)
+}
+
+// HandleStorageObjectGet downloads the decrypted and decompressed storage object to the file at outputPath,
+// the file is named after the object if outputPath is empty
+func HandleStorageObjectGet(folder storage.Folder, objectPath, outputPath string) {
+	if outputPath == "" {
+		outputPath = path.Base(objectPath)
+		if compression.FindDecompressor(utility.GetFileExtension(outputPath)) != nil {
+			outputPath = utility.TrimFileExtension(outputPath)
+		}
+	}
+	HandleStorageObjectCat(folder, objectPath, outputPath)
+}
+
+// HandleStorageObjectPut uploads the file at filePath to the storage object
+func HandleStorageObjectPut(folder storage.Folder, objectPath, filePath string) {
+	fatalOnStorageReadOnly("put", objectPath)
+	file, err := os.Open(filePath)
+	FatalfOnError("Failed to open the file: %v\n", err)
+	defer utility.LoggedClose(file, "")
+
+	err = PutStorageObject(folder, objectPath, file, ConfigureCrypter())
+	FatalOnError(err)
+	tracelog.InfoLogger.Printf("Uploaded %s to %s\n", filePath, objectPath)
+}
+
+// HandleStorageObjectDelete deletes the storage object
+func HandleStorageObjectDelete(folder storage.Folder, objectPath string) {
+	fatalOnStorageReadOnly("delete", objectPath)
+	err := DeleteStorageObject(folder, objectPath)
+	FatalOnError(err)
+	tracelog.InfoLogger.Printf("Deleted %s\n", objectPath)
+}
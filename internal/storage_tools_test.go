@@ -0,0 +1,120 @@
+package internal_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestListStorageFolder(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	require.NoError(t, folder.PutObject("basebackups_005/base_000_backup_stop_sentinel.json", strings.NewReader("{}")))
+	require.NoError(t, folder.PutObject("basebackups_005/base_000/tar_partitions/part_1.tar.lz4",
+		strings.NewReader("part")))
+
+	var output bytes.Buffer
+	require.NoError(t, internal.ListStorageFolder(folder, "basebackups_005", false, &output))
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"name", "modified", "size"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"base_000/"}, strings.Fields(lines[1]))
+	fields := strings.Fields(lines[2])
+	assert.Equal(t, "base_000_backup_stop_sentinel.json", fields[0])
+	assert.Equal(t, "2", fields[len(fields)-1])
+
+	output.Reset()
+	require.NoError(t, internal.ListStorageFolder(folder, "basebackups_005", true, &output))
+	assert.Contains(t, output.String(), "base_000/tar_partitions/part_1.tar.lz4")
+}
+
+func TestListStorageFolder_Tiered(t *testing.T) {
+	hot := memory.NewFolder("hot/", memory.NewStorage())
+	cold := memory.NewFolder("cold/", memory.NewStorage())
+	require.NoError(t, hot.PutObject("wal_005/000000010000000000000002.lz4", strings.NewReader("hot wal")))
+	require.NoError(t, cold.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("cold wal")))
+	folder := internal.NewTieredFolder(hot, cold)
+
+	for _, recursive := range []bool{false, true} {
+		var output bytes.Buffer
+		require.NoError(t, internal.ListStorageFolder(folder, "wal_005", recursive, &output))
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, []string{"name", "modified", "size", "tier"}, strings.Fields(lines[0]))
+		fields := strings.Fields(lines[1])
+		assert.Equal(t, "000000010000000000000001.lz4", fields[0])
+		assert.Equal(t, internal.ColdTierName, fields[len(fields)-1])
+		fields = strings.Fields(lines[2])
+		assert.Equal(t, internal.HotTierName, fields[len(fields)-1])
+	}
+}
+
+func TestPutStorageObject_RoundTrip(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	content := []byte("tar partition content")
+
+	require.NoError(t, internal.PutStorageObject(folder, "part_1.tar.lz4", bytes.NewReader(content), nil))
+	stored, err := folder.ReadObject("part_1.tar.lz4")
+	require.NoError(t, err)
+	var storedContent bytes.Buffer
+	_, err = storedContent.ReadFrom(stored)
+	require.NoError(t, err)
+	assert.NotEqual(t, content, storedContent.Bytes())
+
+	var output bytes.Buffer
+	require.NoError(t, internal.CatStorageObject(folder, "part_1.tar.lz4", nil, &output))
+	assert.Equal(t, content, output.Bytes())
+}
+
+func TestPutStorageObject_Uncompressed(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	content := []byte("tar partition content")
+
+	require.NoError(t, internal.PutStorageObject(folder, "part_1.tar", bytes.NewReader(content), nil))
+	var output bytes.Buffer
+	require.NoError(t, internal.CatStorageObject(folder, "part_1.tar", nil, &output))
+	assert.Equal(t, content, output.Bytes())
+}
+
+func TestPutStorageObject_NoCompressor(t *testing.T) {
+	defer func(compressor compression.Compressor) {
+		compression.Compressors[lz4.AlgorithmName] = compressor
+	}(compression.Compressors[lz4.AlgorithmName])
+	delete(compression.Compressors, lz4.AlgorithmName)
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+
+	err := internal.PutStorageObject(folder, "part_1.tar.lz4", strings.NewReader("content"), nil)
+	assert.IsType(t, internal.UnsupportedCompressionExtensionError{}, err)
+	exists, err := folder.Exists("part_1.tar.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDeleteStorageObject(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	require.NoError(t, folder.PutObject("garbage.json", strings.NewReader("{}")))
+
+	require.NoError(t, internal.DeleteStorageObject(folder, "garbage.json"))
+	exists, err := folder.Exists("garbage.json")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.IsType(t, storage.ObjectNotFoundError{}, internal.DeleteStorageObject(folder, "garbage.json"))
+}
+
+func TestPutStorageObject_ReadOnly(t *testing.T) {
+	folder := internal.NewReadOnlyFolder(testtools.MakeDefaultInMemoryStorageFolder())
+
+	err := internal.PutStorageObject(folder, "sentinel.json", strings.NewReader("{}"), nil)
+
+	assert.IsType(t, internal.StorageReadOnlyError{}, errors.Cause(err))
+}
@@ -17,7 +17,7 @@ const (
 // PushStream compresses a stream and push it
 func (uploader *Uploader) PushStream(stream io.Reader) (string, error) {
 	backupName := StreamPrefix + utility.TimeNowCrossPlatformUTC().Format(utility.BackupTimeFormat)
-	dstPath := GetStreamName(backupName, uploader.Compressor.FileExtension())
+	dstPath := GetStreamName(backupName, uploader.StreamCompression().FileExtension())
 	err := uploader.PushStreamToDestination(stream, dstPath)
 
 	return backupName, err
@@ -29,7 +29,7 @@ func (uploader *Uploader) PushStreamToDestination(stream io.Reader, dstPath stri
 	if uploader.dataSize != nil {
 		stream = NewWithSizeReader(stream, uploader.dataSize)
 	}
-	compressed := CompressAndEncrypt(stream, uploader.Compressor, ConfigureCrypter())
+	compressed := CompressAndEncrypt(stream, uploader.StreamCompression(), ConfigureCrypter())
 	err := uploader.Upload(dstPath, compressed)
 	tracelog.InfoLogger.Println("FILE PATH:", dstPath)
 
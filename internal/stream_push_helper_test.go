@@ -0,0 +1,30 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/internal/compression/lzma"
+)
+
+func TestPushStream_UsesStreamCompressor(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	uploader := internal.NewUploader(lzma.Compressor{}, folder)
+	assert.Equal(t, lzma.Compressor{}, uploader.StreamCompression())
+
+	uploader.StreamCompressor = lz4.Compressor{}
+	assert.Equal(t, lzma.Compressor{}, uploader.Compression())
+	assert.Equal(t, lz4.Compressor{}, uploader.StreamCompression())
+	assert.Equal(t, lz4.Compressor{}, uploader.Clone().StreamCompression())
+
+	backupName, err := uploader.PushStream(strings.NewReader("stream"))
+	require.NoError(t, err)
+	exists, err := folder.Exists(internal.GetStreamName(backupName, lz4.FileExtension))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
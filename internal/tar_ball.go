@@ -3,6 +3,8 @@ package internal
 import (
 	"archive/tar"
 	"io"
+	"io/ioutil"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/wal-g/internal/crypto"
@@ -19,8 +21,45 @@ type TarBall interface {
 	Name() string
 }
 
+const (
+	TarFormatPAX = "pax"
+	TarFormatGNU = "gnu"
+)
+
+// TarHeaderFormat is the format of the tar headers written to the backups, see WALG_TAR_FORMAT.
+// PAX keeps the long names, the long user and group names and the sub-second modification times.
+var TarHeaderFormat = tar.FormatPAX
+
+// ParseTarFormat parses the WALG_TAR_FORMAT value
+func ParseTarFormat(tarFormat string) (tar.Format, error) {
+	switch strings.ToLower(tarFormat) {
+	case TarFormatPAX:
+		return tar.FormatPAX, nil
+	case TarFormatGNU:
+		return tar.FormatGNU, nil
+	default:
+		return tar.FormatUnknown, errors.Errorf("unknown %s value '%s', expected '%s' or '%s'",
+			TarFormatSetting, tarFormat, TarFormatPAX, TarFormatGNU)
+	}
+}
+
+// SetTarHeaderFormat makes the header written in the TarHeaderFormat. The header the format can't keep,
+// e.g. the GNU one with the user name longer than 31 characters or with the PAX records, is written in PAX.
+func SetTarHeaderFormat(header *tar.Header) {
+	header.Format = TarHeaderFormat
+	if header.Format != tar.FormatPAX && !canWriteTarHeader(*header) {
+		header.Format = tar.FormatPAX
+	}
+}
+
+// canWriteTarHeader tells if the header can be written in its format
+func canWriteTarHeader(header tar.Header) bool {
+	return tar.NewWriter(ioutil.Discard).WriteHeader(&header) == nil
+}
+
 func PackFileTo(tarBall TarBall, fileInfoHeader *tar.Header, fileContent io.Reader) (fileSize int64, err error) {
 	tarWriter := tarBall.TarWriter()
+	SetTarHeaderFormat(fileInfoHeader)
 	err = tarWriter.WriteHeader(fileInfoHeader)
 	if err != nil {
 		return 0, errors.Wrap(err, "PackFileTo: failed to write header")
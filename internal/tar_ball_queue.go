@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 
 	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/abool"
 )
 
@@ -17,6 +18,9 @@ type TarBallQueue struct {
 	maxUploadQueue   int
 	mutex            sync.Mutex
 	started          *abool.AtomicBool
+	// memoryLimit bounds the tarballs being filled or uploaded, each is accounted as TarSizeThreshold
+	memoryLimit    int64
+	reservedMemory int64
 
 	TarSizeThreshold   int64
 	AllTarballsSize    *int64
@@ -46,11 +50,16 @@ func (tarQueue *TarBallQueue) StartQueue() error {
 	if err != nil {
 		return err
 	}
+	tarQueue.memoryLimit, err = GetTarQueueMemoryLimit()
+	if err != nil {
+		return err
+	}
+	tarQueue.limitParallelTarballs()
 
 	tarQueue.tarsToFillQueue = make(chan TarBall, tarQueue.parallelTarballs)
 	tarQueue.uploadQueue = make(chan TarBall, tarQueue.parallelTarballs+tarQueue.maxUploadQueue)
 	for i := 0; i < tarQueue.parallelTarballs; i++ {
-		tarQueue.NewTarBall(true)
+		tarQueue.newQueuedTarBall()
 		tarQueue.tarsToFillQueue <- tarQueue.LastCreatedTarball
 	}
 
@@ -59,7 +68,8 @@ func (tarQueue *TarBallQueue) StartQueue() error {
 }
 
 // DequeCtx returns a TarBall from the queue. If the context finishes before it
-// can do so, it returns the result of ctx.Err().
+// can do so, it returns the result of ctx.Err(). With WALG_TAR_QUEUE_MEMORY_LIMIT set it also
+// waits until the uploads of the finished tarballs free the memory for the new one.
 func (tarQueue *TarBallQueue) DequeCtx(ctx context.Context) (TarBall, error) {
 	if tarQueue.started.IsNotSet() {
 		panic("Trying to deque from not started Queue")
@@ -128,12 +138,20 @@ func (tarQueue *TarBallQueue) FinishTarBall(tarBall TarBall) error {
 	for len(tarQueue.uploadQueue) > tarQueue.maxUploadQueue {
 		select {
 		case otb := <-tarQueue.uploadQueue:
-			otb.AwaitUploads()
+			tarQueue.awaitUploads(otb)
+		default:
+		}
+	}
+	for tarQueue.memoryLimit > 0 && tarQueue.reservedMemory+tarQueue.TarSizeThreshold > tarQueue.memoryLimit &&
+		len(tarQueue.uploadQueue) > 0 {
+		select {
+		case otb := <-tarQueue.uploadQueue:
+			tarQueue.awaitUploads(otb)
 		default:
 		}
 	}
 
-	tarQueue.NewTarBall(true)
+	tarQueue.newQueuedTarBall()
 	tarQueue.tarsToFillQueue <- tarQueue.LastCreatedTarball
 	return nil
 }
@@ -153,6 +171,36 @@ func (tarQueue *TarBallQueue) NewTarBall(dedicatedUploader bool) TarBall {
 	return tarQueue.LastCreatedTarball
 }
 
+// newQueuedTarBall starts writing new tarball of the queue and reserves the memory for it
+func (tarQueue *TarBallQueue) newQueuedTarBall() TarBall {
+	tarQueue.reservedMemory += tarQueue.TarSizeThreshold
+	return tarQueue.NewTarBall(true)
+}
+
+// awaitUploads waits for the finished tarball to be uploaded and frees its memory
+func (tarQueue *TarBallQueue) awaitUploads(tarBall TarBall) {
+	tarBall.AwaitUploads()
+	tarQueue.reservedMemory -= tarQueue.TarSizeThreshold
+}
+
+// limitParallelTarballs reduces the number of the tarballs filled at once to fit into the memory limit
+func (tarQueue *TarBallQueue) limitParallelTarballs() {
+	if tarQueue.memoryLimit <= 0 {
+		return
+	}
+	maxTarballs := int(tarQueue.memoryLimit / tarQueue.TarSizeThreshold)
+	if maxTarballs < 1 {
+		tracelog.WarningLogger.Printf("%s %d is less than %s %d, the memory limit is exceeded by a single tarball\n",
+			TarQueueMemoryLimitSetting, tarQueue.memoryLimit, TarSizeThresholdSetting, tarQueue.TarSizeThreshold)
+		maxTarballs = 1
+	}
+	if tarQueue.parallelTarballs > maxTarballs {
+		tracelog.InfoLogger.Printf("Filling %d tarballs at once instead of %d to fit into %s\n",
+			maxTarballs, tarQueue.parallelTarballs, TarQueueMemoryLimitSetting)
+		tarQueue.parallelTarballs = maxTarballs
+	}
+}
+
 func (tarQueue *TarBallQueue) CloseTarball(tarBall TarBall) error {
 	atomic.AddInt64(tarQueue.AllTarballsSize, tarBall.Size())
 	return tarBall.CloseTar()
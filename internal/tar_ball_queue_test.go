@@ -0,0 +1,106 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+// pendingUploadTarBall is uploaded only when the test releases it
+type pendingUploadTarBall struct {
+	internal.TarBall
+	uploaded chan struct{}
+}
+
+func (tarBall *pendingUploadTarBall) AwaitUploads() {
+	<-tarBall.uploaded
+}
+
+type pendingUploadTarBallMaker struct {
+	bufferTarBallMaker *testtools.BufferTarBallMaker
+}
+
+func (tarBallMaker *pendingUploadTarBallMaker) Make(dedicatedUploader bool) internal.TarBall {
+	return &pendingUploadTarBall{tarBallMaker.bufferTarBallMaker.Make(dedicatedUploader), make(chan struct{})}
+}
+
+func startMemoryLimitedQueue(t *testing.T, diskConcurrency int, memoryLimit int64) *internal.TarBallQueue {
+	viper.Set(internal.UploadDiskConcurrencySetting, diskConcurrency)
+	viper.Set(internal.UploadQueueSetting, 2)
+	viper.Set(internal.TarQueueMemoryLimitSetting, memoryLimit)
+	tarBallMaker := &pendingUploadTarBallMaker{
+		&testtools.BufferTarBallMaker{Size: new(int64), BufferToWrite: &bytes.Buffer{}}}
+	tarBallQueue := internal.NewTarBallQueue(10, tarBallMaker)
+	require.NoError(t, tarBallQueue.StartQueue())
+	return tarBallQueue
+}
+
+func resetMemoryLimitedQueueSettings() {
+	viper.Set(internal.UploadDiskConcurrencySetting, nil)
+	viper.Set(internal.UploadQueueSetting, nil)
+	viper.Set(internal.TarQueueMemoryLimitSetting, nil)
+}
+
+func dequeWithTimeout(tarBallQueue *internal.TarBallQueue) (internal.TarBall, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	return tarBallQueue.DequeCtx(ctx)
+}
+
+func TestTarBallQueue_MemoryLimitBoundsParallelTarballs(t *testing.T) {
+	defer resetMemoryLimitedQueueSettings()
+	tarBallQueue := startMemoryLimitedQueue(t, 4, 20)
+	for i := 0; i < 2; i++ {
+		_, err := dequeWithTimeout(tarBallQueue)
+		require.NoError(t, err)
+	}
+	_, err := dequeWithTimeout(tarBallQueue)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestTarBallQueue_MemoryPressureBlocksDeque(t *testing.T) {
+	defer resetMemoryLimitedQueueSettings()
+	tarBallQueue := startMemoryLimitedQueue(t, 1, 20)
+
+	first := tarBallQueue.Deque()
+	first.SetUp(nil)
+	require.NoError(t, tarBallQueue.FinishTarBall(first))
+	// the first tarball is uploading, there is memory for the second one
+	second, err := dequeWithTimeout(tarBallQueue)
+	require.NoError(t, err)
+
+	second.SetUp(nil)
+	finished := make(chan error)
+	go func() {
+		finished <- tarBallQueue.FinishTarBall(second)
+	}()
+	// both tarballs are uploading and take all the memory
+	_, err = dequeWithTimeout(tarBallQueue)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	close(first.(*pendingUploadTarBall).uploaded)
+	require.NoError(t, <-finished)
+	third, err := dequeWithTimeout(tarBallQueue)
+	require.NoError(t, err)
+	assert.NotEqual(t, second, third)
+}
+
+func TestTarBallQueue_NoMemoryLimit(t *testing.T) {
+	defer resetMemoryLimitedQueueSettings()
+	tarBallQueue := startMemoryLimitedQueue(t, 1, 0)
+	// the uploads of both tarballs are pending, but they fit into the upload queue
+	for i := 0; i < 2; i++ {
+		tarBall := tarBallQueue.Deque()
+		tarBall.SetUp(nil)
+		require.NoError(t, tarBallQueue.FinishTarBall(tarBall))
+	}
+	_, err := dequeWithTimeout(tarBallQueue)
+	assert.NoError(t, err)
+}
@@ -6,8 +6,12 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/testtools"
 )
@@ -109,3 +113,78 @@ func TestPackFileTo(t *testing.T) {
 	}
 	assert.Equal(t, []byte(mockData), interpreter.Out)
 }
+
+func TestPackFileTo_TarHeaderFormat(t *testing.T) {
+	defer func() { internal.TarHeaderFormat = tar.FormatPAX }()
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 890, time.UTC)
+	longName := strings.Repeat("pg_tblspc/16384/", 10) + "1259"
+	for _, tarFormat := range []string{internal.TarFormatPAX, internal.TarFormatGNU} {
+		format, err := internal.ParseTarFormat(tarFormat)
+		require.NoError(t, err)
+		internal.TarHeaderFormat = format
+
+		buffer := new(bytes.Buffer)
+		tarBall := (&testtools.BufferTarBallMaker{BufferToWrite: buffer, Size: new(int64)}).Make(false)
+		tarBall.SetUp(nil)
+		_, err = internal.PackFileTo(tarBall, &tar.Header{Name: longName, Mode: 0600, Size: 4, ModTime: modTime,
+			Typeflag: tar.TypeReg}, strings.NewReader("mock"))
+		require.NoError(t, err)
+		require.NoError(t, tarBall.TarWriter().Close())
+
+		header, err := tar.NewReader(buffer).Next()
+		require.NoError(t, err)
+		assert.Equal(t, format, header.Format)
+		assert.Equal(t, longName, header.Name)
+		if format == tar.FormatPAX {
+			assert.Equal(t, modTime, header.ModTime.UTC())
+		}
+	}
+
+	_, err := internal.ParseTarFormat("ustar")
+	assert.Error(t, err)
+}
+
+func TestSetTarHeaderFormat_UpgradesToPAX(t *testing.T) {
+	defer func() { internal.TarHeaderFormat = tar.FormatPAX }()
+	internal.TarHeaderFormat = tar.FormatGNU
+
+	header := &tar.Header{Name: "base/1/1259", Uname: "postgres", Size: 4, Typeflag: tar.TypeReg}
+	internal.SetTarHeaderFormat(header)
+	assert.Equal(t, tar.FormatGNU, header.Format)
+
+	header = &tar.Header{Name: "base/1/1259", Uname: strings.Repeat("postgres", 5), Size: 4, Typeflag: tar.TypeReg}
+	internal.SetTarHeaderFormat(header)
+	assert.Equal(t, tar.FormatPAX, header.Format)
+
+	header = &tar.Header{Name: "base/1/1259", Size: 4, Typeflag: tar.TypeReg,
+		PAXRecords: map[string]string{"SCHILY.xattr.user.mime_type": "text/plain"}}
+	internal.SetTarHeaderFormat(header)
+	assert.Equal(t, tar.FormatPAX, header.Format)
+}
+
+func TestStorageTarBall_SkipExistingParts(t *testing.T) {
+	viper.Set(internal.SkipExistingPartsSetting, true)
+	defer viper.Set(internal.SkipExistingPartsSetting, false)
+
+	storage := memory.NewStorage()
+	uploader := testtools.NewStoringMockUploader(storage, nil)
+	partPath := "mockBackup" + internal.TarPartitionFolderName + "part_abc.tar.mock"
+	assert.NoError(t, uploader.UploadingFolder.PutObject(partPath, strings.NewReader("uploaded")))
+
+	tarBallMaker := internal.NewStorageTarBallMaker("mockBackup", uploader)
+	for _, name := range []string{internal.GetTarPartitionName("abc"), internal.GetTarPartitionName("def")} {
+		tarBall := tarBallMaker.Make(false)
+		tarBall.SetUp(nil, name)
+		_, err := internal.PackFileTo(tarBall, &tar.Header{Name: "mock", Size: 4}, strings.NewReader("mock"))
+		assert.NoError(t, err)
+		assert.NoError(t, tarBall.CloseTar())
+		tarBall.AwaitUploads()
+		assert.Equal(t, name+".mock", tarBall.Name())
+	}
+
+	existing, ok := storage.Load("in_memory/" + partPath)
+	assert.True(t, ok)
+	assert.Equal(t, "uploaded", existing.Data.String())
+	_, ok = storage.Load("in_memory/mockBackup" + internal.TarPartitionFolderName + "part_def.tar.mock")
+	assert.True(t, ok)
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/storages/storage"
+)
+
+const (
+	HotTierName  = "hot"
+	ColdTierName = "cold"
+)
+
+// TieredObject is the storage object annotated with the tier it is stored in
+type TieredObject struct {
+	storage.Object
+	Tier string
+}
+
+// GetObjectTier returns the tier of the object listed from the TieredFolder,
+// the objects listed from the other folders have no tier
+func GetObjectTier(object storage.Object) string {
+	if tieredObject, ok := object.(TieredObject); ok {
+		return tieredObject.Tier
+	}
+	return ""
+}
+
+// TieredFolder combines the hot storage folder with the colder one.
+// New objects are always written to the hot tier, reads fall back
+// to the cold tier when the object is absent from the hot one.
+type TieredFolder struct {
+	hot  storage.Folder
+	cold storage.Folder
+}
+
+func NewTieredFolder(hot, cold storage.Folder) *TieredFolder {
+	return &TieredFolder{hot, cold}
+}
+
+func (folder *TieredFolder) Hot() storage.Folder {
+	return folder.hot
+}
+
+func (folder *TieredFolder) Cold() storage.Folder {
+	return folder.cold
+}
+
+func (folder *TieredFolder) GetPath() string {
+	return folder.hot.GetPath()
+}
+
+// ListFolder merges the objects and subfolders of both tiers.
+// If the object is present in both tiers, the hot one is listed.
+func (folder *TieredFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	hotObjects, hotSubFolders, err := folder.hot.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	coldObjects, coldSubFolders, err := folder.cold.ListFolder()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list cold storage tier")
+	}
+
+	objects = mergeTierObjects(hotObjects, coldObjects)
+
+	subFolderNames := make(map[string]bool, len(hotSubFolders))
+	subFolders = make([]storage.Folder, 0, len(hotSubFolders)+len(coldSubFolders))
+	addSubFolder := func(parent, subFolder storage.Folder) {
+		name := strings.TrimPrefix(subFolder.GetPath(), parent.GetPath())
+		if !subFolderNames[name] {
+			subFolderNames[name] = true
+			subFolders = append(subFolders, folder.GetSubFolder(name))
+		}
+	}
+	for _, subFolder := range hotSubFolders {
+		addSubFolder(folder.hot, subFolder)
+	}
+	for _, subFolder := range coldSubFolders {
+		addSubFolder(folder.cold, subFolder)
+	}
+	return objects, subFolders, nil
+}
+
+// ListFolderRecursively lists the objects of both tiers with their paths relative to the folder,
+// the objects keep the tier they are stored in unlike the ones of storage.ListFolderRecursively
+func (folder *TieredFolder) ListFolderRecursively() ([]storage.Object, error) {
+	hotObjects, err := storage.ListFolderRecursively(folder.hot)
+	if err != nil {
+		return nil, err
+	}
+	coldObjects, err := storage.ListFolderRecursively(folder.cold)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list cold storage tier")
+	}
+	return mergeTierObjects(hotObjects, coldObjects), nil
+}
+
+func mergeTierObjects(hotObjects, coldObjects []storage.Object) []storage.Object {
+	hotNames := make(map[string]bool, len(hotObjects))
+	objects := make([]storage.Object, 0, len(hotObjects)+len(coldObjects))
+	for _, object := range hotObjects {
+		hotNames[object.GetName()] = true
+		objects = append(objects, TieredObject{object, HotTierName})
+	}
+	for _, object := range coldObjects {
+		if !hotNames[object.GetName()] {
+			objects = append(objects, TieredObject{object, ColdTierName})
+		}
+	}
+	return objects
+}
+
+// DeleteObjects deletes objects from both tiers
+func (folder *TieredFolder) DeleteObjects(objectRelativePaths []string) error {
+	err := folder.hot.DeleteObjects(objectRelativePaths)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(folder.cold.DeleteObjects(objectRelativePaths), "failed to delete from cold storage tier")
+}
+
+func (folder *TieredFolder) Exists(objectRelativePath string) (bool, error) {
+	exists, err := folder.hot.Exists(objectRelativePath)
+	if err != nil || exists {
+		return exists, err
+	}
+	return folder.cold.Exists(objectRelativePath)
+}
+
+func (folder *TieredFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewTieredFolder(folder.hot.GetSubFolder(subFolderRelativePath),
+		folder.cold.GetSubFolder(subFolderRelativePath))
+}
+
+func (folder *TieredFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := folder.hot.ReadObject(objectRelativePath)
+	if _, notFound := errors.Cause(err).(storage.ObjectNotFoundError); notFound {
+		return folder.cold.ReadObject(objectRelativePath)
+	}
+	return reader, err
+}
+
+func (folder *TieredFolder) ReadObjectFrom(objectRelativePath string, offset int64,
+	eTag *string) (io.ReadCloser, error) {
+	reader, err := ReadObjectFrom(folder.hot, objectRelativePath, offset, eTag)
+	if _, notFound := errors.Cause(err).(storage.ObjectNotFoundError); notFound {
+		return ReadObjectFrom(folder.cold, objectRelativePath, offset, eTag)
+	}
+	return reader, err
+}
+
+func (folder *TieredFolder) PutObject(name string, content io.Reader) error {
+	return folder.hot.PutObject(name, content)
+}
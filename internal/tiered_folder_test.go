@@ -0,0 +1,84 @@
+package internal_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func newTestTieredFolder(t *testing.T) *internal.TieredFolder {
+	hot := memory.NewFolder("hot/", memory.NewStorage())
+	cold := memory.NewFolder("cold/", memory.NewStorage())
+	require.NoError(t, hot.PutObject("wal_005/000000010000000000000002.lz4", strings.NewReader("hot wal")))
+	require.NoError(t, cold.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("cold wal")))
+	require.NoError(t, cold.PutObject("basebackups_005/base_000000010000000000000001_backup_stop_sentinel.json",
+		strings.NewReader("{}")))
+	return internal.NewTieredFolder(hot, cold)
+}
+
+func TestTieredFolder_ReadObjectFallsBackToCold(t *testing.T) {
+	folder := newTestTieredFolder(t)
+
+	reader, err := folder.GetSubFolder("wal_005/").ReadObject("000000010000000000000001.lz4")
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "cold wal", string(content))
+
+	exists, err := folder.Exists("wal_005/000000010000000000000001.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = folder.ReadObject("wal_005/000000010000000000000003.lz4")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
+
+func TestTieredFolder_ListFolderReportsTier(t *testing.T) {
+	folder := newTestTieredFolder(t)
+
+	_, subFolders, err := folder.ListFolder()
+	require.NoError(t, err)
+	assert.Len(t, subFolders, 2)
+
+	objects, _, err := folder.GetSubFolder("wal_005/").ListFolder()
+	require.NoError(t, err)
+	tiers := make(map[string]string)
+	for _, object := range objects {
+		tiers[object.GetName()] = internal.GetObjectTier(object)
+	}
+	assert.Equal(t, map[string]string{
+		"000000010000000000000001.lz4": internal.ColdTierName,
+		"000000010000000000000002.lz4": internal.HotTierName,
+	}, tiers)
+}
+
+func TestTieredFolder_ListFolderRecursivelyReportsTier(t *testing.T) {
+	folder := newTestTieredFolder(t)
+
+	objects, err := folder.ListFolderRecursively()
+	require.NoError(t, err)
+	tiers := make(map[string]string)
+	for _, object := range objects {
+		tiers[object.GetName()] = internal.GetObjectTier(object)
+	}
+	assert.Equal(t, map[string]string{
+		"wal_005/000000010000000000000001.lz4":                                    internal.ColdTierName,
+		"wal_005/000000010000000000000002.lz4":                                    internal.HotTierName,
+		"basebackups_005/base_000000010000000000000001_backup_stop_sentinel.json": internal.ColdTierName,
+	}, tiers)
+}
+
+func TestGetBackups_ReportsTier(t *testing.T) {
+	folder := newTestTieredFolder(t)
+
+	backups, err := internal.GetBackups(folder.GetSubFolder("basebackups_005/"))
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, internal.ColdTierName, backups[0].Tier)
+}
@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/wal-g/wal-g"
+	serviceName = "wal-g"
+
+	SizeAttribute = attribute.Key("walg.size")
+	PathAttribute = attribute.Key("walg.path")
+)
+
+// flushTimeout bounds the export of the spans left when the command finishes,
+// so the unreachable collector doesn't keep WAL-G from exiting
+var flushTimeout = 5 * time.Second
+
+// prefixedLogger is the tracelog logger, its lines are prefixed with the trace ID
+type prefixedLogger interface {
+	Prefix() string
+	SetPrefix(prefix string)
+}
+
+var (
+	tracerProvider *sdktrace.TracerProvider
+	// commandCtx holds the span of the running command, the parent of the spans started with Start
+	commandCtx  = context.Background()
+	commandSpan trace.Span
+	// logPrefixes are the prefixes of the loggers before the trace ID was added to them
+	logPrefixes map[prefixedLogger]string
+)
+
+// Init exports the spans to the OTLP/HTTP endpoint, e.g. http://localhost:4318.
+// Without Init, the spans are not recorded.
+func Init(endpoint string) error {
+	options, err := exporterOptions(endpoint)
+	if err != nil {
+		return err
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the OTLP trace exporter")
+	}
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	return nil
+}
+
+// exporterOptions accepts either the URL of the collector or its host:port, which is connected with TLS
+func exporterOptions(endpoint string) ([]otlptracehttp.Option, error) {
+	if !strings.Contains(endpoint, "://") {
+		return []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}, nil
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the OTLP endpoint %s", endpoint)
+	}
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpointURL.Host)}
+	switch endpointURL.Scheme {
+	case "http":
+		options = append(options, otlptracehttp.WithInsecure())
+	case "https":
+	default:
+		return nil, errors.Errorf("unsupported scheme of the OTLP endpoint %s, expected http or https", endpoint)
+	}
+	if endpointURL.Path != "" && endpointURL.Path != "/" {
+		options = append(options, otlptracehttp.WithURLPath(endpointURL.Path))
+	}
+	return options, nil
+}
+
+// Enabled reports whether the spans are exported
+func Enabled() bool {
+	return tracerProvider != nil
+}
+
+// StartCommand starts the root span of the command and logs its trace ID, the lines logged
+// later are prefixed with it for the correlation with the logs
+func StartCommand(name string) {
+	commandCtx, commandSpan = otel.Tracer(tracerName).Start(context.Background(), name)
+	if Enabled() {
+		tracelog.InfoLogger.Printf("Trace ID: %s\n", commandSpan.SpanContext().TraceID())
+		addTraceIDToLogs(TraceID())
+	}
+}
+
+// TraceID returns the trace ID of the running command, it is empty if the spans are not exported
+func TraceID() string {
+	if !Enabled() || commandSpan == nil {
+		return ""
+	}
+	return commandSpan.SpanContext().TraceID().String()
+}
+
+func addTraceIDToLogs(traceID string) {
+	logPrefixes = make(map[prefixedLogger]string)
+	for _, logger := range []prefixedLogger{tracelog.InfoLogger, tracelog.WarningLogger,
+		tracelog.ErrorLogger, tracelog.DebugLogger} {
+		logPrefixes[logger] = logger.Prefix()
+		logger.SetPrefix(logger.Prefix() + "trace_id=" + traceID + " ")
+	}
+}
+
+func removeTraceIDFromLogs() {
+	for logger, prefix := range logPrefixes {
+		logger.SetPrefix(prefix)
+	}
+	logPrefixes = nil
+}
+
+// FinishCommand ends the span of the command and flushes the spans to the endpoint within flushTimeout.
+// The spans started later are not exported.
+func FinishCommand() {
+	removeTraceIDFromLogs()
+	if commandSpan != nil {
+		commandSpan.End()
+		commandSpan = nil
+	}
+	if !Enabled() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		tracelog.WarningLogger.Printf("Failed to export the trace spans: %v\n", err)
+	}
+	tracerProvider = nil
+}
+
+// Start starts a span within the span of the running command
+func Start(name string, attributes ...attribute.KeyValue) trace.Span {
+	_, span := otel.Tracer(tracerName).Start(commandCtx, name, trace.WithAttributes(attributes...))
+	return span
+}
+
+// End records the error, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/tracelog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExporterOptions(t *testing.T) {
+	options, err := exporterOptions("collector:4318")
+	require.NoError(t, err)
+	assert.Len(t, options, 1)
+
+	options, err = exporterOptions("http://collector:4318/traces")
+	require.NoError(t, err)
+	assert.Len(t, options, 3)
+
+	_, err = exporterOptions("grpc://collector:4317")
+	assert.Error(t, err)
+}
+
+func TestStart_NotRecordedWithoutInit(t *testing.T) {
+	StartCommand("backup-push")
+	span := Start("upload")
+	assert.False(t, span.IsRecording())
+	End(span, errors.New("failed"))
+	FinishCommand()
+}
+
+func TestFinishCommand_ExportsSpans(t *testing.T) {
+	requests := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests <- request.URL.Path
+	}))
+	defer server.Close()
+	defer func() {
+		tracerProvider = nil
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	}()
+
+	require.NoError(t, Init(server.URL))
+	StartCommand("backup-push")
+	assert.Equal(t, commandSpan.SpanContext().TraceID().String(), TraceID())
+	assert.Equal(t, "INFO: trace_id="+TraceID()+" ", tracelog.InfoLogger.Prefix())
+	span := Start("upload", PathAttribute.String("base_000000010000000000000002/tar_partitions/part_1.tar.lz4"))
+	assert.True(t, span.IsRecording())
+	assert.Equal(t, commandSpan.SpanContext().TraceID(), span.SpanContext().TraceID())
+	End(span, nil)
+	FinishCommand()
+	assert.Equal(t, "/v1/traces", <-requests)
+	assert.Empty(t, TraceID())
+	assert.Equal(t, "INFO: ", tracelog.InfoLogger.Prefix())
+}
+
+func TestFinishCommand_BoundedByFlushTimeout(t *testing.T) {
+	// the collector doesn't respond until the test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	defer func(timeout time.Duration) {
+		flushTimeout = timeout
+		tracerProvider = nil
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	}(flushTimeout)
+	flushTimeout = 100 * time.Millisecond
+
+	require.NoError(t, Init(server.URL))
+	StartCommand("backup-push")
+	End(Start("upload"), nil)
+	started := time.Now()
+	FinishCommand()
+	assert.Less(t, int64(time.Since(started)), int64(flushTimeout+time.Second))
+}
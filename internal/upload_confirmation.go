@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// the uploaded object is looked up this many times after the upload reported success
+const maxUploadConfirmationAttempts = 3
+
+var MinUploadConfirmationWait = time.Second
+var MaxUploadConfirmationWait = 10 * time.Second
+
+type UploadNotConfirmedError struct {
+	error
+}
+
+func newUploadNotConfirmedError(path string, attempts int) UploadNotConfirmedError {
+	return UploadNotConfirmedError{errors.Errorf(
+		"the upload of %s succeeded, but the object is not found in the storage after %d attempts",
+		path, attempts)}
+}
+
+func (err UploadNotConfirmedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// uploadConfirmed uploads the content and checks that the object exists in the storage afterwards.
+// If the object is not found, the seekable content (e.g. the sentinel) is uploaded again,
+// and the upload of the other content (e.g. the streamed tar partitions) fails.
+func (uploader *Uploader) uploadConfirmed(path string, content io.Reader) error {
+	seeker, isSeekable := content.(io.Seeker)
+	var start int64
+	if isSeekable {
+		var seekErr error
+		if start, seekErr = seeker.Seek(0, io.SeekCurrent); seekErr != nil {
+			isSeekable = false
+		}
+	}
+
+	sleeper := NewExponentialSleeper(MinUploadConfirmationWait, MaxUploadConfirmationWait)
+	err := uploader.putObject(path, content)
+	for attempt := 1; ; attempt++ {
+		if err != nil {
+			return err
+		}
+		exists, existsErr := uploader.UploadingFolder.Exists(path)
+		if existsErr != nil {
+			return errors.Wrapf(existsErr, "failed to confirm the upload of %s", path)
+		}
+		if exists {
+			return nil
+		}
+		if !isSeekable || attempt == maxUploadConfirmationAttempts {
+			return newUploadNotConfirmedError(path, attempt)
+		}
+		tracelog.WarningLogger.Printf("Uploaded object %s is not found in the storage, uploading it again\n", path)
+		sleeper.Sleep()
+		if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+			return errors.Wrapf(err, "failed to rewind %s to upload it again", path)
+		}
+		// the size of the content is already counted
+		err = uploader.UploadingFolder.PutObject(path, content)
+	}
+}
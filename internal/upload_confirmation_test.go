@@ -0,0 +1,70 @@
+package internal_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+)
+
+// droppingFolder loses the first droppedPuts uploaded objects, like an eventually consistent storage
+type droppingFolder struct {
+	storage.Folder
+	droppedPuts int
+}
+
+func (folder *droppingFolder) PutObject(name string, content io.Reader) error {
+	if folder.droppedPuts > 0 {
+		folder.droppedPuts--
+		_, err := io.Copy(ioutil.Discard, content)
+		return err
+	}
+	return folder.Folder.PutObject(name, content)
+}
+
+func newConfirmingUploader(droppedPuts int) (*internal.Uploader, storage.Folder) {
+	viper.Set(internal.ConfirmUploadsSetting, true)
+	defer viper.Set(internal.ConfirmUploadsSetting, nil)
+	internal.MinUploadConfirmationWait = time.Millisecond
+	internal.MaxUploadConfirmationWait = time.Millisecond
+	folder := &droppingFolder{memory.NewFolder("in_memory/", memory.NewStorage()), droppedPuts}
+	return internal.NewUploader(nil, folder), folder
+}
+
+func TestUploadConfirmed_UploadsSeekableContentAgain(t *testing.T) {
+	uploader, folder := newConfirmingUploader(2)
+	require.NoError(t, uploader.Upload("sentinel.json", bytes.NewReader([]byte("sentinel"))))
+
+	reader, err := folder.ReadObject("sentinel.json")
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "sentinel", string(content))
+	assert.False(t, uploader.Failed.Load().(bool))
+}
+
+func TestUploadConfirmed_FailsAfterAllAttempts(t *testing.T) {
+	uploader, _ := newConfirmingUploader(3)
+	err := uploader.Upload("sentinel.json", bytes.NewReader([]byte("sentinel")))
+	assert.IsType(t, internal.UploadNotConfirmedError{}, err)
+	assert.True(t, uploader.Failed.Load().(bool))
+}
+
+func TestUploadConfirmed_FailsForStreamedContent(t *testing.T) {
+	uploader, _ := newConfirmingUploader(1)
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		_, _ = pipeWriter.Write([]byte("partition"))
+		_ = pipeWriter.Close()
+	}()
+	err := uploader.Upload("part_1.tar.lz4", pipeReader)
+	assert.IsType(t, internal.UploadNotConfirmedError{}, err)
+}
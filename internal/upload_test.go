@@ -34,6 +34,26 @@ func TestConfigureDeepBucket(t *testing.T) {
 	doConfigureWithBucketPath(t, bucketPath, "subdir/server")
 }
 
+func TestConfigurePrefixQuery(t *testing.T) {
+	viper.Reset()
+	internal.ConfigureSettings("")
+	internal.InitConfig()
+	internal.Configure()
+	viper.Set("AWS_ACCESS_KEY_ID", "aws_access_key_id")
+	viper.Set("AWS_SECRET_ACCESS_KEY", "aws_secret_access_key")
+	viper.Set("AWS_ENDPOINT", "http://127.0.0.1:9000")
+	viper.Set("AWS_REGION", "")
+
+	viper.Set("WALE_S3_PREFIX", "s3://abc.com/server?region=us-east-1")
+	uploader, err := internal.ConfigureUploader()
+	assert.NoError(t, err)
+	assert.Equal(t, "server", strings.TrimSuffix(uploader.UploadingFolder.GetPath(), "/"))
+
+	viper.Set("WALE_S3_PREFIX", "s3://abc.com/server?unknown=1")
+	_, err = internal.ConfigureUploader()
+	assert.IsType(t, internal.InvalidStoragePrefixError{}, errors.Cause(err))
+}
+
 func doConfigureWithBucketPath(t *testing.T, bucketPath string, expectedServer string) {
 	// Test empty environment variables
 	viper.Reset()
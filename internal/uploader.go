@@ -1,28 +1,36 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/wal-g/storages/storage"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/asm"
 	"github.com/wal-g/wal-g/internal/compression"
 	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/tracing"
 	"github.com/wal-g/wal-g/utility"
 )
 
 var ErrorSizeTrackingDisabled = fmt.Errorf("size tracking disabled by DisableSizeTracking method")
 
+// cancelledUploadsTimeout bounds the wait of Cancel for the uploads in flight to fail
+const cancelledUploadsTimeout = 30 * time.Second
+
 type UploaderProvider interface {
 	Upload(path string, content io.Reader) error
 	UploadFile(file ioextensions.NamedReader) error
 	PushStream(stream io.Reader) (string, error)
 	PushStreamToDestination(stream io.Reader, dstPath string) error
 	Compression() compression.Compressor
+	StreamCompression() compression.Compressor
 	DisableSizeTracking()
 	UploadedDataSize() (int64, error)
 	RawDataSize() (int64, error)
@@ -31,14 +39,23 @@ type UploaderProvider interface {
 // Uploader contains fields associated with uploading tarballs.
 // Multiple tarballs can share one uploader.
 type Uploader struct {
-	UploadingFolder        storage.Folder
-	Compressor             compression.Compressor
+	UploadingFolder storage.Folder
+	Compressor      compression.Compressor
+	// StreamCompressor compresses the streams pushed by PushStream and PushStreamToDestination,
+	// Compressor is used if it is nil
+	StreamCompressor       compression.Compressor
 	waitGroup              *sync.WaitGroup
 	ArchiveStatusManager   asm.ArchiveStatusManager
 	PGArchiveStatusManager asm.ArchiveStatusManager
 	Failed                 atomic.Value
 	tarSize                *int64
 	dataSize               *int64
+	// confirmUploads makes Upload check that the uploaded objects exist, see WALG_CONFIRM_UPLOADS
+	confirmUploads bool
+	// ctx is cancelled by Cancel, it is shared by the clones with the uploads in flight
+	ctx           context.Context
+	cancel        context.CancelFunc
+	activeUploads *sync.WaitGroup
 }
 
 // UploadObject
@@ -51,12 +68,17 @@ func NewUploader(
 	compressor compression.Compressor,
 	uploadingLocation storage.Folder,
 ) *Uploader {
+	ctx, cancel := context.WithCancel(context.Background())
 	uploader := &Uploader{
 		UploadingFolder: uploadingLocation,
 		Compressor:      compressor,
 		waitGroup:       &sync.WaitGroup{},
 		tarSize:         new(int64),
 		dataSize:        new(int64),
+		confirmUploads:  viper.GetBool(ConfirmUploadsSetting),
+		ctx:             ctx,
+		cancel:          cancel,
+		activeUploads:   &sync.WaitGroup{},
 	}
 	uploader.Failed.Store(false)
 	return uploader
@@ -92,14 +114,40 @@ func (uploader *Uploader) Clone() *Uploader {
 	return &Uploader{
 		UploadingFolder:      uploader.UploadingFolder,
 		Compressor:           uploader.Compressor,
+		StreamCompressor:     uploader.StreamCompressor,
 		waitGroup:            &sync.WaitGroup{},
 		ArchiveStatusManager: uploader.ArchiveStatusManager,
 		Failed:               uploader.Failed,
 		tarSize:              uploader.tarSize,
 		dataSize:             uploader.dataSize,
+		confirmUploads:       uploader.confirmUploads,
+		ctx:                  uploader.ctx,
+		cancel:               uploader.cancel,
+		activeUploads:        uploader.activeUploads,
+	}
+}
+
+// Cancel fails the uploads in flight of the uploader and its clones and waits for them to return,
+// so the storage aborts their multipart uploads. The uploads started after it fail at once.
+func (uploader *Uploader) Cancel() {
+	uploader.cancel()
+	uploadsDone := make(chan struct{})
+	go func() {
+		uploader.activeUploads.Wait()
+		close(uploadsDone)
+	}()
+	select {
+	case <-uploadsDone:
+	case <-time.After(cancelledUploadsTimeout):
+		tracelog.WarningLogger.Printf("The cancelled uploads did not finish in %v\n", cancelledUploadsTimeout)
 	}
 }
 
+// Cancelled reports whether Cancel was called
+func (uploader *Uploader) Cancelled() bool {
+	return uploader.ctx.Err() != nil
+}
+
 // TODO : unit tests
 // UploadFile compresses a file and uploads it.
 func (uploader *Uploader) UploadFile(file ioextensions.NamedReader) error {
@@ -128,12 +176,22 @@ func (uploader *Uploader) Compression() compression.Compressor {
 	return uploader.Compressor
 }
 
+// StreamCompression returns the compressor of the pushed streams
+func (uploader *Uploader) StreamCompression() compression.Compressor {
+	if uploader.StreamCompressor != nil {
+		return uploader.StreamCompressor
+	}
+	return uploader.Compressor
+}
+
 // TODO : unit tests
 func (uploader *Uploader) Upload(path string, content io.Reader) error {
-	if uploader.tarSize != nil {
-		content = NewWithSizeReader(content, uploader.tarSize)
+	var err error
+	if uploader.confirmUploads {
+		err = uploader.uploadConfirmed(path, content)
+	} else {
+		err = uploader.putObject(path, content)
 	}
-	err := uploader.UploadingFolder.PutObject(path, content)
 	if err == nil {
 		return nil
 	}
@@ -142,6 +200,47 @@ func (uploader *Uploader) Upload(path string, content io.Reader) error {
 	return err
 }
 
+func (uploader *Uploader) putObject(path string, content io.Reader) (err error) {
+	if err = uploader.ctx.Err(); err != nil {
+		return err
+	}
+	uploader.activeUploads.Add(1)
+	defer uploader.activeUploads.Done()
+	span := tracing.Start("upload", tracing.PathAttribute.String(path))
+	uploadedSize := new(int64)
+	defer func() {
+		span.SetAttributes(tracing.SizeAttribute.Int64(*uploadedSize))
+		tracing.End(span, err)
+	}()
+	if sizedContent, ok := content.(interface{ Len() int }); ok {
+		*uploadedSize = int64(sizedContent.Len())
+	} else if _, isSeeker := content.(io.Seeker); !isSeeker && tracing.Enabled() {
+		// the streamed content is counted, the storages may need the other interfaces of the seekable one
+		content = NewWithSizeReader(content, uploadedSize)
+	}
+	if uploader.tarSize != nil {
+		content = NewWithSizeReader(content, uploader.tarSize)
+	}
+	if _, isSeeker := content.(io.Seeker); !isSeeker {
+		// the streams are the long uploads to fail on Cancel, the seekable contents keep their interfaces
+		content = &cancellableReader{uploader.ctx, content}
+	}
+	return uploader.UploadingFolder.PutObject(path, content)
+}
+
+// cancellableReader fails the reads once ctx is cancelled
+type cancellableReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (reader *cancellableReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.reader.Read(p)
+}
+
 // UploadMultiple uploads multiple objects from the start of the slice,
 // returning the first error if any. Note that this operation is not atomic
 // TODO : unit tests
@@ -0,0 +1,52 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestUploader_CancelFailsUploadsInFlight(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	uploader := internal.NewUploader(compression.Compressors[lz4.AlgorithmName], folder)
+	clone := uploader.Clone()
+
+	stream := &endlessReader{firstRead: make(chan struct{})}
+	uploadErr := make(chan error, 1)
+	go func() {
+		uploadErr <- clone.Upload("part_1.tar.lz4", stream)
+	}()
+	<-stream.firstRead
+
+	uploader.Cancel()
+	assert.True(t, errors.Is(<-uploadErr, context.Canceled))
+	exists, err := folder.Exists("part_1.tar.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = uploader.Upload("part_2.tar.lz4", strings.NewReader("data"))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+// endlessReader streams the zeroes until the upload stops reading
+type endlessReader struct {
+	firstRead chan struct{}
+	once      sync.Once
+}
+
+func (reader *endlessReader) Read(p []byte) (int, error) {
+	reader.once.Do(func() { close(reader.firstRead) })
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
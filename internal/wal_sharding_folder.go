@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// WalShardingByHash spreads the WAL objects over 256 shards by the hash of the segment name
+	WalShardingByHash = "hash"
+	// WalShardingBySegment puts the WAL objects of the same log (4GB of WAL) to the same shard
+	WalShardingBySegment = "segment"
+
+	walShardPrefix     = "shard_"
+	walShardCount      = 256
+	walSegmentNameSize = 24
+)
+
+type UnknownWalShardingError struct {
+	error
+}
+
+func newUnknownWalShardingError(sharding string) UnknownWalShardingError {
+	return UnknownWalShardingError{errors.Errorf("unknown %s value '%s', expected %s or %s",
+		WalShardPrefixSetting, sharding, WalShardingByHash, WalShardingBySegment)}
+}
+
+func (err UnknownWalShardingError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// getWalSharding reads WALG_WAL_SHARD_PREFIX, empty value means the WAL objects are not sharded
+func getWalSharding(config *viper.Viper) (string, error) {
+	sharding := config.GetString(WalShardPrefixSetting)
+	switch sharding {
+	case "", WalShardingByHash, WalShardingBySegment:
+		return sharding, nil
+	default:
+		return "", newUnknownWalShardingError(sharding)
+	}
+}
+
+// getWalShardName returns the shard of the object in the WAL folder, it depends only on the object name.
+// The objects of the same segment (e.g. the WAL file and its metadata) are in the same shard.
+// Empty name means the object is stored in the WAL folder itself.
+func getWalShardName(sharding, objectName string) string {
+	key := objectName
+	if len(key) >= walSegmentNameSize && isHex(key[:walSegmentNameSize]) {
+		key = key[:walSegmentNameSize]
+	} else if dot := strings.IndexByte(key, '.'); dot >= 0 {
+		key = key[:dot]
+	}
+	switch sharding {
+	case WalShardingByHash:
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(key))
+		return fmt.Sprintf("%s%02x", walShardPrefix, hash.Sum32()%walShardCount)
+	case WalShardingBySegment:
+		// the log id part of the segment name, i.e. the high bytes of its position
+		if len(key) >= 16 && isHex(key[:16]) {
+			return walShardPrefix + key[8:16]
+		}
+	}
+	return ""
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isWalFolder(folder storage.Folder) bool {
+	return path.Base(folder.GetPath()) == strings.TrimSuffix(utility.WalPath, "/")
+}
+
+// WalShardingFolder stores the objects of the WAL folders under the shard subfolders
+// derived from the object names, so the WAL objects are found without listing all of them.
+// The shards are hidden: the WAL folder lists the objects of all its shards as its own.
+// The objects stored before the sharding was enabled are still read from the WAL folder itself.
+type WalShardingFolder struct {
+	folder      storage.Folder
+	sharding    string
+	isWalFolder bool
+}
+
+func NewWalShardingFolder(folder storage.Folder, sharding string) *WalShardingFolder {
+	return &WalShardingFolder{folder: folder, sharding: sharding, isWalFolder: isWalFolder(folder)}
+}
+
+// getShardedPath returns the path of the object relative to the folder with the shard included
+func (folder *WalShardingFolder) getShardedPath(objectRelativePath string) string {
+	dir, name := path.Split(objectRelativePath)
+	if !(dir == "" && folder.isWalFolder) && path.Base(dir) != strings.TrimSuffix(utility.WalPath, "/") {
+		return objectRelativePath
+	}
+	shard := getWalShardName(folder.sharding, name)
+	if shard == "" {
+		return objectRelativePath
+	}
+	return dir + shard + "/" + name
+}
+
+func (folder *WalShardingFolder) GetPath() string {
+	return folder.folder.GetPath()
+}
+
+func (folder *WalShardingFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	objects, innerSubFolders, err := folder.folder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	subFolders = make([]storage.Folder, 0, len(innerSubFolders))
+	for _, subFolder := range innerSubFolders {
+		if !folder.isWalFolder || !strings.HasPrefix(path.Base(subFolder.GetPath()), walShardPrefix) {
+			subFolders = append(subFolders, NewWalShardingFolder(subFolder, folder.sharding))
+			continue
+		}
+		shardObjects, _, err := subFolder.ListFolder()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to list WAL shard '%s'", subFolder.GetPath())
+		}
+		objects = append(objects, shardObjects...)
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *WalShardingFolder) DeleteObjects(objectRelativePaths []string) error {
+	paths := make([]string, 0, 2*len(objectRelativePaths))
+	for _, objectPath := range objectRelativePaths {
+		paths = append(paths, objectPath)
+		if shardedPath := folder.getShardedPath(objectPath); shardedPath != objectPath {
+			paths = append(paths, shardedPath)
+		}
+	}
+	return folder.folder.DeleteObjects(paths)
+}
+
+func (folder *WalShardingFolder) Exists(objectRelativePath string) (bool, error) {
+	shardedPath := folder.getShardedPath(objectRelativePath)
+	exists, err := folder.folder.Exists(shardedPath)
+	if err != nil || exists || shardedPath == objectRelativePath {
+		return exists, err
+	}
+	return folder.folder.Exists(objectRelativePath)
+}
+
+func (folder *WalShardingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewWalShardingFolder(folder.folder.GetSubFolder(subFolderRelativePath), folder.sharding)
+}
+
+func (folder *WalShardingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	shardedPath := folder.getShardedPath(objectRelativePath)
+	reader, err := folder.folder.ReadObject(shardedPath)
+	if _, notFound := errors.Cause(err).(storage.ObjectNotFoundError); notFound && shardedPath != objectRelativePath {
+		// the object was stored before the sharding was enabled
+		return folder.folder.ReadObject(objectRelativePath)
+	}
+	return reader, err
+}
+
+func (folder *WalShardingFolder) ReadObjectFrom(objectRelativePath string, offset int64,
+	eTag *string) (io.ReadCloser, error) {
+	shardedPath := folder.getShardedPath(objectRelativePath)
+	reader, err := ReadObjectFrom(folder.folder, shardedPath, offset, eTag)
+	if _, notFound := errors.Cause(err).(storage.ObjectNotFoundError); notFound && shardedPath != objectRelativePath {
+		return ReadObjectFrom(folder.folder, objectRelativePath, offset, eTag)
+	}
+	return reader, err
+}
+
+func (folder *WalShardingFolder) PutObject(name string, content io.Reader) error {
+	return folder.folder.PutObject(folder.getShardedPath(name), content)
+}
@@ -0,0 +1,93 @@
+package internal_test
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func listNames(t *testing.T, folder storage.Folder) []string {
+	objects, err := storage.ListFolderRecursively(folder)
+	require.NoError(t, err)
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWalShardingFolder_BySegment(t *testing.T) {
+	base := memory.NewFolder("in_memory/", memory.NewStorage())
+	// stored before the sharding was enabled
+	require.NoError(t, base.PutObject(utility.WalPath+"000000010000000200000001.lz4", strings.NewReader("old")))
+	folder := internal.NewWalShardingFolder(base, internal.WalShardingBySegment)
+	walFolder := folder.GetSubFolder(utility.WalPath)
+
+	require.NoError(t, walFolder.PutObject("000000010000000300000001.lz4", strings.NewReader("new")))
+	require.NoError(t, walFolder.PutObject("00000002.history.lz4", strings.NewReader("history")))
+	require.NoError(t, folder.PutObject("basebackups_005/base_000000010000000300000001_backup_stop_sentinel.json",
+		strings.NewReader("{}")))
+
+	assert.Equal(t, []string{
+		"basebackups_005/base_000000010000000300000001_backup_stop_sentinel.json",
+		utility.WalPath + "000000010000000200000001.lz4",
+		utility.WalPath + "00000002.history.lz4",
+		utility.WalPath + "shard_00000003/000000010000000300000001.lz4",
+	}, listNames(t, base))
+
+	assert.Equal(t, []string{
+		"basebackups_005/base_000000010000000300000001_backup_stop_sentinel.json",
+		utility.WalPath + "000000010000000200000001.lz4",
+		utility.WalPath + "000000010000000300000001.lz4",
+		utility.WalPath + "00000002.history.lz4",
+	}, listNames(t, folder))
+
+	for name, expected := range map[string]string{
+		"000000010000000300000001.lz4": "new",
+		"000000010000000200000001.lz4": "old",
+	} {
+		exists, err := walFolder.Exists(name)
+		require.NoError(t, err)
+		assert.True(t, exists)
+		reader, err := walFolder.ReadObject(name)
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	_, err := walFolder.ReadObject("000000010000000300000002.lz4")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+
+	require.NoError(t, folder.DeleteObjects([]string{
+		utility.WalPath + "000000010000000300000001.lz4",
+		utility.WalPath + "000000010000000200000001.lz4",
+	}))
+	assert.Equal(t, []string{
+		"basebackups_005/base_000000010000000300000001_backup_stop_sentinel.json",
+		utility.WalPath + "00000002.history.lz4",
+	}, listNames(t, base))
+}
+
+func TestWalShardingFolder_ByHash(t *testing.T) {
+	base := memory.NewFolder("in_memory/", memory.NewStorage())
+	walFolder := internal.NewWalShardingFolder(base, internal.WalShardingByHash).GetSubFolder(utility.WalPath)
+
+	require.NoError(t, walFolder.PutObject("000000010000000300000001.lz4", strings.NewReader("wal")))
+	require.NoError(t, walFolder.PutObject("000000010000000300000001.json", strings.NewReader("{}")))
+
+	names := listNames(t, base.GetSubFolder(utility.WalPath))
+	require.Len(t, names, 2)
+	// the WAL file and its metadata are in the same shard
+	shard := strings.SplitN(names[0], "/", 2)[0]
+	assert.True(t, strings.HasPrefix(shard, "shard_"))
+	assert.Equal(t, []string{shard + "/000000010000000300000001.json", shard + "/000000010000000300000001.lz4"}, names)
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ZstdDictionariesPath is the storage folder of the zstd dictionaries, every dictionary
+// used for compression is stored there by its ID to decompress the data without the local file
+const ZstdDictionariesPath = "zstd_dictionaries/"
+
+// storedZstdDictionaries holds the paths of the dictionaries known to be in the storage,
+// so the dictionary is checked once per process however many uploaders are configured
+var storedZstdDictionaries sync.Map
+
+type InvalidZstdDictionaryError struct {
+	error
+}
+
+func newInvalidZstdDictionaryError(dictPath string) InvalidZstdDictionaryError {
+	return InvalidZstdDictionaryError{errors.Errorf(
+		"%s '%s' is not a trained zstd dictionary, make one with train-dict", ZstdDictPathSetting, dictPath)}
+}
+
+func (err InvalidZstdDictionaryError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ReadZstdDictionary reads the trained zstd dictionary, the raw content ones are rejected
+// since their ID is not recorded in the compressed data
+func ReadZstdDictionary(dictPath string) ([]byte, error) {
+	dict, err := ioutil.ReadFile(dictPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", ZstdDictPathSetting)
+	}
+	if compression.DictionaryID(dict) == 0 {
+		return nil, newInvalidZstdDictionaryError(dictPath)
+	}
+	return dict, nil
+}
+
+func getZstdDictionaryObjectName(dictID uint32) string {
+	return fmt.Sprintf("%d.dict", dictID)
+}
+
+// UploadZstdDictionary stores the WALG_ZSTD_DICT_PATH dictionary in the storage unless it is there already.
+// The dictionary is encrypted as the rest of the data, since it is made from the database files.
+func UploadZstdDictionary(folder storage.Folder) error {
+	dictPath := viper.GetString(ZstdDictPathSetting)
+	if dictPath == "" {
+		return nil
+	}
+	dict, err := ReadZstdDictionary(dictPath)
+	if err != nil {
+		return err
+	}
+	dictFolder := folder.GetSubFolder(ZstdDictionariesPath)
+	objectName := getZstdDictionaryObjectName(compression.DictionaryID(dict))
+	objectPath := dictFolder.GetPath() + objectName
+	if _, stored := storedZstdDictionaries.Load(objectPath); stored {
+		return nil
+	}
+	exists, err := dictFolder.Exists(objectName)
+	if err != nil {
+		return errors.Wrap(err, "failed to check the stored zstd dictionary")
+	}
+	if !exists {
+		tracelog.InfoLogger.Printf("Uploading zstd dictionary %s\n", objectName)
+		err = dictFolder.PutObject(objectName, CompressAndEncrypt(bytes.NewReader(dict), nil, ConfigureCrypter()))
+		if err != nil {
+			return errors.Wrap(err, "failed to upload the zstd dictionary")
+		}
+	}
+	storedZstdDictionaries.Store(objectPath, true)
+	return nil
+}
+
+func readStoredZstdDictionary(folder storage.Folder, dictID uint32) ([]byte, error) {
+	objectName := getZstdDictionaryObjectName(dictID)
+	object, err := folder.GetSubFolder(ZstdDictionariesPath).ReadObject(objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(object, "")
+	var reader io.Reader = object
+	if crypter := ConfigureCrypter(); crypter != nil {
+		reader, err = crypter.Decrypt(object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decrypt zstd dictionary %s", objectName)
+		}
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// configureZstdDictionaryLoader makes the zstd decompression find the dictionaries by their ID
+// in the WALG_ZSTD_DICT_PATH file or in the storage
+func configureZstdDictionaryLoader(folder storage.Folder) {
+	var cache sync.Map
+	compression.SetDictionaryLoader(func(dictID uint32) ([]byte, error) {
+		if dict, ok := cache.Load(dictID); ok {
+			return dict.([]byte), nil
+		}
+		var dict []byte
+		if dictPath := viper.GetString(ZstdDictPathSetting); dictPath != "" {
+			localDict, err := ReadZstdDictionary(dictPath)
+			if err == nil && compression.DictionaryID(localDict) == dictID {
+				dict = localDict
+			}
+		}
+		if dict == nil {
+			storedDict, err := readStoredZstdDictionary(folder, dictID)
+			if err != nil {
+				return nil, err
+			}
+			dict = storedDict
+		}
+		cache.Store(dictID, dict)
+		return dict, nil
+	})
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/storages/memory"
+	"github.com/wal-g/storages/storage"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/zstd"
+)
+
+func writeTestZstdDictionary(t *testing.T, dir string) (dict []byte, dictPath string) {
+	samples := make([][]byte, 0)
+	for i := 0; i < 1000; i++ {
+		samples = append(samples, []byte(fmt.Sprintf("relation %d of schema public, tuples %d", i, i*7)))
+	}
+	dict, err := compression.TrainDictionary(samples, 4<<10)
+	require.NoError(t, err)
+	dictPath = filepath.Join(dir, "dict")
+	require.NoError(t, ioutil.WriteFile(dictPath, dict, 0600))
+	return dict, dictPath
+}
+
+// existsCountingFolder counts the existence checks of the objects
+type existsCountingFolder struct {
+	storage.Folder
+	checks *int
+}
+
+func (folder existsCountingFolder) Exists(objectRelativePath string) (bool, error) {
+	*folder.checks++
+	return folder.Folder.Exists(objectRelativePath)
+}
+
+func (folder existsCountingFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return existsCountingFolder{folder.Folder.GetSubFolder(subFolderRelativePath), folder.checks}
+}
+
+func TestUploadZstdDictionary_CheckedOncePerProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zstd_dictionary")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	_, dictPath := writeTestZstdDictionary(t, dir)
+
+	viper.Set(ZstdDictPathSetting, dictPath)
+	defer viper.Set(ZstdDictPathSetting, "")
+	checks := 0
+	folder := existsCountingFolder{memory.NewFolder("checked_once/", memory.NewStorage()), &checks}
+	require.NoError(t, UploadZstdDictionary(folder))
+	require.NoError(t, UploadZstdDictionary(folder))
+	assert.Equal(t, 1, checks)
+}
+
+func TestZstdDictionary_StoredForDecompression(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zstd_dictionary")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dict, dictPath := writeTestZstdDictionary(t, dir)
+
+	viper.Set(ZstdDictPathSetting, dictPath)
+	defer viper.Set(ZstdDictPathSetting, "")
+	folder := memory.NewFolder("in_memory/", memory.NewStorage())
+	configureZstdDictionaryLoader(folder)
+	defer compression.SetDictionaryLoader(nil)
+	require.NoError(t, UploadZstdDictionary(folder))
+	exists, err := folder.GetSubFolder(ZstdDictionariesPath).Exists(
+		fmt.Sprintf("%d.dict", compression.DictionaryID(dict)))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	viper.Set(BackupCompressionSetting, zstd.AlgorithmName)
+	defer viper.Set(BackupCompressionSetting, "")
+	compressor, err := ConfigurePostgresCompressorWithSetting(BackupCompressionSetting)
+	require.NoError(t, err)
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err = writer.Write([]byte("relation 5000 of schema public, tuples 35000"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	// the dictionary is found in the storage without the local file
+	viper.Set(ZstdDictPathSetting, "")
+	var decompressed bytes.Buffer
+	require.NoError(t, zstd.Decompressor{}.Decompress(&decompressed, &compressed))
+	assert.Equal(t, "relation 5000 of schema public, tuples 35000", decompressed.String())
+}
+
+func TestReadZstdDictionary_RawContent(t *testing.T) {
+	file, err := ioutil.TempFile("", "zstd_dictionary")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("raw content")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = ReadZstdDictionary(file.Name())
+	assert.IsType(t, InvalidZstdDictionaryError{}, err)
+}
@@ -6,6 +6,8 @@ import (
 	"math/rand"
 	"strconv"
 	"sync/atomic"
+
+	"github.com/wal-g/wal-g/internal"
 )
 
 var counter int32
@@ -60,9 +62,10 @@ func CreateNamedTar(w io.Writer, r *io.LimitedReader, name string) {
 	tw := tar.NewWriter(w)
 
 	hdr := &tar.Header{
-		Name: name,
-		Size: r.N,
-		Mode: 0600,
+		Name:   name,
+		Size:   r.N,
+		Mode:   0600,
+		Format: internal.TarHeaderFormat,
 	}
 
 	if err := tw.WriteHeader(hdr); err != nil {
@@ -206,6 +206,9 @@ func StripLeftmostBackupName(path string) string {
 }
 
 func stripBackupSuffix(pathValue string) string {
+	if strings.HasSuffix(pathValue, sentinelSuffix) {
+		return strings.TrimSuffix(pathValue, sentinelSuffix)
+	}
 	return strings.Split(pathValue, "_backup")[0]
 }
 
@@ -219,12 +222,80 @@ func StripPrefixName(path string) string {
 // TODO : unit tests
 var patternLSN = "[0-9A-F]{24}"
 var regexpLSN = regexp.MustCompile(patternLSN)
+var regexpLSNIgnoreCase = regexp.MustCompile("(?i)" + patternLSN)
+
+var objectKeyNormalization = false
+
+// SetObjectKeyNormalization enables the case-insensitive parsing of the WAL file names
+// found in the object keys, e.g. in a bucket migrated from WAL-E.
+// The parsed names are converted to the upper case used by WAL-G.
+func SetObjectKeyNormalization(enabled bool) {
+	objectKeyNormalization = enabled
+}
+
+func IsObjectKeyNormalizationEnabled() bool {
+	return objectKeyNormalization
+}
+
+// PatternBackupNamePrefix matches any backup name prefix, so the backups made with the other prefixes,
+// e.g. the default base_ one before WALG_BACKUP_NAME_PREFIX is set, are still recognized
+const PatternBackupNamePrefix = "[A-Za-z0-9]+_"
+
+var regexpBackupNamePrefix = regexp.MustCompile("^" + PatternBackupNamePrefix + "$")
+
+var (
+	backupNamePrefix = BackupNamePrefix
+	sentinelSuffix   = SentinelSuffix
+)
+
+// SetSentinelSuffix overrides the suffix of backup finish sentinel files,
+// e.g. to share the bucket with the tooling expecting a different marker.
+// The suffix must end in ".json" since sentinels are stored as json documents.
+func SetSentinelSuffix(suffix string) error {
+	if suffix == "" || suffix == ".json" || !strings.HasSuffix(suffix, ".json") {
+		return errors.Errorf("invalid sentinel suffix '%s': it must be non-empty and end in '.json'", suffix)
+	}
+	sentinelSuffix = suffix
+	return nil
+}
+
+// GetSentinelSuffix returns the suffix of backup finish sentinel files
+func GetSentinelSuffix() string {
+	return sentinelSuffix
+}
+
+// SetBackupNamePrefix overrides the prefix of the base backup names.
+// The prefix must match PatternBackupNamePrefix to be found in the object keys.
+func SetBackupNamePrefix(prefix string) error {
+	if !regexpBackupNamePrefix.MatchString(prefix) {
+		return errors.Errorf("invalid backup name prefix '%s': it must be letters and digits ending in '_'", prefix)
+	}
+	backupNamePrefix = prefix
+	return nil
+}
+
+// GetBackupNamePrefix returns the prefix of the base backup names
+func GetBackupNamePrefix() string {
+	return backupNamePrefix
+}
+
+// NormalizeWalFileName converts the WAL file name to the upper case if the normalization is enabled
+func NormalizeWalFileName(name string) string {
+	if objectKeyNormalization {
+		return strings.ToUpper(name)
+	}
+	return name
+}
 
 // Strips the backup WAL file name.
 func StripWalFileName(path string) string {
-	foundLsn := regexpLSN.FindAllString(path, 2)
+	lsnRegexp := regexpLSN
+	if objectKeyNormalization {
+		lsnRegexp = regexpLSNIgnoreCase
+	}
+	foundLsn := lsnRegexp.FindAllString(path, 2)
 	if len(foundLsn) > 0 {
-		return foundLsn[0]
+		return NormalizeWalFileName(foundLsn[0])
 	}
 	return strings.Repeat("Z", 24)
 }
@@ -294,6 +365,35 @@ func SelectMatchingFiles(fileMask string, filePathsToFilter map[string]bool) (ma
 	return result, nil
 }
 
+// SplitEscaped splits the value by the separator, which is kept in the parts when escaped with the backslash.
+// The backslash itself is escaped with another one, e.g. `a\,b,c\\` is split into `a,b` and `c\`.
+func SplitEscaped(value string, separator rune) []string {
+	var parts []string
+	var part strings.Builder
+	escaped := false
+	for _, char := range value {
+		switch {
+		case escaped:
+			if char != separator && char != '\\' {
+				part.WriteRune('\\')
+			}
+			part.WriteRune(char)
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == separator:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(char)
+		}
+	}
+	if escaped {
+		part.WriteRune('\\')
+	}
+	return append(parts, part.String())
+}
+
 // ResetTimer safety resets timer (drains channel if required)
 func ResetTimer(t *time.Timer, d time.Duration) {
 	if !t.Stop() {
@@ -424,6 +424,39 @@ func TestStripWalFileName_ReturnFirstLsn(t *testing.T) {
 	assert.Equal(t, paths[0], result)
 }
 
+func TestStripWalFileName_MixedCase(t *testing.T) {
+	path := "basebackups_005/base_00000001000000000000000a_00000040_backup_stop_sentinel.json"
+
+	assert.Equal(t, strings.Repeat("Z", 24), utility.StripWalFileName(path))
+
+	utility.SetObjectKeyNormalization(true)
+	defer utility.SetObjectKeyNormalization(false)
+	assert.Equal(t, "00000001000000000000000A", utility.StripWalFileName(path))
+}
+
+func TestNormalizeWalFileName(t *testing.T) {
+	assert.Equal(t, "0000000100000000000000aB", utility.NormalizeWalFileName("0000000100000000000000aB"))
+
+	utility.SetObjectKeyNormalization(true)
+	defer utility.SetObjectKeyNormalization(false)
+	assert.Equal(t, "0000000100000000000000AB", utility.NormalizeWalFileName("0000000100000000000000aB"))
+}
+
+func TestSetSentinelSuffix_Invalid(t *testing.T) {
+	for _, suffix := range []string{"", ".json", "_backup_stop_sentinel", "_sentinel.txt"} {
+		assert.Error(t, utility.SetSentinelSuffix(suffix))
+	}
+	assert.Equal(t, utility.SentinelSuffix, utility.GetSentinelSuffix())
+}
+
+func TestStripRightmostBackupName_CustomSentinelSuffix(t *testing.T) {
+	assert.NoError(t, utility.SetSentinelSuffix(".done.json"))
+	defer utility.SetSentinelSuffix(utility.SentinelSuffix)
+
+	assert.Equal(t, "base_000000010000000000000002",
+		utility.StripRightmostBackupName("basebackups_005/base_000000010000000000000002.done.json"))
+}
+
 func RandomLsn() string {
 	var letter = []rune("ABCDEF0123456789")
 	const LSNLength = 24
@@ -492,3 +525,21 @@ func TestLoggedCloseWithErrorAndCustomMessage(t *testing.T) {
 
 	assert.Equal(t, "custom error message: mock close: close error\n", string(loggedData))
 }
+
+func TestSetBackupNamePrefix(t *testing.T) {
+	defer utility.SetBackupNamePrefix(utility.BackupNamePrefix)
+	for _, prefix := range []string{"", "nightly", "night/ly_", "night-ly_", "_"} {
+		assert.Error(t, utility.SetBackupNamePrefix(prefix), prefix)
+	}
+	assert.Equal(t, utility.BackupNamePrefix, utility.GetBackupNamePrefix())
+	assert.NoError(t, utility.SetBackupNamePrefix("Nightly2_"))
+	assert.Equal(t, "Nightly2_", utility.GetBackupNamePrefix())
+}
+
+func TestSplitEscaped(t *testing.T) {
+	assert.Equal(t, []string{""}, utility.SplitEscaped("", ','))
+	assert.Equal(t, []string{"a", "", "b"}, utility.SplitEscaped("a,,b", ','))
+	assert.Equal(t, []string{"a,b", `c\`}, utility.SplitEscaped(`a\,b,c\\`, ','))
+	// the backslash before other characters is kept
+	assert.Equal(t, []string{`a\b`, `c\`}, utility.SplitEscaped(`a\b,c\`, ','))
+}